@@ -0,0 +1,59 @@
+package antireplay
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/9seconds/mtg/v2/internal/httpauth"
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+type containsHandler struct {
+	checker mtglib.AntiReplayChecker
+	token   string
+}
+
+type containsResponse struct {
+	Known bool `json:"known"`
+}
+
+func (c containsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !httpauth.AuthorizeGet(w, req, c.token) {
+		return
+	}
+
+	digest, err := hex.DecodeString(req.URL.Query().Get("digest"))
+	if err != nil || len(digest) == 0 {
+		http.Error(w, "digest has to be a non-empty hex string", http.StatusBadRequest)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	json.NewEncoder(w).Encode(containsResponse{ //nolint: errcheck
+		Known: c.checker.Contains(digest),
+	})
+}
+
+// NewContainsHandler returns an HTTP handler which answers if a digest is
+// known to a cache, without marking it as seen. It is intended for
+// debugging of suspected false positives.
+//
+// A digest is passed as a hex-encoded query parameter: GET
+// ?digest=<hex>. A response is a JSON object {"known": true}. A client
+// has to present a header 'Authorization: Bearer <token>', the same as for
+// NewStateHandler.
+//
+// A digest is exactly what a proxy passes to SeenBefore. If
+// mtglib.ProxyOpts.AntiReplayDigestFunc is not set, this is a session id
+// of a client hello. Please remember that for bloom filters "known" has
+// the same false positive rate as SeenBefore.
+func NewContainsHandler(checker mtglib.AntiReplayChecker, token string) http.Handler {
+	return containsHandler{
+		checker: checker,
+		token:   token,
+	}
+}
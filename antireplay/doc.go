@@ -60,12 +60,74 @@
 //   - False positives (1% default): Legitimate messages may occasionally be rejected.
 //     This is acceptable for DoS protection but adjust errorRate if critical.
 //
-//   - Hash collision attacks: Uses xxHash (non-cryptographic). For security-critical
-//     applications, consider using HMAC-based hashing of input digests.
+//   - Hash collision attacks: Uses xxHash (non-cryptographic). Seed is random
+//     per instance, so collision patterns differ between deployments. For
+//     security-critical applications, consider using HMAC-based hashing of
+//     input digests.
 //
 //   - Memory exhaustion: Fixed memory usage prevents unbounded growth, but ensure
 //     byteSize is appropriate for your traffic volume.
 //
+// # Peer Warmup
+//
+// A freshly started instance has an empty filter, so it does not know
+// session IDs which its siblings have already seen. Stable bloom filters
+// implement StateSaver: an instance can serve a snapshot of its filter
+// with NewStateHandler, and another instance can load it on startup with
+// WarmUpFromPeer.
+//
+// Consistency model is best-effort and eventually convergent:
+//   - A snapshot is a point-in-time copy. Session IDs which a peer sees
+//     after a snapshot was taken are not known to a new instance, and
+//     vice versa. There is no continuous replication.
+//   - If a warmup fails, an instance starts with an empty filter as
+//     usual. A failed warmup never prevents a proxy from starting.
+//   - Stable bloom filters constantly evict old entries, so filters of
+//     instances which see the same traffic converge to the same set of
+//     recent entries over time, and a difference made by a warmup fades.
+//   - Filters must be created with the same byte size and error rate.
+//     Incompatible snapshots are rejected with ErrIncompatibleState.
+//
+// Security of the transfer channel:
+//   - A snapshot includes xxHash seed, and a loading instance adopts it.
+//     Anyone who gets a snapshot can craft collisions in filters of all
+//     instances which share this seed. So the endpoint requires a bearer
+//     token (at least MinStateTokenLength characters), compared in
+//     constant time.
+//   - A token and a snapshot are sent in plain text over HTTP. Please
+//     serve the endpoint on a private network, unix socket or behind a TLS
+//     terminating proxy, and use https:// URLs for peers.
+//   - A loading instance never reads more than a size of its own
+//     snapshot and verifies filter parameters before applying it.
+//
+// # Lookups
+//
+// Stable bloom filters and read replicas implement
+// mtglib.AntiReplayChecker: Contains tells if a digest is known without
+// storing it. NewContainsHandler serves such lookups over HTTP, this helps
+// to debug a suspected false positive. Please remember that Contains is
+// SeenBefore minus a side effect: it has exactly the same false positives.
+//
+// # Read Replicas
+//
+// In a fleet with one primary instance which tracks replays, other
+// instances can work as read replicas: NewReadReplica wraps a filter so
+// SeenBefore only tests a digest with Contains and never writes,
+// and SyncFromPeer periodically replaces a filter with a snapshot of a
+// primary. This removes write contention from replicas.
+//
+// A guarantee of a replica is weaker than one of a primary:
+//   - A replica detects only replays of session IDs which a primary had
+//     seen by a time of a last synced snapshot. Session IDs which a
+//     primary sees later are unknown to a replica until a next sync.
+//   - Session IDs which come to a replica are never recorded, so a replay
+//     of such session ID to the same or another replica is not detected,
+//     unless it has also reached a primary.
+//   - If a sync fails, a replica keeps working with a stale snapshot.
+//
+// So please use replicas only if a window of one sync interval is
+// acceptable for your threat model.
+//
 // # Monitoring
 //
 // For production deployments, monitor:
@@ -73,5 +135,7 @@
 //   - False positive rate (compare with expected 1%)
 //   - Mutex contention (if performance degrades)
 //
-// See NewStableBloomFilterWithMetrics for instrumented version.
+// Both constructors return caches implementing StatsReporter with basic
+// check/duplicate counters. See NewStableBloomFilterWithMetrics for
+// instrumented version.
 package antireplay
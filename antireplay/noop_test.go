@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/9seconds/mtg/v2/antireplay"
+	"github.com/9seconds/mtg/v2/mtglib"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -18,6 +19,7 @@ func (suite *NoopTestSuite) TestOp() {
 	suite.False(filter.SeenBefore([]byte{4, 5, 6}))
 	suite.False(filter.SeenBefore([]byte{1, 2, 3}))
 	suite.False(filter.SeenBefore([]byte{4, 5, 6}))
+	suite.False(filter.(mtglib.AntiReplayChecker).Contains([]byte{1, 2, 3}))
 }
 
 func TestNoop(t *testing.T) {
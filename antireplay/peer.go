@@ -0,0 +1,81 @@
+package antireplay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/9seconds/mtg/v2/internal/httpauth"
+)
+
+// MinStateTokenLength is a minimal length of a token which protects filter
+// state transfers between instances.
+const MinStateTokenLength = 16
+
+type stateHandler struct {
+	saver StateSaver
+	token string
+}
+
+func (s stateHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !httpauth.AuthorizeGet(w, req, s.token) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := s.saver.SaveState(buf); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes()) //nolint: errcheck
+}
+
+// NewStateHandler returns an HTTP handler which serves a snapshot of a
+// filter to other instances. A client has to present a header
+// 'Authorization: Bearer <token>'. Please see WarmUpFromPeer.
+//
+// A snapshot contains xxHash seed of a filter. This seed protects a filter
+// from crafted collisions, so please serve this handler on a private
+// network or behind TLS only.
+func NewStateHandler(saver StateSaver, token string) http.Handler {
+	return stateHandler{
+		saver: saver,
+		token: token,
+	}
+}
+
+// WarmUpFromPeer fetches a snapshot from a handler created by
+// NewStateHandler of another instance and loads it into a given filter.
+// Both filters have to be created with the same byte size and error rate,
+// otherwise ErrIncompatibleState is returned.
+//
+// This is a best-effort operation which is intended to be done once on
+// startup, before a filter gets any traffic: current state of a filter is
+// replaced. Please see package documentation for a consistency model.
+func WarmUpFromPeer(ctx context.Context, client *http.Client, url, token string, loader StateSaver) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("cannot build a request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot fetch filter state: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return loader.LoadState(resp.Body) //nolint: wrapcheck
+}
@@ -0,0 +1,189 @@
+package antireplay_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/antireplay"
+	"github.com/stretchr/testify/suite"
+)
+
+const peerTestToken = "0123456789abcdef"
+
+type PeerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PeerTestSuite) TestSaveLoadState() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	source.SeenBefore([]byte{1, 2, 3})
+
+	buf := &bytes.Buffer{}
+	suite.NoError(source.SaveState(buf))
+
+	target := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	suite.NoError(target.LoadState(buf))
+
+	suite.True(target.SeenBefore([]byte{1, 2, 3}))
+	suite.False(target.SeenBefore([]byte{4, 5, 6}))
+}
+
+func (suite *PeerTestSuite) TestLoadIncompatibleState() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+
+	buf := &bytes.Buffer{}
+	suite.NoError(source.SaveState(buf))
+
+	state := buf.Bytes()
+
+	bigger := antireplay.NewStableBloomFilterWithMetrics(1000, 0.001)
+	suite.ErrorIs(bigger.LoadState(bytes.NewReader(state)), antireplay.ErrIncompatibleState)
+
+	otherRate := antireplay.NewStableBloomFilterWithMetrics(500, 0.1)
+	suite.ErrorIs(otherRate.LoadState(bytes.NewReader(state)), antireplay.ErrIncompatibleState)
+
+	target := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	suite.ErrorIs(target.LoadState(bytes.NewReader(state[:len(state)-1])), antireplay.ErrIncompatibleState)
+	suite.ErrorIs(target.LoadState(bytes.NewReader(append(state, 0))), antireplay.ErrIncompatibleState)
+
+	corrupted := bytes.Clone(state)
+	corrupted[0] = 'x'
+	suite.ErrorIs(target.LoadState(bytes.NewReader(corrupted)), antireplay.ErrIncompatibleState)
+}
+
+func (suite *PeerTestSuite) TestWarmUpFromPeer() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	source.SeenBefore([]byte{1, 2, 3})
+
+	server := httptest.NewServer(antireplay.NewStateHandler(source, peerTestToken))
+	defer server.Close()
+
+	target := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+
+	suite.NoError(antireplay.WarmUpFromPeer(context.Background(), server.Client(),
+		server.URL, peerTestToken, target))
+	suite.True(target.SeenBefore([]byte{1, 2, 3}))
+}
+
+func (suite *PeerTestSuite) TestWarmUpFromPeerWrongToken() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+
+	server := httptest.NewServer(antireplay.NewStateHandler(source, peerTestToken))
+	defer server.Close()
+
+	target := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+
+	suite.Error(antireplay.WarmUpFromPeer(context.Background(), server.Client(),
+		server.URL, "fedcba9876543210", target))
+
+	resp, err := server.Client().Post(server.URL, "text/plain", nil)
+	suite.NoError(err)
+	resp.Body.Close()
+	suite.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func (suite *PeerTestSuite) TestContainsHandler() {
+	filter := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	filter.SeenBefore([]byte{1, 2, 3})
+
+	server := httptest.NewServer(antireplay.NewContainsHandler(filter, peerTestToken))
+	defer server.Close()
+
+	get := func(query, token string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/contains?"+query, nil)
+		suite.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := server.Client().Do(req)
+		suite.NoError(err)
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		suite.NoError(err)
+
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	status, body := get("digest=010203", peerTestToken)
+	suite.Equal(http.StatusOK, status)
+	suite.JSONEq(`{"known": true}`, body)
+
+	status, body = get("digest=040506", peerTestToken)
+	suite.Equal(http.StatusOK, status)
+	suite.JSONEq(`{"known": false}`, body)
+
+	// Проверка не должна запоминать digest.
+	status, body = get("digest=040506", peerTestToken)
+	suite.Equal(http.StatusOK, status)
+	suite.JSONEq(`{"known": false}`, body)
+
+	status, _ = get("digest=zz", peerTestToken)
+	suite.Equal(http.StatusBadRequest, status)
+
+	status, _ = get("digest=010203", "fedcba9876543210")
+	suite.Equal(http.StatusUnauthorized, status)
+}
+
+func (suite *PeerTestSuite) TestReadReplica() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	source.SeenBefore([]byte{1, 2, 3})
+
+	server := httptest.NewServer(antireplay.NewStateHandler(source, peerTestToken))
+	defer server.Close()
+
+	filter := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	replica := antireplay.NewReadReplica(filter)
+
+	suite.NoError(antireplay.WarmUpFromPeer(context.Background(), server.Client(),
+		server.URL, peerTestToken, replica.(antireplay.StateSaver)))
+
+	suite.True(replica.SeenBefore([]byte{1, 2, 3}))
+	suite.False(replica.SeenBefore([]byte{4, 5, 6}))
+	suite.False(replica.SeenBefore([]byte{4, 5, 6}))
+	suite.False(filter.Contains([]byte{4, 5, 6}))
+}
+
+func (suite *PeerTestSuite) TestSyncFromPeer() {
+	source := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+
+	server := httptest.NewServer(antireplay.NewStateHandler(source, peerTestToken))
+	defer server.Close()
+
+	replica := antireplay.NewStableBloomFilterWithMetrics(500, 0.001)
+	results := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go antireplay.SyncFromPeer(ctx, server.Client(), server.URL, peerTestToken,
+		replica, 10*time.Millisecond, func(err error) {
+			select {
+			case results <- err:
+			default:
+			}
+		})
+
+	source.SeenBefore([]byte{1, 2, 3})
+
+	suite.Eventually(func() bool {
+		select {
+		case err := <-results:
+			return err == nil && replica.Contains([]byte{1, 2, 3})
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPeer(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PeerTestSuite{})
+}
@@ -0,0 +1,88 @@
+package antireplay
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+// DefaultReplicaSyncInterval is a default period between snapshot
+// fetches of a read replica. Please see SyncFromPeer.
+const DefaultReplicaSyncInterval = time.Minute
+
+// MinReplicaSyncInterval is a minimal sane period between snapshot
+// fetches of a read replica. Each fetch transfers a whole filter, so
+// more frequent fetches load a primary for a little gain.
+const MinReplicaSyncInterval = 5 * time.Second
+
+// ReplicaFilter is a filter which can back a read replica: it is tested
+// without writes with mtglib.AntiReplayChecker and its state is replaced
+// by snapshots of a primary. Both NewStableBloomFilter and
+// NewStableBloomFilterWithMetrics return filters which implement it.
+type ReplicaFilter interface {
+	mtglib.AntiReplayChecker
+	StateSaver
+}
+
+type readReplica struct {
+	ReplicaFilter
+}
+
+func (r readReplica) SeenBefore(digest []byte) bool {
+	return r.Contains(digest)
+}
+
+// NewReadReplica returns an anti-replay cache which never writes into a
+// given filter: SeenBefore only tests a digest. Such cache makes sense
+// only if a filter is periodically replaced by snapshots of a primary
+// instance, please see SyncFromPeer.
+//
+// A read replica gives weaker guarantees than a primary. It does not
+// detect replays of session IDs which it has not synced yet: neither
+// those which a primary has seen after a last snapshot, nor those which
+// went to this replica only. Please see package documentation.
+//
+// Returned cache implements StateSaver, so it can be passed to
+// WarmUpFromPeer and SyncFromPeer directly.
+func NewReadReplica(filter ReplicaFilter) mtglib.AntiReplayCache {
+	return readReplica{
+		ReplicaFilter: filter,
+	}
+}
+
+// SyncFromPeer periodically loads snapshots of a peer into a given
+// filter with WarmUpFromPeer until a context is done. A first fetch
+// happens after a given interval, so please warm up a filter beforehand.
+// callback is called with a result of each fetch, it may be nil.
+//
+// This function blocks, please run it in a separate goroutine.
+func SyncFromPeer(ctx context.Context,
+	client *http.Client,
+	url, token string,
+	loader StateSaver,
+	interval time.Duration,
+	callback func(error),
+) {
+	if interval <= 0 {
+		interval = DefaultReplicaSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := WarmUpFromPeer(ctx, client, url, token, loader)
+
+		if callback != nil {
+			callback(err)
+		}
+	}
+}
@@ -1,23 +1,70 @@
 package antireplay
 
 import (
+	"crypto/rand"
+	"encoding/binary"
 	"sync"
+	"sync/atomic"
 
 	"github.com/9seconds/mtg/v2/mtglib"
 	"github.com/OneOfOne/xxhash"
 	boom "github.com/tylertreat/BoomFilters"
 )
 
+// Stats is a lightweight set of counters which every stable bloom filter
+// maintains.
+type Stats struct {
+	Checks     uint64 // Total number of SeenBefore calls
+	Duplicates uint64 // Number of detected duplicates
+}
+
+// StatsReporter is implemented by anti-replay caches which can report
+// their Stats. Both NewStableBloomFilter and
+// NewStableBloomFilterWithMetrics return caches which implement it.
+type StatsReporter interface {
+	Stats() Stats
+}
+
 type stableBloomFilter struct {
 	filter boom.StableBloomFilter
+	seed   uint64
 	mutex  sync.Mutex
+
+	checks     atomic.Uint64
+	duplicates atomic.Uint64
 }
 
 func (s *stableBloomFilter) SeenBefore(digest []byte) bool {
+	s.checks.Add(1)
+
+	s.mutex.Lock()
+	isDuplicate := s.filter.TestAndAdd(digest)
+	s.mutex.Unlock()
+
+	if isDuplicate {
+		s.duplicates.Add(1)
+	}
+
+	return isDuplicate
+}
+
+// Contains reports if a digest was seen before without adding it and
+// without updating counters. It has the same false positive rate as
+// SeenBefore: a stable bloom filter can not say for sure that an element
+// was seen, only that it probably was.
+func (s *stableBloomFilter) Contains(digest []byte) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.filter.TestAndAdd(digest)
+	return s.filter.Test(digest)
+}
+
+// Stats returns current counters. Thread-safe (uses atomic loads).
+func (s *stableBloomFilter) Stats() Stats {
+	return Stats{
+		Checks:     s.checks.Load(),
+		Duplicates: s.duplicates.Load(),
+	}
 }
 
 // NewStableBloomFilter returns an implementation of AntiReplayCache based on
@@ -33,7 +80,38 @@ func (s *stableBloomFilter) SeenBefore(digest []byte) bool {
 // byteSize is the number of bytes you want to give to a bloom filter.
 // errorRate is desired false-positive error rate. If you want to use default
 // values, please pass 0 for byteSize and <0 for errorRate.
+//
+// Returned cache implements StatsReporter, so it is possible to get
+// check and duplicate counts without switching to
+// NewStableBloomFilterWithMetrics. It also implements StateSaver and
+// mtglib.AntiReplayChecker.
+//
+// xxHash seed is randomly generated, so collision patterns differ for each
+// instance. Use NewStableBloomFilterWithSeed if you need a fixed seed.
 func NewStableBloomFilter(byteSize uint, errorRate float64) mtglib.AntiReplayCache {
+	return newStableBloomFilter(byteSize, errorRate, NewHashSeed())
+}
+
+// NewStableBloomFilterWithSeed is the same as NewStableBloomFilter but
+// uses a given xxHash seed. xxHash is keyless and public, so a fixed
+// well-known seed allows an attacker to craft inputs which collide in the
+// filter. Please use a per-deployment random seed (see NewHashSeed).
+func NewStableBloomFilterWithSeed(byteSize uint, errorRate float64, seed uint64) mtglib.AntiReplayCache {
+	return newStableBloomFilter(byteSize, errorRate, seed)
+}
+
+// NewHashSeed generates a new random seed for xxHash.
+func NewHashSeed() uint64 {
+	buf := [8]byte{}
+
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func newStableBloomFilter(byteSize uint, errorRate float64, seed uint64) *stableBloomFilter {
 	if byteSize == 0 {
 		byteSize = DefaultStableBloomFilterMaxSize
 	}
@@ -43,9 +121,10 @@ func NewStableBloomFilter(byteSize uint, errorRate float64) mtglib.AntiReplayCac
 	}
 
 	sf := boom.NewDefaultStableBloomFilter(byteSize*8, errorRate) //nolint: gomnd
-	sf.SetHash(xxhash.New64())
+	sf.SetHash(xxhash.NewS64(seed))
 
 	return &stableBloomFilter{
 		filter: *sf,
+		seed:   seed,
 	}
 }
@@ -1,40 +1,12 @@
 package antireplay
 
 import (
-	"sync"
-	"sync/atomic"
-
 	"github.com/9seconds/mtg/v2/mtglib"
-	"github.com/OneOfOne/xxhash"
-	boom "github.com/tylertreat/BoomFilters"
 )
 
 // stableBloomFilterWithMetrics wraps stableBloomFilter with performance and security metrics.
 type stableBloomFilterWithMetrics struct {
-	filter boom.StableBloomFilter
-	mutex  sync.Mutex
-
-	// Metrics (atomic counters for lock-free reads)
-	totalChecks   uint64 // Total number of SeenBefore calls
-	replayDetected uint64 // Number of replays detected (duplicates found)
-	uniqueMessages uint64 // Number of unique messages (first-time seen)
-}
-
-func (s *stableBloomFilterWithMetrics) SeenBefore(digest []byte) bool {
-	atomic.AddUint64(&s.totalChecks, 1)
-
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	isDuplicate := s.filter.TestAndAdd(digest)
-
-	if isDuplicate {
-		atomic.AddUint64(&s.replayDetected, 1)
-	} else {
-		atomic.AddUint64(&s.uniqueMessages, 1)
-	}
-
-	return isDuplicate
+	*stableBloomFilter
 }
 
 // Metrics returns current anti-replay statistics.
@@ -48,13 +20,11 @@ type Metrics struct {
 
 // GetMetrics returns current statistics. Thread-safe (uses atomic loads).
 func (s *stableBloomFilterWithMetrics) GetMetrics() Metrics {
-	totalChecks := atomic.LoadUint64(&s.totalChecks)
-	replayDetected := atomic.LoadUint64(&s.replayDetected)
-	uniqueMessages := atomic.LoadUint64(&s.uniqueMessages)
+	stats := s.Stats()
 
 	var replayRate float64
-	if totalChecks > 0 {
-		replayRate = float64(replayDetected) / float64(totalChecks) * 100.0
+	if stats.Checks > 0 {
+		replayRate = float64(stats.Duplicates) / float64(stats.Checks) * 100.0
 	}
 
 	s.mutex.Lock()
@@ -62,9 +32,9 @@ func (s *stableBloomFilterWithMetrics) GetMetrics() Metrics {
 	s.mutex.Unlock()
 
 	return Metrics{
-		TotalChecks:     totalChecks,
-		ReplayDetected:  replayDetected,
-		UniqueMessages:  uniqueMessages,
+		TotalChecks:     stats.Checks,
+		ReplayDetected:  stats.Duplicates,
+		UniqueMessages:  stats.Checks - stats.Duplicates,
 		ReplayRate:      replayRate,
 		EstimatedFPRate: estimatedFPRate,
 	}
@@ -72,9 +42,8 @@ func (s *stableBloomFilterWithMetrics) GetMetrics() Metrics {
 
 // ResetMetrics resets all counters to zero. Does NOT reset the bloom filter itself.
 func (s *stableBloomFilterWithMetrics) ResetMetrics() {
-	atomic.StoreUint64(&s.totalChecks, 0)
-	atomic.StoreUint64(&s.replayDetected, 0)
-	atomic.StoreUint64(&s.uniqueMessages, 0)
+	s.checks.Store(0)
+	s.duplicates.Store(0)
 }
 
 // NewStableBloomFilterWithMetrics returns an instrumented anti-replay cache.
@@ -86,27 +55,34 @@ func (s *stableBloomFilterWithMetrics) ResetMetrics() {
 //   - Replay rate percentage
 //   - Estimated false positive rate
 //
-// Use GetMetrics() to retrieve statistics for monitoring/alerting.
+// Use GetMetrics() to retrieve statistics for monitoring/alerting. Basic
+// counters are also available via Stats(), the same as for
+// NewStableBloomFilter.
+//
+// xxHash seed is randomly generated, the same as for NewStableBloomFilter.
 //
 // Parameters are the same as NewStableBloomFilter:
 //   - byteSize: memory allocation in bytes (0 for default 1 MB)
 //   - errorRate: desired false positive rate (negative for default 1%)
 func NewStableBloomFilterWithMetrics(byteSize uint, errorRate float64) *stableBloomFilterWithMetrics {
-	if byteSize == 0 {
-		byteSize = DefaultStableBloomFilterMaxSize
-	}
-
-	if errorRate < 0 {
-		errorRate = DefaultStableBloomFilterErrorRate
-	}
-
-	sf := boom.NewDefaultStableBloomFilter(byteSize*8, errorRate) //nolint: gomnd
-	sf.SetHash(xxhash.New64())
-
 	return &stableBloomFilterWithMetrics{
-		filter: *sf,
+		stableBloomFilter: newStableBloomFilter(byteSize, errorRate, NewHashSeed()),
 	}
 }
 
 // Ensure interface compliance
-var _ mtglib.AntiReplayCache = (*stableBloomFilterWithMetrics)(nil)
+var (
+	_ mtglib.AntiReplayCache   = (*stableBloomFilterWithMetrics)(nil)
+	_ StatsReporter            = (*stableBloomFilterWithMetrics)(nil)
+	_ StatsReporter            = (*stableBloomFilter)(nil)
+	_ StateSaver               = (*stableBloomFilterWithMetrics)(nil)
+	_ StateSaver               = (*stableBloomFilter)(nil)
+	_ ReplicaFilter            = (*stableBloomFilterWithMetrics)(nil)
+	_ ReplicaFilter            = (*stableBloomFilter)(nil)
+	_ mtglib.AntiReplayCache   = readReplica{}
+	_ mtglib.AntiReplayChecker = (*stableBloomFilterWithMetrics)(nil)
+	_ mtglib.AntiReplayChecker = (*stableBloomFilter)(nil)
+	_ mtglib.AntiReplayChecker = readReplica{}
+	_ mtglib.AntiReplayChecker = noop{}
+	_ StateSaver               = readReplica{}
+)
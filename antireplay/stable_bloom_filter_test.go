@@ -4,15 +4,21 @@ import (
 	"testing"
 
 	"github.com/9seconds/mtg/v2/antireplay"
+	"github.com/9seconds/mtg/v2/mtglib"
 	"github.com/stretchr/testify/suite"
 )
 
+// testFilterSize достаточно велик, чтобы случайное вытеснение при
+// добавлении почти никогда не задевало только что добавленные записи:
+// в фильтре на 500 байт тесты падали примерно раз в 20 запусков.
+const testFilterSize = 64 * 1024
+
 type StableBloomFilterTestSuite struct {
 	suite.Suite
 }
 
 func (suite *StableBloomFilterTestSuite) TestOp() {
-	filter := antireplay.NewStableBloomFilter(500, 0.001)
+	filter := antireplay.NewStableBloomFilter(testFilterSize, 0.001)
 
 	suite.False(filter.SeenBefore([]byte{1, 2, 3}))
 	suite.False(filter.SeenBefore([]byte{4, 5, 6}))
@@ -20,6 +26,63 @@ func (suite *StableBloomFilterTestSuite) TestOp() {
 	suite.True(filter.SeenBefore([]byte{4, 5, 6}))
 }
 
+func (suite *StableBloomFilterTestSuite) TestOpWithSeed() {
+	filter := antireplay.NewStableBloomFilterWithSeed(testFilterSize, 0.001, 0xdeadbeef)
+
+	suite.False(filter.SeenBefore([]byte{1, 2, 3}))
+	suite.False(filter.SeenBefore([]byte{4, 5, 6}))
+	suite.True(filter.SeenBefore([]byte{1, 2, 3}))
+	suite.True(filter.SeenBefore([]byte{4, 5, 6}))
+}
+
+func (suite *StableBloomFilterTestSuite) TestNewHashSeed() {
+	suite.NotEqual(antireplay.NewHashSeed(), antireplay.NewHashSeed())
+}
+
+func (suite *StableBloomFilterTestSuite) TestStats() {
+	filter := antireplay.NewStableBloomFilter(testFilterSize, 0.001)
+
+	reporter, ok := filter.(antireplay.StatsReporter)
+	suite.True(ok)
+
+	filter.SeenBefore([]byte{1, 2, 3})
+	filter.SeenBefore([]byte{4, 5, 6})
+	filter.SeenBefore([]byte{1, 2, 3})
+
+	suite.Equal(antireplay.Stats{Checks: 3, Duplicates: 1}, reporter.Stats())
+}
+
+func (suite *StableBloomFilterTestSuite) TestContains() {
+	filter := antireplay.NewStableBloomFilter(testFilterSize, 0.001)
+
+	checker, ok := filter.(mtglib.AntiReplayChecker)
+	suite.True(ok)
+
+	suite.False(checker.Contains([]byte{1, 2, 3}))
+	suite.False(checker.Contains([]byte{1, 2, 3}))
+	suite.False(filter.SeenBefore([]byte{1, 2, 3}))
+	suite.True(checker.Contains([]byte{1, 2, 3}))
+	suite.Equal(antireplay.Stats{Checks: 1}, filter.(antireplay.StatsReporter).Stats())
+}
+
+func (suite *StableBloomFilterTestSuite) TestMetrics() {
+	filter := antireplay.NewStableBloomFilterWithMetrics(testFilterSize, 0.001)
+
+	filter.SeenBefore([]byte{1, 2, 3})
+	filter.SeenBefore([]byte{1, 2, 3})
+
+	suite.Equal(antireplay.Stats{Checks: 2, Duplicates: 1}, filter.Stats())
+
+	metrics := filter.GetMetrics()
+	suite.EqualValues(2, metrics.TotalChecks)
+	suite.EqualValues(1, metrics.ReplayDetected)
+	suite.EqualValues(1, metrics.UniqueMessages)
+	suite.InDelta(50.0, metrics.ReplayRate, 0.001)
+
+	filter.ResetMetrics()
+	suite.Equal(antireplay.Stats{}, filter.Stats())
+}
+
 func TestStableBloomFilter(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &StableBloomFilterTestSuite{})
@@ -0,0 +1,126 @@
+package antireplay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/OneOfOne/xxhash"
+	boom "github.com/tylertreat/BoomFilters"
+)
+
+const (
+	stateVersion = 1
+
+	// magic + version + xxHash seed
+	stateHeaderSize = len(stateMagic) + 1 + 8
+
+	// m, p, k, max and a length of index buffer of boom.StableBloomFilter.
+	// Index buffer itself (k uint64) goes right after.
+	stateFilterHeaderSize = 8 + 8 + 8 + 1 + 8
+
+	// bucket size, max, count and data length of boom.Buckets
+	stateBucketsHeaderSize = 1 + 1 + 8 + 8
+)
+
+var stateMagic = [4]byte{'m', 't', 'g', 'a'}
+
+// ErrIncompatibleState is returned by LoadState if a snapshot was taken
+// from a filter with different parameters (byte size or error rate) or is
+// corrupted.
+var ErrIncompatibleState = errors.New("incompatible filter state")
+
+// StateSaver is implemented by anti-replay caches which can serialize
+// their state. Both NewStableBloomFilter and
+// NewStableBloomFilterWithMetrics return caches which implement it.
+type StateSaver interface {
+	// SaveState writes a point-in-time snapshot of a filter.
+	SaveState(io.Writer) error
+
+	// LoadState replaces a filter state with a snapshot written by
+	// SaveState. A snapshot carries xxHash seed so a filter adopts a seed
+	// of the filter which produced this snapshot. Current state is lost.
+	LoadState(io.Reader) error
+}
+
+func (s *stableBloomFilter) SaveState(w io.Writer) error {
+	buf := &bytes.Buffer{}
+
+	buf.Write(stateMagic[:])
+	buf.WriteByte(stateVersion)
+	binary.Write(buf, binary.BigEndian, s.seed) //nolint: errcheck
+
+	s.mutex.Lock()
+	_, err := s.filter.WriteTo(buf)
+	s.mutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("cannot serialize filter: %w", err)
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("cannot write filter state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *stableBloomFilter) LoadState(r io.Reader) error {
+	// Local filter serves as a reference: a snapshot of compatible filter
+	// has the same size and the same headers.
+	local := &bytes.Buffer{}
+
+	s.mutex.Lock()
+	_, err := s.filter.WriteTo(local)
+	hashes := s.filter.K()
+	s.mutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("cannot serialize filter: %w", err)
+	}
+
+	expectedSize := int64(stateHeaderSize + local.Len())
+
+	data, err := io.ReadAll(io.LimitReader(r, expectedSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read filter state: %w", err)
+	}
+
+	if int64(len(data)) != expectedSize {
+		return fmt.Errorf("%w: unexpected size %d, expected %d", ErrIncompatibleState, len(data), expectedSize)
+	}
+
+	if !bytes.Equal(data[:len(stateMagic)], stateMagic[:]) || data[len(stateMagic)] != stateVersion {
+		return fmt.Errorf("%w: unknown format", ErrIncompatibleState)
+	}
+
+	seed := binary.BigEndian.Uint64(data[len(stateMagic)+1:])
+	payload := data[stateHeaderSize:]
+
+	// boom.StableBloomFilter.ReadFrom trusts lengths from the stream, so
+	// headers are verified before.
+	bucketsOffset := stateFilterHeaderSize + 8*int(hashes) //nolint: gomnd
+	reference := local.Bytes()
+
+	if !bytes.Equal(payload[:stateFilterHeaderSize], reference[:stateFilterHeaderSize]) ||
+		!bytes.Equal(payload[bucketsOffset:bucketsOffset+stateBucketsHeaderSize],
+			reference[bucketsOffset:bucketsOffset+stateBucketsHeaderSize]) {
+		return fmt.Errorf("%w: filter parameters differ", ErrIncompatibleState)
+	}
+
+	filter := boom.NewDefaultStableBloomFilter(1, DefaultStableBloomFilterErrorRate)
+	if _, err := filter.ReadFrom(bytes.NewReader(payload)); err != nil {
+		return fmt.Errorf("%w: %w", ErrIncompatibleState, err)
+	}
+
+	filter.SetHash(xxhash.NewS64(seed))
+
+	s.mutex.Lock()
+	s.filter = *filter
+	s.seed = seed
+	s.mutex.Unlock()
+
+	return nil
+}
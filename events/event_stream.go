@@ -2,10 +2,13 @@ package events
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
+	"reflect"
 	"runtime"
 	"sync/atomic"
 
+	"github.com/9seconds/mtg/v2/internal/affinity"
 	"github.com/9seconds/mtg/v2/mtglib"
 	"github.com/OneOfOne/xxhash"
 )
@@ -22,21 +25,58 @@ import (
 type EventStream struct {
 	ctx       context.Context
 	ctxCancel context.CancelFunc
-	chans     []chan mtglib.Event
+	chans     []chan streamEvent
+
+	// priorities — приоритеты по типу события. Отсутствующий тип
+	// означает EventPriorityBlocking.
+	priorities map[reflect.Type]EventPriority
+
+	// trafficPriority — priorities для EventTraffic, посчитанный
+	// заранее: SendTraffic не платит за reflect и поиск в map.
+	trafficPriority EventPriority
 
 	// dropped считает количество потерянных событий при overflow.
 	// Указатель — EventStream использует value receiver, atomic.Uint64 содержит noCopy.
 	dropped *atomic.Uint64
 }
 
+// streamEvent — элемент канала процессора. EventTraffic лежит в нём по
+// значению: это самое частое событие, и упаковка в интерфейс стоила бы
+// аллокации на каждое. Остальные события приходят в evt, для traffic
+// он nil.
+//
+// Один канал на оба вида сохраняет порядок событий стрима: traffic не
+// обгонит EventStart и не опоздает к EventFinish.
+type streamEvent struct {
+	evt     mtglib.Event
+	traffic mtglib.EventTraffic
+}
+
 // Send delivers event to observer non-blocking.
-// При переполнении канала EventTraffic события отбрасываются (drop-on-overflow)
-// для предотвращения блокировки relay goroutine.
+// При переполнении канала droppable события отбрасываются (drop-on-overflow)
+// для предотвращения блокировки relay goroutine и accept loop.
 // Важные события (Start, Finish, Connect, Security) всегда доставляются блокирующе.
+// Please see EventPriority.
 func (e EventStream) Send(ctx context.Context, evt mtglib.Event) {
+	if traffic, ok := evt.(mtglib.EventTraffic); ok {
+		e.SendTraffic(ctx, traffic)
+
+		return
+	}
+
+	e.send(ctx, evt.StreamID(), e.priorities[reflect.TypeOf(evt)], streamEvent{evt: evt})
+}
+
+// SendTraffic delivers EventTraffic without boxing it into
+// [mtglib.Event]. Please see [mtglib.TrafficEventStream].
+func (e EventStream) SendTraffic(ctx context.Context, evt mtglib.EventTraffic) {
+	e.send(ctx, evt.StreamID(), e.trafficPriority, streamEvent{traffic: evt})
+}
+
+func (e EventStream) send(ctx context.Context, streamID string, priority EventPriority, item streamEvent) {
 	var chanNo uint32
 
-	if streamID := evt.StreamID(); streamID != "" {
+	if streamID != "" {
 		chanNo = xxhash.ChecksumString32(streamID)
 	} else {
 		chanNo = rand.Uint32()
@@ -48,28 +88,29 @@ func (e EventStream) Send(ctx context.Context, evt mtglib.Event) {
 	// При slow Prometheus consumer (GC pause, disk IO) буфер 64 заполняется
 	// за ~2 секунды, после чего relay goroutine блокируется на Send().
 	// Это замедляет передачу данных клиенту — недопустимо для proxy.
+	// Периодические метрики (размеры списков, кэши, пулы) придут снова
+	// через несколько секунд, терять их тоже не страшно.
 	//
 	// Остальные события (Start, Finish, ConnectedToDC, ReplayAttack и т.д.)
 	// редкие и критичные для Prometheus метрик — для них блокировка допустима.
-	if _, isTraffic := evt.(mtglib.EventTraffic); isTraffic {
+	if priority == EventPriorityDroppable {
 		select {
 		case <-ctx.Done():
 		case <-e.ctx.Done():
-		case ch <- evt:
+		case ch <- item:
 		default:
-			// Буфер переполнен — отбрасываем traffic event.
-			// Метрики traffic будут чуть менее точными, но relay не блокируется.
+			// Буфер переполнен — отбрасываем событие.
+			// Метрики будут чуть менее точными, но отправитель не блокируется.
 			e.dropped.Add(1)
 		}
 
 		return
 	}
 
-	// Для некритичного пути (Start, Finish и т.д.) — блокирующая доставка.
 	select {
 	case <-ctx.Done():
 	case <-e.ctx.Done():
-	case ch <- evt:
+	case ch <- item:
 	}
 }
 
@@ -89,69 +130,199 @@ func (e EventStream) Shutdown() {
 // to be used. If you give many observers, then they will process a
 // message concurrently.
 func NewEventStream(observerFactories []ObserverFactory) EventStream {
+	return NewEventStreamWithPriorities(observerFactories)
+}
+
+// NewEventStreamWithPriorities is NewEventStream which overrides
+// default priorities of some event types. Please see
+// DefaultEventPriorities.
+//
+//	events.NewEventStreamWithPriorities(factories,
+//	    events.PriorityOverride{
+//	        Event:    mtglib.EventScannerDetected{},
+//	        Priority: events.EventPriorityDroppable,
+//	    })
+func NewEventStreamWithPriorities(observerFactories []ObserverFactory, overrides ...PriorityOverride) EventStream {
+	rv, _ := newEventStream(observerFactories, nil, overrides)
+
+	return rv
+}
+
+// NewEventStreamWithAffinity is NewEventStreamWithPriorities which pins
+// event processing goroutines to given CPU cores with sched_setaffinity.
+// Each of them is locked to its own OS thread.
+//
+// If pinning fails, nothing is started and an error is returned. Linux
+// only: on other platforms processors are not pinned.
+func NewEventStreamWithAffinity(observerFactories []ObserverFactory,
+	cpus []int,
+	overrides ...PriorityOverride,
+) (EventStream, error) {
+	return newEventStream(observerFactories, cpus, overrides)
+}
+
+func newEventStream(observerFactories []ObserverFactory,
+	cpus []int,
+	overrides []PriorityOverride,
+) (EventStream, error) {
 	if len(observerFactories) == 0 {
 		observerFactories = append(observerFactories, NewNoopObserver)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	priorities := makeEventPriorities(overrides)
 	rv := EventStream{
 		ctx:       ctx,
 		ctxCancel: cancel,
-		chans:     make([]chan mtglib.Event, runtime.NumCPU()),
+		chans:     make([]chan streamEvent, runtime.NumCPU()),
 		dropped:   &atomic.Uint64{},
+
+		priorities:      priorities,
+		trafficPriority: priorities[reflect.TypeOf(mtglib.EventTraffic{})],
 	}
 
+	// Процессор сначала привязывается к ядрам и сообщает результат, и
+	// только потом создаёт observer: при ошибке observer не нужен.
+	pinned := make(chan error, len(rv.chans))
+
 	for i := 0; i < runtime.NumCPU(); i++ {
 		// Буфер 64: предотвращает блокировку relay при медленной обработке метрик.
 		// connTraffic.Send() вызывается на каждый Read/Write — при буфере 1
 		// relay ждёт observer'а, замедляя передачу данных клиенту.
-		rv.chans[i] = make(chan mtglib.Event, 64)
+		rv.chans[i] = make(chan streamEvent, 64)
+
+		go func(eventChan <-chan streamEvent) {
+			if len(cpus) > 0 {
+				unpin, err := affinity.Pin(cpus)
+				pinned <- err
+
+				if err != nil {
+					return
+				}
+
+				defer unpin()
+			}
+
+			if len(observerFactories) == 1 {
+				eventStreamProcessor(ctx, eventChan, observerFactories[0]())
+			} else {
+				eventStreamProcessor(ctx, eventChan, newMultiObserver(observerFactories))
+			}
+		}(rv.chans[i])
+	}
+
+	if len(cpus) == 0 {
+		return rv, nil
+	}
 
-		if len(observerFactories) == 1 {
-			go eventStreamProcessor(ctx, rv.chans[i], observerFactories[0]())
-		} else {
-			go eventStreamProcessor(ctx, rv.chans[i], newMultiObserver(observerFactories))
+	var pinErr error
+
+	for range rv.chans {
+		if err := <-pinned; err != nil && pinErr == nil {
+			pinErr = err
 		}
 	}
 
-	return rv
+	if pinErr != nil {
+		rv.Shutdown()
+
+		return EventStream{}, fmt.Errorf("cannot pin event processors: %w", pinErr)
+	}
+
+	return rv, nil
 }
 
-func eventStreamProcessor(ctx context.Context, eventChan <-chan mtglib.Event, observer Observer) { //nolint: cyclop
+func eventStreamProcessor(ctx context.Context, eventChan <-chan streamEvent, observer Observer) { //nolint: cyclop
 	defer observer.Shutdown()
 
+	// Фильтры выбирают события по типу (multiObserver кэширует так же),
+	// поэтому получатель traffic один на всё время жизни процессора.
+	trafficTarget := dispatchTarget(observer, mtglib.EventTraffic{})
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case evt := <-eventChan:
+		case item := <-eventChan:
+			evt := item.evt
+			if evt == nil {
+				if trafficTarget != nil {
+					trafficTarget.EventTraffic(item.traffic)
+				}
+
+				continue
+			}
+
+			target := dispatchTarget(observer, evt)
+			if target == nil {
+				continue
+			}
+
 			switch typedEvt := evt.(type) {
-			case mtglib.EventTraffic:
-				observer.EventTraffic(typedEvt)
 			case mtglib.EventStart:
-				observer.EventStart(typedEvt)
+				target.EventStart(typedEvt)
 			case mtglib.EventFinish:
-				observer.EventFinish(typedEvt)
+				target.EventFinish(typedEvt)
 			case mtglib.EventConnectedToDC:
-				observer.EventConnectedToDC(typedEvt)
+				target.EventConnectedToDC(typedEvt)
 			case mtglib.EventDomainFronting:
-				observer.EventDomainFronting(typedEvt)
+				target.EventDomainFronting(typedEvt)
 			case mtglib.EventIPBlocklisted:
-				observer.EventIPBlocklisted(typedEvt)
+				target.EventIPBlocklisted(typedEvt)
+			case mtglib.EventRateLimited:
+				target.EventRateLimited(typedEvt)
 			case mtglib.EventConcurrencyLimited:
-				observer.EventConcurrencyLimited(typedEvt)
+				target.EventConcurrencyLimited(typedEvt)
 			case mtglib.EventReplayAttack:
-				observer.EventReplayAttack(typedEvt)
+				target.EventReplayAttack(typedEvt)
 			case mtglib.EventIPListSize:
-				observer.EventIPListSize(typedEvt)
+				target.EventIPListSize(typedEvt)
 			case mtglib.EventDNSCacheMetrics:
-				observer.EventDNSCacheMetrics(typedEvt)
+				target.EventDNSCacheMetrics(typedEvt)
 			case mtglib.EventPoolMetrics:
-				observer.EventPoolMetrics(typedEvt)
+				target.EventPoolMetrics(typedEvt)
 			case mtglib.EventRateLimiterMetrics:
-				observer.EventRateLimiterMetrics(typedEvt)
+				target.EventRateLimiterMetrics(typedEvt)
+			case mtglib.EventTelegramHandshakeFailed:
+				target.EventTelegramHandshakeFailed(typedEvt)
+			case mtglib.EventScannerDetected:
+				target.EventScannerDetected(typedEvt)
 			case mtglib.EventIPListCacheFallback:
-				observer.EventIPListCacheFallback(typedEvt)
+				target.EventIPListCacheFallback(typedEvt)
+			case mtglib.EventWorkerPoolPressure:
+				target.EventWorkerPoolPressure(typedEvt)
+			case mtglib.EventClientTimeSkew:
+				target.EventClientTimeSkew(typedEvt)
+			case mtglib.EventDNSQueriesSkipped:
+				target.EventDNSQueriesSkipped(typedEvt)
+			case mtglib.EventDomainFrontingDial:
+				target.EventDomainFrontingDial(typedEvt)
+			case mtglib.EventWorkerQueueWait:
+				target.EventWorkerQueueWait(typedEvt)
+			case mtglib.EventTarpitted:
+				target.EventTarpitted(typedEvt)
+			case mtglib.EventDraining:
+				target.EventDraining(typedEvt)
+			case mtglib.EventDCConfigStale:
+				target.EventDCConfigStale(typedEvt)
+			case mtglib.EventDoHQueriesLimited:
+				target.EventDoHQueriesLimited(typedEvt)
+			case mtglib.EventTelegramDCSkipped:
+				target.EventTelegramDCSkipped(typedEvt)
+			case mtglib.EventTelegramDialMetrics:
+				target.EventTelegramDialMetrics(typedEvt)
+			case mtglib.EventAcceptBackpressure:
+				target.EventAcceptBackpressure(typedEvt)
+			case mtglib.EventTelegramHandshakeRetried:
+				target.EventTelegramHandshakeRetried(typedEvt)
+			case mtglib.EventDomainFrontingLimited:
+				target.EventDomainFrontingLimited(typedEvt)
+			case mtglib.EventRelayStarted:
+				target.EventRelayStarted(typedEvt)
+			case mtglib.EventRelaysLimited:
+				target.EventRelaysLimited(typedEvt)
+			case mtglib.EventConcurrencyMetrics:
+				target.EventConcurrencyMetrics(typedEvt)
 			}
 		}
 	}
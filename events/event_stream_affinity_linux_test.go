@@ -0,0 +1,81 @@
+//go:build linux
+
+package events_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/events"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func allowedCPU(t *testing.T) int {
+	t.Helper()
+
+	var set unix.CPUSet
+
+	require.NoError(t, unix.SchedGetaffinity(0, &set))
+
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set.IsSet(cpu) {
+			return cpu
+		}
+	}
+
+	t.Fatal("no cpus are available")
+
+	return 0
+}
+
+func TestEventStreamWithAffinity(t *testing.T) {
+	cpu := allowedCPU(t)
+	pinned := make(chan unix.CPUSet, 1)
+
+	observer := &ObserverMock{}
+	observer.On("Shutdown")
+	observer.
+		On("EventStart", mock.Anything).
+		Once().
+		Run(func(_ mock.Arguments) {
+			var set unix.CPUSet
+
+			unix.SchedGetaffinity(0, &set) //nolint: errcheck
+
+			pinned <- set
+		})
+
+	stream, err := events.NewEventStreamWithAffinity(
+		[]events.ObserverFactory{func() events.Observer { return observer }},
+		[]int{cpu})
+	require.NoError(t, err)
+
+	defer stream.Shutdown()
+
+	stream.Send(context.Background(), mtglib.NewEventStart("connID", net.ParseIP("10.0.0.1")))
+
+	select {
+	case set := <-pinned:
+		assert.Equal(t, 1, set.Count())
+		assert.True(t, set.IsSet(cpu))
+	case <-time.After(time.Second):
+		t.Fatal("event is not processed")
+	}
+}
+
+func TestEventStreamWithAffinityError(t *testing.T) {
+	observer := &ObserverMock{}
+	observer.On("Shutdown").Maybe()
+
+	// Ядра с таким номером нет: sched_setaffinity вернёт EINVAL.
+	_, err := events.NewEventStreamWithAffinity(
+		[]events.ObserverFactory{func() events.Observer { return observer }},
+		[]int{len(unix.CPUSet{})*64 - 1})
+	require.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package events_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/events"
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+type trafficCountingObserver struct {
+	events.Observer
+
+	count *atomic.Uint64
+}
+
+func (t trafficCountingObserver) EventTraffic(_ mtglib.EventTraffic) {
+	t.count.Add(1)
+}
+
+// BenchmarkTrafficEvents измеряет стоимость одного EventTraffic от
+// relay до observer: N соединений параллельно шлют события в один
+// event stream, op — одно событие. Traffic доставляется блокирующе,
+// иначе бенчмарк мерил бы в основном отбрасывание при переполнении.
+//
+// Send — путь любого отправителя, SendTraffic — путь connTraffic.
+func BenchmarkTrafficEvents(b *testing.B) {
+	senders := map[string]func(events.EventStream, context.Context, mtglib.EventTraffic){
+		"Send": func(stream events.EventStream, ctx context.Context, evt mtglib.EventTraffic) {
+			stream.Send(ctx, evt)
+		},
+		"SendTraffic": events.EventStream.SendTraffic,
+	}
+
+	for _, name := range []string{"Send", "SendTraffic"} {
+		for _, connections := range []int{1, 64, 1024} {
+			b.Run(fmt.Sprintf("%s/connections=%d", name, connections), func(b *testing.B) {
+				benchmarkTrafficEvents(b, connections, senders[name])
+			})
+		}
+	}
+}
+
+func benchmarkTrafficEvents(b *testing.B,
+	connections int,
+	send func(events.EventStream, context.Context, mtglib.EventTraffic),
+) {
+	count := &atomic.Uint64{}
+	stream := events.NewEventStreamWithPriorities([]events.ObserverFactory{
+		func() events.Observer {
+			return trafficCountingObserver{
+				Observer: events.NewNoopObserver(),
+				count:    count,
+			}
+		},
+	}, events.PriorityOverride{
+		Event:    mtglib.EventTraffic{},
+		Priority: events.EventPriorityBlocking,
+	})
+
+	defer stream.Shutdown()
+
+	ctx := context.Background()
+	wg := &sync.WaitGroup{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for conn := range connections {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			streamID := fmt.Sprintf("stream-%d", conn)
+
+			for i := conn; i < b.N; i += connections {
+				send(stream, ctx, mtglib.NewEventTraffic(streamID, 1024, i%2 == 0))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for count.Load() < uint64(b.N) {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"reflect"
+
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+// EventFilter is an optional interface of the Observer. If an observer
+// implements it, the default event stream dispatches only those events
+// which are accepted by the filter. Observers which do not implement this
+// interface get all events.
+type EventFilter interface {
+	// AcceptsEvent reports if an observer is interested in the event.
+	//
+	// Filtering is by event type only: the default event stream asks
+	// once per type and caches the answer, so the result must not depend
+	// on event fields.
+	AcceptsEvent(mtglib.Event) bool
+}
+
+type filteredObserver struct {
+	Observer
+
+	types map[reflect.Type]struct{}
+}
+
+func (f filteredObserver) AcceptsEvent(evt mtglib.Event) bool {
+	_, ok := f.types[reflect.TypeOf(evt)]
+
+	return ok
+}
+
+// FilteredObserver wraps an observer factory so its observers get only
+// events of given types. Types are defined by example values:
+//
+//	events.FilteredObserver(factory,
+//	    mtglib.EventStart{},
+//	    mtglib.EventFinish{},
+//	    mtglib.EventTraffic{})
+//
+// Events of other types are not dispatched to such observers at all, so
+// a lightweight observer does not pay for events it ignores.
+func FilteredObserver(inner ObserverFactory, types ...mtglib.Event) ObserverFactory {
+	typeSet := make(map[reflect.Type]struct{}, len(types))
+
+	for _, v := range types {
+		typeSet[reflect.TypeOf(v)] = struct{}{}
+	}
+
+	return func() Observer {
+		return filteredObserver{
+			Observer: inner(),
+			types:    typeSet,
+		}
+	}
+}
+
+func acceptsEvent(observer Observer, evt mtglib.Event) bool {
+	if filter, ok := observer.(EventFilter); ok {
+		return filter.AcceptsEvent(evt)
+	}
+
+	return true
+}
+
+// dispatchTarget returns an observer which has to receive the event or
+// nil if nobody is interested in it.
+func dispatchTarget(observer Observer, evt mtglib.Event) Observer {
+	if multi, ok := observer.(multiObserver); ok {
+		return multi.forEvent(evt)
+	}
+
+	if acceptsEvent(observer, evt) {
+		return observer
+	}
+
+	return nil
+}
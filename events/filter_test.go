@@ -0,0 +1,81 @@
+package events_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/events"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilteredObserverTestSuite struct {
+	suite.Suite
+
+	ctx          context.Context
+	ctxCancel    context.CancelFunc
+	filteredMock *ObserverMock
+	fullMock     *ObserverMock
+}
+
+func (suite *FilteredObserverTestSuite) SetupTest() {
+	suite.ctx, suite.ctxCancel = context.WithCancel(context.Background())
+
+	suite.filteredMock = &ObserverMock{}
+	suite.fullMock = &ObserverMock{}
+
+	suite.filteredMock.On("Shutdown").Maybe()
+	suite.fullMock.On("Shutdown").Maybe()
+}
+
+func (suite *FilteredObserverTestSuite) TearDownTest() {
+	suite.ctxCancel()
+
+	suite.filteredMock.AssertExpectations(suite.T())
+	suite.fullMock.AssertExpectations(suite.T())
+}
+
+func (suite *FilteredObserverTestSuite) filteredFactory() events.ObserverFactory {
+	return events.FilteredObserver(
+		func() events.Observer { return suite.filteredMock },
+		mtglib.EventStart{},
+		mtglib.EventFinish{})
+}
+
+func (suite *FilteredObserverTestSuite) TestSingleObserver() {
+	stream := events.NewEventStream([]events.ObserverFactory{suite.filteredFactory()})
+	defer stream.Shutdown()
+
+	suite.filteredMock.On("EventStart", mock.Anything).Once()
+
+	// ObserverMock паникует на неожиданный вызов: replay attack не должен
+	// дойти до наблюдателя.
+	stream.Send(suite.ctx, mtglib.NewEventReplayAttack("connID"))
+	stream.Send(suite.ctx, mtglib.NewEventStart("connID", net.ParseIP("10.0.0.1")))
+	time.Sleep(100 * time.Millisecond)
+}
+
+func (suite *FilteredObserverTestSuite) TestMultiObserver() {
+	stream := events.NewEventStream([]events.ObserverFactory{
+		suite.filteredFactory(),
+		func() events.Observer { return suite.fullMock },
+	})
+	defer stream.Shutdown()
+
+	suite.filteredMock.On("EventFinish", mock.Anything).Once()
+	suite.fullMock.On("EventFinish", mock.Anything).Once()
+	suite.fullMock.On("EventReplayAttack", mock.Anything).Twice()
+
+	stream.Send(suite.ctx, mtglib.NewEventReplayAttack("connID"))
+	stream.Send(suite.ctx, mtglib.NewEventFinish("connID"))
+	stream.Send(suite.ctx, mtglib.NewEventReplayAttack("connID"))
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestFilteredObserver(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &FilteredObserverTestSuite{})
+}
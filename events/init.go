@@ -47,6 +47,9 @@ type Observer interface {
 	// mtglib.EventConcurrencyLimited event.
 	EventConcurrencyLimited(mtglib.EventConcurrencyLimited)
 
+	// EventRateLimited reacts on incoming mtglib.EventRateLimited event.
+	EventRateLimited(mtglib.EventRateLimited)
+
 	// EventIPBlocklisted reacts on incoming mtglib.EventIPBlocklisted event.
 	EventIPBlocklisted(mtglib.EventIPBlocklisted)
 
@@ -65,9 +68,80 @@ type Observer interface {
 	// EventRateLimiterMetrics reacts on incoming mtglib.EventRateLimiterMetrics event.
 	EventRateLimiterMetrics(mtglib.EventRateLimiterMetrics)
 
+	// EventTelegramHandshakeFailed reacts on incoming
+	// mtglib.EventTelegramHandshakeFailed event.
+	EventTelegramHandshakeFailed(mtglib.EventTelegramHandshakeFailed)
+
+	// EventScannerDetected reacts on incoming mtglib.EventScannerDetected
+	// event.
+	EventScannerDetected(mtglib.EventScannerDetected)
+
 	// EventIPListCacheFallback reacts on incoming mtglib.EventIPListCacheFallback event.
 	EventIPListCacheFallback(mtglib.EventIPListCacheFallback)
 
+	// EventWorkerPoolPressure reacts on incoming
+	// mtglib.EventWorkerPoolPressure event.
+	EventWorkerPoolPressure(mtglib.EventWorkerPoolPressure)
+
+	// EventClientTimeSkew reacts on incoming mtglib.EventClientTimeSkew
+	// event.
+	EventClientTimeSkew(mtglib.EventClientTimeSkew)
+
+	// EventDNSQueriesSkipped reacts on incoming
+	// mtglib.EventDNSQueriesSkipped event.
+	EventDNSQueriesSkipped(mtglib.EventDNSQueriesSkipped)
+
+	// EventDomainFrontingDial reacts on incoming
+	// mtglib.EventDomainFrontingDial event.
+	EventDomainFrontingDial(mtglib.EventDomainFrontingDial)
+
+	// EventWorkerQueueWait reacts on incoming
+	// mtglib.EventWorkerQueueWait event.
+	EventWorkerQueueWait(mtglib.EventWorkerQueueWait)
+
+	// EventTarpitted reacts on incoming mtglib.EventTarpitted event.
+	EventTarpitted(mtglib.EventTarpitted)
+
+	// EventDraining reacts on incoming mtglib.EventDraining event.
+	EventDraining(mtglib.EventDraining)
+
+	// EventDCConfigStale reacts on incoming mtglib.EventDCConfigStale event.
+	EventDCConfigStale(mtglib.EventDCConfigStale)
+
+	// EventDoHQueriesLimited reacts on incoming mtglib.EventDoHQueriesLimited event.
+	EventDoHQueriesLimited(mtglib.EventDoHQueriesLimited)
+
+	// EventTelegramDCSkipped reacts on incoming mtglib.EventTelegramDCSkipped event.
+	EventTelegramDCSkipped(mtglib.EventTelegramDCSkipped)
+
+	// EventTelegramDialMetrics reacts on incoming
+	// mtglib.EventTelegramDialMetrics event.
+	EventTelegramDialMetrics(mtglib.EventTelegramDialMetrics)
+
+	// EventAcceptBackpressure reacts on incoming
+	// mtglib.EventAcceptBackpressure event.
+	EventAcceptBackpressure(mtglib.EventAcceptBackpressure)
+
+	// EventTelegramHandshakeRetried reacts on incoming
+	// mtglib.EventTelegramHandshakeRetried event.
+	EventTelegramHandshakeRetried(mtglib.EventTelegramHandshakeRetried)
+
+	// EventDomainFrontingLimited reacts on incoming
+	// mtglib.EventDomainFrontingLimited event.
+	EventDomainFrontingLimited(mtglib.EventDomainFrontingLimited)
+
+	// EventRelayStarted reacts on incoming
+	// mtglib.EventRelayStarted event.
+	EventRelayStarted(mtglib.EventRelayStarted)
+
+	// EventRelaysLimited reacts on incoming
+	// mtglib.EventRelaysLimited event.
+	EventRelaysLimited(mtglib.EventRelaysLimited)
+
+	// EventConcurrencyMetrics reacts on incoming
+	// mtglib.EventConcurrencyMetrics event.
+	EventConcurrencyMetrics(mtglib.EventConcurrencyMetrics)
+
 	// Shutdown stop observer. Default event stream guarantees:
 	//   1. If shutdown is executed, it is executed only once
 	//   2. Observer won't receieve any new message after this
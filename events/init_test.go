@@ -33,6 +33,10 @@ func (o *ObserverMock) EventConcurrencyLimited(evt mtglib.EventConcurrencyLimite
 	o.Called(evt)
 }
 
+func (o *ObserverMock) EventRateLimited(evt mtglib.EventRateLimited) {
+	o.Called(evt)
+}
+
 func (o *ObserverMock) EventIPBlocklisted(evt mtglib.EventIPBlocklisted) {
 	o.Called(evt)
 }
@@ -57,10 +61,86 @@ func (o *ObserverMock) EventRateLimiterMetrics(evt mtglib.EventRateLimiterMetric
 	o.Called(evt)
 }
 
+func (o *ObserverMock) EventTelegramHandshakeFailed(evt mtglib.EventTelegramHandshakeFailed) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventScannerDetected(evt mtglib.EventScannerDetected) {
+	o.Called(evt)
+}
+
 func (o *ObserverMock) EventIPListCacheFallback(evt mtglib.EventIPListCacheFallback) {
 	o.Called(evt)
 }
 
+func (o *ObserverMock) EventWorkerPoolPressure(evt mtglib.EventWorkerPoolPressure) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventClientTimeSkew(evt mtglib.EventClientTimeSkew) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDNSQueriesSkipped(evt mtglib.EventDNSQueriesSkipped) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDomainFrontingDial(evt mtglib.EventDomainFrontingDial) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventWorkerQueueWait(evt mtglib.EventWorkerQueueWait) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventTarpitted(evt mtglib.EventTarpitted) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDraining(evt mtglib.EventDraining) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDCConfigStale(evt mtglib.EventDCConfigStale) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDoHQueriesLimited(evt mtglib.EventDoHQueriesLimited) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventTelegramDCSkipped(evt mtglib.EventTelegramDCSkipped) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventTelegramDialMetrics(evt mtglib.EventTelegramDialMetrics) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventAcceptBackpressure(evt mtglib.EventAcceptBackpressure) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventTelegramHandshakeRetried(evt mtglib.EventTelegramHandshakeRetried) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventDomainFrontingLimited(evt mtglib.EventDomainFrontingLimited) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventRelayStarted(evt mtglib.EventRelayStarted) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventRelaysLimited(evt mtglib.EventRelaysLimited) {
+	o.Called(evt)
+}
+
+func (o *ObserverMock) EventConcurrencyMetrics(evt mtglib.EventConcurrencyMetrics) {
+	o.Called(evt)
+}
+
 func (o *ObserverMock) Shutdown() {
 	o.Called()
 }
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"reflect"
 	"sync"
 
 	"github.com/9seconds/mtg/v2/mtglib"
@@ -8,6 +9,45 @@ import (
 
 type multiObserver struct {
 	observers []Observer
+
+	// byType кэширует наблюдателей, которым нужен тип события. Каждая
+	// горутина event stream получает свой multiObserver, так что
+	// синхронизация не нужна.
+	byType map[reflect.Type]Observer
+}
+
+// forEvent возвращает наблюдателя для события: себя, если событие нужно
+// всем, multiObserver из подмножества или nil, если оно никому не нужно.
+func (m multiObserver) forEvent(evt mtglib.Event) Observer {
+	evtType := reflect.TypeOf(evt)
+
+	if target, ok := m.byType[evtType]; ok {
+		return target
+	}
+
+	accepted := make([]Observer, 0, len(m.observers))
+
+	for _, v := range m.observers {
+		if acceptsEvent(v, evt) {
+			accepted = append(accepted, v)
+		}
+	}
+
+	var target Observer
+
+	switch len(accepted) {
+	case 0:
+	case len(m.observers):
+		target = m
+	case 1:
+		target = accepted[0]
+	default:
+		target = multiObserver{observers: accepted}
+	}
+
+	m.byType[evtType] = target
+
+	return target
 }
 
 func (m multiObserver) EventStart(evt mtglib.EventStart) {
@@ -100,6 +140,21 @@ func (m multiObserver) EventConcurrencyLimited(evt mtglib.EventConcurrencyLimite
 	wg.Wait()
 }
 
+func (m multiObserver) EventRateLimited(evt mtglib.EventRateLimited) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventRateLimited(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
 func (m multiObserver) EventIPBlocklisted(evt mtglib.EventIPBlocklisted) {
 	wg := &sync.WaitGroup{}
 	wg.Add(len(m.observers))
@@ -190,6 +245,36 @@ func (m multiObserver) EventRateLimiterMetrics(evt mtglib.EventRateLimiterMetric
 	wg.Wait()
 }
 
+func (m multiObserver) EventTelegramHandshakeFailed(evt mtglib.EventTelegramHandshakeFailed) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventTelegramHandshakeFailed(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventScannerDetected(evt mtglib.EventScannerDetected) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventScannerDetected(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
 func (m multiObserver) EventIPListCacheFallback(evt mtglib.EventIPListCacheFallback) {
 	wg := &sync.WaitGroup{}
 	wg.Add(len(m.observers))
@@ -205,6 +290,261 @@ func (m multiObserver) EventIPListCacheFallback(evt mtglib.EventIPListCacheFallb
 	wg.Wait()
 }
 
+func (m multiObserver) EventWorkerPoolPressure(evt mtglib.EventWorkerPoolPressure) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventWorkerPoolPressure(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventClientTimeSkew(evt mtglib.EventClientTimeSkew) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventClientTimeSkew(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDNSQueriesSkipped(evt mtglib.EventDNSQueriesSkipped) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDNSQueriesSkipped(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventWorkerQueueWait(evt mtglib.EventWorkerQueueWait) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventWorkerQueueWait(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDomainFrontingDial(evt mtglib.EventDomainFrontingDial) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDomainFrontingDial(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventTarpitted(evt mtglib.EventTarpitted) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventTarpitted(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDraining(evt mtglib.EventDraining) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDraining(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDCConfigStale(evt mtglib.EventDCConfigStale) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDCConfigStale(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDoHQueriesLimited(evt mtglib.EventDoHQueriesLimited) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDoHQueriesLimited(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventTelegramDCSkipped(evt mtglib.EventTelegramDCSkipped) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventTelegramDCSkipped(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventTelegramDialMetrics(evt mtglib.EventTelegramDialMetrics) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventTelegramDialMetrics(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventAcceptBackpressure(evt mtglib.EventAcceptBackpressure) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventAcceptBackpressure(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventTelegramHandshakeRetried(evt mtglib.EventTelegramHandshakeRetried) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventTelegramHandshakeRetried(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventDomainFrontingLimited(evt mtglib.EventDomainFrontingLimited) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventDomainFrontingLimited(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventRelayStarted(evt mtglib.EventRelayStarted) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventRelayStarted(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventRelaysLimited(evt mtglib.EventRelaysLimited) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventRelaysLimited(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
+func (m multiObserver) EventConcurrencyMetrics(evt mtglib.EventConcurrencyMetrics) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(m.observers))
+
+	for _, v := range m.observers {
+		go func(obs Observer) {
+			defer wg.Done()
+
+			obs.EventConcurrencyMetrics(evt)
+		}(v)
+	}
+
+	wg.Wait()
+}
+
 func (m multiObserver) Shutdown() {
 	for _, v := range m.observers {
 		v.Shutdown()
@@ -220,5 +560,6 @@ func newMultiObserver(factories []ObserverFactory) Observer {
 
 	return multiObserver{
 		observers: observers,
+		byType:    map[reflect.Type]Observer{},
 	}
 }
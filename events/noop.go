@@ -8,7 +8,8 @@ import (
 
 type noop struct{}
 
-func (n noop) Send(ctx context.Context, evt mtglib.Event) {}
+func (n noop) Send(ctx context.Context, evt mtglib.Event)               {}
+func (n noop) SendTraffic(ctx context.Context, evt mtglib.EventTraffic) {}
 
 // NewNoopStream creates a stream which discards each message.
 func NewNoopStream() mtglib.EventStream {
@@ -17,20 +18,40 @@ func NewNoopStream() mtglib.EventStream {
 
 type noopObserver struct{}
 
-func (n noopObserver) EventStart(_ mtglib.EventStart)                             {}
-func (n noopObserver) EventConnectedToDC(_ mtglib.EventConnectedToDC)             {}
-func (n noopObserver) EventDomainFronting(_ mtglib.EventDomainFronting)           {}
-func (n noopObserver) EventTraffic(_ mtglib.EventTraffic)                         {}
-func (n noopObserver) EventFinish(_ mtglib.EventFinish)                           {}
-func (n noopObserver) EventConcurrencyLimited(_ mtglib.EventConcurrencyLimited)   {}
-func (n noopObserver) EventIPBlocklisted(_ mtglib.EventIPBlocklisted)             {}
-func (n noopObserver) EventReplayAttack(_ mtglib.EventReplayAttack)               {}
-func (n noopObserver) EventIPListSize(_ mtglib.EventIPListSize)                   {}
-func (n noopObserver) EventDNSCacheMetrics(_ mtglib.EventDNSCacheMetrics)         {}
-func (n noopObserver) EventPoolMetrics(_ mtglib.EventPoolMetrics)                       {}
-func (n noopObserver) EventRateLimiterMetrics(_ mtglib.EventRateLimiterMetrics)         {}
-func (n noopObserver) EventIPListCacheFallback(_ mtglib.EventIPListCacheFallback) {}
-func (n noopObserver) Shutdown()                                                  {}
+func (n noopObserver) EventStart(_ mtglib.EventStart)                                       {}
+func (n noopObserver) EventConnectedToDC(_ mtglib.EventConnectedToDC)                       {}
+func (n noopObserver) EventDomainFronting(_ mtglib.EventDomainFronting)                     {}
+func (n noopObserver) EventTraffic(_ mtglib.EventTraffic)                                   {}
+func (n noopObserver) EventFinish(_ mtglib.EventFinish)                                     {}
+func (n noopObserver) EventConcurrencyLimited(_ mtglib.EventConcurrencyLimited)             {}
+func (n noopObserver) EventRateLimited(_ mtglib.EventRateLimited)                           {}
+func (n noopObserver) EventIPBlocklisted(_ mtglib.EventIPBlocklisted)                       {}
+func (n noopObserver) EventReplayAttack(_ mtglib.EventReplayAttack)                         {}
+func (n noopObserver) EventIPListSize(_ mtglib.EventIPListSize)                             {}
+func (n noopObserver) EventDNSCacheMetrics(_ mtglib.EventDNSCacheMetrics)                   {}
+func (n noopObserver) EventPoolMetrics(_ mtglib.EventPoolMetrics)                           {}
+func (n noopObserver) EventRateLimiterMetrics(_ mtglib.EventRateLimiterMetrics)             {}
+func (n noopObserver) EventTelegramHandshakeFailed(_ mtglib.EventTelegramHandshakeFailed)   {}
+func (n noopObserver) EventScannerDetected(_ mtglib.EventScannerDetected)                   {}
+func (n noopObserver) EventIPListCacheFallback(_ mtglib.EventIPListCacheFallback)           {}
+func (n noopObserver) EventWorkerPoolPressure(_ mtglib.EventWorkerPoolPressure)             {}
+func (n noopObserver) EventClientTimeSkew(_ mtglib.EventClientTimeSkew)                     {}
+func (n noopObserver) EventDNSQueriesSkipped(_ mtglib.EventDNSQueriesSkipped)               {}
+func (n noopObserver) EventDomainFrontingDial(_ mtglib.EventDomainFrontingDial)             {}
+func (n noopObserver) EventWorkerQueueWait(_ mtglib.EventWorkerQueueWait)                   {}
+func (n noopObserver) EventTarpitted(_ mtglib.EventTarpitted)                               {}
+func (n noopObserver) EventDraining(_ mtglib.EventDraining)                                 {}
+func (n noopObserver) EventDCConfigStale(_ mtglib.EventDCConfigStale)                       {}
+func (n noopObserver) EventDoHQueriesLimited(_ mtglib.EventDoHQueriesLimited)               {}
+func (n noopObserver) EventTelegramDCSkipped(_ mtglib.EventTelegramDCSkipped)               {}
+func (n noopObserver) EventTelegramDialMetrics(_ mtglib.EventTelegramDialMetrics)           {}
+func (n noopObserver) EventAcceptBackpressure(_ mtglib.EventAcceptBackpressure)             {}
+func (n noopObserver) EventTelegramHandshakeRetried(_ mtglib.EventTelegramHandshakeRetried) {}
+func (n noopObserver) EventDomainFrontingLimited(_ mtglib.EventDomainFrontingLimited)       {}
+func (n noopObserver) EventRelayStarted(_ mtglib.EventRelayStarted)                         {}
+func (n noopObserver) EventRelaysLimited(_ mtglib.EventRelaysLimited)                       {}
+func (n noopObserver) EventConcurrencyMetrics(_ mtglib.EventConcurrencyMetrics)             {}
+func (n noopObserver) Shutdown()                                                            {}
 
 // NewNoopObserver creates an observer which discards each message.
 func NewNoopObserver() Observer {
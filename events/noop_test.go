@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/9seconds/mtg/v2/events"
 	"github.com/9seconds/mtg/v2/mtglib"
@@ -25,10 +26,33 @@ func (suite *NoopTestSuite) SetupSuite() {
 		"traffic":                mtglib.NewEventTraffic("connID", 1000, true),
 		"finish":                 mtglib.NewEventFinish("connID"),
 		"concurrency-limited":    mtglib.NewEventConcurrencyLimited(),
+		"rate-limited":           mtglib.NewEventRateLimited(net.ParseIP("10.0.0.10")),
 		"ip-blacklisted":         mtglib.NewEventIPBlocklisted(net.ParseIP("10.0.0.10")),
 		"replay-attack":          mtglib.NewEventReplayAttack("connID"),
 		"ip-list-size":           mtglib.NewEventIPListSize(10, true),
 		"ip-list-cache-fallback": mtglib.NewEventIPListCacheFallback(true),
+		"telegram-handshake-failed": mtglib.NewEventTelegramHandshakeFailed(
+			"connID", 2, mtglib.HandshakeFailureExhausted),
+		"scanner-detected": mtglib.NewEventScannerDetected(
+			"connID", net.ParseIP("10.0.0.10"), mtglib.ScannerReasonTruncated, false),
+		"worker-pool-pressure": mtglib.NewEventWorkerPoolPressure(95, 100, true),
+		"client-time-skew": mtglib.NewEventClientTimeSkew(
+			"connID", net.ParseIP("10.0.0.10"), 2*time.Second),
+		"dns-queries-skipped":  mtglib.NewEventDNSQueriesSkipped(0, 3),
+		"domain-fronting-dial": mtglib.NewEventDomainFrontingDial("connID", time.Second, true),
+		"worker-queue-wait":    mtglib.NewEventWorkerQueueWait("connID", time.Millisecond),
+		"tarpitted":            mtglib.NewEventTarpitted("connID", net.ParseIP("10.0.0.10")),
+		"draining":             mtglib.NewEventDraining(),
+		"dc-config-stale":      mtglib.NewEventDCConfigStale(3),
+		"doh-queries-limited":  mtglib.NewEventDoHQueriesLimited(5),
+		"telegram-dc-skipped":  mtglib.NewEventTelegramDCSkipped("connID", 2, 4),
+		"telegram-dial":        mtglib.NewEventTelegramDialMetrics(2, 10, 1),
+		"accept-backpressure":  mtglib.NewEventAcceptBackpressure(time.Second),
+		"handshake-retried":    mtglib.NewEventTelegramHandshakeRetried("connID", 2, true),
+		"fronting-limited":     mtglib.NewEventDomainFrontingLimited("connID", net.ParseIP("10.0.0.10")),
+		"relay-started":        mtglib.NewEventRelayStarted("connID", 2, time.Second),
+		"relays-limited":       mtglib.NewEventRelaysLimited("connID", net.ParseIP("10.0.0.10")),
+		"concurrency-metrics":  mtglib.NewEventConcurrencyMetrics(10, 200),
 	}
 	suite.ctx = context.Background()
 }
@@ -63,6 +87,8 @@ func (suite *NoopTestSuite) TestObserver() {
 				observer.EventFinish(typedEvt)
 			case mtglib.EventConcurrencyLimited:
 				observer.EventConcurrencyLimited(typedEvt)
+			case mtglib.EventRateLimited:
+				observer.EventRateLimited(typedEvt)
 			case mtglib.EventIPBlocklisted:
 				observer.EventIPBlocklisted(typedEvt)
 			case mtglib.EventReplayAttack:
@@ -71,6 +97,44 @@ func (suite *NoopTestSuite) TestObserver() {
 				observer.EventIPListSize(typedEvt)
 			case mtglib.EventIPListCacheFallback:
 				observer.EventIPListCacheFallback(typedEvt)
+			case mtglib.EventTelegramHandshakeFailed:
+				observer.EventTelegramHandshakeFailed(typedEvt)
+			case mtglib.EventScannerDetected:
+				observer.EventScannerDetected(typedEvt)
+			case mtglib.EventWorkerPoolPressure:
+				observer.EventWorkerPoolPressure(typedEvt)
+			case mtglib.EventClientTimeSkew:
+				observer.EventClientTimeSkew(typedEvt)
+			case mtglib.EventDNSQueriesSkipped:
+				observer.EventDNSQueriesSkipped(typedEvt)
+			case mtglib.EventDomainFrontingDial:
+				observer.EventDomainFrontingDial(typedEvt)
+			case mtglib.EventWorkerQueueWait:
+				observer.EventWorkerQueueWait(typedEvt)
+			case mtglib.EventTarpitted:
+				observer.EventTarpitted(typedEvt)
+			case mtglib.EventDraining:
+				observer.EventDraining(typedEvt)
+			case mtglib.EventDCConfigStale:
+				observer.EventDCConfigStale(typedEvt)
+			case mtglib.EventDoHQueriesLimited:
+				observer.EventDoHQueriesLimited(typedEvt)
+			case mtglib.EventTelegramDCSkipped:
+				observer.EventTelegramDCSkipped(typedEvt)
+			case mtglib.EventTelegramDialMetrics:
+				observer.EventTelegramDialMetrics(typedEvt)
+			case mtglib.EventAcceptBackpressure:
+				observer.EventAcceptBackpressure(typedEvt)
+			case mtglib.EventTelegramHandshakeRetried:
+				observer.EventTelegramHandshakeRetried(typedEvt)
+			case mtglib.EventDomainFrontingLimited:
+				observer.EventDomainFrontingLimited(typedEvt)
+			case mtglib.EventRelayStarted:
+				observer.EventRelayStarted(typedEvt)
+			case mtglib.EventRelaysLimited:
+				observer.EventRelaysLimited(typedEvt)
+			case mtglib.EventConcurrencyMetrics:
+				observer.EventConcurrencyMetrics(typedEvt)
 			}
 		})
 	}
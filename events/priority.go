@@ -0,0 +1,55 @@
+package events
+
+import (
+	"reflect"
+
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+// EventPriority defines how EventStream delivers an event if a buffer
+// of an observer is full.
+type EventPriority uint8
+
+const (
+	// EventPriorityBlocking means that Send waits until an event is
+	// accepted or a context is done. Such events are never dropped.
+	EventPriorityBlocking EventPriority = iota
+
+	// EventPriorityDroppable means that Send drops an event if a buffer
+	// is full. Dropped events are counted in EventStream.Dropped.
+	EventPriorityDroppable
+)
+
+// PriorityOverride sets a priority for all events of the same type as
+// Event. Only a type of Event matters, so an empty value is enough.
+type PriorityOverride struct {
+	Event    mtglib.Event
+	Priority EventPriority
+}
+
+// DefaultEventPriorities returns events which are droppable by default:
+// high-frequency EventTraffic and periodic snapshots which are sent again
+// in a few seconds anyway. All other events are blocking, so security
+// events and connection lifecycle are never lost. Periodic metrics with
+// deltas (EventDNSCacheMetrics, EventPoolMetrics,
+// EventTelegramDialMetrics) are blocking too: a sender does not resend a
+// delta, so a dropped one would be lost.
+func DefaultEventPriorities() []PriorityOverride {
+	return []PriorityOverride{
+		{Event: mtglib.EventTraffic{}, Priority: EventPriorityDroppable},
+		{Event: mtglib.EventIPListSize{}, Priority: EventPriorityDroppable},
+		{Event: mtglib.EventRateLimiterMetrics{}, Priority: EventPriorityDroppable},
+		{Event: mtglib.EventConcurrencyMetrics{}, Priority: EventPriorityDroppable},
+	}
+}
+
+func makeEventPriorities(overrides []PriorityOverride) map[reflect.Type]EventPriority {
+	defaults := DefaultEventPriorities()
+	priorities := make(map[reflect.Type]EventPriority, len(defaults)+len(overrides))
+
+	for _, v := range append(defaults, overrides...) {
+		priorities[reflect.TypeOf(v.Event)] = v.Priority
+	}
+
+	return priorities
+}
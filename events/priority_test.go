@@ -0,0 +1,152 @@
+package events_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/events"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/stretchr/testify/suite"
+)
+
+// stalledObserver блокируется на первом EventTraffic, пока тест не
+// отпустит его: буферы event stream переполняются.
+type stalledObserver struct {
+	events.Observer
+
+	stalled chan<- struct{}
+	release <-chan struct{}
+}
+
+func (s stalledObserver) EventTraffic(_ mtglib.EventTraffic) {
+	select {
+	case s.stalled <- struct{}{}:
+	default:
+	}
+
+	<-s.release
+}
+
+type EventPriorityTestSuite struct {
+	suite.Suite
+
+	stalled chan struct{}
+	release chan struct{}
+	stream  events.EventStream
+}
+
+func (suite *EventPriorityTestSuite) SetupTest() {
+	suite.stalled = make(chan struct{}, runtime.NumCPU())
+	suite.release = make(chan struct{})
+}
+
+func (suite *EventPriorityTestSuite) TearDownTest() {
+	close(suite.release)
+	suite.stream.Shutdown()
+}
+
+// stall создаёт event stream и забивает буферы всех его каналов.
+func (suite *EventPriorityTestSuite) stall(overrides ...events.PriorityOverride) {
+	suite.stream = events.NewEventStreamWithPriorities([]events.ObserverFactory{
+		func() events.Observer {
+			return stalledObserver{
+				Observer: events.NewNoopObserver(),
+				stalled:  suite.stalled,
+				release:  suite.release,
+			}
+		},
+	}, overrides...)
+
+	// Каналы выбираются по хэшу stream id: с запасом покрываем все.
+	// Второй проход дозаполняет буферы, из которых наблюдатели успели
+	// забрать по событию до блокировки.
+	fill := func() {
+		for i := range 1000 * runtime.NumCPU() {
+			suite.stream.Send(context.Background(),
+				mtglib.NewEventTraffic(strconv.Itoa(i), 1, true))
+		}
+	}
+
+	fill()
+
+	for range runtime.NumCPU() {
+		<-suite.stalled
+	}
+
+	fill()
+
+	suite.NotZero(suite.stream.Dropped())
+}
+
+func (suite *EventPriorityTestSuite) assertDropped(evt mtglib.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	dropped := suite.stream.Dropped()
+	started := time.Now()
+
+	suite.stream.Send(ctx, evt)
+
+	suite.Less(time.Since(started), 500*time.Millisecond)
+	suite.Equal(dropped+1, suite.stream.Dropped())
+}
+
+func (suite *EventPriorityTestSuite) assertBlocked(evt mtglib.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	dropped := suite.stream.Dropped()
+
+	suite.stream.Send(ctx, evt)
+
+	suite.ErrorIs(ctx.Err(), context.DeadlineExceeded)
+	suite.Equal(dropped, suite.stream.Dropped())
+}
+
+func (suite *EventPriorityTestSuite) TestDefaultDroppable() {
+	suite.stall()
+
+	suite.assertDropped(mtglib.NewEventTraffic("connID", 10, false))
+	suite.assertDropped(mtglib.NewEventIPListSize(100, true))
+	suite.assertDropped(mtglib.NewEventRateLimiterMetrics(10))
+	suite.assertDropped(mtglib.NewEventConcurrencyMetrics(10, 200))
+}
+
+func (suite *EventPriorityTestSuite) TestDefaultBlocking() {
+	suite.stall()
+
+	suite.assertBlocked(mtglib.NewEventStart("connID", net.ParseIP("10.0.0.10")))
+	suite.assertBlocked(mtglib.NewEventFinish("connID"))
+	suite.assertBlocked(mtglib.NewEventReplayAttack("connID"))
+	suite.assertBlocked(mtglib.NewEventIPBlocklisted(net.ParseIP("10.0.0.10")))
+	suite.assertBlocked(mtglib.NewEventIPAllowlisted(net.ParseIP("10.0.0.10")))
+	suite.assertBlocked(mtglib.NewEventConcurrencyLimited())
+	suite.assertBlocked(mtglib.NewEventDNSCacheMetrics(1, 2, 3, 4))
+	suite.assertBlocked(mtglib.NewEventPoolMetrics(2, 1, 1, 0, 3))
+	suite.assertBlocked(mtglib.NewEventTelegramDialMetrics(2, 10, 1))
+}
+
+func (suite *EventPriorityTestSuite) TestOverride() {
+	suite.stall(
+		events.PriorityOverride{
+			Event:    mtglib.EventIPBlocklisted{},
+			Priority: events.EventPriorityDroppable,
+		},
+		events.PriorityOverride{
+			Event:    mtglib.EventIPListSize{},
+			Priority: events.EventPriorityBlocking,
+		})
+
+	suite.assertDropped(mtglib.NewEventIPBlocklisted(net.ParseIP("10.0.0.10")))
+	suite.assertBlocked(mtglib.NewEventIPListSize(100, true))
+	suite.assertDropped(mtglib.NewEventTraffic("connID", 10, false))
+}
+
+func TestEventPriority(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &EventPriorityTestSuite{})
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+// RecordingObserver is an observer which keeps all received events in
+// memory. It is intended for tests of applications which embed mtglib:
+// build an event stream with [RecordingObserver.Factory], drive a proxy
+// and assert on recorded events:
+//
+//	recorder := events.NewRecordingObserver()
+//	stream := events.NewEventStream([]events.ObserverFactory{recorder.Factory()})
+//	// ... run a proxy with this stream ...
+//	assert.Eventually(t, func() bool {
+//	    return events.CountOf[mtglib.EventReplayAttack](recorder) == 1
+//	}, time.Second, 10*time.Millisecond)
+//
+// Please remember that the default event stream delivers events
+// asynchronously, so an event may appear a bit later than it was sent.
+//
+// RecordingObserver is safe for concurrent use. Events are stored in an
+// order of arrival; events with different stream ids may be reordered.
+type RecordingObserver struct {
+	mutex  sync.Mutex
+	events []mtglib.Event
+}
+
+func (r *RecordingObserver) record(evt mtglib.Event) {
+	r.mutex.Lock()
+	r.events = append(r.events, evt)
+	r.mutex.Unlock()
+}
+
+// Events returns a copy of all recorded events.
+func (r *RecordingObserver) Events() []mtglib.Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	rv := make([]mtglib.Event, len(r.events))
+	copy(rv, r.events)
+
+	return rv
+}
+
+// Len returns a number of recorded events.
+func (r *RecordingObserver) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.events)
+}
+
+// Reset drops all recorded events.
+func (r *RecordingObserver) Reset() {
+	r.mutex.Lock()
+	r.events = nil
+	r.mutex.Unlock()
+}
+
+// Factory returns an observer factory for the default event stream. All
+// observers it creates are the same RecordingObserver, so events from
+// all event stream goroutines end up in one place.
+func (r *RecordingObserver) Factory() ObserverFactory {
+	return func() Observer {
+		return r
+	}
+}
+
+func (r *RecordingObserver) EventStart(evt mtglib.EventStart)                   { r.record(evt) }
+func (r *RecordingObserver) EventConnectedToDC(evt mtglib.EventConnectedToDC)   { r.record(evt) }
+func (r *RecordingObserver) EventDomainFronting(evt mtglib.EventDomainFronting) { r.record(evt) }
+func (r *RecordingObserver) EventTraffic(evt mtglib.EventTraffic)               { r.record(evt) }
+func (r *RecordingObserver) EventFinish(evt mtglib.EventFinish)                 { r.record(evt) }
+func (r *RecordingObserver) EventConcurrencyLimited(evt mtglib.EventConcurrencyLimited) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventRateLimited(evt mtglib.EventRateLimited)         { r.record(evt) }
+func (r *RecordingObserver) EventIPBlocklisted(evt mtglib.EventIPBlocklisted)     { r.record(evt) }
+func (r *RecordingObserver) EventReplayAttack(evt mtglib.EventReplayAttack)       { r.record(evt) }
+func (r *RecordingObserver) EventIPListSize(evt mtglib.EventIPListSize)           { r.record(evt) }
+func (r *RecordingObserver) EventDNSCacheMetrics(evt mtglib.EventDNSCacheMetrics) { r.record(evt) }
+func (r *RecordingObserver) EventPoolMetrics(evt mtglib.EventPoolMetrics)         { r.record(evt) }
+func (r *RecordingObserver) EventRateLimiterMetrics(evt mtglib.EventRateLimiterMetrics) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventTelegramHandshakeFailed(evt mtglib.EventTelegramHandshakeFailed) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventScannerDetected(evt mtglib.EventScannerDetected) { r.record(evt) }
+func (r *RecordingObserver) EventIPListCacheFallback(evt mtglib.EventIPListCacheFallback) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventWorkerPoolPressure(evt mtglib.EventWorkerPoolPressure) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventClientTimeSkew(evt mtglib.EventClientTimeSkew)       { r.record(evt) }
+func (r *RecordingObserver) EventDNSQueriesSkipped(evt mtglib.EventDNSQueriesSkipped) { r.record(evt) }
+func (r *RecordingObserver) EventWorkerQueueWait(evt mtglib.EventWorkerQueueWait)     { r.record(evt) }
+func (r *RecordingObserver) EventDomainFrontingDial(evt mtglib.EventDomainFrontingDial) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventTarpitted(evt mtglib.EventTarpitted)                 { r.record(evt) }
+func (r *RecordingObserver) EventDraining(evt mtglib.EventDraining)                   { r.record(evt) }
+func (r *RecordingObserver) EventDCConfigStale(evt mtglib.EventDCConfigStale)         { r.record(evt) }
+func (r *RecordingObserver) EventDoHQueriesLimited(evt mtglib.EventDoHQueriesLimited) { r.record(evt) }
+func (r *RecordingObserver) EventTelegramDCSkipped(evt mtglib.EventTelegramDCSkipped) { r.record(evt) }
+func (r *RecordingObserver) EventTelegramDialMetrics(evt mtglib.EventTelegramDialMetrics) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventAcceptBackpressure(evt mtglib.EventAcceptBackpressure) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventTelegramHandshakeRetried(evt mtglib.EventTelegramHandshakeRetried) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventDomainFrontingLimited(evt mtglib.EventDomainFrontingLimited) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventRelayStarted(evt mtglib.EventRelayStarted) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventRelaysLimited(evt mtglib.EventRelaysLimited) {
+	r.record(evt)
+}
+func (r *RecordingObserver) EventConcurrencyMetrics(evt mtglib.EventConcurrencyMetrics) {
+	r.record(evt)
+}
+
+// Shutdown does nothing: recorded events stay available after the event
+// stream is shut down. It may be called many times, once per event stream
+// goroutine.
+func (r *RecordingObserver) Shutdown() {}
+
+// NewRecordingObserver creates a new empty RecordingObserver.
+func NewRecordingObserver() *RecordingObserver {
+	return &RecordingObserver{}
+}
+
+// EventsOf returns recorded events of type T.
+func EventsOf[T mtglib.Event](r *RecordingObserver) []T {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var rv []T
+
+	for _, v := range r.events {
+		if typed, ok := v.(T); ok {
+			rv = append(rv, typed)
+		}
+	}
+
+	return rv
+}
+
+// CountOf returns a number of recorded events of type T.
+func CountOf[T mtglib.Event](r *RecordingObserver) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := 0
+
+	for _, v := range r.events {
+		if _, ok := v.(T); ok {
+			count++
+		}
+	}
+
+	return count
+}
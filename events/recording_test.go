@@ -0,0 +1,82 @@
+package events_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/events"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecordingObserverTestSuite struct {
+	suite.Suite
+
+	recorder *events.RecordingObserver
+}
+
+func (suite *RecordingObserverTestSuite) SetupTest() {
+	suite.recorder = events.NewRecordingObserver()
+}
+
+func (suite *RecordingObserverTestSuite) TestDirectCalls() {
+	suite.recorder.EventStart(mtglib.NewEventStart("connID", net.ParseIP("127.0.0.1")))
+	suite.recorder.EventReplayAttack(mtglib.NewEventReplayAttack("connID"))
+	suite.recorder.EventFinish(mtglib.NewEventFinish("connID"))
+	suite.recorder.Shutdown()
+
+	suite.Equal(3, suite.recorder.Len())
+	suite.Equal(1, events.CountOf[mtglib.EventReplayAttack](suite.recorder))
+	suite.Equal(0, events.CountOf[mtglib.EventTraffic](suite.recorder))
+
+	recorded := suite.recorder.Events()
+	suite.Len(recorded, 3)
+	suite.IsType(mtglib.EventStart{}, recorded[0])
+	suite.IsType(mtglib.EventReplayAttack{}, recorded[1])
+	suite.IsType(mtglib.EventFinish{}, recorded[2])
+
+	// изменения копии не должны влиять на записанные события
+	recorded[0] = nil
+	suite.NotNil(suite.recorder.Events()[0])
+
+	suite.recorder.Reset()
+	suite.Equal(0, suite.recorder.Len())
+	suite.Empty(suite.recorder.Events())
+}
+
+func (suite *RecordingObserverTestSuite) TestEventStream() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := events.NewEventStream([]events.ObserverFactory{suite.recorder.Factory()})
+
+	for _, id := range []string{"conn1", "conn2", "conn3", "conn4"} {
+		stream.Send(ctx, mtglib.NewEventStart(id, net.ParseIP("127.0.0.1")))
+		stream.Send(ctx, mtglib.NewEventTraffic(id, 100, true))
+	}
+
+	stream.Send(ctx, mtglib.NewEventReplayAttack("conn2"))
+
+	suite.Eventually(func() bool {
+		return suite.recorder.Len() == 9
+	}, time.Second, 10*time.Millisecond)
+
+	suite.Equal(4, events.CountOf[mtglib.EventStart](suite.recorder))
+	suite.Equal(4, events.CountOf[mtglib.EventTraffic](suite.recorder))
+
+	attacks := events.EventsOf[mtglib.EventReplayAttack](suite.recorder)
+	suite.Len(attacks, 1)
+	suite.Equal("conn2", attacks[0].StreamID())
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	suite.Equal(9, suite.recorder.Len())
+}
+
+func TestRecordingObserver(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RecordingObserverTestSuite{})
+}
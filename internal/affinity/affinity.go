@@ -0,0 +1,17 @@
+// Package affinity pins goroutines to a set of CPU cores.
+//
+// Go scheduler moves goroutines between OS threads and threads between
+// cores as it wants. For long-lived goroutines on NUMA or isolated-core
+// deployments this means cache misses, so a goroutine can be locked to
+// its OS thread and this thread is restricted to some cores with
+// sched_setaffinity. This works only on Linux; on other platforms Pin
+// does nothing.
+//
+// A process needs a permission to run on the requested cores: they have
+// to be a part of its cpuset (cgroups, taskset, container limits).
+// Otherwise Pin returns an error.
+package affinity
+
+// Unpin restores a CPU mask of the thread and unlocks a goroutine
+// from it.
+type Unpin func()
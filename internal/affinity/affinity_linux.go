@@ -0,0 +1,47 @@
+//go:build linux
+
+package affinity
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Pin locks the calling goroutine to its OS thread and restricts this
+// thread to the given CPU cores. Unpin has to be called from the same
+// goroutine.
+func Pin(cpus []int) (Unpin, error) {
+	runtime.LockOSThread()
+
+	var previous unix.CPUSet
+
+	// pid 0 в sched_getaffinity/sched_setaffinity — вызывающий поток,
+	// а не весь процесс.
+	if err := unix.SchedGetaffinity(0, &previous); err != nil {
+		runtime.UnlockOSThread()
+
+		return nil, fmt.Errorf("cannot get cpu affinity: %w", err)
+	}
+
+	var set unix.CPUSet
+
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+
+		return nil, fmt.Errorf("cannot set cpu affinity to %v: %w", cpus, err)
+	}
+
+	return func() {
+		// Если маску вернуть не удалось, поток остаётся заблокированным:
+		// с чужой маской он не должен попасть обратно к планировщику Go.
+		if err := unix.SchedSetaffinity(0, &previous); err == nil {
+			runtime.UnlockOSThread()
+		}
+	}, nil
+}
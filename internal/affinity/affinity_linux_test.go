@@ -0,0 +1,66 @@
+//go:build linux
+
+package affinity_test
+
+import (
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/affinity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func currentCPU(t *testing.T) int {
+	t.Helper()
+
+	var set unix.CPUSet
+
+	require.NoError(t, unix.SchedGetaffinity(0, &set))
+
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set.IsSet(cpu) {
+			return cpu
+		}
+	}
+
+	t.Fatal("no cpus are available")
+
+	return 0
+}
+
+func TestPin(t *testing.T) {
+	cpu := currentCPU(t)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		var before unix.CPUSet
+
+		require.NoError(t, unix.SchedGetaffinity(0, &before))
+
+		unpin, err := affinity.Pin([]int{cpu})
+		require.NoError(t, err)
+
+		var pinned unix.CPUSet
+
+		require.NoError(t, unix.SchedGetaffinity(0, &pinned))
+		assert.Equal(t, 1, pinned.Count())
+		assert.True(t, pinned.IsSet(cpu))
+
+		unpin()
+
+		var after unix.CPUSet
+
+		require.NoError(t, unix.SchedGetaffinity(0, &after))
+		assert.Equal(t, before, after)
+	}()
+
+	<-done
+}
+
+func TestPinNotAllowed(t *testing.T) {
+	_, err := affinity.Pin([]int{len(unix.CPUSet{})*64 - 1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package affinity
+
+// Pin does nothing: CPU affinity of threads is supported only on Linux.
+func Pin(cpus []int) (Unpin, error) {
+	return func() {}, nil
+}
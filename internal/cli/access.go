@@ -7,14 +7,13 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/9seconds/mtg/v2/essentials"
 	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/9seconds/mtg/v2/internal/sharelinks"
 	"github.com/9seconds/mtg/v2/internal/utils"
 	"github.com/9seconds/mtg/v2/mtglib"
 )
@@ -38,7 +37,7 @@ type accessResponseURLs struct {
 }
 
 type Access struct {
-	ConfigPath string `kong:"arg,required,type='existingfile',help='Path to the configuration file.',name='config-path'"`                 //nolint: lll
+	ConfigPath string `kong:"arg,required,help='Path to the configuration file, - for stdin or http(s) URL.',name='config-path'"`         //nolint: lll
 	PublicIPv4 net.IP `kong:"help='Public IPv4 address for proxy. By default it is resolved via remote website',name='ipv4',short='i'"`   //nolint: lll
 	PublicIPv6 net.IP `kong:"help='Public IPv6 address for proxy. By default it is resolved via remote website',name='ipv6',short='I'"`   //nolint: lll
 	Port       uint   `kong:"help='Port number. Default port is taken from configuration file, bind-to parameter',type:'uint',short='p'"` //nolint: lll
@@ -150,32 +149,18 @@ func (a *Access) makeURLs(conf *config.Config, ip net.IP) *accessResponseURLs {
 		portNo = conf.BindTo.Port
 	}
 
-	values := url.Values{}
-	values.Set("server", ip.String())
-	values.Set("port", strconv.Itoa(int(portNo)))
-
+	secret := conf.Secret.Base64()
 	if a.Hex {
-		values.Set("secret", conf.Secret.Hex())
-	} else {
-		values.Set("secret", conf.Secret.Base64())
+		secret = conf.Secret.Hex()
 	}
 
-	urlQuery := values.Encode()
+	links := sharelinks.MakeLinks(ip.String(), portNo, secret)
 
 	rv := &accessResponseURLs{
-		IP:   ip,
-		Port: portNo,
-		TgURL: (&url.URL{
-			Scheme:   "tg",
-			Host:     "proxy",
-			RawQuery: urlQuery,
-		}).String(),
-		TmeURL: (&url.URL{
-			Scheme:   "https",
-			Host:     "t.me",
-			Path:     "proxy",
-			RawQuery: urlQuery,
-		}).String(),
+		IP:     ip,
+		Port:   portNo,
+		TgURL:  links.TgURL,
+		TmeURL: links.TmeURL,
 	}
 	rv.TgQrCode = utils.MakeQRCodeURL(rv.TgURL)
 	rv.TmeQrCode = utils.MakeQRCodeURL(rv.TmeURL)
@@ -8,5 +8,8 @@ type CLI struct {
 	Run            Run              `kong:"cmd,help='Run proxy.'"`
 	SimpleRun      SimpleRun        `kong:"cmd,help='Run proxy without config file.'"`
 	Health         Health           `kong:"cmd,help='Check proxy health via metrics endpoint.'"`
+	Validate       Validate         `kong:"cmd,help='Validate configuration without running proxy.'"`
+	ConfigDump     ConfigDump       `kong:"cmd,help='Print effective proxy settings with defaults applied.'"`
+	IPListCheck    IPListCheck      `kong:"cmd,name='iplist-check',help='Check if allowlist and blocklist let an IP address in.'"`
 	Version        kong.VersionFlag `kong:"help='Print version.',short='v'"`
 }
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/9seconds/mtg/v2/internal/utils"
+	"github.com/9seconds/mtg/v2/logger"
+	"github.com/9seconds/mtg/v2/mtglib"
+)
+
+// ConfigDump печатает эффективные mtglib.ProxyOpts и mtglib.ProxyConfig
+// в JSON: с подставленными дефолтами, которые NewProxy применяет к
+// пустым настройкам. Секрет замаскирован.
+type ConfigDump struct {
+	ConfigPath string `kong:"arg,required,help='Path to the configuration file, - for stdin or http(s) URL.',name='config-path'"` //nolint: lll
+}
+
+func (c *ConfigDump) Run(cli *CLI, version string) error {
+	conf, err := utils.ReadConfig(c.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("cannot init config: %w", err)
+	}
+
+	opts := makeProxyOpts(conf, logger.NewNoopLogger()).Effective()
+
+	return printConfigDump(os.Stdout, opts)
+}
+
+// configDumpDuration сериализуется строкой вида 1m30s: так дамп читают
+// люди, а не парсеры.
+type configDumpDuration time.Duration
+
+func (d configDumpDuration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+type configDumpProxyConfig struct {
+	HandshakeTimeout       configDumpDuration `json:"handshake-timeout"`
+	ClientHelloTimeout     configDumpDuration `json:"client-hello-timeout"`
+	TelegramDialTimeout    configDumpDuration `json:"telegram-dial-timeout"`
+	TCPUserTimeout         configDumpDuration `json:"tcp-user-timeout"`
+	ClientTCPUserTimeout   configDumpDuration `json:"client-tcp-user-timeout"`
+	TelegramTCPUserTimeout configDumpDuration `json:"telegram-tcp-user-timeout"`
+	TelegramWindowClamp    int                `json:"telegram-window-clamp"`
+	RelayBufferSize        int                `json:"relay-buffer-size"`
+	RelayIOTimeout         configDumpDuration `json:"relay-io-timeout"`
+	TCPQuickACKInterval    int                `json:"tcp-quickack-interval"`
+	CPUAffinity            []int              `json:"cpu-affinity"`
+	SilentRejectWindow     configDumpDuration `json:"silent-reject-window"`
+	DrainTimeout           configDumpDuration `json:"drain-timeout"`
+	DCFailureTTL           configDumpDuration `json:"dc-failure-ttl"`
+	MaxDialAddresses       int                `json:"max-dial-addresses"`
+}
+
+type configDumpProxyOpts struct {
+	Secret                        string                        `json:"secret"`
+	SecretHost                    string                        `json:"secret-host"`
+	Concurrency                   uint                          `json:"concurrency"`
+	AcceptBackpressureThreshold   uint                          `json:"accept-backpressure-threshold"`
+	MaxRelays                     uint                          `json:"max-relays"`
+	TolerateTimeSkewness          configDumpDuration            `json:"tolerate-time-skewness"`
+	TolerateTimeSkewnessOverrides map[string]configDumpDuration `json:"tolerate-time-skewness-overrides"`
+	FakeTLSMaxRecordSize          uint                          `json:"faketls-max-record-size"`
+	FakeTLSSmallFirstRecords      uint                          `json:"faketls-small-first-records"`
+	PreferIP                      string                        `json:"prefer-ip"`
+	DomainFrontingPort            uint                          `json:"domain-fronting-port"`
+	DomainFrontingMaxConnections  uint                          `json:"domain-fronting-max-connections"`
+	AllowFallbackOnUnknownDC      bool                          `json:"allow-fallback-on-unknown-dc"`
+	UnknownDCMapping              map[int]int                   `json:"unknown-dc-mapping"`
+	FallbackOnDialError           bool                          `json:"fallback-on-dial-error"`
+	UseTestDCs                    bool                          `json:"use-test-dcs"`
+	ProbeDCsOnStartup             bool                          `json:"probe-dcs-on-startup"`
+	TelegramHealthCheckInterval   configDumpDuration            `json:"telegram-health-check-interval"`
+	TelegramHealthThreshold       uint                          `json:"telegram-health-failure-threshold"`
+	WarmUpTFOOnStartup            bool                          `json:"warm-up-tfo-on-startup"`
+	RejectScanners                bool                          `json:"reject-scanners"`
+	TarpitDuration                configDumpDuration            `json:"tarpit-duration"`
+	RateLimitPerSecond            float64                       `json:"rate-limit-per-second"`
+	RateLimitBurst                int                           `json:"rate-limit-burst"`
+	SkewRateLimitPerSecond        float64                       `json:"skew-rate-limit-per-second"`
+	SkewRateLimitBurst            int                           `json:"skew-rate-limit-burst"`
+	DCConfigFile                  string                        `json:"dc-config-file"`
+	DCConfigURL                   string                        `json:"dc-config-url"`
+	DCRefreshInterval             configDumpDuration            `json:"dc-refresh-interval"`
+	EnableConnectionPool          bool                          `json:"enable-connection-pool"`
+	ConnectionPoolMaxIdle         int                           `json:"connection-pool-max-idle"`
+	ConnectionPoolIdleTimeout     configDumpDuration            `json:"connection-pool-idle-timeout"`
+	ConnectionPoolIdleDrainAfter  configDumpDuration            `json:"connection-pool-idle-drain-after"`
+	Config                        configDumpProxyConfig         `json:"config"`
+}
+
+func printConfigDump(writer io.Writer, opts mtglib.ProxyOpts) error {
+	config := opts.Config
+
+	dump := configDumpProxyOpts{
+		Secret:                        "***",
+		SecretHost:                    opts.Secret.Host,
+		Concurrency:                   opts.Concurrency,
+		AcceptBackpressureThreshold:   opts.AcceptBackpressureThreshold,
+		MaxRelays:                     opts.MaxRelays,
+		TolerateTimeSkewness:          configDumpDuration(opts.TolerateTimeSkewness),
+		TolerateTimeSkewnessOverrides: map[string]configDumpDuration{},
+		FakeTLSMaxRecordSize:          opts.FakeTLSMaxRecordSize,
+		FakeTLSSmallFirstRecords:      opts.FakeTLSSmallFirstRecords,
+		PreferIP:                      opts.PreferIP,
+		DomainFrontingPort:            opts.DomainFrontingPort,
+		DomainFrontingMaxConnections:  opts.DomainFrontingMaxConnections,
+		AllowFallbackOnUnknownDC:      opts.AllowFallbackOnUnknownDC,
+		UnknownDCMapping:              opts.UnknownDCMapping,
+		FallbackOnDialError:           opts.FallbackOnDialError,
+		UseTestDCs:                    opts.UseTestDCs,
+		ProbeDCsOnStartup:             opts.ProbeDCsOnStartup,
+		TelegramHealthCheckInterval:   configDumpDuration(opts.TelegramHealthCheckInterval),
+		TelegramHealthThreshold:       opts.TelegramHealthFailureThreshold,
+		WarmUpTFOOnStartup:            opts.WarmUpTFOOnStartup,
+		RejectScanners:                opts.RejectScanners,
+		TarpitDuration:                configDumpDuration(opts.TarpitDuration),
+		RateLimitPerSecond:            opts.RateLimitPerSecond,
+		RateLimitBurst:                opts.RateLimitBurst,
+		SkewRateLimitPerSecond:        opts.SkewRateLimitPerSecond,
+		SkewRateLimitBurst:            opts.SkewRateLimitBurst,
+		DCConfigFile:                  opts.DCConfigFile,
+		DCConfigURL:                   opts.DCConfigURL,
+		DCRefreshInterval:             configDumpDuration(opts.DCRefreshInterval),
+		EnableConnectionPool:          opts.EnableConnectionPool,
+		ConnectionPoolMaxIdle:         opts.ConnectionPoolMaxIdle,
+		ConnectionPoolIdleTimeout:     configDumpDuration(opts.ConnectionPoolIdleTimeout),
+		ConnectionPoolIdleDrainAfter:  configDumpDuration(opts.ConnectionPoolIdleDrainAfter),
+		Config: configDumpProxyConfig{
+			HandshakeTimeout:       configDumpDuration(config.HandshakeTimeout),
+			ClientHelloTimeout:     configDumpDuration(config.ClientHelloTimeout),
+			TelegramDialTimeout:    configDumpDuration(config.TelegramDialTimeout),
+			TCPUserTimeout:         configDumpDuration(config.TCPUserTimeout),
+			ClientTCPUserTimeout:   configDumpDuration(config.ClientTCPUserTimeout),
+			TelegramTCPUserTimeout: configDumpDuration(config.TelegramTCPUserTimeout),
+			TelegramWindowClamp:    config.TelegramWindowClamp,
+			RelayBufferSize:        config.RelayBufferSize,
+			RelayIOTimeout:         configDumpDuration(config.RelayIOTimeout),
+			TCPQuickACKInterval:    config.TCPQuickACKInterval,
+			CPUAffinity:            config.CPUAffinity,
+			SilentRejectWindow:     configDumpDuration(config.SilentRejectWindow),
+			DrainTimeout:           configDumpDuration(config.DrainTimeout),
+			DCFailureTTL:           configDumpDuration(config.DCFailureTTL),
+			MaxDialAddresses:       config.MaxDialAddresses,
+		},
+	}
+
+	for _, override := range opts.TolerateTimeSkewnessOverrides {
+		dump.TolerateTimeSkewnessOverrides[override.Network.String()] = configDumpDuration(override.Tolerance)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(dump); err != nil {
+		return fmt.Errorf("cannot dump config: %w", err)
+	}
+
+	return nil
+}
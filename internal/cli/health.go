@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/9seconds/mtg/v2/internal/utils"
+	"github.com/9seconds/mtg/v2/stats"
 )
 
 // healthCheckTimeout — максимальное время ожидания ответа от metrics endpoint.
@@ -20,10 +22,21 @@ const healthCheckTimeout = 5 * time.Second
 //
 // Алгоритм:
 // 1. Парсит конфиг для определения адреса Prometheus metrics
-// 2. Если Prometheus не включён — fallback на TCP connect к proxy порту
-// 3. HTTP GET /metrics — ожидает 200 OK
+// 2. Если Prometheus не включён или без bind-to — TCP connect к proxy порту
+// 3. HTTP GET http-path — ожидает 200 OK
+//
+// Если включена проверка telegram-health, вместо метрик запрашивается
+// stats.HealthPath: он отвечает 503, когда ни один DC не доступен.
+// Без Prometheus результат этой проверки узнать неоткуда, и остаётся
+// только TCP connect.
+//
+// Хост берётся из bind-to. Loopback используется только для
+// 0.0.0.0/:: (слушаем на всех интерфейсах) — иначе endpoint на другом
+// интерфейсе или в другом netns был бы недоступен. --address
+// переопределяет хост целиком.
 type Health struct {
-	ConfigPath string `kong:"arg,required,type='existingfile',help='Path to config file.',name='config-path'"` //nolint: lll
+	ConfigPath string `kong:"arg,required,help='Path to config file, - for stdin or http(s) URL.',name='config-path'"`                //nolint: lll
+	Address    string `kong:"help='Host to connect to. By default it is taken from bind-to, loopback for 0.0.0.0 and ::.',short='a'"` //nolint: lll
 }
 
 func (h Health) Run(cli *CLI, version string) error {
@@ -32,26 +45,29 @@ func (h Health) Run(cli *CLI, version string) error {
 		return fmt.Errorf("cannot parse config: %w", err)
 	}
 
-	// Проверяем Prometheus metrics endpoint (предпочтительно)
-	if conf.Stats.Prometheus.Enabled.Get(false) {
-		bindTo := conf.Stats.Prometheus.BindTo.Value
-		httpPath := conf.Stats.Prometheus.HTTPPath.Value
+	// Проверяем Prometheus metrics endpoint (предпочтительно). Без
+	// bind-to метрики отдаются только через порт прокси под токеном,
+	// отдельного endpoint нет — проверяем порт прокси.
+	if bindTo := conf.Stats.Prometheus.BindTo.Get(""); conf.Stats.Prometheus.Enabled.Get(false) && bindTo != "" {
+		// Путь по умолчанию тот же, что у сервера метрик в run_proxy.
+		httpPath := conf.Stats.Prometheus.HTTPPath.Get("/")
 
-		if bindTo == "" {
-			bindTo = "0.0.0.0:9401"
+		if conf.TelegramHealth.CheckInterval.Get(0) > 0 {
+			httpPath = stats.HealthPath
 		}
 
-		if httpPath == "" {
-			httpPath = "/metrics"
+		// Для unix socket хост в URL не важен: соединение всегда идёт в
+		// сокет, --address игнорируется.
+		if conf.Stats.Prometheus.BindTo.IsUnix() {
+			return checkHTTPUnix(conf.Stats.Prometheus.BindTo.Address, "http://localhost"+httpPath)
 		}
 
-		// Для healthcheck всегда подключаемся к localhost
-		_, port, _ := net.SplitHostPort(bindTo)
-		if port == "" {
-			port = "9401"
+		host, port, err := net.SplitHostPort(bindTo)
+		if err != nil {
+			return fmt.Errorf("incorrect prometheus bind address %s: %w", bindTo, err)
 		}
 
-		url := fmt.Sprintf("http://127.0.0.1:%s%s", port, httpPath)
+		url := fmt.Sprintf("http://%s%s", net.JoinHostPort(h.healthHost(host), port), httpPath)
 
 		return checkHTTP(url)
 	}
@@ -62,15 +78,54 @@ func (h Health) Run(cli *CLI, version string) error {
 		return fmt.Errorf("prometheus not enabled and no bind address configured")
 	}
 
-	return checkTCP(bindTo)
+	host, port, err := net.SplitHostPort(bindTo)
+	if err != nil {
+		return fmt.Errorf("incorrect bind address %s: %w", bindTo, err)
+	}
+
+	return checkTCP(net.JoinHostPort(h.healthHost(host), port))
+}
+
+// healthHost возвращает хост, к которому подключается health check.
+func (h Health) healthHost(bindHost string) string {
+	if h.Address != "" {
+		return h.Address
+	}
+
+	ip := net.ParseIP(bindHost)
+
+	switch {
+	case bindHost == "", ip != nil && ip.Equal(net.IPv4zero):
+		return "127.0.0.1"
+	case ip != nil && ip.Equal(net.IPv6unspecified):
+		return "::1"
+	}
+
+	return bindHost
 }
 
 // checkHTTP проверяет HTTP endpoint — ожидает 200 OK.
 func checkHTTP(url string) error {
-	client := &http.Client{
+	return checkHTTPWithClient(&http.Client{
 		Timeout: healthCheckTimeout,
-	}
+	}, url)
+}
+
+// checkHTTPUnix проверяет HTTP endpoint, который слушает unix socket.
+func checkHTTPUnix(socketPath, url string) error {
+	dialer := net.Dialer{}
+
+	return checkHTTPWithClient(&http.Client{
+		Timeout: healthCheckTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}, url)
+}
 
+func checkHTTPWithClient(client *http.Client, url string) error {
 	resp, err := client.Get(url) //nolint: noctx
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"text/tabwriter"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/9seconds/mtg/v2/internal/utils"
+	"github.com/9seconds/mtg/v2/logger"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/rs/zerolog"
+)
+
+type ipListCheckMatch struct {
+	Network string `json:"network"`
+	Source  string `json:"source"`
+}
+
+type ipListCheckList struct {
+	Enabled bool               `json:"enabled"`
+	Matches []ipListCheckMatch `json:"matches"`
+}
+
+type ipListCheckResponse struct {
+	IP        net.IP          `json:"ip"`
+	Allowed   bool            `json:"allowed"`
+	Reason    string          `json:"reason,omitempty"`
+	Allowlist ipListCheckList `json:"allowlist"`
+	Blocklist ipListCheckList `json:"blocklist"`
+}
+
+// IPListCheck загружает allowlist и blocklist из конфига и сообщает,
+// пропустит ли прокси IP и какие записи каких списков сработали.
+// Списки проверяются в том же порядке, что и в прокси: сначала
+// allowlist, потом blocklist.
+//
+// Ошибки загрузки списков печатаются в stderr: без них непойманный IP
+// выглядел бы как настоящий промах.
+type IPListCheck struct {
+	ConfigPath string `kong:"arg,required,help='Path to the configuration file, - for stdin or http(s) URL.',name='config-path'"` //nolint: lll
+	IP         net.IP `kong:"arg,required,help='IP address to check.',name='ip'"`
+	JSON       bool   `kong:"help='Print a result as JSON.',short='j'"`
+}
+
+func (i *IPListCheck) Run(cli *CLI, version string) error {
+	conf, err := utils.ReadConfig(i.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("cannot init config: %w", err)
+	}
+
+	ntw, err := makeNetwork(conf, version)
+	if err != nil {
+		return fmt.Errorf("cannot init network: %w", err)
+	}
+
+	log := logger.NewZeroLogger(zerolog.New(os.Stderr).Level(zerolog.WarnLevel))
+
+	resp := &ipListCheckResponse{
+		IP: i.IP,
+	}
+
+	if resp.Allowlist, err = checkIPList(conf.Defense.Allowlist, log.Named("allowlist"), ntw, i.IP); err != nil {
+		return fmt.Errorf("cannot check allowlist: %w", err)
+	}
+
+	if resp.Blocklist, err = checkIPList(conf.Defense.Blocklist, log.Named("blocklist"), ntw, i.IP); err != nil {
+		return fmt.Errorf("cannot check blocklist: %w", err)
+	}
+
+	switch {
+	case resp.Allowlist.Enabled && len(resp.Allowlist.Matches) == 0:
+		resp.Reason = "allowlist"
+	case len(resp.Blocklist.Matches) > 0:
+		resp.Reason = "blocklist"
+	default:
+		resp.Allowed = true
+	}
+
+	if i.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(resp) //nolint: wrapcheck
+	}
+
+	return printIPListCheck(os.Stdout, resp)
+}
+
+// checkIPList синхронно загружает список и ищет в нём IP. Выключенный
+// список ни с чем не совпадает.
+func checkIPList(conf config.ListConfig,
+	log mtglib.Logger,
+	ntw mtglib.Network,
+	ip net.IP,
+) (ipListCheckList, error) {
+	result := ipListCheckList{
+		Enabled: conf.Enabled.Get(false),
+		Matches: []ipListCheckMatch{},
+	}
+
+	if !result.Enabled {
+		return result, nil
+	}
+
+	list, err := makeFirehol(conf, log, ntw, nil)
+	if err != nil {
+		return result, err
+	}
+
+	defer list.Shutdown()
+
+	list.Update()
+
+	matches, err := list.Match(ip)
+	if err != nil {
+		return result, fmt.Errorf("cannot match ip: %w", err)
+	}
+
+	for _, v := range matches {
+		result.Matches = append(result.Matches, ipListCheckMatch{
+			Network: v.Network.String(),
+			Source:  v.Source,
+		})
+	}
+
+	return result, nil
+}
+
+func printIPListCheck(writer io.Writer, resp *ipListCheckResponse) error {
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0) //nolint: gomnd
+
+	row := func(name string, value any) {
+		fmt.Fprintf(tw, "%s\t%v\n", name, value) //nolint: errcheck
+	}
+
+	list := func(name string, value ipListCheckList) {
+		switch {
+		case !value.Enabled:
+			row(name, "disabled")
+		case len(value.Matches) == 0:
+			row(name, "no match")
+		}
+
+		for _, v := range value.Matches {
+			row(name, v.Network+" ("+v.Source+")")
+		}
+	}
+
+	row("ip", resp.IP)
+	list("allowlist", resp.Allowlist)
+	list("blocklist", resp.Blocklist)
+
+	if resp.Allowed {
+		row("result", "allowed")
+	} else {
+		row("result", "rejected by "+resp.Reason)
+	}
+
+	return tw.Flush() //nolint: wrapcheck
+}
@@ -7,14 +7,24 @@ import (
 )
 
 type Run struct {
-	ConfigPath string `kong:"arg,required,type='existingfile',help='Path to the configuration file.',name='config-path'"` //nolint: lll
+	ConfigPath string `kong:"arg,required,help='Path to the configuration file, - for stdin or http(s) URL.',name='config-path'"` //nolint: lll
+
+	PIDFile  string `kong:"name='pid-file',help='Write PID to this file and share a listen port with a next instance (SO_REUSEPORT).'"` //nolint: lll
+	Takeover bool   `kong:"name='takeover',help='Take a listen port over from an instance in pid-file: it drains and exits.'"`          //nolint: lll
 }
 
 func (r *Run) Run(cli *CLI, version string) error {
+	if r.Takeover && r.PIDFile == "" {
+		return fmt.Errorf("--takeover requires --pid-file")
+	}
+
 	conf, err := utils.ReadConfig(r.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("cannot init config: %w", err)
 	}
 
-	return runProxy(conf, version)
+	return runProxy(conf, version, rollingRestart{
+		PIDFile:  r.PIDFile,
+		Takeover: r.Takeover,
+	})
 }
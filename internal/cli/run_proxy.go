@@ -2,15 +2,21 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/9seconds/mtg/v2/antireplay"
 	"github.com/9seconds/mtg/v2/events"
 	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/9seconds/mtg/v2/internal/sharelinks"
 	"github.com/9seconds/mtg/v2/internal/utils"
 	"github.com/9seconds/mtg/v2/ipblocklist"
 	"github.com/9seconds/mtg/v2/ipblocklist/files"
@@ -39,45 +45,140 @@ func makeLogger(conf *config.Config) mtglib.Logger {
 }
 
 func makeNetwork(conf *config.Config, version string) (mtglib.Network, error) {
+	return makeNetworkWithProxies(conf, version, conf.Network.Proxies)
+}
+
+// makeTelegramNetwork возвращает сеть для подключения к Telegram DC.
+// nil означает, что используется общая сеть (с теми же прокси).
+func makeTelegramNetwork(conf *config.Config, version string) (mtglib.Network, error) {
+	if !conf.Network.TelegramBypassProxies.Get(false) || len(conf.Network.Proxies) == 0 {
+		return nil, nil //nolint: nilnil
+	}
+
+	return makeNetworkWithProxies(conf, version, nil)
+}
+
+// makeUserAgents возвращает User-Agent для исходящих HTTP запросов:
+// набор браузерных при ротации, иначе заданный в конфиге или mtg/<version>.
+func makeUserAgents(conf *config.Config, version string) []string {
+	switch {
+	case conf.Network.UserAgentRotation.Get(false):
+		return network.BrowserUserAgents()
+	case conf.Network.UserAgent != "":
+		return []string{conf.Network.UserAgent}
+	}
+
+	return []string{"mtg/" + version}
+}
+
+func makeNetworkWithProxies(conf *config.Config,
+	version string,
+	proxies []config.TypeProxyURL,
+) (mtglib.Network, error) {
 	tcpTimeout := conf.Network.Timeout.TCP.Get(network.DefaultTimeout)
-	httpTimeout := conf.Network.Timeout.HTTP.Get(network.DefaultHTTPTimeout)
-	dohIP := conf.Network.DOHIP.Get(net.ParseIP(network.DefaultDOHHostname)).String()
-	userAgent := "mtg/" + version
-	usePlainDNS := conf.Network.DNSMode.Get(config.DNSModeDoH) == config.DNSModePlain
 	enableTFO := conf.Network.TCPFastOpen.Get(false)
 
+	// Лимит DoH запросов в network не включается сам: mtg явно
+	// передаёт значение из конфига или DefaultDoHMaxInFlight.
+	networkOpts := network.Options{
+		UserAgents:     makeUserAgents(conf, version),
+		DoHHostname:    conf.Network.DOHIP.Get(net.ParseIP(network.DefaultDOHHostname)).String(),
+		HTTPTimeout:    conf.Network.Timeout.HTTP.Get(network.DefaultHTTPTimeout),
+		UsePlainDNS:    conf.Network.DNSMode.Get(config.DNSModeDoH) == config.DNSModePlain,
+		QueryType:      conf.Network.DNSQueryType.Get(network.DNSQueryTypeBoth),
+		DoHMaxInFlight: int(conf.Network.DoHMaxInFlight.Get(network.DefaultDoHMaxInFlight)),
+	}
+
 	baseDialer, err := network.NewDefaultDialerWithTFO(tcpTimeout, 0, enableTFO)
 	if err != nil {
 		return nil, fmt.Errorf("cannot build a default dialer: %w", err)
 	}
 
-	if len(conf.Network.Proxies) == 0 {
-		return network.NewNetworkWithDNSMode(baseDialer, userAgent, dohIP, httpTimeout, usePlainDNS) //nolint: wrapcheck
-	}
-
-	proxyURLs := make([]*url.URL, 0, len(conf.Network.Proxies))
+	proxyURLs := make([]*url.URL, 0, len(proxies))
 
-	for _, v := range conf.Network.Proxies {
+	for _, v := range proxies {
 		if value := v.Get(nil); value != nil {
 			proxyURLs = append(proxyURLs, value)
 		}
 	}
 
-	if len(proxyURLs) == 1 {
-		socksDialer, err := network.NewSocks5Dialer(baseDialer, proxyURLs[0])
-		if err != nil {
-			return nil, fmt.Errorf("cannot build socks5 dialer: %w", err)
-		}
-
-		return network.NewNetworkWithDNSMode(socksDialer, userAgent, dohIP, httpTimeout, usePlainDNS) //nolint: wrapcheck
+	if len(proxyURLs) == 0 {
+		return network.NewNetworkWithOptions(baseDialer, networkOpts) //nolint: wrapcheck
 	}
 
-	socksDialer, err := network.NewLoadBalancedSocks5Dialer(baseDialer, proxyURLs)
+	// Даже единственный прокси оборачивается в load balanced dialer:
+	// так cooldown применяется ко всем подключениям через прокси,
+	// включая подключения к Telegram DC.
+	socksDialer, err := network.NewLoadBalancedSocks5DialerWithStrategy(baseDialer, proxyURLs,
+		conf.Network.ProxySelection.Get(network.ProxySelectionRandom))
 	if err != nil {
 		return nil, fmt.Errorf("cannot build socks5 dialer: %w", err)
 	}
 
-	return network.NewNetworkWithDNSMode(socksDialer, userAgent, dohIP, httpTimeout, usePlainDNS) //nolint: wrapcheck
+	return network.NewNetworkWithOptions(socksDialer, networkOpts) //nolint: wrapcheck
+}
+
+// tfoWarmUpEnabled сообщает, имеет ли смысл прогрев TFO cookies: TFO
+// должен быть включён и в конфиге, и в ядре, а к DC нужно подключаться
+// напрямую. Иначе прогреваются cookies upstream прокси, а не DC.
+func tfoWarmUpEnabled(conf *config.Config, logger mtglib.Logger) bool {
+	if !conf.Network.TCPFastOpenWarmUp.Get(false) {
+		return false
+	}
+
+	switch {
+	case !conf.Network.TCPFastOpen.Get(false):
+		logger.Warning("network.tcp-fast-open-warmup is ignored because network.tcp-fast-open is disabled")
+	case !network.IsTFOClientEnabled():
+		logger.Warning("network.tcp-fast-open-warmup is ignored because TFO client mode is disabled in the kernel")
+	case len(conf.Network.Proxies) > 0 && !conf.Network.TelegramBypassProxies.Get(false):
+		logger.Warning("network.tcp-fast-open-warmup is ignored because Telegram is dialed through proxies")
+	default:
+		return true
+	}
+
+	return false
+}
+
+func makeUnknownDCMapping(conf *config.Config) map[int]int {
+	if len(conf.UnknownDCMapping) == 0 {
+		return nil
+	}
+
+	mapping := make(map[int]int, len(conf.UnknownDCMapping))
+
+	for from, to := range conf.UnknownDCMapping {
+		// Номера DC уже проверены в Config.Validate.
+		if dc, err := strconv.Atoi(from); err == nil {
+			mapping[dc] = to
+		}
+	}
+
+	return mapping
+}
+
+func makeTimeSkewnessOverrides(conf *config.Config) []mtglib.TimeSkewnessOverride {
+	overrides := make([]mtglib.TimeSkewnessOverride, 0, len(conf.TolerateTimeSkewnessOverrides))
+
+	for value, tolerance := range conf.TolerateTimeSkewnessOverrides {
+		// Сети уже проверены в Config.Validate.
+		if _, ipNet, err := net.ParseCIDR(value); err == nil {
+			overrides = append(overrides, mtglib.TimeSkewnessOverride{
+				Network:   ipNet,
+				Tolerance: tolerance.Value,
+			})
+		}
+	}
+
+	return overrides
+}
+
+func makeTarpitDuration(conf *config.Config) time.Duration {
+	if !conf.Defense.Tarpit.Enabled.Get(false) {
+		return 0
+	}
+
+	return conf.Defense.Tarpit.Duration.Get(mtglib.DefaultTarpitDuration)
 }
 
 func makeAntiReplayCache(conf *config.Config) mtglib.AntiReplayCache {
@@ -85,10 +186,16 @@ func makeAntiReplayCache(conf *config.Config) mtglib.AntiReplayCache {
 		return antireplay.NewNoop()
 	}
 
-	return antireplay.NewStableBloomFilter(
+	filter := antireplay.NewStableBloomFilter(
 		conf.Defense.AntiReplay.MaxSize.Get(antireplay.DefaultStableBloomFilterMaxSize),
 		conf.Defense.AntiReplay.ErrorRate.Get(antireplay.DefaultStableBloomFilterErrorRate),
 	)
+
+	if replica, ok := filter.(antireplay.ReplicaFilter); ok && conf.Defense.AntiReplay.Peer.ReadOnly.Get(false) {
+		return antireplay.NewReadReplica(replica)
+	}
+
+	return filter
 }
 
 func makeIPBlocklist(conf config.ListConfig,
@@ -101,6 +208,26 @@ func makeIPBlocklist(conf config.ListConfig,
 		return ipblocklist.NewNoop(), nil
 	}
 
+	blocklist, err := makeFirehol(conf, logger, ntw, updateCallback)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheFallbackCallback != nil {
+		blocklist.SetCacheFallbackCallback(cacheFallbackCallback)
+	}
+
+	go blocklist.Run(conf.UpdateEach.Get(ipblocklist.DefaultFireholUpdateEach))
+
+	return blocklist, nil
+}
+
+// makeFirehol создаёт список из URL конфига, но не загружает его.
+func makeFirehol(conf config.ListConfig,
+	logger mtglib.Logger,
+	ntw mtglib.Network,
+	updateCallback ipblocklist.FireholUpdateCallback,
+) (*ipblocklist.Firehol, error) {
 	remoteURLs := []string{}
 	localFiles := []string{}
 
@@ -122,12 +249,6 @@ func makeIPBlocklist(conf config.ListConfig,
 		return nil, fmt.Errorf("incorrect parameters for firehol: %w", err)
 	}
 
-	if cacheFallbackCallback != nil {
-		blocklist.SetCacheFallbackCallback(cacheFallbackCallback)
-	}
-
-	go blocklist.Run(conf.UpdateEach.Get(ipblocklist.DefaultFireholUpdateEach))
-
 	return blocklist, nil
 }
 
@@ -173,7 +294,12 @@ func makeIPAllowlist(conf config.ListConfig,
 	return allowlist, nil
 }
 
-func makeEventStream(conf *config.Config, logger mtglib.Logger, version string) (mtglib.EventStream, error) {
+// makeEventStream также возвращает prometheus factory, если она включена:
+// её handler нужен прокси для метрик на своём порту.
+func makeEventStream(conf *config.Config,
+	logger mtglib.Logger,
+	version string,
+) (mtglib.EventStream, *stats.PrometheusFactory, error) {
 	factories := make([]events.ObserverFactory, 0, 2) //nolint: gomnd
 
 	if conf.Stats.StatsD.Enabled.Get(false) {
@@ -183,34 +309,215 @@ func makeEventStream(conf *config.Config, logger mtglib.Logger, version string)
 			conf.Stats.StatsD.MetricPrefix.Get(stats.DefaultStatsdMetricPrefix),
 			conf.Stats.StatsD.TagFormat.Get(stats.DefaultStatsdTagFormat))
 		if err != nil {
-			return nil, fmt.Errorf("cannot build statsd observer: %w", err)
+			return nil, nil, fmt.Errorf("cannot build statsd observer: %w", err)
 		}
 
 		factories = append(factories, statsdFactory.Make)
 	}
 
+	var prometheus *stats.PrometheusFactory
+
 	if conf.Stats.Prometheus.Enabled.Get(false) {
-		prometheus := stats.NewPrometheus(
+		var opts []stats.PrometheusOption
+
+		if !conf.Stats.Prometheus.DisableRuntimeMetrics.Get(false) {
+			opts = append(opts, stats.WithRuntimeMetrics())
+		}
+
+		prometheus = stats.NewPrometheus(
 			conf.Stats.Prometheus.MetricPrefix.Get(stats.DefaultMetricPrefix),
 			conf.Stats.Prometheus.HTTPPath.Get("/"),
 			version,
+			opts...,
 		)
+		prometheus.SetReplayAttackSource(
+			conf.Stats.Prometheus.ReplayAttackSource.Get(stats.ReplayAttackSourceOff))
+		prometheus.SetFrontedSNI(
+			conf.Stats.Prometheus.FrontedSNI.Get(stats.FrontedSNIHashed))
+
+		// Без bind-to метрики доступны только через порт прокси.
+		if bindTo := conf.Stats.Prometheus.BindTo; bindTo.Get("") != "" {
+			listener, err := listenHTTP(bindTo)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot start a listener for prometheus: %w", err)
+			}
 
-		listener, err := net.Listen("tcp", conf.Stats.Prometheus.BindTo.Get(""))
-		if err != nil {
-			return nil, fmt.Errorf("cannot start a listener for prometheus: %w", err)
+			go prometheus.Serve(listener) //nolint: errcheck
 		}
 
-		go prometheus.Serve(listener) //nolint: errcheck
-
 		factories = append(factories, prometheus.Make)
 	}
 
 	if len(factories) > 0 {
-		return events.NewEventStream(factories), nil
+		return makePinnedEventStream(conf, logger, factories), prometheus, nil
 	}
 
-	return events.NewNoopStream(), nil
+	return events.NewNoopStream(), nil, nil
+}
+
+// makePinnedEventStream привязывает обработчики событий к cpu-affinity.
+// Если привязать не удалось, работаем без неё, как и accept loop.
+func makePinnedEventStream(conf *config.Config,
+	logger mtglib.Logger,
+	factories []events.ObserverFactory,
+) mtglib.EventStream {
+	if len(conf.CPUAffinity) == 0 {
+		return events.NewEventStream(factories)
+	}
+
+	cpus := make([]int, 0, len(conf.CPUAffinity))
+	for _, cpu := range conf.CPUAffinity {
+		cpus = append(cpus, int(cpu))
+	}
+
+	eventStream, err := events.NewEventStreamWithAffinity(factories, cpus)
+	if err != nil {
+		logger.WarningError("cannot pin event processors to cpus", err)
+
+		return events.NewEventStream(factories)
+	}
+
+	return eventStream
+}
+
+// listenHTTP открывает listener для metrics endpoint или снимков
+// anti-replay фильтра. Для unix socket сначала удаляется оставшийся от прошлого запуска файл: иначе bind
+// вернёт EADDRINUSE. Удаляется только сокет, обычный файл по этому пути
+// трогать не будем.
+func listenHTTP(bindTo config.TypeListenAddress) (net.Listener, error) {
+	if bindTo.IsUnix() {
+		if stat, err := os.Lstat(bindTo.Address); err == nil && stat.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(bindTo.Address); err != nil {
+				return nil, fmt.Errorf("cannot remove stale socket %s: %w", bindTo.Address, err)
+			}
+		}
+	}
+
+	return net.Listen(bindTo.Network, bindTo.Address) //nolint: wrapcheck
+}
+
+// warmUpAntiReplayCache загружает снимок фильтра соседнего инстанса.
+// Ошибка не мешает старту: фильтр просто остаётся пустым.
+func warmUpAntiReplayCache(conf *config.Config, cache mtglib.AntiReplayCache, logger mtglib.Logger) {
+	peerURL := conf.Defense.AntiReplay.Peer.WarmUpFrom.Get("")
+	saver, ok := cache.(antireplay.StateSaver)
+
+	if peerURL == "" || !ok {
+		return
+	}
+
+	timeout := conf.Network.Timeout.HTTP.Get(network.DefaultHTTPTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Соседи обычно в той же приватной сети, поэтому без network.proxies.
+	client := &http.Client{Timeout: timeout}
+
+	err := antireplay.WarmUpFromPeer(ctx, client, peerURL, conf.Defense.AntiReplay.Peer.Token, saver)
+	if err != nil {
+		logger.BindStr("peer", peerURL).WarningError("cannot warm up anti-replay cache", err)
+
+		return
+	}
+
+	logger.BindStr("peer", peerURL).Info("anti-replay cache is warmed up")
+}
+
+// syncAntiReplayCache периодически обновляет снимок фильтра read replica,
+// пока не отменён ctx. При ошибке остаётся предыдущий снимок.
+func syncAntiReplayCache(ctx context.Context, conf *config.Config, cache mtglib.AntiReplayCache, logger mtglib.Logger) {
+	peerURL := conf.Defense.AntiReplay.Peer.WarmUpFrom.Get("")
+	saver, ok := cache.(antireplay.StateSaver)
+
+	if !conf.Defense.AntiReplay.Peer.ReadOnly.Get(false) || peerURL == "" || !ok {
+		return
+	}
+
+	client := &http.Client{Timeout: conf.Network.Timeout.HTTP.Get(network.DefaultHTTPTimeout)}
+	logger = logger.BindStr("peer", peerURL)
+
+	go antireplay.SyncFromPeer(ctx, client, peerURL, conf.Defense.AntiReplay.Peer.Token, saver,
+		conf.Defense.AntiReplay.Peer.SyncInterval.Get(antireplay.DefaultReplicaSyncInterval),
+		func(err error) {
+			if err != nil {
+				logger.WarningError("cannot sync anti-replay cache", err)
+			}
+		})
+}
+
+// serveAntiReplayState отдаёт снимки фильтра другим инстансам. Возвращает
+// nil, если это выключено.
+func serveAntiReplayState(conf *config.Config, cache mtglib.AntiReplayCache) (*http.Server, error) {
+	bindTo := conf.Defense.AntiReplay.Peer.BindTo
+	saver, ok := cache.(antireplay.StateSaver)
+
+	if bindTo.Get("") == "" || !ok {
+		return nil, nil //nolint: nilnil
+	}
+
+	listener, err := listenHTTP(bindTo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start a listener for anti-replay state: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", antireplay.NewStateHandler(saver, conf.Defense.AntiReplay.Peer.Token))
+
+	// Отладка ложных срабатываний: известен ли фильтру session id.
+	if checker, ok := cache.(mtglib.AntiReplayChecker); ok {
+		mux.Handle("/contains", antireplay.NewContainsHandler(checker, conf.Defense.AntiReplay.Peer.Token))
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second, //nolint: gomnd
+		WriteTimeout:      30 * time.Second, //nolint: gomnd
+	}
+
+	go server.Serve(listener) //nolint: errcheck
+
+	return server, nil
+}
+
+// serveShareLinks отдаёт ссылки на прокси для клиентов. Возвращает nil,
+// если это выключено.
+func serveShareLinks(conf *config.Config) (*http.Server, error) {
+	bindTo := conf.ShareLinks.BindTo
+	if bindTo.Get("") == "" {
+		return nil, nil //nolint: nilnil
+	}
+
+	listener, err := listenHTTP(bindTo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start a listener for share links: %w", err)
+	}
+
+	links := sharelinks.MakeLinks(conf.ShareLinks.PublicHost,
+		conf.ShareLinks.PublicPort.Get(conf.BindTo.Port), conf.Secret.Base64())
+
+	server := &http.Server{
+		Handler:           sharelinks.NewHandler(links, conf.ShareLinks.Token),
+		ReadHeaderTimeout: 10 * time.Second, //nolint: gomnd
+		WriteTimeout:      30 * time.Second, //nolint: gomnd
+	}
+
+	go server.Serve(listener) //nolint: errcheck
+
+	return server, nil
+}
+
+// makeInBandMetrics возвращает настройки метрик на порту прокси или nil,
+// если они выключены.
+func makeInBandMetrics(conf *config.Config, prometheus *stats.PrometheusFactory) *mtglib.InBandMetrics {
+	if prometheus == nil || !conf.Stats.Prometheus.ServeOnProxyPort.Get(false) {
+		return nil
+	}
+
+	return &mtglib.InBandMetrics{
+		Path:    conf.Stats.Prometheus.HTTPPath.Get("/"),
+		Token:   conf.Stats.Prometheus.ProxyPortToken,
+		Handler: prometheus.Handler(),
+	}
 }
 
 // getDCConfigFile возвращает путь к файлу DC-адресов,
@@ -223,12 +530,172 @@ func getDCConfigFile(conf *config.Config) string {
 	return ""
 }
 
-func runProxy(conf *config.Config, version string) error { //nolint: funlen
+// getDCConfigURL возвращает URL с DC-адресами, если dc-config секция
+// включена и url указан.
+func getDCConfigURL(conf *config.Config) string {
+	if conf.DCConfig.Enabled.Get(false) {
+		return conf.DCConfig.URL.Get("")
+	}
+
+	return ""
+}
+
+// makeProxyConfig собирает mtglib.ProxyConfig из конфига.
+func makeProxyConfig(conf *config.Config) mtglib.ProxyConfig {
+	proxyConfig := mtglib.DefaultProxyConfig()
+	proxyConfig.TCPUserTimeout = conf.Network.TCPUserTimeout.Get(mtglib.DefaultTCPUserTimeout)
+	proxyConfig.ClientTCPUserTimeout = conf.Network.ClientTCPUserTimeout.Get(0)
+	proxyConfig.TelegramTCPUserTimeout = conf.Network.TelegramTCPUserTimeout.Get(0)
+	proxyConfig.TelegramWindowClamp = int(conf.Network.TCPWindowClamp.Get(mtglib.DefaultTelegramWindowClamp))
+	proxyConfig.RelayBufferSize = int(conf.RelayBufferSize.Get(mtglib.DefaultRelayBufferSize))
+	proxyConfig.RelayIOTimeout = conf.Network.RelayIOTimeout.Get(0)
+	proxyConfig.SilentRejectWindow = conf.Defense.SilentRejectWindow.Get(0)
+	proxyConfig.DrainTimeout = conf.Network.Timeout.Drain.Get(mtglib.DefaultDrainTimeout)
+	proxyConfig.ClientHelloTimeout = conf.Network.Timeout.ClientHello.Get(0)
+	proxyConfig.DCFailureTTL = conf.DCFailureTTL.Get(mtglib.DefaultDCFailureTTL)
+	proxyConfig.MaxDialAddresses = int(conf.MaxDialAddresses.Get(0))
+
+	for _, cpu := range conf.CPUAffinity {
+		proxyConfig.CPUAffinity = append(proxyConfig.CPUAffinity, int(cpu))
+	}
+
+	return proxyConfig
+}
+
+// makeProxyOpts заполняет mtglib.ProxyOpts настройками из конфига.
+// Сеть, кэши, списки и event stream создаются в runProxy: у них есть
+// побочные эффекты, а config-dump они не нужны.
+func makeProxyOpts(conf *config.Config, logger mtglib.Logger) mtglib.ProxyOpts {
+	proxyConfig := makeProxyConfig(conf)
+
+	return mtglib.ProxyOpts{
+		AntiReplayKey: mtglib.NewAntiReplayKeySessionIP(conf.Defense.AntiReplay.SameClientWindow.Get(0)),
+
+		Secret:             conf.Secret,
+		DomainFrontingPort: conf.DomainFrontingPort.Get(mtglib.DefaultDomainFrontingPort),
+		PreferIP:           conf.PreferIP.Get(mtglib.DefaultPreferIP),
+
+		DomainFrontingMaxConnections: conf.DomainFrontingMaxConnections.Get(mtglib.DefaultDomainFrontingMaxConnections),
+
+		Concurrency:                 conf.Concurrency.Get(mtglib.DefaultConcurrency),
+		AcceptBackpressureThreshold: conf.AcceptBackpressureThreshold.Get(0),
+		MaxRelays:                   conf.MaxRelays.Get(0),
+
+		AllowFallbackOnUnknownDC: conf.AllowFallbackOnUnknownDC.Get(false),
+		UnknownDCMapping:         makeUnknownDCMapping(conf),
+		FallbackOnDialError:      conf.FallbackOnDialError.Get(true), // default: true for reliability
+		ProbeDCsOnStartup:        conf.ProbeDCsOnStartup.Get(false),
+
+		TelegramHealthCheckInterval: conf.TelegramHealth.CheckInterval.Get(0),
+		TelegramHealthFailureThreshold: conf.TelegramHealth.FailureThreshold.Get(
+			mtglib.DefaultTelegramHealthFailureThreshold),
+
+		WarmUpTFOOnStartup:       tfoWarmUpEnabled(conf, logger),
+		RejectScanners:           conf.Defense.RejectScanners.Enabled.Get(false),
+		TarpitDuration:           makeTarpitDuration(conf),
+		TolerateTimeSkewness:     conf.TolerateTimeSkewness.Value,
+		FakeTLSMaxRecordSize:     conf.AntiFingerprint.MaxRecordSize.Get(mtglib.DefaultFakeTLSMaxRecordSize),
+		FakeTLSSmallFirstRecords: conf.AntiFingerprint.SmallFirstRecords.Get(0),
+
+		// FakeTLS: отдельная tolerate-time-skewness для сетей клиентов
+		TolerateTimeSkewnessOverrides: makeTimeSkewnessOverrides(conf),
+
+		// Connection Pool settings
+		EnableConnectionPool:         conf.ConnectionPool.Enabled.Get(false),
+		ConnectionPoolMaxIdle:        int(conf.ConnectionPool.MaxIdleConns.Get(mtglib.DefaultConnectionPoolMaxIdle)),
+		ConnectionPoolIdleTimeout:    conf.ConnectionPool.IdleTimeout.Value,
+		ConnectionPoolIdleDrainAfter: conf.ConnectionPool.IdleDrainAfter.Get(0),
+
+		// DC Config: авто-обновление адресов из файла или по HTTP
+		DCConfigFile:      getDCConfigFile(conf),
+		DCConfigURL:       getDCConfigURL(conf),
+		DCRefreshInterval: conf.DCConfig.RefreshInterval.Value,
+
+		Config: &proxyConfig,
+
+		// Rate Limit settings
+		RateLimitPerSecond: float64(conf.RateLimit.PerSecond.Get(0)),
+		RateLimitBurst:     int(conf.RateLimit.Burst.Get(mtglib.DefaultRateLimitBurst)),
+
+		// A5: CCS padding удалён — RFC 8446 violation, создаёт DPI fingerprint.
+	}
+}
+
+// rollingRestart — настройки перезапуска без простоя. Новый процесс
+// встаёт на тот же порт через SO_REUSEPORT и по PID из файла просит
+// старый (SIGUSR2) перейти в drain и завершиться.
+type rollingRestart struct {
+	PIDFile  string
+	Takeover bool
+}
+
+// takeOver просит процесс из PID файла уступить порт и записывает туда
+// свой PID. Если файла нет, это обычный старт. Возвращённый Closer надо
+// закрыть при выходе, без PID файла он nil.
+func (r rollingRestart) takeOver(logger mtglib.Logger) (io.Closer, error) {
+	if r.PIDFile == "" {
+		return nil, nil
+	}
+
+	if r.Takeover {
+		if err := r.signalPrevious(logger); err != nil {
+			return nil, fmt.Errorf("cannot take over: %w", err)
+		}
+	}
+
+	pidFile, err := utils.WritePIDFile(r.PIDFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write pid file: %w", err)
+	}
+
+	return pidFile, nil
+}
+
+// signalPrevious отправляет SIGUSR2 процессу из PID файла. PID из файла
+// без блокировки остался после краша: его мог занять чужой процесс, для
+// которого SIGUSR2 смертелен, поэтому такой файл пропускается.
+func (r rollingRestart) signalPrevious(logger mtglib.Logger) error {
+	pid, err := utils.ReadPIDFile(r.PIDFile)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		logger.Info("pid file does not exist, nothing to take over")
+
+		return nil
+	case err != nil:
+		return err //nolint: wrapcheck
+	case pid == os.Getpid():
+		return nil
+	}
+
+	logger = logger.BindInt("pid", pid)
+
+	locked, err := utils.PIDFileLocked(r.PIDFile)
+	if err != nil {
+		return err //nolint: wrapcheck
+	}
+
+	if !locked {
+		logger.Warning("pid file is stale, previous instance is not running")
+
+		return nil
+	}
+
+	if err := utils.SignalTakeover(pid); err != nil {
+		return err //nolint: wrapcheck
+	}
+
+	logger.Info("previous instance is asked to drain and exit")
+
+	return nil
+}
+
+func runProxy(conf *config.Config, version string, restart rollingRestart) error { //nolint: funlen
 	logger := makeLogger(conf)
 
 	logger.BindJSON("configuration", conf.String()).Debug("configuration")
 
-	eventStream, err := makeEventStream(conf, logger, version)
+	eventStream, prometheus, err := makeEventStream(conf, logger, version)
 	if err != nil {
 		return fmt.Errorf("cannot build event stream: %w", err)
 	}
@@ -238,6 +705,11 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 		return fmt.Errorf("cannot build network: %w", err)
 	}
 
+	telegramNtw, err := makeTelegramNetwork(conf, version)
+	if err != nil {
+		return fmt.Errorf("cannot build telegram network: %w", err)
+	}
+
 	blocklist, err := makeIPBlocklist(
 		conf.Defense.Blocklist,
 		logger.Named("blocklist"),
@@ -267,46 +739,45 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 		return fmt.Errorf("cannot build ip allowlist: %w", err)
 	}
 
-	opts := mtglib.ProxyOpts{
-		Logger:          logger,
-		Network:         ntw,
-		AntiReplayCache: makeAntiReplayCache(conf),
-		IPBlocklist:     blocklist,
-		IPAllowlist:     allowlist,
-		EventStream:     eventStream,
-
-		Secret:             conf.Secret,
-		DomainFrontingPort: conf.DomainFrontingPort.Get(mtglib.DefaultDomainFrontingPort),
-		PreferIP:           conf.PreferIP.Get(mtglib.DefaultPreferIP),
-
-		AllowFallbackOnUnknownDC: conf.AllowFallbackOnUnknownDC.Get(false),
-		FallbackOnDialError:      conf.FallbackOnDialError.Get(true), // default: true for reliability
-		TolerateTimeSkewness:     conf.TolerateTimeSkewness.Value,
+	antiReplayCache := makeAntiReplayCache(conf)
+	warmUpAntiReplayCache(conf, antiReplayCache, logger.Named("anti-replay"))
 
-		// Connection Pool settings
-		EnableConnectionPool:      conf.ConnectionPool.Enabled.Get(false),
-		ConnectionPoolMaxIdle:     int(conf.ConnectionPool.MaxIdleConns.Get(5)),
-		ConnectionPoolIdleTimeout: conf.ConnectionPool.IdleTimeout.Value,
+	antiReplayServer, err := serveAntiReplayState(conf, antiReplayCache)
+	if err != nil {
+		return fmt.Errorf("cannot serve anti-replay state: %w", err)
+	}
 
-		// DC Config: авто-обновление адресов из файла
-		DCConfigFile:      getDCConfigFile(conf),
-		DCRefreshInterval: conf.DCConfig.RefreshInterval.Value,
+	shareLinksServer, err := serveShareLinks(conf)
+	if err != nil {
+		return fmt.Errorf("cannot serve share links: %w", err)
+	}
 
-		// Rate Limit settings
-		RateLimitPerSecond: float64(conf.RateLimit.PerSecond.Get(0)),
-		RateLimitBurst:     int(conf.RateLimit.Burst.Get(20)),
+	opts := makeProxyOpts(conf, logger)
+	opts.Logger = logger
+	opts.Network = ntw
+	opts.TelegramNetwork = telegramNtw
+	opts.AntiReplayCache = antiReplayCache
+	opts.IPBlocklist = blocklist
+	opts.IPAllowlist = allowlist
+	opts.EventStream = eventStream
 
-		// A5: CCS padding удалён — RFC 8446 violation, создаёт DPI fingerprint.
-	}
+	// Prometheus: метрики через порт прокси по bearer token
+	opts.InBandMetrics = makeInBandMetrics(conf, prometheus)
 
 	proxy, err := mtglib.NewProxy(opts)
 	if err != nil {
 		return fmt.Errorf("cannot create a proxy: %w", err)
 	}
 
+	if prometheus != nil {
+		prometheus.SetHealthCheck(proxy.TelegramReachable)
+	}
+
 	// Создаём listener с опциональной поддержкой TCP Fast Open
 	enableTFO := conf.Network.TCPFastOpen.Get(false)
-	listener, err := utils.NewListenerWithTFO(conf.BindTo.Get(""), 0, enableTFO)
+	listenBacklog := int(conf.Network.ListenBacklog.Get(0))
+	listener, err := utils.NewListenerWithBacklog(conf.BindTo.Get(""), 0, enableTFO, listenBacklog,
+		restart.PIDFile != "")
 	if err != nil {
 		return fmt.Errorf("cannot start proxy: %w", err)
 	}
@@ -320,13 +791,20 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 
 	ctx := utils.RootContext()
 
+	syncAntiReplayCache(ctx, conf, antiReplayCache, logger.Named("anti-replay"))
+
 	// Start DNS cache metrics updater if Prometheus is enabled
 	if conf.Stats.Prometheus.Enabled.Get(false) {
 		go func() {
 			ticker := time.NewTicker(10 * time.Second)
 			defer ticker.Stop()
 
-			var lastHits, lastMisses, lastEvictions uint64
+			var lastHits, lastMisses, lastEvictions, lastSkippedA, lastSkippedAAAA, lastLimited uint64
+
+			type dialStats struct {
+				successes, failures uint64
+			}
+			lastDials := make(map[int]dialStats)
 
 			for {
 				select {
@@ -347,9 +825,41 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 					lastMisses = misses
 					lastEvictions = evictions
 
+					// Пропущенные из-за network.dns-query-type запросы
+					skippedA, skippedAAAA := ntw.GetDNSSkippedQueries()
+					eventStream.Send(ctx, mtglib.NewEventDNSQueriesSkipped(
+						skippedA-lastSkippedA, skippedAAAA-lastSkippedAAAA))
+
+					lastSkippedA = skippedA
+					lastSkippedAAAA = skippedAAAA
+
+					// DoH запросы, упёршиеся в network.doh-max-in-flight
+					limited := ntw.GetDoHLimitedQueries()
+					eventStream.Send(ctx, mtglib.NewEventDoHQueriesLimited(limited-lastLimited))
+
+					lastLimited = limited
+
 					// Rate limiter map size — раннее обнаружение DDoS
 					rlSize := proxy.GetRateLimiterSize()
 					eventStream.Send(ctx, mtglib.NewEventRateLimiterMetrics(rlSize))
+
+					// Занятые воркеры и активные relay: с max-relays это
+					// разные нагрузки
+					eventStream.Send(ctx, mtglib.NewEventConcurrencyMetrics(
+						proxy.ActiveWorkers(), proxy.ActiveRelays()))
+
+					// Исходы подключений к DC, включая ошибки, скрытые пулом
+					for _, ds := range proxy.GetDialStats() {
+						last := lastDials[ds.DC]
+
+						eventStream.Send(ctx, mtglib.NewEventTelegramDialMetrics(
+							ds.DC, ds.Successes-last.successes, ds.Failures-last.failures))
+
+						lastDials[ds.DC] = dialStats{
+							successes: ds.Successes,
+							failures:  ds.Failures,
+						}
+					}
 				}
 			}
 		}()
@@ -406,6 +916,37 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 		serveDone <- proxy.Serve(listener)
 	}()
 
+	// Порт уже слушается нами, так что старый процесс можно отпускать:
+	// соединения дальше пойдут только сюда.
+	pidFile, err := restart.takeOver(logger)
+	if err != nil {
+		listener.Close()
+		proxy.Shutdown()
+
+		return err
+	}
+
+	if pidFile != nil {
+		defer pidFile.Close() //nolint: errcheck
+	}
+
+	// SIGUSR1: перестаём принимать соединения, но обслуживаем текущие
+	// до SIGTERM. Serve при этом возвращает nil.
+	// SIGUSR2: порт забрал новый процесс. Тоже drain, но потом сразу
+	// завершаемся: Shutdown ждёт соединения до network.timeout.drain.
+	takeoverCtx := utils.TakeoverContext()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-utils.DrainContext().Done():
+			proxy.Drain()
+		case <-takeoverCtx.Done():
+			logger.Info("listen port is taken over by a new instance")
+			proxy.Drain()
+		}
+	}()
+
 	select {
 	case <-ctx.Done():
 		// Graceful shutdown по сигналу
@@ -413,11 +954,30 @@ func runProxy(conf *config.Config, version string) error { //nolint: funlen
 		if err != nil {
 			logger.BindStr("error", err.Error()).Warning("proxy.Serve exited unexpectedly")
 		}
+
+		if err == nil && proxy.Draining() && takeoverCtx.Err() == nil {
+			logger.Info("proxy is drained, waiting for a shutdown signal")
+			<-ctx.Done()
+		}
 	}
 
 	listener.Close()
 	proxy.Shutdown()
 
+	// Shutdown http-сервера закрывает и listener метрик, unix socket при
+	// этом удаляется.
+	if prometheus != nil {
+		prometheus.Close() //nolint: errcheck
+	}
+
+	if antiReplayServer != nil {
+		antiReplayServer.Close() //nolint: errcheck
+	}
+
+	if shareLinksServer != nil {
+		shareLinksServer.Close() //nolint: errcheck
+	}
+
 	// Останавливаем network (DNS cache cleanup, resolver) для предотвращения утечки горутин
 	ntw.Stop()
 
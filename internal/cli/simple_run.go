@@ -11,7 +11,7 @@ import (
 
 type SimpleRun struct {
 	BindTo string `kong:"arg,required,name='bind-to',help='A host:port to bind proxy to.'"`
-	Secret string `kong:"arg,required,name='secret',help='Proxy secret.'"`
+	Secret string `kong:"arg,required,name='secret',help='Proxy secret. file:///path or env:NAME reads it from a file or an environment variable.'"`
 
 	Debug               bool          `kong:"name='debug',short='d',help='Run in debug mode.'"`                                                                        //nolint: lll
 	Concurrency         uint64        `kong:"name='concurrency',short='c',default='8192',help='Max number of concurrent connection to proxy.'"`                        //nolint: lll
@@ -31,7 +31,12 @@ func (s *SimpleRun) Run(cli *CLI, version string) error { //nolint: cyclop,funle
 		return fmt.Errorf("incorrect bind-to parameter: %w", err)
 	}
 
-	if err := conf.Secret.Set(s.Secret); err != nil {
+	secret, err := config.ResolveSecret(s.Secret)
+	if err != nil {
+		return fmt.Errorf("cannot resolve secret: %w", err)
+	}
+
+	if err := conf.Secret.Set(secret); err != nil {
 		return fmt.Errorf("incorrect secret: %w", err)
 	}
 
@@ -85,5 +90,5 @@ func (s *SimpleRun) Run(cli *CLI, version string) error { //nolint: cyclop,funle
 		return fmt.Errorf("invalid result configuration: %w", err)
 	}
 
-	return runProxy(conf, version)
+	return runProxy(conf, version, rollingRestart{})
 }
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/9seconds/mtg/v2/antireplay"
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/9seconds/mtg/v2/internal/utils"
+	"github.com/9seconds/mtg/v2/ipblocklist"
+	"github.com/9seconds/mtg/v2/mtglib"
+	"github.com/9seconds/mtg/v2/network"
+	"github.com/9seconds/mtg/v2/stats"
+)
+
+// Validate проверяет конфиг без запуска proxy: Config.Validate() плюс
+// cross-field проверки. Печатает сводку с эффективными значениями
+// (секрет замаскирован) и возвращает ошибку при невалидном конфиге.
+type Validate struct {
+	ConfigPath string `kong:"arg,required,help='Path to the configuration file, - for stdin or http(s) URL.',name='config-path'"` //nolint: lll
+}
+
+func (v *Validate) Run(cli *CLI, version string) error {
+	conf, err := utils.ReadConfig(v.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("cannot init config: %w", err)
+	}
+
+	if err := validateCrossFields(conf); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return printConfigSummary(os.Stdout, conf)
+}
+
+// validateCrossFields проверяет согласованность секций, которую не
+// покрывает Config.Validate(): такие конфиги запускаются, но ведут себя
+// не так, как ожидает оператор.
+func validateCrossFields(conf *config.Config) error {
+	if conf.Defense.Blocklist.Enabled.Get(false) && len(conf.Defense.Blocklist.URLs) == 0 {
+		return fmt.Errorf("defense.blocklist is enabled but has no urls")
+	}
+
+	if conf.Defense.Allowlist.Enabled.Get(false) && len(conf.Defense.Allowlist.URLs) == 0 {
+		return fmt.Errorf("defense.allowlist is enabled but has no urls")
+	}
+
+	// Лимитер работает по per-second независимо от enabled: выключенная
+	// секция с настройками либо запускает лимитер, либо молча их
+	// игнорирует.
+	if !conf.RateLimit.Enabled.Get(false) &&
+		(conf.RateLimit.PerSecond.Value > 0 || conf.RateLimit.Burst.Value > 0) {
+		return fmt.Errorf("rate-limit.per-second or rate-limit.burst is set but rate-limit is disabled")
+	}
+
+	if !conf.ConnectionPool.Enabled.Get(false) &&
+		(conf.ConnectionPool.MaxIdleConns.Value > 0 || conf.ConnectionPool.IdleTimeout.Value > 0 ||
+			conf.ConnectionPool.IdleDrainAfter.Value > 0) {
+		return fmt.Errorf("connection-pool settings are set but connection-pool is disabled")
+	}
+
+	if conf.DCConfig.Enabled.Get(false) {
+		switch {
+		case conf.DCConfig.File == "" && conf.DCConfig.URL.Get("") == "":
+			return fmt.Errorf("dc-config is enabled but neither file nor url is set")
+		case conf.DCConfig.File != "" && conf.DCConfig.URL.Get("") != "":
+			return fmt.Errorf("dc-config has both file and url set")
+		}
+	}
+
+	if conf.Stats.Prometheus.Enabled.Get(false) &&
+		conf.Stats.Prometheus.BindTo.Get("") == conf.BindTo.Get("") {
+		return fmt.Errorf("prometheus.bindTo clashes with proxy bind-to %s", conf.BindTo.Get(""))
+	}
+
+	if peerBindTo := conf.Defense.AntiReplay.Peer.BindTo.Get(""); peerBindTo != "" &&
+		(peerBindTo == conf.BindTo.Get("") || peerBindTo == conf.Stats.Prometheus.BindTo.Get("")) {
+		return fmt.Errorf("defense.anti-replay.peer.bind-to %s clashes with another bind-to", peerBindTo)
+	}
+
+	if linksBindTo := conf.ShareLinks.BindTo.Get(""); linksBindTo != "" &&
+		(linksBindTo == conf.BindTo.Get("") || linksBindTo == conf.Stats.Prometheus.BindTo.Get("") ||
+			linksBindTo == conf.Defense.AntiReplay.Peer.BindTo.Get("")) {
+		return fmt.Errorf("share-links.bind-to %s clashes with another bind-to", linksBindTo)
+	}
+
+	return nil
+}
+
+func printConfigSummary(writer io.Writer, conf *config.Config) error {
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0) //nolint: gomnd
+
+	row := func(name string, value any) {
+		fmt.Fprintf(tw, "%s\t%v\n", name, value) //nolint: errcheck
+	}
+
+	row("secret", "*** (host: "+conf.Secret.Host+")")
+	row("bind-to", conf.BindTo.Get(""))
+	row("prefer-ip", conf.PreferIP.Get(mtglib.DefaultPreferIP))
+	row("domain-fronting-port", conf.DomainFrontingPort.Get(mtglib.DefaultDomainFrontingPort))
+	row("domain-fronting-max-connections",
+		conf.DomainFrontingMaxConnections.Get(mtglib.DefaultDomainFrontingMaxConnections))
+	row("concurrency", conf.Concurrency.Get(mtglib.DefaultConcurrency))
+	row("accept-backpressure-threshold", conf.AcceptBackpressureThreshold.Get(0))
+	row("max-relays", conf.MaxRelays.Get(0))
+	row("relay-buffer-size", conf.RelayBufferSize.Get(mtglib.DefaultRelayBufferSize))
+	row("tolerate-time-skewness", conf.TolerateTimeSkewness.Get(mtglib.DefaultTolerateTimeSkewness))
+	row("tolerate-time-skewness-overrides", len(conf.TolerateTimeSkewnessOverrides))
+	row("anti-fingerprint.max-record-size", conf.AntiFingerprint.MaxRecordSize.Get(mtglib.DefaultFakeTLSMaxRecordSize))
+	row("anti-fingerprint.small-first-records", conf.AntiFingerprint.SmallFirstRecords.Get(0))
+	row("allow-fallback-on-unknown-dc", conf.AllowFallbackOnUnknownDC.Get(false))
+	row("unknown-dc-mapping", len(conf.UnknownDCMapping))
+	row("fallback-on-dial-error", conf.FallbackOnDialError.Get(true))
+	row("dc-failure-ttl", conf.DCFailureTTL.Get(mtglib.DefaultDCFailureTTL))
+	row("max-dial-addresses", conf.MaxDialAddresses.Get(0))
+	row("probe-dcs-on-startup", conf.ProbeDCsOnStartup.Get(false))
+	row("cpu-affinity", conf.CPUAffinity)
+	row("network.timeout.tcp", conf.Network.Timeout.TCP.Get(network.DefaultTimeout))
+	row("network.timeout.http", conf.Network.Timeout.HTTP.Get(network.DefaultHTTPTimeout))
+	row("network.timeout.idle", conf.Network.Timeout.Idle.Get(mtglib.DefaultIdleTimeout))
+	row("network.timeout.drain", conf.Network.Timeout.Drain.Get(mtglib.DefaultDrainTimeout))
+	row("network.timeout.client-hello", conf.Network.Timeout.ClientHello.Get(0))
+	row("network.dns-mode", conf.Network.DNSMode.String())
+	row("network.dns-query-type", conf.Network.DNSQueryType.Get(network.DNSQueryTypeBoth))
+	row("network.tcp-fast-open", conf.Network.TCPFastOpen.Get(false))
+	row("network.tcp-fast-open-warmup", conf.Network.TCPFastOpenWarmUp.Get(false))
+	row("network.listen-backlog", conf.Network.ListenBacklog.Get(0))
+	row("network.tcp-user-timeout", conf.Network.TCPUserTimeout.Get(mtglib.DefaultTCPUserTimeout))
+	row("network.client-tcp-user-timeout",
+		conf.Network.ClientTCPUserTimeout.Get(conf.Network.TCPUserTimeout.Get(mtglib.DefaultTCPUserTimeout)))
+	row("network.telegram-tcp-user-timeout",
+		conf.Network.TelegramTCPUserTimeout.Get(conf.Network.TCPUserTimeout.Get(mtglib.DefaultTCPUserTimeout)))
+	row("network.relay-io-timeout", conf.Network.RelayIOTimeout.Get(0))
+	row("network.tcp-window-clamp", conf.Network.TCPWindowClamp.Get(mtglib.DefaultTelegramWindowClamp))
+	row("network.proxies", len(conf.Network.Proxies))
+	row("network.proxy-selection", conf.Network.ProxySelection.Get(network.ProxySelectionRandom))
+	row("network.telegram-bypass-proxies", conf.Network.TelegramBypassProxies.Get(false))
+
+	row("defense.anti-replay", conf.Defense.AntiReplay.Enabled.Get(false))
+
+	if conf.Defense.AntiReplay.Enabled.Get(false) {
+		row("defense.anti-replay.max-size",
+			conf.Defense.AntiReplay.MaxSize.Get(antireplay.DefaultStableBloomFilterMaxSize))
+		row("defense.anti-replay.error-rate",
+			conf.Defense.AntiReplay.ErrorRate.Get(antireplay.DefaultStableBloomFilterErrorRate))
+		row("defense.anti-replay.same-client-window",
+			conf.Defense.AntiReplay.SameClientWindow.Get(0))
+		row("defense.anti-replay.peer.bind-to", conf.Defense.AntiReplay.Peer.BindTo.Get(""))
+		row("defense.anti-replay.peer.warm-up-from", conf.Defense.AntiReplay.Peer.WarmUpFrom.Get(""))
+		row("defense.anti-replay.peer.read-only", conf.Defense.AntiReplay.Peer.ReadOnly.Get(false))
+		row("defense.anti-replay.peer.sync-interval",
+			conf.Defense.AntiReplay.Peer.SyncInterval.Get(antireplay.DefaultReplicaSyncInterval))
+	}
+
+	printListSummary(row, "defense.blocklist", conf.Defense.Blocklist)
+	printListSummary(row, "defense.allowlist", conf.Defense.Allowlist)
+	row("defense.reject-scanners", conf.Defense.RejectScanners.Enabled.Get(false))
+	row("defense.tarpit", conf.Defense.Tarpit.Enabled.Get(false))
+
+	if conf.Defense.Tarpit.Enabled.Get(false) {
+		row("defense.tarpit.duration", conf.Defense.Tarpit.Duration.Get(mtglib.DefaultTarpitDuration))
+	}
+	row("defense.silent-reject-window", conf.Defense.SilentRejectWindow.Get(0))
+
+	row("connection-pool", conf.ConnectionPool.Enabled.Get(false))
+
+	if conf.ConnectionPool.Enabled.Get(false) {
+		row("connection-pool.max-idle-conns", conf.ConnectionPool.MaxIdleConns.Get(mtglib.DefaultConnectionPoolMaxIdle))
+		row("connection-pool.idle-timeout", conf.ConnectionPool.IdleTimeout.Value)
+		row("connection-pool.idle-drain-after", conf.ConnectionPool.IdleDrainAfter.Get(0))
+	}
+
+	// Как и в runProxy, лимитер включает per-second, а не enabled.
+	row("rate-limit", conf.RateLimit.PerSecond.Get(0) > 0)
+
+	if conf.RateLimit.PerSecond.Get(0) > 0 {
+		row("rate-limit.per-second", conf.RateLimit.PerSecond.Get(0))
+		row("rate-limit.burst", conf.RateLimit.Burst.Get(mtglib.DefaultRateLimitBurst))
+	}
+
+	row("share-links.bind-to", conf.ShareLinks.BindTo.Get(""))
+
+	if conf.ShareLinks.BindTo.Get("") != "" {
+		row("share-links.public-host", conf.ShareLinks.PublicHost)
+		row("share-links.public-port", conf.ShareLinks.PublicPort.Get(conf.BindTo.Port))
+	}
+
+	row("telegram-health.check-interval", conf.TelegramHealth.CheckInterval.Get(0))
+
+	if conf.TelegramHealth.CheckInterval.Get(0) > 0 {
+		row("telegram-health.failure-threshold",
+			conf.TelegramHealth.FailureThreshold.Get(mtglib.DefaultTelegramHealthFailureThreshold))
+	}
+
+	row("stats.statsd", conf.Stats.StatsD.Enabled.Get(false))
+
+	if conf.Stats.StatsD.Enabled.Get(false) {
+		row("stats.statsd.address", conf.Stats.StatsD.Address.Get(""))
+		row("stats.statsd.metric-prefix",
+			conf.Stats.StatsD.MetricPrefix.Get(stats.DefaultStatsdMetricPrefix))
+		row("stats.statsd.tag-format", conf.Stats.StatsD.TagFormat.Get(stats.DefaultStatsdTagFormat))
+	}
+
+	row("stats.prometheus", conf.Stats.Prometheus.Enabled.Get(false))
+
+	if conf.Stats.Prometheus.Enabled.Get(false) {
+		row("stats.prometheus.bind-to", conf.Stats.Prometheus.BindTo.Get(""))
+		row("stats.prometheus.http-path", conf.Stats.Prometheus.HTTPPath.Get("/"))
+		row("stats.prometheus.metric-prefix",
+			conf.Stats.Prometheus.MetricPrefix.Get(stats.DefaultMetricPrefix))
+		row("stats.prometheus.replay-attack-source",
+			conf.Stats.Prometheus.ReplayAttackSource.Get(stats.ReplayAttackSourceOff))
+		row("stats.prometheus.fronted-sni",
+			conf.Stats.Prometheus.FrontedSNI.Get(stats.FrontedSNIHashed))
+		row("stats.prometheus.serve-on-proxy-port", conf.Stats.Prometheus.ServeOnProxyPort.Get(false))
+		row("stats.prometheus.disable-runtime-metrics", conf.Stats.Prometheus.DisableRuntimeMetrics.Get(false))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("cannot print summary: %w", err)
+	}
+
+	return nil
+}
+
+func printListSummary(row func(string, any), name string, conf config.ListConfig) {
+	row(name, conf.Enabled.Get(false))
+
+	if !conf.Enabled.Get(false) {
+		return
+	}
+
+	row(name+".urls", strconv.Itoa(len(conf.URLs)))
+	row(name+".download-concurrency", conf.DownloadConcurrency.Get(1))
+	row(name+".update-each", conf.UpdateEach.Get(ipblocklist.DefaultFireholUpdateEach))
+}
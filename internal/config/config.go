@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 
+	"github.com/9seconds/mtg/v2/antireplay"
+	"github.com/9seconds/mtg/v2/internal/sharelinks"
 	"github.com/9seconds/mtg/v2/mtglib"
 )
 
@@ -24,27 +28,94 @@ type Config struct {
 	Debug                    TypeBool        `json:"debug"`
 	AllowFallbackOnUnknownDC TypeBool        `json:"allowFallbackOnUnknownDc"`
 	FallbackOnDialError      TypeBool        `json:"fallbackOnDialError"`
+	DCFailureTTL             TypeDuration    `json:"dcFailureTtl"`
+	MaxDialAddresses         TypeConcurrency `json:"maxDialAddresses"`
+	ProbeDCsOnStartup        TypeBool        `json:"probeDcsOnStartup"`
 	Secret                   mtglib.Secret   `json:"secret"`
 	BindTo                   TypeHostPort    `json:"bindTo"`
 	PreferIP                 TypePreferIP    `json:"preferIp"`
 	DomainFrontingPort       TypePort        `json:"domainFrontingPort"`
 	TolerateTimeSkewness     TypeDuration    `json:"tolerateTimeSkewness"`
 	Concurrency              TypeConcurrency `json:"concurrency"`
-	Defense                  struct {
+	// AcceptBackpressureThreshold — мягкий лимит активных соединений:
+	// выше него прокси перестаёт вызывать accept, и новые клиенты ждут
+	// в backlog ядра, а не получают отказ на concurrency. Считаются и
+	// relay, отпущенные воркерами при max-relays.
+	// Default: 0 (выключено)
+	AcceptBackpressureThreshold TypeConcurrency `json:"acceptBackpressureThreshold"`
+	// MaxRelays — лимит активных relay. Если задан, воркер занят только
+	// хендшейком, а relay идёт отдельно, и concurrency ограничивает
+	// одновременные хендшейки, а не все соединения.
+	// Default: 0 (relay внутри воркера)
+	MaxRelays TypeConcurrency `json:"maxRelays"`
+	// DomainFrontingMaxConnections — сколько соединений одновременно
+	// уходит на fronting домен, остальные закрываются. 0 — без лимита.
+	// Default: mtglib.DefaultDomainFrontingMaxConnections
+	DomainFrontingMaxConnections TypeConnectionLimit `json:"domainFrontingMaxConnections"`
+	RelayBufferSize              TypeBytes           `json:"relayBufferSize"`
+	Defense                      struct {
 		AntiReplay struct {
 			Optional
 
 			MaxSize   TypeBytes     `json:"maxSize"`
 			ErrorRate TypeErrorRate `json:"errorRate"`
+			// SameClientWindow — окно, в котором клиент из той же сети
+			// (/24 или /64) может повторить session id без флага replay.
+			// Default: 0 (любой повтор — replay)
+			SameClientWindow TypeDuration `json:"sameClientWindow"`
+			// Peer — обмен снимками фильтра с другими инстансами.
+			Peer struct {
+				// BindTo — где отдавать снимок фильтра другим инстансам.
+				// Default: пусто (не отдавать)
+				BindTo TypeListenAddress `json:"bindTo"`
+				// WarmUpFrom — URL снимка соседнего инстанса, который
+				// загружается один раз при старте.
+				// Default: пусто (стартовать с пустым фильтром)
+				WarmUpFrom TypePeerURL `json:"warmUpFrom"`
+				// Token — bearer token, общий для отдачи и загрузки.
+				Token string `json:"token"`
+				// ReadOnly — режим read replica: фильтр только проверяется
+				// и периодически заменяется снимком WarmUpFrom.
+				// Default: false
+				ReadOnly TypeBool `json:"readOnly"`
+				// SyncInterval — как часто read replica забирает снимок.
+				// Не меньше antireplay.MinReplicaSyncInterval.
+				// Default: antireplay.DefaultReplicaSyncInterval
+				SyncInterval TypeDuration `json:"syncInterval"`
+			} `json:"peer"`
 		} `json:"antiReplay"`
-		Blocklist ListConfig `json:"blocklist"`
-		Allowlist ListConfig `json:"allowlist"`
+		Blocklist      ListConfig `json:"blocklist"`
+		Allowlist      ListConfig `json:"allowlist"`
+		RejectScanners Optional   `json:"rejectScanners"`
+		// Tarpit — вместо domain fronting медленно отдавать невалидным
+		// клиентам TLS alert и закрывать соединение.
+		Tarpit struct {
+			Optional
+
+			// Duration — за сколько отдаётся alert.
+			// Default: mtglib.DefaultTarpitDuration
+			Duration TypeDuration `json:"duration"`
+		} `json:"tarpit"`
+
+		// SilentRejectWindow — окно, в котором повторные отказы
+		// allowlist/blocklist для того же IP не логируются и не
+		// отправляют события.
+		// Default: 0 (выключено)
+		SilentRejectWindow TypeDuration `json:"silentRejectWindow"`
 	} `json:"defense"`
 	Network struct {
 		Timeout struct {
 			TCP  TypeDuration `json:"tcp"`
 			HTTP TypeDuration `json:"http"`
 			Idle TypeDuration `json:"idle"`
+			// Drain — сколько Shutdown ждёт соединения после SIGUSR1.
+			// Default: mtglib.DefaultDrainTimeout
+			Drain TypeDuration `json:"drain"`
+			// ClientHello — за сколько клиент должен прислать TLS record
+			// с ClientHello целиком. Медленный клиент закрывается, а не
+			// уходит на fronting домен, и это видно снаружи.
+			// Default: 0 (выключено)
+			ClientHello TypeDuration `json:"clientHello"`
 		} `json:"timeout"`
 		DOHIP   TypeIP         `json:"dohIp"`
 		DNSMode TypeDNSMode    `json:"dnsMode"`
@@ -54,6 +125,60 @@ type Config struct {
 		// Требует поддержки ядром (net.ipv4.tcp_fastopen >= 3).
 		// Default: false (для обратной совместимости)
 		TCPFastOpen TypeBool `json:"tcpFastOpen"`
+		// TCPFastOpenWarmUp — при старте открыть и закрыть соединение к
+		// каждому адресу DC, чтобы ядро закэшировало TFO cookies.
+		// Эвристика: работает только вместе с TCPFastOpen и прямым
+		// подключением к DC.
+		// Default: false
+		TCPFastOpenWarmUp TypeBool `json:"tcpFastOpenWarmup"`
+		// ListenBacklog — размер accept-очереди listener.
+		// Эффективное значение ограничено net.core.somaxconn.
+		// Default: 0 (системное значение)
+		ListenBacklog TypeConcurrency `json:"listenBacklog"`
+		// TCPUserTimeout — TCP_USER_TIMEOUT для relay-соединений: через
+		// сколько неподтверждённые данные считаются признаком мёртвого пира.
+		// Default: 30s
+		TCPUserTimeout TypeDuration `json:"tcpUserTimeout"`
+		// ClientTCPUserTimeout и TelegramTCPUserTimeout — TCP_USER_TIMEOUT
+		// отдельно для соединения с клиентом и с Telegram.
+		// Default: tcp-user-timeout
+		ClientTCPUserTimeout   TypeDuration `json:"clientTcpUserTimeout"`
+		TelegramTCPUserTimeout TypeDuration `json:"telegramTcpUserTimeout"`
+		// TCPWindowClamp — TCP_WINDOW_CLAMP для соединений к Telegram,
+		// ограничивает буферизацию (buffer bloat) на стороне Telegram.
+		// Default: 128kib
+		TCPWindowClamp TypeBytes `json:"tcpWindowClamp"`
+		// TelegramBypassProxies — подключаться к Telegram DC напрямую,
+		// минуя network.proxies. Прокси остаются для domain fronting,
+		// загрузки списков и т.д.
+		// Default: false (Telegram DC идут через ту же цепочку прокси)
+		TelegramBypassProxies TypeBool `json:"telegramBypassProxies"`
+		// DNSQueryType — какие DNS запросы отправлять: both, a или aaaa.
+		// В отличие от prefer-ip, который только упорядочивает адреса,
+		// лишние запросы не отправляются вовсе.
+		// Default: both
+		DNSQueryType TypeDNSQueryType `json:"dnsQueryType"`
+		// DoHMaxInFlight — максимум одновременных DoH запросов. Лишние
+		// ждут свободного слота недолго, затем завершаются ошибкой.
+		// Default: 64
+		DoHMaxInFlight TypeConcurrency `json:"dohMaxInFlight"`
+		// ProxySelection — как выбирать прокси из network.proxies:
+		// random, roundrobin или failover (первый прокси основной,
+		// остальные — запасные). Прокси на cooldown пропускаются.
+		// Default: random
+		ProxySelection TypeProxySelection `json:"proxySelection"`
+		// RelayIOTimeout — сколько relay может не передавать данные ни в
+		// одну сторону и сколько может блокироваться одна запись.
+		// Default: 0 (выключено)
+		RelayIOTimeout TypeDuration `json:"relayIoTimeout"`
+		// UserAgent — User-Agent исходящих HTTP запросов (DoH, загрузка
+		// списков).
+		// Default: mtg/<version>
+		UserAgent string `json:"userAgent"`
+		// UserAgentRotation — выбирать для каждого HTTP запроса случайный
+		// User-Agent из набора браузерных. Несовместим с UserAgent.
+		// Default: false
+		UserAgentRotation TypeBool `json:"userAgentRotation"`
 	} `json:"network"`
 	// ConnectionPool — настройки пула соединений к Telegram DC.
 	// Переиспользование соединений снижает latency на 30-50ms.
@@ -67,6 +192,11 @@ type Config struct {
 		// IdleTimeout — таймаут простоя для соединений в пуле.
 		// Default: 1m
 		IdleTimeout TypeDuration `json:"idleTimeout"`
+
+		// IdleDrainAfter — через сколько без новых соединений к DC
+		// закрыть все его idle соединения.
+		// Default: 0 (не закрывать)
+		IdleDrainAfter TypeDuration `json:"idleDrainAfter"`
 	} `json:"connectionPool"`
 	// RateLimit — ограничение количества handshakes на IP.
 	// Защищает от brute-force подбора секрета.
@@ -90,16 +220,53 @@ type Config struct {
 		// File — путь к JSON файлу с DC-адресами.
 		File string `json:"file"`
 
-		// RefreshInterval — интервал проверки файла на обновления.
+		// URL — http(s) адрес, отдающий тот же JSON. Задаётся вместо
+		// File, оба сразу указывать нельзя. Локальные и приватные адреса
+		// запрещены (SSRF).
+		URL TypeRemoteURL `json:"url"`
+
+		// RefreshInterval — интервал проверки файла или URL на обновления.
 		// Default: 24h
 		RefreshInterval TypeDuration `json:"refreshInterval"`
 	} `json:"dcConfig"`
+	// ShareLinks — отдельный HTTP сервер, который отдаёт готовые
+	// tg:// и https://t.me ссылки на прокси и их QR коды.
+	ShareLinks struct {
+		// BindTo — где слушать.
+		// Default: пусто (не отдавать)
+		BindTo TypeListenAddress `json:"bindTo"`
+		// PublicHost — адрес или доменное имя прокси для клиентов.
+		PublicHost string `json:"publicHost"`
+		// PublicPort — порт прокси для клиентов.
+		// Default: порт из bind-to прокси
+		PublicPort TypePort `json:"publicPort"`
+		// Token — bearer token: ссылки содержат секрет.
+		Token string `json:"token"`
+	} `json:"shareLinks"`
+	// TelegramHealth — периодическая проверка доступности DC. Без
+	// доступных DC прокси считается нездоровым: /healthz метрик и
+	// mtg health сообщают об ошибке.
+	TelegramHealth struct {
+		// CheckInterval — как часто проверять DC.
+		// Default: пусто (не проверять)
+		CheckInterval TypeDuration `json:"checkInterval"`
+		// FailureThreshold — сколько раундов подряд без доступных DC
+		// нужно, чтобы прокси стал нездоровым. Защищает от флаппинга.
+		// Default: mtglib.DefaultTelegramHealthFailureThreshold
+		FailureThreshold TypeConcurrency `json:"failureThreshold"`
+	} `json:"telegramHealth"`
 	// AntiFingerprint — настройки противодействия DPI-анализу.
-	// DEPRECATED: CCS padding удалён — RFC 8446 violation.
-	// Секция сохранена для backward compatibility при парсинге старых конфигов.
 	AntiFingerprint struct {
 		// CCSPadding — DEPRECATED, игнорируется. CCS между ApplicationData = DPI fingerprint.
 		CCSPadding TypeBool `json:"ccsPadding"`
+		// MaxRecordSize — максимальный размер TLS record при записи клиенту.
+		// Не меньше mtglib.MinFakeTLSMaxRecordSize.
+		// Default: 16kib (максимум по RFC 8446)
+		MaxRecordSize TypeBytes `json:"maxRecordSize"`
+		// SmallFirstRecords — сколько первых records писать маленькими,
+		// как браузер. Не больше mtglib.MaxFakeTLSSmallFirstRecords.
+		// Default: 0, выключено.
+		SmallFirstRecords TypeConcurrency `json:"smallFirstRecords"`
 	} `json:"antiFingerprint"`
 	Stats struct {
 		StatsD struct {
@@ -112,11 +279,39 @@ type Config struct {
 		Prometheus struct {
 			Optional
 
-			BindTo       TypeHostPort     `json:"bindTo"`
-			HTTPPath     TypeHTTPPath     `json:"httpPath"`
-			MetricPrefix TypeMetricPrefix `json:"metricPrefix"`
+			BindTo       TypeListenAddress `json:"bindTo"`
+			HTTPPath     TypeHTTPPath      `json:"httpPath"`
+			MetricPrefix TypeMetricPrefix  `json:"metricPrefix"`
+			// ReplayAttackSource — метка источника для replay-атак:
+			// off (default), hashed или raw.
+			ReplayAttackSource TypeReplayAttackSource `json:"replayAttackSource"`
+			// FrontedSNI — метка SNI для клиентов, ушедших в domain
+			// fronting: hashed (default), raw или off.
+			FrontedSNI TypeFrontedSNI `json:"frontedSni"`
+			// ServeOnProxyPort — отдавать метрики и через порт прокси:
+			// авторизованный HTTP GET на http-path вместо domain fronting.
+			ServeOnProxyPort TypeBool `json:"serveOnProxyPort"`
+			// ProxyPortToken — bearer token для метрик на порту прокси.
+			ProxyPortToken string `json:"proxyPortToken"`
+			// DisableRuntimeMetrics — не отдавать стандартные go_* и
+			// process_* метрики: память, паузы GC, файловые дескрипторы.
+			// Флаг отрицательный: TypeBool не отличает явный false от
+			// незаданного значения, а по умолчанию метрики включены.
+			DisableRuntimeMetrics TypeBool `json:"disableRuntimeMetrics"`
 		} `json:"prometheus"`
 	} `json:"stats"`
+	// TolerateTimeSkewnessOverrides — tolerate-time-skewness для отдельных
+	// сетей клиентов: CIDR -> допустимое расхождение часов. Побеждает
+	// самая специфичная сеть.
+	TolerateTimeSkewnessOverrides map[string]TypeDuration `json:"tolerateTimeSkewnessOverrides"`
+	// UnknownDCMapping — замена неизвестных DC на известные: номер DC,
+	// который просит клиент, -> DC 1-5.
+	UnknownDCMapping map[string]int `json:"unknownDcMapping"`
+	// CPUAffinity — ядра, к которым привязываются accept loop и
+	// обработчики событий. Соединения не привязываются. Только Linux,
+	// процессу нужен доступ к ядрам.
+	// Default: пусто (без привязки)
+	CPUAffinity []uint `json:"cpuAffinity"`
 }
 
 func (c *Config) Validate() error {
@@ -146,11 +341,155 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Prometheus: bindTo обязателен если включён
+	// DNS: dns-query-type не должен выключать единственное семейство
+	// адресов, которое разрешает prefer-ip
+	queryType := c.Network.DNSQueryType.Get(TypeDNSQueryTypeBoth)
+	preferIP := c.PreferIP.Get("")
+
+	if (queryType == TypeDNSQueryTypeA && preferIP == TypePreferOnlyIPv6) ||
+		(queryType == TypeDNSQueryTypeAAAA && preferIP == TypePreferOnlyIPv4) {
+		return fmt.Errorf("network.dns-query-type %s conflicts with prefer-ip %s", queryType, preferIP)
+	}
+
+	if c.Network.UserAgent != "" && c.Network.UserAgentRotation.Get(false) {
+		return fmt.Errorf("network.user-agent conflicts with network.user-agent-rotation")
+	}
+
+	// Prometheus: bindTo обязателен если включён, кроме случая, когда
+	// метрики отдаются только через порт прокси.
 	if c.Stats.Prometheus.Enabled.Get(false) {
-		if c.Stats.Prometheus.BindTo.Get("") == "" {
+		serveOnProxyPort := c.Stats.Prometheus.ServeOnProxyPort.Get(false)
+
+		if c.Stats.Prometheus.BindTo.Get("") == "" && !serveOnProxyPort {
 			return fmt.Errorf("prometheus.bindTo is required when prometheus is enabled")
 		}
+
+		if serveOnProxyPort && len(c.Stats.Prometheus.ProxyPortToken) < mtglib.MinInBandMetricsTokenLength {
+			return fmt.Errorf("prometheus.proxyPortToken must be at least %d characters long when serve-on-proxy-port is enabled",
+				mtglib.MinInBandMetricsTokenLength)
+		}
+	}
+
+	// Anti-replay: снимок фильтра содержит seed xxHash, без токена его
+	// не отдаём и не запрашиваем
+	if peer := c.Defense.AntiReplay.Peer; peer.BindTo.Get("") != "" || peer.WarmUpFrom.Get("") != "" {
+		if !c.Defense.AntiReplay.Enabled.Get(false) {
+			return fmt.Errorf("defense.anti-replay.peer requires anti-replay to be enabled")
+		}
+
+		if len(peer.Token) < antireplay.MinStateTokenLength {
+			return fmt.Errorf("defense.anti-replay.peer.token must be at least %d characters long",
+				antireplay.MinStateTokenLength)
+		}
+	}
+
+	// Read replica без источника снимков так и осталась бы пустой
+	if c.Defense.AntiReplay.Peer.ReadOnly.Get(false) && c.Defense.AntiReplay.Peer.WarmUpFrom.Get("") == "" {
+		return fmt.Errorf("defense.anti-replay.peer.read-only requires defense.anti-replay.peer.warm-up-from")
+	}
+
+	// Каждый sync — полный снимок фильтра, слишком частые грузят primary
+	if interval := c.Defense.AntiReplay.Peer.SyncInterval.Get(antireplay.DefaultReplicaSyncInterval); interval <
+		antireplay.MinReplicaSyncInterval {
+		return fmt.Errorf("defense.anti-replay.peer.sync-interval must be at least %s", antireplay.MinReplicaSyncInterval)
+	}
+
+	// Share links: ссылки содержат секрет, без токена их не отдаём
+	if c.ShareLinks.BindTo.Get("") != "" {
+		if c.ShareLinks.PublicHost == "" {
+			return fmt.Errorf("share-links.public-host is required when share-links.bind-to is set")
+		}
+
+		if len(c.ShareLinks.Token) < sharelinks.MinTokenLength {
+			return fmt.Errorf("share-links.token must be at least %d characters long", sharelinks.MinTokenLength)
+		}
+	}
+
+	// Anti-fingerprint: records больше 16kib запрещены RFC 8446, а
+	// крошечные records сами по себе fingerprint и раздувают трафик.
+	if size := c.AntiFingerprint.MaxRecordSize.Get(mtglib.DefaultFakeTLSMaxRecordSize); size < mtglib.MinFakeTLSMaxRecordSize ||
+		size > mtglib.DefaultFakeTLSMaxRecordSize {
+		return fmt.Errorf("anti-fingerprint.max-record-size must be within [%d, %d] bytes",
+			mtglib.MinFakeTLSMaxRecordSize, mtglib.DefaultFakeTLSMaxRecordSize)
+	}
+
+	if c.AntiFingerprint.SmallFirstRecords.Get(0) > mtglib.MaxFakeTLSSmallFirstRecords {
+		return fmt.Errorf("anti-fingerprint.small-first-records must not exceed %d", mtglib.MaxFakeTLSSmallFirstRecords)
+	}
+
+	// Relay buffer: слишком мелкий буфер — syscall на каждые пару пакетов,
+	// слишком крупный — память на каждое соединение
+	if size := c.RelayBufferSize.Get(mtglib.DefaultRelayBufferSize); size < mtglib.MinRelayBufferSize ||
+		size > mtglib.MaxRelayBufferSize {
+		return fmt.Errorf("relay-buffer-size must be within [%d, %d] bytes",
+			mtglib.MinRelayBufferSize, mtglib.MaxRelayBufferSize)
+	}
+
+	// Accept backpressure: имеет смысл только ниже жёсткого лимита. С
+	// max-relays relay не держат воркеры, и активных соединений бывает
+	// до concurrency + max-relays.
+	if threshold := c.AcceptBackpressureThreshold.Get(0); threshold > 0 &&
+		threshold >= c.Concurrency.Get(mtglib.DefaultConcurrency)+c.MaxRelays.Get(0) {
+		return fmt.Errorf("accept-backpressure-threshold must be less than concurrency + max-relays")
+	}
+
+	// Network: TCP-параметры relay в разумных пределах
+	if timeout := c.Network.TCPUserTimeout.Get(mtglib.DefaultTCPUserTimeout); timeout < mtglib.MinTCPUserTimeout ||
+		timeout > mtglib.MaxTCPUserTimeout {
+		return fmt.Errorf("network.tcp-user-timeout must be within [%v, %v]",
+			mtglib.MinTCPUserTimeout, mtglib.MaxTCPUserTimeout)
+	}
+
+	if timeout := c.Network.ClientTCPUserTimeout.Get(0); timeout != 0 &&
+		(timeout < mtglib.MinTCPUserTimeout || timeout > mtglib.MaxTCPUserTimeout) {
+		return fmt.Errorf("network.client-tcp-user-timeout must be within [%v, %v]",
+			mtglib.MinTCPUserTimeout, mtglib.MaxTCPUserTimeout)
+	}
+
+	if timeout := c.Network.TelegramTCPUserTimeout.Get(0); timeout != 0 &&
+		(timeout < mtglib.MinTCPUserTimeout || timeout > mtglib.MaxTCPUserTimeout) {
+		return fmt.Errorf("network.telegram-tcp-user-timeout must be within [%v, %v]",
+			mtglib.MinTCPUserTimeout, mtglib.MaxTCPUserTimeout)
+	}
+
+	if duration := c.Defense.Tarpit.Duration.Get(mtglib.DefaultTarpitDuration); c.Defense.Tarpit.Enabled.Get(false) &&
+		duration > mtglib.MaxTarpitDuration {
+		return fmt.Errorf("defense.tarpit.duration must be at most %v", mtglib.MaxTarpitDuration)
+	}
+
+	if timeout := c.Network.RelayIOTimeout.Get(0); timeout != 0 && timeout < mtglib.MinRelayIOTimeout {
+		return fmt.Errorf("network.relay-io-timeout must be 0 or at least %v", mtglib.MinRelayIOTimeout)
+	}
+
+	if clamp := c.Network.TCPWindowClamp.Get(mtglib.DefaultTelegramWindowClamp); clamp < mtglib.MinTelegramWindowClamp ||
+		clamp > mtglib.MaxTelegramWindowClamp {
+		return fmt.Errorf("network.tcp-window-clamp must be within [%d, %d] bytes",
+			mtglib.MinTelegramWindowClamp, mtglib.MaxTelegramWindowClamp)
+	}
+
+	for network, tolerance := range c.TolerateTimeSkewnessOverrides {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("tolerate-time-skewness-overrides has incorrect network %s: %w", network, err)
+		}
+
+		if tolerance.Value == 0 {
+			return fmt.Errorf("tolerate-time-skewness-overrides has zero tolerance for %s", network)
+		}
+	}
+
+	for from, to := range c.UnknownDCMapping {
+		dc, err := strconv.Atoi(from)
+		if err != nil {
+			return fmt.Errorf("unknown-dc-mapping has incorrect DC %s: %w", from, err)
+		}
+
+		if dc >= 1 && dc <= 5 {
+			return fmt.Errorf("unknown-dc-mapping maps known DC %d", dc)
+		}
+
+		if to < 1 || to > 5 {
+			return fmt.Errorf("unknown-dc-mapping maps DC %d to incorrect DC %d", dc, to)
+		}
 	}
 
 	// StatsD: address обязателен если включён
@@ -164,9 +503,12 @@ func (c *Config) Validate() error {
 }
 
 func (c *Config) String() string {
-	// Маскируем секрет для безопасного логирования
+	// Маскируем секрет и токены для безопасного логирования
 	safe := *c
 	safe.Secret = mtglib.Secret{} // Zero value — не сериализует реальный секрет
+	safe.Defense.AntiReplay.Peer.Token = maskToken(c.Defense.AntiReplay.Peer.Token)
+	safe.Stats.Prometheus.ProxyPortToken = maskToken(c.Stats.Prometheus.ProxyPortToken)
+	safe.ShareLinks.Token = maskToken(c.ShareLinks.Token)
 
 	buf := &bytes.Buffer{}
 	encoder := json.NewEncoder(buf)
@@ -179,3 +521,13 @@ func (c *Config) String() string {
 
 	return buf.String()
 }
+
+// maskToken прячет непустой токен: по маске видно, что он задан, но не
+// его значение.
+func maskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	return "***"
+}
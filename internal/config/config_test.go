@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/9seconds/mtg/v2/internal/config"
 	"github.com/stretchr/testify/suite"
@@ -42,12 +43,302 @@ func (suite *ConfigTestSuite) TestParseMinimalConfig() {
 	suite.Equal("0.0.0.0:3128", conf.BindTo.String())
 }
 
+func (suite *ConfigTestSuite) TestParseUnknownDCMapping() {
+	conf, err := config.Parse(suite.ReadConfig("unknown_dc_mapping.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(map[string]int{"203": 2, "-203": 2}, conf.UnknownDCMapping)
+
+	conf.UnknownDCMapping["204"] = 6
+	suite.Error(conf.Validate())
+
+	delete(conf.UnknownDCMapping, "204")
+	conf.UnknownDCMapping["2"] = 4
+	suite.Error(conf.Validate())
+
+	delete(conf.UnknownDCMapping, "2")
+	conf.UnknownDCMapping["dc"] = 4
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseCPUAffinity() {
+	conf, err := config.Parse(suite.ReadConfig("cpu_affinity.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal([]uint{2, 3}, conf.CPUAffinity)
+}
+
+func (suite *ConfigTestSuite) TestParseAcceptBackpressureThreshold() {
+	conf, err := config.Parse(suite.ReadConfig("accept_backpressure.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(900, conf.AcceptBackpressureThreshold.Get(0))
+
+	conf.AcceptBackpressureThreshold.Value = 1000
+	suite.Error(conf.Validate())
+
+	// С max-relays relay не держат воркеры и тоже считаются.
+	conf.MaxRelays.Value = 500
+	suite.NoError(conf.Validate())
+
+	conf.AcceptBackpressureThreshold.Value = 1500
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseMaxRelays() {
+	conf, err := config.Parse(suite.ReadConfig("max_relays.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(1000, conf.Concurrency.Get(0))
+	suite.EqualValues(20000, conf.MaxRelays.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParseDomainFrontingMaxConnections() {
+	conf, err := config.Parse(suite.ReadConfig("domain_fronting_max_connections.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(64, conf.DomainFrontingMaxConnections.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParseDomainFrontingMaxConnectionsZero() {
+	conf, err := config.Parse(suite.ReadConfig("domain_fronting_max_connections_zero.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(0, conf.DomainFrontingMaxConnections.Get(1024))
+
+	conf, err = config.Parse(suite.ReadConfig("minimal.toml"))
+	suite.NoError(err)
+	suite.EqualValues(1024, conf.DomainFrontingMaxConnections.Get(1024))
+}
+
+func (suite *ConfigTestSuite) TestParsePrometheusRuntimeMetrics() {
+	conf, err := config.Parse(suite.ReadConfig("minimal.toml"))
+	suite.NoError(err)
+	suite.False(conf.Stats.Prometheus.DisableRuntimeMetrics.Get(false))
+
+	conf, err = config.Parse(suite.ReadConfig("prometheus_runtime_metrics.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.True(conf.Stats.Prometheus.DisableRuntimeMetrics.Get(false))
+}
+
+func (suite *ConfigTestSuite) TestParseTelegramHealth() {
+	conf, err := config.Parse(suite.ReadConfig("telegram_health.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(30*time.Second, conf.TelegramHealth.CheckInterval.Get(0))
+	suite.EqualValues(5, conf.TelegramHealth.FailureThreshold.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParseMaxDialAddresses() {
+	conf, err := config.Parse(suite.ReadConfig("max_dial_addresses.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(2, conf.MaxDialAddresses.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParseConnectionPoolIdleDrain() {
+	conf, err := config.Parse(suite.ReadConfig("connection_pool_idle_drain.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(10*time.Minute, conf.ConnectionPool.IdleDrainAfter.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestMaxRecordSizeBounds() {
+	conf, err := config.Parse(suite.ReadConfig("minimal.toml"))
+	suite.NoError(err)
+
+	suite.NoError(conf.AntiFingerprint.MaxRecordSize.Set("512b"))
+	suite.NoError(conf.Validate())
+
+	suite.NoError(conf.AntiFingerprint.MaxRecordSize.Set("511b"))
+	suite.Error(conf.Validate())
+
+	suite.NoError(conf.AntiFingerprint.MaxRecordSize.Set("17kib"))
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseSmallFirstRecords() {
+	conf, err := config.Parse(suite.ReadConfig("small_first_records.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(2, conf.AntiFingerprint.SmallFirstRecords.Get(0))
+
+	conf.AntiFingerprint.SmallFirstRecords.Value = 9
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseTimeSkewnessOverrides() {
+	conf, err := config.Parse(suite.ReadConfig("time_skewness_overrides.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Len(conf.TolerateTimeSkewnessOverrides, 2)
+	suite.Equal(30*time.Second, conf.TolerateTimeSkewnessOverrides["10.0.0.0/8"].Get(0))
+	suite.Equal(time.Minute, conf.TolerateTimeSkewnessOverrides["2001:db8::/32"].Get(0))
+
+	conf.TolerateTimeSkewnessOverrides["10.0.0.1"] = config.TypeDuration{Value: time.Second}
+	suite.Error(conf.Validate())
+
+	delete(conf.TolerateTimeSkewnessOverrides, "10.0.0.1")
+	conf.TolerateTimeSkewnessOverrides["192.168.0.0/16"] = config.TypeDuration{}
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParsePrometheusOnProxyPort() {
+	conf, err := config.Parse(suite.ReadConfig("prometheus_on_proxy_port.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.True(conf.Stats.Prometheus.ServeOnProxyPort.Get(false))
+	suite.Equal("0123456789abcdef", conf.Stats.Prometheus.ProxyPortToken)
+	suite.Empty(conf.Stats.Prometheus.BindTo.Get(""))
+
+	conf.Stats.Prometheus.ProxyPortToken = "short"
+	suite.Error(conf.Validate())
+
+	conf.Stats.Prometheus.ServeOnProxyPort.Value = false
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseAntiReplayPeer() {
+	conf, err := config.Parse(suite.ReadConfig("anti_replay_peer.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal("10.0.0.2:3131", conf.Defense.AntiReplay.Peer.BindTo.Get(""))
+	suite.Equal("http://10.0.0.1:3131/", conf.Defense.AntiReplay.Peer.WarmUpFrom.Get(""))
+
+	conf.Defense.AntiReplay.Peer.Token = "short"
+	suite.Error(conf.Validate())
+
+	conf.Defense.AntiReplay.Peer.Token = "0123456789abcdef"
+	conf.Defense.AntiReplay.Enabled.Value = false
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseAntiReplayReplica() {
+	conf, err := config.Parse(suite.ReadConfig("anti_replay_replica.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.True(conf.Defense.AntiReplay.Peer.ReadOnly.Get(false))
+	suite.Equal(30*time.Second, conf.Defense.AntiReplay.Peer.SyncInterval.Get(0))
+
+	conf.Defense.AntiReplay.Peer.SyncInterval.Value = time.Second
+	suite.Error(conf.Validate())
+
+	conf.Defense.AntiReplay.Peer.SyncInterval.Value = 30 * time.Second
+
+	conf.Defense.AntiReplay.Peer.WarmUpFrom = config.TypePeerURL{}
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseShareLinks() {
+	conf, err := config.Parse(suite.ReadConfig("share_links.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal("127.0.0.1:3132", conf.ShareLinks.BindTo.Get(""))
+	suite.Equal("proxy.example.com", conf.ShareLinks.PublicHost)
+	suite.EqualValues(3128, conf.ShareLinks.PublicPort.Get(conf.BindTo.Port))
+
+	conf.ShareLinks.Token = "short"
+	suite.Error(conf.Validate())
+
+	conf.ShareLinks.Token = "0123456789abcdef"
+	conf.ShareLinks.PublicHost = ""
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseDNSQueryType() {
+	conf, err := config.Parse(suite.ReadConfig("dns_query_type.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(config.TypeDNSQueryTypeA, conf.Network.DNSQueryType.Get(config.TypeDNSQueryTypeBoth))
+
+	suite.NoError(conf.PreferIP.Set(config.TypePreferOnlyIPv6))
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseSilentRejectWindow() {
+	conf, err := config.Parse(suite.ReadConfig("silent_reject_window.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(30*time.Second, conf.Defense.SilentRejectWindow.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParseTarpit() {
+	conf, err := config.Parse(suite.ReadConfig("tarpit.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.True(conf.Defense.Tarpit.Enabled.Get(false))
+	suite.Equal(5*time.Second, conf.Defense.Tarpit.Duration.Get(0))
+
+	suite.NoError(conf.Defense.Tarpit.Duration.Set("2m"))
+	suite.Error(conf.Validate())
+}
+
+func (suite *ConfigTestSuite) TestParseDCConfigURL() {
+	conf, err := config.Parse(suite.ReadConfig("dc_config_url.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal("https://example.com/dc_addresses.json", conf.DCConfig.URL.Get(""))
+
+	_, err = config.Parse(suite.ReadConfig("dc_config_url_private.toml"))
+	suite.Error(err)
+}
+
+func (suite *ConfigTestSuite) TestParseUserAgentConflict() {
+	conf, err := config.Parse(suite.ReadConfig("user_agent_conflict.toml"))
+	suite.NoError(err)
+	suite.Error(conf.Validate())
+
+	conf.Network.UserAgentRotation.Value = false
+	suite.NoError(conf.Validate())
+	suite.Equal("curl/8.0", conf.Network.UserAgent)
+}
+
+func (suite *ConfigTestSuite) TestParseDoHMaxInFlight() {
+	conf, err := config.Parse(suite.ReadConfig("doh_max_in_flight.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(16, conf.Network.DoHMaxInFlight.Get(64))
+}
+
+func (suite *ConfigTestSuite) TestParseListenBacklog() {
+	conf, err := config.Parse(suite.ReadConfig("listen_backlog.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.EqualValues(4096, conf.Network.ListenBacklog.Get(0))
+}
+
+func (suite *ConfigTestSuite) TestParsePrometheusUnix() {
+	conf, err := config.Parse(suite.ReadConfig("prometheus_unix.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.True(conf.Stats.Prometheus.BindTo.IsUnix())
+	suite.Equal("/run/mtg/metrics.sock", conf.Stats.Prometheus.BindTo.Address)
+}
+
+func (suite *ConfigTestSuite) TestParseTCPUserTimeouts() {
+	conf, err := config.Parse(suite.ReadConfig("tcp_user_timeouts.toml"))
+	suite.NoError(err)
+	suite.NoError(conf.Validate())
+	suite.Equal(2*time.Minute, conf.Network.ClientTCPUserTimeout.Get(0))
+	suite.Equal(15*time.Second, conf.Network.TelegramTCPUserTimeout.Get(0))
+}
+
 func (suite *ConfigTestSuite) TestString() {
 	conf, err := config.Parse(suite.ReadConfig("minimal.toml"))
 	suite.NoError(err)
 	suite.NotEmpty(conf.String())
 }
 
+func (suite *ConfigTestSuite) TestStringMasksTokens() {
+	for _, name := range []string{"anti_replay_peer.toml", "prometheus_on_proxy_port.toml", "share_links.toml"} {
+		conf, err := config.Parse(suite.ReadConfig(name))
+		suite.NoError(err)
+		suite.NotContains(conf.String(), "0123456789abcdef", name)
+		suite.Contains(conf.String(), `"***"`, name)
+	}
+}
+
 func TestConfig(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &ConfigTestSuite{})
@@ -9,20 +9,37 @@ import (
 )
 
 type tomlConfig struct {
-	Debug                    bool   `toml:"debug" json:"debug,omitempty"`
-	AllowFallbackOnUnknownDC bool   `toml:"allow-fallback-on-unknown-dc" json:"allowFallbackOnUnknownDc,omitempty"`
-	FallbackOnDialError      *bool  `toml:"fallback-on-dial-error" json:"fallbackOnDialError,omitempty"`
-	Secret                   string `toml:"secret" json:"secret"`
-	BindTo                   string `toml:"bind-to" json:"bindTo"`
-	PreferIP                 string `toml:"prefer-ip" json:"preferIp,omitempty"`
-	DomainFrontingPort       uint   `toml:"domain-fronting-port" json:"domainFrontingPort,omitempty"`
-	TolerateTimeSkewness     string `toml:"tolerate-time-skewness" json:"tolerateTimeSkewness,omitempty"`
-	Concurrency              uint   `toml:"concurrency" json:"concurrency,omitempty"`
-	Defense                  struct {
+	Debug                        bool   `toml:"debug" json:"debug,omitempty"`
+	AllowFallbackOnUnknownDC     bool   `toml:"allow-fallback-on-unknown-dc" json:"allowFallbackOnUnknownDc,omitempty"`
+	FallbackOnDialError          *bool  `toml:"fallback-on-dial-error" json:"fallbackOnDialError,omitempty"`
+	DCFailureTTL                 string `toml:"dc-failure-ttl" json:"dcFailureTtl,omitempty"`
+	MaxDialAddresses             uint   `toml:"max-dial-addresses" json:"maxDialAddresses,omitempty"`
+	ProbeDCsOnStartup            bool   `toml:"probe-dcs-on-startup" json:"probeDcsOnStartup,omitempty"`
+	Secret                       string `toml:"secret" json:"secret"`
+	BindTo                       string `toml:"bind-to" json:"bindTo"`
+	PreferIP                     string `toml:"prefer-ip" json:"preferIp,omitempty"`
+	DomainFrontingPort           uint   `toml:"domain-fronting-port" json:"domainFrontingPort,omitempty"`
+	DomainFrontingMaxConnections *uint  `toml:"domain-fronting-max-connections" json:"domainFrontingMaxConnections,omitempty"`
+	TolerateTimeSkewness         string `toml:"tolerate-time-skewness" json:"tolerateTimeSkewness,omitempty"`
+	Concurrency                  uint   `toml:"concurrency" json:"concurrency,omitempty"`
+	AcceptBackpressureThreshold  uint   `toml:"accept-backpressure-threshold" json:"acceptBackpressureThreshold,omitempty"`
+	MaxRelays                    uint   `toml:"max-relays" json:"maxRelays,omitempty"`
+	RelayBufferSize              string `toml:"relay-buffer-size" json:"relayBufferSize,omitempty"`
+	Defense                      struct {
 		AntiReplay struct {
 			Enabled   bool    `toml:"enabled" json:"enabled,omitempty"`
 			MaxSize   string  `toml:"max-size" json:"maxSize,omitempty"`
 			ErrorRate float64 `toml:"error-rate" json:"errorRate,omitempty"`
+
+			SameClientWindow string `toml:"same-client-window" json:"sameClientWindow,omitempty"`
+			Peer             struct {
+				BindTo     string `toml:"bind-to" json:"bindTo,omitempty"`
+				WarmUpFrom string `toml:"warm-up-from" json:"warmUpFrom,omitempty"`
+				Token      string `toml:"token" json:"token,omitempty"`
+
+				ReadOnly     bool   `toml:"read-only" json:"readOnly,omitempty"`
+				SyncInterval string `toml:"sync-interval" json:"syncInterval,omitempty"`
+			} `toml:"peer" json:"peer,omitempty"`
 		} `toml:"anti-replay" json:"antiReplay,omitempty"`
 		Blocklist struct {
 			Enabled             bool     `toml:"enabled" json:"enabled,omitempty"`
@@ -36,32 +53,67 @@ type tomlConfig struct {
 			URLs                []string `toml:"urls" json:"urls,omitempty"`
 			UpdateEach          string   `toml:"update-each" json:"updateEach,omitempty"`
 		} `toml:"allowlist" json:"allowlist,omitempty"`
+		RejectScanners struct {
+			Enabled bool `toml:"enabled" json:"enabled,omitempty"`
+		} `toml:"reject-scanners" json:"rejectScanners,omitempty"`
+		Tarpit struct {
+			Enabled  bool   `toml:"enabled" json:"enabled,omitempty"`
+			Duration string `toml:"duration" json:"duration,omitempty"`
+		} `toml:"tarpit" json:"tarpit,omitempty"`
+		SilentRejectWindow string `toml:"silent-reject-window" json:"silentRejectWindow,omitempty"`
 	} `toml:"defense" json:"defense,omitempty"`
 	Network struct {
 		Timeout struct {
-			TCP  string `toml:"tcp" json:"tcp,omitempty"`
-			HTTP string `toml:"http" json:"http,omitempty"`
-			Idle string `toml:"idle" json:"idle,omitempty"`
+			TCP         string `toml:"tcp" json:"tcp,omitempty"`
+			HTTP        string `toml:"http" json:"http,omitempty"`
+			Idle        string `toml:"idle" json:"idle,omitempty"`
+			Drain       string `toml:"drain" json:"drain,omitempty"`
+			ClientHello string `toml:"client-hello" json:"clientHello,omitempty"`
 		} `toml:"timeout" json:"timeout,omitempty"`
-		DOHIP       string   `toml:"doh-ip" json:"dohIp,omitempty"`
-		DNSMode     string   `toml:"dns-mode" json:"dnsMode,omitempty"`
-		Proxies     []string `toml:"proxies" json:"proxies,omitempty"`
-		TCPFastOpen bool     `toml:"tcp-fast-open" json:"tcpFastOpen,omitempty"`
+		DOHIP                  string   `toml:"doh-ip" json:"dohIp,omitempty"`
+		DNSMode                string   `toml:"dns-mode" json:"dnsMode,omitempty"`
+		DNSQueryType           string   `toml:"dns-query-type" json:"dnsQueryType,omitempty"`
+		DoHMaxInFlight         uint     `toml:"doh-max-in-flight" json:"dohMaxInFlight,omitempty"`
+		Proxies                []string `toml:"proxies" json:"proxies,omitempty"`
+		ProxySelection         string   `toml:"proxy-selection" json:"proxySelection,omitempty"`
+		TCPFastOpen            bool     `toml:"tcp-fast-open" json:"tcpFastOpen,omitempty"`
+		TCPFastOpenWarmUp      bool     `toml:"tcp-fast-open-warmup" json:"tcpFastOpenWarmup,omitempty"`
+		ListenBacklog          uint     `toml:"listen-backlog" json:"listenBacklog,omitempty"`
+		TCPUserTimeout         string   `toml:"tcp-user-timeout" json:"tcpUserTimeout,omitempty"`
+		ClientTCPUserTimeout   string   `toml:"client-tcp-user-timeout" json:"clientTcpUserTimeout,omitempty"`
+		TelegramTCPUserTimeout string   `toml:"telegram-tcp-user-timeout" json:"telegramTcpUserTimeout,omitempty"`
+		RelayIOTimeout         string   `toml:"relay-io-timeout" json:"relayIoTimeout,omitempty"`
+		TCPWindowClamp         string   `toml:"tcp-window-clamp" json:"tcpWindowClamp,omitempty"`
+		TelegramBypassProxies  bool     `toml:"telegram-bypass-proxies" json:"telegramBypassProxies,omitempty"`
+		UserAgent              string   `toml:"user-agent" json:"userAgent,omitempty"`
+		UserAgentRotation      bool     `toml:"user-agent-rotation" json:"userAgentRotation,omitempty"`
 	} `toml:"network" json:"network,omitempty"`
 	ConnectionPool struct {
-		Enabled      bool   `toml:"enabled" json:"enabled,omitempty"`
-		MaxIdleConns uint   `toml:"max-idle-conns" json:"maxIdleConns,omitempty"`
-		IdleTimeout  string `toml:"idle-timeout" json:"idleTimeout,omitempty"`
+		Enabled        bool   `toml:"enabled" json:"enabled,omitempty"`
+		MaxIdleConns   uint   `toml:"max-idle-conns" json:"maxIdleConns,omitempty"`
+		IdleTimeout    string `toml:"idle-timeout" json:"idleTimeout,omitempty"`
+		IdleDrainAfter string `toml:"idle-drain-after" json:"idleDrainAfter,omitempty"`
 	} `toml:"connection-pool" json:"connectionPool,omitempty"`
 	DCConfig struct {
 		Enabled         bool   `toml:"enabled" json:"enabled,omitempty"`
 		File            string `toml:"file" json:"file,omitempty"`
+		URL             string `toml:"url" json:"url,omitempty"`
 		RefreshInterval string `toml:"refresh-interval" json:"refreshInterval,omitempty"`
 	} `toml:"dc-config" json:"dcConfig,omitempty"`
-	// AntiFingerprint — DEPRECATED: CCS padding удалён.
-	// Секция сохранена для совместимости со старыми конфигами.
+	ShareLinks struct {
+		BindTo     string `toml:"bind-to" json:"bindTo,omitempty"`
+		PublicHost string `toml:"public-host" json:"publicHost,omitempty"`
+		PublicPort uint   `toml:"public-port" json:"publicPort,omitempty"`
+		Token      string `toml:"token" json:"token,omitempty"`
+	} `toml:"share-links" json:"shareLinks,omitempty"`
+	TelegramHealth struct {
+		CheckInterval    string `toml:"check-interval" json:"checkInterval,omitempty"`
+		FailureThreshold uint   `toml:"failure-threshold" json:"failureThreshold,omitempty"`
+	} `toml:"telegram-health" json:"telegramHealth,omitempty"`
 	AntiFingerprint struct {
-		CCSPadding bool `toml:"ccs-padding" json:"ccsPadding,omitempty"`
+		CCSPadding        bool   `toml:"ccs-padding" json:"ccsPadding,omitempty"`
+		MaxRecordSize     string `toml:"max-record-size" json:"maxRecordSize,omitempty"`
+		SmallFirstRecords uint   `toml:"small-first-records" json:"smallFirstRecords,omitempty"`
 	} `toml:"anti-fingerprint" json:"antiFingerprint,omitempty"`
 	Stats struct {
 		StatsD struct {
@@ -71,12 +123,20 @@ type tomlConfig struct {
 			TagFormat    string `toml:"tag-format" json:"tagFormat,omitempty"`
 		} `toml:"statsd" json:"statsd,omitempty"`
 		Prometheus struct {
-			Enabled      bool   `toml:"enabled" json:"enabled,omitempty"`
-			BindTo       string `toml:"bind-to" json:"bindTo,omitempty"`
-			HTTPPath     string `toml:"http-path" json:"httpPath,omitempty"`
-			MetricPrefix string `toml:"metric-prefix" json:"metricPrefix,omitempty"`
+			Enabled               bool   `toml:"enabled" json:"enabled,omitempty"`
+			BindTo                string `toml:"bind-to" json:"bindTo,omitempty"`
+			HTTPPath              string `toml:"http-path" json:"httpPath,omitempty"`
+			MetricPrefix          string `toml:"metric-prefix" json:"metricPrefix,omitempty"`
+			ReplayAttackSource    string `toml:"replay-attack-source" json:"replayAttackSource,omitempty"`
+			FrontedSNI            string `toml:"fronted-sni" json:"frontedSni,omitempty"`
+			ServeOnProxyPort      bool   `toml:"serve-on-proxy-port" json:"serveOnProxyPort,omitempty"`
+			ProxyPortToken        string `toml:"proxy-port-token" json:"proxyPortToken,omitempty"`
+			DisableRuntimeMetrics bool   `toml:"disable-runtime-metrics" json:"disableRuntimeMetrics,omitempty"`
 		} `toml:"prometheus" json:"prometheus,omitempty"`
 	} `toml:"stats" json:"stats,omitempty"`
+	TolerateTimeSkewnessOverrides map[string]string `toml:"tolerate-time-skewness-overrides" json:"tolerateTimeSkewnessOverrides,omitempty"`
+	UnknownDCMapping              map[string]int    `toml:"unknown-dc-mapping" json:"unknownDcMapping,omitempty"`
+	CPUAffinity                   []uint            `toml:"cpu-affinity" json:"cpuAffinity,omitempty"`
 }
 
 func Parse(rawData []byte) (*Config, error) {
@@ -92,6 +152,13 @@ func Parse(rawData []byte) (*Config, error) {
 		return nil, fmt.Errorf("cannot parse toml config: %w", err)
 	}
 
+	secret, err := ResolveSecret(tomlConf.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve secret: %w", err)
+	}
+
+	tomlConf.Secret = secret
+
 	if err := jsonEncoder.Encode(tomlConf); err != nil {
 		return nil, fmt.Errorf("cannot encode parsed config: %w", err)
 	}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// SecretRefFilePrefix is a prefix of a secret which has to be read
+	// from a file: file:///run/secrets/mtg.
+	SecretRefFilePrefix = "file://"
+
+	// SecretRefEnvPrefix is a prefix of a secret which has to be read from
+	// an environment variable: env:MTG_SECRET.
+	SecretRefEnvPrefix = "env:"
+)
+
+var errSecretRefEmpty = errors.New("secret reference is empty")
+
+// ResolveSecret resolves a secret reference into the actual secret. A
+// value with SecretRefFilePrefix is read from a file, a value with
+// SecretRefEnvPrefix is read from an environment variable. Surrounding
+// whitespace (like a trailing newline) is trimmed. Any other value is an
+// inline secret and is returned as is.
+//
+// Errors never contain a resolved value.
+func ResolveSecret(value string) (string, error) {
+	var resolved string
+
+	if path, ok := strings.CutPrefix(value, SecretRefFilePrefix); ok {
+		if path == "" {
+			return "", errSecretRefEmpty
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret file: %w", err)
+		}
+
+		resolved = string(data)
+	} else if name, ok := strings.CutPrefix(value, SecretRefEnvPrefix); ok {
+		if name == "" {
+			return "", errSecretRefEmpty
+		}
+
+		data, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		resolved = data
+	} else {
+		return value, nil
+	}
+
+	resolved = strings.TrimSpace(resolved)
+	if resolved == "" {
+		return "", fmt.Errorf("secret from %s is empty", value)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,70 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/suite"
+)
+
+const secretRefTestSecret = "7oe1GqLy6TBc38CV3jx7q09nb29nbGUuY29t"
+
+type SecretRefTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SecretRefTestSuite) TestInline() {
+	value, err := config.ResolveSecret(secretRefTestSecret)
+	suite.NoError(err)
+	suite.Equal(secretRefTestSecret, value)
+}
+
+func (suite *SecretRefTestSuite) TestFile() {
+	path := filepath.Join(suite.T().TempDir(), "secret")
+	suite.NoError(os.WriteFile(path, []byte(secretRefTestSecret+"\n"), 0o600))
+
+	value, err := config.ResolveSecret(config.SecretRefFilePrefix + path)
+	suite.NoError(err)
+	suite.Equal(secretRefTestSecret, value)
+
+	_, err = config.ResolveSecret(config.SecretRefFilePrefix + path + ".missing")
+	suite.Error(err)
+
+	_, err = config.ResolveSecret(config.SecretRefFilePrefix)
+	suite.Error(err)
+}
+
+func (suite *SecretRefTestSuite) TestEnv() {
+	suite.T().Setenv("MTG_TEST_SECRET", " "+secretRefTestSecret+" ")
+	suite.T().Setenv("MTG_TEST_SECRET_EMPTY", "")
+
+	value, err := config.ResolveSecret(config.SecretRefEnvPrefix + "MTG_TEST_SECRET")
+	suite.NoError(err)
+	suite.Equal(secretRefTestSecret, value)
+
+	_, err = config.ResolveSecret(config.SecretRefEnvPrefix + "MTG_TEST_SECRET_EMPTY")
+	suite.Error(err)
+
+	_, err = config.ResolveSecret(config.SecretRefEnvPrefix + "MTG_TEST_SECRET_MISSING")
+	suite.Error(err)
+}
+
+func (suite *SecretRefTestSuite) TestParse() {
+	suite.T().Setenv("MTG_TEST_SECRET", secretRefTestSecret)
+
+	conf, err := config.Parse([]byte("secret = \"env:MTG_TEST_SECRET\"\nbind-to = \"0.0.0.0:3128\"\n"))
+	suite.NoError(err)
+	suite.Equal(secretRefTestSecret, conf.Secret.Base64())
+	suite.NotContains(conf.String(), secretRefTestSecret)
+	suite.NotContains(conf.String(), conf.Secret.Hex())
+
+	_, err = config.Parse([]byte("secret = \"env:MTG_TEST_SECRET_MISSING\"\nbind-to = \"0.0.0.0:3128\"\n"))
+	suite.Error(err)
+}
+
+// Без t.Parallel: тесты меняют переменные окружения.
+func TestSecretRef(t *testing.T) { //nolint: paralleltest
+	suite.Run(t, &SecretRefTestSuite{})
+}
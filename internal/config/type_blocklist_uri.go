@@ -35,44 +35,56 @@ func (t *TypeBlocklistURI) Set(value string) error {
 		return nil
 	}
 
+	parsedURL, err := ParseRemoteURL(value)
+	if err != nil {
+		return err
+	}
+
+	t.Value = parsedURL.String()
+
+	return nil
+}
+
+// ParseRemoteURL parses and validates a remote http(s) URL. It rejects
+// URLs with credentials and URLs pointing to local or private hosts to
+// protect against SSRF.
+func ParseRemoteURL(value string) (*url.URL, error) {
 	parsedURL, err := url.Parse(value)
 	if err != nil {
-		return fmt.Errorf("incorrect url (%s): %w", value, err)
+		return nil, fmt.Errorf("incorrect url (%s): %w", value, err)
 	}
 
 	switch parsedURL.Scheme {
 	case "http", "https":
 	default:
-		return fmt.Errorf("unknown schema %s (%s)", parsedURL.Scheme, value)
+		return nil, fmt.Errorf("unknown schema %s (%s)", parsedURL.Scheme, value)
 	}
 
 	if parsedURL.Host == "" {
-		return fmt.Errorf("incorrect url %s", value)
+		return nil, fmt.Errorf("incorrect url %s", value)
 	}
 
 	if parsedURL.User != nil {
-		return fmt.Errorf("credentials in url are not allowed (%s)", value)
+		return nil, fmt.Errorf("credentials in url are not allowed (%s)", value)
 	}
 
 	hostname := parsedURL.Hostname()
 	if hostname == "" {
-		return fmt.Errorf("incorrect host in url %s", value)
+		return nil, fmt.Errorf("incorrect host in url %s", value)
 	}
 
 	if port := parsedURL.Port(); port != "" {
 		portNo, err := strconv.Atoi(port)
 		if err != nil || portNo <= 0 || portNo > 65535 {
-			return fmt.Errorf("incorrect port in url %s", value)
+			return nil, fmt.Errorf("incorrect port in url %s", value)
 		}
 	}
 
 	if isBlockedRemoteHost(hostname) {
-		return fmt.Errorf("blocked host in url %s", value)
+		return nil, fmt.Errorf("blocked host in url %s", value)
 	}
 
-	t.Value = parsedURL.String()
-
-	return nil
+	return parsedURL, nil
 }
 
 func (t TypeBlocklistURI) Get(defaultValue string) string {
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TypeConnectionLimit — лимит соединений, где явный 0 означает «без
+// лимита». В отличие от TypeConcurrency, помнит, был ли он задан: не
+// заданный лимит получает значение по умолчанию.
+type TypeConnectionLimit struct {
+	Value uint
+	IsSet bool
+}
+
+func (t *TypeConnectionLimit) Set(value string) error {
+	limitValue, err := strconv.ParseUint(value, 10, 16) //nolint: gomnd
+	if err != nil {
+		return fmt.Errorf("value is not uint (%s): %w", value, err)
+	}
+
+	t.Value = uint(limitValue)
+	t.IsSet = true
+
+	return nil
+}
+
+func (t TypeConnectionLimit) Get(defaultValue uint) uint {
+	if !t.IsSet {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeConnectionLimit) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	return t.Set(string(data))
+}
+
+func (t TypeConnectionLimit) MarshalJSON() ([]byte, error) {
+	if !t.IsSet {
+		return []byte("null"), nil
+	}
+
+	return []byte(t.String()), nil
+}
+
+func (t TypeConnectionLimit) String() string {
+	return strconv.FormatUint(uint64(t.Value), 10) //nolint: gomnd
+}
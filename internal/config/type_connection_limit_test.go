@@ -0,0 +1,76 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeConnectionLimitTestStruct struct {
+	Value config.TypeConnectionLimit `json:"value"`
+}
+
+type TypeConnectionLimitTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypeConnectionLimitTestSuite) TestUnmarshalFail() {
+	testData := []string{
+		`-1`,
+		`1.1`,
+		`"1"`,
+		`"some_value"`,
+	}
+
+	for _, v := range testData {
+		data := []byte(`{"value": ` + v + `}`)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeConnectionLimitTestStruct{}))
+		})
+	}
+}
+
+func (suite *TypeConnectionLimitTestSuite) TestUnmarshalZero() {
+	testStruct := &typeConnectionLimitTestStruct{}
+
+	suite.NoError(json.Unmarshal([]byte(`{"value": 0}`), testStruct))
+	suite.EqualValues(0, testStruct.Value.Get(2))
+}
+
+func (suite *TypeConnectionLimitTestSuite) TestUnmarshalNull() {
+	testStruct := &typeConnectionLimitTestStruct{}
+
+	suite.NoError(json.Unmarshal([]byte(`{"value": null}`), testStruct))
+	suite.EqualValues(2, testStruct.Value.Get(2))
+}
+
+func (suite *TypeConnectionLimitTestSuite) TestMarshalOk() {
+	testStruct := &typeConnectionLimitTestStruct{}
+
+	data, err := json.Marshal(testStruct)
+	suite.NoError(err)
+	suite.JSONEq(`{"value": null}`, string(data))
+
+	suite.NoError(testStruct.Value.Set("0"))
+
+	data, err = json.Marshal(testStruct)
+	suite.NoError(err)
+	suite.JSONEq(`{"value": 0}`, string(data))
+}
+
+func (suite *TypeConnectionLimitTestSuite) TestGet() {
+	value := config.TypeConnectionLimit{}
+	suite.EqualValues(1, value.Get(1))
+
+	suite.NoError(value.Set("3"))
+	suite.EqualValues(3, value.Get(1))
+}
+
+func TestTypeConnectionLimit(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TypeConnectionLimitTestSuite{})
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/9seconds/mtg/v2/network"
+)
+
+const (
+	// TypeDNSQueryTypeBoth states that both A and AAAA records are
+	// resolved.
+	TypeDNSQueryTypeBoth = network.DNSQueryTypeBoth
+
+	// TypeDNSQueryTypeA states that only A records are resolved. AAAA
+	// queries are not sent at all.
+	TypeDNSQueryTypeA = network.DNSQueryTypeA
+
+	// TypeDNSQueryTypeAAAA states that only AAAA records are resolved. A
+	// queries are not sent at all.
+	TypeDNSQueryTypeAAAA = network.DNSQueryTypeAAAA
+)
+
+type TypeDNSQueryType struct {
+	Value string
+}
+
+func (t *TypeDNSQueryType) Set(value string) error {
+	value = strings.ToLower(value)
+
+	switch value {
+	case TypeDNSQueryTypeBoth, TypeDNSQueryTypeA, TypeDNSQueryTypeAAAA:
+		t.Value = value
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported dns query type: %s", value)
+	}
+}
+
+func (t *TypeDNSQueryType) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeDNSQueryType) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t TypeDNSQueryType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t TypeDNSQueryType) String() string {
+	return t.Value
+}
@@ -0,0 +1,110 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeDNSQueryTypeTestStruct struct {
+	Value config.TypeDNSQueryType `json:"value"`
+}
+
+type TypeDNSQueryTypeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypeDNSQueryTypeTestSuite) TestUnmarshalFail() {
+	testData := []string{
+		"",
+		"aa",
+		"ipv4",
+		config.TypeDNSQueryTypeAAAA + "_",
+	}
+
+	for _, v := range testData {
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeDNSQueryTypeTestStruct{}))
+		})
+	}
+}
+
+func (suite *TypeDNSQueryTypeTestSuite) TestUnmarshalOk() {
+	testData := []string{
+		config.TypeDNSQueryTypeBoth,
+		config.TypeDNSQueryTypeA,
+		config.TypeDNSQueryTypeAAAA,
+		strings.ToTitle(config.TypeDNSQueryTypeBoth),
+		strings.ToTitle(config.TypeDNSQueryTypeA),
+		strings.ToTitle(config.TypeDNSQueryTypeAAAA),
+	}
+
+	for _, v := range testData {
+		value := v
+
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeDNSQueryTypeTestStruct{}
+			assert.NoError(t, json.Unmarshal(data, testStruct))
+			assert.Equal(t, strings.ToLower(value), testStruct.Value.Value)
+		})
+	}
+}
+
+func (suite *TypeDNSQueryTypeTestSuite) TestMarshalOk() {
+	testData := []string{
+		config.TypeDNSQueryTypeBoth,
+		config.TypeDNSQueryTypeA,
+		config.TypeDNSQueryTypeAAAA,
+	}
+
+	for _, v := range testData {
+		value := v
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeDNSQueryTypeTestStruct{
+				Value: config.TypeDNSQueryType{
+					Value: value,
+				},
+			}
+
+			encodedJSON, err := json.Marshal(testStruct)
+			assert.NoError(t, err)
+
+			expectedJSON, err := json.Marshal(map[string]string{
+				"value": value,
+			})
+			assert.NoError(t, err)
+
+			assert.JSONEq(t, string(expectedJSON), string(encodedJSON))
+		})
+	}
+}
+
+func (suite *TypeDNSQueryTypeTestSuite) TestGet() {
+	value := config.TypeDNSQueryType{}
+	suite.Equal(config.TypeDNSQueryTypeBoth,
+		value.Get(config.TypeDNSQueryTypeBoth))
+
+	suite.NoError(value.Set(config.TypeDNSQueryTypeA))
+	suite.Equal(config.TypeDNSQueryTypeA,
+		value.Get(config.TypeDNSQueryTypeBoth))
+}
+
+func TestTypeDNSQueryType(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TypeDNSQueryTypeTestSuite{})
+}
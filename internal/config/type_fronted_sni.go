@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// TypeFrontedSNIOff disables labeling of domain fronted connections
+	// by SNI.
+	TypeFrontedSNIOff = "off"
+
+	// TypeFrontedSNIHashed labels domain fronted connections by a bucket
+	// of the hashed SNI.
+	TypeFrontedSNIHashed = "hashed"
+
+	// TypeFrontedSNIRaw labels domain fronted connections by raw SNI.
+	TypeFrontedSNIRaw = "raw"
+)
+
+type TypeFrontedSNI struct {
+	Value string
+}
+
+func (t *TypeFrontedSNI) Set(value string) error {
+	lowercasedValue := strings.ToLower(value)
+
+	switch lowercasedValue {
+	case TypeFrontedSNIOff, TypeFrontedSNIHashed, TypeFrontedSNIRaw:
+		t.Value = lowercasedValue
+
+		return nil
+	default:
+		return fmt.Errorf("unknown fronted sni mode %s", value)
+	}
+}
+
+func (t TypeFrontedSNI) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeFrontedSNI) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t *TypeFrontedSNI) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TypeFrontedSNI) String() string {
+	return t.Value
+}
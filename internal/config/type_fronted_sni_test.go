@@ -0,0 +1,108 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeFrontedSNITestStruct struct {
+	Value config.TypeFrontedSNI `json:"value"`
+}
+
+type FrontedSNITestSuite struct {
+	suite.Suite
+}
+
+func (suite *FrontedSNITestSuite) TestUnmarshalFail() {
+	testData := []string{
+		"",
+		"cooked",
+	}
+
+	for _, v := range testData {
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeFrontedSNITestStruct{}))
+		})
+	}
+}
+
+func (suite *FrontedSNITestSuite) TestUnmarshalOk() {
+	testData := []string{
+		config.TypeFrontedSNIHashed,
+		config.TypeFrontedSNIRaw,
+		config.TypeFrontedSNIOff,
+		strings.ToUpper(config.TypeFrontedSNIHashed),
+		strings.ToUpper(config.TypeFrontedSNIRaw),
+		strings.ToUpper(config.TypeFrontedSNIOff),
+	}
+
+	for _, v := range testData {
+		value := v
+
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeFrontedSNITestStruct{}
+			assert.NoError(t, json.Unmarshal(data, testStruct))
+			assert.Equal(t, strings.ToLower(value), testStruct.Value.Value)
+		})
+	}
+}
+
+func (suite *FrontedSNITestSuite) TestMarshalOk() {
+	testData := []string{
+		config.TypeFrontedSNIHashed,
+		config.TypeFrontedSNIRaw,
+		config.TypeFrontedSNIOff,
+	}
+
+	for _, v := range testData {
+		value := v
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeFrontedSNITestStruct{
+				Value: config.TypeFrontedSNI{
+					Value: value,
+				},
+			}
+
+			encodedJSON, err := json.Marshal(testStruct)
+			assert.NoError(t, err)
+
+			expectedJSON, err := json.Marshal(map[string]string{
+				"value": value,
+			})
+			assert.NoError(t, err)
+
+			assert.JSONEq(t, string(expectedJSON), string(encodedJSON))
+		})
+	}
+}
+
+func (suite *FrontedSNITestSuite) TestGet() {
+	value := config.TypeFrontedSNI{}
+	suite.Equal(config.TypeFrontedSNIHashed,
+		value.Get(config.TypeFrontedSNIHashed))
+
+	suite.NoError(value.Set(config.TypeFrontedSNIRaw))
+	suite.Equal(config.TypeFrontedSNIRaw,
+		value.Get(config.TypeFrontedSNIHashed))
+}
+
+func TestTypeFrontedSNI(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &FrontedSNITestSuite{})
+}
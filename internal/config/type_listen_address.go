@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// listenAddressUnixScheme — префикс адреса unix socket.
+const listenAddressUnixScheme = "unix://"
+
+// TypeListenAddress — адрес, на котором можно слушать: либо host:port
+// (как TypeHostPort), либо unix:///abs/path для unix socket.
+type TypeListenAddress struct {
+	Value string
+	// Network — "tcp" или "unix", подходит для net.Listen.
+	Network string
+	// Address — host:port или путь к unix socket.
+	Address string
+}
+
+func (t *TypeListenAddress) Set(value string) error {
+	if path, ok := strings.CutPrefix(value, listenAddressUnixScheme); ok {
+		if path == "" {
+			return fmt.Errorf("empty unix socket path: %s", value)
+		}
+
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("unix socket path is not absolute: %s", value)
+		}
+
+		t.Value = listenAddressUnixScheme + filepath.Clean(path)
+		t.Network = "unix"
+		t.Address = filepath.Clean(path)
+
+		return nil
+	}
+
+	hostPort := TypeHostPort{}
+	if err := hostPort.Set(value); err != nil {
+		return err
+	}
+
+	t.Value = hostPort.Value
+	t.Network = "tcp"
+	t.Address = hostPort.Value
+
+	return nil
+}
+
+// IsUnix сообщает, что адрес указывает на unix socket.
+func (t TypeListenAddress) IsUnix() bool {
+	return t.Network == "unix"
+}
+
+func (t TypeListenAddress) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeListenAddress) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t TypeListenAddress) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t TypeListenAddress) String() string {
+	return t.Value
+}
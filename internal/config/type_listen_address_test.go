@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeListenAddressTestStruct struct {
+	Value config.TypeListenAddress `json:"value"`
+}
+
+type TypeListenAddressTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypeListenAddressTestSuite) TestUnmarshalFail() {
+	testData := []string{
+		"",
+		"localhost",
+		"127.0.0.1:8000000",
+		"unix://",
+		"unix://relative/path.sock",
+		"tcp://127.0.0.1:80",
+	}
+
+	for _, v := range testData {
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeListenAddressTestStruct{}))
+		})
+	}
+}
+
+func (suite *TypeListenAddressTestSuite) TestUnmarshalOk() {
+	testData := map[string][3]string{
+		"127.0.0.1:80":                 {"127.0.0.1:80", "tcp", "127.0.0.1:80"},
+		"[::1]:9401":                   {"[::1]:9401", "tcp", "[::1]:9401"},
+		"unix:///run/mtg/metrics.sock": {"unix:///run/mtg/metrics.sock", "unix", "/run/mtg/metrics.sock"},
+		"unix:///run//mtg/./m.sock":    {"unix:///run/mtg/m.sock", "unix", "/run/mtg/m.sock"},
+	}
+
+	for k, v := range testData {
+		expected := v
+
+		data, err := json.Marshal(map[string]string{
+			"value": k,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(k, func(t *testing.T) {
+			testStruct := &typeListenAddressTestStruct{}
+			assert.NoError(t, json.Unmarshal(data, testStruct))
+			assert.Equal(t, expected[0], testStruct.Value.Value)
+			assert.Equal(t, expected[1], testStruct.Value.Network)
+			assert.Equal(t, expected[2], testStruct.Value.Address)
+			assert.Equal(t, expected[1] == "unix", testStruct.Value.IsUnix())
+		})
+	}
+}
+
+func (suite *TypeListenAddressTestSuite) TestMarshalOk() {
+	testStruct := typeListenAddressTestStruct{
+		Value: config.TypeListenAddress{
+			Value: "unix:///run/mtg/metrics.sock",
+		},
+	}
+
+	data, err := json.Marshal(testStruct)
+	suite.NoError(err)
+	suite.JSONEq(`{"value": "unix:///run/mtg/metrics.sock"}`, string(data))
+}
+
+func (suite *TypeListenAddressTestSuite) TestGet() {
+	value := config.TypeListenAddress{}
+	suite.Equal("127.0.0.1:9000", value.Get("127.0.0.1:9000"))
+
+	value.Value = "unix:///tmp/m.sock"
+	suite.Equal("unix:///tmp/m.sock", value.Get("127.0.0.1:9000"))
+}
+
+func TestTypeListenAddress(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TypeListenAddressTestSuite{})
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// TypePeerURL is a http(s) URL of another mtg instance. Unlike
+// TypeRemoteURL, local and private hosts are allowed: peers usually live
+// in the same private network.
+type TypePeerURL struct {
+	Value string
+}
+
+func (t *TypePeerURL) Set(value string) error {
+	parsedURL, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("incorrect url (%s): %w", value, err)
+	}
+
+	switch parsedURL.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("unknown schema %s (%s)", parsedURL.Scheme, value)
+	}
+
+	if parsedURL.Hostname() == "" {
+		return fmt.Errorf("incorrect host in url %s", value)
+	}
+
+	if parsedURL.User != nil {
+		return fmt.Errorf("credentials in url are not allowed (%s)", value)
+	}
+
+	t.Value = parsedURL.String()
+
+	return nil
+}
+
+func (t TypePeerURL) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypePeerURL) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t TypePeerURL) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t TypePeerURL) String() string {
+	return t.Value
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/9seconds/mtg/v2/network"
+)
+
+const (
+	// TypeProxySelectionRandom states that each dial starts from a random
+	// proxy.
+	TypeProxySelectionRandom = network.ProxySelectionRandom
+
+	// TypeProxySelectionRoundRobin states that each dial starts from the
+	// next proxy.
+	TypeProxySelectionRoundRobin = network.ProxySelectionRoundRobin
+
+	// TypeProxySelectionFailover states that the first proxy is a primary
+	// one and others are used only if it fails or is on cooldown.
+	TypeProxySelectionFailover = network.ProxySelectionFailover
+)
+
+type TypeProxySelection struct {
+	Value string
+}
+
+func (t *TypeProxySelection) Set(value string) error {
+	value = strings.ToLower(value)
+
+	switch value {
+	case TypeProxySelectionRandom, TypeProxySelectionRoundRobin, TypeProxySelectionFailover:
+		t.Value = value
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported proxy selection: %s", value)
+	}
+}
+
+func (t *TypeProxySelection) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeProxySelection) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t TypeProxySelection) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t TypeProxySelection) String() string {
+	return t.Value
+}
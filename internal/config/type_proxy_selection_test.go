@@ -0,0 +1,110 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeProxySelectionTestStruct struct {
+	Value config.TypeProxySelection `json:"value"`
+}
+
+type TypeProxySelectionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypeProxySelectionTestSuite) TestUnmarshalFail() {
+	testData := []string{
+		"",
+		"round-robin",
+		"weighted",
+		config.TypeProxySelectionFailover + "_",
+	}
+
+	for _, v := range testData {
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeProxySelectionTestStruct{}))
+		})
+	}
+}
+
+func (suite *TypeProxySelectionTestSuite) TestUnmarshalOk() {
+	testData := []string{
+		config.TypeProxySelectionRandom,
+		config.TypeProxySelectionRoundRobin,
+		config.TypeProxySelectionFailover,
+		strings.ToTitle(config.TypeProxySelectionRandom),
+		strings.ToTitle(config.TypeProxySelectionRoundRobin),
+		strings.ToTitle(config.TypeProxySelectionFailover),
+	}
+
+	for _, v := range testData {
+		value := v
+
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeProxySelectionTestStruct{}
+			assert.NoError(t, json.Unmarshal(data, testStruct))
+			assert.Equal(t, strings.ToLower(value), testStruct.Value.Value)
+		})
+	}
+}
+
+func (suite *TypeProxySelectionTestSuite) TestMarshalOk() {
+	testData := []string{
+		config.TypeProxySelectionRandom,
+		config.TypeProxySelectionRoundRobin,
+		config.TypeProxySelectionFailover,
+	}
+
+	for _, v := range testData {
+		value := v
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeProxySelectionTestStruct{
+				Value: config.TypeProxySelection{
+					Value: value,
+				},
+			}
+
+			encodedJSON, err := json.Marshal(testStruct)
+			assert.NoError(t, err)
+
+			expectedJSON, err := json.Marshal(map[string]string{
+				"value": value,
+			})
+			assert.NoError(t, err)
+
+			assert.JSONEq(t, string(expectedJSON), string(encodedJSON))
+		})
+	}
+}
+
+func (suite *TypeProxySelectionTestSuite) TestGet() {
+	value := config.TypeProxySelection{}
+	suite.Equal(config.TypeProxySelectionRandom,
+		value.Get(config.TypeProxySelectionRandom))
+
+	suite.NoError(value.Set(config.TypeProxySelectionRoundRobin))
+	suite.Equal(config.TypeProxySelectionRoundRobin,
+		value.Get(config.TypeProxySelectionRandom))
+}
+
+func TestTypeProxySelection(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TypeProxySelectionTestSuite{})
+}
@@ -0,0 +1,39 @@
+package config
+
+import "fmt"
+
+// TypeRemoteURL is a http(s) URL which passes ParseRemoteURL checks.
+type TypeRemoteURL struct {
+	Value string
+}
+
+func (t *TypeRemoteURL) Set(value string) error {
+	parsedURL, err := ParseRemoteURL(value)
+	if err != nil {
+		return fmt.Errorf("incorrect remote url: %w", err)
+	}
+
+	t.Value = parsedURL.String()
+
+	return nil
+}
+
+func (t TypeRemoteURL) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeRemoteURL) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t TypeRemoteURL) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t TypeRemoteURL) String() string {
+	return t.Value
+}
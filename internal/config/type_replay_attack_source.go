@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// TypeReplayAttackSourceOff disables labeling of replay attacks by
+	// source.
+	TypeReplayAttackSourceOff = "off"
+
+	// TypeReplayAttackSourceHashed labels replay attacks by a bucket of
+	// the hashed client IP.
+	TypeReplayAttackSourceHashed = "hashed"
+
+	// TypeReplayAttackSourceRaw labels replay attacks by raw client IP.
+	TypeReplayAttackSourceRaw = "raw"
+)
+
+type TypeReplayAttackSource struct {
+	Value string
+}
+
+func (t *TypeReplayAttackSource) Set(value string) error {
+	lowercasedValue := strings.ToLower(value)
+
+	switch lowercasedValue {
+	case TypeReplayAttackSourceOff, TypeReplayAttackSourceHashed,
+		TypeReplayAttackSourceRaw:
+		t.Value = lowercasedValue
+
+		return nil
+	default:
+		return fmt.Errorf("unknown replay attack source mode %s", value)
+	}
+}
+
+func (t TypeReplayAttackSource) Get(defaultValue string) string {
+	if t.Value == "" {
+		return defaultValue
+	}
+
+	return t.Value
+}
+
+func (t *TypeReplayAttackSource) UnmarshalText(data []byte) error {
+	return t.Set(string(data))
+}
+
+func (t *TypeReplayAttackSource) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TypeReplayAttackSource) String() string {
+	return t.Value
+}
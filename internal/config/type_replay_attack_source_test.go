@@ -0,0 +1,108 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type typeReplayAttackSourceTestStruct struct {
+	Value config.TypeReplayAttackSource `json:"value"`
+}
+
+type ReplayAttackSourceTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ReplayAttackSourceTestSuite) TestUnmarshalFail() {
+	testData := []string{
+		"",
+		"cooked",
+	}
+
+	for _, v := range testData {
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			assert.Error(t, json.Unmarshal(data, &typeReplayAttackSourceTestStruct{}))
+		})
+	}
+}
+
+func (suite *ReplayAttackSourceTestSuite) TestUnmarshalOk() {
+	testData := []string{
+		config.TypeReplayAttackSourceHashed,
+		config.TypeReplayAttackSourceRaw,
+		config.TypeReplayAttackSourceOff,
+		strings.ToUpper(config.TypeReplayAttackSourceHashed),
+		strings.ToUpper(config.TypeReplayAttackSourceRaw),
+		strings.ToUpper(config.TypeReplayAttackSourceOff),
+	}
+
+	for _, v := range testData {
+		value := v
+
+		data, err := json.Marshal(map[string]string{
+			"value": v,
+		})
+		suite.NoError(err)
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeReplayAttackSourceTestStruct{}
+			assert.NoError(t, json.Unmarshal(data, testStruct))
+			assert.Equal(t, strings.ToLower(value), testStruct.Value.Value)
+		})
+	}
+}
+
+func (suite *ReplayAttackSourceTestSuite) TestMarshalOk() {
+	testData := []string{
+		config.TypeReplayAttackSourceHashed,
+		config.TypeReplayAttackSourceRaw,
+		config.TypeReplayAttackSourceOff,
+	}
+
+	for _, v := range testData {
+		value := v
+
+		suite.T().Run(v, func(t *testing.T) {
+			testStruct := &typeReplayAttackSourceTestStruct{
+				Value: config.TypeReplayAttackSource{
+					Value: value,
+				},
+			}
+
+			encodedJSON, err := json.Marshal(testStruct)
+			assert.NoError(t, err)
+
+			expectedJSON, err := json.Marshal(map[string]string{
+				"value": value,
+			})
+			assert.NoError(t, err)
+
+			assert.JSONEq(t, string(expectedJSON), string(encodedJSON))
+		})
+	}
+}
+
+func (suite *ReplayAttackSourceTestSuite) TestGet() {
+	value := config.TypeReplayAttackSource{}
+	suite.Equal(config.TypeReplayAttackSourceOff,
+		value.Get(config.TypeReplayAttackSourceOff))
+
+	suite.NoError(value.Set(config.TypeReplayAttackSourceHashed))
+	suite.Equal(config.TypeReplayAttackSourceHashed,
+		value.Get(config.TypeReplayAttackSourceOff))
+}
+
+func TestTypeReplayAttackSource(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ReplayAttackSourceTestSuite{})
+}
@@ -0,0 +1,30 @@
+// Package httpauth protects small internal HTTP endpoints (filter
+// snapshots, share links) with a bearer token.
+package httpauth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthorizeGet passes only GET requests with a header
+// 'Authorization: Bearer <expected>'. Otherwise it responds with an
+// error itself and returns false.
+func AuthorizeGet(w http.ResponseWriter, req *http.Request, expected string) bool {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return false
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return false
+	}
+
+	return true
+}
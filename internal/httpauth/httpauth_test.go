@@ -0,0 +1,42 @@
+package httpauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/httpauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizeGet(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]struct {
+		method string
+		header string
+		status int
+	}{
+		"ok":           {http.MethodGet, "Bearer token", http.StatusOK},
+		"wrong token":  {http.MethodGet, "Bearer other", http.StatusUnauthorized},
+		"no bearer":    {http.MethodGet, "token", http.StatusUnauthorized},
+		"no header":    {http.MethodGet, "", http.StatusUnauthorized},
+		"wrong method": {http.MethodPost, "Bearer token", http.StatusMethodNotAllowed},
+	}
+
+	for name, value := range testData {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(value.method, "/", nil)
+			if value.header != "" {
+				req.Header.Set("Authorization", value.header)
+			}
+
+			rec := httptest.NewRecorder()
+
+			assert.Equal(t, value.status == http.StatusOK, httpauth.AuthorizeGet(rec, req, "token"))
+			assert.Equal(t, value.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package sharelinks renders links which add a proxy to Telegram clients:
+// tg://proxy and https://t.me/proxy ones, and QR codes of them.
+//
+// Links contain a secret, so a handler of this package is protected by a
+// bearer token.
+package sharelinks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/9seconds/mtg/v2/internal/httpauth"
+	"rsc.io/qr"
+)
+
+// MinTokenLength is a minimal length of a token which protects links.
+const MinTokenLength = 16
+
+// Links is a pair of links to share with users.
+type Links struct {
+	TgURL  string `json:"tg_url"`  //nolint: tagliatelle
+	TmeURL string `json:"tme_url"` //nolint: tagliatelle
+}
+
+// MakeLinks builds links for a proxy available at a given host (IP address
+// or domain name) and port. Secret should be already encoded, either in
+// hex or base64.
+func MakeLinks(host string, port uint, secret string) Links {
+	values := url.Values{}
+	values.Set("server", host)
+	values.Set("port", strconv.Itoa(int(port)))
+	values.Set("secret", secret)
+
+	urlQuery := values.Encode()
+
+	return Links{
+		TgURL: (&url.URL{
+			Scheme:   "tg",
+			Host:     "proxy",
+			RawQuery: urlQuery,
+		}).String(),
+		TmeURL: (&url.URL{
+			Scheme:   "https",
+			Host:     "t.me",
+			Path:     "proxy",
+			RawQuery: urlQuery,
+		}).String(),
+	}
+}
+
+type handler struct {
+	links Links
+	token string
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !httpauth.AuthorizeGet(w, req, h.token) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch req.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.links) //nolint: errcheck, errchkjson
+	case "/qr.png":
+		h.serveQRCode(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (h handler) serveQRCode(w http.ResponseWriter, req *http.Request) {
+	link := h.links.TmeURL
+
+	switch req.URL.Query().Get("link") {
+	case "", "tme":
+	case "tg":
+		link = h.links.TgURL
+	default:
+		http.Error(w, "unknown link, expected tg or tme", http.StatusBadRequest)
+
+		return
+	}
+
+	code, err := qr.Encode(link, qr.M)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(code.PNG()) //nolint: errcheck
+}
+
+// NewHandler returns an HTTP handler which serves links. A client has to
+// present a header 'Authorization: Bearer <token>'.
+//
+//   - GET / returns links as JSON.
+//   - GET /qr.png returns a QR code of https://t.me link as PNG image.
+//     /qr.png?link=tg returns a QR code of tg:// link.
+func NewHandler(links Links, token string) http.Handler {
+	return handler{
+		links: links,
+		token: token,
+	}
+}
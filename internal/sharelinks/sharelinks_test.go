@@ -0,0 +1,90 @@
+package sharelinks_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/sharelinks"
+	"github.com/stretchr/testify/suite"
+)
+
+const testToken = "0123456789abcdef"
+
+type SharelinksTestSuite struct {
+	suite.Suite
+
+	links sharelinks.Links
+}
+
+func (suite *SharelinksTestSuite) SetupSuite() {
+	suite.links = sharelinks.MakeLinks("proxy.example.com", 443, "7hBO-dP7A8Ux3xZtuBF9-Y9zdG9yYWdlLmdvb2dsZWFwaXMuY29t")
+}
+
+func (suite *SharelinksTestSuite) serve(path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	sharelinks.NewHandler(suite.links, testToken).ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (suite *SharelinksTestSuite) TestMakeLinks() {
+	query := "port=443&secret=7hBO-dP7A8Ux3xZtuBF9-Y9zdG9yYWdlLmdvb2dsZWFwaXMuY29t&server=proxy.example.com"
+
+	suite.Equal("tg://proxy?"+query, suite.links.TgURL)
+	suite.Equal("https://t.me/proxy?"+query, suite.links.TmeURL)
+}
+
+func (suite *SharelinksTestSuite) TestUnauthorized() {
+	suite.Equal(http.StatusUnauthorized, suite.serve("/", "").Code)
+	suite.Equal(http.StatusUnauthorized, suite.serve("/", "wrong-token-wrong-token").Code)
+	suite.Equal(http.StatusUnauthorized, suite.serve("/qr.png", "").Code)
+}
+
+func (suite *SharelinksTestSuite) TestMethodNotAllowed() {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rec := httptest.NewRecorder()
+	sharelinks.NewHandler(suite.links, testToken).ServeHTTP(rec, req)
+
+	suite.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func (suite *SharelinksTestSuite) TestLinks() {
+	rec := suite.serve("/", testToken)
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("no-store", rec.Header().Get("Cache-Control"))
+
+	links := sharelinks.Links{}
+	suite.NoError(json.NewDecoder(rec.Body).Decode(&links))
+	suite.Equal(suite.links, links)
+}
+
+func (suite *SharelinksTestSuite) TestQRCode() {
+	for _, path := range []string{"/qr.png", "/qr.png?link=tme", "/qr.png?link=tg"} {
+		rec := suite.serve(path, testToken)
+		suite.Equal(http.StatusOK, rec.Code, path)
+		suite.Equal("image/png", rec.Header().Get("Content-Type"), path)
+
+		_, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+		suite.NoError(err, path)
+	}
+
+	suite.Equal(http.StatusBadRequest, suite.serve("/qr.png?link=unknown", testToken).Code)
+	suite.Equal(http.StatusNotFound, suite.serve("/unknown", testToken).Code)
+}
+
+func TestSharelinks(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &SharelinksTestSuite{})
+}
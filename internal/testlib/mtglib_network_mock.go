@@ -35,6 +35,15 @@ func (m *MtglibNetworkMock) GetDNSCacheMetrics() (uint64, uint64, uint64, int) {
 	return args.Get(0).(uint64), args.Get(1).(uint64), args.Get(2).(uint64), args.Int(3) //nolint: forcetypeassert
 }
 
+func (m *MtglibNetworkMock) GetDNSSkippedQueries() (uint64, uint64) {
+	args := m.Called()
+	return args.Get(0).(uint64), args.Get(1).(uint64) //nolint: forcetypeassert
+}
+
+func (m *MtglibNetworkMock) GetDoHLimitedQueries() uint64 {
+	return m.Called().Get(0).(uint64) //nolint: forcetypeassert
+}
+
 func (m *MtglibNetworkMock) WarmUp(hostnames []string) {
 	m.Called(hostnames)
 }
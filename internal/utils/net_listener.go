@@ -40,6 +40,17 @@ func NewListener(bindTo string, bufferSize int) (net.Listener, error) {
 
 // NewListenerWithTFO создаёт TCP listener с опциональной поддержкой TFO.
 func NewListenerWithTFO(bindTo string, bufferSize int, enableTFO bool) (net.Listener, error) {
+	return NewListenerWithBacklog(bindTo, bufferSize, enableTFO, 0, false)
+}
+
+// NewListenerWithBacklog создаёт TCP listener с опциональной поддержкой TFO
+// и заданным размером accept-очереди. backlog=0 — системное значение
+// (net.core.somaxconn). Ядро ограничивает значение сверху somaxconn.
+// reusePort включает SO_REUSEPORT, чтобы на этот адрес мог встать
+// новый процесс при rolling restart.
+func NewListenerWithBacklog(bindTo string, bufferSize int, enableTFO bool, backlog int,
+	reusePort bool,
+) (net.Listener, error) {
 	var base net.Listener
 	var err error
 	var tfoActive bool
@@ -49,6 +60,8 @@ func NewListenerWithTFO(bindTo string, bufferSize int, enableTFO bool) (net.List
 			Enabled:  true,
 			QueueLen: network.DefaultTFOQueueLen,
 			Fallback: true, // Всегда fallback на обычный listener
+
+			ReusePort: reusePort,
 		}
 		base, err = network.ListenTFO("tcp", bindTo, config)
 		if err != nil {
@@ -57,15 +70,20 @@ func NewListenerWithTFO(bindTo string, bufferSize int, enableTFO bool) (net.List
 		// Проверяем, действительно ли TFO включился
 		tfoActive = network.IsTFOServerEnabled()
 	} else {
-		base, err = net.Listen("tcp", bindTo)
+		base, err = network.Listen("tcp", bindTo, reusePort)
 		if err != nil {
 			return nil, fmt.Errorf("cannot build a base listener: %w", err)
 		}
 	}
 
+	if err := network.SetListenBacklog(base, backlog); err != nil {
+		base.Close()
+
+		return nil, fmt.Errorf("cannot set listen backlog: %w", err)
+	}
+
 	return Listener{
 		Listener:   base,
 		tfoEnabled: tfoActive,
 	}, nil
 }
-
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrPIDFileIsEmpty is returned by ReadPIDFile if a file has no PID.
+var ErrPIDFileIsEmpty = errors.New("pid file is empty")
+
+type pidFile struct {
+	path string
+	file *os.File
+}
+
+// Close удаляет свой PID файл и отпускает блокировку.
+func (p pidFile) Close() error {
+	p.file.Close()
+
+	return RemovePIDFile(p.path)
+}
+
+// WritePIDFile записывает PID текущего процесса. Запись атомарная:
+// процесс, который читает файл при takeover, не увидит половину числа.
+//
+// Пока возвращённый Closer не закрыт, на файле держится flock: так
+// следующий процесс отличает живой mtg от PID, оставшегося после краша
+// и, возможно, уже занятого чужим процессом. Please see PIDFileLocked.
+func WritePIDFile(path string) (io.Closer, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create pid file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name()) //nolint: errcheck
+
+	if err := lockPIDFile(tmp); err != nil {
+		tmp.Close()
+
+		return nil, fmt.Errorf("cannot lock pid file: %w", err)
+	}
+
+	if _, err := tmp.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err != nil {
+		tmp.Close()
+
+		return nil, fmt.Errorf("cannot write pid file: %w", err)
+	}
+
+	if err := tmp.Chmod(0o644); err != nil { //nolint: gomnd
+		tmp.Close()
+
+		return nil, fmt.Errorf("cannot chmod pid file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		tmp.Close()
+
+		return nil, fmt.Errorf("cannot write pid file: %w", err)
+	}
+
+	return pidFile{
+		path: path,
+		file: tmp,
+	}, nil
+}
+
+// ReadPIDFile возвращает PID из файла.
+func ReadPIDFile(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read pid file: %w", err)
+	}
+
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return 0, ErrPIDFileIsEmpty
+	}
+
+	pid, err := strconv.Atoi(value)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("incorrect pid %q in pid file", value)
+	}
+
+	return pid, nil
+}
+
+// RemovePIDFile удаляет файл, только если в нём PID текущего процесса:
+// после takeover там уже PID нового процесса, и его трогать нельзя.
+func RemovePIDFile(path string) error {
+	if pid, err := ReadPIDFile(path); err != nil || pid != os.Getpid() {
+		return nil //nolint: nilerr
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("cannot remove pid file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockPIDFile берёт эксклюзивный flock. Он живёт, пока файл открыт, и
+// снимается ядром при любом выходе процесса, даже после SIGKILL.
+func lockPIDFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) //nolint: wrapcheck
+}
+
+// PIDFileLocked reports if a process which has written a pid file with
+// WritePIDFile is still running. A pid file left after a crash is not
+// locked, so its PID may belong to an unrelated process.
+func PIDFileLocked(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("cannot open pid file: %w", err)
+	}
+
+	defer file.Close()
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+
+	switch {
+	case errors.Is(err, syscall.EWOULDBLOCK):
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("cannot check pid file lock: %w", err)
+	}
+
+	return false, nil
+}
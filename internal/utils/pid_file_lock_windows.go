@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import "os"
+
+func lockPIDFile(_ *os.File) error {
+	return nil
+}
+
+// PIDFileLocked always returns false on Windows: takeover is not
+// supported there.
+func PIDFileLocked(_ string) (bool, error) {
+	return false, nil
+}
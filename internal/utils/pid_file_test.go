@@ -0,0 +1,87 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/utils"
+	"github.com/stretchr/testify/suite"
+)
+
+type PIDFileTestSuite struct {
+	suite.Suite
+
+	path string
+}
+
+func (suite *PIDFileTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "mtg.pid")
+}
+
+func (suite *PIDFileTestSuite) TestWriteRead() {
+	pidFile, err := utils.WritePIDFile(suite.path)
+	suite.NoError(err)
+
+	defer pidFile.Close()
+
+	pid, err := utils.ReadPIDFile(suite.path)
+	suite.NoError(err)
+	suite.Equal(os.Getpid(), pid)
+
+	entries, err := os.ReadDir(filepath.Dir(suite.path))
+	suite.NoError(err)
+	suite.Len(entries, 1)
+}
+
+func (suite *PIDFileTestSuite) TestReadIncorrect() {
+	_, err := utils.ReadPIDFile(suite.path)
+	suite.ErrorIs(err, os.ErrNotExist)
+
+	suite.NoError(os.WriteFile(suite.path, []byte("\n"), 0o600))
+
+	_, err = utils.ReadPIDFile(suite.path)
+	suite.ErrorIs(err, utils.ErrPIDFileIsEmpty)
+
+	suite.NoError(os.WriteFile(suite.path, []byte("mtg"), 0o600))
+
+	_, err = utils.ReadPIDFile(suite.path)
+	suite.Error(err)
+}
+
+func (suite *PIDFileTestSuite) TestRemoveOwnOnly() {
+	otherPID := strconv.Itoa(os.Getpid() + 1)
+	suite.NoError(os.WriteFile(suite.path, []byte(otherPID), 0o600))
+
+	suite.NoError(utils.RemovePIDFile(suite.path))
+	suite.FileExists(suite.path)
+
+	pidFile, err := utils.WritePIDFile(suite.path)
+	suite.NoError(err)
+	suite.NoError(pidFile.Close())
+	suite.NoFileExists(suite.path)
+}
+
+func (suite *PIDFileTestSuite) TestLocked() {
+	pidFile, err := utils.WritePIDFile(suite.path)
+	suite.NoError(err)
+
+	locked, err := utils.PIDFileLocked(suite.path)
+	suite.NoError(err)
+	suite.True(locked)
+
+	suite.NoError(pidFile.Close())
+
+	// Файл, оставшийся после краша: PID есть, блокировки нет.
+	suite.NoError(os.WriteFile(suite.path, []byte(strconv.Itoa(os.Getpid())), 0o600))
+
+	locked, err = utils.PIDFileLocked(suite.path)
+	suite.NoError(err)
+	suite.False(locked)
+}
+
+func TestPIDFile(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &PIDFileTestSuite{})
+}
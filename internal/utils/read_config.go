@@ -1,14 +1,39 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/9seconds/mtg/v2/internal/config"
 )
 
+const (
+	// ConfigStdin is a special config path which means that config should
+	// be read from standard input.
+	ConfigStdin = "-"
+
+	// RemoteConfigTimeout is a timeout for fetching a config from URL.
+	RemoteConfigTimeout = 10 * time.Second
+
+	// MaxConfigSize is the maximal size of config read from stdin or URL.
+	MaxConfigSize = 1024 * 1024
+
+	maxConfigRedirects = 5
+)
+
+var errConfigTooLarge = errors.New("config is too large")
+
+// ReadConfig reads, parses and validates a config. path could be a path to
+// a file, "-" for stdin or http(s) URL. Remote config is fetched only
+// once: there is no reloading.
 func ReadConfig(path string) (*config.Config, error) {
-	content, err := os.ReadFile(path)
+	content, err := readConfigContent(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
@@ -24,3 +49,70 @@ func ReadConfig(path string) (*config.Config, error) {
 
 	return conf, nil
 }
+
+func readConfigContent(path string) ([]byte, error) {
+	switch {
+	case path == ConfigStdin:
+		return readLimited(os.Stdin)
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return fetchConfig(path)
+	}
+
+	return os.ReadFile(path) //nolint: wrapcheck
+}
+
+func fetchConfig(value string) ([]byte, error) {
+	parsedURL, err := config.ParseRemoteURL(value)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect config url: %w", err)
+	}
+
+	client := &http.Client{
+		Timeout: RemoteConfigTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxConfigRedirects {
+				return fmt.Errorf("too many redirects (%d)", len(via))
+			}
+
+			if _, err := config.ParseRemoteURL(req.URL.String()); err != nil {
+				return fmt.Errorf("incorrect redirect: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RemoteConfigTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch config: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return readLimited(resp.Body)
+}
+
+func readLimited(reader io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(reader, MaxConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read: %w", err)
+	}
+
+	if len(content) > MaxConfigSize {
+		return nil, errConfigTooLarge
+	}
+
+	return content, nil
+}
@@ -5,6 +5,7 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -24,3 +25,47 @@ func RootContext() context.Context {
 
 	return ctx
 }
+
+// DrainContext is done when a process gets SIGUSR1: a request to stop
+// accepting new connections before a shutdown.
+func DrainContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+
+	signal.Notify(sigChan, syscall.SIGUSR1)
+
+	go func() {
+		for range sigChan {
+			cancel()
+		}
+	}()
+
+	return ctx
+}
+
+// TakeoverContext is done when a process gets SIGUSR2: a new process has
+// taken over a listen address, so this one has to drain and exit.
+func TakeoverContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+
+	signal.Notify(sigChan, syscall.SIGUSR2)
+
+	go func() {
+		for range sigChan {
+			cancel()
+		}
+	}()
+
+	return ctx
+}
+
+// SignalTakeover asks a process with a given PID to drain and exit.
+// Please see TakeoverContext.
+func SignalTakeover(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGUSR2); err != nil {
+		return fmt.Errorf("cannot signal process %d: %w", pid, err)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 )
@@ -22,3 +23,18 @@ func RootContext() context.Context {
 
 	return ctx
 }
+
+// DrainContext is never done on Windows: there is no SIGUSR1.
+func DrainContext() context.Context {
+	return context.Background()
+}
+
+// TakeoverContext is never done on Windows: there is no SIGUSR2.
+func TakeoverContext() context.Context {
+	return context.Background()
+}
+
+// SignalTakeover is not supported on Windows.
+func SignalTakeover(_ int) error {
+	return errors.New("takeover is not supported on Windows")
+}
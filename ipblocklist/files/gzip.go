@@ -0,0 +1,83 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxDecompressedSize is a limit for a size of decompressed gzip file.
+// Files which inflate above it are rejected, so a malicious or broken list
+// cannot exhaust memory or disk (gzip bomb). It is the same as
+// maxBlocklistSize in ipblocklist/firehol.go, a limit Firehol applies to
+// a downloaded list.
+const MaxDecompressedSize = 100 * 1024 * 1024 // 100 mib
+
+// ErrDecompressedTooLarge is returned if decompressed file exceeds
+// MaxDecompressedSize.
+var ErrDecompressedTooLarge = errors.New("decompressed file is too large")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type gzipReadCloser struct {
+	reader    *gzip.Reader
+	source    io.Closer
+	remaining int64
+}
+
+func (g *gzipReadCloser) Read(p []byte) (int, error) {
+	if g.remaining <= 0 {
+		// лимит исчерпан: если за ним есть хоть один байт, файл
+		// слишком большой, иначе это честный конец.
+		if _, err := io.ReadFull(g.reader, make([]byte, 1)); err == nil {
+			return 0, ErrDecompressedTooLarge
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > g.remaining {
+		p = p[:g.remaining]
+	}
+
+	n, err := g.reader.Read(p)
+	g.remaining -= int64(n)
+
+	return n, err //nolint: wrapcheck
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.reader.Close() //nolint: errcheck
+
+	return g.source.Close() //nolint: wrapcheck
+}
+
+// newGzipReadCloser transparently decompresses gzip content of source. If
+// source does not start with gzip magic bytes, it is returned as is: HTTP
+// transport may have already decompressed it.
+func newGzipReadCloser(source io.ReadCloser, limit int64) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(source)
+
+	if magic, _ := buffered.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return readCloser{Reader: buffered, Closer: source}, nil
+	}
+
+	reader, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read gzip header: %w", err)
+	}
+
+	return &gzipReadCloser{
+		reader:    reader,
+		source:    source,
+		remaining: limit,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) io.ReadCloser {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return io.NopCloser(buf)
+}
+
+func TestGzipReadCloserPlain(t *testing.T) {
+	t.Parallel()
+
+	reader, err := newGzipReadCloser(io.NopCloser(strings.NewReader("10.0.0.0/8\n")), 1024)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8\n", string(data))
+}
+
+func TestGzipReadCloserExactLimit(t *testing.T) {
+	t.Parallel()
+
+	reader, err := newGzipReadCloser(gzipBytes(t, make([]byte, 1024)), 1024)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Len(t, data, 1024)
+}
+
+func TestGzipReadCloserBomb(t *testing.T) {
+	t.Parallel()
+
+	reader, err := newGzipReadCloser(gzipBytes(t, make([]byte, 1024*1024)), 1024)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	assert.ErrorIs(t, err, ErrDecompressedTooLarge)
+	assert.Len(t, data, 1024)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type httpFile struct {
@@ -30,10 +31,36 @@ func (h httpFile) Open(ctx context.Context) (io.ReadCloser, error) {
 	}
 
 	if response.StatusCode >= http.StatusBadRequest {
+		response.Body.Close()
+
 		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
 	}
 
-	return response.Body, nil
+	if !h.isGzipped(response) {
+		return response.Body, nil
+	}
+
+	body, err := newGzipReadCloser(response.Body, MaxDecompressedSize)
+	if err != nil {
+		response.Body.Close()
+
+		return nil, fmt.Errorf("cannot decompress %s: %w", h.url, err)
+	}
+
+	return body, nil
+}
+
+// isGzipped detects gzipped lists: either by Content-Encoding (if HTTP
+// transport has not decompressed a body on its own) or by .gz suffix of
+// the URL path, because static .gz files are usually served without any
+// Content-Encoding.
+func (h httpFile) isGzipped(response *http.Response) bool {
+	if !response.Uncompressed &&
+		strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		return true
+	}
+
+	return strings.HasSuffix(strings.ToLower(response.Request.URL.Path), ".gz")
 }
 
 func (h httpFile) String() string {
@@ -42,6 +69,9 @@ func (h httpFile) String() string {
 
 // NewHTTP returns a file abstraction for HTTP/HTTPS endpoint. You also need to
 // provide a valid instance of [http.Client] to access it.
+//
+// Gzipped endpoints (Content-Encoding: gzip or .gz URLs) are decompressed
+// transparently, up to MaxDecompressedSize.
 func NewHTTP(client *http.Client, endpoint string) (File, error) {
 	if client == nil {
 		return nil, ErrBadHTTPClient
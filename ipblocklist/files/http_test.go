@@ -1,6 +1,8 @@
 package files_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"io"
 	"net/http"
@@ -29,6 +31,17 @@ func (suite *HTTPTestSuite) SetupSuite() {
 	mux := http.NewServeMux()
 
 	mux.Handle("/", http.FileServer(http.Dir("testdata")))
+	mux.HandleFunc("/list.gz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(gzipData("Hooray!\n")) //nolint: errcheck
+	})
+	mux.HandleFunc("/encoded", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipData("Hooray!\n")) //nolint: errcheck
+	})
+	mux.HandleFunc("/broken.gz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte{0x1f, 0x8b, 0x00}) //nolint: errcheck
+	})
 
 	suite.httpServer = httptest.NewServer(mux)
 	suite.httpClient = suite.httpServer.Client()
@@ -84,6 +97,62 @@ func (suite *HTTPTestSuite) TestOk() {
 	suite.Equal("Hooray!", strings.TrimSpace(string(data)))
 }
 
+func (suite *HTTPTestSuite) TestGzip() {
+	for _, v := range []string{"list.gz", "encoded"} {
+		path := v
+
+		suite.T().Run(path, func(t *testing.T) {
+			file, err := suite.makeFile(path)
+			suite.NoError(err)
+
+			readCloser, err := file.Open(suite.ctx)
+			suite.NoError(err)
+
+			defer readCloser.Close()
+
+			data, err := io.ReadAll(readCloser)
+			suite.NoError(err)
+			suite.Equal("Hooray!", strings.TrimSpace(string(data)))
+		})
+	}
+}
+
+func (suite *HTTPTestSuite) TestGzipContentEncodingWithoutTransport() {
+	// default transport decompresses a response on its own, so here we
+	// check our own Content-Encoding handling.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	file, err := files.NewHTTP(client, suite.httpServer.URL+"/encoded")
+	suite.NoError(err)
+
+	readCloser, err := file.Open(suite.ctx)
+	suite.NoError(err)
+
+	defer readCloser.Close()
+
+	data, err := io.ReadAll(readCloser)
+	suite.NoError(err)
+	suite.Equal("Hooray!", strings.TrimSpace(string(data)))
+}
+
+func (suite *HTTPTestSuite) TestBrokenGzip() {
+	file, err := suite.makeFile("broken.gz")
+	suite.NoError(err)
+
+	_, err = file.Open(suite.ctx)
+	suite.Error(err)
+}
+
+func gzipData(text string) []byte {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+
+	writer.Write([]byte(text)) //nolint: errcheck
+	writer.Close()
+
+	return buf.Bytes()
+}
+
 func TestHTTP(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &HTTPTestSuite{})
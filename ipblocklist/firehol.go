@@ -34,6 +34,29 @@ var (
 	fireholIPv6DefaultCIDR = net.CIDRMask(128, 128) //nolint: gomnd
 )
 
+// FireholMatch is an entry of a list which contains a checked IP
+// address. Please see Firehol.Match.
+type FireholMatch struct {
+	// Network is a network of a list entry. Single IP addresses are
+	// networks with a full mask.
+	Network net.IPNet
+
+	// Source is a file or an URL the entry was loaded from.
+	Source string
+}
+
+// fireholEntry — запись в prefix tree с источником: по нему Match
+// сообщает, какой список сработал. Строка источника общая на весь файл,
+// так что на запись тратится только заголовок строки.
+type fireholEntry struct {
+	network net.IPNet
+	source  string
+}
+
+func (e fireholEntry) Network() net.IPNet {
+	return e.network
+}
+
 // FireholUpdateCallback defines a signature of the callback that has to be
 // execute when ip list is updated.
 type FireholUpdateCallback func(context.Context, int)
@@ -51,6 +74,10 @@ type FireholUpdateCallback func(context.Context, int)
 //	# to ignore
 //	127.0.0.1   # you can specify an IP
 //	10.0.0.0/8  # or cidr
+//
+// Entries are stored in a prefix tree as is, ranges are never expanded
+// into individual addresses. So memory is proportional to a number of
+// lines and a lookup costs O(prefix length) regardless of a list size.
 type Firehol struct {
 	ctx         context.Context
 	ctxCancel   context.CancelFunc
@@ -66,7 +93,8 @@ type Firehol struct {
 	workerPool *ants.Pool
 }
 
-// Shutdown stop a background update process.
+// Shutdown stop a background update process. In-flight downloads are
+// cancelled and a current list stays as is.
 func (f *Firehol) Shutdown() {
 	f.ctxCancel()
 }
@@ -94,6 +122,49 @@ func (f *Firehol) Contains(ip net.IP) bool {
 	return ok && err == nil
 }
 
+// Match returns all entries of lists which contain a given IP address.
+// An empty result means that Contains returns false.
+//
+// It is intended for diagnostics: Contains is cheaper.
+func (f *Firehol) Match(ip net.IP) ([]FireholMatch, error) {
+	if ip == nil {
+		return nil, fmt.Errorf("ip is not defined")
+	}
+
+	f.updateMutex.RLock()
+	defer f.updateMutex.RUnlock()
+
+	entries, err := f.ranger.ContainingNetworks(ip)
+	if err != nil {
+		return nil, fmt.Errorf("cannot match ip %v: %w", ip, err)
+	}
+
+	matches := make([]FireholMatch, 0, len(entries))
+
+	for _, entry := range entries {
+		match := FireholMatch{
+			Network: entry.Network(),
+		}
+
+		if value, ok := entry.(fireholEntry); ok {
+			match.Source = value.source
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// Update loads all lists once and replaces a current one. If update
+// was not complete, a previous list is kept. Run does the same
+// periodically.
+//
+// This is a blocking method.
+func (f *Firehol) Update() {
+	f.update()
+}
+
 // Run starts a background update process.
 //
 // This is a blocking method so you probably want to run it in a goroutine.
@@ -141,7 +212,7 @@ func (f *Firehol) update() {
 
 			logger := f.logger.BindStr("filename", file.String())
 
-			if err := f.updateFromSource(ctx, mutex, ranger, file, logger); err != nil {
+			if err := f.updateFromSource(ctx, mutex, ranger, file, logger); err != nil && ctx.Err() == nil {
 				logger.WarningError("update has failed", err)
 			}
 		}(v)
@@ -149,6 +220,14 @@ func (f *Firehol) update() {
 
 	wg.Wait()
 
+	// Shutdown посреди обновления: новый список неполон, оставляем
+	// прежний.
+	if ctx.Err() != nil {
+		f.logger.Info("ip list update was cancelled, previous list is kept")
+
+		return
+	}
+
 	f.updateMutex.Lock()
 	defer f.updateMutex.Unlock()
 
@@ -175,7 +254,7 @@ func (f *Firehol) updateFromSource(ctx context.Context,
 
 		defer fileContent.Close()
 
-		return f.updateFromFile(mutex, ranger, bufio.NewScanner(fileContent))
+		return f.updateFromFile(mutex, ranger, file.String(), bufio.NewScanner(fileContent))
 	}
 
 	return f.updateFromRemoteWithCache(ctx, mutex, ranger, file, logger)
@@ -203,7 +282,7 @@ func (f *Firehol) updateFromRemoteWithCache(ctx context.Context,
 		return f.updateFromCache(ctx, mutex, ranger, file, logger, err)
 	}
 
-	err = f.updateFromFile(mutex, ranger, bufio.NewScanner(tmpFile))
+	err = f.updateFromFile(mutex, ranger, file.String(), bufio.NewScanner(tmpFile))
 	tmpFile.Close()
 
 	if err != nil {
@@ -224,6 +303,11 @@ func (f *Firehol) updateFromCache(ctx context.Context,
 	logger mtglib.Logger,
 	reason error,
 ) error {
+	// Отмена — не сбой источника, кэш тут не поможет.
+	if ctx.Err() != nil {
+		return reason
+	}
+
 	cacheFile, err := os.Open(f.cachePath(file))
 	if err != nil {
 		return fmt.Errorf("cannot update from remote and cache is unavailable: %w", reason)
@@ -235,7 +319,7 @@ func (f *Firehol) updateFromCache(ctx context.Context,
 		f.cacheFallbackCallback(ctx)
 	}
 
-	if err := f.updateFromFile(mutex, ranger, bufio.NewScanner(cacheFile)); err != nil {
+	if err := f.updateFromFile(mutex, ranger, file.String(), bufio.NewScanner(cacheFile)); err != nil {
 		return fmt.Errorf("cannot update from remote and cached snapshot is invalid: %w", err)
 	}
 
@@ -298,6 +382,7 @@ func isRemoteBlocklist(file files.File) bool {
 
 func (f *Firehol) updateFromFile(mutex sync.Locker,
 	ranger cidranger.Ranger,
+	source string,
 	scanner *bufio.Scanner,
 ) error {
 	for scanner.Scan() {
@@ -315,7 +400,7 @@ func (f *Firehol) updateFromFile(mutex sync.Locker,
 		}
 
 		mutex.Lock()
-		err = ranger.Insert(cidranger.NewBasicRangerEntry(*ipnet))
+		err = ranger.Insert(fireholEntry{network: *ipnet, source: source})
 		mutex.Unlock()
 
 		if err != nil {
@@ -356,6 +441,10 @@ func (f *Firehol) updateParseLine(text string) (*net.IPNet, error) {
 //
 // This method does not start an update process so please execute Run when it
 // is necessary.
+//
+// Remote lists are downloaded with an HTTP client of the network, so each
+// download is limited by its timeout (network.timeout.http in mtg
+// config). Shutdown cancels downloads which are in progress.
 func NewFirehol(logger mtglib.Logger, network mtglib.Network,
 	downloadConcurrency uint,
 	urls []string,
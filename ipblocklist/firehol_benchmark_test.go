@@ -0,0 +1,93 @@
+package ipblocklist_test
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/ipblocklist"
+	"github.com/9seconds/mtg/v2/ipblocklist/files"
+	"github.com/9seconds/mtg/v2/logger"
+)
+
+// benchmarkNetworks генерирует count непересекающихся диапазонов /24
+// подряд, начиная с 10.0.0.0/24 - так выглядят списки по странам.
+func benchmarkNetworks(count int) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, count)
+	mask := net.CIDRMask(24, 32) //nolint: gomnd
+
+	for i := 0; i < count; i++ {
+		networks = append(networks, &net.IPNet{
+			IP:   net.IPv4(byte(10+i>>16), byte(i>>8), byte(i), 0).To4(),
+			Mask: mask,
+		})
+	}
+
+	return networks
+}
+
+func benchmarkIPs(networks []*net.IPNet) []net.IP {
+	rnd := rand.New(rand.NewSource(1)) //nolint: gosec
+	ips := make([]net.IP, 1024)
+
+	for i := range ips {
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, networks[rnd.Intn(len(networks))].IP)
+
+		if i%2 == 0 {
+			ip[0] = 192 // промах: вне всех диапазонов
+		}
+
+		ip[3] = byte(rnd.Intn(256)) //nolint: gomnd
+		ips[i] = ip
+	}
+
+	return ips
+}
+
+func BenchmarkFireholContains(b *testing.B) {
+	for _, count := range []int{1000, 10000, 100000} {
+		networks := benchmarkNetworks(count)
+		ips := benchmarkIPs(networks)
+
+		blocklist, err := ipblocklist.NewFireholFromFiles(logger.NewNoopLogger(),
+			1, []files.File{files.NewMem(networks)}, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		go blocklist.Run(time.Hour)
+
+		for !blocklist.Contains(networks[len(networks)-1].IP) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		b.Run(fmt.Sprintf("radix-%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				blocklist.Contains(ips[i%len(ips)])
+			}
+		})
+
+		// Линейный перебор - то, во что превращается список без
+		// префиксного дерева. Только для сравнения.
+		b.Run(fmt.Sprintf("linear-%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				ip := ips[i%len(ips)]
+
+				for _, network := range networks {
+					if network.Contains(ip) {
+						break
+					}
+				}
+			}
+		})
+
+		blocklist.Shutdown()
+	}
+}
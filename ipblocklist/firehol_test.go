@@ -1,6 +1,7 @@
 package ipblocklist_test
 
 import (
+	"context"
 	"io"
 	"net"
 	"net/http"
@@ -96,12 +97,47 @@ func (suite *FireholTestSuite) TestLocalOk() {
 	time.Sleep(500 * time.Millisecond)
 
 	suite.True(blocklist.Contains(net.ParseIP("10.0.0.10")))
+	suite.True(blocklist.Contains(net.ParseIP("10.1.0.77")))
+	suite.False(blocklist.Contains(net.ParseIP("10.1.1.1")))
 	suite.False(blocklist.Contains(net.ParseIP("127.0.0.1")))
 
 	blocklist.Shutdown()
 	time.Sleep(500 * time.Millisecond)
 }
 
+func (suite *FireholTestSuite) TestMatch() {
+	path := filepath.Join("testdata", "good_ipset.ipset")
+
+	blocklist, err := ipblocklist.NewFirehol(logger.NewNoopLogger(),
+		suite.networkMock, 2,
+		nil, []string{path},
+		nil)
+
+	suite.NoError(err)
+
+	blocklist.Update()
+
+	matches, err := blocklist.Match(net.ParseIP("10.1.0.77"))
+	suite.NoError(err)
+	suite.Len(matches, 1)
+	suite.Equal("10.1.0.0/24", matches[0].Network.String())
+	suite.Contains(matches[0].Source, path)
+
+	matches, err = blocklist.Match(net.ParseIP("10.0.0.10"))
+	suite.NoError(err)
+	suite.Len(matches, 1)
+	suite.Equal("10.0.0.10/32", matches[0].Network.String())
+
+	matches, err = blocklist.Match(net.ParseIP("127.0.0.1"))
+	suite.NoError(err)
+	suite.Empty(matches)
+
+	_, err = blocklist.Match(nil)
+	suite.Error(err)
+
+	blocklist.Shutdown()
+}
+
 func (suite *FireholTestSuite) TestRemoteFail() {
 	blocklist, err := ipblocklist.NewFirehol(logger.NewNoopLogger(),
 		suite.networkMock, 2,
@@ -185,6 +221,68 @@ func (suite *FireholTestSuite) TestRemoteCacheFallback() {
 	time.Sleep(500 * time.Millisecond)
 }
 
+func (suite *FireholTestSuite) TestShutdownDuringDownload() {
+	requests := 0
+	stalled := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shutdown.ipset", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+
+		if requests == 1 {
+			_, _ = io.WriteString(w, "10.9.9.9\n")
+
+			return
+		}
+
+		_, _ = io.WriteString(w, "10.7.7.7\n")
+		w.(http.Flusher).Flush()
+
+		close(stalled)
+		<-req.Context().Done()
+	})
+
+	remoteServer := httptest.NewServer(mux)
+	defer remoteServer.Close()
+
+	dialer, _ := network.NewDefaultDialer(0, 0)
+	ntw, _ := network.NewNetwork(dialer, "mtg", "1.1.1.1", 0)
+
+	updates := 0
+	blocklist, err := ipblocklist.NewFirehol(logger.NewNoopLogger(),
+		ntw, 1,
+		[]string{remoteServer.URL + "/shutdown.ipset"}, nil,
+		func(_ context.Context, _ int) { updates++ })
+	suite.NoError(err)
+
+	done := make(chan struct{})
+
+	go func() {
+		blocklist.Run(100 * time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-stalled:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("second download has not started")
+	}
+
+	suite.True(blocklist.Contains(net.ParseIP("10.9.9.9")))
+
+	blocklist.Shutdown()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		suite.FailNow("shutdown is blocked by a download")
+	}
+
+	suite.True(blocklist.Contains(net.ParseIP("10.9.9.9")))
+	suite.False(blocklist.Contains(net.ParseIP("10.7.7.7")))
+	suite.Equal(1, updates)
+}
+
 func TestFirehol(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &FireholTestSuite{})
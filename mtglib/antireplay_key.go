@@ -0,0 +1,75 @@
+package mtglib
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+const (
+	antiReplayOwnerIPv4Bits = 24
+	antiReplayOwnerIPv6Bits = 64
+)
+
+// AntiReplayKey is a set of keys which are checked in [AntiReplayCache] for
+// a client hello.
+type AntiReplayKey struct {
+	// Session identifies a session. If it was seen before, a connection is
+	// considered a replay attack.
+	Session []byte
+
+	// Owner identifies a legitimate owner of the session. If it is not nil
+	// and was seen before, a connection is a reconnect of the same client
+	// and is not considered a replay attack, even if Session was seen.
+	Owner []byte
+}
+
+// AntiReplayKeyFunc builds antireplay keys for a session id of a client
+// hello, an IP address of the client and a current time.
+type AntiReplayKeyFunc func(sessionID []byte, clientIP net.IP, now time.Time) AntiReplayKey
+
+// AntiReplayKeySessionID keys antireplay cache purely on a session id. Any
+// repeated session is a replay attack. This is a default strategy.
+func AntiReplayKeySessionID(sessionID []byte, _ net.IP, _ time.Time) AntiReplayKey {
+	return AntiReplayKey{
+		Session: sessionID,
+	}
+}
+
+// NewAntiReplayKeySessionIP returns a strategy which lets the same client
+// repeat a session within a time window. An owner key is a session id,
+// truncated client IP (/24 for IPv4, /64 for IPv6) and a time bucket of
+// the window size.
+//
+// Replays from other networks are still detected. Replays from the same
+// network within the window are not: this is a price of avoiding false
+// positives for legitimate reconnects. A reconnect which crosses a bucket
+// boundary is still reported.
+func NewAntiReplayKeySessionIP(window time.Duration) AntiReplayKeyFunc {
+	if window <= 0 {
+		return AntiReplayKeySessionID
+	}
+
+	return func(sessionID []byte, clientIP net.IP, now time.Time) AntiReplayKey {
+		ip := clientIP.To4()
+		mask := net.CIDRMask(antiReplayOwnerIPv4Bits, 8*net.IPv4len) //nolint: gomnd
+
+		if ip == nil {
+			ip = clientIP.To16()
+			mask = net.CIDRMask(antiReplayOwnerIPv6Bits, 8*net.IPv6len) //nolint: gomnd
+		}
+
+		owner := make([]byte, 0, 1+len(sessionID)+len(ip)+8) //nolint: gomnd
+
+		// префикс не даёт owner-ключу совпасть с чьим-то Session
+		owner = append(owner, 'o')
+		owner = append(owner, sessionID...)
+		owner = append(owner, ip.Mask(mask)...)
+		owner = binary.BigEndian.AppendUint64(owner, uint64(now.UnixNano()/int64(window)))
+
+		return AntiReplayKey{
+			Session: sessionID,
+			Owner:   owner,
+		}
+	}
+}
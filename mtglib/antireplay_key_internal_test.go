@@ -0,0 +1,183 @@
+package mtglib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapAntiReplayCache map[string]struct{}
+
+func (m mapAntiReplayCache) SeenBefore(data []byte) bool {
+	_, ok := m[string(data)]
+	m[string(data)] = struct{}{}
+
+	return ok
+}
+
+func TestAntiReplayKeySessionID(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{
+		antiReplayKey: AntiReplayKeySessionID,
+	}
+	proxy.ReplaceAntiReplayCache(mapAntiReplayCache{})
+	sessionID := []byte{1, 2, 3}
+	clientIP := net.ParseIP("10.0.0.1")
+
+	assert.False(t, proxy.isReplayAttack(sessionID, clientIP))
+	assert.True(t, proxy.isReplayAttack(sessionID, clientIP))
+}
+
+func TestAntiReplayKeySessionIP(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]struct {
+		first  string
+		second string
+		replay bool
+	}{
+		"same ip":           {"10.0.0.1", "10.0.0.1", false},
+		"same ipv4 network": {"10.0.0.1", "10.0.0.200", false},
+		"other ipv4":        {"10.0.0.1", "10.0.1.1", true},
+		"same ipv6 network": {"2001:db8::1", "2001:db8::ffff", false},
+		"other ipv6":        {"2001:db8::1", "2001:db8:0:1::1", true},
+	}
+
+	for name, value := range testData {
+		value := value
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			proxy := &Proxy{
+				antiReplayKey: NewAntiReplayKeySessionIP(time.Hour),
+			}
+			proxy.ReplaceAntiReplayCache(mapAntiReplayCache{})
+			sessionID := []byte{1, 2, 3}
+
+			assert.False(t, proxy.isReplayAttack(sessionID, net.ParseIP(value.first)))
+			assert.Equal(t, value.replay, proxy.isReplayAttack(sessionID, net.ParseIP(value.second)))
+		})
+	}
+}
+
+func TestAntiReplayKeySessionIPBucket(t *testing.T) {
+	t.Parallel()
+
+	keyFunc := NewAntiReplayKeySessionIP(time.Minute)
+	sessionID := []byte{1, 2, 3}
+	clientIP := net.ParseIP("10.0.0.1")
+	now := time.Unix(600, 0)
+
+	assert.Equal(t,
+		keyFunc(sessionID, clientIP, now).Owner,
+		keyFunc(sessionID, clientIP, now.Add(59*time.Second)).Owner)
+	assert.NotEqual(t,
+		keyFunc(sessionID, clientIP, now).Owner,
+		keyFunc(sessionID, clientIP, now.Add(time.Minute)).Owner)
+	assert.Equal(t, sessionID, keyFunc(sessionID, clientIP, now).Session)
+	assert.Nil(t, NewAntiReplayKeySessionIP(0)(sessionID, clientIP, now).Owner)
+}
+
+func hmacAntiReplayDigest(key string) func([]byte) []byte {
+	return func(sessionID []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(sessionID)
+
+		return mac.Sum(nil)[:16]
+	}
+}
+
+func TestAntiReplayDigest(t *testing.T) {
+	t.Parallel()
+
+	cache := mapAntiReplayCache{}
+	first := &Proxy{
+		antiReplayKey:    AntiReplayKeySessionID,
+		antiReplayDigest: hmacAntiReplayDigest("first"),
+	}
+	second := &Proxy{
+		antiReplayKey:    AntiReplayKeySessionID,
+		antiReplayDigest: hmacAntiReplayDigest("second"),
+	}
+	identity := &Proxy{
+		antiReplayKey: AntiReplayKeySessionID,
+	}
+
+	first.ReplaceAntiReplayCache(cache)
+	second.ReplaceAntiReplayCache(cache)
+	identity.ReplaceAntiReplayCache(cache)
+
+	sessionID := []byte{1, 2, 3}
+	clientIP := net.ParseIP("10.0.0.1")
+
+	assert.False(t, first.isReplayAttack(sessionID, clientIP))
+	assert.False(t, second.isReplayAttack(sessionID, clientIP))
+	assert.False(t, identity.isReplayAttack(sessionID, clientIP))
+
+	assert.True(t, first.isReplayAttack(sessionID, clientIP))
+	assert.True(t, second.isReplayAttack(sessionID, clientIP))
+	assert.True(t, identity.isReplayAttack(sessionID, clientIP))
+
+	assert.Len(t, cache, 3)
+	assert.Contains(t, cache, string(sessionID))
+}
+
+type syncAntiReplayCache struct {
+	mutex sync.Mutex
+	seen  mapAntiReplayCache
+}
+
+func (s *syncAntiReplayCache) SeenBefore(data []byte) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.seen.SeenBefore(data)
+}
+
+func TestReplaceAntiReplayCache(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{
+		antiReplayKey: AntiReplayKeySessionID,
+	}
+	proxy.ReplaceAntiReplayCache(&syncAntiReplayCache{seen: mapAntiReplayCache{}})
+
+	clientIP := net.ParseIP("10.0.0.1")
+	wg := &sync.WaitGroup{}
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 1000 {
+				proxy.isReplayAttack([]byte{byte(i), byte(j >> 8), byte(j)}, clientIP)
+			}
+		}()
+	}
+
+	for range 100 {
+		proxy.ReplaceAntiReplayCache(&syncAntiReplayCache{seen: mapAntiReplayCache{}})
+	}
+
+	wg.Wait()
+
+	sessionID := []byte{1, 2, 3, 4}
+
+	assert.False(t, proxy.isReplayAttack(sessionID, clientIP))
+	assert.True(t, proxy.isReplayAttack(sessionID, clientIP))
+
+	proxy.ReplaceAntiReplayCache(&syncAntiReplayCache{seen: mapAntiReplayCache{}})
+	assert.False(t, proxy.isReplayAttack(sessionID, clientIP))
+
+	proxy.ReplaceAntiReplayCache(nil)
+	assert.True(t, proxy.isReplayAttack(sessionID, clientIP))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"sync"
 	"sync/atomic"
 
 	"github.com/9seconds/mtg/v2/essentials"
@@ -19,15 +20,23 @@ const (
 type connTraffic struct {
 	essentials.Conn
 
-	streamID string
-	stream   EventStream
-	ctx      context.Context
+	streamID      string
+	stream        EventStream
+	trafficStream TrafficEventStream
+	ctx           context.Context
 
 	// Атомарные аккумуляторы для батчинга EventTraffic.
 	// Pointer-based: connTraffic копируется через value receivers в обёртках,
 	// но все копии разделяют один и тот же accumulator.
 	readAcc  *atomic.Uint64
 	writeAcc *atomic.Uint64
+
+	// closed выставляется перед финальным flush: байты Read/Write,
+	// завершившихся после него, эмитятся сразу, а не теряются в
+	// аккумуляторе. closeOnce гарантирует единственный финальный flush
+	// при повторных Close (relay, streamContext, отмена контекста).
+	closed    *atomic.Bool
+	closeOnce *sync.Once
 }
 
 func (c connTraffic) Read(b []byte) (int, error) {
@@ -35,12 +44,12 @@ func (c connTraffic) Read(b []byte) (int, error) {
 
 	if n > 0 {
 		c.readAcc.Add(uint64(n))
-		if c.readAcc.Load() >= trafficFlushThreshold {
+		if c.readAcc.Load() >= trafficFlushThreshold || c.closed.Load() {
 			// Swap атомарно: забираем ВСЕ накопленные байты и обнуляем.
 			// Между Load() и Swap() другая goroutine может добавить байтов —
 			// они попадут в accumulated (не потеряются).
 			if accumulated := c.readAcc.Swap(0); accumulated > 0 {
-				c.stream.Send(c.ctx, NewEventTraffic(c.streamID, uint(accumulated), true))
+				c.sendTraffic(c.sendContext(), NewEventTraffic(c.streamID, uint(accumulated), true))
 			}
 		}
 	}
@@ -53,9 +62,9 @@ func (c connTraffic) Write(b []byte) (int, error) {
 
 	if n > 0 {
 		c.writeAcc.Add(uint64(n))
-		if c.writeAcc.Load() >= trafficFlushThreshold {
+		if c.writeAcc.Load() >= trafficFlushThreshold || c.closed.Load() {
 			if accumulated := c.writeAcc.Swap(0); accumulated > 0 {
-				c.stream.Send(c.ctx, NewEventTraffic(c.streamID, uint(accumulated), false))
+				c.sendTraffic(c.sendContext(), NewEventTraffic(c.streamID, uint(accumulated), false))
 			}
 		}
 	}
@@ -65,35 +74,80 @@ func (c connTraffic) Write(b []byte) (int, error) {
 
 // FlushTraffic эмитит оставшийся накопленный трафик.
 func (c connTraffic) FlushTraffic() {
+	ctx := c.sendContext()
+
 	if r := c.readAcc.Swap(0); r > 0 {
-		c.stream.Send(c.ctx, NewEventTraffic(c.streamID, uint(r), true))
+		c.sendTraffic(ctx, NewEventTraffic(c.streamID, uint(r), true))
 	}
 
 	if w := c.writeAcc.Swap(0); w > 0 {
-		c.stream.Send(c.ctx, NewEventTraffic(c.streamID, uint(w), false))
+		c.sendTraffic(ctx, NewEventTraffic(c.streamID, uint(w), false))
+	}
+}
+
+// sendTraffic отправляет EventTraffic через SendTraffic, если стрим его
+// поддерживает: так событие не упаковывается в интерфейс.
+func (c connTraffic) sendTraffic(ctx context.Context, evt EventTraffic) {
+	if c.trafficStream != nil {
+		c.trafficStream.SendTraffic(ctx, evt)
+
+		return
 	}
+
+	c.stream.Send(ctx, evt)
+}
+
+// sendContext возвращает контекст для отправки EventTraffic. После
+// закрытия контекст стрима уже отменён (shutdown, idle timeout, ошибка),
+// и Send отбросил бы событие — поэтому отмена отвязывается.
+func (c connTraffic) sendContext() context.Context {
+	if c.closed.Load() {
+		return context.WithoutCancel(c.ctx)
+	}
+
+	return c.ctx
 }
 
 // Close сбрасывает накопленный трафик перед закрытием соединения.
 // Вызывается автоматически через цепочку Close: relay.Relay() → obfuscated2.Conn → connTraffic → tcp.
+// Финальный flush выполняется ровно один раз на любом пути завершения.
 func (c connTraffic) Close() error {
-	c.FlushTraffic()
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		c.FlushTraffic()
+	})
 
 	return c.Conn.Close() //nolint: wrapcheck
 }
 
 // newConnTraffic создаёт connTraffic с инициализированными аккумуляторами.
 func newConnTraffic(conn essentials.Conn, streamID string, stream EventStream, ctx context.Context) connTraffic {
+	trafficStream, _ := stream.(TrafficEventStream)
+
 	return connTraffic{
-		Conn:     conn,
-		streamID: streamID,
-		stream:   stream,
-		ctx:      ctx,
-		readAcc:  &atomic.Uint64{},
-		writeAcc: &atomic.Uint64{},
+		Conn:          conn,
+		streamID:      streamID,
+		stream:        stream,
+		trafficStream: trafficStream,
+		ctx:           ctx,
+		readAcc:       &atomic.Uint64{},
+		writeAcc:      &atomic.Uint64{},
+
+		closed:    &atomic.Bool{},
+		closeOnce: &sync.Once{},
 	}
 }
 
+// connRewind запоминает всё, что прочитано из соединения до Rewind(), и
+// после Rewind() отдаёт это повторно, а затем продолжает читать живое
+// соединение. Так fronting домен получает побайтно тот же поток, что
+// отправил клиент: даже если хендшейк оборвался посреди record или
+// ClientHello разбит на несколько records, недочитанный хвост просто
+// придёт из соединения следом за буфером.
+//
+// Буфер ограничен тем, что читает хендшейк: заголовок и один record
+// (не больше 5 + 16 кб). После успешного хендшейка соединение дальше
+// читается мимо connRewind.
 type connRewind struct {
 	essentials.Conn
 
@@ -71,6 +71,29 @@ func (suite *ConnTrafficTestSuite) TestReadOk() {
 	suite.Equal(readSize, n)
 }
 
+func (suite *ConnTrafficTestSuite) TestSendTraffic() {
+	eventStream := &TrafficEventStreamMock{}
+	conn := newConnTraffic(suite.connMock, "CONNID", eventStream, context.Background())
+	writeSize := int(trafficFlushThreshold)
+
+	eventStream.
+		On("SendTraffic", mock.Anything, mock.Anything).
+		Once().
+		Run(func(args mock.Arguments) {
+			evt := args.Get(1).(EventTraffic) //nolint: forcetypeassert
+
+			suite.EqualValues(writeSize, evt.Traffic)
+			suite.False(evt.IsRead)
+		})
+	suite.connMock.On("Write", mock.Anything).Once().Return(writeSize, nil)
+
+	n, err := conn.Write(make([]byte, writeSize))
+	suite.NoError(err)
+	suite.Equal(writeSize, n)
+	eventStream.AssertExpectations(suite.T())
+	eventStream.AssertNotCalled(suite.T(), "Send", mock.Anything, mock.Anything)
+}
+
 func (suite *ConnTrafficTestSuite) TestReadBelowThreshold() {
 	// Чтение меньше порога — событие НЕ эмитится
 	suite.connMock.On("Read", mock.Anything).Once().Return(10, nil)
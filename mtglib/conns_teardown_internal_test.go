@@ -0,0 +1,125 @@
+package mtglib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/mtglib/internal/relay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trafficRecorder ведёт себя как events.EventStream: EventTraffic с
+// отменённым контекстом отбрасывается.
+type trafficRecorder struct {
+	mu      sync.Mutex
+	read    uint
+	written uint
+}
+
+func (t *trafficRecorder) Send(ctx context.Context, evt Event) {
+	traffic, ok := evt.(EventTraffic)
+	if !ok || ctx.Err() != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if traffic.IsRead {
+		t.read += traffic.Traffic
+	} else {
+		t.written += traffic.Traffic
+	}
+}
+
+func (t *trafficRecorder) Totals() (uint, uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.read, t.written
+}
+
+type relayTestLogger struct{}
+
+func (relayTestLogger) Printf(_ string, _ ...interface{}) {}
+
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close() //nolint: errcheck
+
+	accepted := make(chan net.Conn, 1)
+
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+
+	dialed, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	server := <-accepted
+	require.NotNil(t, server)
+
+	return dialed.(*net.TCPConn), server.(*net.TCPConn) //nolint: forcetypeassert
+}
+
+// TestConnTrafficCancelMidRelay отменяет контекст посреди relay и
+// проверяет, что EventTraffic сходится с реально скопированными байтами.
+func TestConnTrafficCancelMidRelay(t *testing.T) {
+	t.Parallel()
+
+	const (
+		uploadSize   = 100_001
+		downloadSize = 70_003
+	)
+
+	telegramConn, telegramPeer := tcpPair(t)
+	clientConn, clientPeer := tcpPair(t)
+
+	defer telegramPeer.Close() //nolint: errcheck
+	defer clientPeer.Close()   //nolint: errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder := &trafficRecorder{}
+	traffic := newConnTraffic(telegramConn, "CONNID", recorder, ctx)
+
+	relayDone := make(chan struct{})
+
+	go func() {
+		defer close(relayDone)
+		relay.Relay(ctx, relayTestLogger{}, traffic, clientConn)
+	}()
+
+	upload := bytes.Repeat([]byte{1}, uploadSize)
+	download := bytes.Repeat([]byte{2}, downloadSize)
+
+	go clientPeer.Write(upload)     //nolint: errcheck
+	go telegramPeer.Write(download) //nolint: errcheck
+
+	_, err := io.ReadFull(telegramPeer, make([]byte, uploadSize))
+	require.NoError(t, err)
+
+	_, err = io.ReadFull(clientPeer, make([]byte, downloadSize))
+	require.NoError(t, err)
+
+	// Соединения ещё открыты: отмена прерывает relay посреди работы.
+	// Как и streamContext.Close, закрываем оба соединения сразу после
+	// отмены; relay затем закрывает их повторно.
+	cancel()
+	traffic.Close()    //nolint: errcheck
+	clientConn.Close() //nolint: errcheck
+	<-relayDone
+
+	read, written := recorder.Totals()
+	assert.EqualValues(t, downloadSize, read)
+	assert.EqualValues(t, uploadSize, written)
+}
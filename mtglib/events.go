@@ -35,11 +35,17 @@ type EventConnectedToDC struct {
 	eventBase
 
 	// RemoteIP is an IP address of the Telegram server proxy has been connected
-	// to.
+	// to. It is nil if TelegramDialer has returned a non-TCP connection.
 	RemoteIP net.IP
 
 	// DC is an index of the datacenter proxy has been connected to.
 	DC int
+
+	// UsedTFO is true if the connection was established with TCP Fast
+	// Open cookie, saving a round trip. This is a best-effort value: it is
+	// always false on non-Linux platforms, for connections made through
+	// upstream proxies and for connections taken from the connection pool.
+	UsedTFO bool
 }
 
 // EventTraffic is emitted when we read/write some bytes on a connection.
@@ -70,6 +76,22 @@ type EventFinish struct {
 // Telegram server.
 type EventDomainFronting struct {
 	eventBase
+
+	// SNI is a server name which a client has sent in its client hello.
+	// It is not authenticated and is empty if a client has sent no SNI or
+	// it was not possible to parse it.
+	SNI string
+
+	// ExpectedSNI is true if SNI matches a hostname from a secret.
+	// Usually these are clients of a fronting domain. Scanners often send
+	// random or no SNI.
+	ExpectedSNI bool
+}
+
+// SNIHash returns a salted hash of the SNI, like RemoteIPHash of
+// EventReplayAttack. An empty string is returned if SNI is unknown.
+func (e EventDomainFronting) SNIHash() string {
+	return hashSNI(e.SNI)
 }
 
 // EventConcurrencyLimited is emitted when connection was declined because of
@@ -78,6 +100,14 @@ type EventConcurrencyLimited struct {
 	eventBase
 }
 
+// EventRateLimited is emitted when connection was declined because its
+// IP address exceeded a per-IP handshake rate limit.
+type EventRateLimited struct {
+	eventBase
+
+	RemoteIP net.IP
+}
+
 // EventIPBlocklisted is emitted when connection was declined because IP
 // address was found in IP blocklist.
 type EventIPBlocklisted struct {
@@ -91,6 +121,21 @@ type EventIPBlocklisted struct {
 // connection.
 type EventReplayAttack struct {
 	eventBase
+
+	// RemoteIP is an IP address of the client which has sent a replayed
+	// handshake. It could be nil if the source is unknown.
+	RemoteIP net.IP
+}
+
+// RemoteIPHash returns a salted hash of the RemoteIP. This is the same
+// value mtg writes to logs instead of raw client IP addresses. An empty
+// string is returned if the source is unknown.
+func (e EventReplayAttack) RemoteIPHash() string {
+	if e.RemoteIP == nil {
+		return ""
+	}
+
+	return hashIP(e.RemoteIP)
 }
 
 // EventIPListSize is emitted when mtg updates a contents of the ip lists:
@@ -123,6 +168,12 @@ func NewEventStart(streamID string, remoteIP net.IP) EventStart {
 
 // NewEventConnectedToDC creates a new EventConnectedToDC event.
 func NewEventConnectedToDC(streamID string, remoteIP net.IP, dc int) EventConnectedToDC {
+	return NewEventConnectedToDCWithTFO(streamID, remoteIP, dc, false)
+}
+
+// NewEventConnectedToDCWithTFO creates a new EventConnectedToDC event with
+// a mark if TCP Fast Open was used for the connection.
+func NewEventConnectedToDCWithTFO(streamID string, remoteIP net.IP, dc int, usedTFO bool) EventConnectedToDC {
 	return EventConnectedToDC{
 		eventBase: eventBase{
 			timestamp: time.Now(),
@@ -130,6 +181,7 @@ func NewEventConnectedToDC(streamID string, remoteIP net.IP, dc int) EventConnec
 		},
 		RemoteIP: remoteIP,
 		DC:       dc,
+		UsedTFO:  usedTFO,
 	}
 }
 
@@ -155,13 +207,22 @@ func NewEventFinish(streamID string) EventFinish {
 	}
 }
 
-// NewEventDomainFronting creates a new EventDomainFronting event.
+// NewEventDomainFronting creates a new EventDomainFronting event without
+// SNI.
 func NewEventDomainFronting(streamID string) EventDomainFronting {
+	return NewEventDomainFrontingWithSNI(streamID, "", false)
+}
+
+// NewEventDomainFrontingWithSNI creates a new EventDomainFronting event
+// with SNI sent by a client.
+func NewEventDomainFrontingWithSNI(streamID, sni string, expectedSNI bool) EventDomainFronting {
 	return EventDomainFronting{
 		eventBase: eventBase{
 			timestamp: time.Now(),
 			streamID:  streamID,
 		},
+		SNI:         sni,
+		ExpectedSNI: expectedSNI,
 	}
 }
 
@@ -175,6 +236,16 @@ func NewEventConcurrencyLimited() EventConcurrencyLimited {
 	}
 }
 
+// NewEventRateLimited creates a new EventRateLimited event.
+func NewEventRateLimited(remoteIP net.IP) EventRateLimited {
+	return EventRateLimited{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		RemoteIP: remoteIP,
+	}
+}
+
 // NewEventIPBlocklisted creates a new EventIPBlocklisted event.
 func NewEventIPBlocklisted(remoteIP net.IP) EventIPBlocklisted {
 	return EventIPBlocklisted{
@@ -198,13 +269,21 @@ func NewEventIPAllowlisted(remoteIP net.IP) EventIPBlocklisted {
 	}
 }
 
-// NewEventReplayAttack creates a new EventReplayAttack event.
+// NewEventReplayAttack creates a new EventReplayAttack event without a
+// source IP address.
 func NewEventReplayAttack(streamID string) EventReplayAttack {
+	return NewEventReplayAttackFromIP(streamID, nil)
+}
+
+// NewEventReplayAttackFromIP creates a new EventReplayAttack event with a
+// source IP address of the client.
+func NewEventReplayAttackFromIP(streamID string, remoteIP net.IP) EventReplayAttack {
 	return EventReplayAttack{
 		eventBase: eventBase{
 			timestamp: time.Now(),
 			streamID:  streamID,
 		},
+		RemoteIP: remoteIP,
 	}
 }
 
@@ -279,6 +358,17 @@ type EventPoolMetrics struct {
 	Idle int
 }
 
+// HitRatio returns a share of hits among all pool lookups since last
+// update. ok is false if there were no lookups.
+func (e EventPoolMetrics) HitRatio() (ratio float64, ok bool) {
+	total := e.DeltaHits + e.DeltaMisses
+	if total == 0 {
+		return 0, false
+	}
+
+	return float64(e.DeltaHits) / float64(total), true
+}
+
 // NewEventPoolMetrics creates a new EventPoolMetrics event.
 func NewEventPoolMetrics(dc int, deltaHits, deltaMisses, deltaUnhealthy uint64, idle int) EventPoolMetrics {
 	return EventPoolMetrics{
@@ -310,3 +400,528 @@ func NewEventRateLimiterMetrics(size int) EventRateLimiterMetrics {
 		Size: size,
 	}
 }
+
+const (
+	// HandshakeFailureExhausted means that mtg could not generate a valid
+	// obfuscated2 handshake frame. This practically happens only if RNG
+	// is broken, so it is worth to alarm on.
+	HandshakeFailureExhausted = "frame_exhausted"
+
+	// HandshakeFailureCipherInit means that mtg could not initialize
+	// ciphers for obfuscated2 handshake.
+	HandshakeFailureCipherInit = "cipher_init"
+
+	// HandshakeFailureWrite means that mtg could not send obfuscated2
+	// handshake frame to Telegram.
+	HandshakeFailureWrite = "write"
+
+	// HandshakeFailureOther is used for all other failures.
+	HandshakeFailureOther = "other"
+)
+
+// EventTelegramHandshakeFailed is emitted when mtg fails to perform
+// obfuscated2 handshake with Telegram server.
+type EventTelegramHandshakeFailed struct {
+	eventBase
+
+	// DC is an index of the datacenter proxy has tried to connect to.
+	DC int
+
+	// Reason is one of HandshakeFailure* constants.
+	Reason string
+}
+
+// NewEventTelegramHandshakeFailed creates a new
+// EventTelegramHandshakeFailed event.
+func NewEventTelegramHandshakeFailed(streamID string, dc int, reason string) EventTelegramHandshakeFailed {
+	return EventTelegramHandshakeFailed{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		DC:     dc,
+		Reason: reason,
+	}
+}
+
+const (
+	// ScannerReasonNotTLS means that the first bytes of the connection do
+	// not look like a TLS handshake record.
+	ScannerReasonNotTLS = "not_tls"
+
+	// ScannerReasonTruncated means that the client has closed the
+	// connection or stalled before sending a full TLS record header.
+	ScannerReasonTruncated = "truncated"
+
+	// ScannerReasonSlow means that the client has not sent a full TLS
+	// record with ClientHello within ProxyConfig.ClientHelloTimeout.
+	// Such connections are always closed.
+	ScannerReasonSlow = "slow"
+)
+
+// EventScannerDetected is emitted when the first bytes of a client
+// connection are clearly not a TLS ClientHello. Such connections are
+// usually made by mass scanners or active probes.
+type EventScannerDetected struct {
+	eventBase
+
+	// RemoteIP is an IP address of the client.
+	RemoteIP net.IP
+
+	// Reason is one of ScannerReason* constants.
+	Reason string
+
+	// Rejected is true if the connection was closed immediately instead
+	// of being routed to the fronting domain.
+	Rejected bool
+}
+
+// NewEventScannerDetected creates a new EventScannerDetected event.
+func NewEventScannerDetected(streamID string, remoteIP net.IP, reason string, rejected bool) EventScannerDetected {
+	return EventScannerDetected{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		RemoteIP: remoteIP,
+		Reason:   reason,
+		Rejected: rejected,
+	}
+}
+
+// EventWorkerPoolPressure is emitted when utilization of the worker pool
+// crosses WorkerPoolHighWatermark (High is true) and when it drops back
+// below WorkerPoolLowWatermark (High is false).
+//
+// Unlike EventConcurrencyLimited, this event comes before connections
+// start to be rejected, so it can be used for alerting or autoscaling.
+type EventWorkerPoolPressure struct {
+	eventBase
+
+	// Running is a number of busy workers.
+	Running int
+
+	// Capacity is a size of the worker pool.
+	Capacity int
+
+	// High is true if pool has entered a high pressure state and false
+	// if it has left it.
+	High bool
+}
+
+// Utilization returns a ratio of busy workers, from 0 to 1.
+func (e EventWorkerPoolPressure) Utilization() float64 {
+	if e.Capacity <= 0 {
+		return 0
+	}
+
+	return float64(e.Running) / float64(e.Capacity)
+}
+
+// NewEventWorkerPoolPressure creates a new EventWorkerPoolPressure event.
+func NewEventWorkerPoolPressure(running, capacity int, high bool) EventWorkerPoolPressure {
+	return EventWorkerPoolPressure{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		Running:  running,
+		Capacity: capacity,
+		High:     high,
+	}
+}
+
+// EventClientTimeSkew is emitted for each FakeTLS client hello with a
+// valid digest, after its timestamp is checked against a time skewness
+// tolerance. So, it is emitted both for accepted and rejected clients.
+type EventClientTimeSkew struct {
+	eventBase
+
+	// RemoteIP is an IP address of the client.
+	RemoteIP net.IP
+
+	// Skew is a difference between proxy time and client timestamp. It
+	// is positive if client clock is behind and negative if it is ahead.
+	Skew time.Duration
+
+	// Rejected is true if the client hello was rejected because Skew is
+	// out of a time skewness tolerance.
+	Rejected bool
+}
+
+// NewEventClientTimeSkew creates a new EventClientTimeSkew event for a
+// client hello which was not rejected by its timestamp.
+func NewEventClientTimeSkew(streamID string, remoteIP net.IP, skew time.Duration) EventClientTimeSkew {
+	return NewEventClientTimeSkewChecked(streamID, remoteIP, skew, false)
+}
+
+// NewEventClientTimeSkewChecked creates a new EventClientTimeSkew event
+// with a result of a time skewness check.
+func NewEventClientTimeSkewChecked(streamID string,
+	remoteIP net.IP,
+	skew time.Duration,
+	rejected bool,
+) EventClientTimeSkew {
+	return EventClientTimeSkew{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		RemoteIP: remoteIP,
+		Skew:     skew,
+		Rejected: rejected,
+	}
+}
+
+// EventDNSQueriesSkipped is emitted periodically with a number of DNS
+// queries which were not sent because a network resolves only A or only
+// AAAA records.
+type EventDNSQueriesSkipped struct {
+	eventBase
+
+	// DeltaA is the number of skipped A queries since last update.
+	DeltaA uint64
+
+	// DeltaAAAA is the number of skipped AAAA queries since last update.
+	DeltaAAAA uint64
+}
+
+// NewEventDNSQueriesSkipped creates a new EventDNSQueriesSkipped event.
+func NewEventDNSQueriesSkipped(deltaA, deltaAAAA uint64) EventDNSQueriesSkipped {
+	return EventDNSQueriesSkipped{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		DeltaA:    deltaA,
+		DeltaAAAA: deltaAAAA,
+	}
+}
+
+// EventDomainFrontingDial is emitted when proxy has dialed a fronting
+// domain, successfully or not.
+type EventDomainFrontingDial struct {
+	eventBase
+
+	// Duration is a time spent on dial, including DNS resolving.
+	Duration time.Duration
+
+	// Failed is true if fronting domain was not reachable.
+	Failed bool
+}
+
+// NewEventDomainFrontingDial creates a new EventDomainFrontingDial event.
+func NewEventDomainFrontingDial(streamID string, duration time.Duration, failed bool) EventDomainFrontingDial {
+	return EventDomainFrontingDial{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		Duration: duration,
+		Failed:   failed,
+	}
+}
+
+// EventWorkerQueueWait is emitted when a worker starts to serve a
+// connection accepted by Serve. Duration is a time between Accept and
+// this moment, so one can tell if latency comes from waiting for a free
+// worker or from a handshake itself. Connections served by ServeConn do
+// not have this event.
+type EventWorkerQueueWait struct {
+	eventBase
+
+	// Duration is a time spent between Accept and a start of serving.
+	Duration time.Duration
+}
+
+// NewEventWorkerQueueWait creates a new EventWorkerQueueWait event.
+func NewEventWorkerQueueWait(streamID string, duration time.Duration) EventWorkerQueueWait {
+	return EventWorkerQueueWait{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		Duration: duration,
+	}
+}
+
+// EventTarpitted is emitted when a client connection with an invalid
+// handshake is tarpitted instead of being routed to the fronting
+// domain. Please see ProxyOpts.TarpitDuration.
+type EventTarpitted struct {
+	eventBase
+
+	// RemoteIP is an IP address of the client.
+	RemoteIP net.IP
+}
+
+// NewEventTarpitted creates a new EventTarpitted event.
+func NewEventTarpitted(streamID string, remoteIP net.IP) EventTarpitted {
+	return EventTarpitted{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		RemoteIP: remoteIP,
+	}
+}
+
+// EventDraining is emitted when proxy starts draining: it stops
+// accepting new connections but keeps serving existing ones. Please see
+// Proxy.Drain.
+type EventDraining struct {
+	eventBase
+}
+
+// NewEventDraining creates a new EventDraining event.
+func NewEventDraining() EventDraining {
+	return EventDraining{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+	}
+}
+
+// EventDCConfigStale is emitted when managed DC addresses (a DC config
+// file or URL) could not be loaded several times in a row, so proxy
+// uses hardcoded addresses which may be outdated. It is repeated on each
+// following failure with a growing Failures. When a load succeeds again,
+// it is emitted once with Failures equal to 0.
+type EventDCConfigStale struct {
+	eventBase
+
+	// Failures is a number of consecutive failed loads. 0 means that
+	// managed addresses are applied again.
+	Failures int
+}
+
+// NewEventDCConfigStale creates a new EventDCConfigStale event.
+func NewEventDCConfigStale(failures int) EventDCConfigStale {
+	return EventDCConfigStale{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		Failures: failures,
+	}
+}
+
+// EventDoHQueriesLimited is emitted periodically with a number of
+// DNS-over-HTTPS queries which hit a limit of concurrent queries: they
+// either waited for a free slot or failed.
+type EventDoHQueriesLimited struct {
+	eventBase
+
+	// Delta is the number of limited queries since last update.
+	Delta uint64
+}
+
+// NewEventDoHQueriesLimited creates a new EventDoHQueriesLimited event.
+func NewEventDoHQueriesLimited(delta uint64) EventDoHQueriesLimited {
+	return EventDoHQueriesLimited{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		Delta: delta,
+	}
+}
+
+// EventTelegramDCSkipped is emitted when proxy does not even try to
+// connect to a DC which has recently failed to dial and connects to a
+// fallback DC straight away. Please see ProxyConfig.DCFailureTTL.
+type EventTelegramDCSkipped struct {
+	eventBase
+
+	// DC is an index of the datacenter a client has requested.
+	DC int
+
+	// FallbackDC is an index of the datacenter proxy connects to instead.
+	FallbackDC int
+}
+
+// NewEventTelegramDCSkipped creates a new EventTelegramDCSkipped event.
+func NewEventTelegramDCSkipped(streamID string, dc, fallbackDC int) EventTelegramDCSkipped {
+	return EventTelegramDCSkipped{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		DC:         dc,
+		FallbackDC: fallbackDC,
+	}
+}
+
+// EventTelegramDialMetrics is emitted periodically with a number of
+// connections to a Telegram DC which proxy has established or failed to
+// establish since last update. Both direct and pooled connections are
+// counted. Dials which are canceled by a client are not counted.
+type EventTelegramDialMetrics struct {
+	eventBase
+
+	// DC is an index of the datacenter.
+	DC int
+
+	// DeltaSuccesses is a number of successful dials since last update.
+	DeltaSuccesses uint64
+
+	// DeltaFailures is a number of failed dials since last update.
+	DeltaFailures uint64
+}
+
+// NewEventTelegramDialMetrics creates a new EventTelegramDialMetrics
+// event.
+func NewEventTelegramDialMetrics(dc int, deltaSuccesses, deltaFailures uint64) EventTelegramDialMetrics {
+	return EventTelegramDialMetrics{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		DC:             dc,
+		DeltaSuccesses: deltaSuccesses,
+		DeltaFailures:  deltaFailures,
+	}
+}
+
+// EventAcceptBackpressure is emitted when Serve resumes accepting
+// connections after a pause: a number of active connections has reached
+// ProxyOpts.AcceptBackpressureThreshold, and new connections were waiting
+// in a listen backlog.
+type EventAcceptBackpressure struct {
+	eventBase
+
+	// Duration is a time when Serve did not accept connections.
+	Duration time.Duration
+}
+
+// NewEventAcceptBackpressure creates a new EventAcceptBackpressure event.
+func NewEventAcceptBackpressure(duration time.Duration) EventAcceptBackpressure {
+	return EventAcceptBackpressure{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		Duration: duration,
+	}
+}
+
+// EventTelegramHandshakeRetried is emitted when obfuscated2 handshake
+// with Telegram fails with broken pipe and proxy retries it on a fresh
+// connection, bypassing a connection pool. This usually means that a
+// pooled connection was stale, so a rate of these events helps to tune
+// pool timeouts.
+type EventTelegramHandshakeRetried struct {
+	eventBase
+
+	// DC is an index of the datacenter.
+	DC int
+
+	// Failed is true if the retry has failed too: either a fresh
+	// connection could not be dialed or a handshake on it failed.
+	Failed bool
+}
+
+// NewEventTelegramHandshakeRetried creates a new
+// EventTelegramHandshakeRetried event.
+func NewEventTelegramHandshakeRetried(streamID string, dc int, failed bool) EventTelegramHandshakeRetried {
+	return EventTelegramHandshakeRetried{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		DC:     dc,
+		Failed: failed,
+	}
+}
+
+// EventDomainFrontingLimited is emitted when a connection is not routed
+// to the fronting domain because ProxyOpts.DomainFrontingMaxConnections
+// connections are fronted already. Such connection is closed.
+type EventDomainFrontingLimited struct {
+	eventBase
+
+	// RemoteIP is an IP address of the client.
+	RemoteIP net.IP
+}
+
+// NewEventDomainFrontingLimited creates a new EventDomainFrontingLimited
+// event.
+func NewEventDomainFrontingLimited(streamID string, remoteIP net.IP) EventDomainFrontingLimited {
+	return EventDomainFrontingLimited{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		RemoteIP: remoteIP,
+	}
+}
+
+// EventRelayStarted is emitted when both handshakes are complete and a
+// connection to Telegram is established, right before a relay starts.
+// This is the point where a connection is known to come from a real
+// client, not a scanner: a ratio of EventRelayStarted to EventStart is a
+// handshake completion rate.
+type EventRelayStarted struct {
+	eventBase
+
+	// DC is an index of the datacenter a client is relayed to. It is the
+	// same DC as in EventConnectedToDC: it may differ from the requested
+	// one because of unknown DC mapping or a fallback.
+	DC int
+
+	// HandshakeDuration is a time between EventStart and this event. It
+	// includes a client handshake and a dial to Telegram.
+	HandshakeDuration time.Duration
+}
+
+// NewEventRelayStarted creates a new EventRelayStarted event.
+func NewEventRelayStarted(streamID string, dc int, handshakeDuration time.Duration) EventRelayStarted {
+	return EventRelayStarted{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		DC:                dc,
+		HandshakeDuration: handshakeDuration,
+	}
+}
+
+// EventRelaysLimited is emitted when a client has completed a handshake
+// but is closed instead of being relayed to Telegram, because
+// ProxyOpts.MaxRelays relays are active already.
+type EventRelaysLimited struct {
+	eventBase
+
+	// RemoteIP is an IP address of the client.
+	RemoteIP net.IP
+}
+
+// NewEventRelaysLimited creates a new EventRelaysLimited event.
+func NewEventRelaysLimited(streamID string, remoteIP net.IP) EventRelaysLimited {
+	return EventRelaysLimited{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+			streamID:  streamID,
+		},
+		RemoteIP: remoteIP,
+	}
+}
+
+// EventConcurrencyMetrics is emitted periodically to update counts of
+// busy workers and active relays. If ProxyOpts.MaxRelays is set, a
+// worker is busy only until a relay starts, so these numbers differ:
+// Workers is a handshake load and Relays is a count of served clients.
+type EventConcurrencyMetrics struct {
+	eventBase
+
+	// Workers is a number of busy workers in the worker pool.
+	Workers int
+
+	// Relays is a number of active relays to Telegram.
+	Relays int
+}
+
+// NewEventConcurrencyMetrics creates a new EventConcurrencyMetrics event.
+func NewEventConcurrencyMetrics(workers, relays int) EventConcurrencyMetrics {
+	return EventConcurrencyMetrics{
+		eventBase: eventBase{
+			timestamp: time.Now(),
+		},
+		Workers: workers,
+		Relays:  relays,
+	}
+}
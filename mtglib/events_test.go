@@ -48,6 +48,26 @@ func (suite *EventsTestSuite) TestEventDomainFronting() {
 	suite.WithinDuration(time.Now(), evt.Timestamp(), 10*time.Millisecond)
 }
 
+func (suite *EventsTestSuite) TestEventDomainFrontingWithSNI() {
+	evt := mtglib.NewEventDomainFrontingWithSNI("CONNID", "example.com", true)
+
+	suite.Equal("CONNID", evt.StreamID())
+	suite.Equal("example.com", evt.SNI)
+	suite.True(evt.ExpectedSNI)
+	suite.Len(evt.SNIHash(), 12)
+	suite.NotContains(evt.SNIHash(), "example")
+	suite.Empty(mtglib.NewEventDomainFronting("CONNID").SNIHash())
+}
+
+func (suite *EventsTestSuite) TestEventPoolMetricsHitRatio() {
+	ratio, ok := mtglib.NewEventPoolMetrics(2, 3, 1, 0, 5).HitRatio()
+	suite.True(ok)
+	suite.InDelta(0.75, ratio, 0.001)
+
+	_, ok = mtglib.NewEventPoolMetrics(2, 0, 0, 1, 5).HitRatio()
+	suite.False(ok)
+}
+
 func (suite *EventsTestSuite) TestEventConcurrencyLimited() {
 	evt := mtglib.NewEventConcurrencyLimited()
 
@@ -76,6 +96,17 @@ func (suite *EventsTestSuite) TestEventReplayAttack() {
 
 	suite.Equal("CONNID", evt.StreamID())
 	suite.WithinDuration(time.Now(), evt.Timestamp(), 10*time.Millisecond)
+	suite.Nil(evt.RemoteIP)
+	suite.Empty(evt.RemoteIPHash())
+}
+
+func (suite *EventsTestSuite) TestEventReplayAttackFromIP() {
+	evt := mtglib.NewEventReplayAttackFromIP("CONNID", net.ParseIP("10.0.0.10"))
+
+	suite.Equal("CONNID", evt.StreamID())
+	suite.Equal("10.0.0.10", evt.RemoteIP.String())
+	suite.Len(evt.RemoteIPHash(), 12)
+	suite.NotContains(evt.RemoteIPHash(), "10.0.0.10")
 }
 
 func (suite *EventsTestSuite) TestEventIPListSize() {
@@ -87,6 +118,32 @@ func (suite *EventsTestSuite) TestEventIPListSize() {
 	suite.False(evt.IsBlockList)
 }
 
+func (suite *EventsTestSuite) TestEventRelayStarted() {
+	evt := mtglib.NewEventRelayStarted("CONNID", 2, time.Second)
+
+	suite.Equal("CONNID", evt.StreamID())
+	suite.WithinDuration(time.Now(), evt.Timestamp(), 10*time.Millisecond)
+	suite.Equal(2, evt.DC)
+	suite.Equal(time.Second, evt.HandshakeDuration)
+}
+
+func (suite *EventsTestSuite) TestEventRelaysLimited() {
+	evt := mtglib.NewEventRelaysLimited("CONNID", net.ParseIP("10.0.0.10"))
+
+	suite.Equal("CONNID", evt.StreamID())
+	suite.WithinDuration(time.Now(), evt.Timestamp(), 10*time.Millisecond)
+	suite.Equal("10.0.0.10", evt.RemoteIP.String())
+}
+
+func (suite *EventsTestSuite) TestEventConcurrencyMetrics() {
+	evt := mtglib.NewEventConcurrencyMetrics(10, 200)
+
+	suite.Empty(evt.StreamID())
+	suite.WithinDuration(time.Now(), evt.Timestamp(), 10*time.Millisecond)
+	suite.Equal(10, evt.Workers)
+	suite.Equal(200, evt.Relays)
+}
+
 func TestEvents(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &EventsTestSuite{})
@@ -0,0 +1,132 @@
+package mtglib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/9seconds/mtg/v2/mtglib/internal/faketls/record"
+)
+
+// MinInBandMetricsTokenLength is a minimal length of InBandMetrics.Token.
+const MinInBandMetricsTokenLength = 16
+
+// InBandMetrics defines how to serve metrics through the proxy port.
+//
+// If a client sends a plain HTTP GET request for Path with a header
+// 'Authorization: Bearer <Token>' instead of TLS ClientHello, the request
+// is handed to Handler. All other requests, including ones with a wrong
+// token or path, are routed to the fronting domain as usual, so the
+// endpoint is not visible to active probes.
+//
+// Please be aware that this exposes metrics on a public port. Token is the
+// only protection here, and it is sent in plain text.
+type InBandMetrics struct {
+	// Path is an exact HTTP path of the metrics endpoint.
+	Path string
+
+	// Token is a bearer token a client has to present.
+	Token string
+
+	// Handler serves metrics.
+	Handler http.Handler
+}
+
+func (i InBandMetrics) valid() error {
+	switch {
+	case i.Handler == nil:
+		return errors.New("handler is not defined")
+	case !strings.HasPrefix(i.Path, "/"):
+		return fmt.Errorf("path %q has to start with /", i.Path)
+	case len(i.Token) < MinInBandMetricsTokenLength:
+		return fmt.Errorf("token has to be at least %d characters long", MinInBandMetricsTokenLength)
+	}
+
+	return nil
+}
+
+// authorized проверяет path и bearer token запроса.
+func (i InBandMetrics) authorized(req *http.Request) bool {
+	if req.Method != http.MethodGet || req.URL.Path != i.Path {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(i.Token)) == 1
+}
+
+// inBandResponseWriter буферизует ответ handler, чтобы отправить его
+// одним HTTP/1.1 ответом с Connection: close.
+type inBandResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *inBandResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *inBandResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(p) //nolint: wrapcheck
+}
+
+func (w *inBandResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// serveInBandMetrics отдаёт метрики, если клиент прислал авторизованный
+// HTTP GET на путь метрик. Возвращает false, если запрос не для нас:
+// тогда прочитанные байты остаются в conn и уходят fronting домену.
+func (p *Proxy) serveInBandMetrics(ctx *streamContext,
+	conn *connRewind,
+	header [record.HeaderSize]byte,
+) bool {
+	if p.inBandMetrics == nil || !bytes.HasPrefix(header[:], []byte("GET /")) {
+		return false
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(io.MultiReader(bytes.NewReader(header[:]), conn)))
+	if err != nil || !p.inBandMetrics.authorized(req) {
+		return false
+	}
+
+	writer := &inBandResponseWriter{
+		header: http.Header{},
+	}
+
+	p.inBandMetrics.Handler.ServeHTTP(writer, req.WithContext(ctx))
+
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+
+	resp := &http.Response{
+		StatusCode:    writer.status,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        writer.header,
+		Body:          io.NopCloser(&writer.body),
+		ContentLength: int64(writer.body.Len()),
+		Close:         true,
+		Request:       req,
+	}
+
+	if err := resp.Write(ctx.clientConn); err != nil {
+		ctx.logger.DebugError("cannot send in-band metrics", err)
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package mtglib
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testInBandMetricsToken = "0123456789abcdef"
+
+func TestInBandMetricsValid(t *testing.T) {
+	t.Parallel()
+
+	handler := http.NotFoundHandler()
+
+	assert.NoError(t, InBandMetrics{Path: "/metrics", Token: testInBandMetricsToken, Handler: handler}.valid())
+	assert.Error(t, InBandMetrics{Path: "/metrics", Token: testInBandMetricsToken}.valid())
+	assert.Error(t, InBandMetrics{Path: "metrics", Token: testInBandMetricsToken, Handler: handler}.valid())
+	assert.Error(t, InBandMetrics{Path: "/metrics", Token: "short", Handler: handler}.valid())
+}
+
+// serveInBand прогоняет clientData через doFakeTLSHandshake прокси с
+// метриками на /metrics. Возвращает ответ клиенту и байты, которые
+// получил fronting домен.
+func serveInBand(t *testing.T, clientData string) (string, string) {
+	t.Helper()
+
+	frontListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer frontListener.Close()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer proxyListener.Close()
+
+	fronted := make(chan []byte, 1)
+
+	go func() {
+		conn, err := frontListener.Accept()
+		if err != nil {
+			fronted <- nil
+
+			return
+		}
+
+		defer conn.Close()
+
+		data, _ := io.ReadAll(conn)
+		fronted <- data
+	}()
+
+	frontConn, err := net.Dial("tcp", frontListener.Addr().String())
+	require.NoError(t, err)
+
+	defer frontConn.Close()
+
+	clientConn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+
+	defer clientConn.Close()
+
+	serverConn, err := proxyListener.Accept()
+	require.NoError(t, err)
+
+	networkMock := &testlib.MtglibNetworkMock{}
+	networkMock.
+		On("DialContext", mock.Anything, "tcp", "example.com:443").
+		Return(essentials.Conn(frontConn.(*net.TCPConn)), nil)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:                context.Background(),
+		secret:             Secret{Host: "example.com"},
+		domainFrontingPort: 443,
+		config:             DefaultProxyConfig(),
+		network:            networkMock,
+		eventStream:        eventStream,
+		logger:             NoopLogger{},
+		inBandMetrics: &InBandMetrics{
+			Path:  "/metrics",
+			Token: testInBandMetricsToken,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				io.WriteString(w, "mtg_client_connections 1\n") //nolint: errcheck
+			}),
+		},
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	done := make(chan bool, 1)
+
+	go func() {
+		defer streamCtx.Close()
+
+		done <- proxy.doFakeTLSHandshake(streamCtx)
+	}()
+
+	_, err = io.WriteString(clientConn, clientData)
+	require.NoError(t, err)
+	require.NoError(t, clientConn.(*net.TCPConn).CloseWrite())
+
+	select {
+	case result := <-done:
+		assert.False(t, result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handshake has not finished")
+	}
+
+	response, err := io.ReadAll(clientConn)
+	require.NoError(t, err)
+
+	frontConn.Close()
+
+	return string(response), string(<-fronted)
+}
+
+func TestInBandMetricsServed(t *testing.T) {
+	t.Parallel()
+
+	response, fronted := serveInBand(t,
+		"GET /metrics HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer "+testInBandMetricsToken+"\r\n\r\n")
+
+	assert.Empty(t, fronted)
+
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(response)), nil)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, resp.Close)
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "mtg_client_connections 1\n", string(body))
+}
+
+func TestInBandMetricsFronted(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]string{
+		"no token":    "GET /metrics HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"wrong token": "GET /metrics HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer fedcba9876543210\r\n\r\n",
+		"wrong path": "GET /other HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer " +
+			testInBandMetricsToken + "\r\n\r\n",
+		"not http": "GET /metrics junk",
+	}
+
+	for name, value := range testData {
+		clientData := value
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			response, fronted := serveInBand(t, clientData)
+
+			assert.Empty(t, response)
+			assert.Equal(t, clientData, fronted)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/mtglib/internal/relay"
 )
 
 var (
@@ -58,6 +59,19 @@ var (
 	// ErrLoggerIsNotDefined is returned if you are trying to create a proxy but
 	// logger is not defined.
 	ErrLoggerIsNotDefined = errors.New("logger is not defined")
+
+	// ErrProxyOptsInvalid is returned by NewProxy if ProxyOpts are invalid.
+	// It wraps a concrete reason, so you can also match it with one of the
+	// errors above (ErrSecretInvalid, ErrNetworkIsNotDefined and so on).
+	ErrProxyOptsInvalid = errors.New("invalid settings")
+
+	// ErrTelegramDialerFailed is returned by NewProxy if it cannot build a
+	// dialer to Telegram servers (e.g. unknown ip preference).
+	ErrTelegramDialerFailed = errors.New("cannot build telegram dialer")
+
+	// ErrWorkerPoolFailed is returned by NewProxy if it cannot create a pool
+	// of workers which serve client connections.
+	ErrWorkerPoolFailed = errors.New("cannot create worker pool")
 )
 
 const (
@@ -74,6 +88,18 @@ const (
 	// of probe-resistance activity.
 	DefaultDomainFrontingPort = 443
 
+	// DefaultDomainFrontingMaxConnections is a recommended max count of
+	// connections which are routed to a fronting domain at the same time.
+	// ProxyOpts does not apply it implicitly, mtg uses it as a default
+	// of its config.
+	DefaultDomainFrontingMaxConnections = 1024
+
+	// DefaultTelegramHealthFailureThreshold is a default count of
+	// consecutive health check rounds without reachable DCs after which
+	// Telegram is considered unreachable. Please see
+	// ProxyOpts.TelegramHealthCheckInterval.
+	DefaultTelegramHealthFailureThreshold = 3
+
 	// DefaultIdleTimeout is a default timeout for closing a connection in case of
 	// idling.
 	//
@@ -84,9 +110,96 @@ const (
 	// faketls timeout verification.
 	DefaultTolerateTimeSkewness = 3 * time.Second
 
+	// DefaultSkewRateLimitBurst is a default value of
+	// ProxyOpts.SkewRateLimitBurst.
+	DefaultSkewRateLimitBurst = 5
+
+	// DefaultRateLimitBurst is a default value of ProxyOpts.RateLimitBurst
+	// and ListenerOptions.RateLimitBurst.
+	DefaultRateLimitBurst = 20
+
+	// DefaultConnectionPoolMaxIdle is a default value of
+	// ProxyOpts.ConnectionPoolMaxIdle.
+	DefaultConnectionPoolMaxIdle = 5
+
 	// DefaultPreferIP is a default value for Telegram IP connectivity preference.
 	DefaultPreferIP = "prefer-ipv6"
 
+	// DefaultFakeTLSMaxRecordSize is a default maximal payload size of TLS
+	// records written to a client. This is a maximum allowed by RFC 8446.
+	DefaultFakeTLSMaxRecordSize = 16 * 1024 // 16 kib
+
+	// MinFakeTLSMaxRecordSize is a minimal allowed value of
+	// ProxyOpts.FakeTLSMaxRecordSize. Tiny records add a lot of overhead
+	// and are unusual for real TLS servers.
+	MinFakeTLSMaxRecordSize = 512
+
+	// MaxFakeTLSSmallFirstRecords is a maximal count of small first TLS
+	// records. Browsers send only a few short frames before full records,
+	// a long series of small records is a fingerprint itself.
+	MaxFakeTLSSmallFirstRecords = 8
+
+	// DefaultTCPUserTimeout is a default value of TCP_USER_TIMEOUT for
+	// relayed connections.
+	DefaultTCPUserTimeout = relay.DefaultTCPUserTimeout
+
+	// MinTCPUserTimeout is a minimal allowed TCP_USER_TIMEOUT. Smaller
+	// values kill healthy connections on any packet loss.
+	MinTCPUserTimeout = time.Second
+
+	// MaxTCPUserTimeout is a maximal allowed TCP_USER_TIMEOUT. This is
+	// roughly when Linux gives up retransmissions anyway.
+	MaxTCPUserTimeout = 15 * time.Minute
+
+	// DefaultTelegramWindowClamp is a default value of TCP_WINDOW_CLAMP
+	// for connections to Telegram. This is DEFAULT_WINDOW_CLAMP of the
+	// original MTProxy.
+	DefaultTelegramWindowClamp = relay.DefaultWindowClamp
+
+	// MinTelegramWindowClamp is a minimal allowed TCP_WINDOW_CLAMP.
+	MinTelegramWindowClamp = 4 * 1024 // 4 kib
+
+	// MaxTelegramWindowClamp is a maximal allowed TCP_WINDOW_CLAMP.
+	MaxTelegramWindowClamp = 64 * 1024 * 1024 // 64 mib
+
+	// DefaultRelayBufferSize is a default size of a relay copy buffer.
+	DefaultRelayBufferSize = 256 * 1024 // 256 kib
+
+	// MinRelayBufferSize is a minimal allowed size of a relay copy
+	// buffer. Smaller buffers mean a syscall per few packets.
+	MinRelayBufferSize = 4 * 1024 // 4 kib
+
+	// MaxRelayBufferSize is a maximal allowed size of a relay copy buffer.
+	MaxRelayBufferSize = 4 * 1024 * 1024 // 4 mib
+
+	// MinRelayIOTimeout is a minimal allowed ProxyConfig.RelayIOTimeout.
+	// It is an idle timeout, so smaller values kill healthy connections
+	// of clients which are silent for a moment.
+	MinRelayIOTimeout = time.Second
+
+	// TCPQuickACKDisabled is a value of ProxyConfig.TCPQuickACKInterval
+	// which disables TCP_QUICKACK in relay.
+	TCPQuickACKDisabled = -1
+
+	// DefaultDrainTimeout is a default ProxyConfig.DrainTimeout.
+	DefaultDrainTimeout = 30 * time.Second
+
+	// DefaultDCFailureTTL is a default ProxyConfig.DCFailureTTL. It is
+	// long enough to spare reconnecting clients from dial timeouts during
+	// a DC outage and short enough to return to a DC soon after it
+	// recovers.
+	DefaultDCFailureTTL = 5 * time.Second
+
+	// WorkerPoolHighWatermark is a ratio of busy workers in the worker pool
+	// when EventWorkerPoolPressure is emitted.
+	WorkerPoolHighWatermark = 0.9
+
+	// WorkerPoolLowWatermark is a ratio of busy workers in the worker pool
+	// when pressure is considered to be gone. A gap between watermarks
+	// prevents a flood of events when utilization oscillates around
+	// WorkerPoolHighWatermark.
+	WorkerPoolLowWatermark = 0.8
+
 	// SecretKeyLength defines a length of the secret bytes used by Telegram and a
 	// proxy.
 	SecretKeyLength = 16
@@ -94,6 +207,13 @@ const (
 	// ConnectionIDBytesLength defines a count of random bytes used to generate a
 	// stream/connection ids.
 	ConnectionIDBytesLength = 16
+
+	// LoggerFieldStreamID is a name of the field which carries a stream ID.
+	// Every log line written while a stream is processed (handshakes, dial
+	// to Telegram or a fronting domain, relay) has this field. Values are
+	// the same as StreamID of events, so logs and events of a connection
+	// can be matched.
+	LoggerFieldStreamID = "stream-id"
 )
 
 // Network defines a knowledge how to work with a network. It may sound fun but
@@ -124,6 +244,14 @@ type Network interface {
 	// Returns: hits, misses, evictions (uint64), size (int)
 	GetDNSCacheMetrics() (uint64, uint64, uint64, int)
 
+	// GetDNSSkippedQueries returns a number of A and AAAA queries which
+	// were not sent at all because of a DNS query type setting.
+	GetDNSSkippedQueries() (uint64, uint64)
+
+	// GetDoHLimitedQueries returns a number of DNS-over-HTTPS queries
+	// which hit a limit of concurrent queries.
+	GetDoHLimitedQueries() uint64
+
 	// WarmUp pre-resolves a list of hostnames to populate the DNS cache.
 	// This reduces latency for the first connection to each host.
 	// Pass FakeTLS domain and any other frequently accessed hostnames.
@@ -170,6 +298,17 @@ type AntiReplayCache interface {
 	SeenBefore(data []byte) bool
 }
 
+// AntiReplayChecker is an optional extension of AntiReplayCache which can
+// check data without marking it as seen. It is intended for tools and
+// debugging, for example, to find out if a session id of a suspected false
+// positive is known to a cache.
+type AntiReplayChecker interface {
+	// Contains reports if this set of bytes was observed before. Unlike
+	// SeenBefore, it never stores data. Probabilistic caches have the same
+	// false positives for Contains as for SeenBefore.
+	Contains(data []byte) bool
+}
+
 // IPBlocklist filters requests based on IP address.
 //
 // If this filter has an IP address, then mtg closes a request without reading
@@ -188,6 +327,40 @@ type IPBlocklist interface {
 	Shutdown()
 }
 
+// TelegramDialer establishes connections to Telegram datacenters. Proxy
+// uses it to pick a DC for a client, to fall back to another DC on dial
+// errors and to retry a handshake on a fresh connection.
+//
+// mtg has its own implementation built from ProxyOpts.TelegramNetwork.
+// Custom implementations are mostly useful for tests: they let you
+// exercise DC fallback and handshake retries without a real network.
+type TelegramDialer interface {
+	// Dial returns a connection to a given DC. It may be taken from a
+	// connection pool. A connection does not have to be TCP, but then
+	// EventConnectedToDC has no remote IP.
+	Dial(ctx context.Context, dc int) (essentials.Conn, error)
+
+	// DialDirect establishes a new connection to a given DC, bypassing
+	// a connection pool.
+	DialDirect(ctx context.Context, dc int) (essentials.Conn, error)
+
+	// IsKnownDC reports if a given DC has addresses.
+	IsKnownDC(dc int) bool
+
+	// RecentlyFailed reports if a dial to a given DC has recently failed
+	// so it makes sense to go to a fallback DC straight away.
+	RecentlyFailed(dc int) bool
+
+	// GetFallbackDC returns a DC for clients which requested an unknown
+	// one.
+	GetFallbackDC() int
+
+	// GetFallbackDCExcluding returns a DC to use if given ones are
+	// unavailable. It should not return any of them unless there is no
+	// other DC.
+	GetFallbackDCExcluding(dcs ...int) int
+}
+
 // Event is a data structure which is populated during mtg request processing
 // lifecycle. Each request popluates many events:
 //  1. Client connected
@@ -223,6 +396,15 @@ type EventStream interface {
 	Send(context.Context, Event)
 }
 
+// TrafficEventStream is an optional interface of the EventStream.
+// EventTraffic is the most frequent event by far: if a stream implements
+// this interface, mtg sends EventTraffic with SendTraffic, so it is not
+// boxed into Event on each Read and Write of a relay.
+type TrafficEventStream interface {
+	// SendTraffic is Send for EventTraffic.
+	SendTraffic(context.Context, EventTraffic)
+}
+
 // Logger defines an interface of the logger used by mtglib.
 //
 // Each logger has a name. It is possible to stack names to organize poor-man
@@ -242,6 +424,8 @@ type EventStream interface {
 //
 // logger1 should see no param2 and vice versa, logger2 should not see param1
 // If you attach a parameter to a logger, parents should not know about that.
+//
+// Loggers of streams are bound with LoggerFieldStreamID.
 type Logger interface {
 	// Named returns a new logger with a bound name. Name chaining is allowed and
 	// appreciated.
@@ -27,3 +27,11 @@ type EventStreamMock struct {
 func (e *EventStreamMock) Send(ctx context.Context, evt Event) {
 	e.Called(ctx, evt)
 }
+
+type TrafficEventStreamMock struct {
+	EventStreamMock
+}
+
+func (e *TrafficEventStreamMock) SendTraffic(ctx context.Context, evt EventTraffic) {
+	e.Called(ctx, evt)
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,21 +20,29 @@ type ClientHello struct {
 	CipherSuite uint16
 }
 
+// TimeSkew returns a difference between now and a client timestamp. It is
+// positive if client clock is behind and negative if it is ahead.
+func (c ClientHello) TimeSkew(now time.Time) time.Duration {
+	return now.Sub(c.Time)
+}
+
 func (c ClientHello) Valid(hostname string, tolerateTimeSkewness time.Duration) error {
 	if c.Host != "" && c.Host != hostname {
-		return fmt.Errorf("incorrect hostname %s", hostname)
+		// Полученный SNI в ошибку не попадает: ошибка идёт в лог, а SNI
+		// логируется только хэшем.
+		return fmt.Errorf("%w, expected=%q", ErrIncorrectHostname, hostname)
 	}
 
 	now := time.Now()
 
-	timeDiff := now.Sub(c.Time)
+	timeDiff := c.TimeSkew(now)
 	if timeDiff < 0 {
 		timeDiff = -timeDiff
 	}
 
 	if timeDiff > tolerateTimeSkewness {
-		return fmt.Errorf("incorrect timestamp. got=%d, now=%d, diff=%s",
-			c.Time.Unix(), now.Unix(), timeDiff.String())
+		return fmt.Errorf("%w. got=%d, now=%d, diff=%s",
+			ErrIncorrectTimestamp, c.Time.Unix(), now.Unix(), timeDiff.String())
 	}
 
 	return nil
@@ -43,11 +52,11 @@ func ParseClientHello(secret, handshake []byte) (ClientHello, error) {
 	hello := ClientHello{}
 
 	if len(handshake) < ClientHelloMinLen {
-		return hello, fmt.Errorf("lengh of handshake is too small: %d", len(handshake))
+		return hello, fmt.Errorf("%w: lengh of handshake is too small: %d", ErrClientHelloMalformed, len(handshake))
 	}
 
 	if handshake[0] != HandshakeTypeClient {
-		return hello, fmt.Errorf("unknown handshake type %#x", handshake[0])
+		return hello, fmt.Errorf("%w: unknown handshake type %#x", ErrClientHelloMalformed, handshake[0])
 	}
 
 	handshakeSizeBytes := [4]byte{0, handshake[1], handshake[2], handshake[3]}
@@ -55,8 +64,8 @@ func ParseClientHello(secret, handshake []byte) (ClientHello, error) {
 
 	if len(handshake)-4 != int(handshakeLength) {
 		return hello,
-			fmt.Errorf("incorrect handshake size. manifested=%d, real=%d",
-				handshakeLength, len(handshake)-4) //nolint: gomnd
+			fmt.Errorf("%w: incorrect handshake size. manifested=%d, real=%d",
+				ErrClientHelloMalformed, handshakeLength, len(handshake)-4) //nolint: gomnd
 	}
 
 	copy(hello.Random[:], handshake[ClientHelloRandomOffset:])
@@ -88,12 +97,114 @@ func ParseClientHello(secret, handshake []byte) (ClientHello, error) {
 	hello.Time = time.Unix(timestamp, 0)
 
 	if err := safeParseFields(&hello, handshake); err != nil {
-		return hello, fmt.Errorf("failed to parse client hello fields: %w", err)
+		return hello, fmt.Errorf("%w: failed to parse client hello fields: %w", ErrClientHelloMalformed, err)
 	}
 
 	return hello, nil
 }
 
+// ParseSNI достаёт SNI из ClientHello без проверки HMAC. Значение не
+// аутентифицировано, поэтому годится только для статистики: например,
+// чтобы понять, с каким SNI приходят клиенты, ушедшие в domain fronting.
+// Для малформированного handshake возвращается пустая строка.
+func ParseSNI(handshake []byte) (host string) {
+	defer func() {
+		if r := recover(); r != nil {
+			host = ""
+		}
+	}()
+
+	if len(handshake) < ClientHelloMinLen || handshake[0] != HandshakeTypeClient {
+		return ""
+	}
+
+	hello := ClientHello{}
+
+	parseSessionID(&hello, handshake)
+	parseSNI(&hello, handshake)
+
+	return hello.Host
+}
+
+// ClientHelloInfo — то, что клиент предложил в ClientHello. Нужно для
+// диагностики отказов, поэтому session ID сюда не попадает.
+type ClientHelloInfo struct {
+	// Version — legacy_version из ClientHello. У TLS 1.3 это 0x0303,
+	// настоящие версии лежат в SupportedVersions.
+	Version           uint16
+	SupportedVersions []uint16
+	CipherSuites      []uint16
+}
+
+// ParseClientHelloInfo разбирает ClientHello без проверки HMAC, так что
+// значения не аутентифицированы и годятся только для логов. Разбор
+// останавливается на первом несоответствии длин: возвращается то, что
+// удалось прочитать до него.
+func ParseClientHelloInfo(handshake []byte) (info ClientHelloInfo) {
+	// Обрезанный ClientHello: оставляем то, что успели разобрать.
+	defer func() {
+		_ = recover()
+	}()
+
+	if len(handshake) < ClientHelloMinLen || handshake[0] != HandshakeTypeClient {
+		return info
+	}
+
+	info.Version = binary.BigEndian.Uint16(handshake[4:6])
+
+	// random, потом session id
+	handshake = handshake[ClientHelloSessionIDOffset:]
+	handshake = handshake[1+int(handshake[0]):]
+
+	cipherSuitesLength := int(binary.BigEndian.Uint16(handshake[:2]))
+	cipherSuites := handshake[2 : 2+cipherSuitesLength]
+	handshake = handshake[2+cipherSuitesLength:]
+
+	for ; len(cipherSuites) >= 2; cipherSuites = cipherSuites[2:] { //nolint: gomnd
+		info.CipherSuites = append(info.CipherSuites, binary.BigEndian.Uint16(cipherSuites))
+	}
+
+	compressionMethodsLength := int(handshake[0])
+	handshake = handshake[1+compressionMethodsLength:]
+
+	extensionsLength := binary.BigEndian.Uint16(handshake[:2])
+	handshake = handshake[2 : 2+extensionsLength]
+
+	for len(handshake) > 0 {
+		extensionType := binary.BigEndian.Uint16(handshake[:2])
+		extensionLength := int(binary.BigEndian.Uint16(handshake[2:4]))
+		extension := handshake[4 : 4+extensionLength]
+		handshake = handshake[4+extensionLength:]
+
+		if extensionType != ExtensionSupportedVersions {
+			continue
+		}
+
+		versions := extension[1 : 1+int(extension[0])]
+		for ; len(versions) >= 2; versions = versions[2:] { //nolint: gomnd
+			info.SupportedVersions = append(info.SupportedVersions, binary.BigEndian.Uint16(versions))
+		}
+	}
+
+	return info
+}
+
+// FailedCheck называет проверку ClientHello, которая вернула err:
+// hostname, time-skew, digest (не тот секрет или не mtg клиент) или
+// structure.
+func FailedCheck(err error) string {
+	switch {
+	case errors.Is(err, ErrIncorrectHostname):
+		return "hostname"
+	case errors.Is(err, ErrIncorrectTimestamp):
+		return "time-skew"
+	case errors.Is(err, ErrBadDigest):
+		return "digest"
+	}
+
+	return "structure"
+}
+
 // safeParseFields вызывает parse-функции с защитой от паники.
 // HMAC-проверка выше гарантирует аутентичность, но defense in depth
 // защищает от edge cases малформированных данных.
@@ -1,6 +1,7 @@
 package faketls_test
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"os"
@@ -153,6 +154,103 @@ func (suite *ClientHelloTestSuite) TestSnapshotBad() {
 	}
 }
 
+func (suite *ClientHelloTestSuite) TestParseSNI() {
+	files, err := os.ReadDir("testdata")
+	suite.NoError(err)
+
+	for _, v := range files {
+		if !strings.HasPrefix(v.Name(), "client-hello-") {
+			continue
+		}
+
+		path := filepath.Join("testdata", v.Name())
+
+		suite.T().Run(v.Name(), func(t *testing.T) {
+			fileData, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			snapshot := &ClientHelloSnapshot{}
+			assert.NoError(t, json.Unmarshal(fileData, snapshot))
+
+			host := faketls.ParseSNI(snapshot.GetFull())
+
+			if strings.HasPrefix(v.Name(), "client-hello-ok") {
+				assert.Equal(t, snapshot.GetHost(), host)
+			}
+		})
+	}
+
+	suite.Empty(faketls.ParseSNI(nil))
+	suite.Empty(faketls.ParseSNI(bytes.Repeat([]byte{faketls.HandshakeTypeClient}, faketls.ClientHelloMinLen)))
+}
+
+func (suite *ClientHelloTestSuite) TestParseClientHelloInfo() {
+	files, err := os.ReadDir("testdata")
+	suite.NoError(err)
+
+	for _, v := range files {
+		if !strings.HasPrefix(v.Name(), "client-hello-ok") {
+			continue
+		}
+
+		path := filepath.Join("testdata", v.Name())
+
+		suite.T().Run(v.Name(), func(t *testing.T) {
+			fileData, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			snapshot := &ClientHelloSnapshot{}
+			assert.NoError(t, json.Unmarshal(fileData, snapshot))
+
+			info := faketls.ParseClientHelloInfo(snapshot.GetFull())
+
+			assert.EqualValues(t, 0x0303, info.Version)
+			assert.NotEmpty(t, info.CipherSuites)
+			assert.Equal(t, snapshot.GetCipherSuite(), info.CipherSuites[0])
+			assert.Contains(t, info.SupportedVersions, uint16(0x0304))
+		})
+	}
+
+	suite.Empty(faketls.ParseClientHelloInfo(nil))
+}
+
+func (suite *ClientHelloTestSuite) TestParseClientHelloInfoTruncated() {
+	handshake := make([]byte, faketls.ClientHelloSessionIDOffset+7)
+	handshake[0] = faketls.HandshakeTypeClient
+	handshake[4] = 0x03
+	handshake[5] = 0x03
+	handshake[faketls.ClientHelloSessionIDOffset] = 0      // session id
+	handshake[faketls.ClientHelloSessionIDOffset+2] = 4    // cipher suites length
+	handshake[faketls.ClientHelloSessionIDOffset+3] = 0x13 // TLS_AES_128_GCM_SHA256
+	handshake[faketls.ClientHelloSessionIDOffset+4] = 0x01
+	handshake[faketls.ClientHelloSessionIDOffset+5] = 0x13 // TLS_AES_256_GCM_SHA384
+	handshake[faketls.ClientHelloSessionIDOffset+6] = 0x02
+
+	info := faketls.ParseClientHelloInfo(handshake)
+
+	suite.EqualValues(0x0303, info.Version)
+	suite.Equal([]uint16{0x1301, 0x1302}, info.CipherSuites)
+	suite.Empty(info.SupportedVersions)
+}
+
+func (suite *ClientHelloTestSuite) TestFailedCheck() {
+	hello := faketls.ClientHello{
+		Time: time.Now(),
+		Host: "hostname",
+	}
+
+	suite.Equal("hostname", faketls.FailedCheck(hello.Valid("hostname2", time.Second)))
+
+	hello.Time = time.Now().Add(-time.Hour)
+	suite.Equal("time-skew", faketls.FailedCheck(hello.Valid("hostname", time.Second)))
+
+	_, err := faketls.ParseClientHello(suite.secret.Key[:], nil)
+	suite.ErrorIs(err, faketls.ErrClientHelloMalformed)
+	suite.Equal("structure", faketls.FailedCheck(err))
+
+	suite.Equal("digest", faketls.FailedCheck(faketls.ErrBadDigest))
+}
+
 func (suite *ClientHelloTestSuite) TestValidateHostname() {
 	hello := faketls.ClientHello{
 		Time: time.Now(),
@@ -160,7 +258,7 @@ func (suite *ClientHelloTestSuite) TestValidateHostname() {
 	suite.NoError(hello.Valid("hostname", time.Second))
 
 	hello.Host = "hostname"
-	suite.Error(hello.Valid("hostname2", time.Second))
+	suite.ErrorIs(hello.Valid("hostname2", time.Second), faketls.ErrIncorrectHostname)
 	suite.NoError(hello.Valid("hostname", time.Second))
 }
 
@@ -178,13 +276,23 @@ func (suite *ClientHelloTestSuite) TestValidateTime() {
 				Host: "hostname",
 				Time: time.Now().Add(value),
 			}
-			suite.Error(hello.Valid("hostname", 500*time.Millisecond))
-			suite.Error(hello.Valid("hostname", time.Second))
+			suite.ErrorIs(hello.Valid("hostname", 500*time.Millisecond), faketls.ErrIncorrectTimestamp)
+			suite.ErrorIs(hello.Valid("hostname", time.Second), faketls.ErrIncorrectTimestamp)
 			suite.NoError(hello.Valid("hostname", 3*time.Second))
 		})
 	}
 }
 
+func (suite *ClientHelloTestSuite) TestTimeSkew() {
+	now := time.Now()
+
+	hello := faketls.ClientHello{Time: now.Add(-5 * time.Second)}
+	suite.Equal(5*time.Second, hello.TimeSkew(now))
+
+	hello.Time = now.Add(3 * time.Second)
+	suite.Equal(-3*time.Second, hello.TimeSkew(now))
+}
+
 func TestClientHello(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &ClientHelloTestSuite{})
@@ -2,6 +2,7 @@ package faketls
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/9seconds/mtg/v2/essentials"
@@ -24,10 +25,74 @@ import (
 type Conn struct {
 	essentials.Conn
 
-	readBuffer bytes.Buffer
+	// MaxWriteRecordSize — максимальный размер payload одного TLS record
+	// при записи. 0 означает record.TLSMaxWriteRecordSize; значения больше
+	// него урезаются до него же (RFC 8446 Section 5.1).
+	MaxWriteRecordSize int
+
+	// SmallFirstRecords — сколько первых records после хендшейка писать
+	// маленькими, как браузер пишет короткий HTTP/2 SETTINGS перед
+	// полными records. Размер берётся из smallRecordSizes. 0 выключает.
+	SmallFirstRecords int
+
+	readBuffer     bytes.Buffer
+	writtenRecords int
+}
+
+// smallRecordSizes — взвешенное распределение размеров первых records.
+// Основная масса — короткие кадры HTTP/2 (SETTINGS, WINDOW_UPDATE,
+// HEADERS), хвост — первый кусок ответа, не больше MTU.
+var smallRecordSizes = [...]struct {
+	min    int
+	max    int
+	weight int
+}{
+	{min: 24, max: 64, weight: 3},
+	{min: 64, max: 256, weight: 4},
+	{min: 256, max: 1024, weight: 2},
+	{min: 1024, max: 1400, weight: 1},
+}
+
+// smallRecordSize выбирает интервал из smallRecordSizes с учётом веса,
+// а внутри него размер — равномерно.
+func smallRecordSize() int {
+	total := 0
+	for _, v := range smallRecordSizes {
+		total += v.weight
+	}
+
+	chosen := secureRandIntn(total)
+
+	for _, v := range smallRecordSizes {
+		if chosen < v.weight {
+			return v.min + secureRandIntn(v.max-v.min+1)
+		}
+
+		chosen -= v.weight
+	}
+
+	return smallRecordSizes[0].min
+}
+
+func (c *Conn) maxWriteRecordSize() int {
+	if c.MaxWriteRecordSize <= 0 || c.MaxWriteRecordSize > record.TLSMaxWriteRecordSize {
+		return record.TLSMaxWriteRecordSize
+	}
+
+	return c.MaxWriteRecordSize
+}
+
+func (c *Conn) smallRecordsLeft() int {
+	return max(c.SmallFirstRecords-c.writtenRecords, 0)
 }
 
 func (c *Conn) Read(p []byte) (int, error) {
+	// Пустой p не должен тянуть новый record из сокета: данные из
+	// readBuffer ещё не отданы, а Read заблокировался бы на сети.
+	if len(p) == 0 {
+		return 0, nil
+	}
+
 	if n, _ := c.readBuffer.Read(p); n > 0 {
 		return n, nil
 	}
@@ -42,6 +107,13 @@ func (c *Conn) Read(p []byte) (int, error) {
 
 		switch rec.Type { //nolint: exhaustive
 		case record.TypeApplicationData:
+			// Пустой record допустим по RFC 8446 Section 5.1. Отдавать
+			// из него нечего, а readBuffer.Read на пустом буфере вернул
+			// бы io.EOF — и relay счёл бы соединение закрытым.
+			if rec.Payload.Len() == 0 {
+				continue
+			}
+
 			rec.Payload.WriteTo(&c.readBuffer) //nolint: errcheck
 
 			return c.readBuffer.Read(p) //nolint: wrapcheck
@@ -55,35 +127,46 @@ func (c *Conn) Read(p []byte) (int, error) {
 }
 
 func (c *Conn) Write(p []byte) (int, error) {
-	rec := record.AcquireRecord()
-	defer record.ReleaseRecord(rec)
-
-	rec.Type = record.TypeApplicationData
-	rec.Version = record.Version12
-
 	sendBuffer := acquireBytesBuffer()
 	defer releaseBytesBuffer(sendBuffer)
 
 	lenP := len(p)
+	maxChunkSize := c.maxWriteRecordSize()
+
+	// Records пишутся сразу в sendBuffer, без промежуточного
+	// record.Payload: payload копируется один раз, а весь Write уходит
+	// в сокет одним вызовом.
+	sendBuffer.Grow(lenP + record.HeaderSize*((lenP+maxChunkSize-1)/maxChunkSize+c.smallRecordsLeft()))
+
+	header := [record.HeaderSize]byte{byte(record.TypeApplicationData)}
+	binary.BigEndian.PutUint16(header[1:], uint16(record.Version12))
 
 	for len(p) > 0 {
 		// Chrome/Firefox TLS 1.3 профиль: полные 16384-байтные records.
 		// Реальные TLS-стеки всегда заполняют records до максимума при bulk transfer.
 		// Последний record содержит оставшиеся данные (< 16384).
+		// Максимум можно уменьшить через MaxWriteRecordSize, а первые
+		// records сделать маленькими через SmallFirstRecords.
 		//
 		// Предыдущее поведение (uniform random [256, 16384]) создавало уникальный
 		// fingerprint: ни один реальный TLS-стек не генерирует равномерно случайные
 		// размеры records. DPI-системы (GFW, Roskomnadzor) детектируют это.
-		chunkSize := record.TLSMaxWriteRecordSize
+		chunkSize := maxChunkSize
+		if c.smallRecordsLeft() > 0 {
+			chunkSize = min(smallRecordSize(), maxChunkSize)
+		}
+
 		if chunkSize > len(p) {
 			chunkSize = len(p)
 		}
 
-		rec.Payload.Reset()
-		rec.Payload.Write(p[:chunkSize])
-		rec.Dump(sendBuffer) //nolint: errcheck
+		binary.BigEndian.PutUint16(header[3:], uint16(chunkSize)) //nolint: gosec
+
+		sendBuffer.Write(header[:])
+		sendBuffer.Write(p[:chunkSize])
 
 		p = p[chunkSize:]
+		c.writtenRecords++
 	}
 
 	if _, err := c.Conn.Write(sendBuffer.Bytes()); err != nil {
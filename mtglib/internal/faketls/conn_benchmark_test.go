@@ -0,0 +1,83 @@
+package faketls_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/9seconds/mtg/v2/mtglib/internal/faketls"
+	"github.com/9seconds/mtg/v2/mtglib/internal/obfuscated2"
+)
+
+// writeCountingConn считает Write: каждый из них — как минимум один
+// write() syscall на настоящем сокете.
+type writeCountingConn struct {
+	testlib.EssentialsConnMock
+
+	writes int
+}
+
+func (w *writeCountingConn) Write(p []byte) (int, error) {
+	w.writes++
+
+	return len(p), nil
+}
+
+// chunkedReader отдаёт данные кусками не больше chunkSize, как сокет
+// Telegram в relay.
+type chunkedReader struct {
+	left      int
+	chunkSize int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.left == 0 {
+		return 0, io.EOF
+	}
+
+	n := min(len(p), c.chunkSize, c.left)
+	c.left -= n
+
+	return n, nil
+}
+
+// BenchmarkDownloadWrites измеряет число Write в сокет клиента на
+// мегабайт download через те же слои, что и relay: obfuscated2 поверх
+// faketls.
+func BenchmarkDownloadWrites(b *testing.B) {
+	const downloadSize = 8 * 1024 * 1024
+
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, chunkSize := range []int{1400, 16 * 1024, 128 * 1024} {
+		b.Run(fmt.Sprintf("read=%d", chunkSize), func(b *testing.B) {
+			b.SetBytes(downloadSize)
+			b.ReportAllocs()
+
+			buf := make([]byte, 256*1024)
+			writes := 0
+
+			for range b.N {
+				sock := &writeCountingConn{}
+				conn := obfuscated2.Conn{
+					Conn:      &faketls.Conn{Conn: sock},
+					Encryptor: cipher.NewCTR(block, make([]byte, aes.BlockSize)),
+				}
+
+				if _, err := io.CopyBuffer(conn, &chunkedReader{left: downloadSize, chunkSize: chunkSize}, buf); err != nil {
+					b.Fatal(err)
+				}
+
+				writes += sock.writes
+			}
+
+			b.ReportMetric(float64(writes)/float64(b.N)/(downloadSize/(1024*1024)), "writes/MB")
+		})
+	}
+}
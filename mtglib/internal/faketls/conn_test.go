@@ -85,6 +85,83 @@ func (suite *ConnTestSuite) TestRead() {
 	suite.Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, resultBuffer.Bytes())
 }
 
+// TestReadMaxRecordsSmallBuffer читает records максимального размера
+// вперемешку с CCS через 2-байтный буфер: границы records не должны
+// терять или дублировать данные.
+func (suite *ConnTestSuite) TestReadMaxRecordsSmallBuffer() {
+	suite.connMock.On("Read", mock.Anything).Return(0, nil)
+
+	rec := record.AcquireRecord()
+	defer record.ReleaseRecord(rec)
+
+	expected := &bytes.Buffer{}
+
+	for _, size := range []int{record.TLSMaxWriteRecordSize, record.TLSMaxWriteRecordSize, 1, record.TLSMaxWriteRecordSize - 1} {
+		rec.Reset()
+		rec.Type = record.TypeChangeCipherSpec
+		rec.Version = record.Version12
+		rec.Payload.WriteByte(0x01)
+		rec.Dump(&suite.connMock.readBuffer) //nolint: errcheck
+
+		payload := make([]byte, size)
+		rand.Read(payload)
+		expected.Write(payload)
+
+		rec.Reset()
+		rec.Type = record.TypeApplicationData
+		rec.Version = record.Version12
+		rec.Payload.Write(payload)
+		rec.Dump(&suite.connMock.readBuffer) //nolint: errcheck
+	}
+
+	resultBuffer := &bytes.Buffer{}
+	buf := make([]byte, 2)
+
+	for {
+		n, err := suite.c.Read(buf)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		suite.NoError(err)
+		resultBuffer.Write(buf[:n])
+	}
+
+	suite.Equal(expected.Bytes(), resultBuffer.Bytes())
+}
+
+func (suite *ConnTestSuite) TestReadEmptyRecord() {
+	suite.connMock.On("Read", mock.Anything).Return(0, nil)
+
+	rec := record.AcquireRecord()
+	defer record.ReleaseRecord(rec)
+
+	rec.Type = record.TypeApplicationData
+	rec.Version = record.Version12
+	rec.Dump(&suite.connMock.readBuffer) //nolint: errcheck
+
+	rec.Payload.Write([]byte{1, 2, 3})
+	rec.Dump(&suite.connMock.readBuffer) //nolint: errcheck
+
+	buf := make([]byte, 2)
+
+	n, err := suite.c.Read(buf[:0])
+	suite.NoError(err)
+	suite.Equal(0, n)
+
+	n, err = suite.c.Read(buf)
+	suite.NoError(err)
+	suite.Equal([]byte{1, 2}, buf[:n])
+
+	n, err = suite.c.Read(buf[:0])
+	suite.NoError(err)
+	suite.Equal(0, n)
+
+	n, err = suite.c.Read(buf)
+	suite.NoError(err)
+	suite.Equal([]byte{3}, buf[:n])
+}
+
 func (suite *ConnTestSuite) TestReadUnexpected() {
 	suite.connMock.On("Read", mock.Anything).Return(0, nil)
 
@@ -129,6 +206,9 @@ func (suite *ConnTestSuite) TestWrite() {
 	suite.NoError(err)
 	suite.Equal(len(dataToRec), n)
 
+	// Все records уходят в сокет одним Write.
+	suite.connMock.AssertNumberOfCalls(suite.T(), "Write", 1)
+
 	rec := record.AcquireRecord()
 	defer record.ReleaseRecord(rec)
 
@@ -148,39 +228,108 @@ func (suite *ConnTestSuite) TestWrite() {
 }
 
 // TestWriteChromeLikeRecordSizes проверяет, что Chrome-like распределение
-// создаёт полные 16384-байтные records для больших данных.
+// создаёт полные records заданного размера для больших данных, а данные
+// собираются обратно без потерь.
 func (suite *ConnTestSuite) TestWriteChromeLikeRecordSizes() {
+	testData := map[string]struct {
+		configured int
+		expected   int
+	}{
+		"default":   {configured: 0, expected: record.TLSMaxWriteRecordSize},
+		"max":       {configured: record.TLSMaxWriteRecordSize, expected: record.TLSMaxWriteRecordSize},
+		"small":     {configured: 1400, expected: 1400},
+		"too-large": {configured: record.TLSMaxWriteRecordSize + 1, expected: record.TLSMaxWriteRecordSize},
+	}
+
+	for name, tc := range testData {
+		suite.Run(name, func() {
+			connMock := &ConnMock{}
+			connMock.On("Write", mock.Anything).Return(0, nil)
+
+			conn := &faketls.Conn{
+				Conn:               connMock,
+				MaxWriteRecordSize: tc.configured,
+			}
+
+			// 3 полных record + 1 с остатком
+			dataSize := tc.expected*3 + 100
+			data := make([]byte, dataSize)
+			rand.Read(data)
+
+			n, err := conn.Write(data)
+			suite.NoError(err)
+			suite.Equal(dataSize, n)
+
+			rec := record.AcquireRecord()
+			defer record.ReleaseRecord(rec)
+
+			var recordSizes []int
+
+			reconstructed := &bytes.Buffer{}
+
+			for {
+				if err := rec.Read(&connMock.writeBuffer); err != nil {
+					break
+				}
+
+				suite.Equal(record.TypeApplicationData, rec.Type)
+				recordSizes = append(recordSizes, rec.Payload.Len())
+				rec.Payload.WriteTo(reconstructed) //nolint: errcheck
+			}
+
+			suite.Equal([]int{tc.expected, tc.expected, tc.expected, 100}, recordSizes)
+			suite.Equal(data, reconstructed.Bytes())
+			connMock.AssertExpectations(suite.T())
+		})
+	}
+}
+
+// TestWriteSmallFirstRecords проверяет, что только первые
+// SmallFirstRecords records маленькие, в том числе если они разнесены
+// по нескольким Write, а остальные — полного размера.
+func (suite *ConnTestSuite) TestWriteSmallFirstRecords() {
 	suite.connMock.On("Write", mock.Anything).Return(0, nil)
 
-	// 16384*3 + 100 байт — ожидаем 3 полных record + 1 с остатком
-	dataSize := record.TLSMaxWriteRecordSize*3 + 100
-	data := make([]byte, dataSize)
+	suite.c.SmallFirstRecords = 3
+
+	data := make([]byte, record.TLSMaxWriteRecordSize*4)
 	rand.Read(data)
 
-	n, err := suite.c.Write(data)
-	suite.NoError(err)
-	suite.Equal(dataSize, n)
+	// Первый Write даёт один record: маленький размер не больше данных.
+	for _, chunk := range [][]byte{data[:10], data[10:]} {
+		n, err := suite.c.Write(chunk)
+		suite.NoError(err)
+		suite.Equal(len(chunk), n)
+	}
 
 	rec := record.AcquireRecord()
 	defer record.ReleaseRecord(rec)
 
 	var recordSizes []int
 
+	reconstructed := &bytes.Buffer{}
+
 	for {
 		if err := rec.Read(&suite.connMock.writeBuffer); err != nil {
 			break
 		}
 
-		suite.Equal(record.TypeApplicationData, rec.Type)
 		recordSizes = append(recordSizes, rec.Payload.Len())
+		rec.Payload.WriteTo(reconstructed) //nolint: errcheck
 	}
 
-	// Ожидаем 4 records: 3 × 16384 + 1 × 100
-	suite.Len(recordSizes, 4)
-	suite.Equal(record.TLSMaxWriteRecordSize, recordSizes[0])
-	suite.Equal(record.TLSMaxWriteRecordSize, recordSizes[1])
-	suite.Equal(record.TLSMaxWriteRecordSize, recordSizes[2])
-	suite.Equal(100, recordSizes[3])
+	suite.Equal(data, reconstructed.Bytes())
+	suite.Greater(len(recordSizes), 4)
+	suite.Equal(10, recordSizes[0])
+
+	for _, size := range recordSizes[1:3] {
+		suite.GreaterOrEqual(size, 24)
+		suite.LessOrEqual(size, 1400)
+	}
+
+	for _, size := range recordSizes[3 : len(recordSizes)-1] {
+		suite.Equal(record.TLSMaxWriteRecordSize, size)
+	}
 }
 
 // A5: CCS padding удалён — тест TestWriteWithCCSPadding удалён.
@@ -37,6 +37,10 @@ const (
 
 	// ExtensionSNI is a value for TLS extension 'SNI'.
 	ExtensionSNI = 0x00
+
+	// ExtensionSupportedVersions is a value for TLS extension
+	// 'supported_versions'.
+	ExtensionSupportedVersions = 0x2b
 )
 
 var (
@@ -44,6 +48,23 @@ var (
 	// derived one.
 	ErrBadDigest = errors.New("bad digest")
 
+	// ErrClientHelloMalformed is returned if TLS Client Hello cannot be
+	// parsed: it is truncated, has a wrong type or inconsistent lengths.
+	ErrClientHelloMalformed = errors.New("malformed client hello")
+
+	// ErrIncorrectHostname is returned if SNI of TLS Client Hello
+	// mismatches with a hostname of the secret.
+	ErrIncorrectHostname = errors.New("incorrect hostname")
+
+	// ErrIncorrectTimestamp is returned if a timestamp of TLS Client Hello
+	// is out of a time skewness tolerance.
+	ErrIncorrectTimestamp = errors.New("incorrect timestamp")
+
+	// ErrWelcomePacketTruncated is returned if only a part of the welcome
+	// packet was sent to a client. Such a connection is useless: client
+	// waits for the rest of the packet until timeout.
+	ErrWelcomePacketTruncated = errors.New("welcome packet is truncated")
+
 	serverHelloSuffix = []byte{
 		0x00,       // no compression
 		0x00, 0x2e, // 46 bytes of data
@@ -1,6 +1,12 @@
 package record
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// HeaderSize — размер заголовка TLS record: type(1) + version(2) + length(2).
+const HeaderSize = 5
 
 const TLSMaxRecordSize = 65535 // max uint16 — для чтения (принимаем любой валидный TLS record)
 
@@ -92,3 +98,26 @@ func (v Version) Valid() error {
 
 	return fmt.Errorf("unknown version %d", uint16(v))
 }
+
+// ValidateHandshakeHeader дёшево проверяет, что первые байты соединения
+// похожи на заголовок TLS record с ClientHello. Позволяет отсеять
+// сканеры с мусором до полного разбора ClientHello.
+//
+// RFC 8446 Section 5.1: длина plaintext record не превышает 2^14.
+func ValidateHandshakeHeader(header [HeaderSize]byte) error {
+	if Type(header[0]) != TypeHandshake {
+		return fmt.Errorf("not a handshake record: %v", Type(header[0]))
+	}
+
+	version := Version(binary.BigEndian.Uint16(header[1:3]))
+	if err := version.Valid(); err != nil {
+		return fmt.Errorf("invalid version: %w", err)
+	}
+
+	length := binary.BigEndian.Uint16(header[3:5])
+	if length == 0 || length > TLSMaxWriteRecordSize {
+		return fmt.Errorf("invalid record length %d", length)
+	}
+
+	return nil
+}
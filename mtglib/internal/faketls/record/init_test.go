@@ -68,6 +68,31 @@ func (suite *VersionTestSuite) TestUnknown() {
 	suite.Error(value.Valid())
 }
 
+type HandshakeHeaderTestSuite struct {
+	suite.Suite
+}
+
+func (suite *HandshakeHeaderTestSuite) TestValid() {
+	suite.NoError(record.ValidateHandshakeHeader([record.HeaderSize]byte{0x16, 0x03, 0x01, 0x02, 0x00}))
+	suite.NoError(record.ValidateHandshakeHeader([record.HeaderSize]byte{0x16, 0x03, 0x03, 0x40, 0x00}))
+}
+
+func (suite *HandshakeHeaderTestSuite) TestInvalid() {
+	testData := map[string][record.HeaderSize]byte{
+		"http":             {'G', 'E', 'T', ' ', '/'},
+		"application-data": {0x17, 0x03, 0x03, 0x02, 0x00},
+		"ssl2":             {0x16, 0x02, 0x00, 0x02, 0x00},
+		"zero-length":      {0x16, 0x03, 0x01, 0x00, 0x00},
+		"too-long":         {0x16, 0x03, 0x01, 0x40, 0x01},
+	}
+
+	for name, header := range testData {
+		suite.Run(name, func() {
+			suite.Error(record.ValidateHandshakeHeader(header))
+		})
+	}
+}
+
 func TestType(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &TypeTestSuite{})
@@ -77,3 +102,8 @@ func TestVersion(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &VersionTestSuite{})
 }
+
+func TestHandshakeHeader(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &HandshakeHeaderTestSuite{})
+}
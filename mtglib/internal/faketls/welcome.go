@@ -12,6 +12,12 @@ import (
 	"golang.org/x/crypto/curve25519"
 )
 
+// SendWelcomePacket writes a ServerHello, ChangeCipherSpec and a random
+// ApplicationData record to the writer.
+//
+// The packet is written in full: short writes are retried until all bytes
+// are sent. If writer fails in the middle, ErrWelcomePacketTruncated is
+// returned.
 func SendWelcomePacket(writer io.Writer, secret []byte, clientHello ClientHello) error {
 	buf := acquireBytesBuffer()
 	defer releaseBytesBuffer(buf)
@@ -50,8 +56,24 @@ func SendWelcomePacket(writer io.Writer, secret []byte, clientHello ClientHello)
 
 	copy(packet[WelcomePacketRandomOffset:], mac.Sum(nil))
 
-	if _, err := writer.Write(packet); err != nil {
-		return err //nolint: wrapcheck
+	return writeFull(writer, packet)
+}
+
+func writeFull(writer io.Writer, packet []byte) error {
+	sent := 0
+
+	for sent < len(packet) {
+		n, err := writer.Write(packet[sent:])
+		sent += n
+
+		switch {
+		case err != nil && sent == 0:
+			return err //nolint: wrapcheck
+		case err != nil:
+			return fmt.Errorf("%w (%d of %d bytes): %w", ErrWelcomePacketTruncated, sent, len(packet), err)
+		case n == 0:
+			return fmt.Errorf("%w (%d of %d bytes): %w", ErrWelcomePacketTruncated, sent, len(packet), io.ErrShortWrite)
+		}
 	}
 
 	return nil
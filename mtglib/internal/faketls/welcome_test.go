@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
+	"errors"
+	"io"
 	"math/rand"
 	"testing"
 	"time"
@@ -14,6 +16,26 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+// chunkedWriter accepts at most chunkSize bytes per Write call. If limit
+// is positive, it fails after limit bytes are written.
+type chunkedWriter struct {
+	buf       bytes.Buffer
+	chunkSize int
+	limit     int
+}
+
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	if c.limit > 0 && c.buf.Len() >= c.limit {
+		return 0, errors.New("connection reset by peer")
+	}
+
+	if len(p) > c.chunkSize {
+		p = p[:c.chunkSize]
+	}
+
+	return c.buf.Write(p)
+}
+
 type WelcomeTestSuite struct {
 	suite.Suite
 
@@ -76,6 +98,42 @@ func (suite *WelcomeTestSuite) TestOk() {
 	suite.Equal(random, mac.Sum(nil))
 }
 
+func (suite *WelcomeTestSuite) TestChunkedWrites() {
+	writer := &chunkedWriter{chunkSize: 7}
+
+	suite.NoError(faketls.SendWelcomePacket(writer, suite.secret.Key[:], *suite.h))
+
+	rec := record.AcquireRecord()
+	defer record.ReleaseRecord(rec)
+
+	for _, recordType := range []record.Type{
+		record.TypeHandshake,
+		record.TypeChangeCipherSpec,
+		record.TypeApplicationData,
+	} {
+		suite.NoError(rec.Read(&writer.buf))
+		suite.Equal(recordType, rec.Type)
+	}
+
+	suite.Empty(writer.buf.Bytes())
+}
+
+func (suite *WelcomeTestSuite) TestPartialWrite() {
+	writer := &chunkedWriter{chunkSize: 16, limit: 64}
+
+	err := faketls.SendWelcomePacket(writer, suite.secret.Key[:], *suite.h)
+	suite.ErrorIs(err, faketls.ErrWelcomePacketTruncated)
+	suite.Equal(64, writer.buf.Len())
+}
+
+func (suite *WelcomeTestSuite) TestNoProgress() {
+	writer := &chunkedWriter{chunkSize: 0}
+
+	err := faketls.SendWelcomePacket(writer, suite.secret.Key[:], *suite.h)
+	suite.ErrorIs(err, faketls.ErrWelcomePacketTruncated)
+	suite.ErrorIs(err, io.ErrShortWrite)
+}
+
 func TestWelcome(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &WelcomeTestSuite{})
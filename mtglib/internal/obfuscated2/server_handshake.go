@@ -4,10 +4,28 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
 
+var (
+	// ErrServerHandshakeExhausted означает, что за maxAttempts попыток
+	// не удалось сгенерировать валидный фрейм. На практике это возможно
+	// только при сломанном RNG, поэтому на эту ошибку нужно алертить.
+	ErrServerHandshakeExhausted = errors.New("handshake frame generation exhausted")
+
+	// ErrServerHandshakeCipherInit означает ошибку инициализации AES-CTR.
+	ErrServerHandshakeCipherInit = errors.New("handshake cipher init failed")
+
+	// ErrServerHandshakeWrite означает ошибку отправки фрейма в Telegram.
+	ErrServerHandshakeWrite = errors.New("handshake write failed")
+)
+
+// serverHandshakeRandom — источник случайных данных для фрейма.
+// Подменяется в тестах.
+var serverHandshakeRandom io.Reader = rand.Reader
+
 type serverHandshakeFrame struct {
 	handshakeFrame
 }
@@ -31,12 +49,12 @@ func ServerHandshake(writer io.Writer) (cipher.Stream, cipher.Stream, error) {
 	copyHandshake := handshake
 	encryptor, err := handshake.encryptor()
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot create encryptor: %w", err)
+		return nil, nil, fmt.Errorf("cannot create encryptor: %w: %w", ErrServerHandshakeCipherInit, err)
 	}
 
 	decryptor, err := handshake.decryptor()
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot create decryptor: %w", err)
+		return nil, nil, fmt.Errorf("cannot create decryptor: %w: %w", ErrServerHandshakeCipherInit, err)
 	}
 
 	encryptor.XORKeyStream(handshake.data[:], handshake.data[:])
@@ -44,7 +62,7 @@ func ServerHandshake(writer io.Writer) (cipher.Stream, cipher.Stream, error) {
 	copy(handshake.iv(), copyHandshake.iv())
 
 	if _, err := writer.Write(handshake.data[:]); err != nil {
-		return nil, nil, fmt.Errorf("cannot send a handshake frame to telegram: %w", err)
+		return nil, nil, fmt.Errorf("cannot send a handshake frame to telegram: %w: %w", ErrServerHandshakeWrite, err)
 	}
 
 	return encryptor, decryptor, nil
@@ -61,7 +79,7 @@ func generateServerHanshakeFrame() (serverHandshakeFrame, error) {
 	for i := range maxAttempts {
 		_ = i
 
-		if _, err := rand.Read(frame.data[:]); err != nil {
+		if _, err := io.ReadFull(serverHandshakeRandom, frame.data[:]); err != nil {
 			return serverHandshakeFrame{}, fmt.Errorf("cannot generate random data: %w", err)
 		}
 
@@ -83,5 +101,5 @@ func generateServerHanshakeFrame() (serverHandshakeFrame, error) {
 		return frame, nil
 	}
 
-	return serverHandshakeFrame{}, fmt.Errorf("failed to generate valid handshake frame after %d attempts", maxAttempts)
+	return serverHandshakeFrame{}, fmt.Errorf("%w: %d attempts", ErrServerHandshakeExhausted, maxAttempts)
 }
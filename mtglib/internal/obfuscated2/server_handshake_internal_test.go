@@ -0,0 +1,34 @@
+package obfuscated2
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestServerHandshakeExhausted(t *testing.T) {
+	oldRandom := serverHandshakeRandom
+	serverHandshakeRandom = bytes.NewReader(bytes.Repeat([]byte{0xef}, 100*handshakeFrameLen))
+
+	defer func() {
+		serverHandshakeRandom = oldRandom
+	}()
+
+	_, _, err := ServerHandshake(io.Discard)
+	assert.ErrorIs(t, err, ErrServerHandshakeExhausted)
+}
+
+func TestServerHandshakeWriteFailed(t *testing.T) {
+	_, _, err := ServerHandshake(failingWriter{})
+	assert.ErrorIs(t, err, ErrServerHandshakeWrite)
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+	assert.NotErrorIs(t, err, ErrServerHandshakeExhausted)
+}
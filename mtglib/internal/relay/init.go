@@ -1,12 +1,99 @@
 package relay
 
+import "time"
+
 const (
-	// 256KB буфер для копирования данных между соединениями.
-	// Оптимально для медиа-трафика Telegram: меньше syscalls, лучший throughput.
-	// Размер выбран как BDP для мобильных сетей (100Mbps × 20ms RTT ≈ 250KB).
-	copyBufferSize = 262144 // 256 KB
+	// DefaultCopyBufferSize — 256KB буфер для копирования данных между
+	// соединениями. Оптимально для медиа-трафика Telegram: меньше syscalls,
+	// лучший throughput. Размер выбран как BDP для мобильных сетей
+	// (100Mbps × 20ms RTT ≈ 250KB).
+	DefaultCopyBufferSize = 262144 // 256 KB
+
+	// DefaultTCPUserTimeout — TCP_USER_TIMEOUT по умолчанию.
+	DefaultTCPUserTimeout = 30 * time.Second
+
+	// DefaultWindowClamp — TCP_WINDOW_CLAMP по умолчанию: 128KB,
+	// DEFAULT_WINDOW_CLAMP из оригинального MTProxy.
+	DefaultWindowClamp = 131072
 )
 
 type Logger interface {
 	Printf(msg string, args ...interface{})
 }
+
+// Options — TCP-настройки сокетов relay. Нулевые значения заменяются
+// на значения по умолчанию.
+type Options struct {
+	// TCPUserTimeout — сколько ждать ACK перед закрытием соединения.
+	TCPUserTimeout time.Duration
+
+	// ClientTCPUserTimeout и TelegramTCPUserTimeout переопределяют
+	// TCPUserTimeout для соединения с клиентом и с Telegram. 0 — берётся
+	// TCPUserTimeout.
+	ClientTCPUserTimeout   time.Duration
+	TelegramTCPUserTimeout time.Duration
+
+	// WindowClamp — ограничение receive window соединения к Telegram.
+	WindowClamp int
+
+	// CopyBufferSize — размер буфера копирования. На каждое соединение
+	// их два (по одному на направление), и держатся они всё время жизни
+	// соединения.
+	CopyBufferSize int
+
+	// IOTimeout — сколько relay может жить без передачи данных в обе
+	// стороны и сколько может блокироваться один Write. Read молчащего
+	// направления ждёт, пока идут данные в обратную сторону. 0 —
+	// выключено.
+	IOTimeout time.Duration
+
+	// QuickACKInterval — как часто pump заново взводит TCP_QUICKACK на
+	// сокете, из которого читает: раз в столько Read. Ядро само
+	// сбрасывает quickack mode, так что одной установки хватает ненадолго,
+	// но каждая установка — это setsockopt. 0 — только при старте relay.
+	QuickACKInterval int
+
+	// DisableQuickACK — relay вообще не трогает TCP_QUICKACK,
+	// QuickACKInterval игнорируется.
+	DisableQuickACK bool
+}
+
+func (o Options) getTCPUserTimeout() time.Duration {
+	if o.TCPUserTimeout <= 0 {
+		return DefaultTCPUserTimeout
+	}
+
+	return o.TCPUserTimeout
+}
+
+func (o Options) getClientTCPUserTimeout() time.Duration {
+	if o.ClientTCPUserTimeout <= 0 {
+		return o.getTCPUserTimeout()
+	}
+
+	return o.ClientTCPUserTimeout
+}
+
+func (o Options) getTelegramTCPUserTimeout() time.Duration {
+	if o.TelegramTCPUserTimeout <= 0 {
+		return o.getTCPUserTimeout()
+	}
+
+	return o.TelegramTCPUserTimeout
+}
+
+func (o Options) getCopyBufferSize() int {
+	if o.CopyBufferSize <= 0 {
+		return DefaultCopyBufferSize
+	}
+
+	return o.CopyBufferSize
+}
+
+func (o Options) getWindowClamp() int {
+	if o.WindowClamp <= 0 {
+		return DefaultWindowClamp
+	}
+
+	return o.WindowClamp
+}
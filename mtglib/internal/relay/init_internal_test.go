@@ -0,0 +1,25 @@
+package relay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsTCPUserTimeout(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{}
+	assert.Equal(t, DefaultTCPUserTimeout, opts.getClientTCPUserTimeout())
+	assert.Equal(t, DefaultTCPUserTimeout, opts.getTelegramTCPUserTimeout())
+
+	opts.TCPUserTimeout = time.Minute
+	assert.Equal(t, time.Minute, opts.getClientTCPUserTimeout())
+	assert.Equal(t, time.Minute, opts.getTelegramTCPUserTimeout())
+
+	opts.ClientTCPUserTimeout = 2 * time.Minute
+	opts.TelegramTCPUserTimeout = 10 * time.Second
+	assert.Equal(t, 2*time.Minute, opts.getClientTCPUserTimeout())
+	assert.Equal(t, 10*time.Second, opts.getTelegramTCPUserTimeout())
+}
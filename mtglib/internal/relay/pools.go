@@ -2,18 +2,30 @@ package relay
 
 import "sync"
 
-var copyBufferPool = sync.Pool{
-	New: func() interface{} {
-		rv := make([]byte, copyBufferSize)
+// copyBufferPools хранит sync.Pool на каждый размер буфера. На практике
+// размер один на процесс, но разные Proxy могут использовать разные.
+var copyBufferPools sync.Map // map[int]*sync.Pool
 
-		return &rv
-	},
+func getCopyBufferPool(size int) *sync.Pool {
+	if pool, ok := copyBufferPools.Load(size); ok {
+		return pool.(*sync.Pool) //nolint: forcetypeassert
+	}
+
+	pool, _ := copyBufferPools.LoadOrStore(size, &sync.Pool{
+		New: func() interface{} {
+			rv := make([]byte, size)
+
+			return &rv
+		},
+	})
+
+	return pool.(*sync.Pool) //nolint: forcetypeassert
 }
 
-func acquireCopyBuffer() *[]byte {
-	return copyBufferPool.Get().(*[]byte) //nolint: forcetypeassert
+func acquireCopyBuffer(size int) *[]byte {
+	return getCopyBufferPool(size).Get().(*[]byte) //nolint: forcetypeassert
 }
 
 func releaseCopyBuffer(buf *[]byte) {
-	copyBufferPool.Put(buf)
+	getCopyBufferPool(len(*buf)).Put(buf)
 }
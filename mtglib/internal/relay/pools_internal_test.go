@@ -0,0 +1,31 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyBufferSizes(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{4096, DefaultCopyBufferSize, 1024 * 1024} {
+		buf := acquireCopyBuffer(size)
+		assert.Len(t, *buf, size)
+
+		releaseCopyBuffer(buf)
+
+		// буфер другого размера не должен вернуться из чужого пула
+		other := acquireCopyBuffer(size + 1)
+		assert.Len(t, *other, size+1)
+
+		releaseCopyBuffer(other)
+	}
+}
+
+func TestOptionsCopyBufferSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DefaultCopyBufferSize, Options{}.getCopyBufferSize())
+	assert.Equal(t, 16384, Options{CopyBufferSize: 16384}.getCopyBufferSize())
+}
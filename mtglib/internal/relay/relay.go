@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
 )
@@ -15,6 +18,99 @@ func copyRelay(dst, src essentials.Conn, buf []byte) (int64, error) {
 	return io.CopyBuffer(dst, src, buf)
 }
 
+// relayActivity — время последней передачи данных в любом из
+// направлений relay. Общее для обоих pump.
+type relayActivity struct {
+	last atomic.Int64
+}
+
+func newRelayActivity() *relayActivity {
+	activity := &relayActivity{}
+	activity.touch()
+
+	return activity
+}
+
+func (r *relayActivity) touch() {
+	r.last.Store(time.Now().UnixNano())
+}
+
+func (r *relayActivity) lastAt() time.Time {
+	return time.Unix(0, r.last.Load())
+}
+
+// deadlineConn обрывает relay, в котором данные не идут ни в одну
+// сторону дольше timeout, или запись в котором заблокирована дольше
+// timeout. В отличие от TCP_USER_TIMEOUT это ловит и соединения, где
+// пир держит TCP живым, но данных не шлёт.
+//
+// Read может ждать сколько угодно, пока данные идут в обратную
+// сторону: при долгом download upload обычно молчит.
+type deadlineConn struct {
+	essentials.Conn
+
+	timeout  time.Duration
+	activity *relayActivity
+}
+
+func (d deadlineConn) Read(p []byte) (int, error) {
+	for {
+		if err := d.Conn.SetReadDeadline(d.activity.lastAt().Add(d.timeout)); err != nil {
+			return 0, err //nolint: wrapcheck
+		}
+
+		n, err := d.Conn.Read(p)
+		if n > 0 {
+			d.activity.touch()
+		}
+
+		// Пока ждали, данные прошли в другую сторону: deadline считается
+		// от них заново.
+		if n == 0 && errors.Is(err, os.ErrDeadlineExceeded) &&
+			time.Since(d.activity.lastAt()) < d.timeout {
+			continue
+		}
+
+		return n, err //nolint: wrapcheck
+	}
+}
+
+func (d deadlineConn) Write(p []byte) (int, error) {
+	if err := d.Conn.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil {
+		return 0, err //nolint: wrapcheck
+	}
+
+	n, err := d.Conn.Write(p)
+	if n > 0 {
+		d.activity.touch()
+	}
+
+	return n, err //nolint: wrapcheck
+}
+
+// quickACKConn заново взводит TCP_QUICKACK на исходном сокете каждые
+// interval вызовов Read.
+type quickACKConn struct {
+	essentials.Conn
+
+	socket   essentials.Conn
+	interval int
+	reads    int
+}
+
+func (q *quickACKConn) Read(p []byte) (int, error) {
+	q.reads++
+	if q.reads >= q.interval {
+		q.reads = 0
+		setQuickACK(q.socket)
+	}
+
+	return q.Conn.Read(p) //nolint: wrapcheck
+}
+
+// setQuickACK — точка подмены setTCPQuickACK для бенчмарков.
+var setQuickACK = func(conn essentials.Conn) { setTCPQuickACK(conn) }
+
 // Направление передачи данных
 type direction int
 
@@ -23,7 +119,15 @@ const (
 	dirDownload                  // telegram -> client (приоритетное)
 )
 
+// Relay перекачивает данные между соединениями с TCP-настройками по
+// умолчанию.
 func Relay(ctx context.Context, log Logger, telegramConn, clientConn essentials.Conn) {
+	RelayWithOptions(ctx, log, telegramConn, clientConn, Options{})
+}
+
+// RelayWithOptions перекачивает данные между соединениями, применяя
+// TCP-настройки из opts.
+func RelayWithOptions(ctx context.Context, log Logger, telegramConn, clientConn essentials.Conn, opts Options) {
 	defer telegramConn.Close()
 	defer clientConn.Close()
 
@@ -47,59 +151,79 @@ func Relay(ctx context.Context, log Logger, telegramConn, clientConn essentials.
 	setTCPNoDelay(clientConn)
 
 	// TCP_WINDOW_CLAMP: ограничение receive window для соединения к Telegram.
-	// 128KB (131072) — значение из оригинального MTProxy (DEFAULT_WINDOW_CLAMP).
+	// По умолчанию 128KB — значение из оригинального MTProxy (DEFAULT_WINDOW_CLAMP).
 	// Предотвращает buffer bloat: без clamp TCP window растёт неограниченно,
 	// и Telegram может отправить больше данных, чем proxy успевает relay.
 	// Применяется ТОЛЬКО к telegramConn — клиентское соединение не ограничиваем.
-	setTCPWindowClamp(telegramConn, 131072) //nolint: gomnd
+	setTCPWindowClamp(telegramConn, opts.getWindowClamp())
 
-	// TCP_USER_TIMEOUT: закрыть соединение если нет ACK 30 секунд.
-	// Без этого мёртвые соединения висят до TCP retransmit timeout (~15 мин),
-	// расходуя file descriptors и goroutine worker slots.
-	setTCPUserTimeout(telegramConn, 30000)
-	setTCPUserTimeout(clientConn, 30000)
+	// TCP_USER_TIMEOUT: закрыть соединение если нет ACK за заданное время
+	// (по умолчанию 30 секунд). Без этого мёртвые соединения висят до TCP
+	// retransmit timeout (~15 мин), расходуя file descriptors и goroutine
+	// worker slots. Таймауты раздельные: у мобильного клиента бывают
+	// длинные легитимные паузы, а мёртвый DC лучше обнаружить быстрее.
+	setTCPUserTimeout(telegramConn, int(opts.getTelegramTCPUserTimeout().Milliseconds()))
+	setTCPUserTimeout(clientConn, int(opts.getClientTCPUserTimeout().Milliseconds()))
+
+	activity := newRelayActivity()
 
 	// Upload: client -> telegram (обычный приоритет)
 	go func() {
 		defer close(closeChan)
-		pump(log, telegramConn, clientConn, "client -> telegram", dirUpload)
+		pump(log, telegramConn, clientConn, "client -> telegram", dirUpload, opts, activity)
 	}()
 
 	// Download: telegram -> client (высокий приоритет)
 	// Для download настраиваем TCP для минимальной latency
-	setTCPQuickACK(clientConn) // Немедленные ACK
+	if !opts.DisableQuickACK {
+		setQuickACK(clientConn) // Немедленные ACK
+	}
 
-	pump(log, clientConn, telegramConn, "telegram -> client", dirDownload)
+	pump(log, clientConn, telegramConn, "telegram -> client", dirDownload, opts, activity)
 
 	<-closeChan
 }
 
-func pump(log Logger, src, dst essentials.Conn, directionStr string, dir direction) {
+func pump(log Logger, src, dst essentials.Conn, directionStr string, dir direction, opts Options,
+	activity *relayActivity,
+) {
 	defer src.CloseRead()  //nolint: errcheck
 	defer dst.CloseWrite() //nolint: errcheck
 
-	copyBuffer := acquireCopyBuffer()
+	copyBuffer := acquireCopyBuffer(opts.getCopyBufferSize())
 	defer releaseCopyBuffer(copyBuffer)
 
 	// TCP оптимизации для обоих направлений (много мелких пакетов)
 	// TCP_NODELAY уже установлен в Relay(), здесь дополнительные настройки
 
+	// TCP_QUICKACK - немедленные ACK для снижения latency в обоих
+	// направлениях. На слабых узлах с тысячами соединений эти setsockopt
+	// заметны, а выигрыш на коротких RTT мал — их можно отключить.
+	if !opts.DisableQuickACK {
+		setQuickACK(src)
+		setQuickACK(dst)
+	}
+
 	if dir == dirDownload {
 		// Download: telegram -> client (приоритетное направление)
 
-		// TCP_QUICKACK - немедленные ACK для снижения latency
-		setTCPQuickACK(src)
-		setTCPQuickACK(dst)
-
 		// TCP_NOTSENT_LOWAT — порог неотправленных данных для wake-up epoll.
 		// 128KB = значение Cloudflare в production (blog 2022).
 		// Меньшие значения дают больше write events и overhead.
 		setTCPNotSentLowat(dst, 131072)
-	} else {
-		// Upload: client -> telegram
-		// Также применяем QUICKACK для снижения latency в обоих направлениях
-		setTCPQuickACK(src)
-		setTCPQuickACK(dst)
+	}
+
+	// TCP-настройки выше применяются к исходным соединениям, deadline и
+	// повторный QUICKACK — только к копированию.
+	socket := src
+
+	if opts.IOTimeout > 0 {
+		src = deadlineConn{Conn: src, timeout: opts.IOTimeout, activity: activity}
+		dst = deadlineConn{Conn: dst, timeout: opts.IOTimeout, activity: activity}
+	}
+
+	if !opts.DisableQuickACK && opts.QuickACKInterval > 0 {
+		src = &quickACKConn{Conn: src, socket: socket, interval: opts.QuickACKInterval}
 	}
 
 	n, err := copyRelay(dst, src, *copyBuffer)
@@ -107,6 +231,9 @@ func pump(log Logger, src, dst essentials.Conn, directionStr string, dir directi
 	switch {
 	case err == nil:
 		log.Printf("%s has been finished", directionStr)
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		log.Printf("%s has been aborted: no data in both directions or a blocked write for more than %v (written %d bytes)",
+			directionStr, opts.IOTimeout, n)
 	case errors.Is(err, io.EOF):
 		log.Printf("%s has been finished because of EOF. Written %d bytes", directionStr, n)
 	default:
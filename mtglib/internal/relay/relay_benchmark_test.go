@@ -8,6 +8,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
 )
 
 // mockConn имитирует essentials.Conn для бенчмарков
@@ -153,3 +155,51 @@ func BenchmarkConcurrentRelay(b *testing.B) {
 		})
 	}
 }
+
+type benchLogger struct{}
+
+func (benchLogger) Printf(string, ...interface{}) {}
+
+// BenchmarkPumpQuickACK считает вызовы setsockopt(TCP_QUICKACK) на одно
+// направление relay: 1MB читается буфером 16KB, то есть за 64 Read.
+func BenchmarkPumpQuickACK(b *testing.B) {
+	dataSize := 1024 * 1024
+	data := make([]byte, dataSize)
+
+	testData := []struct {
+		name     string
+		interval int
+		disabled bool
+	}{
+		{"once", 0, false},
+		{"every_8_reads", 8, false},
+		{"every_read", 1, false},
+		{"disabled", 0, true},
+	}
+
+	for _, tc := range testData {
+		b.Run(tc.name, func(b *testing.B) {
+			var calls int
+
+			original := setQuickACK
+			setQuickACK = func(essentials.Conn) { calls++ }
+
+			defer func() { setQuickACK = original }()
+
+			opts := Options{
+				CopyBufferSize:   16 * 1024,
+				QuickACKInterval: tc.interval,
+				DisableQuickACK:  tc.disabled,
+			}
+
+			b.SetBytes(int64(dataSize))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				pump(benchLogger{}, newMockConn(data), newMockConn(nil), "bench", dirUpload, opts, newRelayActivity())
+			}
+
+			b.ReportMetric(float64(calls)/float64(b.N), "quickacks/op")
+		})
+	}
+}
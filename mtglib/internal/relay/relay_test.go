@@ -3,11 +3,17 @@ package relay_test
 import (
 	"context"
 	"io"
+	"net"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/9seconds/mtg/v2/essentials"
 	"github.com/9seconds/mtg/v2/internal/testlib"
 	"github.com/9seconds/mtg/v2/mtglib/internal/relay"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -56,3 +62,107 @@ func TestRelay(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &RelayTestSuite{})
 }
+
+// tcpPair возвращает два конца TCP соединения через loopback.
+func tcpPair(t *testing.T) (essentials.Conn, net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	peer, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { peer.Close() })
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	return conn.(*net.TCPConn), peer
+}
+
+func TestRelayIOTimeout(t *testing.T) {
+	t.Parallel()
+
+	clientConn, clientPeer := tcpPair(t)
+	telegramConn, telegramPeer := tcpPair(t)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		relay.RelayWithOptions(context.Background(), &loggerMock{}, telegramConn, clientConn,
+			relay.Options{IOTimeout: 200 * time.Millisecond})
+	}()
+
+	// Данные идут чаще, чем IOTimeout, поэтому upload живёт дольше него.
+	for range 5 {
+		_, err := clientPeer.Write([]byte{1, 2, 3})
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	received := make([]byte, 15)
+	_, err := io.ReadFull(telegramPeer, received)
+	require.NoError(t, err)
+
+	// Клиент замолчал: relay должен закрыть соединения сам.
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay has not been aborted by io timeout")
+	}
+
+	_, err = io.ReadAll(telegramPeer)
+	assert.NoError(t, err)
+}
+
+func TestRelayIOTimeoutQuietDirection(t *testing.T) {
+	t.Parallel()
+
+	clientConn, clientPeer := tcpPair(t)
+	telegramConn, telegramPeer := tcpPair(t)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		relay.RelayWithOptions(context.Background(), &loggerMock{}, telegramConn, clientConn,
+			relay.Options{IOTimeout: 200 * time.Millisecond})
+	}()
+
+	// Долгий download: клиент ничего не шлёт дольше IOTimeout, но
+	// relay жив, пока данные идут от Telegram.
+	for range 8 {
+		_, err := telegramPeer.Write([]byte{1, 2, 3})
+		require.NoError(t, err)
+
+		received := make([]byte, 3)
+		_, err = io.ReadFull(clientPeer, received)
+		require.NoError(t, err)
+
+		select {
+		case <-done:
+			t.Fatal("relay has been aborted while download is active")
+		default:
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Upload тоже не закрыт: Telegram не получил FIN.
+	require.NoError(t, telegramPeer.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+
+	_, err := telegramPeer.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// Замолчали обе стороны: relay закрывается.
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay has not been aborted by io timeout")
+	}
+}
@@ -1,6 +1,9 @@
 package telegram
 
-import "math/rand"
+import (
+	"math/rand"
+	"slices"
+)
 
 type addressPool struct {
 	v4 [][]tgAddr
@@ -17,22 +20,68 @@ func (a addressPool) getRandomDC() int {
 	return 1 + rand.Intn(len(a.v4))
 }
 
-// getRandomDCExcluding returns a random DC excluding the specified one.
+// getRandomDCExcluding returns a random DC excluding the specified ones.
 // Used for fallback when primary DC is unavailable.
-func (a addressPool) getRandomDCExcluding(exclude int) int {
-	n := len(a.v4)
-	if n <= 1 {
-		// Only one DC available, return it even if it's the excluded one
+func (a addressPool) getRandomDCExcluding(exclude ...int) int {
+	candidates := make([]int, 0, len(a.v4))
+
+	for dc := 1; dc <= len(a.v4); dc++ {
+		if !slices.Contains(exclude, dc) {
+			candidates = append(candidates, dc)
+		}
+	}
+
+	if len(candidates) == 0 {
+		// All DCs are excluded, return any of them
 		return a.getRandomDC()
 	}
 
-	// Pick random from n-1 options, then adjust if we hit the excluded one
-	dc := 1 + rand.Intn(n-1)
-	if dc >= exclude {
-		dc++
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// changedDCs возвращает DC (1-5), у которых множество адресов v4 или v6
+// отличается от other. Порядок адресов внутри DC не важен.
+func (a addressPool) changedDCs(other addressPool) []int {
+	changed := []int{}
+
+	for dc := 1; dc <= 5; dc++ {
+		if !sameAddresses(a.rawGet(a.v4, dc-1), other.rawGet(other.v4, dc-1)) ||
+			!sameAddresses(a.rawGet(a.v6, dc-1), other.rawGet(other.v6, dc-1)) {
+			changed = append(changed, dc)
+		}
+	}
+
+	return changed
+}
+
+func (a addressPool) rawGet(addresses [][]tgAddr, dc int) []tgAddr {
+	if dc < 0 || dc >= len(addresses) {
+		return nil
+	}
+
+	return addresses[dc]
+}
+
+func sameAddresses(left, right []tgAddr) bool {
+	if len(left) != len(right) {
+		return false
+	}
+
+	seen := make(map[tgAddr]int, len(left))
+
+	for _, addr := range left {
+		seen[addr]++
+	}
+
+	for _, addr := range right {
+		if seen[addr] == 0 {
+			return false
+		}
+
+		seen[addr]--
 	}
 
-	return dc
+	return true
 }
 
 func (a addressPool) getV4(dc int) []tgAddr {
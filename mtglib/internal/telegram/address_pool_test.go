@@ -31,6 +31,16 @@ func TestAddressPool_GetRandomDCExcluding(t *testing.T) {
 		}
 	})
 
+	t.Run("excludes several DCs", func(t *testing.T) {
+		seen := make(map[int]bool)
+		for i := 0; i < 100; i++ {
+			seen[pool.getRandomDCExcluding(2, 4)] = true
+		}
+		if len(seen) != 3 || seen[2] || seen[4] {
+			t.Errorf("getRandomDCExcluding(2, 4) should produce DCs 1, 3 and 5, got %v", seen)
+		}
+	})
+
 	t.Run("single DC pool returns that DC", func(t *testing.T) {
 		singlePool := addressPool{
 			v4: [][]tgAddr{{{network: "tcp4", address: "1.2.3.4:443"}}},
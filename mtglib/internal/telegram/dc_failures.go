@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+)
+
+// dcFailures помнит DC, к которым недавно не удалось подключиться.
+type dcFailures struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	until map[int]time.Time
+}
+
+func (d *dcFailures) mark(dc int) {
+	d.mutex.Lock()
+	d.until[dc] = time.Now().Add(d.ttl)
+	d.mutex.Unlock()
+}
+
+func (d *dcFailures) clear(dc int) {
+	d.mutex.Lock()
+	delete(d.until, dc)
+	d.mutex.Unlock()
+}
+
+// failed сообщает, что DC недавно не отвечал. Протухшая запись
+// удаляется: следующий dial снова проверит DC.
+func (d *dcFailures) failed(dc int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	until, ok := d.until[dc]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(until) {
+		delete(d.until, dc)
+
+		return false
+	}
+
+	return true
+}
+
+func newDCFailures(ttl time.Duration) *dcFailures {
+	return &dcFailures{
+		ttl:   ttl,
+		until: map[int]time.Time{},
+	}
+}
@@ -1,9 +1,15 @@
 package telegram
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,6 +18,25 @@ import (
 // Telegram меняет адреса крайне редко, 24ч достаточно.
 const DefaultDCRefreshInterval = 24 * time.Hour
 
+// DCRefreshFailureThreshold — после стольких неудачных загрузок
+// DC-конфига подряд вызывается DCRefreshFailureCallback.
+const DCRefreshFailureThreshold = 3
+
+// DCRefreshFailureCallback вызывается, когда DC-конфиг не загрузился
+// DCRefreshFailureThreshold раз подряд, и затем на каждой следующей
+// неудаче: всё это время используются hardcoded адреса. Когда загрузка
+// снова удалась, callback вызывается с failures == 0 и err == nil.
+type DCRefreshFailureCallback func(failures int, err error)
+
+const (
+	// DefaultDCConfigFetchTimeout — таймаут одной загрузки DC-конфига по HTTP.
+	DefaultDCConfigFetchTimeout = 30 * time.Second
+
+	// maxDCConfigSize — предел размера DC-конфига, скачанного по HTTP.
+	// Настоящий файл весит около килобайта, всё сверх лимита — ошибка.
+	maxDCConfigSize = 64 * 1024
+)
+
 // DCConfigFile — формат JSON файла с DC-адресами.
 // Позволяет обновлять адреса без пересборки образа.
 //
@@ -38,11 +63,15 @@ type DCConfigFile struct {
 	V6 map[string][]string `json:"v6"`
 }
 
-// dcRefresher управляет периодическим обновлением DC-адресов из файла.
+// dcRefresher управляет периодическим обновлением DC-адресов из файла
+// или по HTTP. Если задан url, filePath не используется.
 type dcRefresher struct {
 	filePath     string
+	url          string
+	httpClient   *http.Client
 	interval     time.Duration
 	fallbackPool addressPool // hardcoded адреса — всегда доступны
+	failures     int         // неудачные загрузки подряд
 	stopCh       chan struct{}
 	once         sync.Once
 }
@@ -61,6 +90,46 @@ func newDCRefresher(filePath string, interval time.Duration, fallback addressPoo
 	}
 }
 
+// newDCURLRefresher создаёт refresher, скачивающий DC-адреса по url.
+func newDCURLRefresher(url string, httpClient *http.Client, interval time.Duration, fallback addressPool) *dcRefresher {
+	refresher := newDCRefresher("", interval, fallback)
+	refresher.url = url
+	refresher.httpClient = httpClient
+
+	return refresher
+}
+
+// load загружает DC-адреса из источника refresher.
+func (r *dcRefresher) load() (*addressPool, error) {
+	if r.url != "" {
+		return fetchDCConfig(r.httpClient, r.url)
+	}
+
+	return loadDCConfig(r.filePath)
+}
+
+// countLoad обновляет счётчик неудачных загрузок подряд и вызывает
+// onFailure по правилам DCRefreshFailureCallback. Загрузки идут
+// последовательно, поэтому синхронизация не нужна.
+func (r *dcRefresher) countLoad(err error, onFailure DCRefreshFailureCallback) {
+	if err == nil {
+		alerted := r.failures >= DCRefreshFailureThreshold
+		r.failures = 0
+
+		if alerted && onFailure != nil {
+			onFailure(0, nil)
+		}
+
+		return
+	}
+
+	r.failures++
+
+	if r.failures >= DCRefreshFailureThreshold && onFailure != nil {
+		onFailure(r.failures, err)
+	}
+}
+
 // loadDCConfig загружает DC-адреса из JSON файла.
 // При ошибке возвращает nil — вызывающий код должен использовать fallback.
 func loadDCConfig(filePath string) (*addressPool, error) {
@@ -77,6 +146,46 @@ func loadDCConfig(filePath string) (*addressPool, error) {
 	return parseDCConfig(&config)
 }
 
+// fetchDCConfig скачивает DC-адреса в формате DCConfigFile по HTTP.
+// Ответ больше maxDCConfigSize и любой статус кроме 200 — ошибка,
+// вызывающий код должен использовать fallback.
+func fetchDCConfig(client *http.Client, url string) (*addressPool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDCConfigFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build DC config request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch DC config: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch DC config: unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDCConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read DC config: %w", err)
+	}
+
+	if len(data) > maxDCConfigSize {
+		return nil, fmt.Errorf("DC config is larger than %d bytes", maxDCConfigSize)
+	}
+
+	var config DCConfigFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("cannot parse DC config: %w", err)
+	}
+
+	return parseDCConfig(&config)
+}
+
 // parseDCConfig конвертирует JSON-конфиг в addressPool.
 // Валидирует наличие DC 1-5 для IPv4 (минимальное требование).
 func parseDCConfig(config *DCConfigFile) (*addressPool, error) {
@@ -96,7 +205,12 @@ func parseDCConfig(config *DCConfigFile) (*addressPool, error) {
 
 		tgAddrs := make([]tgAddr, 0, len(addrs))
 		for _, addr := range addrs {
-			tgAddrs = append(tgAddrs, tgAddr{network: "tcp4", address: addr})
+			parsed, err := parseDCAddress(dc, "tcp4", addr)
+			if err != nil {
+				return nil, err
+			}
+
+			tgAddrs = append(tgAddrs, parsed)
 		}
 
 		v4[dc-1] = tgAddrs
@@ -111,7 +225,12 @@ func parseDCConfig(config *DCConfigFile) (*addressPool, error) {
 
 		tgAddrs := make([]tgAddr, 0, len(addrs))
 		for _, addr := range addrs {
-			tgAddrs = append(tgAddrs, tgAddr{network: "tcp6", address: addr})
+			parsed, err := parseDCAddress(dc, "tcp6", addr)
+			if err != nil {
+				return nil, err
+			}
+
+			tgAddrs = append(tgAddrs, parsed)
 		}
 
 		v6[dc-1] = tgAddrs
@@ -134,6 +253,41 @@ func parseDCConfig(config *DCConfigFile) (*addressPool, error) {
 	return &addressPool{v4: v4, v6: v6}, nil
 }
 
+// parseDCAddress проверяет адрес DC из конфига: host должен быть IP-литералом
+// нужного семейства, IPv6 — в квадратных скобках, опционально с зоной
+// (например, "[fe80::1%eth0]:443"). Ошибка называет DC и адрес, чтобы
+// битая запись не превращалась молча в неудачный dial.
+func parseDCAddress(dc int, network, address string) (tgAddr, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return tgAddr{}, fmt.Errorf("dc %d: invalid address %q: %w", dc, address, err)
+	}
+
+	if portNum, err := strconv.ParseUint(port, 10, 16); err != nil || portNum == 0 {
+		return tgAddr{}, fmt.Errorf("dc %d: invalid port in address %q", dc, address)
+	}
+
+	ipStr, zone, hasZone := strings.Cut(host, "%")
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return tgAddr{}, fmt.Errorf("dc %d: host of address %q is not an IP", dc, address)
+	}
+
+	isV4 := ip.To4() != nil
+
+	switch {
+	case network == "tcp4" && !isV4:
+		return tgAddr{}, fmt.Errorf("dc %d: address %q is not IPv4", dc, address)
+	case network == "tcp6" && isV4:
+		return tgAddr{}, fmt.Errorf("dc %d: address %q is not IPv6", dc, address)
+	case hasZone && (isV4 || zone == ""):
+		return tgAddr{}, fmt.Errorf("dc %d: invalid zone in address %q", dc, address)
+	}
+
+	return tgAddr{network: network, address: net.JoinHostPort(host, port)}, nil
+}
+
 // parseDCNumber парсит строковый номер DC.
 func parseDCNumber(s string) int {
 	if len(s) != 1 || s[0] < '1' || s[0] > '9' {
@@ -1,11 +1,18 @@
 package telegram
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/9seconds/mtg/v2/essentials"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,6 +90,68 @@ func TestParseDCConfig_PartialDCs(t *testing.T) {
 	assert.Len(t, pool.v4[2], 1, "DC3 должен быть заполнен")
 }
 
+func TestParseDCConfig_BracketedIPv6(t *testing.T) {
+	config := &DCConfigFile{
+		V4: map[string][]string{
+			"1": {"149.154.175.50:443"},
+		},
+		V6: map[string][]string{
+			"1": {"[2001:b28:f23d:f001::a]:443"},
+			"2": {"[fe80::1%eth0]:443"},
+		},
+	}
+
+	pool, err := parseDCConfig(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, "[2001:b28:f23d:f001::a]:443", pool.v6[0][0].address)
+	assert.Equal(t, "[fe80::1%eth0]:443", pool.v6[1][0].address)
+	assert.Equal(t, "tcp6", pool.v6[1][0].network)
+}
+
+func TestParseDCConfig_InvalidAddress(t *testing.T) {
+	tests := map[string]DCConfigFile{
+		"no-port": {
+			V4: map[string][]string{"1": {"149.154.175.50"}},
+		},
+		"hostname": {
+			V4: map[string][]string{"1": {"example.com:443"}},
+		},
+		"bad-port": {
+			V4: map[string][]string{"1": {"149.154.175.50:http"}},
+		},
+		"zero-port": {
+			V4: map[string][]string{"1": {"149.154.175.50:0"}},
+		},
+		"v6-in-v4": {
+			V4: map[string][]string{"1": {"[2001:b28:f23d:f001::a]:443"}},
+		},
+		"v4-in-v6": {
+			V4: map[string][]string{"1": {"149.154.175.50:443"}},
+			V6: map[string][]string{"1": {"149.154.175.50:443"}},
+		},
+		"unbracketed-v6": {
+			V4: map[string][]string{"1": {"149.154.175.50:443"}},
+			V6: map[string][]string{"2": {"2001:b28:f23d:f001::a:443"}},
+		},
+		"v4-zone": {
+			V4: map[string][]string{"1": {"[149.154.175.50%eth0]:443"}},
+		},
+		"empty-zone": {
+			V4: map[string][]string{"1": {"149.154.175.50:443"}},
+			V6: map[string][]string{"1": {"[fe80::1%]:443"}},
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseDCConfig(&config)
+			require.Error(t, err)
+			assert.Regexp(t, `^dc [12]: `, err.Error())
+		})
+	}
+}
+
 func TestParseDCNumber(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -143,6 +212,119 @@ func TestLoadDCConfig_InvalidJSON(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// tcpDialer соединяется с реальным loopback listener.
+type tcpDialer struct{}
+
+func (tcpDialer) DialContext(ctx context.Context, network, address string) (essentials.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(essentials.Conn), nil //nolint: forcetypeassert
+}
+
+func TestWithDCConfigURL_Loads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(DCConfigFile{ //nolint: errcheck
+			V4: map[string][]string{"2": {"10.0.0.2:443"}},
+		})
+	}))
+	defer server.Close()
+
+	tg, err := New(tcpDialer{}, "only-ipv4", false, WithDCConfigURL(server.URL, 0))
+	require.NoError(t, err)
+
+	defer tg.Close()
+
+	assert.Equal(t, []tgAddr{{network: "tcp4", address: "10.0.0.2:443"}}, tg.getAddresses(2))
+	assert.Empty(t, tg.getAddresses(1))
+}
+
+func TestWithDCConfigURL_FallbackOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tg, err := New(tcpDialer{}, "only-ipv4", false, WithDCConfigURL(server.URL, 0))
+	require.NoError(t, err)
+
+	defer tg.Close()
+
+	assert.ElementsMatch(t, productionV4Addresses[1], tg.getAddresses(2))
+}
+
+func TestFetchDCConfig_Errors(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"too large": func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"v4": {"1": ["` + strings.Repeat("1", maxDCConfigSize) + `"]}}`)) //nolint: errcheck
+		},
+		"redirect": func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		},
+		"invalid json": func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("not json")) //nolint: errcheck
+		},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			tg := &Telegram{dialer: tcpDialer{}}
+			WithDCConfigURL(server.URL, 0)(tg)
+
+			_, err := tg.refresher.load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDCRefresher_CountLoad(t *testing.T) {
+	var calls []int
+
+	callback := func(failures int, err error) {
+		assert.Equal(t, failures == 0, err == nil)
+
+		calls = append(calls, failures)
+	}
+
+	refresher := newDCRefresher("", 0, addressPool{})
+	loadErr := errors.New("cannot load")
+
+	// Успех без предшествующей тревоги ничего не сообщает.
+	refresher.countLoad(nil, callback)
+
+	for range DCRefreshFailureThreshold + 1 {
+		refresher.countLoad(loadErr, callback)
+	}
+
+	refresher.countLoad(nil, callback)
+	refresher.countLoad(nil, callback)
+
+	// Счётчик сброшен: одна неудача снова не повод для тревоги.
+	refresher.countLoad(loadErr, callback)
+
+	assert.Equal(t, []int{DCRefreshFailureThreshold, DCRefreshFailureThreshold + 1, 0}, calls)
+}
+
+func TestDCRefreshFailureCallback_InitialLoad(t *testing.T) {
+	calls := 0
+
+	tg, err := New(&addrDialer{dials: map[string]int{}}, "only-ipv4", false,
+		WithDCConfigFile("/nonexistent/dc_addresses.json", 0),
+		WithDCRefreshFailureCallback(func(int, error) { calls++ }))
+	require.NoError(t, err)
+
+	defer tg.Close()
+
+	// Начальная загрузка считается, но одной неудачи мало для тревоги.
+	assert.Equal(t, 1, tg.refresher.failures)
+	assert.Zero(t, calls)
+}
+
 func TestAddressPoolIsValidDC_WithRefreshedPool(t *testing.T) {
 	// Проверяем что pool.isValidDC работает с refreshed pool
 	pool := addressPool{
@@ -158,3 +340,65 @@ func TestAddressPoolIsValidDC_WithRefreshedPool(t *testing.T) {
 	assert.False(t, pool.isValidDC(6))
 	assert.False(t, pool.isValidDC(203))
 }
+
+func TestUpdatePool_FlushesOnlyChangedDCs(t *testing.T) {
+	dialer := &addrDialer{dials: map[string]int{}}
+
+	tg, err := New(dialer, "only-ipv4", false, WithConnectionPool(DefaultPoolConfig()))
+	require.NoError(t, err)
+
+	defer tg.Close()
+
+	// Прогреваем пулы DC1 и DC2: чистое соединение возвращается в пул.
+	for _, dc := range []int{1, 2} {
+		conn, err := tg.Dial(context.Background(), dc)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	}
+
+	idle := func() map[int]int {
+		rv := map[int]int{}
+		for _, stat := range tg.PoolStats() {
+			rv[stat.DC] = stat.Idle
+		}
+
+		return rv
+	}
+
+	require.Equal(t, map[int]int{1: 1, 2: 1}, idle())
+
+	newPool := addressPool{
+		v4: make([][]tgAddr, len(productionV4Addresses)),
+		v6: productionV6Addresses,
+	}
+	copy(newPool.v4, productionV4Addresses)
+	newPool.v4[0] = []tgAddr{{network: "tcp4", address: "149.154.175.53:443"}}
+
+	tg.updatePool(newPool)
+
+	assert.Equal(t, map[int]int{1: 0, 2: 1}, idle())
+
+	// Новые соединения DC1 идут на новый адрес.
+	conn, err := tg.Dial(context.Background(), 1)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	assert.Equal(t, 1, dialer.Dials("149.154.175.53:443"))
+}
+
+func TestUpdatePool_ReorderIsNotAChange(t *testing.T) {
+	left := addressPool{
+		v4: [][]tgAddr{{
+			{network: "tcp4", address: "149.154.167.51:443"},
+			{network: "tcp4", address: "95.161.76.100:443"},
+		}},
+	}
+	right := addressPool{
+		v4: [][]tgAddr{{
+			{network: "tcp4", address: "95.161.76.100:443"},
+			{network: "tcp4", address: "149.154.167.51:443"},
+		}},
+	}
+
+	assert.Empty(t, left.changedDCs(right))
+}
@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// DialStats — счётчики подключений к DC с момента старта. Считается один
+// вызов dial, а не каждая попытка по отдельным адресам DC.
+type DialStats struct {
+	DC        int
+	Successes uint64
+	Failures  uint64
+}
+
+// dialStats считает успешные и неудачные dial по DC.
+type dialStats struct {
+	mutex sync.Mutex
+	dcs   map[int]*DialStats
+}
+
+// report учитывает результат dial. Отмена контекста — не вина DC, а DC
+// без адресов (клиент попросил несуществующий) не учитываются, чтобы
+// клиенты не раздували число DC.
+func (d *dialStats) report(ctx context.Context, dc int, err error) {
+	if d == nil || errors.Is(err, errNoAddresses) || (err != nil && ctx.Err() != nil) {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	stats, ok := d.dcs[dc]
+	if !ok {
+		stats = &DialStats{DC: dc}
+		d.dcs[dc] = stats
+	}
+
+	if err == nil {
+		stats.Successes++
+	} else {
+		stats.Failures++
+	}
+}
+
+func (d *dialStats) all() []DialStats {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	rv := make([]DialStats, 0, len(d.dcs))
+
+	for _, v := range d.dcs {
+		rv = append(rv, *v)
+	}
+
+	slices.SortFunc(rv, func(a, b DialStats) int {
+		return a.DC - b.DC
+	})
+
+	return rv
+}
+
+func newDialStats() *dialStats {
+	return &dialStats{
+		dcs: map[int]*DialStats{},
+	}
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialStats(t *testing.T) {
+	t.Parallel()
+
+	stats := newDialStats()
+	ctx := context.Background()
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	stats.report(ctx, 4, nil)
+	stats.report(ctx, 2, nil)
+	stats.report(ctx, 2, nil)
+	stats.report(ctx, 2, errors.New("connection refused"))
+	stats.report(ctx, 100, errNoAddresses)
+	stats.report(canceledCtx, 4, context.Canceled)
+
+	assert.Equal(t, []DialStats{
+		{DC: 2, Successes: 2, Failures: 1},
+		{DC: 4, Successes: 1},
+	}, stats.all())
+
+	var disabled *dialStats
+
+	assert.NotPanics(t, func() {
+		disabled.report(ctx, 2, nil)
+	})
+}
@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDCHealthCheckTimeout — таймаут одной проверки доступности DC.
+	DefaultDCHealthCheckTimeout = 5 * time.Second
+
+	// DCLatencyWindowSize — сколько последних успешных замеров latency
+	// хранится на DC для расчёта перцентилей.
+	DCLatencyWindowSize = 16
+)
+
+// DCHealth — результат проверки доступности одного DC.
+//
+// Latency — замер этой проверки, P50 и P95 — перцентили по последним
+// DCLatencyWindowSize успешным замерам, включая этот. Один случайный
+// всплеск почти не двигает P50, поэтому для сравнения DC лучше
+// использовать его.
+type DCHealth struct {
+	DC      int
+	Latency time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	Err     error
+}
+
+// Reachable сообщает, удалось ли подключиться к DC.
+func (h DCHealth) Reachable() bool {
+	return h.Err == nil
+}
+
+// DCHealthChecker проверяет доступность DC Telegram прямым подключением.
+//
+// Проверка идёт мимо connection pool: нас интересует, можно ли
+// установить новое соединение, а не есть ли живое в пуле.
+type DCHealthChecker struct {
+	telegram *Telegram
+	timeout  time.Duration
+
+	mutex     sync.Mutex
+	latencies map[int]*latencyWindow
+}
+
+// latencyWindow — кольцевой буфер последних замеров latency.
+type latencyWindow struct {
+	samples [DCLatencyWindowSize]time.Duration
+	next    int
+	count   int
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % len(w.samples)
+	w.count = min(w.count+1, len(w.samples))
+}
+
+// percentile считает перцентиль методом nearest-rank. Для пустого
+// окна возвращает 0.
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(w.samples[:w.count])
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+// record добавляет успешный замер (если он есть) и заполняет
+// перцентили результата.
+func (c *DCHealthChecker) record(health DCHealth) DCHealth {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	window, ok := c.latencies[health.DC]
+	if !ok {
+		window = &latencyWindow{}
+		c.latencies[health.DC] = window
+	}
+
+	if health.Reachable() {
+		window.add(health.Latency)
+	}
+
+	health.P50 = window.percentile(0.5)
+	health.P95 = window.percentile(0.95)
+
+	return health
+}
+
+// checkDC подключается к DC и сразу закрывает соединение.
+func (c *DCHealthChecker) checkDC(ctx context.Context, dc int) DCHealth {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+
+	conn, err := c.telegram.DialDirect(ctx, dc)
+	if err != nil {
+		return c.record(DCHealth{DC: dc, Err: err})
+	}
+
+	latency := time.Since(started)
+
+	conn.Close() //nolint: errcheck
+
+	return c.record(DCHealth{DC: dc, Latency: latency})
+}
+
+// CheckAll параллельно проверяет все известные DC. Результаты
+// упорядочены по номеру DC. Checker можно вызывать повторно: окно
+// замеров копится между вызовами.
+func (c *DCHealthChecker) CheckAll(ctx context.Context) []DCHealth {
+	dcs := c.telegram.knownDCs()
+	results := make([]DCHealth, len(dcs))
+	wg := &sync.WaitGroup{}
+
+	for i, dc := range dcs {
+		wg.Add(1)
+
+		go func(i, dc int) {
+			defer wg.Done()
+
+			results[i] = c.checkDC(ctx, dc)
+		}(i, dc)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// NewDCHealthChecker создаёт проверку доступности DC. Если timeout
+// не задан, используется DefaultDCHealthCheckTimeout.
+func NewDCHealthChecker(tg *Telegram, timeout time.Duration) *DCHealthChecker {
+	if timeout <= 0 {
+		timeout = DefaultDCHealthCheckTimeout
+	}
+
+	return &DCHealthChecker{
+		telegram:  tg,
+		timeout:   timeout,
+		latencies: map[int]*latencyWindow{},
+	}
+}
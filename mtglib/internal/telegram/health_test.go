@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDCHealthChecker_CheckAll(t *testing.T) {
+	dialer := &addrDialer{
+		dials:  map[string]int{},
+		broken: map[string]bool{testV4Addresses[1][0].address: true},
+	}
+
+	tg, err := New(dialer, "only-ipv4", true)
+	require.NoError(t, err)
+
+	results := NewDCHealthChecker(tg, 0).CheckAll(context.Background())
+	require.Len(t, results, len(testV4Addresses))
+
+	for i, res := range results {
+		assert.Equal(t, i+1, res.DC)
+	}
+
+	assert.True(t, results[0].Reachable())
+	assert.False(t, results[1].Reachable())
+	assert.Error(t, results[1].Err)
+	assert.True(t, results[2].Reachable())
+}
+
+func TestDCHealthChecker_NoneReachable(t *testing.T) {
+	dialer := &addrDialer{dials: map[string]int{}, broken: map[string]bool{}}
+
+	for _, addrs := range testV4Addresses {
+		dialer.broken[addrs[0].address] = true
+	}
+
+	tg, err := New(dialer, "only-ipv4", true)
+	require.NoError(t, err)
+
+	for _, res := range NewDCHealthChecker(tg, 0).CheckAll(context.Background()) {
+		assert.False(t, res.Reachable())
+	}
+}
+
+func TestLatencyWindowPercentiles(t *testing.T) {
+	window := &latencyWindow{}
+
+	assert.Zero(t, window.percentile(0.5))
+
+	// Один всплеск среди стабильных замеров не двигает медиану.
+	for _, ms := range []int{40, 42, 41, 900, 43, 40, 44, 42, 41, 43} {
+		window.add(time.Duration(ms) * time.Millisecond)
+	}
+
+	assert.Equal(t, 42*time.Millisecond, window.percentile(0.5))
+	assert.Equal(t, 900*time.Millisecond, window.percentile(0.95))
+}
+
+func TestLatencyWindowRolling(t *testing.T) {
+	window := &latencyWindow{}
+
+	for range DCLatencyWindowSize {
+		window.add(time.Second)
+	}
+
+	// Старые замеры вытесняются новыми.
+	for range DCLatencyWindowSize {
+		window.add(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, DCLatencyWindowSize, window.count)
+	assert.Equal(t, 10*time.Millisecond, window.percentile(0.5))
+	assert.Equal(t, 10*time.Millisecond, window.percentile(0.95))
+}
+
+func TestDCHealthChecker_Record(t *testing.T) {
+	checker := NewDCHealthChecker(nil, 0)
+
+	for _, ms := range []int{50, 60, 500} {
+		checker.record(DCHealth{DC: 2, Latency: time.Duration(ms) * time.Millisecond})
+	}
+
+	// Неудачная проверка не портит окно, но получает перцентили.
+	res := checker.record(DCHealth{DC: 2, Err: errNoAddresses})
+	assert.False(t, res.Reachable())
+	assert.Equal(t, 60*time.Millisecond, res.P50)
+	assert.Equal(t, 500*time.Millisecond, res.P95)
+
+	res = checker.record(DCHealth{DC: 4, Err: errNoAddresses})
+	assert.Zero(t, res.P50)
+}
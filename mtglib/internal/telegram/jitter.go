@@ -0,0 +1,18 @@
+package telegram
+
+import (
+	"math/rand"
+	"time"
+)
+
+// intervalJitter — доля случайного разброса периодических интервалов.
+// Инстансы, запущенные одновременно (rolling deploy), иначе чистят пулы
+// и перечитывают DC-файл синхронно, создавая всплески нагрузки.
+const intervalJitter = 0.1
+
+// jitterInterval возвращает interval ± intervalJitter.
+func jitterInterval(interval time.Duration) time.Duration {
+	delta := (rand.Float64()*2 - 1) * intervalJitter * float64(interval) //nolint: gosec
+
+	return interval + time.Duration(delta)
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterInterval(t *testing.T) {
+	t.Parallel()
+
+	interval := 10 * time.Second
+	seen := map[time.Duration]bool{}
+
+	for i := 0; i < 1000; i++ {
+		value := jitterInterval(interval)
+		seen[value] = true
+
+		assert.GreaterOrEqual(t, value, 9*time.Second)
+		assert.LessOrEqual(t, value, 11*time.Second)
+	}
+
+	assert.Greater(t, len(seen), 1)
+}
+
+func TestSafeRefreshInterval(t *testing.T) {
+	t.Parallel()
+
+	for _, interval := range []time.Duration{0, time.Second, time.Minute} {
+		value := safeRefreshInterval(interval)
+
+		assert.GreaterOrEqual(t, value, 54*time.Second)
+		assert.LessOrEqual(t, value, 66*time.Second)
+	}
+
+	value := safeRefreshInterval(time.Hour)
+	assert.GreaterOrEqual(t, value, 54*time.Minute)
+	assert.LessOrEqual(t, value, 66*time.Minute)
+}
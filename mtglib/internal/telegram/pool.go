@@ -13,10 +13,14 @@ import (
 
 // Конфигурация по умолчанию для connection pool.
 const (
-	DefaultPoolSize    = 5  // Размер пула на один DC
+	DefaultPoolSize    = 5 // Размер пула на один DC
 	DefaultDialTimeout = 10 * time.Second
 	DefaultHealthCheck = 30 * time.Second
 
+	// DefaultDialStagger — задержка между параллельными попытками подключения
+	// к адресам одного DC (Happy Eyeballs). 250ms — рекомендация RFC 8305.
+	DefaultDialStagger = 250 * time.Millisecond
+
 	// DefaultIdleTimeout — агрессивный таймаут для избежания проблемы с Telegram.
 	// Telegram закрывает idle-соединения через ~30-60 секунд.
 	// Используем 20 секунд — достаточно консервативно, чтобы гарантировать
@@ -44,6 +48,16 @@ type PoolConfig struct {
 
 	// HealthCheckInterval — интервал проверки соединений.
 	HealthCheckInterval time.Duration
+
+	// MaxDialAddresses — сколько адресов DC пробовать за один dial,
+	// начиная с очередного по кругу. 0 — все.
+	MaxDialAddresses int
+
+	// IdleDrainAfter — через сколько без единого Get закрыть все idle
+	// соединения DC. Пул наполняется заново уже по новым запросам.
+	// Проверка идёт в cleanupLoop, так что пул сдувается с задержкой
+	// до одного его интервала. 0 — не сдувать.
+	IdleDrainAfter time.Duration
 }
 
 // DefaultPoolConfig возвращает конфигурацию по умолчанию.
@@ -59,9 +73,14 @@ func DefaultPoolConfig() PoolConfig {
 type pooledConn struct {
 	essentials.Conn
 	dc         int
+	generation uint64 // поколение адресов DCPool на момент dial
 	createdAt  time.Time
 	lastUsedAt time.Time
 	usageCount uint64
+
+	// tainted — на соединении был obfuscated2 handshake (или любые байты
+	// протокола). Оно несёт per-session состояние, и Put его закрывает.
+	tainted atomic.Bool
 }
 
 // maxConnectionAge — максимальный возраст соединения независимо от активности.
@@ -94,15 +113,21 @@ func (p *pooledConn) markUsed() {
 
 // DCPool — пул соединений для одного DC.
 type DCPool struct {
-	dc      int
-	dialer  Dialer
-	addrs   []tgAddr
-	config  PoolConfig
+	dc     int
+	dialer Dialer
+	addrs  []tgAddr
+	config PoolConfig
 
-	conns  chan *pooledConn
-	mu     sync.Mutex
-	closed atomic.Bool
-	stopCh chan struct{} // сигнал остановки background cleanup
+	conns      chan *pooledConn
+	mu         sync.Mutex
+	next       atomic.Uint32 // индекс стартового адреса для round-robin
+	generation atomic.Uint64 // увеличивается при смене адресов DC
+	closed     atomic.Bool
+	stopCh     chan struct{} // сигнал остановки background cleanup
+
+	// lastGetAt — UnixNano последнего Get, по нему пул понимает, что
+	// трафика нет и idle соединения можно закрыть.
+	lastGetAt atomic.Int64
 
 	// Статистика
 	stats struct {
@@ -111,7 +136,11 @@ type DCPool struct {
 		created   atomic.Uint64
 		closed    atomic.Uint64
 		unhealthy atomic.Uint64
+		tainted   atomic.Uint64
 	}
+
+	// dialStats — общие с Telegram счётчики dial. Может быть nil.
+	dialStats *dialStats
 }
 
 // NewDCPool создаёт пул для конкретного DC.
@@ -131,6 +160,7 @@ func NewDCPool(dc int, dialer Dialer, addrs []tgAddr, config PoolConfig) *DCPool
 		conns:  make(chan *pooledConn, config.MaxIdleConns),
 		stopCh: make(chan struct{}),
 	}
+	pool.lastGetAt.Store(time.Now().UnixNano())
 
 	// Background cleanup — вычищает stale/expired соединения из пула,
 	// чтобы первый клиент после паузы не получил мёртвое соединение.
@@ -145,6 +175,8 @@ func (p *DCPool) Get(ctx context.Context) (essentials.Conn, error) {
 		return nil, ErrPoolClosed
 	}
 
+	p.lastGetAt.Store(time.Now().UnixNano())
+
 	// Пробуем взять из пула
 	for {
 		// Проверяем context cancellation
@@ -158,7 +190,8 @@ func (p *DCPool) Get(ctx context.Context) (essentials.Conn, error) {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case conn := <-p.conns:
-			if conn.isHealthy(p.config.IdleTimeout) {
+			if conn.generation == p.generation.Load() &&
+				conn.isHealthy(p.config.IdleTimeout) && !hasLeftoverData(conn.Conn) {
 				conn.markUsed()
 				p.stats.hits.Add(1)
 				return conn, nil
@@ -190,12 +223,33 @@ func (p *DCPool) Put(conn essentials.Conn) {
 		pc = &pooledConn{
 			Conn:       conn,
 			dc:         p.dc,
+			generation: p.generation.Load(),
 			createdAt:  time.Now(),
 			lastUsedAt: time.Now(),
 		}
 	}
 
-	if !pc.isHealthy(p.config.IdleTimeout) {
+	// Соединение с состоянием протокола в пуле означает, что следующий
+	// клиент получит чужой поток. Так быть не должно: PooledConn не
+	// возвращает такие соединения, так что это защита от регрессий.
+	if pc.tainted.Load() {
+		pc.Close()
+		p.stats.tainted.Add(1)
+		return
+	}
+
+	// Соединение установлено к адресу, которого уже может не быть в
+	// конфиге: адреса DC сменились, пока оно было выдано клиенту.
+	if pc.generation != p.generation.Load() {
+		pc.Close()
+		p.stats.closed.Add(1)
+		return
+	}
+
+	// Непрочитанные байты в сокете означают, что Telegram успел ответить
+	// на прерванную сессию: следующий клиент получил бы рассинхронизированный
+	// поток вместо чистой ошибки.
+	if !pc.isHealthy(p.config.IdleTimeout) || hasLeftoverData(pc.Conn) {
 		pc.Close()
 		p.stats.unhealthy.Add(1)
 		return
@@ -214,15 +268,34 @@ func (p *DCPool) Put(conn essentials.Conn) {
 }
 
 // dial создаёт новое соединение к DC.
+// Адреса перебираются round-robin: каждый dial начинает со следующего
+// адреса, чтобы распределять соединения по всем IP DC. При ошибке
+// переходит к следующему адресу.
 // Устанавливает TCP keepalive для быстрого обнаружения мёртвых соединений.
-func (p *DCPool) dial(ctx context.Context) (essentials.Conn, error) {
+func (p *DCPool) dial(ctx context.Context) (_ essentials.Conn, err error) {
+	defer func() { p.dialStats.report(ctx, p.dc, err) }()
+
 	p.mu.Lock()
 	addrs := make([]tgAddr, len(p.addrs))
 	copy(addrs, p.addrs)
+	generation := p.generation.Load()
 	p.mu.Unlock()
 
+	if len(addrs) == 0 {
+		return nil, errNoAddresses
+	}
+
+	start := int((p.next.Add(1) - 1) % uint32(len(addrs)))
+
+	tries := len(addrs)
+	if p.config.MaxDialAddresses > 0 && tries > p.config.MaxDialAddresses {
+		tries = p.config.MaxDialAddresses
+	}
+
 	var lastErr error
-	for _, addr := range addrs {
+	for i := range tries {
+		addr := addrs[(start+i)%len(addrs)]
+
 		conn, err := p.dialer.DialContext(ctx, addr.network, addr.address)
 		if err != nil {
 			lastErr = err
@@ -240,15 +313,38 @@ func (p *DCPool) dial(ctx context.Context) (essentials.Conn, error) {
 		return &pooledConn{
 			Conn:       conn,
 			dc:         p.dc,
+			generation: generation,
 			createdAt:  time.Now(),
 			lastUsedAt: time.Now(),
 		}, nil
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	return nil, lastErr
+}
+
+// UpdateAddresses заменяет адреса DC и закрывает все idle соединения:
+// они могут вести на адреса, которых больше нет в конфиге. Соединения,
+// выданные клиентам, не вернутся в пул благодаря смене поколения.
+func (p *DCPool) UpdateAddresses(addrs []tgAddr) {
+	p.mu.Lock()
+	p.addrs = addrs
+	p.generation.Add(1)
+	p.mu.Unlock()
+
+	p.drain()
+}
+
+// drain закрывает все idle соединения пула.
+func (p *DCPool) drain() {
+	for {
+		select {
+		case conn := <-p.conns:
+			conn.Close()
+			p.stats.closed.Add(1)
+		default:
+			return
+		}
 	}
-	return nil, errNoAddresses
 }
 
 // Close закрывает пул и все соединения.
@@ -262,27 +358,22 @@ func (p *DCPool) Close() error {
 
 	// Дренируем и закрываем оставшиеся соединения.
 	// Не закрываем channel — cleanupLoop может ещё писать в него.
-	for {
-		select {
-		case conn := <-p.conns:
-			conn.Close()
-			p.stats.closed.Add(1)
-		default:
-			return nil
-		}
-	}
+	p.drain()
+
+	return nil
 }
 
 // cleanupLoop периодически удаляет stale соединения из пула.
 // Интервал = IdleTimeout/2 — достаточно часто чтобы stale соединения
-// не накапливались, но не слишком агрессивно.
+// не накапливались, но не слишком агрессивно. Каждый интервал берётся
+// с jitter.
 func (p *DCPool) cleanupLoop() {
 	interval := p.config.IdleTimeout / 2
 	if interval < 5*time.Second {
 		interval = 5 * time.Second
 	}
 
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(jitterInterval(interval))
 	defer ticker.Stop()
 
 	for {
@@ -290,6 +381,7 @@ func (p *DCPool) cleanupLoop() {
 		case <-p.stopCh:
 			return
 		case <-ticker.C:
+			ticker.Reset(jitterInterval(interval))
 			p.evictStale()
 		}
 	}
@@ -304,6 +396,14 @@ func (p *DCPool) evictStale() {
 		return
 	}
 
+	// Трафика давно нет: держать соединения к Telegram незачем, следующий
+	// Get просто установит новое.
+	if p.isQuiet() {
+		p.drain()
+
+		return
+	}
+
 	// Считаем сколько сейчас в пуле — дренируем ровно столько
 	n := len(p.conns)
 	for i := 0; i < n; i++ {
@@ -316,7 +416,7 @@ func (p *DCPool) evictStale() {
 				continue
 			}
 
-			if conn.isHealthy(p.config.IdleTimeout) {
+			if conn.generation == p.generation.Load() && conn.isHealthy(p.config.IdleTimeout) {
 				// Живое — возвращаем
 				select {
 				case p.conns <- conn:
@@ -335,6 +435,15 @@ func (p *DCPool) evictStale() {
 	}
 }
 
+// isQuiet сообщает, что Get не вызывали дольше IdleDrainAfter.
+func (p *DCPool) isQuiet() bool {
+	if p.config.IdleDrainAfter <= 0 {
+		return false
+	}
+
+	return time.Since(time.Unix(0, p.lastGetAt.Load())) > p.config.IdleDrainAfter
+}
+
 // Stats возвращает статистику пула.
 func (p *DCPool) Stats() PoolStats {
 	return PoolStats{
@@ -344,6 +453,7 @@ func (p *DCPool) Stats() PoolStats {
 		Created:   p.stats.created.Load(),
 		Closed:    p.stats.closed.Load(),
 		Unhealthy: p.stats.unhealthy.Load(),
+		Tainted:   p.stats.tainted.Load(),
 		Idle:      len(p.conns),
 	}
 }
@@ -356,6 +466,7 @@ type PoolStats struct {
 	Created   uint64 // Всего создано соединений
 	Closed    uint64 // Закрыто соединений
 	Unhealthy uint64 // Отклонено нездоровых
+	Tainted   uint64 // Отклонено с состоянием протокола; > 0 — это баг
 	Idle      int    // Текущее количество idle
 }
 
@@ -366,6 +477,9 @@ type ConnectionPoolManager struct {
 	dialer Dialer
 	config PoolConfig
 	closed atomic.Bool
+
+	// dialStats передаётся всем пулам DC.
+	dialStats *dialStats
 }
 
 // NewConnectionPoolManager создаёт менеджер пулов.
@@ -396,10 +510,24 @@ func (m *ConnectionPoolManager) GetPool(dc int, addrs []tgAddr) *DCPool {
 	}
 
 	pool = NewDCPool(dc, m.dialer, addrs, m.config)
+	pool.dialStats = m.dialStats
 	m.pools[dc] = pool
 	return pool
 }
 
+// UpdateAddresses обновляет адреса пула DC, сбрасывая его idle
+// соединения. Если пул для DC ещё не создан, ничего не делает: он
+// получит актуальные адреса при первом Get.
+func (m *ConnectionPoolManager) UpdateAddresses(dc int, addrs []tgAddr) {
+	m.mu.RLock()
+	pool, exists := m.pools[dc]
+	m.mu.RUnlock()
+
+	if exists {
+		pool.UpdateAddresses(addrs)
+	}
+}
+
 // Get получает соединение для DC.
 func (m *ConnectionPoolManager) Get(ctx context.Context, dc int, addrs []tgAddr) (essentials.Conn, error) {
 	if m.closed.Load() {
@@ -456,14 +584,47 @@ type PooledConn struct {
 	dc      int
 	manager *ConnectionPoolManager
 	closed  atomic.Bool
+
+	// dirty — через соединение уже прошли байты протокола. Такое соединение
+	// несёт per-session состояние и не может быть возвращено в пул.
+	dirty atomic.Bool
+}
+
+// markDirty помечает соединение как использованное протоколом, включая
+// соединение из пула под ним: так его не примет и прямой Put.
+func (c *PooledConn) markDirty() {
+	c.dirty.Store(true)
+
+	if pc, ok := c.Conn.(*pooledConn); ok {
+		pc.tainted.Store(true)
+	}
+}
+
+// Read помечает соединение как использованное протоколом.
+func (c *PooledConn) Read(p []byte) (int, error) {
+	c.markDirty()
+	return c.Conn.Read(p) //nolint: wrapcheck
+}
+
+// Write помечает соединение как использованное протоколом.
+// Даже частично записанный handshake делает соединение непригодным для
+// повторного использования.
+func (c *PooledConn) Write(p []byte) (int, error) {
+	c.markDirty()
+	return c.Conn.Write(p) //nolint: wrapcheck
 }
 
 // Close возвращает соединение в пул вместо закрытия.
+// Если соединение уже использовалось протоколом, оно закрывается.
 func (c *PooledConn) Close() error {
 	if c.closed.Swap(true) {
 		return nil
 	}
 
+	if c.dirty.Load() {
+		return c.Conn.Close()
+	}
+
 	// Возвращаем в пул, а не закрываем
 	c.manager.Put(c.dc, c.Conn)
 	return nil
@@ -480,7 +641,9 @@ func (c *PooledConn) ForceClose() error {
 // Unwrap извлекает внутреннее соединение и помечает PooledConn как закрытый.
 // Используется после установки состояния протокола (obfuscated2 handshake),
 // чтобы Close() на внутреннем соединении реально закрыл TCP, а не вернул в пул.
+// Извлечённое соединение считается использованным протоколом.
 func (c *PooledConn) Unwrap() essentials.Conn {
+	c.markDirty()
 	c.closed.Store(true)
 	return c.Conn
 }
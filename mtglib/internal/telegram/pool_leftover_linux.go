@@ -0,0 +1,38 @@
+//go:build linux
+
+package telegram
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// hasLeftoverData проверяет, есть ли в сокете непрочитанные данные или FIN.
+// Используется MSG_PEEK|MSG_DONTWAIT: данные не извлекаются из буфера,
+// вызов не блокируется. Соединение без данных возвращает EAGAIN.
+func hasLeftoverData(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	leftover := false
+	buf := [1]byte{}
+
+	rawConn.Read(func(fd uintptr) bool { //nolint: errcheck
+		n, _, err := unix.Recvfrom(int(fd), buf[:], unix.MSG_PEEK|unix.MSG_DONTWAIT)
+		// n > 0 — остались байты предыдущей сессии, n == 0 без ошибки — peer закрыл соединение.
+		leftover = n > 0 || (n == 0 && err == nil)
+
+		return true
+	})
+
+	return leftover
+}
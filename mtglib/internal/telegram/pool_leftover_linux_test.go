@@ -0,0 +1,95 @@
+//go:build linux
+
+package telegram
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDCPool_LeftoverDataRejected — регрессия: Telegram прислал байты в
+// соединение прерванной сессии, пока оно лежало в пуле. Такое соединение
+// нельзя отдавать следующему клиенту: decryptor рассинхронизируется.
+func TestDCPool_LeftoverDataRejected(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	serverConns := make(chan net.Conn, 2)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			serverConns <- conn
+		}
+	}()
+
+	addrs := []tgAddr{{network: "tcp4", address: listener.Addr().String()}}
+	pool := NewDCPool(1, tcpDialer{}, addrs, PoolConfig{
+		MaxIdleConns: 3,
+		IdleTimeout:  time.Minute,
+	})
+
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	conn1, err := pool.Get(ctx)
+	require.NoError(t, err)
+
+	pool.Put(conn1)
+	assert.Equal(t, 1, pool.Stats().Idle)
+
+	server1 := <-serverConns
+	defer server1.Close()
+
+	_, err = server1.Write([]byte("late bytes"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return hasLeftoverData(conn1.(*pooledConn).Conn)
+	}, time.Second, 10*time.Millisecond)
+
+	conn2, err := pool.Get(ctx)
+	require.NoError(t, err)
+
+	defer conn2.Close()
+
+	assert.NotSame(t, conn1, conn2)
+
+	stats := pool.Stats()
+	assert.Equal(t, uint64(1), stats.Unhealthy)
+	assert.Equal(t, uint64(2), stats.Misses)
+}
+
+func TestHasLeftoverData_CleanConnection(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	conn, err := net.Dial("tcp4", listener.Addr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	assert.False(t, hasLeftoverData(conn))
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package telegram
+
+import "net"
+
+// hasLeftoverData — no-op для платформ без MSG_PEEK|MSG_DONTWAIT проверки.
+// Защиту обеспечивает пометка dirty в PooledConn.
+func hasLeftoverData(conn net.Conn) bool {
+	return false
+}
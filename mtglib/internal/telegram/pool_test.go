@@ -3,12 +3,14 @@ package telegram
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/mtglib/internal/obfuscated2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -309,3 +311,241 @@ func TestDCPool_UnhealthyConnectionRejected(t *testing.T) {
 	assert.Equal(t, uint64(1), stats.Unhealthy, "expired connection should be rejected as unhealthy")
 	assert.Equal(t, uint64(2), stats.Misses, "should create new connection after unhealthy rejection")
 }
+
+// TestDCPool_IdleDrainAfter проверяет, что после периода без Get пул
+// закрывает все idle соединения, а следующий Get устанавливает новое.
+func TestDCPool_IdleDrainAfter(t *testing.T) {
+	dialer := &mockDialer{}
+	addrs := []tgAddr{{network: "tcp4", address: "127.0.0.1:443"}}
+
+	config := PoolConfig{
+		MaxIdleConns:   3,
+		IdleTimeout:    time.Minute,
+		IdleDrainAfter: 20 * time.Millisecond,
+	}
+
+	pool := NewDCPool(1, dialer, addrs, config)
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	conns := make([]essentials.Conn, 3)
+	for i := range conns {
+		conn, err := pool.Get(ctx)
+		require.NoError(t, err)
+
+		conns[i] = conn
+	}
+
+	for _, conn := range conns {
+		pool.Put(conn)
+	}
+
+	// Период тишины ещё не прошёл — здоровые соединения остаются.
+	pool.evictStale()
+	assert.Equal(t, 3, pool.Stats().Idle)
+
+	time.Sleep(50 * time.Millisecond)
+
+	pool.evictStale()
+	assert.Equal(t, 0, pool.Stats().Idle, "idle connections should be drained after a quiet period")
+	assert.Equal(t, uint64(3), pool.Stats().Closed)
+
+	conn, err := pool.Get(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, uint64(4), pool.Stats().Created)
+}
+
+// TestPooledConn_DirtyNotReturned проверяет, что соединение, через которое
+// уже прошли байты протокола, закрывается вместо возврата в пул.
+func TestPooledConn_DirtyNotReturned(t *testing.T) {
+	dialer := &mockDialer{}
+	config := DefaultPoolConfig()
+
+	manager := NewConnectionPoolManager(dialer, config)
+	defer manager.Close()
+
+	ctx := context.Background()
+	addrs := []tgAddr{{network: "tcp4", address: "127.0.0.1:443"}}
+
+	rawConn, err := manager.Get(ctx, 1, addrs)
+	require.NoError(t, err)
+
+	wrapped := &PooledConn{
+		Conn:    rawConn,
+		dc:      1,
+		manager: manager,
+	}
+
+	// Частично записанный handshake
+	_, err = wrapped.Write([]byte{0x01, 0x02, 0x03})
+	require.NoError(t, err)
+
+	require.NoError(t, wrapped.Close())
+
+	pool := manager.GetPool(1, addrs)
+	assert.Equal(t, 0, pool.Stats().Idle)
+	assert.True(t, rawConn.(*pooledConn).Conn.(*mockConn).IsClosed())
+}
+
+// TestPooledConn_HandshakeCanary — canary против утечки состояния
+// протокола между сессиями: соединение, на котором был obfuscated2
+// handshake, не должно вернуться в пул ни через Close, ни через прямой Put
+// после Unwrap (как в doTelegramCall).
+func TestPooledConn_HandshakeCanary(t *testing.T) {
+	dialer := &mockDialer{}
+	manager := NewConnectionPoolManager(dialer, DefaultPoolConfig())
+	defer manager.Close()
+
+	ctx := context.Background()
+	addrs := []tgAddr{{network: "tcp4", address: "127.0.0.1:443"}}
+	pool := manager.GetPool(1, addrs)
+
+	for _, name := range []string{"close", "unwrap-put"} {
+		t.Run(name, func(t *testing.T) {
+			rawConn, err := manager.Get(ctx, 1, addrs)
+			require.NoError(t, err)
+
+			wrapped := &PooledConn{
+				Conn:    rawConn,
+				dc:      1,
+				manager: manager,
+			}
+
+			_, _, err = obfuscated2.ServerHandshake(wrapped)
+			require.NoError(t, err)
+
+			if name == "close" {
+				require.NoError(t, wrapped.Close())
+			} else {
+				manager.Put(1, wrapped.Unwrap())
+			}
+
+			assert.Equal(t, 0, pool.Stats().Idle)
+			assert.True(t, rawConn.(*pooledConn).Conn.(*mockConn).IsClosed())
+		})
+	}
+
+	assert.Equal(t, uint64(1), pool.Stats().Tainted)
+}
+
+// TestPooledConn_UnwrapTaints проверяет, что Unwrap помечает соединение,
+// даже если через него ещё не прошли байты.
+func TestPooledConn_UnwrapTaints(t *testing.T) {
+	dialer := &mockDialer{}
+	manager := NewConnectionPoolManager(dialer, DefaultPoolConfig())
+	defer manager.Close()
+
+	addrs := []tgAddr{{network: "tcp4", address: "127.0.0.1:443"}}
+
+	rawConn, err := manager.Get(context.Background(), 1, addrs)
+	require.NoError(t, err)
+
+	wrapped := &PooledConn{
+		Conn:    rawConn,
+		dc:      1,
+		manager: manager,
+	}
+
+	manager.Put(1, wrapped.Unwrap())
+
+	stats := manager.GetPool(1, addrs).Stats()
+	assert.Equal(t, 0, stats.Idle)
+	assert.Equal(t, uint64(1), stats.Tainted)
+}
+
+// addrDialer — dialer, считающий попытки по адресам.
+type addrDialer struct {
+	mu     sync.Mutex
+	dials  map[string]int
+	broken map[string]bool
+}
+
+func (a *addrDialer) DialContext(ctx context.Context, network, address string) (essentials.Conn, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.dials[address]++
+
+	if a.broken[address] {
+		return nil, errors.New("dial failed")
+	}
+
+	return newMockConn(), nil
+}
+
+func (a *addrDialer) Dials(address string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dials[address]
+}
+
+func TestDCPool_RoundRobinAddresses(t *testing.T) {
+	dialer := &addrDialer{dials: map[string]int{}}
+	addrs := []tgAddr{
+		{network: "tcp4", address: "127.0.0.1:443"},
+		{network: "tcp4", address: "127.0.0.2:443"},
+	}
+
+	pool := NewDCPool(1, dialer, addrs, DefaultPoolConfig())
+	defer pool.Close()
+
+	for range 10 {
+		conn, err := pool.Get(context.Background())
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	assert.Equal(t, 5, dialer.Dials("127.0.0.1:443"))
+	assert.Equal(t, 5, dialer.Dials("127.0.0.2:443"))
+}
+
+func TestDCPool_RoundRobinFallback(t *testing.T) {
+	dialer := &addrDialer{
+		dials:  map[string]int{},
+		broken: map[string]bool{"127.0.0.1:443": true},
+	}
+	addrs := []tgAddr{
+		{network: "tcp4", address: "127.0.0.1:443"},
+		{network: "tcp4", address: "127.0.0.2:443"},
+	}
+
+	pool := NewDCPool(1, dialer, addrs, DefaultPoolConfig())
+	defer pool.Close()
+
+	for range 4 {
+		conn, err := pool.Get(context.Background())
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	assert.Equal(t, 4, dialer.Dials("127.0.0.2:443"))
+}
+
+func TestDCPool_MaxDialAddresses(t *testing.T) {
+	dialer := &addrDialer{dials: map[string]int{}, broken: map[string]bool{}}
+	addrs := make([]tgAddr, 10)
+
+	for i := range addrs {
+		addrs[i] = tgAddr{network: "tcp4", address: fmt.Sprintf("127.0.0.%d:443", i+1)}
+		dialer.broken[addrs[i].address] = true
+	}
+
+	config := DefaultPoolConfig()
+	config.MaxDialAddresses = 3
+
+	pool := NewDCPool(1, dialer, addrs, config)
+	defer pool.Close()
+
+	_, err := pool.Get(context.Background())
+	require.Error(t, err)
+
+	tried := 0
+	for _, addr := range addrs {
+		tried += dialer.Dials(addr.address)
+	}
+
+	assert.Equal(t, 3, tried)
+}
@@ -3,8 +3,11 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
@@ -24,13 +27,34 @@ type Telegram struct {
 	useConnPool bool                   // Включен ли connection pooling
 
 	// DC auto-refresh
-	refresher *dcRefresher
+	refresher        *dcRefresher
+	onRefreshFailure DCRefreshFailureCallback
+
+	// dialStagger — задержка перед следующей параллельной попыткой
+	// подключения к другому адресу того же DC.
+	dialStagger time.Duration
+
+	// dcFailures — DC с недавними ошибками dial. nil, если выключено.
+	dcFailures *dcFailures
+
+	// dialStats — счётчики dial по DC, общие с пулом соединений.
+	dialStats *dialStats
+
+	// maxDialAddresses — сколько адресов DC пробовать за один dial.
+	// 0 — все.
+	maxDialAddresses int
+
+	// dialOffset сдвигает окно из maxDialAddresses адресов на каждый
+	// dial, как DCPool.dial.
+	dialOffset atomic.Uint32
 }
 
 // Dial создаёт или переиспользует соединение к DC.
 // Если включен connection pooling, соединение будет взято из пула.
 // Возвращённое соединение при закрытии вернётся в пул автоматически.
-func (t *Telegram) Dial(ctx context.Context, dc int) (essentials.Conn, error) {
+func (t *Telegram) Dial(ctx context.Context, dc int) (conn essentials.Conn, err error) {
+	defer func() { t.reportDial(ctx, dc, err) }()
+
 	addresses := t.getAddresses(dc)
 
 	// Используем connection pool если включен
@@ -56,7 +80,30 @@ func (t *Telegram) Dial(ctx context.Context, dc int) (essentials.Conn, error) {
 // Используется когда pooling отключён или для одноразовых соединений.
 func (t *Telegram) DialDirect(ctx context.Context, dc int) (essentials.Conn, error) {
 	addresses := t.getAddresses(dc)
-	return t.dialDirect(ctx, addresses, dc)
+	conn, err := t.dialDirect(ctx, addresses, dc)
+
+	t.reportDial(ctx, dc, err)
+
+	return conn, err
+}
+
+// reportDial запоминает результат dial для RecentlyFailed. Отмена
+// контекста (клиент ушёл, закончился handshake timeout) — не вина DC.
+func (t *Telegram) reportDial(ctx context.Context, dc int, err error) {
+	switch {
+	case t.dcFailures == nil:
+	case err == nil:
+		t.dcFailures.clear(dc)
+	case ctx.Err() == nil:
+		t.dcFailures.mark(dc)
+	}
+}
+
+// RecentlyFailed сообщает, что dial к DC завершился ошибкой не раньше,
+// чем WithDCFailureTTL назад, и с тех пор успешных dial не было.
+// Всегда false, если опция не задана.
+func (t *Telegram) RecentlyFailed(dc int) bool {
+	return t.dcFailures != nil && t.dcFailures.failed(dc)
 }
 
 // getAddresses возвращает адреса для DC согласно IP preference.
@@ -80,15 +127,99 @@ func (t *Telegram) getAddresses(dc int) []tgAddr {
 	}
 }
 
+// rotateAddresses возвращает count адресов, начиная со start, по кругу.
+// Постоянное окно из первых адресов никогда не доходило бы до второго
+// семейства IP и до адресов за мёртвыми.
+func rotateAddresses(addresses []tgAddr, start, count int) []tgAddr {
+	rv := make([]tgAddr, count)
+
+	for i := range rv {
+		rv[i] = addresses[(start+i)%len(addresses)]
+	}
+
+	return rv
+}
+
 // dialDirect выполняет непосредственное подключение к DC.
-func (t *Telegram) dialDirect(ctx context.Context, addresses []tgAddr, dc int) (essentials.Conn, error) {
-	var conn essentials.Conn
-	err := errNoAddresses
-
-	for _, v := range addresses {
-		conn, err = t.dialer.DialContext(ctx, v.network, v.address)
-		if err == nil {
-			return conn, nil
+//
+// Адреса DC перебираются в стиле Happy Eyeballs (RFC 8305): следующая
+// попытка стартует через dialStagger или сразу после ошибки предыдущей.
+// Побеждает первое успешное соединение, остальные попытки отменяются.
+// Так blackholed-адрес не заставляет ждать полный dial timeout.
+func (t *Telegram) dialDirect(ctx context.Context, addresses []tgAddr, dc int) (conn essentials.Conn, err error) {
+	defer func() { t.dialStats.report(ctx, dc, err) }()
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("cannot dial to %d dc: %w", dc, errNoAddresses)
+	}
+
+	// Полностью недоступный DC с длинным списком адресов иначе стоил бы
+	// по dial timeout на каждый адрес, прежде чем сработает fallback.
+	if t.maxDialAddresses > 0 && len(addresses) > t.maxDialAddresses {
+		start := int((t.dialOffset.Add(1) - 1) % uint32(len(addresses)))
+		addresses = rotateAddresses(addresses, start, t.maxDialAddresses)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialResult struct {
+		conn essentials.Conn
+		err  error
+	}
+
+	results := make(chan dialResult, len(addresses))
+	next := 0
+	pending := 0
+
+	startNext := func() {
+		addr := addresses[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := t.dialer.DialContext(ctx, addr.network, addr.address)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	stagger := time.NewTimer(t.dialStagger)
+	defer stagger.Stop()
+
+	startNext()
+
+	err = errNoAddresses
+
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				// Проигравшие попытки могут успеть подключиться до отмены —
+				// закрываем их в фоне, чтобы не утекали сокеты.
+				go func(left int) {
+					for range left {
+						if late := <-results; late.err == nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+
+				return res.conn, nil
+			}
+
+			err = res.err
+
+			if next < len(addresses) {
+				startNext()
+				stagger.Reset(t.dialStagger)
+			}
+		case <-stagger.C:
+			if next < len(addresses) {
+				startNext()
+				stagger.Reset(t.dialStagger)
+			}
 		}
 	}
 
@@ -103,24 +234,49 @@ func (t *Telegram) IsKnownDC(dc int) bool {
 	return valid
 }
 
+// knownDCs возвращает номера известных DC по возрастанию.
+func (t *Telegram) knownDCs() []int {
+	dcs := []int{}
+
+	for dc := 1; dc <= 5; dc++ {
+		if t.IsKnownDC(dc) {
+			dcs = append(dcs, dc)
+		}
+	}
+
+	return dcs
+}
+
 func (t *Telegram) GetFallbackDC() int {
 	return t.pool.getRandomDC()
 }
 
-// GetFallbackDCExcluding returns a random DC excluding the specified one.
-// Used when a specific DC is unavailable.
-func (t *Telegram) GetFallbackDCExcluding(exclude int) int {
-	return t.pool.getRandomDCExcluding(exclude)
+// GetFallbackDCExcluding returns a random DC excluding the specified ones.
+// Used when specific DCs are unavailable.
+func (t *Telegram) GetFallbackDCExcluding(exclude ...int) int {
+	return t.pool.getRandomDCExcluding(exclude...)
 }
 
 // updatePool атомарно обновляет пул DC-адресов.
+// Пулы соединений DC, чьи адреса изменились, сбрасываются, чтобы не
+// переиспользовать соединения к удалённым из конфига IP. Пулы остальных
+// DC остаются тёплыми.
 func (t *Telegram) updatePool(newPool addressPool) {
 	t.poolMu.Lock()
+	changed := t.pool.changedDCs(newPool)
 	t.pool = newPool
 	t.poolMu.Unlock()
+
+	if t.connPool == nil {
+		return
+	}
+
+	for _, dc := range changed {
+		t.connPool.UpdateAddresses(dc, t.getAddresses(dc))
+	}
 }
 
-// startDCRefresh запускает фоновое обновление DC-адресов из файла.
+// startDCRefresh запускает фоновое обновление DC-адресов из файла или по URL.
 // При ошибке загрузки используются hardcoded адреса (fallbackPool).
 func (t *Telegram) startDCRefresh() {
 	if t.refresher == nil {
@@ -128,15 +284,19 @@ func (t *Telegram) startDCRefresh() {
 	}
 
 	// Начальная загрузка
-	if newPool, err := loadDCConfig(t.refresher.filePath); err == nil {
+	newPool, err := t.refresher.load()
+	if err == nil {
 		t.updatePool(*newPool)
 	}
 	// При ошибке остаётся fallbackPool (hardcoded)
 
+	t.refresher.countLoad(err, t.onRefreshFailure)
+
 	go t.refreshLoop()
 }
 
-// refreshLoop периодически обновляет DC-адреса из файла.
+// refreshLoop периодически обновляет DC-адреса из источника. Каждый
+// следующий интервал берётся с jitter.
 func (t *Telegram) refreshLoop() {
 	ticker := newSafeRefreshTicker(t.refresher.interval)
 	defer ticker.Stop()
@@ -146,7 +306,11 @@ func (t *Telegram) refreshLoop() {
 		case <-t.refresher.stopCh:
 			return
 		case <-ticker.C:
-			newPool, err := loadDCConfig(t.refresher.filePath)
+			ticker.Reset(safeRefreshInterval(t.refresher.interval))
+
+			newPool, err := t.refresher.load()
+			t.refresher.countLoad(err, t.onRefreshFailure)
+
 			if err != nil {
 				// Ошибка загрузки — откатываемся на hardcoded
 				t.updatePool(t.refresher.fallbackPool)
@@ -161,11 +325,17 @@ func (t *Telegram) refreshLoop() {
 
 // newSafeRefreshTicker создаёт тикер с минимальным интервалом 1 минута.
 func newSafeRefreshTicker(interval time.Duration) *time.Ticker {
+	return time.NewTicker(safeRefreshInterval(interval))
+}
+
+// safeRefreshInterval возвращает интервал с jitter; до jitter он
+// поднимается минимум до 1 минуты.
+func safeRefreshInterval(interval time.Duration) time.Duration {
 	if interval < time.Minute {
 		interval = time.Minute
 	}
 
-	return time.NewTicker(interval)
+	return jitterInterval(interval)
 }
 
 // Close закрывает все пулы соединений и останавливает DC refresh.
@@ -190,6 +360,11 @@ func (t *Telegram) PoolStats() []PoolStats {
 	return t.connPool.AllStats()
 }
 
+// DialStats возвращает счётчики dial по DC: и прямых, и из пула.
+func (t *Telegram) DialStats() []DialStats {
+	return t.dialStats.all()
+}
+
 // TelegramOption — опция для конфигурации Telegram.
 type TelegramOption func(*Telegram)
 
@@ -197,6 +372,7 @@ type TelegramOption func(*Telegram)
 func WithConnectionPool(config PoolConfig) TelegramOption {
 	return func(t *Telegram) {
 		t.connPool = NewConnectionPoolManager(t.dialer, config)
+		t.connPool.dialStats = t.dialStats
 		t.useConnPool = true
 	}
 }
@@ -209,6 +385,64 @@ func WithDCConfigFile(filePath string, refreshInterval time.Duration) TelegramOp
 	}
 }
 
+// WithDCConfigURL включает авто-обновление DC-адресов по HTTP: url
+// должен отдавать JSON в формате DCConfigFile. Fallback на hardcoded
+// адреса при ошибке загрузки — так же, как у WithDCConfigFile.
+//
+// Запросы идут через dialer Telegram. URL проверяется на SSRF при
+// разборе конфига, поэтому редиректы не выполняются: ответ 3xx
+// считается ошибкой загрузки.
+func WithDCConfigURL(url string, refreshInterval time.Duration) TelegramOption {
+	return func(t *Telegram) {
+		httpClient := &http.Client{
+			Timeout: DefaultDCConfigFetchTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					return t.dialer.DialContext(ctx, network, address)
+				},
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		t.refresher = newDCURLRefresher(url, httpClient, refreshInterval, t.fallbackPool)
+	}
+}
+
+// WithDCRefreshFailureCallback задаёт callback, который сообщает о
+// неудачных загрузках DC-конфига подряд. Имеет смысл только вместе с
+// WithDCConfigFile или WithDCConfigURL.
+func WithDCRefreshFailureCallback(callback DCRefreshFailureCallback) TelegramOption {
+	return func(t *Telegram) {
+		t.onRefreshFailure = callback
+	}
+}
+
+// WithDCFailureTTL включает память о DC с ошибками dial: после ошибки
+// RecentlyFailed возвращает true в течение ttl.
+func WithDCFailureTTL(ttl time.Duration) TelegramOption {
+	return func(t *Telegram) {
+		if ttl > 0 {
+			t.dcFailures = newDCFailures(ttl)
+		}
+	}
+}
+
+// WithMaxDialAddresses ограничивает число адресов DC, которые
+// пробуются за один dial. Окно адресов сдвигается на каждый dial, так
+// что со временем пробуются все адреса обоих семейств IP. Первое окно
+// начинается с адресов предпочтительного семейства. 0 — все адреса.
+//
+// Пул соединений ограничивается отдельно: PoolConfig.MaxDialAddresses.
+func WithMaxDialAddresses(limit int) TelegramOption {
+	return func(t *Telegram) {
+		if limit > 0 {
+			t.maxDialAddresses = limit
+		}
+	}
+}
+
 // WithoutConnectionPool отключает connection pooling (по умолчанию).
 func WithoutConnectionPool() TelegramOption {
 	return func(t *Telegram) {
@@ -248,6 +482,8 @@ func New(dialer Dialer, ipPreference string, useTestDCs bool, opts ...TelegramOp
 		pool:         pool,
 		fallbackPool: pool, // hardcoded копия — никогда не меняется
 		useConnPool:  false, // По умолчанию выключен
+		dialStagger:  DefaultDialStagger,
+		dialStats:    newDialStats(),
 	}
 
 	// Применяем опции
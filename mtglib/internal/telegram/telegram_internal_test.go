@@ -7,6 +7,7 @@ import (
 	"net"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/9seconds/mtg/v2/internal/testlib"
 	"github.com/stretchr/testify/assert"
@@ -153,6 +154,145 @@ func (suite *TelegramTestSuite) TestFallbackDC() {
 	}
 }
 
+func (suite *TelegramTestSuite) TestDialRacesBlackholedAddress() {
+	blackholed := productionV4Addresses[1][0]
+	alive := productionV4Addresses[1][1]
+	conn := &net.TCPConn{}
+	cancelled := make(chan struct{})
+
+	suite.dialerMock.
+		On("DialContext", mock.Anything, blackholed.network, blackholed.address).
+		Once().
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done() //nolint: forcetypeassert
+			close(cancelled)
+		}).
+		Return((*net.TCPConn)(nil), context.Canceled)
+	suite.dialerMock.
+		On("DialContext", mock.Anything, alive.network, alive.address).
+		Once().
+		Return(conn, nil)
+
+	suite.t.dialStagger = 10 * time.Millisecond
+
+	res, err := suite.t.dialDirect(context.Background(), []tgAddr{blackholed, alive}, 2)
+	suite.NoError(err)
+	suite.Equal(conn, res)
+
+	// Заблокированная попытка должна быть отменена после победы второй
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		suite.Fail("blackholed dial was not cancelled")
+	}
+}
+
+func (suite *TelegramTestSuite) TestDialMaxAddresses() {
+	tg, err := New(suite.dialerMock, "only-ipv4", false, WithMaxDialAddresses(2))
+	suite.NoError(err)
+
+	tg.dialStagger = time.Millisecond
+	addresses := make([]tgAddr, 10)
+
+	for i := range addresses {
+		addresses[i] = tgAddr{network: "tcp4", address: "127.0.0." + strconv.Itoa(i+1) + ":443"}
+	}
+
+	for _, addr := range addresses[:2] {
+		suite.dialerMock.
+			On("DialContext", mock.Anything, addr.network, addr.address).
+			Once().
+			Return((*net.TCPConn)(nil), io.EOF)
+	}
+
+	_, err = tg.dialDirect(context.Background(), addresses, 2)
+	suite.ErrorIs(err, io.EOF)
+	suite.dialerMock.AssertNumberOfCalls(suite.T(), "DialContext", 2)
+}
+
+func (suite *TelegramTestSuite) TestDialMaxAddressesRotates() {
+	tg, err := New(suite.dialerMock, "prefer-ipv6", false, WithMaxDialAddresses(2))
+	suite.NoError(err)
+
+	tg.dialStagger = time.Millisecond
+	addresses := []tgAddr{
+		{network: "tcp6", address: "[::1]:443"},
+		{network: "tcp6", address: "[::2]:443"},
+		{network: "tcp4", address: "127.0.0.1:443"},
+	}
+
+	suite.dialerMock.
+		On("DialContext", mock.Anything, "tcp6", mock.Anything).
+		Return((*net.TCPConn)(nil), io.EOF)
+	suite.dialerMock.
+		On("DialContext", mock.Anything, "tcp4", mock.Anything).
+		Return((*net.TCPConn)(nil), io.EOF)
+
+	// Первое окно — только предпочтительный IPv6.
+	_, err = tg.dialDirect(context.Background(), addresses, 2)
+	suite.ErrorIs(err, io.EOF)
+	suite.dialerMock.AssertNotCalled(suite.T(), "DialContext", mock.Anything, "tcp4", "127.0.0.1:443")
+
+	// Сломанный IPv6 не прячет IPv4 навсегда.
+	_, err = tg.dialDirect(context.Background(), addresses, 2)
+	suite.ErrorIs(err, io.EOF)
+	suite.dialerMock.AssertCalled(suite.T(), "DialContext", mock.Anything, "tcp4", "127.0.0.1:443")
+}
+
+func (suite *TelegramTestSuite) TestRecentlyFailed() {
+	tg, err := New(suite.dialerMock, "only-ipv4", false, WithDCFailureTTL(100*time.Millisecond))
+	suite.NoError(err)
+
+	for _, addr := range productionV4Addresses[1] {
+		suite.dialerMock.
+			On("DialContext", mock.Anything, addr.network, addr.address).
+			Return((*net.TCPConn)(nil), io.EOF)
+	}
+
+	suite.False(tg.RecentlyFailed(2))
+
+	_, err = tg.Dial(context.Background(), 2)
+	suite.Error(err)
+	suite.True(tg.RecentlyFailed(2))
+	suite.False(tg.RecentlyFailed(3))
+
+	suite.Eventually(func() bool {
+		return !tg.RecentlyFailed(2)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *TelegramTestSuite) TestRecentlyFailedIgnoresCancel() {
+	tg, err := New(suite.dialerMock, "only-ipv4", false, WithDCFailureTTL(time.Minute))
+	suite.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, addr := range productionV4Addresses[1] {
+		suite.dialerMock.
+			On("DialContext", mock.Anything, addr.network, addr.address).
+			Return((*net.TCPConn)(nil), context.Canceled).
+			Maybe()
+	}
+
+	_, err = tg.Dial(ctx, 2)
+	suite.Error(err)
+	suite.False(tg.RecentlyFailed(2))
+}
+
+func (suite *TelegramTestSuite) TestRecentlyFailedDisabled() {
+	for _, addr := range append(append([]tgAddr{}, productionV4Addresses[1]...), productionV6Addresses[1]...) {
+		suite.dialerMock.
+			On("DialContext", mock.Anything, addr.network, addr.address).
+			Return((*net.TCPConn)(nil), io.EOF).
+			Maybe()
+	}
+
+	_, err := suite.t.Dial(context.Background(), 2)
+	suite.Error(err)
+	suite.False(suite.t.RecentlyFailed(2))
+}
+
 func TestTelegram(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &TelegramTestSuite{})
@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TFOWarmUpResult — итог прогрева TFO cookies.
+type TFOWarmUpResult struct {
+	Warmed int
+	Failed int
+}
+
+// WarmUpTFO открывает и сразу закрывает соединение к каждому адресу
+// каждого известного DC, чтобы ядро получило и закэшировало TCP Fast
+// Open cookies до первых клиентов.
+//
+// Это эвристика: cookies хранит ядро, а не процесс, поэтому обычно они
+// переживают рестарт mtg, и прогрев ничего не меняет. Он полезен, если
+// кэш cookies сброшен или привязан к network namespace (например,
+// новый контейнер). Если TFO на dialer выключен, это просто проверка
+// соединения с каждым адресом.
+//
+// Соединения идут мимо connection pool и мимо Happy Eyeballs в
+// dialDirect: нужен каждый адрес, а не первый ответивший.
+func (t *Telegram) WarmUpTFO(ctx context.Context, timeout time.Duration) TFOWarmUpResult {
+	if timeout <= 0 {
+		timeout = DefaultDCHealthCheckTimeout
+	}
+
+	var warmed, failed atomic.Int64
+
+	wg := &sync.WaitGroup{}
+
+	for _, dc := range t.knownDCs() {
+		for _, addr := range t.getAddresses(dc) {
+			wg.Add(1)
+
+			go func(addr tgAddr) {
+				defer wg.Done()
+
+				dialCtx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+
+				conn, err := t.dialer.DialContext(dialCtx, addr.network, addr.address)
+				if err != nil {
+					failed.Add(1)
+
+					return
+				}
+
+				conn.Close() //nolint: errcheck
+				warmed.Add(1)
+			}(addr)
+		}
+	}
+
+	wg.Wait()
+
+	return TFOWarmUpResult{
+		Warmed: int(warmed.Load()),
+		Failed: int(failed.Load()),
+	}
+}
@@ -0,0 +1,44 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUpTFO_DialsEveryAddress(t *testing.T) {
+	dialer := &addrDialer{
+		dials:  map[string]int{},
+		broken: map[string]bool{testV6Addresses[2][0].address: true},
+	}
+
+	tg, err := New(dialer, "prefer-ipv4", true)
+	require.NoError(t, err)
+
+	res := tg.WarmUpTFO(context.Background(), 0)
+	assert.Equal(t, len(testV4Addresses)+len(testV6Addresses)-1, res.Warmed)
+	assert.Equal(t, 1, res.Failed)
+
+	for _, addrs := range append(testV4Addresses, testV6Addresses...) {
+		for _, addr := range addrs {
+			assert.Equal(t, 1, dialer.Dials(addr.address), addr.address)
+		}
+	}
+}
+
+func TestWarmUpTFO_RespectsIPPreference(t *testing.T) {
+	dialer := &addrDialer{dials: map[string]int{}, broken: map[string]bool{}}
+
+	tg, err := New(dialer, "only-ipv4", true)
+	require.NoError(t, err)
+
+	res := tg.WarmUpTFO(context.Background(), 0)
+	assert.Equal(t, len(testV4Addresses), res.Warmed)
+	assert.Zero(t, res.Failed)
+
+	for _, addrs := range testV6Addresses {
+		assert.Zero(t, dialer.Dials(addrs[0].address))
+	}
+}
@@ -55,3 +55,22 @@ func hashIP(ip net.IP) string {
 
 	return hex.EncodeToString(sum[:6]) // 12 hex chars = 48 бит
 }
+
+// hashSNI хэширует SNI клиента так же, как hashIP: с той же per-instance
+// солью. SNI, пришедший от сканера, может быть уникальным и указывать на
+// конкретного человека, поэтому в логи и метрики по умолчанию идёт хэш.
+// Пустой SNI остаётся пустым.
+func hashSNI(sni string) string {
+	if sni == "" {
+		return ""
+	}
+
+	initIPHashSalt()
+
+	h := sha256.New()
+	h.Write(ipHashSalt)
+	h.Write([]byte(sni))
+	sum := h.Sum(nil)
+
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,120 @@
+package mtglib
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"golang.org/x/time/rate"
+)
+
+// ListenerOptions are per-listener overrides of proxy-wide limits. They
+// allow to serve, for example, IPv4 and IPv6 listeners with different
+// limits because of different abuse profiles:
+//
+//	go proxy.ServeWithOptions(v4Listener, mtglib.ListenerOptions{})
+//	go proxy.ServeWithOptions(v6Listener, mtglib.ListenerOptions{
+//	    Concurrency:        1024,
+//	    RateLimitPerSecond: 2,
+//	})
+//
+// Zero value means no overrides: a listener uses proxy-wide limits only.
+type ListenerOptions struct {
+	// Concurrency limits how many connections accepted by this listener
+	// are served at the same time. These connections also count against
+	// ProxyOpts.Concurrency, so the effective limit is a minimum of both.
+	//
+	// 0 means that only a proxy-wide limit applies.
+	Concurrency uint
+
+	// RateLimitPerSecond defines the maximum number of handshakes per
+	// second per IP for connections accepted by this listener. If set, a
+	// listener has its own rate limiter instead of a proxy-wide one.
+	//
+	// 0 means that a proxy-wide rate limiter is used.
+	RateLimitPerSecond float64
+
+	// RateLimitBurst defines the maximum burst size for
+	// RateLimitPerSecond.
+	//
+	// This is an optional setting. Default: DefaultRateLimitBurst
+	RateLimitBurst int
+}
+
+func (l ListenerOptions) getRateLimitBurst() int {
+	if l.RateLimitBurst <= 0 {
+		return DefaultRateLimitBurst
+	}
+
+	return l.RateLimitBurst
+}
+
+// listenerLimits — лимиты одного listener. Живут, пока работает
+// ServeWithOptions, но соединения могут пережить его: release и
+// rateLimiter безопасны и после возврата.
+type listenerLimits struct {
+	concurrency int64
+	active      atomic.Int64
+	rateLimiter *RateLimiter
+}
+
+func newListenerLimits(opts ListenerOptions) *listenerLimits {
+	limits := &listenerLimits{
+		concurrency: int64(opts.Concurrency),
+	}
+
+	if opts.RateLimitPerSecond > 0 {
+		limits.rateLimiter = NewRateLimiter(
+			rate.Limit(opts.RateLimitPerSecond),
+			opts.getRateLimitBurst(),
+			time.Minute)
+	}
+
+	return limits
+}
+
+// acquire занимает слот listener. false — лимит исчерпан.
+func (l *listenerLimits) acquire() bool {
+	if l.concurrency <= 0 {
+		return true
+	}
+
+	if l.active.Add(1) > l.concurrency {
+		l.active.Add(-1)
+
+		return false
+	}
+
+	return true
+}
+
+func (l *listenerLimits) release() {
+	if l.concurrency > 0 {
+		l.active.Add(-1)
+	}
+}
+
+// getRateLimiter возвращает собственный rate limiter listener или
+// общий, если своего нет.
+func (l *listenerLimits) getRateLimiter(proxyWide *RateLimiter) *RateLimiter {
+	if l.rateLimiter != nil {
+		return l.rateLimiter
+	}
+
+	return proxyWide
+}
+
+func (l *listenerLimits) stop() {
+	if l.rateLimiter != nil {
+		l.rateLimiter.Stop()
+	}
+}
+
+// listenerConn — соединение вместе с лимитами listener, который его
+// принял, и временем Accept. В таком виде оно уходит в worker pool.
+type listenerConn struct {
+	essentials.Conn
+
+	limits     *listenerLimits
+	acceptedAt time.Time
+}
@@ -0,0 +1,197 @@
+package mtglib
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticIPList bool
+
+func (s staticIPList) Contains(net.IP) bool { return bool(s) }
+func (s staticIPList) Run(time.Duration)    {}
+func (s staticIPList) Shutdown()            {}
+
+type countingEventStream struct {
+	mutex  sync.Mutex
+	counts map[reflect.Type]int
+}
+
+func (c *countingEventStream) Send(_ context.Context, evt Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.counts == nil {
+		c.counts = map[reflect.Type]int{}
+	}
+
+	c.counts[reflect.TypeOf(evt)]++
+}
+
+func (c *countingEventStream) count(evt Event) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.counts[reflect.TypeOf(evt)]
+}
+
+// listenerTestProxy — прокси, worker pool которого не делает хендшейк, а
+// держит принятые соединения до закрытия hold.
+type listenerTestProxy struct {
+	proxy  *Proxy
+	events *countingEventStream
+	hold   chan struct{}
+
+	servedMutex sync.Mutex
+	served      map[string]int
+}
+
+func newListenerTestProxy(t *testing.T, rateLimiter *RateLimiter) *listenerTestProxy {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rv := &listenerTestProxy{
+		events: &countingEventStream{},
+		hold:   make(chan struct{}),
+		served: map[string]int{},
+	}
+	rv.proxy = &Proxy{
+		ctx:         ctx,
+		ctxCancel:   cancel,
+		eventStream: rv.events,
+		logger:      NoopLogger{},
+		allowlist:   staticIPList(true),
+		blocklist:   staticIPList(false),
+		rateLimiter: rateLimiter,
+	}
+
+	pool, err := ants.NewPoolWithFunc(100, func(arg interface{}) {
+		conn := arg.(listenerConn) //nolint: forcetypeassert
+		defer conn.limits.release()
+		defer conn.Close()
+
+		if !rv.proxy.allowRate(conn.Conn, conn.limits.getRateLimiter(rv.proxy.rateLimiter)) {
+			return
+		}
+
+		rv.servedMutex.Lock()
+		rv.served[conn.LocalAddr().String()]++
+		rv.servedMutex.Unlock()
+
+		<-rv.hold
+	}, ants.WithNonblocking(true))
+	require.NoError(t, err)
+
+	rv.proxy.workerPool = pool
+
+	t.Cleanup(func() {
+		close(rv.hold)
+		cancel()
+		pool.Release()
+	})
+
+	return rv
+}
+
+func (l *listenerTestProxy) serve(t *testing.T, opts ListenerOptions) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { listener.Close() })
+
+	go l.proxy.ServeWithOptions(listener, opts) //nolint: errcheck
+
+	return listener.Addr().String()
+}
+
+func (l *listenerTestProxy) servedBy(addr string) int {
+	l.servedMutex.Lock()
+	defer l.servedMutex.Unlock()
+
+	return l.served[addr]
+}
+
+func (l *listenerTestProxy) dial(t *testing.T, addr string, count int) {
+	t.Helper()
+
+	for range count {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+
+		t.Cleanup(func() { conn.Close() })
+	}
+}
+
+func TestListenerConcurrencyLimits(t *testing.T) {
+	t.Parallel()
+
+	testProxy := newListenerTestProxy(t, nil)
+	limited := testProxy.serve(t, ListenerOptions{Concurrency: 1})
+	unlimited := testProxy.serve(t, ListenerOptions{})
+
+	testProxy.dial(t, limited, 3)
+	testProxy.dial(t, unlimited, 3)
+
+	assert.Eventually(t, func() bool {
+		return testProxy.servedBy(unlimited) == 3 &&
+			testProxy.events.count(EventConcurrencyLimited{}) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, testProxy.servedBy(limited))
+}
+
+func TestListenerRateLimits(t *testing.T) {
+	t.Parallel()
+
+	// Общий rate limiter пропускает 3 handshake с IP, собственный
+	// limiter listener — только один. Лимиты не должны влиять друг на
+	// друга, хотя клиент один и тот же.
+	proxyWide := NewRateLimiter(0.001, 3, time.Minute)
+	t.Cleanup(proxyWide.Stop)
+
+	testProxy := newListenerTestProxy(t, proxyWide)
+	own := testProxy.serve(t, ListenerOptions{RateLimitPerSecond: 0.001, RateLimitBurst: 1})
+	shared := testProxy.serve(t, ListenerOptions{})
+
+	testProxy.dial(t, own, 3)
+	testProxy.dial(t, shared, 3)
+
+	assert.Eventually(t, func() bool {
+		return testProxy.servedBy(shared) == 3 &&
+			testProxy.events.count(EventRateLimited{}) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, testProxy.servedBy(own))
+}
+
+func TestListenerLimitsRelease(t *testing.T) {
+	t.Parallel()
+
+	limits := newListenerLimits(ListenerOptions{Concurrency: 2})
+	defer limits.stop()
+
+	assert.True(t, limits.acquire())
+	assert.True(t, limits.acquire())
+	assert.False(t, limits.acquire())
+
+	limits.release()
+	assert.True(t, limits.acquire())
+
+	unlimited := newListenerLimits(ListenerOptions{})
+	defer unlimited.stop()
+
+	for range 10 {
+		assert.True(t, unlimited.acquire())
+	}
+
+	assert.Nil(t, unlimited.getRateLimiter(nil))
+}
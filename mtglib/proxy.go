@@ -1,17 +1,23 @@
 package mtglib
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/affinity"
 	"github.com/9seconds/mtg/v2/mtglib/internal/faketls"
 	"github.com/9seconds/mtg/v2/mtglib/internal/faketls/record"
 	"github.com/9seconds/mtg/v2/mtglib/internal/obfuscated2"
@@ -20,6 +26,21 @@ import (
 	"github.com/panjf2000/ants/v2"
 )
 
+// handshakeFailureReason сопоставляет ошибку obfuscated2 handshake с одной
+// из причин HandshakeFailure*.
+func handshakeFailureReason(err error) string {
+	switch {
+	case errors.Is(err, obfuscated2.ErrServerHandshakeExhausted):
+		return HandshakeFailureExhausted
+	case errors.Is(err, obfuscated2.ErrServerHandshakeCipherInit):
+		return HandshakeFailureCipherInit
+	case errors.Is(err, obfuscated2.ErrServerHandshakeWrite):
+		return HandshakeFailureWrite
+	}
+
+	return HandshakeFailureOther
+}
+
 // isBrokenPipeError проверяет, является ли ошибка broken pipe или connection reset.
 // Это происходит когда соединение из pool было закрыто Telegram до использования.
 func isBrokenPipeError(err error) bool {
@@ -45,21 +66,46 @@ type Proxy struct {
 	streamWaitGroup sync.WaitGroup
 
 	allowFallbackOnUnknownDC bool
+	unknownDCMapping         map[int]int
 	fallbackOnDialError      bool
-	tolerateTimeSkewness     time.Duration
+	rejectScanners           bool
+	tolerateTimeSkewness     timeSkewness
 	domainFrontingPort       int
+	domainFrontingMax        int64
+	domainFrontingActive     atomic.Int64
+	fakeTLSMaxRecordSize     int
+	fakeTLSSmallFirstRecords int
 	workerPool               *ants.PoolWithFunc
+	workerPoolBusy           atomic.Int64
+	workerPoolPressure       atomic.Bool
+	maxRelays                int64
+	relaysActive             atomic.Int64
+	detachedConns            atomic.Int64
+	acceptBackpressure       int64
 	telegram                 *telegram.Telegram
+	telegramDialer           TelegramDialer
 	config                   ProxyConfig
 	rateLimiter              *RateLimiter
-
-	secret          Secret
-	network         Network
-	antiReplayCache AntiReplayCache
-	blocklist       IPBlocklist
-	allowlist       IPBlocklist
-	eventStream     EventStream
-	logger          Logger
+	skewRateLimiter          *RateLimiter
+	inBandMetrics            *InBandMetrics
+	silentRejects            *silentRejects
+	tarpitDuration           time.Duration
+	tarpitActive             atomic.Int64
+	draining                 atomic.Bool
+	telegramHealthThreshold  int64
+	telegramHealthFailures   atomic.Int64
+	listenersMutex           sync.Mutex
+	listeners                map[net.Listener]struct{}
+
+	secret           Secret
+	network          Network
+	antiReplayCache  atomic.Pointer[AntiReplayCache]
+	antiReplayKey    AntiReplayKeyFunc
+	antiReplayDigest func([]byte) []byte
+	blocklist        IPBlocklist
+	allowlist        IPBlocklist
+	eventStream      EventStream
+	logger           Logger
 }
 
 // DomainFrontingAddress returns a host:port pair for a fronting domain.
@@ -73,13 +119,130 @@ func (p *Proxy) ServeConn(conn essentials.Conn) {
 	p.streamWaitGroup.Add(1)
 	defer p.streamWaitGroup.Done()
 
-	// Rate limiting check BEFORE creating stream context
+	p.serveConn(conn, p.rateLimiter, time.Time{}, nil)
+}
+
+// serveWorker — функция worker pool: обслуживает соединение от Serve с
+// лимитами его listener. streamWaitGroup для него увеличил Serve.
+//
+// С MaxRelays соединение обслуживается в отдельной горутине, а воркер
+// ждёт только до начала relay или domain fronting, или до конца
+// соединения, если до них дело не дошло. Лимиты listener держатся всё
+// время соединения. Отпущенные воркером соединения считаются в
+// detachedConns, чтобы backpressure видел все активные соединения.
+func (p *Proxy) serveWorker(conn listenerConn) {
+	rateLimiter := conn.limits.getRateLimiter(p.rateLimiter)
+
+	if p.maxRelays == 0 {
+		defer p.streamWaitGroup.Done()
+		defer conn.limits.release()
+
+		p.serveConn(conn.Conn, rateLimiter, conn.acceptedAt, nil)
+
+		return
+	}
+
+	released := make(chan struct{})
+	detached := false
+
+	// Вызывается только из горутины соединения, поэтому detached не
+	// нужна синхронизация. detachedConns растёт до того, как воркер
+	// освободится, и сумма activeConns не проседает.
+	releaseWorker := func() {
+		if !detached {
+			detached = true
+			p.detachedConns.Add(1)
+			close(released)
+		}
+	}
+
+	go func() {
+		defer p.streamWaitGroup.Done()
+		defer conn.limits.release()
+		defer func() {
+			if detached {
+				p.detachedConns.Add(-1)
+			} else {
+				close(released)
+			}
+		}()
+
+		p.serveConn(conn.Conn, rateLimiter, conn.acceptedAt, releaseWorker)
+	}()
+
+	<-released
+}
+
+// activeConns — все активные соединения из Serve: занятые воркеры и
+// отпущенные ими relay и domain fronting.
+func (p *Proxy) activeConns() int64 {
+	return p.workerPoolBusy.Load() + p.detachedConns.Load()
+}
+
+// ActiveWorkers returns a number of busy workers in the worker pool.
+// If ProxyOpts.MaxRelays is set, this is a number of connections in
+// handshake, otherwise it is a number of all connections from Serve.
+// AcceptBackpressureThreshold is applied to all connections from Serve
+// regardless of it.
+func (p *Proxy) ActiveWorkers() int {
+	return int(p.workerPoolBusy.Load())
+}
+
+// ActiveRelays returns a number of active relays to Telegram. It is
+// tracked even if ProxyOpts.MaxRelays is not set.
+func (p *Proxy) ActiveRelays() int {
+	return int(p.relaysActive.Load())
+}
+
+// acquireRelay занимает место под relay. Отказ означает, что
+// MaxRelays уже исчерпан и соединение надо закрыть.
+func (p *Proxy) acquireRelay(ctx *streamContext) bool {
+	active := p.relaysActive.Add(1)
+
+	if p.maxRelays > 0 && active > p.maxRelays {
+		p.relaysActive.Add(-1)
+		ctx.logger.Debug("too many active relays, close immediately")
+		p.eventStream.Send(p.ctx, NewEventRelaysLimited(ctx.streamID, ctx.ClientIP()))
+
+		return false
+	}
+
+	return true
+}
+
+// allowRate проверяет rate limit до создания stream context. Отклонённое
+// соединение закрывается.
+func (p *Proxy) allowRate(conn essentials.Conn, rateLimiter *RateLimiter) bool {
 	ipAddr := conn.RemoteAddr().(*net.TCPAddr).IP //nolint: forcetypeassert
-	if p.rateLimiter != nil && !p.rateLimiter.Allow(ipAddr) {
+	if p.skewRateLimiter != nil && p.skewRateLimiter.Exhausted(ipAddr) {
+		p.logger.BindStr("ip", hashIP(ipAddr)).Warning("Rate limited for time skew")
+		p.eventStream.Send(p.ctx, NewEventRateLimited(ipAddr))
+		conn.Close()
+
+		return false
+	}
+
+	if rateLimiter != nil && !rateLimiter.Allow(ipAddr) {
 		p.logger.BindStr("ip", hashIP(ipAddr)).Warning("Rate limited")
-		p.eventStream.Send(p.ctx, NewEventConcurrencyLimited())
+		p.eventStream.Send(p.ctx, NewEventRateLimited(ipAddr))
 		conn.Close()
 
+		return false
+	}
+
+	return true
+}
+
+// serveConn обслуживает соединение. acceptedAt — время Accept для
+// соединений из Serve, для ServeConn оно нулевое. releaseWorker, если
+// задан, вызывается прямо перед началом relay или domain fronting.
+func (p *Proxy) serveConn(conn essentials.Conn,
+	rateLimiter *RateLimiter,
+	acceptedAt time.Time,
+	releaseWorker func(),
+) {
+	// Rate limiting check BEFORE creating stream context
+	if !p.allowRate(conn, rateLimiter) {
 		return
 	}
 
@@ -92,11 +255,14 @@ func (p *Proxy) ServeConn(conn essentials.Conn) {
 	}
 	defer ctx.Close()
 
+	ctx.releaseWorker = releaseWorker
+
 	// Handshake deadline: сбрасывается ЯВНО после хендшейка, а не через defer.
 	// defer здесь нельзя — deadline остался бы активен во время relay, убивая
 	// все соединения через HandshakeTimeout секунд.
 	if p.config.HandshakeTimeout > 0 {
-		conn.SetDeadline(time.Now().Add(p.config.HandshakeTimeout)) //nolint: errcheck
+		ctx.handshakeDeadline = time.Now().Add(p.config.HandshakeTimeout)
+		conn.SetDeadline(ctx.handshakeDeadline) //nolint: errcheck
 	}
 
 	go func() {
@@ -107,6 +273,10 @@ func (p *Proxy) ServeConn(conn essentials.Conn) {
 	p.eventStream.Send(ctx, NewEventStart(ctx.streamID, ctx.ClientIP()))
 	ctx.logger.Info("Stream has been started")
 
+	if !acceptedAt.IsZero() {
+		p.eventStream.Send(ctx, NewEventWorkerQueueWait(ctx.streamID, time.Since(acceptedAt)))
+	}
+
 	defer func() {
 		p.eventStream.Send(ctx, NewEventFinish(ctx.streamID))
 		ctx.logger.Info("Stream has been finished")
@@ -117,46 +287,101 @@ func (p *Proxy) ServeConn(conn essentials.Conn) {
 	}
 
 	if err := p.doObfuscated2Handshake(ctx); err != nil {
-		p.logger.InfoError("obfuscated2 handshake is failed", err)
+		ctx.logger.InfoError("obfuscated2 handshake is failed", err)
 
 		return
 	}
 
 	// Хендшейк завершён — сбрасываем deadline перед relay.
 	// TCP_USER_TIMEOUT (30s) в relay.go берёт на себя защиту от мёртвых соединений.
+	// Зависшие на одном Read/Write соединения рвёт RelayIOTimeout, если он задан.
 	conn.SetDeadline(time.Time{}) //nolint: errcheck
 
+	// Лимит проверяется до dial: незачем ходить в Telegram ради клиента,
+	// которого всё равно закроем.
+	if !p.acquireRelay(ctx) {
+		return
+	}
+	defer p.relaysActive.Add(-1)
+
 	if err := p.doTelegramCall(ctx); err != nil {
 		// Не логировать спам для несуществующих DC (203, 999 и т.д.)
 		if !strings.Contains(err.Error(), "invalid DC") {
-			p.logger.WarningError("cannot dial to telegram", err)
+			ctx.logger.WarningError("cannot dial to telegram", err)
 		}
 
 		return
 	}
 
-	relay.Relay(
+	p.eventStream.Send(ctx, NewEventRelayStarted(ctx.streamID, ctx.telegramDC, time.Since(ctx.startedAt)))
+
+	ctx.detachFromWorker()
+
+	relay.RelayWithOptions(
 		ctx,
 		ctx.logger.Named("relay"),
 		ctx.telegramConn,
 		ctx.clientConn,
+		p.relayOptions(),
 	)
 }
 
 // Serve starts a proxy on a given listener.
 func (p *Proxy) Serve(listener net.Listener) error {
+	return p.ServeWithOptions(listener, ListenerOptions{})
+}
+
+// ServeWithOptions starts a proxy on a given listener with per-listener
+// limits. It is possible to serve many listeners with different options
+// at the same time. Please see ListenerOptions.
+func (p *Proxy) ServeWithOptions(listener net.Listener, opts ListenerOptions) error {
 	p.streamWaitGroup.Add(1)
 	defer p.streamWaitGroup.Done()
 
+	if !p.trackListener(listener) {
+		return nil
+	}
+	defer p.untrackListener(listener)
+
+	limits := newListenerLimits(opts)
+	defer limits.stop()
+
+	if len(p.config.CPUAffinity) > 0 {
+		if unpin, err := affinity.Pin(p.config.CPUAffinity); err != nil {
+			p.logger.WarningError("cannot pin accept loop to cpus", err)
+		} else {
+			defer unpin()
+		}
+	}
+
 	for {
+		if waited := p.waitAcceptBackpressure(); waited > 0 {
+			p.eventStream.Send(p.ctx, NewEventAcceptBackpressure(waited))
+		}
+
 		conn, err := listener.Accept()
+		acceptedAt := time.Now()
+
 		if err != nil {
 			select {
 			case <-p.ctx.Done():
 				return nil
 			default:
-				return fmt.Errorf("cannot accept a new connection: %w", err)
 			}
+
+			if p.draining.Load() {
+				return nil
+			}
+
+			return fmt.Errorf("cannot accept a new connection: %w", err)
+		}
+
+		// Shutdown уже начался, а listener ещё не закрыт: соединение
+		// не обслуживаем.
+		if p.ctx.Err() != nil {
+			conn.Close()
+
+			return nil
 		}
 
 		ipAddr := conn.RemoteAddr().(*net.TCPAddr).IP //nolint: forcetypeassert
@@ -164,39 +389,189 @@ func (p *Proxy) Serve(listener net.Listener) error {
 
 		if !p.allowlist.Contains(ipAddr) {
 			conn.Close()
-			logger.Info("ip was rejected by allowlist")
-			p.eventStream.Send(p.ctx, NewEventIPAllowlisted(ipAddr))
+
+			if !p.silenceReject(ipAddr) {
+				logger.Info("ip was rejected by allowlist")
+				p.eventStream.Send(p.ctx, NewEventIPAllowlisted(ipAddr))
+			}
 
 			continue
 		}
 
 		if p.blocklist.Contains(ipAddr) {
 			conn.Close()
-			logger.Info("ip was blacklisted")
-			p.eventStream.Send(p.ctx, NewEventIPBlocklisted(ipAddr))
+
+			if !p.silenceReject(ipAddr) {
+				logger.Info("ip was blacklisted")
+				p.eventStream.Send(p.ctx, NewEventIPBlocklisted(ipAddr))
+			}
 
 			continue
 		}
 
-		err = p.workerPool.Invoke(conn)
+		if !limits.acquire() {
+			conn.Close()
+			logger.Info("connection was concurrency limited by listener")
+			p.eventStream.Send(p.ctx, NewEventConcurrencyLimited())
+
+			continue
+		}
+
+		// streamWaitGroup увеличивается до Invoke, а не в воркере:
+		// иначе Shutdown может дождаться группы и освободить пул, пока
+		// соединение ещё не дошло до воркера.
+		p.streamWaitGroup.Add(1)
+
+		err = p.workerPool.Invoke(listenerConn{
+			Conn:       conn.(essentials.Conn), //nolint: forcetypeassert
+			limits:     limits,
+			acceptedAt: acceptedAt,
+		})
+		if err != nil {
+			limits.release()
+			conn.Close()
+			p.streamWaitGroup.Done()
+		}
 
 		switch {
 		case err == nil:
 		case errors.Is(err, ants.ErrPoolClosed):
-			conn.Close()
-
 			return nil
 		case errors.Is(err, ants.ErrPoolOverload):
-			conn.Close()
 			logger.Info("connection was concurrency limited")
 			p.eventStream.Send(p.ctx, NewEventConcurrencyLimited())
+		default:
+			logger.WarningError("cannot serve a connection", err)
+		}
+	}
+}
+
+// silenceReject сообщает, что отказ для ip уже был в пределах
+// ProxyConfig.SilentRejectWindow и о нём не нужно ни логировать, ни
+// отправлять событие.
+func (p *Proxy) silenceReject(ip net.IP) bool {
+	return p.silentRejects != nil && p.silentRejects.silenced(ip, time.Now())
+}
+
+// ServeContext starts a proxy on a given listener and stops accepting new
+// connections when ctx is done. In that case a listener is closed, so
+// Accept unblocks and ServeContext returns nil.
+//
+// Cancelling ctx does not terminate active connections, use Shutdown for
+// that.
+func (p *Proxy) ServeContext(ctx context.Context, listener net.Listener) error {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close() //nolint: errcheck
+		case <-stop:
 		}
+	}()
+
+	err := p.Serve(listener)
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// Drain stops accepting new connections but keeps serving existing ones.
+// It closes listeners of all running Serve calls, so they return nil;
+// Serve on a new listener returns immediately. This is useful for
+// zero-downtime deployments: a load balancer routes new connections
+// elsewhere, while existing clients finish their sessions.
+//
+// Shutdown of a draining proxy waits for remaining connections up to
+// ProxyConfig.DrainTimeout before it terminates them. Drain is
+// idempotent.
+func (p *Proxy) Drain() {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+
+	if !p.draining.CompareAndSwap(false, true) {
+		return
+	}
+
+	for listener := range p.listeners {
+		listener.Close() //nolint: errcheck
+	}
+
+	p.logger.Info("proxy is draining, new connections are not accepted")
+	p.eventStream.Send(p.ctx, NewEventDraining())
+}
+
+// Draining reports if Drain was called.
+func (p *Proxy) Draining() bool {
+	return p.draining.Load()
+}
+
+// trackListener запоминает listener, чтобы Drain мог его закрыть.
+// Возвращает false, если прокси уже в режиме drain.
+func (p *Proxy) trackListener(listener net.Listener) bool {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+
+	if p.draining.Load() {
+		return false
+	}
+
+	if p.listeners == nil {
+		p.listeners = map[net.Listener]struct{}{}
+	}
+
+	p.listeners[listener] = struct{}{}
+
+	return true
+}
+
+func (p *Proxy) untrackListener(listener net.Listener) {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+
+	delete(p.listeners, listener)
+}
+
+// waitDrained ждёт завершения соединений draining прокси, но не
+// дольше timeout. Serve к этому моменту уже вернулись, так что
+// streamWaitGroup считает только соединения.
+func (p *Proxy) waitDrained(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		p.streamWaitGroup.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		p.logger.Warning("drain timeout has expired, remaining connections are terminated")
 	}
 }
 
 // Shutdown 'gracefully' shutdowns all connections. Please remember that it
-// does not close an underlying listener.
+// does not close an underlying listener, and it waits for Serve to return,
+// so a listener has to be closed before. Every connection accepted by
+// Serve is closed before Shutdown returns.
+//
+// If proxy is draining, Shutdown waits for connections to finish first.
+// Please see Drain.
 func (p *Proxy) Shutdown() {
+	if p.draining.Load() {
+		p.waitDrained(p.config.DrainTimeout)
+	}
+
 	p.ctxCancel()
 	p.streamWaitGroup.Wait()
 	p.workerPool.Release()
@@ -209,16 +584,173 @@ func (p *Proxy) Shutdown() {
 		p.rateLimiter.Stop()
 	}
 
+	if p.skewRateLimiter != nil {
+		p.skewRateLimiter.Stop()
+	}
+
 	// Закрытие connection pool к Telegram DC
-	p.telegram.Close()
+	if p.telegram != nil {
+		p.telegram.Close()
+	}
+}
+
+// acceptBackpressurePollInterval — как часто Serve проверяет, не
+// освободились ли соединения.
+const acceptBackpressurePollInterval = 10 * time.Millisecond
+
+// waitAcceptBackpressure ждёт, пока активных соединений станет меньше
+// acceptBackpressure, и возвращает время ожидания. Пока ждём, новые
+// клиенты копятся в backlog ядра, а не получают отказ от пула. При
+// shutdown ожидание прерывается: дальше Accept вернёт ошибку.
+func (p *Proxy) waitAcceptBackpressure() time.Duration {
+	if p.acceptBackpressure == 0 || p.activeConns() < p.acceptBackpressure {
+		return 0
+	}
+
+	startedAt := time.Now()
+	ticker := time.NewTicker(acceptBackpressurePollInterval)
+
+	defer ticker.Stop()
+
+	for p.activeConns() >= p.acceptBackpressure {
+		select {
+		case <-p.ctx.Done():
+			return time.Since(startedAt)
+		case <-ticker.C:
+		}
+	}
+
+	return time.Since(startedAt)
+}
+
+// checkWorkerPoolPressure отправляет EventWorkerPoolPressure, когда
+// загрузка пула пересекает WorkerPoolHighWatermark вверх или
+// WorkerPoolLowWatermark вниз. Между порогами событий нет, поэтому
+// колебания около одного порога не заваливают подписчиков.
+//
+// Считаем занятых воркеров сами: ants.Pool.Running() учитывает и
+// простаивающих воркеров, которые ещё не истекли.
+func (p *Proxy) checkWorkerPoolPressure(running, capacity int) {
+	if capacity <= 0 {
+		return
+	}
+
+	utilization := float64(running) / float64(capacity)
+
+	switch {
+	case utilization >= WorkerPoolHighWatermark:
+		if p.workerPoolPressure.CompareAndSwap(false, true) {
+			p.logger.BindInt("running", running).
+				BindInt("capacity", capacity).
+				Warning("worker pool is under pressure")
+			p.eventStream.Send(p.ctx, NewEventWorkerPoolPressure(running, capacity, true))
+		}
+	case utilization < WorkerPoolLowWatermark:
+		if p.workerPoolPressure.CompareAndSwap(true, false) {
+			p.logger.BindInt("running", running).
+				BindInt("capacity", capacity).
+				Info("worker pool pressure is gone")
+			p.eventStream.Send(p.ctx, NewEventWorkerPoolPressure(running, capacity, false))
+		}
+	}
+}
+
+// probeDCs проверяет доступность всех известных DC и пишет результат
+// в лог. Старт прокси не блокирует: вызывается в отдельной горутине.
+func (p *Proxy) probeDCs(checker *telegram.DCHealthChecker) {
+	logger := p.logger.Named("dc-probe")
+	reachable := 0
+
+	for _, res := range checker.CheckAll(p.ctx) {
+		dcLogger := logger.BindInt("dc", res.DC)
+
+		if !res.Reachable() {
+			dcLogger.WarningError("dc is unreachable", res.Err)
+
+			continue
+		}
+
+		reachable++
+
+		dcLogger.BindStr("latency", res.Latency.String()).Info("dc is reachable")
+	}
+
+	if reachable == 0 && p.ctx.Err() == nil {
+		logger.Warning("no Telegram DC is reachable, proxy will not be able to serve clients")
+	}
+}
+
+// warmUpTFO прогревает TFO cookies всех адресов DC и пишет итог в лог.
+// Старт прокси не блокирует: вызывается в отдельной горутине.
+func (p *Proxy) warmUpTFO(timeout time.Duration) {
+	res := p.telegram.WarmUpTFO(p.ctx, timeout)
+
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	p.logger.Named("tfo-warmup").
+		BindInt("warmed", res.Warmed).
+		BindInt("failed", res.Failed).
+		Info("TCP Fast Open cookies are warmed up")
+}
+
+// relayOptions возвращает TCP-настройки relay из конфигурации прокси.
+func (p *Proxy) relayOptions() relay.Options {
+	return relay.Options{
+		TCPUserTimeout:         p.config.TCPUserTimeout,
+		ClientTCPUserTimeout:   p.config.ClientTCPUserTimeout,
+		TelegramTCPUserTimeout: p.config.TelegramTCPUserTimeout,
+		WindowClamp:            p.config.TelegramWindowClamp,
+		CopyBufferSize:         p.config.RelayBufferSize,
+		IOTimeout:              p.config.RelayIOTimeout,
+		QuickACKInterval:       max(p.config.TCPQuickACKInterval, 0),
+		DisableQuickACK:        p.config.TCPQuickACKInterval == TCPQuickACKDisabled,
+	}
 }
 
 // GetPoolStats returns connection pool statistics for all DCs.
-// Returns nil if connection pooling is disabled.
+// Returns nil if connection pooling is disabled or
+// ProxyOpts.TelegramDialer is set.
 func (p *Proxy) GetPoolStats() []telegram.PoolStats {
+	if p.telegram == nil {
+		return nil
+	}
+
 	return p.telegram.PoolStats()
 }
 
+// GetDialStats returns counters of successful and failed dials to
+// Telegram DCs, both direct and pooled. Returns nil if
+// ProxyOpts.TelegramDialer is set.
+func (p *Proxy) GetDialStats() []telegram.DialStats {
+	if p.telegram == nil {
+		return nil
+	}
+
+	return p.telegram.DialStats()
+}
+
+// ReplaceAntiReplayCache swaps anti-replay cache of a running proxy. It is
+// safe to call concurrently with handshakes: a handshake which has already
+// started keeps checking an old cache. This is how a filter with another
+// size or error rate is applied without a restart and without dropping
+// established sessions.
+//
+// A new cache does not know handshakes seen by the old one, so replays of
+// them are not detected until they fade out anyway. To keep them, seed a
+// new filter before a swap, for example with antireplay.StateSaver
+// (SaveState of the old filter, then LoadState into the new one). Note
+// that a snapshot can only be loaded into a filter with the same
+// parameters. A nil cache is ignored.
+func (p *Proxy) ReplaceAntiReplayCache(cache AntiReplayCache) {
+	if cache == nil {
+		return
+	}
+
+	p.antiReplayCache.Store(&cache)
+}
+
 // GetRateLimiterSize returns number of tracked IPs in rate limiter.
 // Returns 0 if rate limiting is disabled.
 func (p *Proxy) GetRateLimiterSize() int {
@@ -235,52 +767,212 @@ func (p *Proxy) doFakeTLSHandshake(ctx *streamContext) bool {
 
 	rewind := newConnRewind(ctx.clientConn)
 
-	if err := rec.Read(rewind); err != nil {
-		p.logger.InfoError("cannot read client hello", err)
-		p.doDomainFronting(ctx, rewind)
+	// Дешёвая проверка заголовка до разбора ClientHello: сканеры шлют
+	// мусор или обрывают соединение, и тратить на них dial к fronting
+	// домену не всегда нужно.
+	header := [record.HeaderSize]byte{}
+
+	reason, err := p.readClientHello(ctx, rewind, &header, rec)
+
+	switch {
+	case reason == ScannerReasonSlow:
+		// Fronting домену такой клиент не нужен: он держал бы worker и
+		// там. Закрываем соединение сразу.
+		p.eventStream.Send(p.ctx,
+			NewEventScannerDetected(ctx.streamID, ctx.ClientIP(), reason, true))
+		ctx.logger.DebugError("client hello is too slow", err)
+
+		return false
+	case reason != "":
+		if reason == ScannerReasonNotTLS && p.serveInBandMetrics(ctx, rewind, header) {
+			return false
+		}
+
+		p.eventStream.Send(p.ctx,
+			NewEventScannerDetected(ctx.streamID, ctx.ClientIP(), reason, p.rejectScanners))
+
+		if p.rejectScanners {
+			ctx.logger.DebugError("scanner has been rejected", err)
+
+			return false
+		}
+
+		ctx.logger.InfoError("cannot read client hello", err)
+		p.doInvalidHandshake(ctx, rewind)
+
+		return false
+	case err != nil:
+		ctx.logger.InfoError("cannot read client hello", err)
+		p.doInvalidHandshake(ctx, rewind)
 
 		return false
 	}
 
 	hello, err := faketls.ParseClientHello(p.secret.Key[:], rec.Payload.Bytes())
 	if err != nil {
-		p.logger.InfoError("cannot parse client hello", err)
-		p.doDomainFronting(ctx, rewind)
+		ctx.clientSNI = faketls.ParseSNI(rec.Payload.Bytes())
+
+		bindClientHelloInfo(ctx.logger, rec.Payload.Bytes(), err).
+			BindStr("sni", hashSNI(ctx.clientSNI)).
+			InfoError("cannot parse client hello", err)
+		p.doInvalidHandshake(ctx, rewind)
 
 		return false
 	}
 
-	if err := hello.Valid(p.secret.Host, p.tolerateTimeSkewness); err != nil {
-		p.logger.
-			BindStr("hostname", hello.Host).
+	ctx.clientSNI = hello.Host
+
+	err = hello.Valid(p.secret.Host, p.tolerateTimeSkewness.For(ctx.ClientIP()))
+	skewRejected := errors.Is(err, faketls.ErrIncorrectTimestamp)
+
+	p.eventStream.Send(p.ctx,
+		NewEventClientTimeSkewChecked(ctx.streamID, ctx.ClientIP(), hello.TimeSkew(time.Now()), skewRejected))
+
+	if skewRejected && p.skewRateLimiter != nil {
+		// Токен тратится только на отказ по времени: источник, который
+		// шлёт их пачками, упирается в лимит и отсекается уже в
+		// allowRate, до чтения и разбора hello.
+		p.skewRateLimiter.Allow(ctx.ClientIP())
+	}
+
+	if err != nil {
+		bindClientHelloInfo(ctx.logger, rec.Payload.Bytes(), err).
+			BindStr("sni", hashSNI(hello.Host)).
 			BindStr("hello-time", hello.Time.String()).
 			InfoError("invalid faketls client hello", err)
-		p.doDomainFronting(ctx, rewind)
+		p.doInvalidHandshake(ctx, rewind)
 
 		return false
 	}
 
-	if p.antiReplayCache.SeenBefore(hello.SessionID) {
-		p.logger.Warning("replay attack has been detected!")
-		p.eventStream.Send(p.ctx, NewEventReplayAttack(ctx.streamID))
-		p.doDomainFronting(ctx, rewind)
+	if p.isReplayAttack(hello.SessionID, ctx.ClientIP()) {
+		ctx.logger.Warning("replay attack has been detected!")
+		p.eventStream.Send(p.ctx, NewEventReplayAttackFromIP(ctx.streamID, ctx.ClientIP()))
+		p.doInvalidHandshake(ctx, rewind)
 
 		return false
 	}
 
 	if err := faketls.SendWelcomePacket(rewind, p.secret.Key[:], hello); err != nil {
-		p.logger.InfoError("cannot send welcome packet", err)
+		ctx.logger.InfoError("cannot send welcome packet", err)
 
 		return false
 	}
 
 	ctx.clientConn = &faketls.Conn{
-		Conn: ctx.clientConn,
+		Conn:               ctx.clientConn,
+		MaxWriteRecordSize: p.fakeTLSMaxRecordSize,
+		SmallFirstRecords:  p.fakeTLSSmallFirstRecords,
 	}
 
 	return true
 }
 
+// bindClientHelloInfo добавляет к логгеру то, что нужно для разбора
+// «клиент не подключается»: какая проверка не прошла, версии TLS и
+// предложенные cipher suites. Session ID и секрет в лог не попадают.
+func bindClientHelloInfo(logger Logger, handshake []byte, err error) Logger {
+	info := faketls.ParseClientHelloInfo(handshake)
+
+	versions := make([]string, 0, len(info.SupportedVersions))
+	for _, v := range info.SupportedVersions {
+		versions = append(versions, tls.VersionName(v))
+	}
+
+	cipherSuites := make([]string, 0, len(info.CipherSuites))
+	for _, v := range info.CipherSuites {
+		cipherSuites = append(cipherSuites, tls.CipherSuiteName(v))
+	}
+
+	return logger.
+		BindStr("failed-check", faketls.FailedCheck(err)).
+		BindStr("tls-version", tls.VersionName(info.Version)).
+		BindStr("tls-supported-versions", strings.Join(versions, ",")).
+		BindStr("tls-cipher-suites", strings.Join(cipherSuites, ","))
+}
+
+// doInvalidHandshake решает судьбу клиента с невалидным хендшейком:
+// tarpit, если он включён, иначе domain fronting.
+func (p *Proxy) doInvalidHandshake(ctx *streamContext, conn *connRewind) {
+	if p.tarpitDuration > 0 {
+		p.doTarpit(ctx)
+
+		return
+	}
+
+	p.doDomainFronting(ctx, conn)
+}
+
+// isReplayAttack проверяет сессию в antireplay кэше. Owner-ключ
+// проверяется первым: SeenBefore запоминает ключ, и при первом
+// подключении должны сохраниться оба.
+func (p *Proxy) isReplayAttack(sessionID []byte, clientIP net.IP) bool {
+	// nil digest — identity, session id уходит в ключ как есть.
+	if p.antiReplayDigest != nil {
+		sessionID = p.antiReplayDigest(sessionID)
+	}
+
+	key := p.antiReplayKey(sessionID, clientIP, time.Now())
+
+	// Оба ключа должны попасть в один и тот же кэш, даже если его
+	// заменили между проверками.
+	cache := *p.antiReplayCache.Load()
+
+	if key.Owner != nil && cache.SeenBefore(key.Owner) {
+		return false
+	}
+
+	return cache.SeenBefore(key.Session)
+}
+
+// readClientHello читает первый TLS record клиента. Если задан
+// ClientHelloTimeout, весь record должен прийти за это время: иначе
+// клиент, присылающий ClientHello по байту, держит worker почти до конца
+// HandshakeTimeout. Для такого клиента возвращается ScannerReasonSlow.
+func (p *Proxy) readClientHello(ctx *streamContext, conn *connRewind,
+	header *[record.HeaderSize]byte, rec *record.Record,
+) (string, error) {
+	limited := false
+
+	if timeout := p.config.ClientHelloTimeout; timeout > 0 {
+		deadline := time.Now().Add(timeout)
+
+		if ctx.handshakeDeadline.IsZero() || deadline.Before(ctx.handshakeDeadline) {
+			limited = true
+
+			ctx.clientConn.SetReadDeadline(deadline) //nolint: errcheck
+
+			defer ctx.clientConn.SetReadDeadline(ctx.handshakeDeadline) //nolint: errcheck
+		}
+	}
+
+	reason, err := readHandshakeHeader(conn, header)
+	if err == nil {
+		err = rec.Read(io.MultiReader(bytes.NewReader(header[:]), conn))
+	}
+
+	if limited && errors.Is(err, os.ErrDeadlineExceeded) {
+		return ScannerReasonSlow, err
+	}
+
+	return reason, err //nolint: wrapcheck
+}
+
+// readHandshakeHeader читает заголовок первого TLS record и проверяет, что
+// он похож на ClientHello. При ошибке возвращается одна из причин
+// ScannerReason*.
+func readHandshakeHeader(reader io.Reader, header *[record.HeaderSize]byte) (string, error) {
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return ScannerReasonTruncated, fmt.Errorf("cannot read tls record header: %w", err)
+	}
+
+	if err := record.ValidateHandshakeHeader(*header); err != nil {
+		return ScannerReasonNotTLS, fmt.Errorf("unexpected tls record header: %w", err)
+	}
+
+	return "", nil
+}
+
 func (p *Proxy) doObfuscated2Handshake(ctx *streamContext) error {
 	dc, encryptor, decryptor, err := obfuscated2.ClientHandshake(p.secret.Key[:], ctx.clientConn)
 	if err != nil {
@@ -304,26 +996,52 @@ func (p *Proxy) doTelegramCall(ctx *streamContext) error {
 
 	// Telegram официально поддерживает только DC 1-5
 	// Отклонять запросы к несуществующим DC (203, 999 и т.д.) без логирования
-	if !p.telegram.IsKnownDC(dc) {
-		if p.allowFallbackOnUnknownDC {
-			dc = p.telegram.GetFallbackDC()
+	if !p.telegramDialer.IsKnownDC(dc) {
+		mappedDC, mapped := p.unknownDCMapping[dc]
+
+		switch {
+		case mapped:
+			// Для таких DC известна замена: не отказываем и не
+			// выбираем случайный.
+			dc = mappedDC
+			ctx.logger = ctx.logger.BindInt("mapped_dc", dc)
+			ctx.logger.Debug("unknown DC is mapped")
+		case p.allowFallbackOnUnknownDC:
+			dc = p.telegramDialer.GetFallbackDC()
 			ctx.logger = ctx.logger.BindInt("fallback_dc", dc)
 			ctx.logger.Warning("unknown DC, fallbacks")
-		} else {
+		default:
 			// Silent reject для DC > 5 - избегаем спама в логах
 			return fmt.Errorf("invalid DC %d (only DC 1-5 are supported)", dc)
 		}
 	}
 
-	conn, err := p.telegram.Dial(ctx, dc)
+	// DC, которые уже не ответили: fallback после ошибки dial не должен
+	// вернуться ни к одному из них.
+	failedDCs := make([]int, 0, 2) //nolint: gomnd
+
+	// DC недавно не отвечал: сразу идём в fallback, не тратя dial timeout.
+	// После DCFailureTTL DC снова проверяется обычным dial.
+	if p.fallbackOnDialError && p.telegramDialer.RecentlyFailed(dc) {
+		failedDCs = append(failedDCs, dc)
+		fallbackDC := p.telegramDialer.GetFallbackDCExcluding(failedDCs...)
+		ctx.logger = ctx.logger.BindInt("original_dc", originalDC).BindInt("fallback_dc", fallbackDC)
+		ctx.logger.Debug("DC has recently failed, skipping to fallback")
+		p.eventStream.Send(ctx, NewEventTelegramDCSkipped(ctx.streamID, dc, fallbackDC))
+
+		dc = fallbackDC
+	}
+
+	conn, err := p.telegramDialer.Dial(ctx, dc)
 	if err != nil {
 		// Fallback to another DC on dial error
 		if p.fallbackOnDialError {
-			fallbackDC := p.telegram.GetFallbackDCExcluding(dc)
+			failedDCs = append(failedDCs, dc)
+			fallbackDC := p.telegramDialer.GetFallbackDCExcluding(failedDCs...)
 			ctx.logger = ctx.logger.BindInt("original_dc", originalDC).BindInt("fallback_dc", fallbackDC)
 			ctx.logger.Warning("DC unavailable, trying fallback")
 
-			conn, err = p.telegram.Dial(ctx, fallbackDC)
+			conn, err = p.telegramDialer.Dial(ctx, fallbackDC)
 			if err != nil {
 				return fmt.Errorf("cannot dial to Telegram (fallback DC %d also failed): %w", fallbackDC, err)
 			}
@@ -336,6 +1054,8 @@ func (p *Proxy) doTelegramCall(ctx *streamContext) error {
 
 	encryptor, decryptor, err := obfuscated2.ServerHandshake(conn)
 	if err != nil {
+		p.reportTelegramHandshakeFailure(ctx, dc, err)
+
 		// ForceClose: соединение с ошибкой handshake нельзя возвращать в пул
 		if pc, ok := conn.(*telegram.PooledConn); ok {
 			pc.ForceClose()
@@ -348,13 +1068,18 @@ func (p *Proxy) doTelegramCall(ctx *streamContext) error {
 			ctx.logger.Debug("broken pipe on handshake, retrying with fresh connection")
 
 			// Получаем новое соединение напрямую (минуя pool)
-			conn, err = p.telegram.DialDirect(ctx, dc)
+			conn, err = p.telegramDialer.DialDirect(ctx, dc)
 			if err != nil {
+				p.eventStream.Send(ctx, NewEventTelegramHandshakeRetried(ctx.streamID, dc, true))
+
 				return fmt.Errorf("cannot dial to Telegram (retry): %w", err)
 			}
 
 			encryptor, decryptor, err = obfuscated2.ServerHandshake(conn)
+			p.eventStream.Send(ctx, NewEventTelegramHandshakeRetried(ctx.streamID, dc, err != nil))
+
 			if err != nil {
+				p.reportTelegramHandshakeFailure(ctx, dc, err)
 				conn.Close()
 				return fmt.Errorf("cannot perform obfuscated2 handshake (retry): %w", err)
 			}
@@ -371,45 +1096,108 @@ func (p *Proxy) doTelegramCall(ctx *streamContext) error {
 	}
 
 	ctx.telegramConn = obfuscated2.Conn{
-		Conn: newConnTraffic(conn, ctx.streamID, p.eventStream, ctx),
+		Conn:      newConnTraffic(conn, ctx.streamID, p.eventStream, ctx),
 		Encryptor: encryptor,
 		Decryptor: decryptor,
 	}
+	ctx.telegramDC = dc
+
+	// TelegramDialer может отдать не TCP соединение: тогда IP неизвестен.
+	var remoteIP net.IP
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		remoteIP = addr.IP
+	}
 
 	p.eventStream.Send(ctx,
-		NewEventConnectedToDC(ctx.streamID,
-			conn.RemoteAddr().(*net.TCPAddr).IP, //nolint: forcetypeassert
-			dc),
-	)
+		NewEventConnectedToDCWithTFO(ctx.streamID, remoteIP, dc, connUsedTFO(conn)))
 
 	return nil
 }
 
+// reportTelegramHandshakeFailure отправляет событие с причиной ошибки
+// obfuscated2 handshake. Исчерпание попыток генерации фрейма означает
+// сломанный RNG, поэтому логируется как warning.
+func (p *Proxy) reportTelegramHandshakeFailure(ctx *streamContext, dc int, err error) {
+	reason := handshakeFailureReason(err)
+
+	if reason == HandshakeFailureExhausted {
+		ctx.logger.WarningError("cannot generate obfuscated2 handshake frame, RNG is probably broken", err)
+	}
+
+	p.eventStream.Send(ctx, NewEventTelegramHandshakeFailed(ctx.streamID, dc, reason))
+}
+
 func (p *Proxy) doDomainFronting(ctx *streamContext, conn *connRewind) {
-	p.eventStream.Send(p.ctx, NewEventDomainFronting(ctx.streamID))
+	// Fronting — бесплатный relay к чужому домену: без лимита им можно
+	// выкачать полосу и занять всех воркеров.
+	active := p.domainFrontingActive.Add(1)
+	defer p.domainFrontingActive.Add(-1)
+
+	if p.domainFrontingMax > 0 && active > p.domainFrontingMax {
+		ctx.logger.Debug("too many domain fronted connections, close immediately")
+		p.eventStream.Send(p.ctx, NewEventDomainFrontingLimited(ctx.streamID, ctx.ClientIP()))
+
+		return
+	}
+
+	// Fronting может длиться сколько угодно: воркер ему не нужен, лимит
+	// держит domainFrontingMax.
+	ctx.detachFromWorker()
+
+	// SNI пишется в лог только хэшем: сырое значение есть в событии, и
+	// observer сам решает, как его показывать.
+	ctx.logger.BindStr("sni", hashSNI(ctx.clientSNI)).Debug("domain fronting")
+	p.eventStream.Send(p.ctx, NewEventDomainFrontingWithSNI(
+		ctx.streamID, ctx.clientSNI, ctx.clientSNI != "" && ctx.clientSNI == p.secret.Host))
 	conn.Rewind()
 
+	// Fronting домен — наша легенда: его деградация должна быть видна
+	// в метриках раньше, чем её заметят пользователи.
+	started := time.Now()
 	frontConn, err := p.network.DialContext(ctx, "tcp", p.DomainFrontingAddress())
+
+	p.eventStream.Send(p.ctx, NewEventDomainFrontingDial(ctx.streamID, time.Since(started), err != nil))
+
 	if err != nil {
-		p.logger.WarningError("cannot dial to the fronting domain", err)
+		ctx.logger.WarningError("cannot dial to the fronting domain", err)
 
 		return
 	}
 
 	frontConn = newConnTraffic(frontConn, ctx.streamID, p.eventStream, ctx)
 
-	relay.Relay(
+	relay.RelayWithOptions(
 		ctx,
 		ctx.logger.Named("domain-fronting"),
 		frontConn,
 		conn,
+		p.relayOptions(),
 	)
 }
 
-// NewProxy makes a new proxy instance.
-func NewProxy(opts ProxyOpts) (*Proxy, error) {
-	if err := opts.valid(); err != nil {
-		return nil, fmt.Errorf("invalid settings: %w", err)
+// makeDCRefreshFailureCallback сообщает о том, что управляемые
+// DC-адреса не применяются и прокси работает на hardcoded.
+func makeDCRefreshFailureCallback(logger Logger, eventStream EventStream) telegram.DCRefreshFailureCallback {
+	logger = logger.Named("dc-config")
+
+	return func(failures int, err error) {
+		if failures == 0 {
+			logger.Info("DC config is loaded again")
+		} else {
+			logger.BindInt("failures", failures).WarningError(
+				"cannot load DC config, hardcoded DC addresses are in use", err)
+		}
+
+		eventStream.Send(context.Background(), NewEventDCConfigStale(failures))
+	}
+}
+
+// makeTelegram создаёт встроенный dialer к Telegram. С внешним
+// ProxyOpts.TelegramDialer он не нужен: DC refresh, пул и проверки
+// работали бы с сетью, в которую прокси не ходит.
+func makeTelegram(opts ProxyOpts, config ProxyConfig) (*telegram.Telegram, error) {
+	if opts.TelegramDialer != nil {
+		return nil, nil
 	}
 
 	// Подготовка опций для telegram dialer
@@ -419,21 +1207,56 @@ func NewProxy(opts ProxyOpts) (*Proxy, error) {
 			MaxIdleConns:        opts.getConnectionPoolMaxIdle(),
 			IdleTimeout:         opts.getConnectionPoolIdleTimeout(),
 			HealthCheckInterval: 30 * time.Second,
+			MaxDialAddresses:    config.MaxDialAddresses,
+			IdleDrainAfter:      opts.ConnectionPoolIdleDrainAfter,
 		}
 		tgOpts = append(tgOpts, telegram.WithConnectionPool(poolConfig))
 	}
 
-	// DC auto-refresh из JSON файла
-	if opts.DCConfigFile != "" {
+	// DC auto-refresh из JSON файла или по HTTP
+	switch {
+	case opts.DCConfigURL != "":
+		tgOpts = append(tgOpts, telegram.WithDCConfigURL(
+			opts.DCConfigURL,
+			opts.DCRefreshInterval,
+		))
+	case opts.DCConfigFile != "":
 		tgOpts = append(tgOpts, telegram.WithDCConfigFile(
 			opts.DCConfigFile,
 			opts.DCRefreshInterval,
 		))
 	}
 
-	tg, err := telegram.New(opts.Network, opts.getPreferIP(), opts.UseTestDCs, tgOpts...)
+	// Память о недоступных DC нужна только для fallback
+	if opts.getFallbackOnDialError() {
+		tgOpts = append(tgOpts, telegram.WithDCFailureTTL(config.DCFailureTTL))
+	}
+
+	tgOpts = append(tgOpts, telegram.WithMaxDialAddresses(config.MaxDialAddresses))
+	tgOpts = append(tgOpts, telegram.WithDCRefreshFailureCallback(
+		makeDCRefreshFailureCallback(opts.Logger, opts.EventStream)))
+
+	return telegram.New(opts.getTelegramNetwork(), opts.getPreferIP(), opts.UseTestDCs, tgOpts...) //nolint: wrapcheck
+}
+
+// NewProxy makes a new proxy instance.
+func NewProxy(opts ProxyOpts) (*Proxy, error) {
+	if err := opts.valid(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProxyOptsInvalid, err)
+	}
+
+	// Get config or use defaults
+	config := opts.getConfig()
+
+	tg, err := makeTelegram(opts, config)
 	if err != nil {
-		return nil, fmt.Errorf("cannot build telegram dialer: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrTelegramDialerFailed, err)
+	}
+
+	// nil *telegram.Telegram в интерфейсе не был бы nil.
+	telegramDialer := opts.TelegramDialer
+	if tg != nil {
+		telegramDialer = tg
 	}
 
 	// DNS pre-warming: resolve FakeTLS domain before accepting connections.
@@ -444,9 +1267,6 @@ func NewProxy(opts ProxyOpts) (*Proxy, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Get config or use defaults
-	config := opts.getConfig()
-
 	// Create rate limiter if enabled
 	var rateLimiter *RateLimiter
 	if opts.getRateLimitPerSecond() > 0 {
@@ -457,36 +1277,86 @@ func NewProxy(opts ProxyOpts) (*Proxy, error) {
 		)
 	}
 
+	var skewRateLimiter *RateLimiter
+	if opts.getSkewRateLimitPerSecond() > 0 {
+		skewRateLimiter = NewRateLimiter(
+			opts.getSkewRateLimitPerSecond(),
+			opts.getSkewRateLimitBurst(),
+			time.Minute,
+		)
+	}
+
 	proxy := &Proxy{
 		ctx:                      ctx,
 		ctxCancel:                cancel,
 		secret:                   opts.Secret,
 		network:                  opts.Network,
-		antiReplayCache:          opts.AntiReplayCache,
+		antiReplayKey:            opts.getAntiReplayKey(),
+		antiReplayDigest:         opts.AntiReplayDigestFunc,
 		blocklist:                opts.IPBlocklist,
 		allowlist:                opts.IPAllowlist,
 		eventStream:              opts.EventStream,
 		logger:                   opts.getLogger("proxy"),
 		domainFrontingPort:       opts.getDomainFrontingPort(),
-		tolerateTimeSkewness:     opts.getTolerateTimeSkewness(),
+		domainFrontingMax:        int64(opts.DomainFrontingMaxConnections),
+		telegramHealthThreshold:  int64(opts.getTelegramHealthFailureThreshold()),
+		fakeTLSMaxRecordSize:     opts.getFakeTLSMaxRecordSize(),
+		fakeTLSSmallFirstRecords: opts.getFakeTLSSmallFirstRecords(),
+		tolerateTimeSkewness:     newTimeSkewness(opts.getTolerateTimeSkewness(), opts.TolerateTimeSkewnessOverrides),
 		allowFallbackOnUnknownDC: opts.AllowFallbackOnUnknownDC,
+		unknownDCMapping:         opts.UnknownDCMapping,
+		acceptBackpressure:       int64(opts.AcceptBackpressureThreshold),
+		maxRelays:                int64(opts.MaxRelays),
 		fallbackOnDialError:      opts.getFallbackOnDialError(),
+		rejectScanners:           opts.RejectScanners,
+		tarpitDuration:           opts.TarpitDuration,
 		telegram:                 tg,
+		telegramDialer:           telegramDialer,
 		config:                   config,
 		rateLimiter:              rateLimiter,
+		skewRateLimiter:          skewRateLimiter,
+		inBandMetrics:            opts.InBandMetrics,
+	}
+
+	proxy.ReplaceAntiReplayCache(opts.AntiReplayCache)
+
+	if config.SilentRejectWindow > 0 {
+		proxy.silentRejects = newSilentRejects(config.SilentRejectWindow)
 	}
 
 	pool, err := ants.NewPoolWithFunc(opts.getConcurrency(),
 		func(arg interface{}) {
-			proxy.ServeConn(arg.(essentials.Conn)) //nolint: forcetypeassert
+			capacity := proxy.workerPool.Cap()
+
+			proxy.checkWorkerPoolPressure(int(proxy.workerPoolBusy.Add(1)), capacity)
+			proxy.serveWorker(arg.(listenerConn)) //nolint: forcetypeassert
+			proxy.checkWorkerPoolPressure(int(proxy.workerPoolBusy.Add(-1)), capacity)
 		},
 		ants.WithLogger(opts.getLogger("ants")),
 		ants.WithNonblocking(true))
 	if err != nil {
-		return nil, fmt.Errorf("cannot create worker pool: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrWorkerPoolFailed, err)
 	}
 
 	proxy.workerPool = pool
 
+	if tg == nil {
+		return proxy, nil
+	}
+
+	if opts.ProbeDCsOnStartup {
+		go proxy.probeDCs(telegram.NewDCHealthChecker(tg, config.TelegramDialTimeout))
+	}
+
+	if opts.TelegramHealthCheckInterval > 0 {
+		go proxy.checkTelegramHealth(
+			telegram.NewDCHealthChecker(tg, config.TelegramDialTimeout),
+			opts.TelegramHealthCheckInterval)
+	}
+
+	if opts.WarmUpTFOOnStartup {
+		go proxy.warmUpTFO(config.TelegramDialTimeout)
+	}
+
 	return proxy, nil
 }
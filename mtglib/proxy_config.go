@@ -1,6 +1,9 @@
 package mtglib
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // ProxyConfig contains configurable parameters for Proxy.
 type ProxyConfig struct {
@@ -8,9 +11,151 @@ type ProxyConfig struct {
 	// Default: 30 seconds
 	HandshakeTimeout time.Duration
 
+	// ClientHelloTimeout is how long a client may take to send a full TLS
+	// record with ClientHello. A client which drips it byte by byte would
+	// otherwise hold a worker for the whole HandshakeTimeout.
+	//
+	// Such clients are closed instead of being routed to the fronting
+	// domain, so an active prober can tell a proxy from a real web server
+	// by this timeout. Please enable it only if slow clients are a real
+	// problem. ClientHello fits into a couple of TCP segments, so 10
+	// seconds is a plenty even for slow mobile networks.
+	//
+	// Default: 0 (disabled).
+	ClientHelloTimeout time.Duration
+
 	// TelegramDialTimeout is the timeout for dialing to Telegram servers.
 	// Default: 10 seconds
 	TelegramDialTimeout time.Duration
+
+	// TCPUserTimeout is a value of TCP_USER_TIMEOUT for relayed
+	// connections: how long transmitted data may stay unacknowledged before
+	// the kernel drops a connection. This is how dead peers are detected
+	// during relay; without it they hang until TCP retransmission gives up
+	// (~15 minutes). High-RTT links (satellite) may need a larger value.
+	//
+	// Must be within [MinTCPUserTimeout, MaxTCPUserTimeout]. Zero means
+	// DefaultTCPUserTimeout. Linux only.
+	TCPUserTimeout time.Duration
+
+	// ClientTCPUserTimeout overrides TCPUserTimeout for a connection to a
+	// client. Mobile clients may stall legitimately for a long time (e.g.
+	// entering a tunnel), so it is reasonable to be more patient here.
+	//
+	// Please remember that sockets also have TCP keepalive enabled. When
+	// TCP_USER_TIMEOUT is set, Linux uses it to bound keepalive probing as
+	// well: an idle connection with unanswered probes is dropped after this
+	// timeout rather than after a number of probes.
+	//
+	// Must be within [MinTCPUserTimeout, MaxTCPUserTimeout]. Zero means
+	// TCPUserTimeout. Linux only.
+	ClientTCPUserTimeout time.Duration
+
+	// TelegramTCPUserTimeout overrides TCPUserTimeout for a connection to
+	// Telegram. Telegram DCs are well connected, so a smaller value helps
+	// to detect dead media downloads faster. The same keepalive
+	// interaction as for ClientTCPUserTimeout applies.
+	//
+	// Must be within [MinTCPUserTimeout, MaxTCPUserTimeout]. Zero means
+	// TCPUserTimeout. Linux only.
+	TelegramTCPUserTimeout time.Duration
+
+	// TelegramWindowClamp is a value of TCP_WINDOW_CLAMP for connections
+	// to Telegram. It bounds the receive window, so Telegram cannot send
+	// much more than proxy manages to relay to a client (buffer bloat).
+	// Paths with a high bandwidth-delay product may need a larger value.
+	//
+	// Must be within [MinTelegramWindowClamp, MaxTelegramWindowClamp].
+	// Zero means DefaultTelegramWindowClamp. Linux only.
+	TelegramWindowClamp int
+
+	// RelayBufferSize is a size of a user-space buffer which relay uses
+	// to copy data between a client and Telegram. Each connection holds
+	// 2 such buffers (one per direction) for its whole lifetime, so
+	// memory is roughly 2 * RelayBufferSize * connections. Larger buffers
+	// mean less syscalls and better throughput on fast links, smaller ones
+	// save memory on nodes with many idle connections.
+	//
+	// Must be within [MinRelayBufferSize, MaxRelayBufferSize]. Zero means
+	// DefaultRelayBufferSize. This is not a deprecated
+	// ProxyOpts.BufferSize.
+	RelayBufferSize int
+
+	// RelayIOTimeout is a maximal time relay may pass no data in both
+	// directions, and a maximal time a single write may block. A quiet
+	// direction is fine as long as the other one is active: during a
+	// long download a client usually sends nothing. A connection is
+	// aborted even if TCP is kept alive with ACKs. It complements
+	// TCPUserTimeout, which catches only unacknowledged writes.
+	//
+	// Must be at least MinRelayIOTimeout. Zero disables it.
+	RelayIOTimeout time.Duration
+
+	// TCPQuickACKInterval defines how relay sets TCP_QUICKACK on relayed
+	// sockets. The kernel leaves quick ACK mode by itself, so relay may
+	// re-arm it every TCPQuickACKInterval reads of each direction. Each
+	// time this is a setsockopt syscall: on small nodes with many
+	// connections this is noticeable, while latency gain on low-RTT paths
+	// is marginal.
+	//
+	// Zero means to set it only once when relay starts.
+	// TCPQuickACKDisabled means never to set it.
+	TCPQuickACKInterval int
+
+	// CPUAffinity is a set of CPU cores to which accept loops are pinned
+	// with sched_setaffinity. This is the only thing Proxy pins. Event
+	// processors can be pinned to the same cores with
+	// events.NewEventStreamWithAffinity.
+	//
+	// Pinning locks a goroutine to its OS thread, so only a few
+	// long-lived goroutines are pinned. Relays, domain fronting and
+	// connection handshakes are not: each of them would hold an OS thread
+	// for a lifetime of a connection, and thousands of clients would
+	// exhaust the runtime thread limit.
+	//
+	// A process has to be allowed to run on these cores (cpuset of a
+	// cgroup or a container, taskset), otherwise pinning fails and is
+	// logged. Empty means no pinning. Linux only.
+	CPUAffinity []int
+
+	// SilentRejectWindow is a window in which repeated rejections of the
+	// same IP by allowlist or blocklist are silent: a connection is closed
+	// without a log record and an event. Mass scanners hit a proxy from
+	// the same addresses, so this cuts per-connection overhead and keeps
+	// metrics and logs readable. Each IP is still reported once per window.
+	//
+	// Zero disables it.
+	SilentRejectWindow time.Duration
+
+	// DrainTimeout is a maximal time Shutdown waits for connections of
+	// a draining proxy to finish before it terminates them. Please see
+	// Proxy.Drain.
+	//
+	// Default: DefaultDrainTimeout. Zero means do not wait.
+	DrainTimeout time.Duration
+
+	// DCFailureTTL is how long a DC is considered unavailable after a
+	// failed dial. During this time clients which request it are sent to
+	// a fallback DC straight away instead of paying a dial timeout on each
+	// reconnect. After that, the next client probes the DC again. This
+	// works only with ProxyOpts.FallbackOnDialError.
+	//
+	// Default: DefaultDCFailureTTL. Zero disables it.
+	DCFailureTTL time.Duration
+
+	// MaxDialAddresses is a maximal number of addresses of a DC which are
+	// tried on a single dial. DC config files may list many addresses per
+	// DC: if such DC is down, a dial takes a timeout for each of them
+	// before FallbackOnDialError has a chance to kick in. Please
+	// remember that addresses are dialed concurrently with a small
+	// stagger, so the worst case is about this number of staggers plus
+	// TelegramDialTimeout.
+	//
+	// Each dial takes the next window of addresses, so a dead address or
+	// a broken IP family does not hide the rest of them.
+	//
+	// Zero means all addresses.
+	MaxDialAddresses int
 }
 
 // DefaultProxyConfig returns default configuration for Proxy.
@@ -18,5 +163,79 @@ func DefaultProxyConfig() ProxyConfig {
 	return ProxyConfig{
 		HandshakeTimeout:    30 * time.Second,
 		TelegramDialTimeout: 10 * time.Second,
+		TCPUserTimeout:      DefaultTCPUserTimeout,
+		TelegramWindowClamp: DefaultTelegramWindowClamp,
+		RelayBufferSize:     DefaultRelayBufferSize,
+		DrainTimeout:        DefaultDrainTimeout,
+		DCFailureTTL:        DefaultDCFailureTTL,
+	}
+}
+
+func (c ProxyConfig) valid() error {
+	userTimeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"tcp user timeout", c.TCPUserTimeout},
+		{"client tcp user timeout", c.ClientTCPUserTimeout},
+		{"telegram tcp user timeout", c.TelegramTCPUserTimeout},
+	}
+
+	for _, timeout := range userTimeouts {
+		if timeout.value != 0 &&
+			(timeout.value < MinTCPUserTimeout || timeout.value > MaxTCPUserTimeout) {
+			return fmt.Errorf("%s %v is out of range [%v, %v]",
+				timeout.name, timeout.value, MinTCPUserTimeout, MaxTCPUserTimeout)
+		}
+	}
+
+	if c.TelegramWindowClamp != 0 &&
+		(c.TelegramWindowClamp < MinTelegramWindowClamp || c.TelegramWindowClamp > MaxTelegramWindowClamp) {
+		return fmt.Errorf("telegram window clamp %d is out of range [%d, %d]",
+			c.TelegramWindowClamp, MinTelegramWindowClamp, MaxTelegramWindowClamp)
 	}
+
+	if c.RelayBufferSize != 0 &&
+		(c.RelayBufferSize < MinRelayBufferSize || c.RelayBufferSize > MaxRelayBufferSize) {
+		return fmt.Errorf("relay buffer size %d is out of range [%d, %d]",
+			c.RelayBufferSize, MinRelayBufferSize, MaxRelayBufferSize)
+	}
+
+	if c.RelayIOTimeout < 0 || (c.RelayIOTimeout > 0 && c.RelayIOTimeout < MinRelayIOTimeout) {
+		return fmt.Errorf("relay io timeout %v must be 0 or at least %v",
+			c.RelayIOTimeout, MinRelayIOTimeout)
+	}
+
+	if c.TCPQuickACKInterval < TCPQuickACKDisabled {
+		return fmt.Errorf("tcp quickack interval %d must be %d (disabled) or not negative",
+			c.TCPQuickACKInterval, TCPQuickACKDisabled)
+	}
+
+	for _, cpu := range c.CPUAffinity {
+		if cpu < 0 {
+			return fmt.Errorf("cpu affinity has incorrect cpu %d", cpu)
+		}
+	}
+
+	if c.ClientHelloTimeout < 0 {
+		return fmt.Errorf("client hello timeout %v must not be negative", c.ClientHelloTimeout)
+	}
+
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("drain timeout %v must not be negative", c.DrainTimeout)
+	}
+
+	if c.DCFailureTTL < 0 {
+		return fmt.Errorf("dc failure ttl %v must not be negative", c.DCFailureTTL)
+	}
+
+	if c.MaxDialAddresses < 0 {
+		return fmt.Errorf("max dial addresses %d must not be negative", c.MaxDialAddresses)
+	}
+
+	if c.SilentRejectWindow < 0 {
+		return fmt.Errorf("silent reject window %v must not be negative", c.SilentRejectWindow)
+	}
+
+	return nil
 }
@@ -0,0 +1,134 @@
+package mtglib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyConfigValid(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]struct {
+		modify func(*ProxyConfig)
+		valid  bool
+	}{
+		"default": {
+			modify: func(*ProxyConfig) {},
+			valid:  true,
+		},
+		"zero values": {
+			modify: func(c *ProxyConfig) {
+				c.TCPUserTimeout = 0
+				c.TelegramWindowClamp = 0
+				c.RelayBufferSize = 0
+			},
+			valid: true,
+		},
+		"user timeout too small": {
+			modify: func(c *ProxyConfig) { c.TCPUserTimeout = time.Millisecond },
+		},
+		"user timeout too large": {
+			modify: func(c *ProxyConfig) { c.TCPUserTimeout = time.Hour },
+		},
+		"per-direction user timeouts": {
+			modify: func(c *ProxyConfig) {
+				c.ClientTCPUserTimeout = 2 * time.Minute
+				c.TelegramTCPUserTimeout = 10 * time.Second
+			},
+			valid: true,
+		},
+		"client user timeout too small": {
+			modify: func(c *ProxyConfig) { c.ClientTCPUserTimeout = time.Millisecond },
+		},
+		"telegram user timeout too large": {
+			modify: func(c *ProxyConfig) { c.TelegramTCPUserTimeout = time.Hour },
+		},
+		"window clamp too small": {
+			modify: func(c *ProxyConfig) { c.TelegramWindowClamp = 1024 },
+		},
+		"window clamp too large": {
+			modify: func(c *ProxyConfig) { c.TelegramWindowClamp = MaxTelegramWindowClamp + 1 },
+		},
+		"relay buffer too small": {
+			modify: func(c *ProxyConfig) { c.RelayBufferSize = 1024 },
+		},
+		"relay buffer too large": {
+			modify: func(c *ProxyConfig) { c.RelayBufferSize = MaxRelayBufferSize + 1 },
+		},
+		"relay io timeout": {
+			modify: func(c *ProxyConfig) { c.RelayIOTimeout = 5 * time.Minute },
+			valid:  true,
+		},
+		"relay io timeout too small": {
+			modify: func(c *ProxyConfig) { c.RelayIOTimeout = time.Millisecond },
+		},
+		"relay io timeout negative": {
+			modify: func(c *ProxyConfig) { c.RelayIOTimeout = -time.Second },
+		},
+		"tcp quickack refresh": {
+			modify: func(c *ProxyConfig) { c.TCPQuickACKInterval = 8 },
+			valid:  true,
+		},
+		"tcp quickack disabled": {
+			modify: func(c *ProxyConfig) { c.TCPQuickACKInterval = TCPQuickACKDisabled },
+			valid:  true,
+		},
+		"tcp quickack invalid": {
+			modify: func(c *ProxyConfig) { c.TCPQuickACKInterval = -2 },
+		},
+		"cpu affinity": {
+			modify: func(c *ProxyConfig) { c.CPUAffinity = []int{0, 2} },
+			valid:  true,
+		},
+		"cpu affinity negative": {
+			modify: func(c *ProxyConfig) { c.CPUAffinity = []int{-1} },
+		},
+		"client hello timeout": {
+			modify: func(c *ProxyConfig) { c.ClientHelloTimeout = 10 * time.Second },
+			valid:  true,
+		},
+		"client hello timeout negative": {
+			modify: func(c *ProxyConfig) { c.ClientHelloTimeout = -time.Second },
+		},
+		"drain timeout disabled": {
+			modify: func(c *ProxyConfig) { c.DrainTimeout = 0 },
+			valid:  true,
+		},
+		"drain timeout negative": {
+			modify: func(c *ProxyConfig) { c.DrainTimeout = -time.Second },
+		},
+		"silent reject window": {
+			modify: func(c *ProxyConfig) { c.SilentRejectWindow = time.Minute },
+			valid:  true,
+		},
+		"silent reject window negative": {
+			modify: func(c *ProxyConfig) { c.SilentRejectWindow = -time.Minute },
+		},
+		"max dial addresses": {
+			modify: func(c *ProxyConfig) { c.MaxDialAddresses = 2 },
+			valid:  true,
+		},
+		"max dial addresses negative": {
+			modify: func(c *ProxyConfig) { c.MaxDialAddresses = -1 },
+		},
+	}
+
+	for name, value := range testData {
+		value := value
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conf := DefaultProxyConfig()
+			value.modify(&conf)
+
+			if value.valid {
+				assert.NoError(t, conf.valid())
+			} else {
+				assert.Error(t, conf.valid())
+			}
+		})
+	}
+}
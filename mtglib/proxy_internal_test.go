@@ -0,0 +1,907 @@
+package mtglib
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/9seconds/mtg/v2/mtglib/internal/faketls/record"
+	"github.com/9seconds/mtg/v2/mtglib/internal/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadHandshakeHeaderTestSuite struct {
+	suite.Suite
+
+	connMock *ConnRewindBaseConn
+	conn     *connRewind
+	header   [record.HeaderSize]byte
+}
+
+func (suite *ReadHandshakeHeaderTestSuite) SetupTest() {
+	suite.connMock = &ConnRewindBaseConn{}
+	suite.connMock.On("Read", mock.Anything)
+	suite.conn = newConnRewind(suite.connMock)
+	suite.header = [record.HeaderSize]byte{}
+}
+
+func (suite *ReadHandshakeHeaderTestSuite) TestClientHello() {
+	suite.connMock.readBuffer.Write([]byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01})
+
+	reason, err := readHandshakeHeader(suite.conn, &suite.header)
+	suite.NoError(err)
+	suite.Empty(reason)
+	suite.Equal([record.HeaderSize]byte{0x16, 0x03, 0x01, 0x02, 0x00}, suite.header)
+}
+
+func (suite *ReadHandshakeHeaderTestSuite) TestNotTLS() {
+	data := []byte("GET / HTTP/1.1\r\n\r\n")
+	suite.connMock.readBuffer.Write(data)
+
+	reason, err := readHandshakeHeader(suite.conn, &suite.header)
+	suite.Error(err)
+	suite.Equal(ScannerReasonNotTLS, reason)
+
+	// Прочитанные байты должны вернуться при domain fronting.
+	suite.conn.Rewind()
+
+	rest, err := io.ReadAll(suite.conn)
+	suite.NoError(err)
+	suite.Equal(data, rest)
+}
+
+func (suite *ReadHandshakeHeaderTestSuite) TestTruncated() {
+	suite.connMock.readBuffer.Write([]byte{0x16, 0x03})
+
+	reason, err := readHandshakeHeader(suite.conn, &suite.header)
+	suite.ErrorIs(err, io.ErrUnexpectedEOF)
+	suite.Equal(ScannerReasonTruncated, reason)
+}
+
+func (suite *ReadHandshakeHeaderTestSuite) TestEmpty() {
+	reason, err := readHandshakeHeader(suite.conn, &suite.header)
+	suite.ErrorIs(err, io.EOF)
+	suite.Equal(ScannerReasonTruncated, reason)
+}
+
+func TestReadHandshakeHeader(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ReadHandshakeHeaderTestSuite{})
+}
+
+func TestServeContextCancel(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	proxy := &Proxy{ctx: context.Background()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proxy.ServeContext(ctx, listener)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeContext has not returned after context cancellation")
+	}
+
+	_, err = listener.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestServeContextListenerError(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+
+	proxy := &Proxy{ctx: context.Background()}
+
+	assert.Error(t, proxy.ServeContext(context.Background(), listener))
+}
+
+// frontDomainFronting прогоняет clientData через doFakeTLSHandshake и
+// возвращает байты, которые получил fronting домен.
+func frontDomainFronting(t *testing.T, clientData []byte) []byte {
+	t.Helper()
+
+	frontListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer frontListener.Close()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer proxyListener.Close()
+
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := frontListener.Accept()
+		if err != nil {
+			received <- nil
+
+			return
+		}
+
+		defer conn.Close()
+
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	frontConn, err := net.Dial("tcp", frontListener.Addr().String())
+	require.NoError(t, err)
+
+	clientConn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+
+	defer clientConn.Close()
+
+	serverConn, err := proxyListener.Accept()
+	require.NoError(t, err)
+
+	networkMock := &testlib.MtglibNetworkMock{}
+	networkMock.
+		On("DialContext", mock.Anything, "tcp", "example.com:443").
+		Return(essentials.Conn(frontConn.(*net.TCPConn)), nil)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:                context.Background(),
+		secret:             Secret{Host: "example.com"},
+		domainFrontingPort: 443,
+		config:             DefaultProxyConfig(),
+		network:            networkMock,
+		eventStream:        eventStream,
+		logger:             NoopLogger{},
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	defer streamCtx.Close()
+
+	done := make(chan bool, 1)
+
+	go func() {
+		done <- proxy.doFakeTLSHandshake(streamCtx)
+	}()
+
+	_, err = clientConn.Write(clientData)
+	require.NoError(t, err)
+	require.NoError(t, clientConn.(*net.TCPConn).CloseWrite())
+
+	select {
+	case data := <-received:
+		assert.False(t, <-done)
+
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("fronting domain has not received client data")
+	}
+
+	return nil
+}
+
+func TestDomainFrontingReplaysClientBytes(t *testing.T) {
+	t.Parallel()
+
+	// Валидный заголовок, мусор вместо ClientHello и второй flight
+	// клиента: всё должно дойти до fronting домена без изменений.
+	invalidHello := append([]byte{0x16, 0x03, 0x01, 0x00, 0x20}, bytes.Repeat([]byte{0xAA}, 0x20)...)
+	invalidHello = append(invalidHello, bytes.Repeat([]byte{0x17, 0x03, 0x03, 0x00, 0x01, 0xBB}, 3)...)
+
+	testData := map[string][]byte{
+		"invalid client hello": invalidHello,
+		"not tls":              []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		"truncated record":     {0x16, 0x03, 0x01, 0x10, 0x00, 0x01, 0x02, 0x03},
+		"truncated header":     {0x16, 0x03},
+	}
+
+	for name, value := range testData {
+		clientData := value
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, clientData, frontDomainFronting(t, clientData))
+		})
+	}
+}
+
+func TestClientHelloTimeout(t *testing.T) {
+	t.Parallel()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer proxyListener.Close()
+
+	clientConn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+
+	defer clientConn.Close()
+
+	serverConn, err := proxyListener.Accept()
+	require.NoError(t, err)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	config := DefaultProxyConfig()
+	config.ClientHelloTimeout = 300 * time.Millisecond
+
+	proxy := &Proxy{
+		ctx:         context.Background(),
+		secret:      Secret{Host: "example.com"},
+		config:      config,
+		network:     &testlib.MtglibNetworkMock{},
+		eventStream: eventStream,
+		logger:      NoopLogger{},
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	defer streamCtx.Close()
+
+	// Клиент присылает валидный заголовок и дальше капает по байту:
+	// каждый Read успешен, но record не приходит целиком.
+	go func() {
+		clientData := append([]byte{0x16, 0x03, 0x01, 0x02, 0x00}, bytes.Repeat([]byte{0x01}, 0x200)...)
+
+		for _, b := range clientData {
+			if _, err := clientConn.Write([]byte{b}); err != nil {
+				return
+			}
+
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	started := time.Now()
+
+	assert.False(t, proxy.doFakeTLSHandshake(streamCtx))
+	assert.Less(t, time.Since(started), 2*time.Second)
+
+	detected := []EventScannerDetected{}
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventScannerDetected); ok {
+			detected = append(detected, evt)
+		}
+	}
+
+	require.Len(t, detected, 1)
+	assert.Equal(t, ScannerReasonSlow, detected[0].Reason)
+	assert.True(t, detected[0].Rejected)
+}
+
+func TestCheckWorkerPoolPressure(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:         context.Background(),
+		eventStream: eventStream,
+		logger:      NoopLogger{},
+	}
+
+	// Рост до порога, колебания между порогами и спад ниже нижнего.
+	for _, running := range []int{10, 50, 89, 90, 95, 85, 91, 100, 81, 80, 79, 85, 50, 90} {
+		proxy.checkWorkerPoolPressure(running, 100)
+	}
+
+	sent := []EventWorkerPoolPressure{}
+
+	for _, call := range eventStream.Calls {
+		sent = append(sent, call.Arguments.Get(1).(EventWorkerPoolPressure)) //nolint: forcetypeassert
+	}
+
+	require.Len(t, sent, 3)
+	assert.True(t, sent[0].High)
+	assert.Equal(t, 90, sent[0].Running)
+	assert.Equal(t, 100, sent[0].Capacity)
+	assert.False(t, sent[1].High)
+	assert.Equal(t, 79, sent[1].Running)
+	assert.True(t, sent[2].High)
+	assert.InDelta(t, 0.9, sent[2].Utilization(), 0.001)
+	assert.True(t, proxy.workerPoolPressure.Load())
+}
+
+func TestDomainFrontingDialFailure(t *testing.T) {
+	t.Parallel()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer proxyListener.Close()
+
+	clientConn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+
+	defer clientConn.Close()
+
+	serverConn, err := proxyListener.Accept()
+	require.NoError(t, err)
+
+	networkMock := &testlib.MtglibNetworkMock{}
+	networkMock.
+		On("DialContext", mock.Anything, "tcp", "example.com:443").
+		Return(essentials.Conn((*net.TCPConn)(nil)), io.ErrUnexpectedEOF)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:                context.Background(),
+		secret:             Secret{Host: "example.com"},
+		domainFrontingPort: 443,
+		config:             DefaultProxyConfig(),
+		network:            networkMock,
+		eventStream:        eventStream,
+		logger:             NoopLogger{},
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	defer streamCtx.Close()
+
+	// С MaxRelays fronting не держит воркер.
+	released := 0
+	streamCtx.releaseWorker = func() { released++ }
+
+	proxy.doDomainFronting(streamCtx, newConnRewind(streamCtx.clientConn))
+
+	assert.Equal(t, 1, released)
+
+	dials := []EventDomainFrontingDial{}
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventDomainFrontingDial); ok {
+			dials = append(dials, evt)
+		}
+	}
+
+	require.Len(t, dials, 1)
+	assert.True(t, dials[0].Failed)
+	assert.Equal(t, streamCtx.streamID, dials[0].StreamID())
+}
+
+func TestDomainFrontingLimited(t *testing.T) {
+	t.Parallel()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer proxyListener.Close()
+
+	clientConn, err := net.Dial("tcp", proxyListener.Addr().String())
+	require.NoError(t, err)
+
+	defer clientConn.Close()
+
+	serverConn, err := proxyListener.Accept()
+	require.NoError(t, err)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	// Network без ожиданий: dial к fronting домену уронит тест.
+	proxy := &Proxy{
+		ctx:                context.Background(),
+		secret:             Secret{Host: "example.com"},
+		domainFrontingPort: 443,
+		domainFrontingMax:  2,
+		config:             DefaultProxyConfig(),
+		network:            &testlib.MtglibNetworkMock{},
+		eventStream:        eventStream,
+		logger:             NoopLogger{},
+	}
+	proxy.domainFrontingActive.Store(2)
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	defer streamCtx.Close()
+
+	proxy.doDomainFronting(streamCtx, newConnRewind(streamCtx.clientConn))
+
+	limited := []EventDomainFrontingLimited{}
+
+	for _, call := range eventStream.Calls {
+		switch evt := call.Arguments.Get(1).(type) {
+		case EventDomainFrontingLimited:
+			limited = append(limited, evt)
+		case EventDomainFronting, EventDomainFrontingDial:
+			t.Fatalf("unexpected event %T", evt)
+		}
+	}
+
+	require.Len(t, limited, 1)
+	assert.Equal(t, streamCtx.streamID, limited[0].StreamID())
+	assert.Equal(t, "127.0.0.1", limited[0].RemoteIP.String())
+	assert.EqualValues(t, 2, proxy.domainFrontingActive.Load())
+}
+
+func TestNewProxyInvalidOpts(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProxy(ProxyOpts{})
+	assert.ErrorIs(t, err, ErrProxyOptsInvalid)
+	assert.ErrorIs(t, err, ErrNetworkIsNotDefined)
+	assert.NotErrorIs(t, err, ErrTelegramDialerFailed)
+
+	_, err = NewProxy(ProxyOpts{
+		Network:          &testlib.MtglibNetworkMock{},
+		AntiReplayCache:  mapAntiReplayCache{},
+		IPBlocklist:      noopIPBlocklist{},
+		IPAllowlist:      allowAllIPBlocklist{},
+		EventStream:      &EventStreamMock{},
+		Logger:           NoopLogger{},
+		Secret:           GenerateSecret("example.com"),
+		UnknownDCMapping: map[int]int{203: 7},
+	})
+	assert.ErrorIs(t, err, ErrProxyOptsInvalid)
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proxy := &Proxy{
+		ctx:         ctx,
+		ctxCancel:   cancel,
+		eventStream: eventStream,
+		logger:      NoopLogger{},
+	}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proxy.Serve(listener)
+	}()
+
+	// Serve должен успеть зарегистрировать listener.
+	require.Eventually(t, func() bool {
+		proxy.listenersMutex.Lock()
+		defer proxy.listenersMutex.Unlock()
+
+		return len(proxy.listeners) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	proxy.Drain()
+	proxy.Drain()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve has not returned after Drain")
+	}
+
+	assert.True(t, proxy.Draining())
+	assert.NoError(t, ctx.Err())
+	assert.NoError(t, proxy.Serve(listener))
+
+	drained := 0
+
+	for _, call := range eventStream.Calls {
+		if _, ok := call.Arguments.Get(1).(EventDraining); ok {
+			drained++
+		}
+	}
+
+	assert.Equal(t, 1, drained)
+}
+
+func TestWaitDrained(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{logger: NoopLogger{}}
+	proxy.streamWaitGroup.Add(1)
+
+	started := time.Now()
+
+	proxy.waitDrained(50 * time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		proxy.streamWaitGroup.Done()
+	}()
+
+	started = time.Now()
+
+	proxy.waitDrained(5 * time.Second)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestServeWorkerQueueWait(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := newTestProxy(t, eventStream, 1)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go proxy.Serve(listener) //nolint: errcheck
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")) //nolint: errcheck
+	io.Copy(io.Discard, conn)                    //nolint: errcheck
+	conn.Close()
+
+	listener.Close()
+	proxy.Shutdown()
+
+	waits := []EventWorkerQueueWait{}
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventWorkerQueueWait); ok {
+			waits = append(waits, evt)
+		}
+	}
+
+	require.Len(t, waits, 1)
+	assert.Positive(t, waits[0].Duration)
+	assert.Less(t, waits[0].Duration, 5*time.Second)
+}
+
+func TestSkewRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:             context.Background(),
+		eventStream:     eventStream,
+		logger:          NoopLogger{},
+		skewRateLimiter: NewRateLimiter(0.001, 2, time.Minute),
+	}
+
+	defer proxy.skewRateLimiter.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	accept := func() net.Conn {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	clientIP := net.ParseIP("127.0.0.1")
+
+	// Клиент с кривыми часами: пара отказов не мешает подключаться.
+	proxy.skewRateLimiter.Allow(clientIP)
+	assert.True(t, proxy.allowRate(accept().(essentials.Conn), nil))
+
+	proxy.skewRateLimiter.Allow(clientIP)
+	assert.False(t, proxy.allowRate(accept().(essentials.Conn), nil))
+	assert.False(t, proxy.skewRateLimiter.Exhausted(net.ParseIP("127.0.0.2")))
+
+	limited := 0
+
+	for _, call := range eventStream.Calls {
+		if _, ok := call.Arguments.Get(1).(EventRateLimited); ok {
+			limited++
+		}
+	}
+
+	assert.Equal(t, 1, limited)
+}
+
+// addressRecordingDialer запоминает адреса и не даёт подключиться.
+type addressRecordingDialer struct {
+	mutex     sync.Mutex
+	addresses []string
+}
+
+func (d *addressRecordingDialer) DialContext(_ context.Context, _, address string) (essentials.Conn, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.addresses = append(d.addresses, address)
+
+	return nil, io.ErrUnexpectedEOF
+}
+
+func (d *addressRecordingDialer) Dialed() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]string{}, d.addresses...)
+}
+
+func TestUnknownDCMapping(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]struct {
+		dc       int
+		targetDC int
+	}{
+		"mapped":   {203, 2},
+		"unmapped": {204, 0},
+		"known":    {4, 4},
+	}
+
+	for name, value := range testData {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dial := func(dc int) ([]string, error) {
+				dialer := &addressRecordingDialer{}
+				tg, err := telegram.New(dialer, "only-ipv4", false)
+				require.NoError(t, err)
+
+				proxy := &Proxy{
+					ctx:              context.Background(),
+					telegram:         tg,
+					telegramDialer:   tg,
+					unknownDCMapping: map[int]int{203: 2},
+					eventStream:      &EventStreamMock{},
+					logger:           NoopLogger{},
+				}
+
+				clientConn, serverConn := tcpPair(t)
+
+				defer clientConn.Close()
+
+				streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn)
+				require.NoError(t, err)
+
+				defer streamCtx.Close()
+
+				streamCtx.dc = dc
+				err = proxy.doTelegramCall(streamCtx)
+
+				return dialer.Dialed(), err
+			}
+
+			dialed, err := dial(value.dc)
+			require.Error(t, err)
+
+			if value.targetDC == 0 {
+				assert.Contains(t, err.Error(), "invalid DC")
+				assert.Empty(t, dialed)
+
+				return
+			}
+
+			expected, _ := dial(value.targetDC)
+
+			assert.NotEmpty(t, dialed)
+			assert.ElementsMatch(t, expected, dialed)
+		})
+	}
+}
+
+func TestAcceptBackpressure(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := newTestProxy(t, eventStream, 4)
+	proxy.acceptBackpressure = 1
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go proxy.Serve(listener) //nolint: errcheck
+
+	// Молчащий клиент держит воркера, пока ждёт client hello.
+	first, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return proxy.workerPoolBusy.Load() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	second, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	defer second.Close()
+
+	second.Write([]byte("GET / HTTP/1.1\r\n\r\n"))                 //nolint: errcheck
+	second.SetReadDeadline(time.Now().Add(300 * time.Millisecond)) //nolint: errcheck
+
+	_, err = second.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	first.Close()
+
+	second.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint: errcheck
+
+	// Обслуженное соединение закрывается: EOF или RST, но не таймаут.
+	_, err = io.Copy(io.Discard, second)
+	require.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	listener.Close()
+	proxy.Shutdown()
+
+	waits := []EventAcceptBackpressure{}
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventAcceptBackpressure); ok {
+			waits = append(waits, evt)
+		}
+	}
+
+	require.Len(t, waits, 1)
+	assert.GreaterOrEqual(t, waits[0].Duration, 300*time.Millisecond)
+}
+
+func TestAcquireRelay(t *testing.T) {
+	t.Parallel()
+
+	clientConn, serverConn := tcpPair(t)
+
+	defer clientConn.Close()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := &Proxy{
+		ctx:         context.Background(),
+		maxRelays:   2,
+		eventStream: eventStream,
+		logger:      NoopLogger{},
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn)
+	require.NoError(t, err)
+
+	defer streamCtx.Close()
+
+	assert.True(t, proxy.acquireRelay(streamCtx))
+	assert.True(t, proxy.acquireRelay(streamCtx))
+	assert.False(t, proxy.acquireRelay(streamCtx))
+	assert.Equal(t, 2, proxy.ActiveRelays())
+
+	proxy.relaysActive.Add(-1)
+
+	assert.True(t, proxy.acquireRelay(streamCtx))
+	assert.Equal(t, 2, proxy.ActiveRelays())
+
+	limited := []EventRelaysLimited{}
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventRelaysLimited); ok {
+			limited = append(limited, evt)
+		}
+	}
+
+	require.Len(t, limited, 1)
+	assert.Equal(t, streamCtx.streamID, limited[0].StreamID())
+	assert.Equal(t, "127.0.0.1", limited[0].RemoteIP.String())
+
+	// Без лимита relay только считаются.
+	proxy.maxRelays = 0
+
+	assert.True(t, proxy.acquireRelay(streamCtx))
+	assert.Equal(t, 3, proxy.ActiveRelays())
+}
+
+func TestAcceptBackpressureCountsDetached(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{
+		ctx:                context.Background(),
+		acceptBackpressure: 2,
+	}
+
+	// Воркер занят одним хендшейком, а relay уже отпущен воркером: всего
+	// активных соединений 2, и accept должен ждать.
+	proxy.workerPoolBusy.Store(1)
+	proxy.detachedConns.Store(1)
+
+	time.AfterFunc(50*time.Millisecond, func() {
+		proxy.detachedConns.Add(-1)
+	})
+
+	assert.GreaterOrEqual(t, proxy.waitAcceptBackpressure(), 50*time.Millisecond)
+	assert.EqualValues(t, 1, proxy.activeConns())
+}
+
+func TestServeWorkerMaxRelays(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	proxy := newTestProxy(t, eventStream, 4)
+	proxy.maxRelays = 10
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go proxy.Serve(listener) //nolint: errcheck
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	// Хендшейк ещё не закончен: соединение держит воркера.
+	require.Eventually(t, func() bool {
+		return proxy.ActiveWorkers() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")) //nolint: errcheck
+	io.Copy(io.Discard, conn)                    //nolint: errcheck
+	conn.Close()
+
+	// До relay дело не дошло, воркер освобождается вместе с соединением.
+	require.Eventually(t, func() bool {
+		return proxy.ActiveWorkers() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Zero(t, proxy.ActiveRelays())
+
+	listener.Close()
+	proxy.Shutdown()
+}
+
+func TestRelayOptionsQuickACK(t *testing.T) {
+	t.Parallel()
+
+	config := DefaultProxyConfig()
+	config.TCPQuickACKInterval = 8
+	proxy := &Proxy{config: config}
+
+	opts := proxy.relayOptions()
+	assert.False(t, opts.DisableQuickACK)
+	assert.Equal(t, 8, opts.QuickACKInterval)
+
+	proxy.config.TCPQuickACKInterval = TCPQuickACKDisabled
+
+	opts = proxy.relayOptions()
+	assert.True(t, opts.DisableQuickACK)
+	assert.Zero(t, opts.QuickACKInterval)
+}
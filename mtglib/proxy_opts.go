@@ -1,8 +1,10 @@
 package mtglib
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/9seconds/mtg/v2/mtglib/internal/telegram"
 	"golang.org/x/time/rate"
 )
 
@@ -22,11 +24,48 @@ type ProxyOpts struct {
 	// This is a mandatory setting.
 	Network Network
 
+	// TelegramNetwork defines a network instance which should be used to
+	// dial Telegram datacenters.
+	//
+	// This is an optional setting. By default Network is used, so
+	// Telegram connections traverse the same upstream proxy chain as
+	// domain fronting (including its cooldown/circuit breaker). Set it to
+	// a network without proxies to dial Telegram directly.
+	TelegramNetwork Network
+
+	// TelegramDialer defines a dialer which should be used to connect to
+	// Telegram datacenters instead of a built-in one.
+	//
+	// This is an optional setting intended for tests. If it is set, a
+	// built-in dialer is not created at all, so all settings of it are
+	// ignored: connection pool, DC refresh, DC probing on startup, health
+	// checks and TFO warmup. Proxy.TelegramReachable always returns true,
+	// Proxy.GetPoolStats and Proxy.GetDialStats return nil. Proxy does
+	// not close a custom dialer.
+	TelegramDialer TelegramDialer
+
 	// AntiReplayCache defines an instance of antireplay cache.
 	//
 	// This is a mandatory setting.
 	AntiReplayCache AntiReplayCache
 
+	// AntiReplayKey defines how a client hello is keyed in AntiReplayCache.
+	// Please see AntiReplayKeySessionID and NewAntiReplayKeySessionIP.
+	//
+	// This is an optional setting. Default: AntiReplayKeySessionID
+	AntiReplayKey AntiReplayKeyFunc
+
+	// AntiReplayDigestFunc transforms a session id of a client hello
+	// before it is keyed by AntiReplayKey and checked in AntiReplayCache.
+	// It can shorten a digest or make it keyed (for example, HMAC with a
+	// secret of the proxy) without changing a cache implementation.
+	//
+	// The function must be deterministic and safe for concurrent use.
+	// Changing it makes all previously seen sessions unknown.
+	//
+	// This is an optional setting. Default: identity
+	AntiReplayDigestFunc func([]byte) []byte
+
 	// IPBlocklist defines an instance of IP blocklist.
 	//
 	// This is a mandatory setting.
@@ -54,7 +93,8 @@ type ProxyOpts struct {
 	//
 	// This is an optional setting.
 	//
-	// Deprecated: this setting is no longer makes any effect.
+	// Deprecated: this setting is no longer makes any effect. Please use
+	// ProxyConfig.RelayBufferSize.
 	BufferSize uint
 
 	// Concurrency is a size of the worker pool for connection management.
@@ -65,6 +105,40 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	Concurrency uint
 
+	// AcceptBackpressureThreshold is a soft limit of active connections.
+	// When it is reached, Serve stops accepting new connections until
+	// some active ones finish: new clients wait in a listen backlog of
+	// the kernel instead of being rejected at Concurrency. Each relay
+	// holds buffers, so this also bounds memory under sustained load.
+	//
+	// All connections from Serve are counted: if MaxRelays is set,
+	// relays and domain fronted connections which do not hold workers
+	// anymore are counted too.
+	//
+	// It has to be less than Concurrency + MaxRelays. Set to 0 to
+	// disable it.
+	//
+	// This is an optional setting. Default: 0
+	AcceptBackpressureThreshold uint
+
+	// MaxRelays is a limit of active relays to Telegram. If it is set,
+	// a worker serves a connection only until its relay or domain
+	// fronting starts: then the connection continues in its own goroutine
+	// and the worker is free for the next handshake. Domain fronting is
+	// limited by DomainFrontingMaxConnections instead. So Concurrency limits connections in handshake, and
+	// MaxRelays limits relays, and long downloads do not take workers
+	// from new clients. Clients above this limit are closed after the
+	// handshake, EventRelaysLimited is emitted for each of them.
+	//
+	// Connections from ServeConn do not take workers, but are counted
+	// in this limit too.
+	//
+	// Set to 0 to relay within a worker, so Concurrency limits all
+	// connections.
+	//
+	// This is an optional setting. Default: 0
+	MaxRelays uint
+
 	// IdleTimeout is a timeout for relay when we have to break a stream.
 	//
 	// This is a timeout for any activity. So, if we have any message which will
@@ -83,6 +157,37 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	TolerateTimeSkewness time.Duration
 
+	// TolerateTimeSkewnessOverrides overrides TolerateTimeSkewness for
+	// clients from given networks. If a client IP belongs to many networks,
+	// the most specific one wins.
+	//
+	// This is useful for regions where mobile clients often have bad clocks:
+	// a tolerance is loosened only for them, not for everyone. Please use
+	// EventClientTimeSkew to see a real distribution of time skewness.
+	//
+	// This is an optional setting.
+	TolerateTimeSkewnessOverrides []TimeSkewnessOverride
+
+	// FakeTLSMaxRecordSize is a maximal payload size of TLS records we write
+	// to a client. Bulk writes are split into records of exactly this size.
+	//
+	// Values above DefaultFakeTLSMaxRecordSize are capped to it, because
+	// real TLS stacks never send larger records. Values below
+	// MinFakeTLSMaxRecordSize are raised to it.
+	//
+	// This is an optional setting. Default: DefaultFakeTLSMaxRecordSize
+	FakeTLSMaxRecordSize uint
+
+	// FakeTLSSmallFirstRecords is a count of the first TLS records after
+	// a handshake which are written small, like browsers send a short
+	// HTTP/2 SETTINGS frame before full records. Sizes are random and
+	// weighted towards typical short frames; next records are written
+	// as usual. Values above MaxFakeTLSSmallFirstRecords are capped to
+	// it.
+	//
+	// This is an optional setting. Default: 0, disabled.
+	FakeTLSSmallFirstRecords uint
+
 	// PreferIP defines an IP connectivity preference. Valid values are:
 	// 'prefer-ipv4', 'prefer-ipv6', 'only-ipv4', 'only-ipv6'.
 	//
@@ -97,6 +202,22 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	DomainFrontingPort uint
 
+	// DomainFrontingMaxConnections is a max count of connections which
+	// are routed to a fronting domain at the same time. Domain fronting
+	// makes proxy a relay to the fronting domain, so without a limit
+	// somebody could abuse it for a free bandwidth and take all workers.
+	// Connections above this limit are closed, EventDomainFrontingLimited
+	// is emitted for each of them.
+	//
+	// Please note that a closed connection is visible to active probers:
+	// a real website would answer instead. So a limit which is reached
+	// by a normal load makes a proxy detectable.
+	// DefaultDomainFrontingMaxConnections is a reasonable value for most
+	// installations.
+	//
+	// This is an optional setting. Default: 0 (no limit)
+	DomainFrontingMaxConnections uint
+
 	// AllowFallbackOnUnknownDC defines how proxy behaves if unknown DC was
 	// requested. If this setting is set to false, then such connection will be
 	// rejected. Otherwise, proxy will chose any DC.
@@ -107,6 +228,17 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	AllowFallbackOnUnknownDC bool
 
+	// UnknownDCMapping routes unknown DC numbers to known ones, for
+	// example, 203 to DC 2. Some clients request odd DC numbers which
+	// have a known good mapping. A mapped DC is neither rejected nor
+	// replaced by a random one, so it takes precedence over
+	// AllowFallbackOnUnknownDC.
+	//
+	// Targets have to be within [1, 5].
+	//
+	// This is an optional setting.
+	UnknownDCMapping map[int]int
+
 	// FallbackOnDialError enables fallback to another DC when connection
 	// to the requested DC fails. This improves reliability when a specific
 	// DC is temporarily unavailable.
@@ -123,6 +255,90 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	UseTestDCs bool
 
+	// ProbeDCsOnStartup enables a one-shot connectivity check of all
+	// known DCs right after proxy is created. Results are logged: each
+	// reachable and unreachable DC, and a warning if none of them
+	// responded. It never blocks or fails startup.
+	//
+	// This is mostly useful together with UseTestDCs, because staging
+	// DCs are much less reliable than production ones.
+	//
+	// This is an optional setting.
+	ProbeDCsOnStartup bool
+
+	// TelegramHealthCheckInterval enables a periodic connectivity check
+	// of all known DCs. If none of them is reachable for
+	// TelegramHealthFailureThreshold rounds in a row, Proxy.TelegramReachable
+	// returns false: a proxy which cannot reach Telegram is useless, even
+	// if it accepts connections.
+	//
+	// This is an optional setting. Zero disables it.
+	TelegramHealthCheckInterval time.Duration
+
+	// TelegramHealthFailureThreshold is a count of consecutive health
+	// check rounds without reachable DCs after which Telegram is
+	// considered unreachable. Larger values avoid flapping on short DC
+	// outages. A single round with any reachable DC resets it.
+	//
+	// This is an optional setting. Default:
+	// DefaultTelegramHealthFailureThreshold
+	TelegramHealthFailureThreshold uint
+
+	// WarmUpTFOOnStartup enables a one-shot TCP Fast Open cookie warmup
+	// right after proxy is created: mtg opens and immediately closes a
+	// connection to each address of each known DC, so the kernel caches
+	// TFO cookies before the first client arrives.
+	//
+	// This is a heuristic. Cookies are kept by the kernel, not by mtg, so
+	// usually they survive restarts and the warmup changes nothing. It
+	// helps only if the cookie cache is flushed or is per network
+	// namespace (e.g. a fresh container). It makes sense only if
+	// TelegramNetwork dials with TCP Fast Open and without upstream
+	// proxies. It never blocks or fails startup.
+	//
+	// This is an optional setting.
+	WarmUpTFOOnStartup bool
+
+	// RejectScanners defines how proxy behaves if the first bytes of a
+	// client connection clearly are not a TLS handshake: junk, plain HTTP
+	// or a connection closed before a full TLS record header.
+	//
+	// By default such connections are routed to the fronting domain as
+	// any other invalid handshake. If this setting is true, they are
+	// closed immediately, saving a dial to the fronting domain. Please
+	// note that this behavior differs from a real web server, so an
+	// active prober may notice it.
+	//
+	// EventScannerDetected is emitted in both cases.
+	//
+	// This is an optional setting.
+	RejectScanners bool
+
+	// TarpitDuration enables a tarpit for invalid handshakes. Instead of
+	// routing such a connection to the fronting domain, proxy slowly sends
+	// a TLS alert byte by byte over this duration and closes the
+	// connection. This wastes resources of mass scanners and never dials
+	// the fronting domain, so it suits honeypot-like deployments. Please
+	// remember that an active prober may notice it, as with
+	// RejectScanners. If RejectScanners is set, scanners are still closed
+	// immediately.
+	//
+	// At most MaxTarpitConnections are tarpitted at the same time, others
+	// are closed immediately. EventTarpitted is emitted for each
+	// tarpitted connection.
+	//
+	// Must be at most MaxTarpitDuration. This is an optional setting,
+	// zero disables a tarpit.
+	TarpitDuration time.Duration
+
+	// InBandMetrics enables serving metrics through the proxy port: an
+	// authorized plain HTTP GET request to a metrics path is handled by
+	// InBandMetrics.Handler instead of domain fronting. Please see
+	// InBandMetrics for details.
+	//
+	// This is an optional setting, disabled by default.
+	InBandMetrics *InBandMetrics
+
 	// Config contains timeouts and other configurable parameters.
 	//
 	// This is an optional setting. If not provided, default values will be used.
@@ -137,9 +353,27 @@ type ProxyOpts struct {
 
 	// RateLimitBurst defines the maximum burst size for rate limiting.
 	//
-	// This is an optional setting. Default: 20
+	// This is an optional setting. Default: DefaultRateLimitBurst
 	RateLimitBurst int
 
+	// SkewRateLimitPerSecond defines how many FakeTLS client hellos per
+	// second an IP may send with a timestamp out of a time skewness
+	// tolerance. When this limit is exceeded, all connections from the
+	// IP are rejected until the limiter refills, before a client hello
+	// is read. A client with a bad clock sends a few of them, a scanner
+	// replaying captured hellos sends a lot.
+	//
+	// Set to 0 to disable it.
+	//
+	// This is an optional setting. Default: 0
+	SkewRateLimitPerSecond float64
+
+	// SkewRateLimitBurst defines the maximum burst size for
+	// SkewRateLimitPerSecond.
+	//
+	// This is an optional setting. Default: DefaultSkewRateLimitBurst
+	SkewRateLimitBurst int
+
 	// DCConfigFile — путь к JSON файлу с DC-адресами.
 	// Если указан, адреса периодически перезагружаются из файла.
 	// При ошибке загрузки используются hardcoded адреса.
@@ -147,7 +381,17 @@ type ProxyOpts struct {
 	// This is an optional setting.
 	DCConfigFile string
 
-	// DCRefreshInterval — интервал проверки DC-файла на обновления.
+	// DCConfigURL — http(s) URL, отдающий JSON с DC-адресами в том же
+	// формате, что и DCConfigFile. Имеет приоритет над DCConfigFile.
+	// При ошибке загрузки используются hardcoded адреса.
+	//
+	// URL должен быть проверен на SSRF вызывающим кодом: редиректы не
+	// выполняются, но сам адрес не фильтруется.
+	//
+	// This is an optional setting.
+	DCConfigURL string
+
+	// DCRefreshInterval — интервал проверки DC-файла или URL на обновления.
 	// Default: 24h
 	DCRefreshInterval time.Duration
 
@@ -159,7 +403,7 @@ type ProxyOpts struct {
 
 	// ConnectionPoolMaxIdle — максимальное количество idle соединений на DC.
 	//
-	// This is an optional setting. Default: 5
+	// This is an optional setting. Default: DefaultConnectionPoolMaxIdle
 	ConnectionPoolMaxIdle int
 
 	// ConnectionPoolIdleTimeout — таймаут простоя для соединений в пуле.
@@ -167,6 +411,14 @@ type ProxyOpts struct {
 	// This is an optional setting. Default: 1 minute
 	ConnectionPoolIdleTimeout time.Duration
 
+	// ConnectionPoolIdleDrainAfter — через сколько без новых соединений к
+	// DC закрыть все его idle соединения в пуле. Ночью, когда трафика
+	// нет, прокси не держит открытыми соединения к Telegram; пул
+	// наполняется заново по первым же запросам.
+	//
+	// This is an optional setting. Default: 0 (never drain)
+	ConnectionPoolIdleDrainAfter time.Duration
+
 	// A5: CCS padding удалён — RFC 8446 violation, создаёт DPI fingerprint.
 	// См. комментарий в mtglib/internal/faketls/conn.go.
 }
@@ -189,9 +441,78 @@ func (p ProxyOpts) valid() error {
 		return ErrSecretInvalid
 	}
 
+	if p.AcceptBackpressureThreshold >= uint(p.getConcurrency())+p.MaxRelays {
+		return fmt.Errorf("accept backpressure threshold %d has to be less than concurrency %d + max relays %d",
+			p.AcceptBackpressureThreshold, p.getConcurrency(), p.MaxRelays)
+	}
+
+	for from, to := range p.UnknownDCMapping {
+		if to < 1 || to > 5 {
+			return fmt.Errorf("unknown DC %d is mapped to incorrect DC %d", from, to)
+		}
+	}
+
+	if p.ConnectionPoolIdleDrainAfter < 0 {
+		return fmt.Errorf("connection pool idle drain period %v must not be negative", p.ConnectionPoolIdleDrainAfter)
+	}
+
+	if p.TelegramHealthCheckInterval < 0 {
+		return fmt.Errorf("telegram health check interval %v must not be negative", p.TelegramHealthCheckInterval)
+	}
+
+	if p.SkewRateLimitBurst < 0 {
+		return fmt.Errorf("skew rate limit burst %d must not be negative", p.SkewRateLimitBurst)
+	}
+
+	if p.TarpitDuration < 0 || p.TarpitDuration > MaxTarpitDuration {
+		return fmt.Errorf("tarpit duration %v is out of range [0, %v]", p.TarpitDuration, MaxTarpitDuration)
+	}
+
+	if p.Config != nil {
+		if err := p.Config.valid(); err != nil {
+			return fmt.Errorf("invalid proxy config: %w", err)
+		}
+	}
+
+	if p.InBandMetrics != nil {
+		if err := p.InBandMetrics.valid(); err != nil {
+			return fmt.Errorf("invalid in-band metrics: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// Effective returns a copy of these options with all defaults which
+// NewProxy applies to empty settings filled in. It is intended for
+// diagnostics: this is what a proxy is actually going to run with.
+//
+// Options are not validated there.
+func (p ProxyOpts) Effective() ProxyOpts {
+	config := p.getConfig()
+
+	p.Config = &config
+	p.TelegramNetwork = p.getTelegramNetwork()
+	p.AntiReplayKey = p.getAntiReplayKey()
+	p.Concurrency = uint(p.getConcurrency())
+	p.TolerateTimeSkewness = p.getTolerateTimeSkewness()
+	p.FakeTLSMaxRecordSize = uint(p.getFakeTLSMaxRecordSize())
+	p.FakeTLSSmallFirstRecords = uint(p.getFakeTLSSmallFirstRecords())
+	p.PreferIP = p.getPreferIP()
+	p.DomainFrontingPort = uint(p.getDomainFrontingPort())
+	p.TelegramHealthFailureThreshold = uint(p.getTelegramHealthFailureThreshold())
+	p.RateLimitBurst = p.getRateLimitBurst()
+	p.SkewRateLimitBurst = p.getSkewRateLimitBurst()
+	p.ConnectionPoolMaxIdle = p.getConnectionPoolMaxIdle()
+	p.ConnectionPoolIdleTimeout = p.getConnectionPoolIdleTimeout()
+
+	if p.DCRefreshInterval <= 0 {
+		p.DCRefreshInterval = telegram.DefaultDCRefreshInterval
+	}
+
+	return p
+}
+
 func (p ProxyOpts) getConcurrency() int {
 	if p.Concurrency == 0 {
 		return DefaultConcurrency
@@ -200,6 +521,14 @@ func (p ProxyOpts) getConcurrency() int {
 	return int(p.Concurrency)
 }
 
+func (p ProxyOpts) getTelegramHealthFailureThreshold() int {
+	if p.TelegramHealthFailureThreshold == 0 {
+		return DefaultTelegramHealthFailureThreshold
+	}
+
+	return int(p.TelegramHealthFailureThreshold)
+}
+
 func (p ProxyOpts) getDomainFrontingPort() int {
 	if p.DomainFrontingPort == 0 {
 		return DefaultDomainFrontingPort
@@ -208,6 +537,29 @@ func (p ProxyOpts) getDomainFrontingPort() int {
 	return int(p.DomainFrontingPort)
 }
 
+func (p ProxyOpts) getFakeTLSSmallFirstRecords() int {
+	return int(min(p.FakeTLSSmallFirstRecords, MaxFakeTLSSmallFirstRecords))
+}
+
+func (p ProxyOpts) getFakeTLSMaxRecordSize() int {
+	switch {
+	case p.FakeTLSMaxRecordSize == 0 || p.FakeTLSMaxRecordSize > DefaultFakeTLSMaxRecordSize:
+		return DefaultFakeTLSMaxRecordSize
+	case p.FakeTLSMaxRecordSize < MinFakeTLSMaxRecordSize:
+		return MinFakeTLSMaxRecordSize
+	}
+
+	return int(p.FakeTLSMaxRecordSize)
+}
+
+func (p ProxyOpts) getAntiReplayKey() AntiReplayKeyFunc {
+	if p.AntiReplayKey == nil {
+		return AntiReplayKeySessionID
+	}
+
+	return p.AntiReplayKey
+}
+
 func (p ProxyOpts) getTolerateTimeSkewness() time.Duration {
 	if p.TolerateTimeSkewness == 0 {
 		return DefaultTolerateTimeSkewness
@@ -224,6 +576,14 @@ func (p ProxyOpts) getPreferIP() string {
 	return p.PreferIP
 }
 
+func (p ProxyOpts) getTelegramNetwork() Network {
+	if p.TelegramNetwork == nil {
+		return p.Network
+	}
+
+	return p.TelegramNetwork
+}
+
 func (p ProxyOpts) getLogger(name string) Logger {
 	return p.Logger.Named(name)
 }
@@ -246,15 +606,27 @@ func (p ProxyOpts) getRateLimitPerSecond() rate.Limit {
 
 func (p ProxyOpts) getRateLimitBurst() int {
 	if p.RateLimitBurst == 0 {
-		return 20 // default burst
+		return DefaultRateLimitBurst
 	}
 
 	return p.RateLimitBurst
 }
 
+func (p ProxyOpts) getSkewRateLimitPerSecond() rate.Limit {
+	return rate.Limit(p.SkewRateLimitPerSecond)
+}
+
+func (p ProxyOpts) getSkewRateLimitBurst() int {
+	if p.SkewRateLimitBurst == 0 {
+		return DefaultSkewRateLimitBurst
+	}
+
+	return p.SkewRateLimitBurst
+}
+
 func (p ProxyOpts) getConnectionPoolMaxIdle() int {
 	if p.ConnectionPoolMaxIdle == 0 {
-		return 5 // default
+		return DefaultConnectionPoolMaxIdle
 	}
 
 	return p.ConnectionPoolMaxIdle
@@ -0,0 +1,63 @@
+package mtglib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/mtglib/internal/telegram"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyOptsEffectiveDefaults(t *testing.T) {
+	t.Parallel()
+
+	opts := ProxyOpts{}.Effective()
+
+	assert.EqualValues(t, DefaultConcurrency, opts.Concurrency)
+	assert.EqualValues(t, DefaultDomainFrontingPort, opts.DomainFrontingPort)
+	assert.Zero(t, opts.DomainFrontingMaxConnections)
+	assert.EqualValues(t, DefaultFakeTLSMaxRecordSize, opts.FakeTLSMaxRecordSize)
+	assert.Zero(t, opts.FakeTLSSmallFirstRecords)
+	assert.EqualValues(t, DefaultTelegramHealthFailureThreshold, opts.TelegramHealthFailureThreshold)
+	assert.Equal(t, DefaultTolerateTimeSkewness, opts.TolerateTimeSkewness)
+	assert.Equal(t, DefaultPreferIP, opts.PreferIP)
+	assert.Equal(t, DefaultRateLimitBurst, opts.RateLimitBurst)
+	assert.Equal(t, DefaultSkewRateLimitBurst, opts.SkewRateLimitBurst)
+	assert.Equal(t, DefaultConnectionPoolMaxIdle, opts.ConnectionPoolMaxIdle)
+	assert.Equal(t, 20*time.Second, opts.ConnectionPoolIdleTimeout)
+	assert.Equal(t, telegram.DefaultDCRefreshInterval, opts.DCRefreshInterval)
+	assert.NotNil(t, opts.AntiReplayKey)
+	assert.Equal(t, DefaultProxyConfig(), *opts.Config)
+}
+
+func TestProxyOptsEffectiveKeepsExplicit(t *testing.T) {
+	t.Parallel()
+
+	config := DefaultProxyConfig()
+	config.RelayBufferSize = 64 * 1024
+
+	original := ProxyOpts{
+		Concurrency:               10,
+		PreferIP:                  "only-ipv4",
+		ConnectionPoolIdleTimeout: time.Minute,
+		DCRefreshInterval:         time.Hour,
+		Config:                    &config,
+	}
+	opts := original.Effective()
+
+	assert.EqualValues(t, 10, opts.Concurrency)
+	assert.Equal(t, "only-ipv4", opts.PreferIP)
+	assert.Equal(t, time.Minute, opts.ConnectionPoolIdleTimeout)
+	assert.Equal(t, time.Hour, opts.DCRefreshInterval)
+	assert.Equal(t, 64*1024, opts.Config.RelayBufferSize)
+	assert.Nil(t, original.AntiReplayKey)
+}
+
+func TestProxyOptsEffectiveCapsFakeTLS(t *testing.T) {
+	t.Parallel()
+
+	opts := ProxyOpts{FakeTLSSmallFirstRecords: 100, FakeTLSMaxRecordSize: 1}.Effective()
+
+	assert.EqualValues(t, MaxFakeTLSSmallFirstRecords, opts.FakeTLSSmallFirstRecords)
+	assert.EqualValues(t, MinFakeTLSMaxRecordSize, opts.FakeTLSMaxRecordSize)
+}
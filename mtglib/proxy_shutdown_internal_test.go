@@ -0,0 +1,150 @@
+package mtglib
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type noopIPBlocklist struct{}
+
+func (n noopIPBlocklist) Contains(_ net.IP) bool { return false }
+func (n noopIPBlocklist) Run(_ time.Duration)    {}
+func (n noopIPBlocklist) Shutdown()              {}
+
+type allowAllIPBlocklist struct {
+	noopIPBlocklist
+}
+
+func (a allowAllIPBlocklist) Contains(_ net.IP) bool { return true }
+
+func countOpenFDs(t *testing.T) int {
+	t.Helper()
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("cannot count open file descriptors on this platform")
+	}
+
+	return len(entries)
+}
+
+// newTestProxy создаёт прокси, у которого domain fronting всегда
+// завершается ошибкой dial.
+func newTestProxy(t *testing.T, eventStream EventStream, concurrency uint) *Proxy {
+	t.Helper()
+
+	networkMock := &testlib.MtglibNetworkMock{}
+	networkMock.On("WarmUp", mock.Anything).Maybe()
+	networkMock.
+		On("DialContext", mock.Anything, mock.Anything, mock.Anything).
+		Return(essentials.Conn((*net.TCPConn)(nil)), io.ErrUnexpectedEOF).
+		Maybe()
+
+	proxy, err := NewProxy(ProxyOpts{
+		Secret:          GenerateSecret("example.com"),
+		Network:         networkMock,
+		AntiReplayCache: mapAntiReplayCache{},
+		IPBlocklist:     noopIPBlocklist{},
+		IPAllowlist:     allowAllIPBlocklist{},
+		EventStream:     eventStream,
+		Logger:          NoopLogger{},
+		Concurrency:     concurrency,
+	})
+	require.NoError(t, err)
+
+	return proxy
+}
+
+// shutdownUnderAccepts запускает прокси, подключает клиентов во время
+// Shutdown и проверяет, что прокси закрыл все принятые соединения.
+func shutdownUnderAccepts(t *testing.T, clients int) {
+	t.Helper()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything).Maybe()
+
+	proxy := newTestProxy(t, eventStream, uint(clients/2)) //nolint: gosec
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go proxy.Serve(listener) //nolint: errcheck
+
+	connsMutex := sync.Mutex{}
+	conns := []net.Conn{}
+	wg := &sync.WaitGroup{}
+
+	for i := range clients {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				return
+			}
+
+			// Половина клиентов присылает начало ClientHello и зависает,
+			// вторая половина молчит.
+			if i%2 == 0 {
+				conn.Write([]byte{0x16, 0x03, 0x01, 0x02, 0x00}) //nolint: errcheck
+			}
+
+			connsMutex.Lock()
+			conns = append(conns, conn)
+			connsMutex.Unlock()
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	listener.Close()
+
+	shutdownDone := make(chan struct{})
+
+	go func() {
+		proxy.Shutdown()
+		close(shutdownDone)
+	}()
+
+	select {
+	case <-shutdownDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Shutdown has not returned")
+	}
+
+	wg.Wait()
+
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint: errcheck
+
+		_, err := io.Copy(io.Discard, conn)
+		assert.False(t, errors.Is(err, os.ErrDeadlineExceeded), "connection is not closed by proxy")
+
+		conn.Close()
+	}
+}
+
+func TestShutdownClosesAcceptedConnections(t *testing.T) {
+	// Не parallel: считаем открытые дескрипторы процесса.
+	baseline := countOpenFDs(t)
+
+	for range 20 {
+		shutdownUnderAccepts(t, 32)
+	}
+
+	assert.Eventually(t, func() bool {
+		return countOpenFDs(t) <= baseline
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -106,7 +106,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoSecret() {
 	opts.Secret = mtglib.Secret{}
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrSecretInvalid)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoNetwork() {
@@ -114,7 +115,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoNetwork() {
 	opts.Network = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrNetworkIsNotDefined)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoAntiReplayCache() {
@@ -122,7 +124,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoAntiReplayCache() {
 	opts.AntiReplayCache = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrAntiReplayCacheIsNotDefined)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoIPBlocklist() {
@@ -130,7 +133,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoIPBlocklist() {
 	opts.IPBlocklist = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrIPBlocklistIsNotDefined)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoIPAllowlist() {
@@ -138,7 +142,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoIPAllowlist() {
 	opts.IPAllowlist = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrIPAllowlistIsNotDefined)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoEventStream() {
@@ -146,7 +151,8 @@ func (suite *ProxyTestSuite) TestCannotInitNoEventStream() {
 	opts.EventStream = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrEventStreamIsNotDefined)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitNoLogger() {
@@ -154,7 +160,16 @@ func (suite *ProxyTestSuite) TestCannotInitNoLogger() {
 	opts.Logger = nil
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
+	suite.ErrorIs(err, mtglib.ErrLoggerIsNotDefined)
+}
+
+func (suite *ProxyTestSuite) TestCannotInitNegativeSkewRateLimitBurst() {
+	opts := *suite.opts
+	opts.SkewRateLimitBurst = -1
+
+	_, err := mtglib.NewProxy(opts)
+	suite.ErrorIs(err, mtglib.ErrProxyOptsInvalid)
 }
 
 func (suite *ProxyTestSuite) TestCannotInitIncorrectPreferIP() {
@@ -162,7 +177,7 @@ func (suite *ProxyTestSuite) TestCannotInitIncorrectPreferIP() {
 	opts.PreferIP = "xxx"
 
 	_, err := mtglib.NewProxy(opts)
-	suite.Error(err)
+	suite.ErrorIs(err, mtglib.ErrTelegramDialerFailed)
 }
 
 func (suite *ProxyTestSuite) TestDomainFrontingAddress() {
@@ -1,8 +1,11 @@
 package mtglib
 
 import (
+	"hash/maphash"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -13,17 +16,33 @@ const (
 	// Превышение означает DDoS — новые IP отклоняются.
 	// 50 000 записей ≈ 5 МБ памяти (limiter + lastUsed + ключи).
 	maxRateLimiterEntries = 50_000
+
+	// Количество шардов. Cleanup за один тик проходит только один шард,
+	// так что ни один проход не держит lock над всей таблицей: раньше
+	// полный проход раз в минуту давал всплеск TTFB у всех клиентов.
+	rateLimiterShards = 64
+
+	// Доля случайного разброса интервала между тиками cleanup. Иначе
+	// лимитеры, созданные одновременно, чистятся синхронно.
+	rateLimiterCleanupJitter = 0.1
 )
 
-// RateLimiter provides IP-based rate limiting for connections.
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
+// rateLimiterShard — часть таблицы лимитеров со своим lock.
+type rateLimiterShard struct {
 	mu       sync.RWMutex
-	r        rate.Limit
-	b        int
-	cleanup  time.Duration
+	limiters map[string]*rate.Limiter
 	lastUsed map[string]time.Time
-	stopCh   chan struct{}
+}
+
+// RateLimiter provides IP-based rate limiting for connections.
+type RateLimiter struct {
+	shards  [rateLimiterShards]rateLimiterShard
+	seed    maphash.Seed
+	size    atomic.Int64
+	r       rate.Limit
+	b       int
+	cleanup time.Duration
+	stopCh  chan struct{}
 }
 
 // NewRateLimiter creates a new rate limiter.
@@ -32,12 +51,16 @@ type RateLimiter struct {
 // cleanup is how often to clean up old entries.
 func NewRateLimiter(r rate.Limit, b int, cleanup time.Duration) *RateLimiter {
 	rl := &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		lastUsed: make(map[string]time.Time),
-		r:        r,
-		b:        b,
-		cleanup:  cleanup,
-		stopCh:   make(chan struct{}),
+		seed:    maphash.MakeSeed(),
+		r:       r,
+		b:       b,
+		cleanup: cleanup,
+		stopCh:  make(chan struct{}),
+	}
+
+	for i := range rl.shards {
+		rl.shards[i].limiters = make(map[string]*rate.Limiter)
+		rl.shards[i].lastUsed = make(map[string]time.Time)
 	}
 
 	go rl.cleanupLoop()
@@ -57,40 +80,63 @@ func (rl *RateLimiter) Allow(ip net.IP) bool {
 
 	// string(ip) — raw bytes (16 байт), дешевле ip.String() (форматирование "1.2.3.4")
 	key := string(normalized)
+	shard := rl.shard(key)
 
 	// Fast path: для существующих IP достаточно RLock (read-only).
 	// lastUsed не обновляем — worst case: limiter пересоздастся при cleanup,
 	// что безопасно (rate сбросится, клиент получит больше, не меньше).
-	rl.mu.RLock()
-	limiter, exists := rl.limiters[key]
-	rl.mu.RUnlock()
+	shard.mu.RLock()
+	limiter, exists := shard.limiters[key]
+	shard.mu.RUnlock()
 
 	if exists {
 		return limiter.Allow()
 	}
 
 	// Slow path: новый IP — нужен write lock
-	rl.mu.Lock()
+	shard.mu.Lock()
 	// Double-check после escalation — другая goroutine могла добавить
-	limiter, exists = rl.limiters[key]
+	limiter, exists = shard.limiters[key]
 	if !exists {
 		// Защита от переполнения: при DDoS с множеством IP
-		// отклоняем новые соединения, пока cleanup не освободит место
-		if len(rl.limiters) >= maxRateLimiterEntries {
-			rl.mu.Unlock()
+		// отклоняем новые соединения, пока cleanup не освободит место.
+		// Лимит общий на все шарды; гонка между шардами может превысить
+		// его на несколько записей, это не страшно.
+		if rl.size.Load() >= maxRateLimiterEntries {
+			shard.mu.Unlock()
 
 			return false
 		}
 
 		limiter = rate.NewLimiter(rl.r, rl.b)
-		rl.limiters[key] = limiter
+		shard.limiters[key] = limiter
+		rl.size.Add(1)
 	}
-	rl.lastUsed[key] = time.Now()
-	rl.mu.Unlock()
+	shard.lastUsed[key] = time.Now()
+	shard.mu.Unlock()
 
 	return limiter.Allow()
 }
 
+// Exhausted checks if the given IP has no tokens left. Unlike Allow, it
+// does not consume a token, so a limiter can be charged with one kind of
+// events and checked on another.
+func (rl *RateLimiter) Exhausted(ip net.IP) bool {
+	normalized := ip.To16()
+	if normalized == nil {
+		return false
+	}
+
+	key := string(normalized)
+	shard := rl.shard(key)
+
+	shard.mu.RLock()
+	limiter, exists := shard.limiters[key]
+	shard.mu.RUnlock()
+
+	return exists && limiter.Tokens() < 1
+}
+
 // Stop gracefully stops the rate limiter cleanup goroutine.
 func (rl *RateLimiter) Stop() {
 	close(rl.stopCh)
@@ -98,31 +144,54 @@ func (rl *RateLimiter) Stop() {
 
 // Size returns current number of tracked IPs in the rate limiter.
 func (rl *RateLimiter) Size() int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+	return int(rl.size.Load())
+}
 
-	return len(rl.limiters)
+func (rl *RateLimiter) shard(key string) *rateLimiterShard {
+	return &rl.shards[maphash.String(rl.seed, key)%rateLimiterShards]
 }
 
 // cleanupLoop removes old rate limiters that haven't been used recently.
+// Каждый тик чистит один шард по кругу, так что вся таблица проходится
+// примерно раз в cleanup, но маленькими порциями.
 func (rl *RateLimiter) cleanupLoop() {
-	ticker := time.NewTicker(rl.cleanup)
-	defer ticker.Stop()
+	interval := rl.cleanup / rateLimiterShards
+	if interval <= 0 {
+		interval = rl.cleanup
+	}
 
-	for {
+	timer := time.NewTimer(jitterCleanupInterval(interval))
+	defer timer.Stop()
+
+	for next := 0; ; next = (next + 1) % rateLimiterShards {
 		select {
 		case <-rl.stopCh:
 			return
-		case <-ticker.C:
-			rl.mu.Lock()
-			now := time.Now()
-			for key, lastUsed := range rl.lastUsed {
-				if now.Sub(lastUsed) > rl.cleanup*2 {
-					delete(rl.limiters, key)
-					delete(rl.lastUsed, key)
-				}
-			}
-			rl.mu.Unlock()
+		case <-timer.C:
+			rl.cleanupShard(&rl.shards[next], time.Now())
+			timer.Reset(jitterCleanupInterval(interval))
+		}
+	}
+}
+
+// cleanupShard удаляет из шарда лимитеры, которыми не пользовались
+// дольше двух интервалов cleanup.
+func (rl *RateLimiter) cleanupShard(shard *rateLimiterShard, now time.Time) {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	for key, lastUsed := range shard.lastUsed {
+		if now.Sub(lastUsed) > rl.cleanup*2 {
+			delete(shard.limiters, key)
+			delete(shard.lastUsed, key)
+			rl.size.Add(-1)
 		}
 	}
 }
+
+// jitterCleanupInterval возвращает interval ± rateLimiterCleanupJitter.
+func jitterCleanupInterval(interval time.Duration) time.Duration {
+	delta := (rand.Float64()*2 - 1) * rateLimiterCleanupJitter * float64(interval) //nolint: gosec
+
+	return interval + time.Duration(delta)
+}
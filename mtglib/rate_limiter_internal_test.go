@@ -0,0 +1,92 @@
+package mtglib
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimiterTestIP(n int) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, uint32(n)) //nolint: gosec
+
+	return ip
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRateLimiter(1, 1, 64*time.Millisecond)
+	defer rl.Stop()
+
+	for i := range 1000 {
+		assert.True(t, rl.Allow(rateLimiterTestIP(i)))
+	}
+
+	assert.Equal(t, 1000, rl.Size())
+	assert.True(t, rl.Exhausted(rateLimiterTestIP(1)))
+
+	assert.Eventually(t, func() bool {
+		return rl.Size() == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Лимитер пересоздан: клиент снова получает полный burst.
+	assert.False(t, rl.Exhausted(rateLimiterTestIP(1)))
+	assert.True(t, rl.Allow(rateLimiterTestIP(1)))
+	assert.Equal(t, 1, rl.Size())
+}
+
+func TestRateLimiterCleanupShardKeepsFresh(t *testing.T) {
+	t.Parallel()
+
+	rl := NewRateLimiter(1, 1, time.Hour)
+	defer rl.Stop()
+
+	rl.Allow(rateLimiterTestIP(1))
+	rl.Allow(rateLimiterTestIP(2))
+
+	now := time.Now()
+
+	for i := range rl.shards {
+		rl.cleanupShard(&rl.shards[i], now)
+	}
+
+	assert.Equal(t, 2, rl.Size())
+
+	for i := range rl.shards {
+		rl.cleanupShard(&rl.shards[i], now.Add(3*time.Hour))
+	}
+
+	assert.Equal(t, 0, rl.Size())
+}
+
+// BenchmarkRateLimiterAllowDuringCleanup измеряет хвост задержки Allow,
+// пока cleanup раз за разом проходит по полной таблице.
+func BenchmarkRateLimiterAllowDuringCleanup(b *testing.B) {
+	rl := NewRateLimiter(1000, 1000, 10*time.Millisecond)
+	defer rl.Stop()
+
+	for i := range maxRateLimiterEntries {
+		rl.Allow(rateLimiterTestIP(i))
+	}
+
+	latencies := make([]time.Duration, b.N)
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		started := time.Now()
+		rl.Allow(rateLimiterTestIP(i % maxRateLimiterEntries))
+		latencies[i] = time.Since(started)
+	}
+
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+	b.ReportMetric(float64(latencies[len(latencies)-1].Nanoseconds()), "max-ns")
+}
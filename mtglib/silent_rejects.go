@@ -0,0 +1,83 @@
+package mtglib
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Максимальное количество IP, которые помнит silentRejects. Когда
+// места нет даже после чистки, отказ обрабатывается как обычно: лучше
+// лишнее событие, чем неограниченный рост памяти при массовом сканировании.
+const maxSilentRejectsEntries = 50_000
+
+// Сколько записей просматривает одна чистка. Чистка идёт под mutex на
+// пути accept, так что полный проход по карте при переполнении дал бы
+// флуду способ тормозить приём соединений.
+const silentRejectsEvictBatch = 128
+
+// silentRejects помнит IP, отклонённые allowlist или blocklist, чтобы
+// повторные отказы в пределах окна закрывались молча: без лога и
+// события. Массовые сканеры стучатся с одних и тех же адресов, и на
+// каждое соединение уходило больше работы, чем на сам отказ.
+//
+// Окно не продлевается повторными отказами: постоянный сканер виден в
+// метриках раз в окно, а не один раз навсегда.
+type silentRejects struct {
+	mutex  sync.Mutex
+	window time.Duration
+	seen   map[string]time.Time
+}
+
+// silenced сообщает, был ли ip отклонён в пределах окна. Если нет, ip
+// запоминается, и следующие отказы в пределах окна будут молчаливыми.
+func (s *silentRejects) silenced(ip net.IP, now time.Time) bool {
+	normalized := ip.To16()
+	if normalized == nil {
+		return false
+	}
+
+	key := string(normalized)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if rejectedAt, ok := s.seen[key]; ok && now.Sub(rejectedAt) < s.window {
+		return true
+	}
+
+	if len(s.seen) >= maxSilentRejectsEntries {
+		s.evictExpired(now)
+	}
+
+	if len(s.seen) < maxSilentRejectsEntries {
+		s.seen[key] = now
+	}
+
+	return false
+}
+
+// evictExpired удаляет устаревшие записи среди не более чем
+// silentRejectsEvictBatch просмотренных. Обход карты начинается со
+// случайного места, так что повторные чистки доходят до всех записей.
+func (s *silentRejects) evictExpired(now time.Time) {
+	checked := 0
+
+	for key, rejectedAt := range s.seen {
+		if now.Sub(rejectedAt) >= s.window {
+			delete(s.seen, key)
+		}
+
+		checked++
+		if checked >= silentRejectsEvictBatch {
+			return
+		}
+	}
+}
+
+func newSilentRejects(window time.Duration) *silentRejects {
+	return &silentRejects{
+		window: window,
+		seen:   map[string]time.Time{},
+	}
+}
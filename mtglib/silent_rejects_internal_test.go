@@ -0,0 +1,77 @@
+package mtglib
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilentRejectsWindow(t *testing.T) {
+	t.Parallel()
+
+	rejects := newSilentRejects(time.Minute)
+	now := time.Now()
+	ip := net.ParseIP("10.0.0.10")
+
+	assert.False(t, rejects.silenced(ip, now))
+	assert.True(t, rejects.silenced(ip, now.Add(time.Second)))
+	assert.True(t, rejects.silenced(net.ParseIP("::ffff:10.0.0.10"), now.Add(time.Second)))
+	assert.False(t, rejects.silenced(net.ParseIP("10.0.0.11"), now.Add(time.Second)))
+
+	// Окно не продлевается повторами: через минуту отказ снова виден.
+	assert.False(t, rejects.silenced(ip, now.Add(time.Minute)))
+	assert.True(t, rejects.silenced(ip, now.Add(time.Minute+time.Second)))
+}
+
+func TestSilentRejectsOverflow(t *testing.T) {
+	t.Parallel()
+
+	rejects := newSilentRejects(time.Minute)
+	now := time.Now()
+
+	for i := range maxSilentRejectsEntries {
+		rejects.seen[strconv.Itoa(i)] = now
+	}
+
+	ip := net.ParseIP("10.0.0.10")
+
+	// Места нет: отказ не молчаливый и не запоминается.
+	assert.False(t, rejects.silenced(ip, now))
+	assert.False(t, rejects.silenced(ip, now))
+
+	// После истечения окна старые записи вычищаются, но понемногу за раз.
+	assert.False(t, rejects.silenced(ip, now.Add(time.Minute)))
+	assert.True(t, rejects.silenced(ip, now.Add(time.Minute+time.Second)))
+	assert.Len(t, rejects.seen, maxSilentRejectsEntries-silentRejectsEvictBatch+1)
+}
+
+func TestSilentRejectsEvictBounded(t *testing.T) {
+	t.Parallel()
+
+	rejects := newSilentRejects(time.Minute)
+	now := time.Now()
+
+	for i := range maxSilentRejectsEntries {
+		rejects.seen[strconv.Itoa(i)] = now
+	}
+
+	// Все записи свежие: чистка ничего не удаляет и не обходит всю карту.
+	rejects.evictExpired(now)
+	assert.Len(t, rejects.seen, maxSilentRejectsEntries)
+
+	rejects.evictExpired(now.Add(time.Minute))
+	assert.Len(t, rejects.seen, maxSilentRejectsEntries-silentRejectsEvictBatch)
+}
+
+func TestSilenceRejectDisabled(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{}
+	ip := net.ParseIP("10.0.0.10")
+
+	assert.False(t, proxy.silenceReject(ip))
+	assert.False(t, proxy.silenceReject(ip))
+}
@@ -18,7 +18,32 @@ type streamContext struct {
 	telegramConn essentials.Conn
 	streamID     string
 	dc           int
+	startedAt    time.Time
 	logger       Logger
+
+	// telegramDC — DC, к которому реально подключились. Может отличаться
+	// от dc из-за маппинга неизвестных DC и fallback.
+	telegramDC int
+
+	// clientSNI — SNI из ClientHello клиента, если его удалось разобрать.
+	// Нужен только для статистики domain fronting.
+	clientSNI string
+
+	// handshakeDeadline — deadline всего хендшейка. Нулевой, если
+	// HandshakeTimeout выключен.
+	handshakeDeadline time.Time
+
+	// releaseWorker отпускает воркер пула, если соединение обслуживается
+	// с MaxRelays. Иначе nil.
+	releaseWorker func()
+}
+
+// detachFromWorker отпускает воркер перед долгой частью соединения:
+// relay или domain fronting.
+func (s *streamContext) detachFromWorker() {
+	if s.releaseWorker != nil {
+		s.releaseWorker()
+	}
 }
 
 func (s *streamContext) Deadline() (time.Time, bool) {
@@ -66,9 +91,10 @@ func newStreamContext(ctx context.Context, logger Logger, clientConn essentials.
 		ctxCancel:  cancel,
 		clientConn: clientConn,
 		streamID:   base64.RawURLEncoding.EncodeToString(connIDBytes),
+		startedAt:  time.Now(),
 	}
 	streamCtx.logger = logger.
-		BindStr("stream-id", streamCtx.streamID).
+		BindStr(LoggerFieldStreamID, streamCtx.streamID).
 		BindStr("client-ip", hashIP(streamCtx.ClientIP()))
 
 	return streamCtx, nil
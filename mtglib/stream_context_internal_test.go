@@ -91,6 +91,31 @@ func (suite *StreamContextTestSuite) TestClose() {
 	tgConnMock.AssertExpectations(suite.T())
 }
 
+func (suite *StreamContextTestSuite) TestLoggerStreamID() {
+	logger := &bindingLogger{}
+
+	streamCtx, err := newStreamContext(context.Background(), logger, suite.connMock)
+	suite.NoError(err)
+
+	suite.Equal(streamCtx.streamID, logger.fields[LoggerFieldStreamID])
+}
+
+type bindingLogger struct {
+	NoopLogger
+
+	fields map[string]string
+}
+
+func (b *bindingLogger) BindStr(name, value string) Logger {
+	if b.fields == nil {
+		b.fields = map[string]string{}
+	}
+
+	b.fields[name] = value
+
+	return b
+}
+
 func TestStreamContext(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &StreamContextTestSuite{})
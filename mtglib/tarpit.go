@@ -0,0 +1,78 @@
+package mtglib
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// DefaultTarpitDuration is a reasonable value of
+	// ProxyOpts.TarpitDuration: long enough to waste a scanner slot,
+	// short enough not to hold a worker for nothing.
+	DefaultTarpitDuration = 10 * time.Second
+
+	// MaxTarpitDuration is a maximal value of ProxyOpts.TarpitDuration.
+	MaxTarpitDuration = time.Minute
+
+	// MaxTarpitConnections is a maximal number of connections which are
+	// tarpitted at the same time. Each of them holds a worker, so
+	// connections above this limit are closed immediately.
+	MaxTarpitConnections = 256
+)
+
+// tarpitAlert — TLS alert record: fatal handshake_failure. Ровно то, что
+// отправил бы настоящий TLS сервер на плохой ClientHello, только медленно.
+var tarpitAlert = [...]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x28}
+
+// doTarpit по байту отдаёт клиенту TLS alert, растягивая его на
+// p.tarpitDuration, и закрывает соединение. Сканер тратит на нас своё
+// время, а мы — только одного воркера и ни одного dial к fronting
+// домену.
+func (p *Proxy) doTarpit(ctx *streamContext) {
+	defer ctx.clientConn.Close()
+
+	if p.tarpitActive.Add(1) > MaxTarpitConnections {
+		p.tarpitActive.Add(-1)
+		ctx.logger.Debug("too many tarpitted connections, close immediately")
+
+		return
+	}
+
+	defer p.tarpitActive.Add(-1)
+
+	p.eventStream.Send(p.ctx, NewEventTarpitted(ctx.streamID, ctx.ClientIP()))
+
+	interval := p.tarpitDuration / time.Duration(len(tarpitAlert))
+
+	// Deadline страхует от клиента, который не читает: запись не
+	// заблокирует воркера дольше, чем на tarpitDuration и ещё один
+	// интервал — запас на опоздание тиков под нагрузкой.
+	ctx.clientConn.SetDeadline(time.Now().Add(p.tarpitDuration + interval)) //nolint: errcheck
+
+	// Настоящий сервер дочитывает запрос. Кроме того, закрытие сокета
+	// с непрочитанными данными отправляет RST, и клиент может не
+	// получить конец alert.
+	go io.Copy(io.Discard, ctx.clientConn) //nolint: errcheck
+
+	// Байты уходят в начале каждого интервала, а после последнего
+	// соединение держится ещё один интервал: все записи укладываются
+	// в deadline. Ticker, а не Reset таймера: задержки между записями
+	// не накапливаются.
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for i := range tarpitAlert {
+		if _, err := ctx.clientConn.Write(tarpitAlert[i : i+1]); err != nil {
+			ctx.logger.DebugError("cannot write to tarpitted connection", err)
+
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
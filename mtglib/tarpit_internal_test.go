@@ -0,0 +1,118 @@
+package mtglib
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// tarpitProxy возвращает прокси с включённым tarpit и пару соединений:
+// клиентское и stream context серверной стороны.
+func tarpitProxy(t *testing.T) (*Proxy, *EventStreamMock, net.Conn, *streamContext) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { clientConn.Close() })
+
+	serverConn, err := listener.Accept()
+	require.NoError(t, err)
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	// Network без ожиданий: любой dial к fronting домену уронит тест.
+	proxy := &Proxy{
+		ctx:            context.Background(),
+		secret:         Secret{Host: "example.com"},
+		config:         DefaultProxyConfig(),
+		network:        &testlib.MtglibNetworkMock{},
+		eventStream:    eventStream,
+		logger:         NoopLogger{},
+		tarpitDuration: 70 * time.Millisecond,
+	}
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn.(*net.TCPConn))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { streamCtx.Close() })
+
+	return proxy, eventStream, clientConn, streamCtx
+}
+
+func tarpittedEvents(eventStream *EventStreamMock) int {
+	count := 0
+
+	for _, call := range eventStream.Calls {
+		if _, ok := call.Arguments.Get(1).(EventTarpitted); ok {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestTarpitInvalidHandshake(t *testing.T) {
+	t.Parallel()
+
+	proxy, eventStream, clientConn, streamCtx := tarpitProxy(t)
+
+	_, err := clientConn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+
+	// Читаем параллельно: непрочитанный запрос клиента превращает
+	// закрытие в RST, и буферизованные байты alert могли бы потеряться.
+	received := make(chan []byte, 1)
+
+	go func() {
+		data := []byte{}
+		buf := make([]byte, 16)
+
+		for {
+			n, err := clientConn.Read(buf)
+			data = append(data, buf[:n]...)
+
+			if err != nil {
+				received <- data
+
+				return
+			}
+		}
+	}()
+
+	started := time.Now()
+
+	assert.False(t, proxy.doFakeTLSHandshake(streamCtx))
+	assert.Equal(t, tarpitAlert[:], <-received)
+	assert.GreaterOrEqual(t, time.Since(started), proxy.tarpitDuration)
+	assert.Equal(t, 1, tarpittedEvents(eventStream))
+	assert.Zero(t, proxy.tarpitActive.Load())
+}
+
+func TestTarpitTooManyConnections(t *testing.T) {
+	t.Parallel()
+
+	proxy, eventStream, clientConn, streamCtx := tarpitProxy(t)
+	proxy.tarpitActive.Store(MaxTarpitConnections)
+
+	proxy.doTarpit(streamCtx)
+
+	data, err := io.ReadAll(clientConn)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	assert.Zero(t, tarpittedEvents(eventStream))
+	assert.EqualValues(t, MaxTarpitConnections, proxy.tarpitActive.Load())
+}
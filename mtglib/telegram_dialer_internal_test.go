@@ -0,0 +1,281 @@
+package mtglib
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenPipeConn имитирует протухшее соединение из пула.
+type brokenPipeConn struct {
+	essentials.Conn
+}
+
+func (c brokenPipeConn) Write(_ []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+// pipeConn — не TCP соединение, как у dialer поверх произвольного
+// транспорта.
+type pipeConn struct {
+	net.Conn
+}
+
+func (c pipeConn) CloseRead() error  { return nil }
+func (c pipeConn) CloseWrite() error { return nil }
+
+// fakeTelegramDialer отдаёт TCP соединения по loopback, ошибки dial
+// задаются по DC.
+type fakeTelegramDialer struct {
+	t *testing.T
+
+	mutex          sync.Mutex
+	failDCs        map[int]bool
+	recentlyFailed map[int]bool
+	brokenPipe     bool
+	pipe           bool
+	dialed         []int
+	direct         []int
+	excluded       [][]int
+}
+
+func (f *fakeTelegramDialer) Dial(_ context.Context, dc int) (essentials.Conn, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.dialed = append(f.dialed, dc)
+
+	if f.failDCs[dc] {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	conn := f.conn()
+	if f.brokenPipe {
+		return brokenPipeConn{Conn: conn}, nil
+	}
+
+	return conn, nil
+}
+
+func (f *fakeTelegramDialer) DialDirect(_ context.Context, dc int) (essentials.Conn, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.direct = append(f.direct, dc)
+
+	return f.conn(), nil
+}
+
+func (f *fakeTelegramDialer) conn() essentials.Conn {
+	if f.pipe {
+		client, server := net.Pipe()
+
+		go io.Copy(io.Discard, server) //nolint: errcheck
+
+		f.t.Cleanup(func() {
+			client.Close()
+			server.Close()
+		})
+
+		return pipeConn{Conn: client}
+	}
+
+	client, server := tcpPair(f.t)
+
+	f.t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	return client
+}
+
+func (f *fakeTelegramDialer) IsKnownDC(dc int) bool      { return dc >= 1 && dc <= 5 }
+func (f *fakeTelegramDialer) RecentlyFailed(dc int) bool { return f.recentlyFailed[dc] }
+func (f *fakeTelegramDialer) GetFallbackDC() int         { return 2 }
+
+func (f *fakeTelegramDialer) GetFallbackDCExcluding(dcs ...int) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.excluded = append(f.excluded, append([]int{}, dcs...))
+
+	return 4
+}
+
+func (f *fakeTelegramDialer) Calls() ([]int, []int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]int{}, f.dialed...), append([]int{}, f.direct...)
+}
+
+func newTelegramDialerProxy(t *testing.T, dialer TelegramDialer, eventStream EventStream) *Proxy {
+	t.Helper()
+
+	networkMock := &testlib.MtglibNetworkMock{}
+	networkMock.On("WarmUp", mock.Anything).Maybe()
+
+	proxy, err := NewProxy(ProxyOpts{
+		Secret:              GenerateSecret("example.com"),
+		Network:             networkMock,
+		TelegramDialer:      dialer,
+		AntiReplayCache:     mapAntiReplayCache{},
+		IPBlocklist:         noopIPBlocklist{},
+		IPAllowlist:         allowAllIPBlocklist{},
+		EventStream:         eventStream,
+		Logger:              NoopLogger{},
+		FallbackOnDialError: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(proxy.Shutdown)
+
+	return proxy
+}
+
+func callTelegram(t *testing.T, proxy *Proxy, dc int) (*streamContext, error) {
+	t.Helper()
+
+	clientConn, serverConn := tcpPair(t)
+
+	t.Cleanup(func() { clientConn.Close() })
+
+	streamCtx, err := newStreamContext(proxy.ctx, proxy.logger, serverConn)
+	require.NoError(t, err)
+
+	t.Cleanup(streamCtx.Close)
+
+	streamCtx.dc = dc
+
+	return streamCtx, proxy.doTelegramCall(streamCtx)
+}
+
+func TestTelegramDialerFallback(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	dialer := &fakeTelegramDialer{t: t, failDCs: map[int]bool{2: true}}
+	proxy := newTelegramDialerProxy(t, dialer, eventStream)
+
+	streamCtx, err := callTelegram(t, proxy, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, streamCtx.telegramConn)
+	assert.Equal(t, 2, streamCtx.dc)
+	assert.Equal(t, 4, streamCtx.telegramDC)
+
+	dialed, direct := dialer.Calls()
+	assert.Equal(t, []int{2, 4}, dialed)
+	assert.Empty(t, direct)
+
+	dialer.failDCs[4] = true
+
+	_, err = callTelegram(t, proxy, 2)
+	assert.ErrorContains(t, err, "fallback DC 4 also failed")
+}
+
+func TestTelegramDialerBrokenPipeRetry(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	dialer := &fakeTelegramDialer{t: t, brokenPipe: true}
+	proxy := newTelegramDialerProxy(t, dialer, eventStream)
+
+	streamCtx, err := callTelegram(t, proxy, 3)
+	require.NoError(t, err)
+	assert.NotNil(t, streamCtx.telegramConn)
+
+	dialed, direct := dialer.Calls()
+	assert.Equal(t, []int{3}, dialed)
+	assert.Equal(t, []int{3}, direct)
+
+	retried := false
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventTelegramHandshakeRetried); ok {
+			retried = true
+
+			assert.Equal(t, 3, evt.DC)
+			assert.False(t, evt.Failed)
+		}
+	}
+
+	assert.True(t, retried)
+}
+
+func TestTelegramDialerSkipsBuiltin(t *testing.T) {
+	t.Parallel()
+
+	dialer := &fakeTelegramDialer{t: t}
+	proxy := newTelegramDialerProxy(t, dialer, &EventStreamMock{})
+
+	assert.Nil(t, proxy.telegram)
+	assert.Same(t, dialer, proxy.telegramDialer)
+	assert.Nil(t, proxy.GetPoolStats())
+	assert.Nil(t, proxy.GetDialStats())
+	assert.True(t, proxy.TelegramReachable())
+}
+
+func TestTelegramDialerFallbackExcludesSkippedDC(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	// DC 2 недавно не отвечал, его fallback тоже не отвечает: второй
+	// fallback не должен вернуться к DC 2.
+	dialer := &fakeTelegramDialer{
+		t:              t,
+		failDCs:        map[int]bool{4: true},
+		recentlyFailed: map[int]bool{2: true},
+	}
+	proxy := newTelegramDialerProxy(t, dialer, eventStream)
+
+	_, err := callTelegram(t, proxy, 2)
+	require.Error(t, err)
+
+	dialer.mutex.Lock()
+	defer dialer.mutex.Unlock()
+
+	assert.Equal(t, [][]int{{2}, {2, 4}}, dialer.excluded)
+}
+
+func TestTelegramDialerNonTCPConn(t *testing.T) {
+	t.Parallel()
+
+	eventStream := &EventStreamMock{}
+	eventStream.On("Send", mock.Anything, mock.Anything)
+
+	dialer := &fakeTelegramDialer{t: t, pipe: true}
+	proxy := newTelegramDialerProxy(t, dialer, eventStream)
+
+	streamCtx, err := callTelegram(t, proxy, 3)
+	require.NoError(t, err)
+	assert.NotNil(t, streamCtx.telegramConn)
+
+	connected := false
+
+	for _, call := range eventStream.Calls {
+		if evt, ok := call.Arguments.Get(1).(EventConnectedToDC); ok {
+			connected = true
+
+			assert.Equal(t, 3, evt.DC)
+			assert.Nil(t, evt.RemoteIP)
+		}
+	}
+
+	assert.True(t, connected)
+}
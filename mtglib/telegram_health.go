@@ -0,0 +1,67 @@
+package mtglib
+
+import (
+	"time"
+
+	"github.com/9seconds/mtg/v2/mtglib/internal/telegram"
+)
+
+// TelegramReachable reports if Telegram is reachable according to a
+// periodic health check (please see ProxyOpts.TelegramHealthCheckInterval).
+// It returns false only if no DC was reachable for
+// ProxyOpts.TelegramHealthFailureThreshold check rounds in a row.
+//
+// If a health check is disabled, this method always returns true.
+func (p *Proxy) TelegramReachable() bool {
+	return p.telegramHealthFailures.Load() < p.telegramHealthThreshold
+}
+
+// checkTelegramHealth проверяет все DC сразу и затем каждые interval,
+// пока прокси не остановлен.
+func (p *Proxy) checkTelegramHealth(checker *telegram.DCHealthChecker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		results := checker.CheckAll(p.ctx)
+
+		// Отменённый контекст роняет все проверки, но Telegram тут ни
+		// при чём.
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		p.recordTelegramHealth(results)
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordTelegramHealth учитывает один раунд проверки: хотя бы один
+// доступный DC сбрасывает счётчик неудачных раундов.
+func (p *Proxy) recordTelegramHealth(results []telegram.DCHealth) {
+	logger := p.logger.Named("telegram-health")
+
+	for _, res := range results {
+		if res.Reachable() {
+			if p.telegramHealthFailures.Swap(0) >= p.telegramHealthThreshold {
+				logger.Info("Telegram is reachable again")
+			}
+
+			return
+		}
+	}
+
+	failures := p.telegramHealthFailures.Add(1)
+	logger = logger.BindInt("failures", int(failures))
+
+	if failures == p.telegramHealthThreshold {
+		logger.Warning("no Telegram DC is reachable, proxy is unhealthy")
+	} else {
+		logger.Debug("no Telegram DC is reachable")
+	}
+}
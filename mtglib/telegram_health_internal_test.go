@@ -0,0 +1,38 @@
+package mtglib
+
+import (
+	"io"
+	"testing"
+
+	"github.com/9seconds/mtg/v2/mtglib/internal/telegram"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramReachable(t *testing.T) {
+	t.Parallel()
+
+	proxy := &Proxy{
+		logger:                  NoopLogger{},
+		telegramHealthThreshold: 2,
+	}
+
+	down := []telegram.DCHealth{{DC: 1, Err: io.EOF}, {DC: 2, Err: io.EOF}}
+	up := []telegram.DCHealth{{DC: 1, Err: io.EOF}, {DC: 2}}
+
+	assert.True(t, proxy.TelegramReachable())
+
+	proxy.recordTelegramHealth(down)
+	assert.True(t, proxy.TelegramReachable())
+
+	proxy.recordTelegramHealth(down)
+	assert.False(t, proxy.TelegramReachable())
+
+	proxy.recordTelegramHealth(down)
+	assert.False(t, proxy.TelegramReachable())
+
+	proxy.recordTelegramHealth(up)
+	assert.True(t, proxy.TelegramReachable())
+
+	proxy.recordTelegramHealth(down)
+	assert.True(t, proxy.TelegramReachable())
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package mtglib
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpiOptSynData — флаг TCPI_OPT_SYN_DATA в tcp_info.tcpi_options: данные,
+// отправленные в SYN (TCP Fast Open), были подтверждены сервером.
+const tcpiOptSynData = 0x20
+
+// connUsedTFO сообщает, было ли соединение установлено с TFO cookie.
+// Проверка best-effort: соединения, не дающие доступ к сокету (SOCKS5,
+// connection pool), считаются установленными без TFO.
+func connUsedTFO(conn any) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	var (
+		info    *unix.TCPInfo
+		infoErr error
+	)
+
+	if err := raw.Control(func(fd uintptr) {
+		info, infoErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil || infoErr != nil {
+		return false
+	}
+
+	return info.Options&tcpiOptSynData != 0
+}
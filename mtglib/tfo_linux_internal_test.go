@@ -0,0 +1,43 @@
+//go:build linux
+
+package mtglib
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnUsedTFO(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	conn, err := net.Dial("tcp4", listener.Addr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	// Обычный connect() без TFO.
+	assert.False(t, connUsedTFO(conn))
+
+	// Соединения без доступа к сокету (например, через SOCKS5).
+	client, server := net.Pipe()
+
+	defer client.Close()
+	defer server.Close()
+
+	assert.False(t, connUsedTFO(client))
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package mtglib
+
+// connUsedTFO всегда возвращает false: вне Linux ядро не сообщает,
+// было ли соединение установлено с TFO cookie.
+func connUsedTFO(_ any) bool {
+	return false
+}
@@ -0,0 +1,58 @@
+package mtglib
+
+import (
+	"net"
+	"sort"
+	"time"
+)
+
+// TimeSkewnessOverride sets a FakeTLS time skewness tolerance for clients
+// from a given network. Please see ProxyOpts.TolerateTimeSkewness.
+type TimeSkewnessOverride struct {
+	// Network is a network of client IP addresses.
+	Network *net.IPNet
+
+	// Tolerance is a time skewness tolerance for this network.
+	Tolerance time.Duration
+}
+
+// timeSkewness picks a time skewness tolerance for a client IP.
+type timeSkewness struct {
+	fallback  time.Duration
+	overrides []TimeSkewnessOverride
+}
+
+// For returns a tolerance of the most specific override which contains ip,
+// or a default one.
+func (t timeSkewness) For(ip net.IP) time.Duration {
+	for _, v := range t.overrides {
+		if v.Network.Contains(ip) {
+			return v.Tolerance
+		}
+	}
+
+	return t.fallback
+}
+
+func newTimeSkewness(fallback time.Duration, overrides []TimeSkewnessOverride) timeSkewness {
+	sorted := make([]TimeSkewnessOverride, 0, len(overrides))
+
+	for _, v := range overrides {
+		if v.Network != nil && v.Tolerance > 0 {
+			sorted = append(sorted, v)
+		}
+	}
+
+	// Более длинный префикс — более специфичная сеть, она и побеждает.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		left, _ := sorted[i].Network.Mask.Size()
+		right, _ := sorted[j].Network.Mask.Size()
+
+		return left > right
+	})
+
+	return timeSkewness{
+		fallback:  fallback,
+		overrides: sorted,
+	}
+}
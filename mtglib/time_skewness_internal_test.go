@@ -0,0 +1,46 @@
+package mtglib
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseCIDR(t *testing.T, value string) *net.IPNet {
+	t.Helper()
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ipNet
+}
+
+func TestTimeSkewnessFor(t *testing.T) {
+	t.Parallel()
+
+	skewness := newTimeSkewness(3*time.Second, []TimeSkewnessOverride{
+		{Network: mustParseCIDR(t, "10.0.0.0/8"), Tolerance: 30 * time.Second},
+		{Network: mustParseCIDR(t, "10.1.0.0/16"), Tolerance: time.Minute},
+		{Network: mustParseCIDR(t, "2001:db8::/32"), Tolerance: 10 * time.Second},
+		{Network: mustParseCIDR(t, "192.168.0.0/16")},
+		{Tolerance: time.Hour},
+	})
+
+	testData := map[string]time.Duration{
+		"10.2.0.1":        30 * time.Second,
+		"10.1.2.3":        time.Minute,
+		"2001:db8::1":     10 * time.Second,
+		"192.168.1.1":     3 * time.Second,
+		"8.8.8.8":         3 * time.Second,
+		"2001:db9::1":     3 * time.Second,
+		"::ffff:10.1.0.1": time.Minute,
+	}
+
+	for ip, expected := range testData {
+		assert.Equal(t, expected, skewness.For(net.ParseIP(ip)), ip)
+	}
+}
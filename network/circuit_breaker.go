@@ -74,6 +74,14 @@ func (c *cooldownDialer) DialContext(ctx context.Context,
 	return conn, err //nolint: wrapcheck
 }
 
+// available сообщает, что прокси не на "остывании" и его можно пробовать.
+func (c *cooldownDialer) available() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cooldownUntil.IsZero() || !time.Now().Before(c.cooldownUntil)
+}
+
 func newCooldownDialer(baseDialer Dialer,
 	openThreshold uint32, reconnectTimeout time.Duration,
 ) Dialer {
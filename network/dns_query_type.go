@@ -0,0 +1,121 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DNSQueryTypeBoth resolves both A and AAAA records. This is a default.
+	DNSQueryTypeBoth = "both"
+
+	// DNSQueryTypeA resolves only A records, AAAA queries are never sent.
+	// This makes sense for IPv4-only hosts.
+	DNSQueryTypeA = "a"
+
+	// DNSQueryTypeAAAA resolves only AAAA records, A queries are never sent.
+	// This makes sense for IPv6-only hosts.
+	DNSQueryTypeAAAA = "aaaa"
+)
+
+// queryTypeResolver пропускает DNS запросы выключенного типа, а не просто
+// сортирует результаты, как prefer-ip: бесполезный AAAA на IPv4-only
+// хосте стоит времени и засоряет кэш недостижимыми адресами.
+type queryTypeResolver struct {
+	dnsResolverInterface
+
+	skipA       bool
+	skipAAAA    bool
+	skippedA    atomic.Uint64
+	skippedAAAA atomic.Uint64
+}
+
+func (q *queryTypeResolver) LookupA(hostname string) []string {
+	return q.LookupACtx(context.Background(), hostname)
+}
+
+func (q *queryTypeResolver) LookupACtx(ctx context.Context, hostname string) []string {
+	if q.skipA {
+		q.skippedA.Add(1)
+
+		return nil
+	}
+
+	return q.dnsResolverInterface.LookupACtx(ctx, hostname)
+}
+
+func (q *queryTypeResolver) LookupAAAA(hostname string) []string {
+	return q.LookupAAAACtx(context.Background(), hostname)
+}
+
+func (q *queryTypeResolver) LookupAAAACtx(ctx context.Context, hostname string) []string {
+	if q.skipAAAA {
+		q.skippedAAAA.Add(1)
+
+		return nil
+	}
+
+	return q.dnsResolverInterface.LookupAAAACtx(ctx, hostname)
+}
+
+func (q *queryTypeResolver) LookupBoth(hostname string) []string {
+	return q.LookupBothCtx(context.Background(), hostname)
+}
+
+// LookupBothCtx делает только разрешённый запрос, без goroutine.
+func (q *queryTypeResolver) LookupBothCtx(ctx context.Context, hostname string) []string {
+	switch {
+	case q.skipAAAA:
+		q.skippedAAAA.Add(1)
+
+		return q.dnsResolverInterface.LookupACtx(ctx, hostname)
+	case q.skipA:
+		q.skippedA.Add(1)
+
+		return q.dnsResolverInterface.LookupAAAACtx(ctx, hostname)
+	}
+
+	return q.dnsResolverInterface.LookupBothCtx(ctx, hostname)
+}
+
+// WarmUp прогревает кэш через LookupBoth этой обёртки, иначе
+// вложенный resolver отправит и выключенные запросы.
+func (q *queryTypeResolver) WarmUp(hostnames []string) {
+	var wg sync.WaitGroup
+
+	wg.Add(len(hostnames))
+
+	for _, hostname := range hostnames {
+		go func(h string) {
+			defer wg.Done()
+			q.LookupBoth(h)
+		}(hostname)
+	}
+
+	wg.Wait()
+}
+
+// Skipped возвращает количество пропущенных A и AAAA запросов.
+func (q *queryTypeResolver) Skipped() (uint64, uint64) {
+	return q.skippedA.Load(), q.skippedAAAA.Load()
+}
+
+func newQueryTypeResolver(resolver dnsResolverInterface, queryType string) (*queryTypeResolver, error) {
+	rv := &queryTypeResolver{
+		dnsResolverInterface: resolver,
+	}
+
+	switch queryType {
+	case DNSQueryTypeBoth, "":
+	case DNSQueryTypeA:
+		rv.skipAAAA = true
+	case DNSQueryTypeAAAA:
+		rv.skipA = true
+	default:
+		return nil, fmt.Errorf("unsupported dns query type %q", queryType)
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,96 @@
+package network
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingResolver считает отправленные запросы каждого типа.
+type countingResolver struct {
+	dnsResolverInterface
+
+	mutex   sync.Mutex
+	queries map[string]int
+}
+
+func (c *countingResolver) query(qtype string, ips ...string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.queries[qtype]++
+
+	return ips
+}
+
+func (c *countingResolver) LookupACtx(_ context.Context, _ string) []string {
+	return c.query("A", "10.0.0.1")
+}
+
+func (c *countingResolver) LookupAAAACtx(_ context.Context, _ string) []string {
+	return c.query("AAAA", "2001:db8::1")
+}
+
+func (c *countingResolver) LookupBothCtx(ctx context.Context, hostname string) []string {
+	return append(c.LookupACtx(ctx, hostname), c.LookupAAAACtx(ctx, hostname)...)
+}
+
+func TestQueryTypeResolver(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]struct {
+		ips                   []string
+		queries               map[string]int
+		skippedA, skippedAAAA uint64
+	}{
+		DNSQueryTypeBoth: {
+			ips:     []string{"10.0.0.1", "2001:db8::1"},
+			queries: map[string]int{"A": 3, "AAAA": 3},
+		},
+		DNSQueryTypeA: {
+			ips:         []string{"10.0.0.1"},
+			queries:     map[string]int{"A": 3},
+			skippedAAAA: 3,
+		},
+		DNSQueryTypeAAAA: {
+			ips:      []string{"2001:db8::1"},
+			queries:  map[string]int{"AAAA": 3},
+			skippedA: 3,
+		},
+	}
+
+	for name, value := range testData {
+		queryType, expected := name, value
+
+		t.Run(queryType, func(t *testing.T) {
+			t.Parallel()
+
+			counting := &countingResolver{queries: map[string]int{}}
+
+			resolver, err := newQueryTypeResolver(counting, queryType)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected.ips, resolver.LookupBoth("example.com"))
+
+			resolver.LookupA("example.com")
+			resolver.LookupAAAA("example.com")
+			resolver.WarmUp([]string{"example.com"})
+
+			assert.Equal(t, expected.queries, counting.queries)
+
+			skippedA, skippedAAAA := resolver.Skipped()
+			assert.Equal(t, expected.skippedA, skippedA)
+			assert.Equal(t, expected.skippedAAAA, skippedAAAA)
+		})
+	}
+}
+
+func TestQueryTypeResolverUnknown(t *testing.T) {
+	t.Parallel()
+
+	_, err := newQueryTypeResolver(&countingResolver{}, "mx")
+	assert.Error(t, err)
+}
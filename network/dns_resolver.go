@@ -1,12 +1,16 @@
 package network
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/miekg/dns"
@@ -23,17 +27,74 @@ const (
 	// RFC 8484 не ограничивает ответ, но реальные ответы < 4 КБ.
 	// 64 КБ — верхняя граница UDP DNS (с EDNS0) с большим запасом.
 	maxDoHResponseSize = 64 * 1024
+
+	// doHQueueTimeout — сколько запрос ждёт свободного слота, если
+	// одновременных DoH запросов уже maxInFlight. Короткий шторм
+	// переживается ожиданием, длинный — быстрым отказом.
+	doHQueueTimeout = 200 * time.Millisecond
 )
 
+var errDoHQueriesLimited = errors.New("too many DoH queries in flight")
+
 type dnsResolver struct {
 	dohServer  string
 	httpClient *http.Client
 	cache      *LRUDNSCache
 	cleanupStop chan struct{} // Stop channel for cleanup goroutine
+
+	// inFlight — семафор одновременных DoH запросов, nil — без
+	// ограничения. limited считает запросы, заставшие семафор полным.
+	inFlight chan struct{}
+	limited  atomic.Uint64
+
+	// calls — DoH запросы в полёте по ключу кэша, см. lookup.
+	callsMutex sync.Mutex
+	calls      map[string]*dohCall
+}
+
+// acquire занимает слот семафора. Если слотов нет, запрос ждёт не
+// дольше doHQueueTimeout и ctx.
+func (d *dnsResolver) acquire(ctx context.Context) error {
+	if d.inFlight == nil {
+		return nil
+	}
+
+	select {
+	case d.inFlight <- struct{}{}:
+		return nil
+	default:
+	}
+
+	d.limited.Add(1)
+
+	timer := time.NewTimer(doHQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case d.inFlight <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errDoHQueriesLimited
+	case <-ctx.Done():
+		return ctx.Err() //nolint: wrapcheck
+	}
+}
+
+func (d *dnsResolver) release() {
+	if d.inFlight != nil {
+		<-d.inFlight
+	}
+}
+
+// Limited returns a number of DoH queries which found all in-flight
+// slots busy, whether they got a slot after a short wait or failed.
+func (d *dnsResolver) Limited() uint64 {
+	return d.limited.Load()
 }
 
-// doQuery выполняет DNS-over-HTTPS запрос
-func (d *dnsResolver) doQuery(hostname string, qtype uint16) ([]dns.RR, error) {
+// doQuery выполняет DNS-over-HTTPS запрос. Запрос отменяется по ctx,
+// но не позже DNSTimeout (таймаут HTTP-клиента).
+func (d *dnsResolver) doQuery(ctx context.Context, hostname string, qtype uint16) ([]dns.RR, error) {
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(hostname), qtype)
 	msg.RecursionDesired = true
@@ -48,13 +109,18 @@ func (d *dnsResolver) doQuery(hostname string, qtype uint16) ([]dns.RR, error) {
 		d.dohServer,
 		base64.RawURLEncoding.EncodeToString(packed))
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/dns-message")
 
+	if err := d.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("DoH request is not sent: %w", err)
+	}
+	defer d.release()
+
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("DoH request failed: %w", err)
@@ -79,66 +145,152 @@ func (d *dnsResolver) doQuery(hostname string, qtype uint16) ([]dns.RR, error) {
 }
 
 func (d *dnsResolver) LookupA(hostname string) []string {
-	key := "\x00" + hostname
+	return d.LookupACtx(context.Background(), hostname)
+}
+
+// LookupACtx resolves A records of a hostname. A query is cancelled
+// when ctx is done.
+func (d *dnsResolver) LookupACtx(ctx context.Context, hostname string) []string {
+	return d.lookup(ctx, "LookupA", "\x00"+hostname, hostname, dns.TypeA)
+}
+
+func (d *dnsResolver) LookupAAAA(hostname string) []string {
+	return d.LookupAAAACtx(context.Background(), hostname)
+}
+
+// LookupAAAACtx resolves AAAA records of a hostname. A query is cancelled
+// when ctx is done.
+func (d *dnsResolver) LookupAAAACtx(ctx context.Context, hostname string) []string {
+	return d.lookup(ctx, "LookupAAAA", "\x01"+hostname, hostname, dns.TypeAAAA)
+}
+
+// dohCall — DoH запрос, результат которого ждут несколько lookup.
+type dohCall struct {
+	done    chan struct{}
+	ips     []string
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
 
+// lookup берёт адреса из кэша или делает DoH запрос. Одновременные
+// промахи по одному ключу кэша объединяются (singleflight): на (имя,
+// тип) в полёте не больше одного запроса, остальные ждут его
+// результата. Запрос отменяется, только когда ушли все ожидающие.
+func (d *dnsResolver) lookup(ctx context.Context, operation, key, hostname string, qtype uint16) []string {
 	// Check cache first
 	if cached := d.cache.Get(key); cached != nil {
 		return cached.IPs
 	}
 
-	// Cache miss - perform DNS query
-	var ips []string
-	var ttl uint32 = defaultDNSTTL
+	call := d.joinCall(ctx, key, hostname, qtype)
 
-	recs, err := d.doQuery(hostname, dns.TypeA)
-	if err != nil {
-		logDNSError("LookupA", hostname, err)
-		return ips
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		d.leaveCall(key, call)
+		logDNSError(operation, hostname, ctx.Err())
+
+		return nil
 	}
 
-	for _, rr := range recs {
-		if a, ok := rr.(*dns.A); ok {
-			ips = append(ips, a.A.String())
-			// Extract TTL from DNS response
-			if rr.Header().Ttl > 0 {
-				ttl = normalizeTTL(rr.Header().Ttl)
-			}
-		}
+	if call.err != nil {
+		logDNSError(operation, hostname, call.err)
+
+		return nil
 	}
 
-	// Store in cache with TTL
-	if len(ips) > 0 {
-		d.cache.Set(key, ips, ttl)
+	// У каждого ожидающего своя копия: вызывающий код может
+	// перемешивать адреса.
+	return slices.Clone(call.ips)
+}
+
+// joinCall присоединяется к запросу ключа в полёте или запускает новый.
+func (d *dnsResolver) joinCall(ctx context.Context, key, hostname string, qtype uint16) *dohCall {
+	d.callsMutex.Lock()
+	defer d.callsMutex.Unlock()
+
+	if call, ok := d.calls[key]; ok {
+		call.waiters++
+
+		return call
 	}
 
-	return ips
+	if d.calls == nil {
+		d.calls = map[string]*dohCall{}
+	}
+
+	// Запрос живёт, пока его кто-то ждёт, а не пока жив ctx первого.
+	queryCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &dohCall{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+	d.calls[key] = call
+
+	go func() {
+		defer cancel()
+
+		call.ips, call.err = d.resolve(queryCtx, key, hostname, qtype)
+
+		d.callsMutex.Lock()
+		if d.calls[key] == call {
+			delete(d.calls, key)
+		}
+		d.callsMutex.Unlock()
+
+		close(call.done)
+	}()
+
+	return call
 }
 
-func (d *dnsResolver) LookupAAAA(hostname string) []string {
-	key := "\x01" + hostname
+// leaveCall снимает ожидающего. Последний ушедший отменяет запрос и
+// убирает его из полёта, чтобы новые lookup не получили отмену.
+func (d *dnsResolver) leaveCall(key string, call *dohCall) {
+	d.callsMutex.Lock()
+	defer d.callsMutex.Unlock()
 
-	// Check cache first
-	if cached := d.cache.Get(key); cached != nil {
-		return cached.IPs
+	call.waiters--
+
+	if call.waiters > 0 {
+		return
 	}
 
-	// Cache miss - perform DNS query
-	var ips []string
-	var ttl uint32 = defaultDNSTTL
+	call.cancel()
+
+	if d.calls[key] == call {
+		delete(d.calls, key)
+	}
+}
 
-	recs, err := d.doQuery(hostname, dns.TypeAAAA)
+// resolve делает DoH запрос и кладёт найденные адреса в кэш.
+func (d *dnsResolver) resolve(ctx context.Context, key, hostname string, qtype uint16) ([]string, error) {
+	recs, err := d.doQuery(ctx, hostname, qtype)
 	if err != nil {
-		logDNSError("LookupAAAA", hostname, err)
-		return ips
+		return nil, err
 	}
 
+	var ips []string
+	var ttl uint32 = defaultDNSTTL
+
 	for _, rr := range recs {
-		if aaaa, ok := rr.(*dns.AAAA); ok {
-			ips = append(ips, aaaa.AAAA.String())
-			// Extract TTL from DNS response
-			if rr.Header().Ttl > 0 {
-				ttl = normalizeTTL(rr.Header().Ttl)
-			}
+		// CNAME и прочие записи ответа пропускаются
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+
+		switch record := rr.(type) {
+		case *dns.A:
+			ips = append(ips, record.A.String())
+		case *dns.AAAA:
+			ips = append(ips, record.AAAA.String())
+		}
+
+		// Extract TTL from DNS response
+		if rr.Header().Ttl > 0 {
+			ttl = normalizeTTL(rr.Header().Ttl)
 		}
 	}
 
@@ -147,7 +299,7 @@ func (d *dnsResolver) LookupAAAA(hostname string) []string {
 		d.cache.Set(key, ips, ttl)
 	}
 
-	return ips
+	return ips, nil
 }
 
 // normalizeTTL ensures TTL is within acceptable bounds
@@ -170,6 +322,11 @@ func (d *dnsResolver) GetCacheMetrics() DNSCacheMetrics {
 // This reduces latency by 30-50% compared to sequential lookups.
 // Returns IPv4 addresses first, then IPv6.
 func (d *dnsResolver) LookupBoth(hostname string) []string {
+	return d.LookupBothCtx(context.Background(), hostname)
+}
+
+// LookupBothCtx is LookupBoth which cancels both queries when ctx is done.
+func (d *dnsResolver) LookupBothCtx(ctx context.Context, hostname string) []string {
 	var (
 		ipv4 []string
 		ipv6 []string
@@ -181,13 +338,13 @@ func (d *dnsResolver) LookupBoth(hostname string) []string {
 	// Parallel A record lookup
 	go func() {
 		defer wg.Done()
-		ipv4 = d.LookupA(hostname)
+		ipv4 = d.LookupACtx(ctx, hostname)
 	}()
 
 	// Parallel AAAA record lookup
 	go func() {
 		defer wg.Done()
-		ipv6 = d.LookupAAAA(hostname)
+		ipv6 = d.LookupAAAACtx(ctx, hostname)
 	}()
 
 	wg.Wait()
@@ -200,13 +357,21 @@ func (d *dnsResolver) LookupBoth(hostname string) []string {
 	return result
 }
 
-func newDNSResolver(hostname string, httpClient *http.Client) *dnsResolver {
+// newDNSResolver создаёт DoH резолвер. Если cache равен nil, создаётся
+// новый кэш. Кэш предыдущего резолвера можно передать, чтобы он остался
+// тёплым при смене режима DNS: ключи кэша у обоих резолверов одинаковые.
+//
+// maxInFlight ограничивает число одновременных DoH запросов, 0 — без
+// ограничения.
+func newDNSResolver(hostname string, httpClient *http.Client, cache *LRUDNSCache, maxInFlight int) *dnsResolver {
 	if net.ParseIP(hostname).To4() == nil {
 		// the hostname is an IPv6 address
 		hostname = fmt.Sprintf("[%s]", hostname)
 	}
 
-	cache := NewLRUDNSCache(defaultDNSCacheSize)
+	if cache == nil {
+		cache = NewLRUDNSCache(defaultDNSCacheSize)
+	}
 
 	resolver := &dnsResolver{
 		dohServer:  hostname,
@@ -214,6 +379,10 @@ func newDNSResolver(hostname string, httpClient *http.Client) *dnsResolver {
 		cache:      cache,
 	}
 
+	if maxInFlight > 0 {
+		resolver.inFlight = make(chan struct{}, maxInFlight)
+	}
+
 	// Start background cleanup of expired entries every 5 minutes
 	resolver.cleanupStop = cache.StartCleanupLoop(5 * time.Minute)
 
@@ -1,11 +1,14 @@
 package network
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -43,7 +46,7 @@ func (suite *DNSResolverTestSuite) TestLookupAAAA() {
 func (suite *DNSResolverTestSuite) SetupTest() {
 	suite.d = newDNSResolver("1.1.1.1", &http.Client{
 		Timeout: 5 * time.Second, // Таймаут для предотвращения зависания
-	})
+	}, nil, 0)
 }
 
 func TestDNSResolver(t *testing.T) {
@@ -53,3 +56,88 @@ func TestDNSResolver(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &DNSResolverTestSuite{})
 }
+
+func TestDNSResolverSharedCache(t *testing.T) {
+	t.Parallel()
+
+	cache := NewLRUDNSCache(0)
+
+	// DoH сервер недоступен: всё, что вернул резолвер, взято из кэша.
+	doh := newDNSResolver("127.0.0.1", &http.Client{
+		Timeout: 100 * time.Millisecond,
+	}, cache, 0)
+
+	doh.cache.Set("\x00mtg.invalid", []string{"10.0.0.10"}, 3600)
+	doh.cache.Set("\x01mtg.invalid", []string{"2001:db8::10"}, 3600)
+
+	assert.Equal(t, []string{"10.0.0.10"}, doh.LookupA("mtg.invalid"))
+
+	doh.Stop()
+
+	plain := newPlainDNSResolver(cache)
+	defer plain.Stop()
+
+	assert.Equal(t, []string{"10.0.0.10"}, plain.LookupA("mtg.invalid"))
+	assert.Equal(t, []string{"2001:db8::10"}, plain.LookupAAAA("mtg.invalid"))
+
+	metrics := plain.GetCacheMetrics()
+	assert.EqualValues(t, 3, metrics.Hits)
+	assert.Zero(t, metrics.Misses)
+	assert.Equal(t, 2, metrics.Size)
+}
+
+// blockingRoundTripper держит каждый запрос, пока не закрыт release.
+type blockingRoundTripper struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b blockingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	b.started <- struct{}{}
+
+	select {
+	case <-b.release:
+	case <-req.Context().Done():
+	}
+
+	return nil, context.Canceled
+}
+
+func TestDNSResolverInFlightLimit(t *testing.T) {
+	t.Parallel()
+
+	transport := blockingRoundTripper{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	doh := newDNSResolver("127.0.0.1", &http.Client{Transport: transport}, nil, 1)
+
+	defer doh.Stop()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		doh.LookupA("first.invalid")
+	}()
+
+	<-transport.started
+
+	// Единственный слот занят: запрос ждёт недолго и не уходит.
+	started := time.Now()
+	_, err := doh.doQuery(context.Background(), "second.invalid", dns.TypeA)
+
+	assert.ErrorIs(t, err, errDoHQueriesLimited)
+	assert.GreaterOrEqual(t, time.Since(started), doHQueueTimeout)
+	assert.EqualValues(t, 1, doh.Limited())
+
+	close(transport.release)
+	<-done
+
+	// Слот освобождён: запрос снова доходит до транспорта.
+	go doh.LookupA("third.invalid")
+
+	<-transport.started
+	assert.EqualValues(t, 1, doh.Limited())
+}
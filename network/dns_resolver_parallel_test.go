@@ -1,10 +1,18 @@
 package network
 
 import (
+	"context"
+	"encoding/base64"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 // TestDNSResolver_LookupBoth_CacheOnly проверяет параллельный lookup с кэшем
@@ -149,3 +157,166 @@ func BenchmarkDNSResolver_Sequential(b *testing.B) {
 		_ = resolver.LookupAAAA("bench.com")
 	}
 }
+
+// TestDNSResolver_LookupCtx_Deadline проверяет, что DoH-запрос
+// отменяется по дедлайну caller, не дожидаясь таймаута HTTP-клиента.
+func TestDNSResolver_LookupCtx_Deadline(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 10 * time.Second
+
+	resolver := &dnsResolver{
+		dohServer:  strings.TrimPrefix(server.URL, "https://"),
+		cache:      NewLRUDNSCache(100),
+		httpClient: client,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := resolver.LookupBothCtx(ctx, "example.com")
+	duration := time.Since(start)
+
+	if len(result) != 0 {
+		t.Errorf("Expected no results, got %v", result)
+	}
+
+	if duration > 5*time.Second {
+		t.Errorf("Lookup ignored context deadline: took %v", duration)
+	}
+}
+
+// TestDNSResolver_Singleflight проверяет, что одновременные промахи
+// кэша по одному имени дают один DoH запрос на тип записи.
+func TestDNSResolver_Singleflight(t *testing.T) {
+	var queriesA, queriesAAAA atomic.Int32
+
+	release := make(chan struct{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		packed, _ := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+
+		query := new(dns.Msg)
+		if err := query.Unpack(packed); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		reply := new(dns.Msg)
+		reply.SetReply(query)
+
+		header := dns.RR_Header{Name: query.Question[0].Name, Class: dns.ClassINET, Ttl: 300}
+
+		switch query.Question[0].Qtype {
+		case dns.TypeA:
+			queriesA.Add(1)
+
+			header.Rrtype = dns.TypeA
+			reply.Answer = append(reply.Answer, &dns.A{Hdr: header, A: net.ParseIP("10.0.0.1")})
+		case dns.TypeAAAA:
+			queriesAAAA.Add(1)
+
+			header.Rrtype = dns.TypeAAAA
+			reply.Answer = append(reply.Answer, &dns.AAAA{Hdr: header, AAAA: net.ParseIP("2001:db8::1")})
+		}
+
+		<-release
+
+		data, _ := reply.Pack()
+		w.Write(data) //nolint: errcheck
+	}))
+	defer server.Close()
+
+	resolver := &dnsResolver{
+		dohServer:  strings.TrimPrefix(server.URL, "https://"),
+		cache:      NewLRUDNSCache(100),
+		httpClient: server.Client(),
+	}
+
+	const numGoroutines = 100
+
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+
+			result := resolver.LookupBoth("burst.example")
+			if len(result) != 2 || result[0] != "10.0.0.1" || result[1] != "2001:db8::1" {
+				t.Errorf("Unexpected result %v", result)
+			}
+		}()
+	}
+
+	// Даём всем goroutine встать в ожидание, пока запросы висят на сервере.
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := queriesA.Load(); n != 1 {
+		t.Errorf("Expected 1 A query, got %d", n)
+	}
+
+	if n := queriesAAAA.Load(); n != 1 {
+		t.Errorf("Expected 1 AAAA query, got %d", n)
+	}
+}
+
+// TestDNSResolver_SingleflightWaiterCancel проверяет, что отмена ctx
+// одного ожидающего не отменяет общий запрос для остальных.
+func TestDNSResolver_SingleflightWaiterCancel(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		packed, _ := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+
+		query := new(dns.Msg)
+		query.Unpack(packed) //nolint: errcheck
+
+		reply := new(dns.Msg)
+		reply.SetReply(query)
+		reply.Answer = append(reply.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("10.0.0.1"),
+		})
+
+		<-release
+
+		data, _ := reply.Pack()
+		w.Write(data) //nolint: errcheck
+	}))
+	defer server.Close()
+
+	resolver := &dnsResolver{
+		dohServer:  strings.TrimPrefix(server.URL, "https://"),
+		cache:      NewLRUDNSCache(100),
+		httpClient: server.Client(),
+	}
+
+	result := make(chan []string)
+
+	go func() {
+		result <- resolver.LookupA("cancel.example")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if ips := resolver.LookupACtx(ctx, "cancel.example"); len(ips) != 0 {
+		t.Errorf("Expected no results for cancelled lookup, got %v", ips)
+	}
+
+	close(release)
+
+	if ips := <-result; len(ips) != 1 || ips[0] != "10.0.0.1" {
+		t.Errorf("Unexpected result %v", ips)
+	}
+}
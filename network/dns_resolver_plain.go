@@ -14,8 +14,12 @@ type plainDNSResolver struct {
 	resolver    *net.Resolver
 }
 
-func newPlainDNSResolver() *plainDNSResolver {
-	cache := NewLRUDNSCache(defaultDNSCacheSize)
+// newPlainDNSResolver создаёт системный резолвер. С кэшем работает так
+// же, как newDNSResolver.
+func newPlainDNSResolver(cache *LRUDNSCache) *plainDNSResolver {
+	if cache == nil {
+		cache = NewLRUDNSCache(defaultDNSCacheSize)
+	}
 
 	resolver := &plainDNSResolver{
 		cache: cache,
@@ -31,6 +35,10 @@ func newPlainDNSResolver() *plainDNSResolver {
 }
 
 func (p *plainDNSResolver) LookupA(hostname string) []string {
+	return p.LookupACtx(context.Background(), hostname)
+}
+
+func (p *plainDNSResolver) LookupACtx(ctx context.Context, hostname string) []string {
 	key := "\x00" + hostname
 
 	// Check cache first
@@ -38,8 +46,9 @@ func (p *plainDNSResolver) LookupA(hostname string) []string {
 		return cached.IPs
 	}
 
-	// Cache miss - perform DNS query
-	ctx, cancel := context.WithTimeout(context.Background(), DNSTimeout)
+	// Cache miss - perform DNS query. Deadline of ctx wins if it is
+	// earlier than DNSTimeout.
+	ctx, cancel := context.WithTimeout(ctx, DNSTimeout)
 	defer cancel()
 
 	addrs, err := p.resolver.LookupIPAddr(ctx, hostname)
@@ -64,6 +73,10 @@ func (p *plainDNSResolver) LookupA(hostname string) []string {
 }
 
 func (p *plainDNSResolver) LookupAAAA(hostname string) []string {
+	return p.LookupAAAACtx(context.Background(), hostname)
+}
+
+func (p *plainDNSResolver) LookupAAAACtx(ctx context.Context, hostname string) []string {
 	key := "\x01" + hostname
 
 	// Check cache first
@@ -71,8 +84,9 @@ func (p *plainDNSResolver) LookupAAAA(hostname string) []string {
 		return cached.IPs
 	}
 
-	// Cache miss - perform DNS query
-	ctx, cancel := context.WithTimeout(context.Background(), DNSTimeout)
+	// Cache miss - perform DNS query. Deadline of ctx wins if it is
+	// earlier than DNSTimeout.
+	ctx, cancel := context.WithTimeout(ctx, DNSTimeout)
 	defer cancel()
 
 	addrs, err := p.resolver.LookupIPAddr(ctx, hostname)
@@ -97,6 +111,10 @@ func (p *plainDNSResolver) LookupAAAA(hostname string) []string {
 }
 
 func (p *plainDNSResolver) LookupBoth(hostname string) []string {
+	return p.LookupBothCtx(context.Background(), hostname)
+}
+
+func (p *plainDNSResolver) LookupBothCtx(ctx context.Context, hostname string) []string {
 	var (
 		ipv4 []string
 		ipv6 []string
@@ -107,12 +125,12 @@ func (p *plainDNSResolver) LookupBoth(hostname string) []string {
 
 	go func() {
 		defer wg.Done()
-		ipv4 = p.LookupA(hostname)
+		ipv4 = p.LookupACtx(ctx, hostname)
 	}()
 
 	go func() {
 		defer wg.Done()
-		ipv6 = p.LookupAAAA(hostname)
+		ipv6 = p.LookupAAAACtx(ctx, hostname)
 	}()
 
 	wg.Wait()
@@ -60,6 +60,11 @@ const (
 	// DNSTimeout defines a timeout for DNS queries.
 	DNSTimeout = 5 * time.Second
 
+	// DefaultDoHMaxInFlight defines a default limit of concurrent
+	// DNS-over-HTTPS queries. It is not applied implicitly: please pass
+	// it to Options.DoHMaxInFlight.
+	DefaultDoHMaxInFlight = 64
+
 	// tcpLingerTimeout defines a number of seconds to wait for sending
 	// unacknowledged data.
 	tcpLingerTimeout = 1
@@ -73,6 +78,10 @@ var (
 	// ErrCannotDialWithAllProxies is returned when load balancing client is
 	// trying to access proxies but all of them are failed.
 	ErrCannotDialWithAllProxies = errors.New("cannot dial with all proxies")
+
+	// ErrReusePortNotSupported is returned by listeners which are asked
+	// to share a port with SO_REUSEPORT on a platform without it.
+	ErrReusePortNotSupported = errors.New("SO_REUSEPORT is not supported on this platform")
 )
 
 // Dialer defines an interface which is required to bootstrap a network
@@ -0,0 +1,19 @@
+package network
+
+import (
+	"context"
+	"net"
+)
+
+// Listen создаёт обычный TCP listener. reusePort включает
+// SO_REUSEADDR/SO_REUSEPORT до bind: тогда на тот же адрес может встать
+// listener нового процесса, пока старый ещё работает (rolling restart).
+func Listen(network, address string, reusePort bool) (net.Listener, error) {
+	lc := net.ListenConfig{}
+
+	if reusePort {
+		lc.Control = controlReusePort
+	}
+
+	return lc.Listen(context.Background(), network, address) //nolint: wrapcheck
+}
@@ -0,0 +1,27 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenReusePort(t *testing.T) {
+	t.Parallel()
+
+	first, err := Listen("tcp", "127.0.0.1:0", true)
+	require.NoError(t, err)
+
+	defer first.Close()
+
+	// Новый процесс при rolling restart встаёт на тот же порт.
+	second, err := Listen("tcp", first.Addr().String(), true)
+	require.NoError(t, err)
+
+	defer second.Close()
+
+	// Без SO_REUSEPORT порт по-прежнему занят.
+	_, err = net.Listen("tcp", first.Addr().String())
+	require.Error(t, err)
+}
@@ -5,27 +5,51 @@ import (
 	"fmt"
 	"math/rand"
 	"net/url"
+	"sync/atomic"
 
 	"github.com/9seconds/mtg/v2/essentials"
 )
 
+const (
+	// ProxySelectionRandom starts each dial from a random proxy and tries
+	// others one by one if it fails. This is a default.
+	ProxySelectionRandom = "random"
+
+	// ProxySelectionRoundRobin starts each dial from the next proxy in a
+	// list.
+	ProxySelectionRoundRobin = "roundrobin"
+
+	// ProxySelectionFailover always starts from the first proxy in a list.
+	// Other proxies are used only if previous ones fail or are on
+	// cooldown. So the first proxy is a primary one, others are backups.
+	ProxySelectionFailover = "failover"
+)
+
 type loadBalancedSocks5Dialer struct {
 	dialers []Dialer
+	// breakers[i] — cooldown dialer прокси dialers[i]. Прокси на
+	// "остывании" пропускаются без попытки подключения.
+	breakers []*cooldownDialer
+	strategy string
+	next     atomic.Uint32
 }
 
-func (l loadBalancedSocks5Dialer) Dial(network, address string) (essentials.Conn, error) {
+func (l *loadBalancedSocks5Dialer) Dial(network, address string) (essentials.Conn, error) {
 	return l.DialContext(context.Background(), network, address)
 }
 
-func (l loadBalancedSocks5Dialer) DialContext(ctx context.Context, network, address string) (essentials.Conn, error) {
+func (l *loadBalancedSocks5Dialer) DialContext(ctx context.Context, network, address string) (essentials.Conn, error) {
 	length := len(l.dialers)
-	start := rand.Intn(length)
-	moved := false
+	start := l.firstIndex()
 
-	for i := start; i != start || !moved; i = (i + 1) % length {
-		moved = true
+	for i := range length {
+		idx := (start + i) % length
 
-		if conn, err := l.dialers[i].DialContext(ctx, network, address); err == nil {
+		if !l.breakers[idx].available() {
+			continue
+		}
+
+		if conn, err := l.dialers[idx].DialContext(ctx, network, address); err == nil {
 			return conn, nil
 		}
 	}
@@ -33,6 +57,18 @@ func (l loadBalancedSocks5Dialer) DialContext(ctx context.Context, network, addr
 	return nil, ErrCannotDialWithAllProxies
 }
 
+// firstIndex возвращает индекс прокси, с которого начинается перебор.
+func (l *loadBalancedSocks5Dialer) firstIndex() int {
+	switch l.strategy {
+	case ProxySelectionFailover:
+		return 0
+	case ProxySelectionRoundRobin:
+		return int((l.next.Add(1) - 1) % uint32(len(l.dialers)))
+	}
+
+	return rand.Intn(len(l.dialers)) //nolint: gosec
+}
+
 // NewLoadBalancedSocks5Dialer builds a new load balancing SOCKS5 dialer.
 //
 // The main difference from one which is made by NewSocks5Dialer is that we
@@ -42,18 +78,43 @@ func (l loadBalancedSocks5Dialer) DialContext(ctx context.Context, network, addr
 // So, it is mostly useful if you have some routes with proxies which are not
 // always online or having buggy network.
 func NewLoadBalancedSocks5Dialer(baseDialer Dialer, proxyURLs []*url.URL) (Dialer, error) {
+	return NewLoadBalancedSocks5DialerWithStrategy(baseDialer, proxyURLs, ProxySelectionRandom)
+}
+
+// NewLoadBalancedSocks5DialerWithStrategy is NewLoadBalancedSocks5Dialer
+// with a given proxy selection strategy. Please see ProxySelectionRandom,
+// ProxySelectionRoundRobin and ProxySelectionFailover.
+//
+// Whatever strategy is chosen, proxies on cooldown after a series of
+// failed dials are skipped.
+func NewLoadBalancedSocks5DialerWithStrategy(baseDialer Dialer,
+	proxyURLs []*url.URL,
+	strategy string,
+) (Dialer, error) {
+	switch strategy {
+	case ProxySelectionRandom, ProxySelectionRoundRobin, ProxySelectionFailover:
+	default:
+		return nil, fmt.Errorf("unknown proxy selection strategy %q", strategy)
+	}
+
 	dialers := make([]Dialer, 0, len(proxyURLs))
+	breakers := make([]*cooldownDialer, 0, len(proxyURLs))
 
 	for _, u := range proxyURLs {
-		dialer, err := NewSocks5Dialer(newProxyDialer(baseDialer, u), u)
+		breaker := newProxyDialer(baseDialer, u).(*cooldownDialer) //nolint: forcetypeassert
+
+		dialer, err := NewSocks5Dialer(breaker, u)
 		if err != nil {
 			return nil, fmt.Errorf("cannot build dialer for %s: %w", u.String(), err)
 		}
 
 		dialers = append(dialers, dialer)
+		breakers = append(breakers, breaker)
 	}
 
-	return loadBalancedSocks5Dialer{
-		dialers: dialers,
+	return &loadBalancedSocks5Dialer{
+		dialers:  dialers,
+		breakers: breakers,
+		strategy: strategy,
 	}, nil
 }
@@ -0,0 +1,141 @@
+package network
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/9seconds/mtg/v2/essentials"
+	"github.com/9seconds/mtg/v2/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// makeBalancer собирает балансировщик из прокси, которые подключаются
+// к адресам 10.0.0.N, где N — номер прокси.
+func makeBalancer(strategy string, baseDialer *DialerMock, count int) *loadBalancedSocks5Dialer {
+	balancer := &loadBalancedSocks5Dialer{strategy: strategy}
+
+	for i := range count {
+		breaker := newCooldownDialer(
+			&addressDialer{Dialer: baseDialer, address: "10.0.0." + strconv.Itoa(i+1)},
+			2, time.Hour).(*cooldownDialer) //nolint: forcetypeassert
+
+		balancer.dialers = append(balancer.dialers, breaker)
+		balancer.breakers = append(balancer.breakers, breaker)
+	}
+
+	return balancer
+}
+
+// addressDialer подменяет адрес назначения адресом прокси, чтобы в
+// DialerMock было видно, через какой прокси шло подключение.
+type addressDialer struct {
+	Dialer
+
+	address string
+}
+
+func (a *addressDialer) DialContext(ctx context.Context, network, _ string) (essentials.Conn, error) {
+	return a.Dialer.DialContext(ctx, network, a.address)
+}
+
+func TestLoadBalancedFailover(t *testing.T) {
+	t.Parallel()
+
+	conn := &testlib.EssentialsConnMock{}
+	baseDialer := &DialerMock{}
+
+	// Основной прокси падает дважды и уходит на cooldown.
+	baseDialer.On("DialContext", mock.Anything, "tcp", "10.0.0.1").
+		Times(2).
+		Return(conn, io.EOF)
+	baseDialer.On("DialContext", mock.Anything, "tcp", "10.0.0.2").
+		Times(4).
+		Return(conn, nil)
+
+	balancer := makeBalancer(ProxySelectionFailover, baseDialer, 3)
+
+	for range 4 {
+		dialed, err := balancer.Dial("tcp", "149.154.175.50:443")
+		require.NoError(t, err)
+		assert.Equal(t, conn, dialed)
+	}
+
+	assert.False(t, balancer.breakers[0].available())
+	assert.True(t, balancer.breakers[1].available())
+	baseDialer.AssertExpectations(t)
+}
+
+func TestLoadBalancedFailoverPrimaryFirst(t *testing.T) {
+	t.Parallel()
+
+	conn := &testlib.EssentialsConnMock{}
+	baseDialer := &DialerMock{}
+	baseDialer.On("DialContext", mock.Anything, "tcp", "10.0.0.1").
+		Times(5).
+		Return(conn, nil)
+
+	balancer := makeBalancer(ProxySelectionFailover, baseDialer, 3)
+
+	for range 5 {
+		_, err := balancer.Dial("tcp", "149.154.175.50:443")
+		require.NoError(t, err)
+	}
+
+	baseDialer.AssertExpectations(t)
+}
+
+func TestLoadBalancedRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	conn := &testlib.EssentialsConnMock{}
+	baseDialer := &DialerMock{}
+
+	for _, address := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		baseDialer.On("DialContext", mock.Anything, "tcp", address).
+			Times(2).
+			Return(conn, nil)
+	}
+
+	balancer := makeBalancer(ProxySelectionRoundRobin, baseDialer, 3)
+
+	for range 6 {
+		_, err := balancer.Dial("tcp", "149.154.175.50:443")
+		require.NoError(t, err)
+	}
+
+	baseDialer.AssertExpectations(t)
+}
+
+func TestLoadBalancedAllOnCooldown(t *testing.T) {
+	t.Parallel()
+
+	conn := &testlib.EssentialsConnMock{}
+	baseDialer := &DialerMock{}
+
+	for _, address := range []string{"10.0.0.1", "10.0.0.2"} {
+		baseDialer.On("DialContext", mock.Anything, "tcp", address).
+			Times(2).
+			Return(conn, io.EOF)
+	}
+
+	balancer := makeBalancer(ProxySelectionFailover, baseDialer, 2)
+
+	for range 4 {
+		_, err := balancer.Dial("tcp", "149.154.175.50:443")
+		assert.ErrorIs(t, err, ErrCannotDialWithAllProxies)
+	}
+
+	baseDialer.AssertExpectations(t)
+}
+
+func TestLoadBalancedUnknownStrategy(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLoadBalancedSocks5DialerWithStrategy(&DialerMock{}, nil, "weighted")
+	assert.Error(t, err)
+}
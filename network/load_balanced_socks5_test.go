@@ -1,6 +1,7 @@
 package network_test
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net"
@@ -72,6 +73,27 @@ func (suite *LoadBalancedSocks5TestSuite) TestCannotDial() {
 	baseDialer.AssertExpectations(suite.T())
 }
 
+func (suite *LoadBalancedSocks5TestSuite) TestTelegramDialCooldown() {
+	// Подключения к Telegram DC через единственный прокси тоже попадают
+	// под cooldown: после порога ошибок прокси не дёргается.
+	baseDialer := &DialerMock{}
+	baseDialer.On("DialContext", mock.Anything, "tcp4", "127.0.0.1:1080").
+		Times(network.ProxyDialerOpenThreshold).
+		Return(&net.TCPConn{}, io.EOF)
+
+	lbDialer, err := network.NewLoadBalancedSocks5Dialer(baseDialer, []*url.URL{
+		{Scheme: "socks5", Host: "127.0.0.1:1080"},
+	})
+	suite.NoError(err)
+
+	for i := 0; i < network.ProxyDialerOpenThreshold*2; i++ {
+		_, err = lbDialer.DialContext(context.Background(), "tcp4", "149.154.175.50:443")
+		suite.True(errors.Is(err, network.ErrCannotDialWithAllProxies))
+	}
+
+	baseDialer.AssertExpectations(suite.T())
+}
+
 func (suite *LoadBalancedSocks5TestSuite) TestDialOk() {
 	resp, err := suite.httpClient.Get(suite.MakeURL("/get")) //nolint: noctx
 	if err == nil {
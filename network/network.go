@@ -2,32 +2,51 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/9seconds/mtg/v2/essentials"
 	"github.com/9seconds/mtg/v2/mtglib"
 )
 
+// networkHTTPTransport ставит User-Agent на каждый запрос. Если агентов
+// несколько, для каждого запроса выбирается случайный: один и тот же
+// User-Agent на всех запросах сам по себе отпечаток.
 type networkHTTPTransport struct {
-	userAgent string
-	next      http.RoundTripper
+	userAgents []string
+	next       http.RoundTripper
+}
+
+func (n networkHTTPTransport) userAgent() string {
+	if len(n.userAgents) == 1 {
+		return n.userAgents[0]
+	}
+
+	return n.userAgents[rand.Intn(len(n.userAgents))] //nolint: gosec
 }
 
 func (n networkHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("User-Agent", n.userAgent)
+	req.Header.Set("User-Agent", n.userAgent())
 
 	return n.next.RoundTrip(req) //nolint: wrapcheck
 }
 
-// dnsResolverInterface defines the interface for DNS resolvers
+// dnsResolverInterface defines the interface for DNS resolvers.
+//
+// *Ctx variants cancel a lookup when a given context is done, so
+// a deadline of a caller covers the whole resolve+dial chain.
 type dnsResolverInterface interface {
 	LookupA(hostname string) []string
 	LookupAAAA(hostname string) []string
 	LookupBoth(hostname string) []string
+	LookupACtx(ctx context.Context, hostname string) []string
+	LookupAAAACtx(ctx context.Context, hostname string) []string
+	LookupBothCtx(ctx context.Context, hostname string) []string
 	GetCacheMetrics() DNSCacheMetrics
 	Stop()
 	WarmUp(hostnames []string)
@@ -36,8 +55,9 @@ type dnsResolverInterface interface {
 type network struct {
 	dialer      Dialer
 	httpTimeout time.Duration
-	userAgent   string
+	userAgents  []string
 	dns         dnsResolverInterface
+	doh         *dnsResolver // nil для plain DNS
 }
 
 func (n *network) Dial(protocol, address string) (essentials.Conn, error) {
@@ -47,7 +67,7 @@ func (n *network) Dial(protocol, address string) (essentials.Conn, error) {
 func (n *network) DialContext(ctx context.Context, protocol, address string) (essentials.Conn, error) {
 	host, port, _ := net.SplitHostPort(address)
 
-	ips, err := n.dnsResolve(protocol, host)
+	ips, err := n.dnsResolve(ctx, protocol, host)
 	if err != nil {
 		return nil, fmt.Errorf("cannot resolve dns names: %w", err)
 	}
@@ -76,19 +96,19 @@ func (n *network) MakeHTTPClient(dialFunc func(ctx context.Context,
 		dialFunc = n.DialContext
 	}
 
-	return makeHTTPClient(n.userAgent, n.httpTimeout, dialFunc)
+	return makeHTTPClient(n.userAgents, n.httpTimeout, dialFunc)
 }
 
-func (n *network) dnsResolve(protocol, address string) ([]string, error) {
+func (n *network) dnsResolve(ctx context.Context, protocol, address string) ([]string, error) {
 	if net.ParseIP(address) != nil {
 		return []string{address}, nil
 	}
 
 	// Optimize for "tcp" protocol - use parallel A+AAAA lookup
 	if protocol == "tcp" {
-		ips := n.dns.LookupBoth(address)
+		ips := n.dns.LookupBothCtx(ctx, address)
 		if len(ips) == 0 {
-			return nil, fmt.Errorf("cannot find any ips for %s:%s", protocol, address)
+			return nil, resolveError(ctx, protocol, address)
 		}
 		return ips, nil
 	}
@@ -99,18 +119,28 @@ func (n *network) dnsResolve(protocol, address string) ([]string, error) {
 
 	switch protocol {
 	case "tcp4":
-		ips = n.dns.LookupA(address)
+		ips = n.dns.LookupACtx(ctx, address)
 	case "tcp6":
-		ips = n.dns.LookupAAAA(address)
+		ips = n.dns.LookupAAAACtx(ctx, address)
 	}
 
 	if len(ips) == 0 {
-		return nil, fmt.Errorf("cannot find any ips for %s:%s", protocol, address)
+		return nil, resolveError(ctx, protocol, address)
 	}
 
 	return ips, nil
 }
 
+// resolveError сообщает причину пустого результата: если caller
+// отменил ctx, наружу уходит ошибка контекста, а не "not found".
+func resolveError(ctx context.Context, protocol, address string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cannot resolve %s:%s: %w", protocol, address, err)
+	}
+
+	return fmt.Errorf("cannot find any ips for %s:%s", protocol, address)
+}
+
 // GetDNSCacheMetrics returns DNS cache statistics for monitoring.
 func (n *network) GetDNSCacheMetrics() (uint64, uint64, uint64, int) {
 	metrics := n.dns.GetCacheMetrics()
@@ -123,6 +153,26 @@ func (n *network) WarmUp(hostnames []string) {
 	n.dns.WarmUp(hostnames)
 }
 
+// GetDNSSkippedQueries returns a number of A and AAAA queries which were
+// not sent because of a DNS query type setting.
+func (n *network) GetDNSSkippedQueries() (uint64, uint64) {
+	if resolver, ok := n.dns.(*queryTypeResolver); ok {
+		return resolver.Skipped()
+	}
+
+	return 0, 0
+}
+
+// GetDoHLimitedQueries returns a number of DoH queries which found all
+// in-flight slots busy. It is always 0 for plain DNS.
+func (n *network) GetDoHLimitedQueries() uint64 {
+	if n.doh == nil {
+		return 0
+	}
+
+	return n.doh.Limited()
+}
+
 // Stop gracefully stops the network and releases resources.
 func (n *network) Stop() {
 	n.dns.Stop()
@@ -147,41 +197,140 @@ func NewNetworkWithDNSMode(dialer Dialer,
 	httpTimeout time.Duration,
 	usePlainDNS bool,
 ) (mtglib.Network, error) {
+	return NewNetworkWithDNSQueryType(dialer, userAgent, dohHostname, httpTimeout, usePlainDNS, DNSQueryTypeBoth)
+}
+
+// NewNetworkWithDNSQueryType is NewNetworkWithDNSMode which also defines
+// which DNS queries are sent at all. Please see DNSQueryTypeBoth,
+// DNSQueryTypeA and DNSQueryTypeAAAA.
+//
+// This is different from IP preference: preference orders resolved
+// addresses, while a query type disables a query of another type
+// completely.
+func NewNetworkWithDNSQueryType(dialer Dialer,
+	userAgent, dohHostname string,
+	httpTimeout time.Duration,
+	usePlainDNS bool,
+	queryType string,
+) (mtglib.Network, error) {
+	return NewNetworkWithUserAgents(dialer, []string{userAgent}, dohHostname, httpTimeout, usePlainDNS, queryType)
+}
+
+// NewNetworkWithUserAgents is NewNetworkWithDNSQueryType which takes a
+// list of User-Agent values for outgoing HTTP requests (DoH, blocklist
+// downloads). If there are several of them, each request gets a random
+// one. Please see BrowserUserAgents for a realistic set.
+func NewNetworkWithUserAgents(dialer Dialer,
+	userAgents []string,
+	dohHostname string,
+	httpTimeout time.Duration,
+	usePlainDNS bool,
+	queryType string,
+) (mtglib.Network, error) {
+	return NewNetworkWithOptions(dialer, Options{
+		UserAgents:  userAgents,
+		DoHHostname: dohHostname,
+		HTTPTimeout: httpTimeout,
+		UsePlainDNS: usePlainDNS,
+		QueryType:   queryType,
+	})
+}
+
+// Options defines parameters of NewNetworkWithOptions. A zero value of
+// each field means a default behaviour.
+type Options struct {
+	// UserAgents is a list of User-Agent values for outgoing HTTP
+	// requests. If there are several of them, each request gets a random
+	// one. It is mandatory.
+	UserAgents []string
+
+	// DoHHostname is an IP address of DNS-over-HTTPS server. It is
+	// ignored if UsePlainDNS is set.
+	DoHHostname string
+
+	// HTTPTimeout is a timeout for outgoing HTTP requests. 0 means
+	// DefaultHTTPTimeout.
+	HTTPTimeout time.Duration
+
+	// UsePlainDNS switches DNS-over-HTTPS to a system DNS resolver.
+	UsePlainDNS bool
+
+	// QueryType defines which DNS queries are sent at all. Empty value
+	// means DNSQueryTypeBoth.
+	QueryType string
+
+	// DoHMaxInFlight limits a number of concurrent DNS-over-HTTPS
+	// queries. If all slots are busy, a query waits for 200ms and then
+	// fails. 0 means no limit. Please see DefaultDoHMaxInFlight for a
+	// sane value. It is ignored for plain DNS.
+	DoHMaxInFlight int
+}
+
+// NewNetworkWithOptions assembles an mtglib.Network based on a dialer and
+// given options. Other NewNetwork* constructors are shortcuts for it.
+func NewNetworkWithOptions(dialer Dialer, opts Options) (mtglib.Network, error) {
+	httpTimeout := opts.HTTPTimeout
+	userAgents := opts.UserAgents
+	dohHostname := opts.DoHHostname
+
+	queryType := opts.QueryType
+	if queryType == "" {
+		queryType = DNSQueryTypeBoth
+	}
+
 	switch {
+	case len(userAgents) == 0:
+		return nil, errors.New("at least one user agent should be given")
+	case opts.DoHMaxInFlight < 0:
+		return nil, fmt.Errorf("doh max in-flight should not be negative %d", opts.DoHMaxInFlight)
 	case httpTimeout < 0:
 		return nil, fmt.Errorf("timeout should be positive number %s", httpTimeout)
 	case httpTimeout == 0:
 		httpTimeout = DefaultHTTPTimeout
 	}
 
-	var dns dnsResolverInterface
+	var (
+		dns dnsResolverInterface
+		doh *dnsResolver
+	)
 
-	if usePlainDNS {
-		dns = newPlainDNSResolver()
+	if opts.UsePlainDNS {
+		dns = newPlainDNSResolver(nil)
 	} else {
 		if net.ParseIP(dohHostname) == nil {
 			return nil, fmt.Errorf("hostname %s should be IP address", dohHostname)
 		}
-		dns = newDNSResolver(dohHostname,
-			makeHTTPClient(userAgent, DNSTimeout, dialer.DialContext))
+		doh = newDNSResolver(dohHostname,
+			makeHTTPClient(userAgents, DNSTimeout, dialer.DialContext), nil, opts.DoHMaxInFlight)
+		dns = doh
+	}
+
+	if queryType != DNSQueryTypeBoth {
+		resolver, err := newQueryTypeResolver(dns, queryType)
+		if err != nil {
+			return nil, err
+		}
+
+		dns = resolver
 	}
 
 	return &network{
 		dialer:      dialer,
 		httpTimeout: httpTimeout,
-		userAgent:   userAgent,
+		userAgents:  slices.Clone(userAgents),
 		dns:         dns,
+		doh:         doh,
 	}, nil
 }
 
-func makeHTTPClient(userAgent string,
+func makeHTTPClient(userAgents []string,
 	timeout time.Duration,
 	dialFunc func(ctx context.Context, network, address string) (essentials.Conn, error),
 ) *http.Client {
 	return &http.Client{
 		Timeout: timeout,
 		Transport: networkHTTPTransport{
-			userAgent: userAgent,
+			userAgents: userAgents,
 			next: &http.Transport{
 				DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
 					return dialFunc(ctx, network, address)
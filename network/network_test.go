@@ -50,6 +50,49 @@ func (suite *NetworkTestSuite) TestLocalHTTPRequest() {
 	suite.Equal([]string{"itsme"}, jsonStruct.Headers.UserAgent)
 }
 
+func (suite *NetworkTestSuite) TestUserAgentRotation() {
+	ntw, err := network.NewNetworkWithUserAgents(
+		suite.dialer, []string{"agent1", "agent2"}, "1.1.1.1", 0, false, network.DNSQueryTypeBoth)
+	suite.NoError(err)
+
+	client := ntw.MakeHTTPClient(nil)
+	seen := map[string]bool{}
+
+	for range 40 {
+		resp, err := client.Get(suite.httpServer.URL + "/headers") //nolint: noctx
+		suite.NoError(err)
+
+		jsonStruct := struct {
+			Headers struct {
+				UserAgent []string `json:"User-Agent"` //nolint: tagliatelle
+			} `json:"headers"`
+		}{}
+
+		suite.NoError(json.NewDecoder(resp.Body).Decode(&jsonStruct))
+		resp.Body.Close()
+
+		suite.Len(jsonStruct.Headers.UserAgent, 1)
+		seen[jsonStruct.Headers.UserAgent[0]] = true
+	}
+
+	suite.Equal(map[string]bool{"agent1": true, "agent2": true}, seen)
+}
+
+func (suite *NetworkTestSuite) TestNoUserAgents() {
+	_, err := network.NewNetworkWithUserAgents(
+		suite.dialer, nil, "1.1.1.1", 0, false, network.DNSQueryTypeBoth)
+	suite.Error(err)
+}
+
+func (suite *NetworkTestSuite) TestNegativeDoHMaxInFlight() {
+	_, err := network.NewNetworkWithOptions(suite.dialer, network.Options{
+		UserAgents:     []string{"itsme"},
+		DoHHostname:    "1.1.1.1",
+		DoHMaxInFlight: -1,
+	})
+	suite.Error(err)
+}
+
 func (suite *NetworkTestSuite) TestRealHTTPRequest() {
 	if testing.Short() {
 		suite.T().Skip("skipping integration test in short mode")
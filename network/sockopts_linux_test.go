@@ -0,0 +1,59 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// listenBacklog читает размер accept-очереди слушающего сокета: для
+// listen-сокета ядро отдаёт его в tcpi_sacked.
+func listenBacklog(t *testing.T, listener net.Listener) uint32 {
+	t.Helper()
+
+	rawConn, err := listener.(*net.TCPListener).SyscallConn()
+	require.NoError(t, err)
+
+	var (
+		info   *unix.TCPInfo
+		sysErr error
+	)
+
+	require.NoError(t, rawConn.Control(func(fd uintptr) {
+		info, sysErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}))
+	require.NoError(t, sysErr)
+
+	return info.Sacked
+}
+
+func TestSetListenBacklog(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	require.NoError(t, SetListenBacklog(listener, 7))
+	require.EqualValues(t, 7, listenBacklog(t, listener))
+}
+
+func TestSetListenBacklogZeroKeepsDefault(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	before := listenBacklog(t, listener)
+
+	require.NoError(t, SetListenBacklog(listener, 0))
+	require.Equal(t, before, listenBacklog(t, listener))
+}
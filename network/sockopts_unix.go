@@ -20,6 +20,32 @@ const (
 	socketBufferSize = 256 * 1024 // 256 KB
 )
 
+// controlReusePort — Control для net.ListenConfig: SO_REUSEPORT нужно
+// выставить до bind, иначе второй процесс получит EADDRINUSE. Linux
+// разрешает делить порт только процессам с тем же effective UID.
+func controlReusePort(_, _ string, conn syscall.RawConn) error {
+	var err error
+
+	ctrlErr := conn.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1) //nolint: nosnakecase
+		if err != nil {
+			err = fmt.Errorf("cannot set SO_REUSEADDR: %w", err)
+
+			return
+		}
+
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1) //nolint: nosnakecase
+		if err != nil {
+			err = fmt.Errorf("cannot set SO_REUSEPORT: %w", err)
+		}
+	})
+	if ctrlErr != nil {
+		return ctrlErr //nolint: wrapcheck
+	}
+
+	return err
+}
+
 func setSocketReuseAddrPort(conn syscall.RawConn) error {
 	var err error
 
@@ -95,3 +121,37 @@ func SetTCPQuickACK(conn net.Conn) error {
 
 	return nil
 }
+
+// SetListenBacklog меняет размер accept-очереди уже слушающего сокета.
+//
+// Go всегда вызывает listen(2) с net.core.somaxconn, поэтому backlog
+// меняется повторным listen(2) на том же сокете — ядро просто обновляет
+// размер очереди. Эффективное значение всё равно ограничено
+// net.core.somaxconn (и net.ipv4.tcp_max_syn_backlog для SYN-очереди).
+func SetListenBacklog(listener net.Listener, backlog int) error {
+	if backlog <= 0 {
+		return nil
+	}
+
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		return nil // Не TCP, игнорируем
+	}
+
+	rawConn, err := tcpListener.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("cannot get raw conn for listen backlog: %w", err)
+	}
+
+	var sysErr error
+
+	rawConn.Control(func(fd uintptr) { //nolint: errcheck
+		sysErr = unix.Listen(int(fd), backlog)
+	})
+
+	if sysErr != nil {
+		return fmt.Errorf("cannot set listen backlog=%d: %w", backlog, sysErr)
+	}
+
+	return nil
+}
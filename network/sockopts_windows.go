@@ -5,6 +5,7 @@ package network
 
 import (
 	"fmt"
+	"net"
 	"syscall"
 )
 
@@ -14,6 +15,12 @@ const (
 	socketBufferSize = 1024 * 1024 // 1 MB (было 256 KB)
 )
 
+// controlReusePort: на Windows нет SO_REUSEPORT, а SO_REUSEADDR там
+// позволяет чужому процессу перехватить порт. Поэтому явно отказываем.
+func controlReusePort(_, _ string, _ syscall.RawConn) error {
+	return ErrReusePortNotSupported
+}
+
 func setSocketReuseAddrPort(conn syscall.RawConn) error {
 	var err error
 
@@ -35,3 +42,9 @@ func setSocketReuseAddrPort(conn syscall.RawConn) error {
 
 	return err
 }
+
+// SetListenBacklog не поддерживается на Windows: используется
+// системное значение backlog.
+func SetListenBacklog(listener net.Listener, backlog int) error {
+	return nil
+}
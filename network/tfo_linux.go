@@ -100,6 +100,9 @@ type TFOConfig struct {
 
 	// Fallback — использовать обычное соединение если TFO не работает
 	Fallback bool
+
+	// ReusePort включает SO_REUSEPORT на listener (см. Listen)
+	ReusePort bool
 }
 
 // DefaultTFOConfig возвращает конфигурацию по умолчанию.
@@ -115,13 +118,13 @@ func DefaultTFOConfig() TFOConfig {
 // Если TFO не поддерживается и Fallback=true, возвращает обычный listener.
 func ListenTFO(network, address string, config TFOConfig) (net.Listener, error) {
 	if !config.Enabled {
-		return net.Listen(network, address)
+		return Listen(network, address, config.ReusePort)
 	}
 
 	// Проверяем поддержку TFO сервером
 	if !IsTFOServerEnabled() {
 		if config.Fallback {
-			return net.Listen(network, address)
+			return Listen(network, address, config.ReusePort)
 		}
 		return nil, ErrTFONotSupported
 	}
@@ -133,6 +136,12 @@ func ListenTFO(network, address string, config TFOConfig) (net.Listener, error)
 
 	lc := net.ListenConfig{
 		Control: func(network, address string, c syscall.RawConn) error {
+			if config.ReusePort {
+				if err := controlReusePort(network, address, c); err != nil {
+					return err
+				}
+			}
+
 			var opErr error
 			err := c.Control(func(fd uintptr) {
 				// Включаем TCP_FASTOPEN на listener socket
@@ -50,9 +50,10 @@ func IsTFOClientEnabled() bool {
 
 // TFOConfig — конфигурация TCP Fast Open.
 type TFOConfig struct {
-	Enabled  bool
-	QueueLen int
-	Fallback bool
+	Enabled   bool
+	QueueLen  int
+	Fallback  bool
+	ReusePort bool
 }
 
 // DefaultTFOConfig возвращает конфигурацию по умолчанию.
@@ -69,7 +70,7 @@ func ListenTFO(network, address string, config TFOConfig) (net.Listener, error)
 	if config.Enabled && !config.Fallback {
 		return nil, ErrTFONotSupported
 	}
-	return net.Listen(network, address)
+	return Listen(network, address, config.ReusePort)
 }
 
 // DialerTFO — dialer без TFO для не-Linux систем.
@@ -0,0 +1,19 @@
+package network
+
+// browserUserAgents — небольшой набор User-Agent актуальных браузеров
+// на популярных платформах. Обновлять вместе с релизами браузеров:
+// слишком старая версия выделяется так же, как нестандартный агент.
+var browserUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:144.0) Gecko/20100101 Firefox/144.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/26.0 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36 Edg/141.0.0.0",
+}
+
+// BrowserUserAgents returns a small set of realistic browser User-Agent
+// values to rotate among. Please see NewNetworkWithUserAgents.
+func BrowserUserAgents() []string {
+	return append([]string(nil), browserUserAgents...)
+}
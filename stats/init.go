@@ -31,10 +31,80 @@ const (
 	//
 	//     Type: gauge
 	//     Tags:
-	//       telegram_ip | IP address of the telegram server.
-	//       dc          | Index of the datacenter to connect to.
+	//       telegram_ip        | IP address of the telegram server.
+	//       telegram_ip_family | A type of IP (ipv4 or ipv6) of the
+	//                          | telegram server.
+	//       dc                 | Index of the datacenter to connect to.
 	MetricTelegramConnections = "telegram_connections"
 
+	// MetricTelegramConnectionsTFO defines a metric for a count of
+	// connections to Telegram servers established with TCP Fast Open
+	// cookie. Compare it with a number of connections to measure TFO
+	// effectiveness. This is always 0 on non-Linux platforms.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc | Index of the datacenter to connect to.
+	MetricTelegramConnectionsTFO = "telegram_connections_tfo_total"
+
+	// MetricTelegramDCSkips defines a metric for a count of connections
+	// which were sent to a fallback DC without a dial attempt because a
+	// requested DC has recently failed.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc | Index of the datacenter a client has requested.
+	MetricTelegramDCSkips = "telegram_dc_skips_total"
+
+	// MetricTelegramDials defines a metric for a count of connections to
+	// Telegram DCs which proxy has established or failed to establish,
+	// both direct and pooled. Unlike pool hits and misses, failures are
+	// not masked by a connection pool.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc     | Index of the datacenter.
+	//       result | TagResultSuccess or TagResultFailure.
+	MetricTelegramDials = "telegram_dial_total"
+
+	// MetricTelegramHandshakeRetries defines a metric for a count of
+	// obfuscated2 handshakes with Telegram which failed with broken pipe
+	// and were retried on a fresh connection. A pooled connection is
+	// usually stale in this case.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc | Index of the datacenter.
+	MetricTelegramHandshakeRetries = "telegram_handshake_retries_total"
+
+	// MetricTelegramHandshakeRetryFailures defines a metric for a count
+	// of retried handshakes which have failed too.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc | Index of the datacenter.
+	MetricTelegramHandshakeRetryFailures = "telegram_handshake_retry_failures_total"
+
+	// MetricRelayStarted defines a metric for a count of client
+	// connections which completed both handshakes and got connected to
+	// Telegram. Compared to a count of all client connections, it shows
+	// how many connections come from real clients, not scanners.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc | Index of the datacenter a client is relayed to.
+	MetricRelayStarted = "relay_started_total"
+
+	// MetricHandshakeDuration defines a metric for a time between a start
+	// of client connection processing and a start of relay: a client
+	// handshake and a dial to Telegram.
+	//
+	// Prometheus exports it in seconds with a '_seconds' suffix, statsd
+	// as a timing in milliseconds.
+	//
+	//     Type: histogram
+	MetricHandshakeDuration = "handshake_duration"
+
 	// MetricDomainFrontingConnections defines a metric which is
 	// responsible for a count of active connections to a fronting domain.
 	// Fronting domain is that one that is encoded in a secret.
@@ -44,15 +114,52 @@ const (
 	//       ip_family | A type of IP (ipv4 or ipv6) that was used.
 	MetricDomainFrontingConnections = "domain_fronting_connections"
 
+	// MetricDomainFrontingDialFailures defines a metric for a count of
+	// failed dials to a fronting domain.
+	//
+	//     Type: counter
+	MetricDomainFrontingDialFailures = "domain_fronting_dial_failures_total"
+
+	// MetricDomainFrontingDialDuration defines a metric for a time of
+	// successful dials to a fronting domain, including DNS resolving.
+	//
+	// Prometheus exports it in seconds with a '_seconds' suffix, statsd
+	// as a timing in milliseconds.
+	//
+	//     Type: histogram
+	MetricDomainFrontingDialDuration = "domain_fronting_dial_duration"
+
+	// MetricWorkerQueueWait defines a metric for a time between Accept of
+	// a connection and a start of serving it by a worker.
+	//
+	// Prometheus exports it in seconds with a '_seconds' suffix, statsd
+	// as a timing in milliseconds.
+	//
+	//     Type: histogram
+	MetricWorkerQueueWait = "worker_queue_wait"
+
+	// MetricAcceptBackpressure defines a metric for a time when proxy did
+	// not accept new connections because a number of active ones reached
+	// a backpressure threshold.
+	//
+	// Prometheus exports it as a counter of seconds with a
+	// '_seconds_total' suffix, statsd as a timing of each pause in
+	// milliseconds.
+	//
+	//     Type: counter
+	MetricAcceptBackpressure = "accept_backpressure"
+
 	// MetricTelegramTraffic defines a metric for traffic (in bytes) that
 	// is sent to and from Telegram servers.
 	//
 	//     Type: counter
 	//     Tags:
-	//       telegram_ip | IP address of the telegram server.
-	//       dc          | Index of the datacenter
-	//       direction   | Direction of the traffc flow. Values are
-	//                   | 'to_client' and 'from_client'
+	//       telegram_ip        | IP address of the telegram server.
+	//       telegram_ip_family | A type of IP (ipv4 or ipv6) of the
+	//                          | telegram server.
+	//       dc                 | Index of the datacenter
+	//       direction          | Direction of the traffc flow. Values are
+	//                          | 'to_client' and 'from_client'
 	MetricTelegramTraffic = "telegram_traffic"
 
 	// MetricDomainFrontingTraffic defines a metric for traffic (in bytes)
@@ -70,12 +177,33 @@ const (
 	//     Type: counter
 	MetricDomainFronting = "domain_fronting"
 
+	// MetricDomainFrontingLimited defines a metric for a count of
+	// connections which were closed instead of domain fronting because
+	// mtglib.ProxyOpts.DomainFrontingMaxConnections connections are
+	// fronted already.
+	//
+	//     Type: counter
+	MetricDomainFrontingLimited = "domain_fronting_limited"
+
+	// MetricRelaysLimited defines a metric for a count of clients which
+	// were closed after a handshake because mtglib.ProxyOpts.MaxRelays
+	// relays are active already.
+	//
+	//     Type: counter
+	MetricRelaysLimited = "relays_limited"
+
 	// MetricConcurrencyLimited defines a metric for a count of events,
 	// when the client was blocked due to the concurrency limit.
 	//
 	//     Type: counter
 	MetricConcurrencyLimited = "concurrency_limited"
 
+	// MetricRateLimitRejects defines a metric for a count of events, when
+	// the client was blocked due to the per-IP handshake rate limit.
+	//
+	//     Type: counter
+	MetricRateLimitRejects = "rate_limit_rejects"
+
 	// MetricIPBlocklisted defines a metric for a count of events, when
 	// client was blocked because her IP address was found in blocklists.
 	//
@@ -89,6 +217,65 @@ const (
 	//     Type: counter
 	MetricReplayAttacks = "replay_attacks"
 
+	// MetricReplayAttackSources defines a metric for a count of detected
+	// replay attacks per source. This metric is populated only if source
+	// labeling is enabled (see ReplayAttackSourceHashed and
+	// ReplayAttackSourceRaw).
+	//
+	//     Type: counter
+	//     Tags:
+	//       source | A bucket of the hashed client IP or raw client IP.
+	MetricReplayAttackSources = "replay_attack_sources"
+
+	// MetricDomainFrontingSNI defines a metric for a count of domain
+	// fronted connections per SNI which a client has sent. SNI of a
+	// fronting domain from a secret is always shown as is; other values
+	// are labeled according to FrontedSNI* mode. Connections without SNI
+	// have an empty label.
+	//
+	//     Type: counter
+	//     Tags:
+	//       sni | A bucket of the hashed SNI or raw SNI.
+	MetricDomainFrontingSNI = "domain_fronting_sni"
+
+	// MetricTelegramHandshakeFailures defines a metric for a count of
+	// failed obfuscated2 handshakes with Telegram servers. Please alarm on
+	// 'frame_exhausted' reason: it means that RNG is broken.
+	//
+	//     Type: counter
+	//     Tags:
+	//       dc     | Index of the datacenter.
+	//       reason | 'frame_exhausted', 'cipher_init', 'write' or 'other'
+	MetricTelegramHandshakeFailures = "telegram_handshake_failures"
+
+	// MetricScannerProbes defines a metric for a count of client
+	// connections which clearly were not TLS handshakes: mass scanners
+	// and active probes.
+	//
+	//     Type: counter
+	//     Tags:
+	//       reason | 'not_tls' or 'truncated'
+	MetricScannerProbes = "scanner_probes"
+
+	// MetricTarpittedConnections defines a metric for a count of client
+	// connections with invalid handshakes which were tarpitted.
+	//
+	//     Type: counter
+	MetricTarpittedConnections = "tarpitted_connections"
+
+	// MetricDraining defines a metric which is 1 if proxy is draining:
+	// it does not accept new connections but serves existing ones.
+	//
+	//     Type: gauge
+	MetricDraining = "draining"
+
+	// MetricDCConfigFailures defines a metric for a number of consecutive
+	// failed DC config loads. It is reported only after several failures
+	// in a row and reset to 0 on a successful load.
+	//
+	//     Type: gauge
+	MetricDCConfigFailures = "dc_config_failures"
+
 	// MetricIPListSize defines a metric for the size of the the ip list.
 	//
 	//     Type: gauge
@@ -104,6 +291,46 @@ const (
 	//       ip_list | 'allowlist' or 'blocklist'
 	MetricIPListCacheFallback = "iplist_cache_fallback"
 
+	// MetricWorkerPoolPressure defines a metric which is 1 if the worker
+	// pool utilization has crossed a high watermark and has not dropped
+	// below a low watermark yet, and 0 otherwise.
+	//
+	//     Type: gauge
+	MetricWorkerPoolPressure = "worker_pool_pressure"
+
+	// MetricWorkerPoolBusy defines a metric for a number of busy workers.
+	// If mtglib.ProxyOpts.MaxRelays is set, a worker is released when a
+	// relay or domain fronting starts, so this is a number of
+	// connections in handshake.
+	//
+	//     Type: gauge
+	MetricWorkerPoolBusy = "worker_pool_busy"
+
+	// MetricRelaysActive defines a metric for a number of active relays
+	// to Telegram.
+	//
+	//     Type: gauge
+	MetricRelaysActive = "relays_active"
+
+	// MetricClientTimeSkew defines a metric for an absolute difference
+	// between proxy time and a timestamp of FakeTLS client hello. Only
+	// hellos with a valid digest are taken into account. Please use it to
+	// choose tolerate-time-skewness.
+	//
+	// Prometheus exports it in seconds with a '_seconds' suffix, statsd
+	// as a timing in milliseconds.
+	//
+	//     Type: histogram
+	MetricClientTimeSkew = "faketls_client_time_skew"
+
+	// MetricClientTimeSkewRejections defines a metric for a number of
+	// FakeTLS client hellos which were rejected because their timestamp
+	// is out of a time skewness tolerance. A steady flow of them is more
+	// likely a probing than clients with bad clocks.
+	//
+	//     Type: counter
+	MetricClientTimeSkewRejections = "faketls_skew_rejections_total"
+
 	// MetricDNSCacheSize defines a metric for the current size of the DNS cache.
 	//
 	//     Type: gauge
@@ -124,6 +351,20 @@ const (
 	//     Type: counter
 	MetricDNSCacheEvictions = "dns_cache_evictions"
 
+	// MetricDNSQueriesSkipped defines a metric for DNS queries which were
+	// not sent because mtg resolves only A or only AAAA records.
+	//
+	//     Type: counter
+	//     Tags:
+	//       TagQueryType
+	MetricDNSQueriesSkipped = "dns_queries_skipped"
+
+	// MetricDoHQueriesLimited defines a metric for DNS-over-HTTPS queries
+	// which hit a limit of concurrent queries.
+	//
+	//     Type: counter
+	MetricDoHQueriesLimited = "doh_queries_limited"
+
 	// TagIPFamily defines a name of the 'ip_family' tag and all values.
 	TagIPFamily = "ip_family"
 
@@ -136,6 +377,10 @@ const (
 	// TagTelegramIP defines a name of the 'telegram_ip' tag.
 	TagTelegramIP = "telegram_ip"
 
+	// TagTelegramIPFamily defines a name of the 'telegram_ip_family' tag.
+	// Values are the same as for 'ip_family'.
+	TagTelegramIPFamily = "telegram_ip_family"
+
 	// TagDC defines a name of the 'dc' tag.
 	TagDC = "dc"
 
@@ -150,6 +395,63 @@ const (
 	// Telegram.
 	TagDirectionFromClient = "from_client"
 
+	// TagReason defines a name of the 'reason' tag.
+	TagReason = "reason"
+
+	// TagSource defines a name of the 'source' tag.
+	TagSource = "source"
+
+	// TagResult defines a name of the 'result' tag.
+	TagResult = "result"
+
+	// TagResultSuccess defines a value of 'result' of a successful
+	// operation.
+	TagResultSuccess = "success"
+
+	// TagResultFailure defines a value of 'result' of a failed operation.
+	TagResultFailure = "failure"
+
+	// TagSNI defines a name of the 'sni' tag.
+	TagSNI = "sni"
+
+	// FrontedSNIOff disables MetricDomainFrontingSNI.
+	FrontedSNIOff = "off"
+
+	// FrontedSNIHashed labels domain fronted connections by a bucket of
+	// the salted SNI hash. This is a default: cardinality of the label is
+	// bounded and SNI of random scanners is not exposed.
+	FrontedSNIHashed = "hashed"
+
+	// FrontedSNIRaw labels domain fronted connections by raw SNI. Please
+	// be aware that cardinality of such label is unbounded.
+	FrontedSNIRaw = "raw"
+
+	// HealthPath is a path of the health endpoint of PrometheusFactory.
+	// Please see PrometheusFactory.SetHealthCheck.
+	HealthPath = "/healthz"
+
+	// ReplayAttackSourceOff disables labeling of replay attacks by source.
+	ReplayAttackSourceOff = "off"
+
+	// ReplayAttackSourceHashed labels replay attacks by a bucket of the
+	// salted client IP hash. Real IP addresses are never exposed and a
+	// cardinality of the label is bounded.
+	ReplayAttackSourceHashed = "hashed"
+
+	// ReplayAttackSourceRaw labels replay attacks by raw client IP
+	// address. Please be aware that cardinality of such label is
+	// unbounded.
+	ReplayAttackSourceRaw = "raw"
+
+	// TagQueryType defines a name of the 'query_type' tag and all values.
+	TagQueryType = "query_type"
+
+	// TagQueryTypeA defines a value of 'query_type' of A queries.
+	TagQueryTypeA = "A"
+
+	// TagQueryTypeAAAA defines a value of 'query_type' of AAAA queries.
+	TagQueryTypeAAAA = "AAAA"
+
 	// TagIPList defines a name of the 'ip_list' and all values.
 	TagIPList = "ip_list"
 
@@ -5,14 +5,25 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/9seconds/mtg/v2/events"
 	"github.com/9seconds/mtg/v2/mtglib"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// replayAttackSourceBucketLen is a number of hex chars of the hashed IP
+// used as a source bucket: 65536 buckets at most.
+const replayAttackSourceBucketLen = 4
+
+// frontedSNIBucketLen is a number of hex chars of the hashed SNI used as
+// a label of domain fronted connections.
+const frontedSNIBucketLen = 4
+
 type prometheusProcessor struct {
 	streams map[string]*streamInfo
 	factory *PrometheusFactory
@@ -22,11 +33,7 @@ func (p prometheusProcessor) EventStart(evt mtglib.EventStart) {
 	info := acquireStreamInfo()
 	info.startTime = time.Now()
 
-	if evt.RemoteIP.To4() != nil {
-		info.tags[TagIPFamily] = TagIPFamilyIPv4
-	} else {
-		info.tags[TagIPFamily] = TagIPFamilyIPv6
-	}
+	info.tags[TagIPFamily] = getIPFamily(evt.RemoteIP)
 
 	p.streams[evt.StreamID()] = info
 
@@ -42,11 +49,18 @@ func (p prometheusProcessor) EventConnectedToDC(evt mtglib.EventConnectedToDC) {
 	}
 
 	info.tags[TagTelegramIP] = evt.RemoteIP.String()
+	info.tags[TagTelegramIPFamily] = getIPFamily(evt.RemoteIP)
 	info.tags[TagDC] = strconv.Itoa(evt.DC)
 
 	p.factory.metricTelegramConnections.
-		WithLabelValues(info.tags[TagTelegramIP], info.tags[TagDC]).
+		WithLabelValues(info.tags[TagTelegramIP], info.tags[TagTelegramIPFamily], info.tags[TagDC]).
 		Inc()
+
+	if evt.UsedTFO {
+		p.factory.metricTelegramConnectionsTFO.
+			WithLabelValues(info.tags[TagDC]).
+			Inc()
+	}
 }
 
 func (p prometheusProcessor) EventDomainFronting(evt mtglib.EventDomainFronting) {
@@ -58,6 +72,11 @@ func (p prometheusProcessor) EventDomainFronting(evt mtglib.EventDomainFronting)
 	info.isDomainFronted = true
 
 	p.factory.metricDomainFronting.Inc()
+
+	if sni, ok := p.factory.frontedSNILabel(evt); ok {
+		p.factory.metricDomainFrontingSNI.WithLabelValues(sni).Inc()
+	}
+
 	p.factory.metricDomainFrontingConnections.
 		WithLabelValues(info.tags[TagIPFamily]).
 		Inc()
@@ -85,8 +104,14 @@ func (p prometheusProcessor) EventTraffic(evt mtglib.EventTraffic) {
 			Add(float64(evt.Traffic))
 	} else {
 		p.factory.metricTelegramTraffic.
-			WithLabelValues(info.tags[TagTelegramIP], info.tags[TagDC], direction).
+			WithLabelValues(info.tags[TagTelegramIP], info.tags[TagTelegramIPFamily], info.tags[TagDC], direction).
 			Add(float64(evt.Traffic))
+
+		if evt.IsRead {
+			info.bytesToClient += uint64(evt.Traffic)
+		} else {
+			info.bytesFromClient += uint64(evt.Traffic)
+		}
 	}
 }
 
@@ -105,6 +130,17 @@ func (p prometheusProcessor) EventFinish(evt mtglib.EventFinish) {
 	if !info.startTime.IsZero() {
 		duration := time.Since(info.startTime).Seconds()
 		p.factory.metricSessionDuration.Observe(duration)
+
+		// Средний throughput сессии Telegram по направлениям: отличает
+		// загрузку медиа от чата. Domain fronting не учитывается.
+		if _, ok := info.tags[TagDC]; ok && !info.isDomainFronted && duration > 0 {
+			p.factory.metricStreamThroughput.
+				WithLabelValues(TagDirectionToClient).
+				Observe(float64(info.bytesToClient) / duration)
+			p.factory.metricStreamThroughput.
+				WithLabelValues(TagDirectionFromClient).
+				Observe(float64(info.bytesFromClient) / duration)
+		}
 	}
 
 	p.factory.metricClientConnections.
@@ -117,7 +153,7 @@ func (p prometheusProcessor) EventFinish(evt mtglib.EventFinish) {
 			Dec()
 	} else if telegramIP, ok := info.tags[TagTelegramIP]; ok {
 		p.factory.metricTelegramConnections.
-			WithLabelValues(telegramIP, info.tags[TagDC]).
+			WithLabelValues(telegramIP, info.tags[TagTelegramIPFamily], info.tags[TagDC]).
 			Dec()
 	}
 }
@@ -126,6 +162,10 @@ func (p prometheusProcessor) EventConcurrencyLimited(_ mtglib.EventConcurrencyLi
 	p.factory.metricConcurrencyLimited.Inc()
 }
 
+func (p prometheusProcessor) EventRateLimited(_ mtglib.EventRateLimited) {
+	p.factory.metricRateLimitRejects.Inc()
+}
+
 func (p prometheusProcessor) EventIPBlocklisted(evt mtglib.EventIPBlocklisted) {
 	tag := TagIPListBlock
 	if !evt.IsBlockList {
@@ -135,8 +175,24 @@ func (p prometheusProcessor) EventIPBlocklisted(evt mtglib.EventIPBlocklisted) {
 	p.factory.metricIPBlocklisted.WithLabelValues(tag).Inc()
 }
 
-func (p prometheusProcessor) EventReplayAttack(_ mtglib.EventReplayAttack) {
+func (p prometheusProcessor) EventReplayAttack(evt mtglib.EventReplayAttack) {
 	p.factory.metricReplayAttacks.Inc()
+
+	if source := p.factory.replayAttackSourceLabel(evt); source != "" {
+		p.factory.metricReplayAttackSources.WithLabelValues(source).Inc()
+	}
+}
+
+func (p prometheusProcessor) EventTelegramHandshakeFailed(evt mtglib.EventTelegramHandshakeFailed) {
+	p.factory.metricTelegramHandshakeFailures.
+		WithLabelValues(strconv.Itoa(evt.DC), evt.Reason).
+		Inc()
+}
+
+func (p prometheusProcessor) EventScannerDetected(evt mtglib.EventScannerDetected) {
+	p.factory.metricScannerProbes.
+		WithLabelValues(evt.Reason).
+		Inc()
 }
 
 func (p prometheusProcessor) EventIPListSize(evt mtglib.EventIPListSize) {
@@ -163,12 +219,112 @@ func (p prometheusProcessor) EventDNSCacheMetrics(evt mtglib.EventDNSCacheMetric
 
 func (p prometheusProcessor) EventPoolMetrics(evt mtglib.EventPoolMetrics) {
 	p.factory.UpdatePoolMetricsDelta(evt.DC, evt.DeltaHits, evt.DeltaMisses, evt.DeltaUnhealthy, evt.Idle)
+
+	// Без обращений к пулу за интервал ratio не определён: оставляем
+	// прошлое значение.
+	if ratio, ok := evt.HitRatio(); ok {
+		p.factory.metricPoolHitRatio.WithLabelValues(strconv.Itoa(evt.DC)).Set(ratio)
+	}
 }
 
 func (p prometheusProcessor) EventRateLimiterMetrics(evt mtglib.EventRateLimiterMetrics) {
 	p.factory.metricRateLimiterSize.Set(float64(evt.Size))
 }
 
+func (p prometheusProcessor) EventWorkerPoolPressure(evt mtglib.EventWorkerPoolPressure) {
+	if evt.High {
+		p.factory.metricWorkerPoolPressure.Set(1)
+	} else {
+		p.factory.metricWorkerPoolPressure.Set(0)
+	}
+}
+
+func (p prometheusProcessor) EventClientTimeSkew(evt mtglib.EventClientTimeSkew) {
+	p.factory.metricClientTimeSkew.Observe(evt.Skew.Abs().Seconds())
+
+	if evt.Rejected {
+		p.factory.metricClientTimeSkewRejections.Inc()
+	}
+}
+
+func (p prometheusProcessor) EventDNSQueriesSkipped(evt mtglib.EventDNSQueriesSkipped) {
+	p.factory.metricDNSQueriesSkipped.WithLabelValues(TagQueryTypeA).Add(float64(evt.DeltaA))
+	p.factory.metricDNSQueriesSkipped.WithLabelValues(TagQueryTypeAAAA).Add(float64(evt.DeltaAAAA))
+}
+
+func (p prometheusProcessor) EventDomainFrontingDial(evt mtglib.EventDomainFrontingDial) {
+	if evt.Failed {
+		p.factory.metricDomainFrontingDialFailures.Inc()
+
+		return
+	}
+
+	p.factory.metricDomainFrontingDialDuration.Observe(evt.Duration.Seconds())
+}
+
+func (p prometheusProcessor) EventWorkerQueueWait(evt mtglib.EventWorkerQueueWait) {
+	p.factory.metricWorkerQueueWait.Observe(evt.Duration.Seconds())
+}
+
+func (p prometheusProcessor) EventTarpitted(_ mtglib.EventTarpitted) {
+	p.factory.metricTarpittedConnections.Inc()
+}
+
+func (p prometheusProcessor) EventDraining(_ mtglib.EventDraining) {
+	p.factory.metricDraining.Set(1)
+}
+
+func (p prometheusProcessor) EventDCConfigStale(evt mtglib.EventDCConfigStale) {
+	p.factory.metricDCConfigFailures.Set(float64(evt.Failures))
+}
+
+func (p prometheusProcessor) EventDoHQueriesLimited(evt mtglib.EventDoHQueriesLimited) {
+	p.factory.metricDoHQueriesLimited.Add(float64(evt.Delta))
+}
+
+func (p prometheusProcessor) EventTelegramDCSkipped(evt mtglib.EventTelegramDCSkipped) {
+	p.factory.metricTelegramDCSkips.WithLabelValues(strconv.Itoa(evt.DC)).Inc()
+}
+
+func (p prometheusProcessor) EventTelegramDialMetrics(evt mtglib.EventTelegramDialMetrics) {
+	dc := strconv.Itoa(evt.DC)
+
+	p.factory.metricTelegramDials.WithLabelValues(dc, TagResultSuccess).Add(float64(evt.DeltaSuccesses))
+	p.factory.metricTelegramDials.WithLabelValues(dc, TagResultFailure).Add(float64(evt.DeltaFailures))
+}
+
+func (p prometheusProcessor) EventAcceptBackpressure(evt mtglib.EventAcceptBackpressure) {
+	p.factory.metricAcceptBackpressure.Add(evt.Duration.Seconds())
+}
+
+func (p prometheusProcessor) EventTelegramHandshakeRetried(evt mtglib.EventTelegramHandshakeRetried) {
+	dc := strconv.Itoa(evt.DC)
+
+	p.factory.metricTelegramHandshakeRetries.WithLabelValues(dc).Inc()
+
+	if evt.Failed {
+		p.factory.metricTelegramHandshakeRetryFailures.WithLabelValues(dc).Inc()
+	}
+}
+
+func (p prometheusProcessor) EventDomainFrontingLimited(_ mtglib.EventDomainFrontingLimited) {
+	p.factory.metricDomainFrontingLimited.Inc()
+}
+
+func (p prometheusProcessor) EventRelaysLimited(_ mtglib.EventRelaysLimited) {
+	p.factory.metricRelaysLimited.Inc()
+}
+
+func (p prometheusProcessor) EventConcurrencyMetrics(evt mtglib.EventConcurrencyMetrics) {
+	p.factory.metricWorkerPoolBusy.Set(float64(evt.Workers))
+	p.factory.metricRelaysActive.Set(float64(evt.Relays))
+}
+
+func (p prometheusProcessor) EventRelayStarted(evt mtglib.EventRelayStarted) {
+	p.factory.metricRelayStarted.WithLabelValues(strconv.Itoa(evt.DC)).Inc()
+	p.factory.metricHandshakeDuration.Observe(evt.HandshakeDuration.Seconds())
+}
+
 func (p prometheusProcessor) Shutdown() {
 	for k, v := range p.streams {
 		releaseStreamInfo(v)
@@ -184,6 +340,10 @@ func (p prometheusProcessor) Shutdown() {
 type PrometheusFactory struct {
 	httpServer *http.Server
 
+	replayAttackSource string
+	frontedSNI         string
+	healthCheck        atomic.Pointer[func() bool]
+
 	metricClientConnections         *prometheus.GaugeVec
 	metricTelegramConnections       *prometheus.GaugeVec
 	metricDomainFrontingConnections *prometheus.GaugeVec
@@ -194,27 +354,61 @@ type PrometheusFactory struct {
 	metricIPBlocklisted         *prometheus.CounterVec
 	metricIPListCacheFallback   *prometheus.CounterVec
 
+	metricTelegramHandshakeFailures *prometheus.CounterVec
+	metricScannerProbes             *prometheus.CounterVec
+	metricTelegramConnectionsTFO    *prometheus.CounterVec
+	metricTelegramDCSkips           *prometheus.CounterVec
+	metricTelegramDials             *prometheus.CounterVec
+
+	metricTelegramHandshakeRetries       *prometheus.CounterVec
+	metricTelegramHandshakeRetryFailures *prometheus.CounterVec
+	metricRelayStarted                   *prometheus.CounterVec
+	metricHandshakeDuration              prometheus.Histogram
+
 	metricDomainFronting     prometheus.Counter
 	metricConcurrencyLimited prometheus.Counter
 	metricReplayAttacks      prometheus.Counter
+	metricWorkerPoolPressure prometheus.Gauge
+	metricWorkerPoolBusy     prometheus.Gauge
+	metricRelaysActive       prometheus.Gauge
+
+	metricReplayAttackSources *prometheus.CounterVec
+	metricDomainFrontingSNI   *prometheus.CounterVec
+
+	metricDomainFrontingDialFailures prometheus.Counter
+	metricDomainFrontingDialDuration prometheus.Histogram
+	metricWorkerQueueWait            prometheus.Histogram
+	metricAcceptBackpressure         prometheus.Counter
+	metricTarpittedConnections       prometheus.Counter
+	metricDomainFrontingLimited      prometheus.Counter
+	metricRelaysLimited              prometheus.Counter
+	metricDraining                   prometheus.Gauge
+	metricDCConfigFailures           prometheus.Gauge
 
 	// Performance metrics (PHASE 3)
 	metricDNSCacheHits      prometheus.Counter
 	metricDNSCacheMisses    prometheus.Counter
 	metricDNSCacheSize      prometheus.Gauge
 	metricDNSCacheEvictions prometheus.Counter
+	metricDNSQueriesSkipped *prometheus.CounterVec
+	metricDoHQueriesLimited prometheus.Counter
 	metricRateLimitRejects  prometheus.Counter
 	metricRateLimiterSize   prometheus.Gauge
 
 	// Mobile optimization metrics (PHASE 4)
-	metricSessionDuration prometheus.Histogram // Длительность сессий для расчёта throughput
-	metricTTFB            prometheus.Histogram // Time To First Byte для latency анализа
+	metricSessionDuration  prometheus.Histogram     // Длительность сессий для расчёта throughput
+	metricTTFB             prometheus.Histogram     // Time To First Byte для latency анализа
+	metricClientTimeSkew   prometheus.Histogram     // Расхождение часов клиента и прокси
+	metricStreamThroughput *prometheus.HistogramVec // Средний throughput сессии по направлениям
+
+	metricClientTimeSkewRejections prometheus.Counter // Отказы по времени client hello
 
 	// Connection pool metrics (PHASE 3.3)
 	metricPoolHits      *prometheus.CounterVec // Успешные взятия из пула
 	metricPoolMisses    *prometheus.CounterVec // Промахи (создание нового)
 	metricPoolUnhealthy *prometheus.CounterVec // Отклонено нездоровых
 	metricPoolIdle      *prometheus.GaugeVec   // Текущее количество idle
+	metricPoolHitRatio  *prometheus.GaugeVec   // Доля hits за последний интервал
 
 	// Build info metric
 	metricBuildInfo *prometheus.GaugeVec
@@ -233,12 +427,85 @@ func (p *PrometheusFactory) Serve(listener net.Listener) error {
 	return p.httpServer.Serve(listener) //nolint: wrapcheck
 }
 
-// Close stops a factory. Please pay attention that underlying listener
-// is not closed.
+// Handler returns an HTTP handler which serves metrics. It can be used to
+// expose metrics without a dedicated listener, e.g. with
+// mtglib.InBandMetrics.
+func (p *PrometheusFactory) Handler() http.Handler {
+	return p.httpServer.Handler
+}
+
+// Close stops a factory. A listener given to Serve is closed as well; for
+// unix sockets this also removes the socket file.
 func (p *PrometheusFactory) Close() error {
 	return p.httpServer.Shutdown(context.Background()) //nolint: wrapcheck
 }
 
+// SetHealthCheck sets a function which HealthPath endpoint consults:
+// it responds with 503 if check returns false and with 200 otherwise.
+// Without a check, the endpoint always responds with 200. If metrics are
+// served on HealthPath, there is no health endpoint.
+//
+// It is safe to call this method at any time.
+func (p *PrometheusFactory) SetHealthCheck(check func() bool) {
+	p.healthCheck.Store(&check)
+}
+
+func (p *PrometheusFactory) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	if check := p.healthCheck.Load(); check != nil && *check != nil && !(*check)() {
+		http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	w.Write([]byte("ok\n")) //nolint: errcheck
+}
+
+// SetReplayAttackSource sets how replay attacks are labeled by source.
+// Valid values are ReplayAttackSourceOff (default), ReplayAttackSourceHashed
+// and ReplayAttackSourceRaw. Please call it before Make.
+func (p *PrometheusFactory) SetReplayAttackSource(mode string) {
+	p.replayAttackSource = mode
+}
+
+func (p *PrometheusFactory) replayAttackSourceLabel(evt mtglib.EventReplayAttack) string {
+	if evt.RemoteIP == nil {
+		return ""
+	}
+
+	switch p.replayAttackSource {
+	case ReplayAttackSourceHashed:
+		return evt.RemoteIPHash()[:replayAttackSourceBucketLen]
+	case ReplayAttackSourceRaw:
+		return evt.RemoteIP.String()
+	}
+
+	return ""
+}
+
+// SetFrontedSNI sets how domain fronted connections are labeled by SNI.
+// Valid values are FrontedSNIHashed (default), FrontedSNIRaw and
+// FrontedSNIOff. Please call it before Make.
+func (p *PrometheusFactory) SetFrontedSNI(mode string) {
+	p.frontedSNI = mode
+}
+
+func (p *PrometheusFactory) frontedSNILabel(evt mtglib.EventDomainFronting) (string, bool) {
+	switch {
+	case p.frontedSNI == FrontedSNIOff:
+		return "", false
+	case evt.ExpectedSNI, p.frontedSNI == FrontedSNIRaw:
+		// SNI не аутентифицирован, а невалидный UTF-8 в метке роняет
+		// prometheus client.
+		return strings.ToValidUTF8(evt.SNI, "?"), true
+	}
+
+	if hash := evt.SNIHash(); hash != "" {
+		return hash[:frontedSNIBucketLen], true
+	}
+
+	return "", true
+}
+
 // UpdateDNSCacheMetrics updates DNS cache metrics from provided stats.
 // This should be called periodically (e.g., every 10 seconds) to keep metrics fresh.
 func (p *PrometheusFactory) UpdateDNSCacheMetrics(hits, misses, evictions uint64, size int) {
@@ -248,11 +515,6 @@ func (p *PrometheusFactory) UpdateDNSCacheMetrics(hits, misses, evictions uint64
 	p.metricDNSCacheSize.Set(float64(size))
 }
 
-// IncrementRateLimitRejects increments the rate limit rejection counter.
-func (p *PrometheusFactory) IncrementRateLimitRejects() {
-	p.metricRateLimitRejects.Inc()
-}
-
 // UpdatePoolMetrics updates connection pool metrics for a specific DC.
 // Should be called periodically to keep metrics fresh.
 func (p *PrometheusFactory) UpdatePoolMetrics(dc int, hits, misses, unhealthy uint64, idle int) {
@@ -281,8 +543,10 @@ func (p *PrometheusFactory) UpdatePoolMetricsDelta(dc int, deltaHits, deltaMisse
 }
 
 // NewPrometheus builds an events.ObserverFactory which can serve HTTP
-// endpoint with Prometheus scrape data.
-func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory { //nolint: funlen
+// endpoint with Prometheus scrape data. Latency histograms can be tuned
+// with options; defaults are used otherwise.
+func NewPrometheus(metricPrefix, httpPath, version string, opts ...PrometheusOption) *PrometheusFactory { //nolint: funlen
+	options := newPrometheusOptions(opts)
 	registry := prometheus.NewPedanticRegistry()
 	httpHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
@@ -309,7 +573,7 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 			Namespace: metricPrefix,
 			Name:      MetricTelegramConnections,
 			Help:      "A number of connections to Telegram servers.",
-		}, []string{TagTelegramIP, TagDC}),
+		}, []string{TagTelegramIP, TagTelegramIPFamily, TagDC}),
 		metricDomainFrontingConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: metricPrefix,
 			Name:      MetricDomainFrontingConnections,
@@ -325,7 +589,7 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 			Namespace: metricPrefix,
 			Name:      MetricTelegramTraffic,
 			Help:      "Traffic which is generated talking with Telegram servers.",
-		}, []string{TagTelegramIP, TagDC, TagDirection}),
+		}, []string{TagTelegramIP, TagTelegramIPFamily, TagDC, TagDirection}),
 		metricDomainFrontingTraffic: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: metricPrefix,
 			Name:      MetricDomainFrontingTraffic,
@@ -341,22 +605,140 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 			Name:      MetricIPListCacheFallback,
 			Help:      "A number of updates where cached ip list snapshot was used after remote fetch failure.",
 		}, []string{TagIPList}),
+		metricTelegramHandshakeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramHandshakeFailures,
+			Help:      "A number of failed obfuscated2 handshakes with Telegram servers.",
+		}, []string{TagDC, TagReason}),
+		metricScannerProbes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricScannerProbes,
+			Help:      "A number of client connections which clearly were not TLS handshakes.",
+		}, []string{TagReason}),
+		metricTelegramConnectionsTFO: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramConnectionsTFO,
+			Help:      "A number of connections to Telegram servers established with TCP Fast Open cookie.",
+		}, []string{TagDC}),
+		metricTelegramDCSkips: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramDCSkips,
+			Help:      "A number of connections sent to a fallback DC because a requested DC has recently failed.",
+		}, []string{TagDC}),
+		metricTelegramDials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramDials,
+			Help:      "A number of established and failed connections to Telegram servers, both direct and pooled.",
+		}, []string{TagDC, TagResult}),
+		metricTelegramHandshakeRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramHandshakeRetries,
+			Help:      "A number of handshakes with Telegram retried on a fresh connection after broken pipe.",
+		}, []string{TagDC}),
+		metricTelegramHandshakeRetryFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTelegramHandshakeRetryFailures,
+			Help:      "A number of retried handshakes with Telegram which have failed too.",
+		}, []string{TagDC}),
+		metricRelayStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricRelayStarted,
+			Help:      "A number of client connections which have completed handshakes and started relaying to Telegram.",
+		}, []string{TagDC}),
+		metricHandshakeDuration: prometheus.NewHistogram(options.histogramOpts(prometheus.HistogramOpts{
+			Namespace: metricPrefix,
+			Name:      MetricHandshakeDuration + "_seconds",
+			Help:      "Time from a start of client connection processing to a start of relay.",
+			Buckets:   options.handshakeDurationBuckets,
+		})),
 
 		metricDomainFronting: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: metricPrefix,
 			Name:      MetricDomainFronting,
 			Help:      "A number of routings to front domain.",
 		}),
+		metricDomainFrontingDialFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDomainFrontingDialFailures,
+			Help:      "A number of failed dials to front domain.",
+		}),
+		metricDomainFrontingDialDuration: prometheus.NewHistogram(options.histogramOpts(prometheus.HistogramOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDomainFrontingDialDuration + "_seconds",
+			Help:      "Time of successful dials to front domain, including DNS resolving.",
+			Buckets:   options.domainFrontingDialBuckets,
+		})),
+		metricWorkerQueueWait: prometheus.NewHistogram(options.histogramOpts(prometheus.HistogramOpts{
+			Namespace: metricPrefix,
+			Name:      MetricWorkerQueueWait + "_seconds",
+			Help:      "Time between accept of a connection and a start of its serving by a worker.",
+			Buckets:   options.workerQueueWaitBuckets,
+		})),
+		metricAcceptBackpressure: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricAcceptBackpressure + "_seconds_total",
+			Help:      "Total time when new connections were not accepted because of backpressure.",
+		}),
+		metricTarpittedConnections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricTarpittedConnections,
+			Help:      "A number of client connections with invalid handshakes which were tarpitted.",
+		}),
+		metricDomainFrontingLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDomainFrontingLimited,
+			Help:      "A number of connections which were closed because of the domain fronting connection limit.",
+		}),
+		metricRelaysLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricRelaysLimited,
+			Help:      "A number of clients which were closed after a handshake because of the relay limit.",
+		}),
+		metricDraining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDraining,
+			Help:      "1 if proxy is draining: it does not accept new connections but serves existing ones.",
+		}),
+		metricDCConfigFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDCConfigFailures,
+			Help:      "A number of consecutive failed DC config loads while hardcoded DC addresses are in use.",
+		}),
 		metricConcurrencyLimited: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: metricPrefix,
 			Name:      MetricConcurrencyLimited,
 			Help:      "A number of sessions that were rejected by concurrency limiter.",
 		}),
+		metricWorkerPoolPressure: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      MetricWorkerPoolPressure,
+			Help:      "1 if worker pool is close to saturation, 0 otherwise.",
+		}),
+		metricWorkerPoolBusy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      MetricWorkerPoolBusy,
+			Help:      "A number of busy workers in the worker pool.",
+		}),
+		metricRelaysActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      MetricRelaysActive,
+			Help:      "A number of active relays to Telegram.",
+		}),
 		metricReplayAttacks: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: metricPrefix,
 			Name:      MetricReplayAttacks,
 			Help:      "A number of detected replay attacks.",
 		}),
+		metricDomainFrontingSNI: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDomainFrontingSNI,
+			Help:      "A number of domain fronted connections by SNI sent by a client.",
+		}, []string{TagSNI}),
+		metricReplayAttackSources: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricReplayAttackSources,
+			Help:      "A number of detected replay attacks per source.",
+		}, []string{TagSource}),
 
 		// Performance metrics (PHASE 3)
 		metricDNSCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
@@ -379,9 +761,19 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 			Name:      "dns_cache_evictions",
 			Help:      "Number of DNS cache entries evicted due to LRU policy.",
 		}),
+		metricDNSQueriesSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDNSQueriesSkipped,
+			Help:      "Number of DNS queries not sent because of a DNS query type setting.",
+		}, []string{TagQueryType}),
+		metricDoHQueriesLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricDoHQueriesLimited,
+			Help:      "Number of DoH queries which waited for or failed because of a limit of concurrent queries.",
+		}),
 		metricRateLimitRejects: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: metricPrefix,
-			Name:      "rate_limit_rejects",
+			Name:      MetricRateLimitRejects,
 			Help:      "Number of connections rejected due to rate limiting.",
 		}),
 		metricRateLimiterSize: prometheus.NewGauge(prometheus.GaugeOpts{
@@ -391,17 +783,34 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 		}),
 
 		// Mobile optimization metrics (PHASE 4)
-		metricSessionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+		metricSessionDuration: prometheus.NewHistogram(options.histogramOpts(prometheus.HistogramOpts{
 			Namespace: metricPrefix,
 			Name:      "session_duration_seconds",
 			Help:      "Duration of client sessions in seconds. Use with traffic metrics to calculate throughput.",
-			Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
-		}),
-		metricTTFB: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Buckets:   options.sessionDurationBuckets,
+		})),
+		metricTTFB: prometheus.NewHistogram(options.histogramOpts(prometheus.HistogramOpts{
 			Namespace: metricPrefix,
 			Name:      "time_to_first_byte_seconds",
 			Help:      "Time from connection start to first byte received (download latency indicator).",
-			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5},
+			Buckets:   options.ttfbBuckets,
+		})),
+		metricStreamThroughput: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricPrefix,
+			Name:      "stream_throughput_bytes_per_second",
+			Help:      "Average throughput of finished Telegram sessions by direction (traffic divided by session duration).",
+			Buckets:   []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216},
+		}, []string{TagDirection}),
+		metricClientTimeSkew: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricPrefix,
+			Name:      MetricClientTimeSkew + "_seconds",
+			Help:      "Absolute difference between proxy time and FakeTLS client hello timestamp.",
+			Buckets:   []float64{0.5, 1, 2, 3, 5, 10, 30, 60, 300, 3600},
+		}),
+		metricClientTimeSkewRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricPrefix,
+			Name:      MetricClientTimeSkewRejections,
+			Help:      "Number of FakeTLS client hellos rejected because of time skewness.",
 		}),
 
 		// Connection pool metrics (PHASE 3.3)
@@ -425,6 +834,11 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 			Name:      "connection_pool_idle",
 			Help:      "Current number of idle connections in pool.",
 		}, []string{TagDC}),
+		metricPoolHitRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricPrefix,
+			Name:      "connection_pool_hit_ratio",
+			Help:      "Share of connections taken from pool among all pool lookups over the last update interval.",
+		}, []string{TagDC}),
 
 		// Build info metric
 		metricBuildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
@@ -443,32 +857,70 @@ func NewPrometheus(metricPrefix, httpPath, version string) *PrometheusFactory {
 	registry.MustRegister(factory.metricDomainFrontingTraffic)
 	registry.MustRegister(factory.metricIPBlocklisted)
 	registry.MustRegister(factory.metricIPListCacheFallback)
+	registry.MustRegister(factory.metricTelegramHandshakeFailures)
+	registry.MustRegister(factory.metricScannerProbes)
+	registry.MustRegister(factory.metricTelegramConnectionsTFO)
+	registry.MustRegister(factory.metricTelegramDCSkips)
+	registry.MustRegister(factory.metricTelegramDials)
+	registry.MustRegister(factory.metricTelegramHandshakeRetries)
+	registry.MustRegister(factory.metricTelegramHandshakeRetryFailures)
+	registry.MustRegister(factory.metricRelayStarted)
+	registry.MustRegister(factory.metricHandshakeDuration)
 
 	registry.MustRegister(factory.metricDomainFronting)
 	registry.MustRegister(factory.metricConcurrencyLimited)
+	registry.MustRegister(factory.metricWorkerPoolPressure)
+	registry.MustRegister(factory.metricWorkerPoolBusy)
+	registry.MustRegister(factory.metricRelaysActive)
 	registry.MustRegister(factory.metricReplayAttacks)
+	registry.MustRegister(factory.metricReplayAttackSources)
+	registry.MustRegister(factory.metricDomainFrontingSNI)
+	registry.MustRegister(factory.metricDomainFrontingDialFailures)
+	registry.MustRegister(factory.metricDomainFrontingDialDuration)
+	registry.MustRegister(factory.metricWorkerQueueWait)
+	registry.MustRegister(factory.metricAcceptBackpressure)
+	registry.MustRegister(factory.metricTarpittedConnections)
+	registry.MustRegister(factory.metricDomainFrontingLimited)
+	registry.MustRegister(factory.metricRelaysLimited)
+	registry.MustRegister(factory.metricDraining)
+	registry.MustRegister(factory.metricDCConfigFailures)
 
 	// Register performance metrics (PHASE 3)
 	registry.MustRegister(factory.metricDNSCacheHits)
 	registry.MustRegister(factory.metricDNSCacheMisses)
 	registry.MustRegister(factory.metricDNSCacheSize)
 	registry.MustRegister(factory.metricDNSCacheEvictions)
+	registry.MustRegister(factory.metricDNSQueriesSkipped)
+	registry.MustRegister(factory.metricDoHQueriesLimited)
 	registry.MustRegister(factory.metricRateLimitRejects)
 	registry.MustRegister(factory.metricRateLimiterSize)
 
 	// Register mobile optimization metrics (PHASE 4)
 	registry.MustRegister(factory.metricSessionDuration)
 	registry.MustRegister(factory.metricTTFB)
+	registry.MustRegister(factory.metricStreamThroughput)
+	registry.MustRegister(factory.metricClientTimeSkew)
+	registry.MustRegister(factory.metricClientTimeSkewRejections)
 
 	// Register connection pool metrics (PHASE 3.3)
 	registry.MustRegister(factory.metricPoolHits)
 	registry.MustRegister(factory.metricPoolMisses)
 	registry.MustRegister(factory.metricPoolUnhealthy)
 	registry.MustRegister(factory.metricPoolIdle)
+	registry.MustRegister(factory.metricPoolHitRatio)
 
 	// Register build info metric and set version
 	registry.MustRegister(factory.metricBuildInfo)
 	factory.metricBuildInfo.WithLabelValues(version).Set(1)
 
+	if httpPath != HealthPath {
+		mux.HandleFunc(HealthPath, factory.serveHealth)
+	}
+
+	if options.runtimeMetrics {
+		registry.MustRegister(collectors.NewGoCollector())
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
 	return factory
 }
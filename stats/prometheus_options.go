@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusOption customizes a factory built by NewPrometheus.
+type PrometheusOption func(*prometheusOptions)
+
+type prometheusOptions struct {
+	sessionDurationBuckets    []float64
+	ttfbBuckets               []float64
+	domainFrontingDialBuckets []float64
+	workerQueueWaitBuckets    []float64
+	handshakeDurationBuckets  []float64
+
+	nativeHistogramBucketFactor float64
+	runtimeMetrics              bool
+}
+
+func (p prometheusOptions) histogramOpts(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if p.nativeHistogramBucketFactor > 1 {
+		opts.NativeHistogramBucketFactor = p.nativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = 160 //nolint: gomnd
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+
+	return opts
+}
+
+func newPrometheusOptions(opts []PrometheusOption) prometheusOptions {
+	rv := prometheusOptions{
+		sessionDurationBuckets:    []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+		ttfbBuckets:               []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5},
+		domainFrontingDialBuckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+		workerQueueWaitBuckets:    []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+		handshakeDurationBuckets:  []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}
+
+	for _, opt := range opts {
+		opt(&rv)
+	}
+
+	return rv
+}
+
+// validBuckets проверяет, что границы строго возрастают: на других
+// prometheus паникует при создании гистограммы.
+func validBuckets(buckets []float64) bool {
+	if len(buckets) == 0 {
+		return false
+	}
+
+	if math.IsNaN(buckets[0]) {
+		return false
+	}
+
+	for i := 1; i < len(buckets); i++ {
+		if math.IsNaN(buckets[i]) || buckets[i] <= buckets[i-1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithSessionDurationBuckets sets bucket boundaries (in seconds) of a
+// session duration histogram in increasing order. Empty slice keeps
+// defaults, so does a slice which is not strictly increasing: prometheus
+// panics on it.
+func WithSessionDurationBuckets(buckets []float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		if validBuckets(buckets) {
+			p.sessionDurationBuckets = buckets
+		}
+	}
+}
+
+// WithTTFBBuckets sets bucket boundaries (in seconds) of a time to first
+// byte histogram in increasing order. Empty or invalid slice keeps
+// defaults, please see WithSessionDurationBuckets.
+func WithTTFBBuckets(buckets []float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		if validBuckets(buckets) {
+			p.ttfbBuckets = buckets
+		}
+	}
+}
+
+// WithDomainFrontingDialBuckets sets bucket boundaries (in seconds) of a
+// histogram of dial times to a fronting domain in increasing order.
+// Empty or invalid slice keeps defaults, please see
+// WithSessionDurationBuckets.
+func WithDomainFrontingDialBuckets(buckets []float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		if validBuckets(buckets) {
+			p.domainFrontingDialBuckets = buckets
+		}
+	}
+}
+
+// WithWorkerQueueWaitBuckets sets bucket boundaries (in seconds) of a
+// histogram of time which accepted connections wait for a worker in
+// increasing order. Empty or invalid slice keeps defaults, please see
+// WithSessionDurationBuckets.
+func WithWorkerQueueWaitBuckets(buckets []float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		if validBuckets(buckets) {
+			p.workerQueueWaitBuckets = buckets
+		}
+	}
+}
+
+// WithHandshakeDurationBuckets sets bucket boundaries (in seconds) of a
+// histogram of time from a start of client connection processing to a
+// start of relay in increasing order. Empty or invalid slice keeps
+// defaults, please see WithSessionDurationBuckets.
+func WithHandshakeDurationBuckets(buckets []float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		if validBuckets(buckets) {
+			p.handshakeDurationBuckets = buckets
+		}
+	}
+}
+
+// WithNativeHistograms additionally exposes latency histograms as
+// Prometheus native histograms with a given growth factor between
+// buckets, like 1.1. Classic buckets are kept for scrapers which do not
+// support native histograms. Factor <= 1 disables native histograms.
+func WithNativeHistograms(bucketFactor float64) PrometheusOption {
+	return func(p *prometheusOptions) {
+		p.nativeHistogramBucketFactor = bucketFactor
+	}
+}
+
+// WithRuntimeMetrics additionally exposes standard go_* and process_*
+// metrics of mtg process: memory, GC pauses, goroutines, open file
+// descriptors and so on. They have no metric prefix.
+func WithRuntimeMetrics() PrometheusOption {
+	return func(p *prometheusOptions) {
+		p.runtimeMetrics = true
+	}
+}
@@ -69,7 +69,7 @@ func (suite *PrometheusTestSuite) TestTelegramPath() {
 
 	data, err = suite.Get()
 	suite.NoError(err)
-	suite.Contains(data, `mtg_telegram_connections{dc="4",telegram_ip="10.0.0.1"} 1`)
+	suite.Contains(data, `mtg_telegram_connections{dc="4",telegram_ip="10.0.0.1",telegram_ip_family="ipv4"} 1`)
 
 	suite.prometheus.EventTraffic(
 		mtglib.NewEventTraffic("connID", 200, true))
@@ -77,7 +77,7 @@ func (suite *PrometheusTestSuite) TestTelegramPath() {
 
 	data, err = suite.Get()
 	suite.NoError(err)
-	suite.Contains(data, `mtg_telegram_traffic{dc="4",direction="to_client",telegram_ip="10.0.0.1"} 200`)
+	suite.Contains(data, `mtg_telegram_traffic{dc="4",direction="to_client",telegram_ip="10.0.0.1",telegram_ip_family="ipv4"} 200`)
 
 	suite.prometheus.EventTraffic(
 		mtglib.NewEventTraffic("connID", 100, false))
@@ -85,7 +85,7 @@ func (suite *PrometheusTestSuite) TestTelegramPath() {
 
 	data, err = suite.Get()
 	suite.NoError(err)
-	suite.Contains(data, `mtg_telegram_traffic{dc="4",direction="from_client",telegram_ip="10.0.0.1"} 100`)
+	suite.Contains(data, `mtg_telegram_traffic{dc="4",direction="from_client",telegram_ip="10.0.0.1",telegram_ip_family="ipv4"} 100`)
 
 	suite.prometheus.EventFinish(mtglib.NewEventFinish("connID"))
 	time.Sleep(100 * time.Millisecond)
@@ -93,7 +93,9 @@ func (suite *PrometheusTestSuite) TestTelegramPath() {
 	data, err = suite.Get()
 	suite.NoError(err)
 	suite.Contains(data, `mtg_client_connections{ip_family="ipv4"} 0`)
-	suite.Contains(data, `mtg_telegram_connections{dc="4",telegram_ip="10.0.0.1"} 0`)
+	suite.Contains(data, `mtg_telegram_connections{dc="4",telegram_ip="10.0.0.1",telegram_ip_family="ipv4"} 0`)
+	suite.Contains(data, `mtg_stream_throughput_bytes_per_second_count{direction="to_client"} 1`)
+	suite.Contains(data, `mtg_stream_throughput_bytes_per_second_count{direction="from_client"} 1`)
 }
 
 func (suite *PrometheusTestSuite) TestDomainFrontingPath() {
@@ -136,6 +138,7 @@ func (suite *PrometheusTestSuite) TestDomainFrontingPath() {
 	suite.NoError(err)
 	suite.Contains(data, `mtg_client_connections{ip_family="ipv4"} 0`)
 	suite.Contains(data, `mtg_domain_fronting_connections{ip_family="ipv4"} 0`)
+	suite.NotContains(data, `mtg_stream_throughput_bytes_per_second_count`)
 }
 
 func (suite *PrometheusTestSuite) TestEventConcurrencyLimited() {
@@ -148,6 +151,18 @@ func (suite *PrometheusTestSuite) TestEventConcurrencyLimited() {
 	suite.Contains(data, `mtg_concurrency_limited 1`)
 }
 
+func (suite *PrometheusTestSuite) TestEventRateLimited() {
+	suite.prometheus.EventRateLimited(
+		mtglib.NewEventRateLimited(net.ParseIP("10.0.0.10")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_rate_limit_rejects 1`)
+	suite.Contains(data, `mtg_concurrency_limited 0`)
+}
+
 func (suite *PrometheusTestSuite) TestEventIPBlocklisted() {
 	suite.prometheus.EventIPBlocklisted(
 		mtglib.NewEventIPBlocklisted(net.ParseIP("2001:db8::68")))
@@ -180,6 +195,124 @@ func (suite *PrometheusTestSuite) TestEventReplayAttack() {
 	suite.Contains(data, `mtg_replay_attacks 1`)
 }
 
+func (suite *PrometheusTestSuite) TestEventReplayAttackSourceOff() {
+	suite.prometheus.EventReplayAttack(
+		mtglib.NewEventReplayAttackFromIP("connID", net.ParseIP("10.0.0.10")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_replay_attacks 1`)
+	suite.NotContains(data, `mtg_replay_attack_sources{`)
+}
+
+func (suite *PrometheusTestSuite) TestEventReplayAttackSourceHashed() {
+	suite.factory.SetReplayAttackSource(stats.ReplayAttackSourceHashed)
+
+	evt := mtglib.NewEventReplayAttackFromIP("connID", net.ParseIP("10.0.0.10"))
+	suite.prometheus.EventReplayAttack(evt)
+	suite.prometheus.EventReplayAttack(evt)
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data,
+		fmt.Sprintf(`mtg_replay_attack_sources{source="%s"} 2`, evt.RemoteIPHash()[:4]))
+	suite.NotContains(data, "10.0.0.10")
+}
+
+func (suite *PrometheusTestSuite) TestEventReplayAttackSourceRaw() {
+	suite.factory.SetReplayAttackSource(stats.ReplayAttackSourceRaw)
+
+	suite.prometheus.EventReplayAttack(
+		mtglib.NewEventReplayAttackFromIP("connID", net.ParseIP("10.0.0.10")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_replay_attack_sources{source="10.0.0.10"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDomainFrontingSNIHashed() {
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID2", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID3", net.ParseIP("10.0.0.10")))
+
+	evt := mtglib.NewEventDomainFrontingWithSNI("connID", "scanner.example", false)
+	suite.prometheus.EventDomainFronting(evt)
+	suite.prometheus.EventDomainFronting(
+		mtglib.NewEventDomainFrontingWithSNI("connID2", "example.com", true))
+	suite.prometheus.EventDomainFronting(mtglib.NewEventDomainFronting("connID3"))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data,
+		fmt.Sprintf(`mtg_domain_fronting_sni{sni="%s"} 1`, evt.SNIHash()[:4]))
+	suite.Contains(data, `mtg_domain_fronting_sni{sni="example.com"} 1`)
+	suite.Contains(data, `mtg_domain_fronting_sni{sni=""} 1`)
+	suite.NotContains(data, "scanner.example")
+}
+
+func (suite *PrometheusTestSuite) TestEventDomainFrontingSNIRaw() {
+	suite.factory.SetFrontedSNI(stats.FrontedSNIRaw)
+
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventDomainFronting(
+		mtglib.NewEventDomainFrontingWithSNI("connID", "scanner.\xff", false))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_domain_fronting_sni{sni="scanner.?"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDomainFrontingSNIOff() {
+	suite.factory.SetFrontedSNI(stats.FrontedSNIOff)
+
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventDomainFronting(
+		mtglib.NewEventDomainFrontingWithSNI("connID", "example.com", true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_domain_fronting 1`)
+	suite.NotContains(data, `mtg_domain_fronting_sni{`)
+}
+
+func (suite *PrometheusTestSuite) TestEventPoolMetrics() {
+	suite.prometheus.EventPoolMetrics(mtglib.NewEventPoolMetrics(2, 3, 1, 0, 5))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_connection_pool_hits_total{dc="2"} 3`)
+	suite.Contains(data, `mtg_connection_pool_idle{dc="2"} 5`)
+	suite.Contains(data, `mtg_connection_pool_hit_ratio{dc="2"} 0.75`)
+
+	// Интервал без обращений к пулу не сбрасывает ratio.
+	suite.prometheus.EventPoolMetrics(mtglib.NewEventPoolMetrics(2, 0, 0, 0, 5))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_connection_pool_hit_ratio{dc="2"} 0.75`)
+}
+
 func (suite *PrometheusTestSuite) TestEventIPListSize() {
 	suite.prometheus.EventIPListSize(mtglib.NewEventIPListSize(10, false))
 	suite.prometheus.EventIPListSize(mtglib.NewEventIPListSize(3, true))
@@ -204,6 +337,412 @@ func (suite *PrometheusTestSuite) TestEventIPListCacheFallback() {
 	suite.Contains(data, `mtg_iplist_cache_fallback{ip_list="blocklist"} 1`)
 }
 
+func (suite *PrometheusTestSuite) TestEventDNSQueriesSkipped() {
+	suite.prometheus.EventDNSQueriesSkipped(mtglib.NewEventDNSQueriesSkipped(0, 3))
+	suite.prometheus.EventDNSQueriesSkipped(mtglib.NewEventDNSQueriesSkipped(0, 2))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_dns_queries_skipped{query_type="A"} 0`)
+	suite.Contains(data, `mtg_dns_queries_skipped{query_type="AAAA"} 5`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDoHQueriesLimited() {
+	suite.prometheus.EventDoHQueriesLimited(mtglib.NewEventDoHQueriesLimited(3))
+	suite.prometheus.EventDoHQueriesLimited(mtglib.NewEventDoHQueriesLimited(0))
+	suite.prometheus.EventDoHQueriesLimited(mtglib.NewEventDoHQueriesLimited(4))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_doh_queries_limited 7`)
+}
+
+func (suite *PrometheusTestSuite) TestEventWorkerPoolPressure() {
+	suite.prometheus.EventWorkerPoolPressure(mtglib.NewEventWorkerPoolPressure(95, 100, true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_worker_pool_pressure 1`)
+
+	suite.prometheus.EventWorkerPoolPressure(mtglib.NewEventWorkerPoolPressure(70, 100, false))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_worker_pool_pressure 0`)
+}
+
+func (suite *PrometheusTestSuite) TestEventClientTimeSkew() {
+	suite.prometheus.EventClientTimeSkew(
+		mtglib.NewEventClientTimeSkew("connID", net.ParseIP("10.0.0.10"), 2*time.Second))
+	suite.prometheus.EventClientTimeSkew(
+		mtglib.NewEventClientTimeSkew("connID", net.ParseIP("10.0.0.10"), -20*time.Second))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_faketls_client_time_skew_seconds_bucket{le="1"} 0`)
+	suite.Contains(data, `mtg_faketls_client_time_skew_seconds_bucket{le="2"} 1`)
+	suite.Contains(data, `mtg_faketls_client_time_skew_seconds_bucket{le="30"} 2`)
+	suite.Contains(data, `mtg_faketls_client_time_skew_seconds_sum 22`)
+	suite.Contains(data, `mtg_faketls_client_time_skew_seconds_count 2`)
+	suite.Contains(data, `mtg_faketls_skew_rejections_total 0`)
+
+	suite.prometheus.EventClientTimeSkew(
+		mtglib.NewEventClientTimeSkewChecked("connID", net.ParseIP("10.0.0.10"), time.Minute, true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_faketls_skew_rejections_total 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDomainFrontingDial() {
+	suite.prometheus.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 200*time.Millisecond, false))
+	suite.prometheus.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 5*time.Second, true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_domain_fronting_dial_failures_total 1`)
+	suite.Contains(data, `mtg_domain_fronting_dial_duration_seconds_bucket{le="0.1"} 0`)
+	suite.Contains(data, `mtg_domain_fronting_dial_duration_seconds_bucket{le="0.25"} 1`)
+	suite.Contains(data, `mtg_domain_fronting_dial_duration_seconds_count 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventWorkerQueueWait() {
+	suite.prometheus.EventWorkerQueueWait(
+		mtglib.NewEventWorkerQueueWait("connID", 3*time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_worker_queue_wait_seconds_bucket{le="0.001"} 0`)
+	suite.Contains(data, `mtg_worker_queue_wait_seconds_bucket{le="0.005"} 1`)
+	suite.Contains(data, `mtg_worker_queue_wait_seconds_count 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventAcceptBackpressure() {
+	suite.prometheus.EventAcceptBackpressure(mtglib.NewEventAcceptBackpressure(1500 * time.Millisecond))
+	suite.prometheus.EventAcceptBackpressure(mtglib.NewEventAcceptBackpressure(time.Second))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_accept_backpressure_seconds_total 2.5`)
+}
+
+func (suite *PrometheusTestSuite) TestEventTelegramHandshakeRetried() {
+	suite.prometheus.EventTelegramHandshakeRetried(mtglib.NewEventTelegramHandshakeRetried("connID", 2, false))
+	suite.prometheus.EventTelegramHandshakeRetried(mtglib.NewEventTelegramHandshakeRetried("connID", 2, true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_telegram_handshake_retries_total{dc="2"} 2`)
+	suite.Contains(data, `mtg_telegram_handshake_retry_failures_total{dc="2"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventRelayStarted() {
+	suite.prometheus.EventRelayStarted(mtglib.NewEventRelayStarted("connID", 2, 300*time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_relay_started_total{dc="2"} 1`)
+	suite.Contains(data, `mtg_handshake_duration_seconds_bucket{le="0.25"} 0`)
+	suite.Contains(data, `mtg_handshake_duration_seconds_bucket{le="0.5"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestCustomBuckets() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+
+	factory := stats.NewPrometheus("mtg", "/", "test-version",
+		stats.WithDomainFrontingDialBuckets([]float64{0.3, 3}),
+		stats.WithTTFBBuckets(nil),
+		stats.WithNativeHistograms(1.1))
+	observer := factory.Make()
+
+	go factory.Serve(listener) //nolint: errcheck
+
+	defer func() {
+		observer.Shutdown()
+		suite.NoError(factory.Close())
+	}()
+
+	observer.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 200*time.Millisecond, false))
+
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", listener.Addr())) //nolint: noctx
+	suite.NoError(err)
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	suite.NoError(err)
+	suite.Contains(string(data), `mtg_domain_fronting_dial_duration_seconds_bucket{le="0.3"} 1`)
+	suite.NotContains(string(data), `mtg_domain_fronting_dial_duration_seconds_bucket{le="0.25"}`)
+	suite.Contains(string(data), `mtg_time_to_first_byte_seconds_bucket{le="0.05"} 0`)
+}
+
+func (suite *PrometheusTestSuite) TestHealth() {
+	url := fmt.Sprintf("http://%s%s", suite.httpListener.Addr(), stats.HealthPath)
+	healthy := true
+
+	status := func() int {
+		resp, err := http.Get(url) //nolint: noctx
+		suite.NoError(err)
+
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	suite.Equal(http.StatusOK, status())
+
+	suite.factory.SetHealthCheck(func() bool { return healthy })
+	suite.Equal(http.StatusOK, status())
+
+	healthy = false
+	suite.Equal(http.StatusServiceUnavailable, status())
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, "mtg_build_info")
+}
+
+func (suite *PrometheusTestSuite) TestRuntimeMetrics() {
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.NotContains(data, "go_goroutines")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+
+	factory := stats.NewPrometheus("mtg", "/", "test-version", stats.WithRuntimeMetrics())
+
+	go factory.Serve(listener) //nolint: errcheck
+
+	defer func() {
+		suite.NoError(factory.Close())
+	}()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", listener.Addr())) //nolint: noctx
+	suite.NoError(err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	suite.NoError(err)
+	suite.Contains(string(body), "go_goroutines")
+	suite.Contains(string(body), "go_gc_duration_seconds")
+	suite.Contains(string(body), "mtg_build_info")
+}
+
+func (suite *PrometheusTestSuite) TestInvalidBucketsKeepDefaults() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+
+	// Prometheus паникует на неупорядоченных границах, поэтому такие
+	// остаются по умолчанию.
+	factory := stats.NewPrometheus("mtg", "/", "test-version",
+		stats.WithDomainFrontingDialBuckets([]float64{3, 0.3}),
+		stats.WithTTFBBuckets([]float64{1, 1}))
+	observer := factory.Make()
+
+	go factory.Serve(listener) //nolint: errcheck
+
+	defer func() {
+		observer.Shutdown()
+		suite.NoError(factory.Close())
+	}()
+
+	observer.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 200*time.Millisecond, false))
+
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", listener.Addr())) //nolint: noctx
+	suite.NoError(err)
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	suite.NoError(err)
+	suite.Contains(string(data), `mtg_domain_fronting_dial_duration_seconds_bucket{le="0.25"} 1`)
+	suite.Contains(string(data), `mtg_time_to_first_byte_seconds_bucket{le="0.05"} 0`)
+}
+
+func (suite *PrometheusTestSuite) TestEventTarpitted() {
+	suite.prometheus.EventTarpitted(
+		mtglib.NewEventTarpitted("connID", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventTarpitted(
+		mtglib.NewEventTarpitted("connID", net.ParseIP("10.0.0.11")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_tarpitted_connections 2`)
+}
+
+func (suite *PrometheusTestSuite) TestEventRelaysLimited() {
+	suite.prometheus.EventRelaysLimited(
+		mtglib.NewEventRelaysLimited("connID", net.ParseIP("10.0.0.10")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_relays_limited 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventConcurrencyMetrics() {
+	suite.prometheus.EventConcurrencyMetrics(mtglib.NewEventConcurrencyMetrics(10, 200))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_worker_pool_busy 10`)
+	suite.Contains(data, `mtg_relays_active 200`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDomainFrontingLimited() {
+	suite.prometheus.EventDomainFrontingLimited(
+		mtglib.NewEventDomainFrontingLimited("connID", net.ParseIP("10.0.0.10")))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_domain_fronting_limited 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDraining() {
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_draining 0`)
+
+	suite.prometheus.EventDraining(mtglib.NewEventDraining())
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_draining 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventDCConfigStale() {
+	suite.prometheus.EventDCConfigStale(mtglib.NewEventDCConfigStale(4))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_dc_config_failures 4`)
+
+	suite.prometheus.EventDCConfigStale(mtglib.NewEventDCConfigStale(0))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_dc_config_failures 0`)
+}
+
+func (suite *PrometheusTestSuite) TestEventTelegramDCSkipped() {
+	suite.prometheus.EventTelegramDCSkipped(mtglib.NewEventTelegramDCSkipped("connID", 2, 4))
+	suite.prometheus.EventTelegramDCSkipped(mtglib.NewEventTelegramDCSkipped("connID", 2, 1))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_telegram_dc_skips_total{dc="2"} 2`)
+}
+
+func (suite *PrometheusTestSuite) TestEventTelegramDialMetrics() {
+	suite.prometheus.EventTelegramDialMetrics(mtglib.NewEventTelegramDialMetrics(2, 10, 1))
+	suite.prometheus.EventTelegramDialMetrics(mtglib.NewEventTelegramDialMetrics(2, 5, 0))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_telegram_dial_total{dc="2",result="success"} 15`)
+	suite.Contains(data, `mtg_telegram_dial_total{dc="2",result="failure"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventTelegramHandshakeFailed() {
+	suite.prometheus.EventTelegramHandshakeFailed(
+		mtglib.NewEventTelegramHandshakeFailed("connID", 2, mtglib.HandshakeFailureExhausted))
+	suite.prometheus.EventTelegramHandshakeFailed(
+		mtglib.NewEventTelegramHandshakeFailed("connID", 2, mtglib.HandshakeFailureWrite))
+	suite.prometheus.EventTelegramHandshakeFailed(
+		mtglib.NewEventTelegramHandshakeFailed("connID", 2, mtglib.HandshakeFailureWrite))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_telegram_handshake_failures{dc="2",reason="frame_exhausted"} 1`)
+	suite.Contains(data, `mtg_telegram_handshake_failures{dc="2",reason="write"} 2`)
+}
+
+func (suite *PrometheusTestSuite) TestEventScannerDetected() {
+	suite.prometheus.EventScannerDetected(
+		mtglib.NewEventScannerDetected("connID", net.ParseIP("10.0.0.10"), mtglib.ScannerReasonNotTLS, true))
+	suite.prometheus.EventScannerDetected(
+		mtglib.NewEventScannerDetected("connID", net.ParseIP("10.0.0.10"), mtglib.ScannerReasonNotTLS, true))
+	suite.prometheus.EventScannerDetected(
+		mtglib.NewEventScannerDetected("connID", net.ParseIP("10.0.0.10"), mtglib.ScannerReasonTruncated, true))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_scanner_probes{reason="not_tls"} 2`)
+	suite.Contains(data, `mtg_scanner_probes{reason="truncated"} 1`)
+}
+
+func (suite *PrometheusTestSuite) TestEventConnectedToDCWithTFO() {
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID1", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventStart(
+		mtglib.NewEventStart("connID2", net.ParseIP("10.0.0.10")))
+	suite.prometheus.EventConnectedToDC(
+		mtglib.NewEventConnectedToDCWithTFO("connID1", net.ParseIP("10.0.0.1"), 4, true))
+	suite.prometheus.EventConnectedToDC(
+		mtglib.NewEventConnectedToDCWithTFO("connID2", net.ParseIP("10.0.0.1"), 4, false))
+
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := suite.Get()
+	suite.NoError(err)
+	suite.Contains(data, `mtg_telegram_connections{dc="4",telegram_ip="10.0.0.1",telegram_ip_family="ipv4"} 2`)
+	suite.Contains(data, `mtg_telegram_connections_tfo_total{dc="4"} 1`)
+}
+
 func (suite *PrometheusTestSuite) TestBuildInfo() {
 	// Build info should be set immediately on creation
 	data, err := suite.Get()
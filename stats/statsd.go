@@ -19,11 +19,7 @@ type statsdProcessor struct {
 func (s statsdProcessor) EventStart(evt mtglib.EventStart) {
 	info := acquireStreamInfo()
 
-	if evt.RemoteIP.To4() != nil {
-		info.tags[TagIPFamily] = TagIPFamilyIPv4
-	} else {
-		info.tags[TagIPFamily] = TagIPFamilyIPv6
-	}
+	info.tags[TagIPFamily] = getIPFamily(evt.RemoteIP)
 
 	s.streams[evt.StreamID()] = info
 
@@ -39,12 +35,18 @@ func (s statsdProcessor) EventConnectedToDC(evt mtglib.EventConnectedToDC) {
 	}
 
 	info.tags[TagTelegramIP] = evt.RemoteIP.String()
+	info.tags[TagTelegramIPFamily] = getIPFamily(evt.RemoteIP)
 	info.tags[TagDC] = strconv.Itoa(evt.DC)
 
 	s.client.GaugeDelta(MetricTelegramConnections,
 		1,
 		info.T(TagTelegramIP),
+		info.T(TagTelegramIPFamily),
 		info.T(TagDC))
+
+	if evt.UsedTFO {
+		s.client.Incr(MetricTelegramConnectionsTFO, 1, info.T(TagDC))
+	}
 }
 
 func (s statsdProcessor) EventDomainFronting(evt mtglib.EventDomainFronting) {
@@ -77,6 +79,7 @@ func (s statsdProcessor) EventTraffic(evt mtglib.EventTraffic) {
 		s.client.Incr(MetricTelegramTraffic,
 			int64(evt.Traffic),
 			info.T(TagTelegramIP),
+			info.T(TagTelegramIPFamily),
 			info.T(TagDC),
 			directionTag)
 	}
@@ -105,6 +108,7 @@ func (s statsdProcessor) EventFinish(evt mtglib.EventFinish) {
 		s.client.GaugeDelta(MetricTelegramConnections,
 			-1,
 			info.T(TagTelegramIP),
+			info.T(TagTelegramIPFamily),
 			info.T(TagDC))
 	}
 }
@@ -113,6 +117,10 @@ func (s statsdProcessor) EventConcurrencyLimited(_ mtglib.EventConcurrencyLimite
 	s.client.Incr(MetricConcurrencyLimited, 1)
 }
 
+func (s statsdProcessor) EventRateLimited(_ mtglib.EventRateLimited) {
+	s.client.Incr(MetricRateLimitRejects, 1)
+}
+
 func (s statsdProcessor) EventIPBlocklisted(evt mtglib.EventIPBlocklisted) {
 	tag := TagIPListBlock
 	if !evt.IsBlockList {
@@ -126,6 +134,19 @@ func (s statsdProcessor) EventReplayAttack(_ mtglib.EventReplayAttack) {
 	s.client.Incr(MetricReplayAttacks, 1)
 }
 
+func (s statsdProcessor) EventTelegramHandshakeFailed(evt mtglib.EventTelegramHandshakeFailed) {
+	s.client.Incr(MetricTelegramHandshakeFailures,
+		1,
+		statsd.StringTag(TagDC, strconv.Itoa(evt.DC)),
+		statsd.StringTag(TagReason, evt.Reason))
+}
+
+func (s statsdProcessor) EventScannerDetected(evt mtglib.EventScannerDetected) {
+	s.client.Incr(MetricScannerProbes,
+		1,
+		statsd.StringTag(TagReason, evt.Reason))
+}
+
 func (s statsdProcessor) EventIPListSize(evt mtglib.EventIPListSize) {
 	tag := TagIPListBlock
 	if !evt.IsBlockList {
@@ -163,6 +184,115 @@ func (s statsdProcessor) EventRateLimiterMetrics(evt mtglib.EventRateLimiterMetr
 	s.client.Gauge("rate_limiter_tracked_ips", int64(evt.Size))
 }
 
+func (s statsdProcessor) EventWorkerPoolPressure(evt mtglib.EventWorkerPoolPressure) {
+	var value int64
+	if evt.High {
+		value = 1
+	}
+
+	s.client.Gauge(MetricWorkerPoolPressure, value)
+}
+
+func (s statsdProcessor) EventClientTimeSkew(evt mtglib.EventClientTimeSkew) {
+	s.client.PrecisionTiming(MetricClientTimeSkew, evt.Skew.Abs())
+
+	if evt.Rejected {
+		s.client.Incr(MetricClientTimeSkewRejections, 1)
+	}
+}
+
+func (s statsdProcessor) EventDNSQueriesSkipped(evt mtglib.EventDNSQueriesSkipped) {
+	if evt.DeltaA > 0 {
+		s.client.Incr(MetricDNSQueriesSkipped, int64(evt.DeltaA), statsd.StringTag(TagQueryType, TagQueryTypeA))
+	}
+
+	if evt.DeltaAAAA > 0 {
+		s.client.Incr(MetricDNSQueriesSkipped, int64(evt.DeltaAAAA), statsd.StringTag(TagQueryType, TagQueryTypeAAAA))
+	}
+}
+
+func (s statsdProcessor) EventDomainFrontingDial(evt mtglib.EventDomainFrontingDial) {
+	if evt.Failed {
+		s.client.Incr(MetricDomainFrontingDialFailures, 1)
+
+		return
+	}
+
+	s.client.PrecisionTiming(MetricDomainFrontingDialDuration, evt.Duration)
+}
+
+func (s statsdProcessor) EventWorkerQueueWait(evt mtglib.EventWorkerQueueWait) {
+	s.client.PrecisionTiming(MetricWorkerQueueWait, evt.Duration)
+}
+
+func (s statsdProcessor) EventTarpitted(_ mtglib.EventTarpitted) {
+	s.client.Incr(MetricTarpittedConnections, 1)
+}
+
+func (s statsdProcessor) EventDraining(_ mtglib.EventDraining) {
+	s.client.Gauge(MetricDraining, 1)
+}
+
+func (s statsdProcessor) EventDCConfigStale(evt mtglib.EventDCConfigStale) {
+	s.client.Gauge(MetricDCConfigFailures, int64(evt.Failures))
+}
+
+func (s statsdProcessor) EventDoHQueriesLimited(evt mtglib.EventDoHQueriesLimited) {
+	if evt.Delta > 0 {
+		s.client.Incr(MetricDoHQueriesLimited, int64(evt.Delta))
+	}
+}
+
+func (s statsdProcessor) EventTelegramDCSkipped(evt mtglib.EventTelegramDCSkipped) {
+	s.client.Incr(MetricTelegramDCSkips, 1, statsd.StringTag(TagDC, strconv.Itoa(evt.DC)))
+}
+
+func (s statsdProcessor) EventTelegramDialMetrics(evt mtglib.EventTelegramDialMetrics) {
+	dcTag := statsd.StringTag(TagDC, strconv.Itoa(evt.DC))
+
+	if evt.DeltaSuccesses > 0 {
+		s.client.Incr(MetricTelegramDials, int64(evt.DeltaSuccesses),
+			dcTag, statsd.StringTag(TagResult, TagResultSuccess))
+	}
+
+	if evt.DeltaFailures > 0 {
+		s.client.Incr(MetricTelegramDials, int64(evt.DeltaFailures),
+			dcTag, statsd.StringTag(TagResult, TagResultFailure))
+	}
+}
+
+func (s statsdProcessor) EventAcceptBackpressure(evt mtglib.EventAcceptBackpressure) {
+	s.client.PrecisionTiming(MetricAcceptBackpressure, evt.Duration)
+}
+
+func (s statsdProcessor) EventTelegramHandshakeRetried(evt mtglib.EventTelegramHandshakeRetried) {
+	dcTag := statsd.StringTag(TagDC, strconv.Itoa(evt.DC))
+
+	s.client.Incr(MetricTelegramHandshakeRetries, 1, dcTag)
+
+	if evt.Failed {
+		s.client.Incr(MetricTelegramHandshakeRetryFailures, 1, dcTag)
+	}
+}
+
+func (s statsdProcessor) EventDomainFrontingLimited(_ mtglib.EventDomainFrontingLimited) {
+	s.client.Incr(MetricDomainFrontingLimited, 1)
+}
+
+func (s statsdProcessor) EventRelaysLimited(_ mtglib.EventRelaysLimited) {
+	s.client.Incr(MetricRelaysLimited, 1)
+}
+
+func (s statsdProcessor) EventConcurrencyMetrics(evt mtglib.EventConcurrencyMetrics) {
+	s.client.Gauge(MetricWorkerPoolBusy, int64(evt.Workers))
+	s.client.Gauge(MetricRelaysActive, int64(evt.Relays))
+}
+
+func (s statsdProcessor) EventRelayStarted(evt mtglib.EventRelayStarted) {
+	s.client.Incr(MetricRelayStarted, 1, statsd.StringTag(TagDC, strconv.Itoa(evt.DC)))
+	s.client.PrecisionTiming(MetricHandshakeDuration, evt.HandshakeDuration)
+}
+
 func (s statsdProcessor) Shutdown() {
 	events := make([]mtglib.EventFinish, 0, len(s.streams))
 
@@ -114,24 +114,24 @@ func (suite *StatsdTestSuite) TestTelegramPath() {
 		mtglib.NewEventConnectedToDC("connID", net.ParseIP("10.1.0.10"), 2))
 	time.Sleep(statsdSleepTime)
 	suite.Contains(suite.statsdServer.String(),
-		"mtg.telegram_connections:+1|g|#telegram_ip:10.1.0.10,dc:2")
+		"mtg.telegram_connections:+1|g|#telegram_ip:10.1.0.10,telegram_ip_family:ipv4,dc:2")
 
 	suite.statsd.EventTraffic(
 		mtglib.NewEventTraffic("connID", 30, true))
 	time.Sleep(statsdSleepTime)
 	suite.Contains(suite.statsdServer.String(),
-		"mtg.telegram_traffic:30|c|#telegram_ip:10.1.0.10,dc:2,direction:to_client")
+		"mtg.telegram_traffic:30|c|#telegram_ip:10.1.0.10,telegram_ip_family:ipv4,dc:2,direction:to_client")
 
 	suite.statsd.EventTraffic(
 		mtglib.NewEventTraffic("connID", 90, false))
 	time.Sleep(statsdSleepTime)
 	suite.Contains(suite.statsdServer.String(),
-		"mtg.telegram_traffic:90|c|#telegram_ip:10.1.0.10,dc:2,direction:from_client")
+		"mtg.telegram_traffic:90|c|#telegram_ip:10.1.0.10,telegram_ip_family:ipv4,dc:2,direction:from_client")
 
 	suite.statsd.EventFinish(mtglib.NewEventFinish("connID"))
 	time.Sleep(statsdSleepTime)
 	suite.Contains(suite.statsdServer.String(),
-		"mtg.telegram_connections:-1|g|#telegram_ip:10.1.0.10,dc:2")
+		"mtg.telegram_connections:-1|g|#telegram_ip:10.1.0.10,telegram_ip_family:ipv4,dc:2")
 	suite.Contains(suite.statsdServer.String(),
 		"mtg.client_connections:-1|g|#ip_family:ipv4")
 
@@ -181,6 +181,14 @@ func (suite *StatsdTestSuite) TestEventConcurrencyLimited() {
 	suite.Equal("mtg.concurrency_limited:1|c", suite.statsdServer.String())
 }
 
+func (suite *StatsdTestSuite) TestEventRateLimited() {
+	suite.statsd.EventRateLimited(
+		mtglib.NewEventRateLimited(net.ParseIP("10.0.0.10")))
+
+	time.Sleep(statsdSleepTime)
+	suite.Equal("mtg.rate_limit_rejects:1|c", suite.statsdServer.String())
+}
+
 func (suite *StatsdTestSuite) TestEventIPBlocklisted() {
 	suite.statsd.EventIPBlocklisted(
 		mtglib.NewEventIPBlocklisted(net.ParseIP("10.0.0.10")))
@@ -232,6 +240,156 @@ func (suite *StatsdTestSuite) TestEventIPListCacheFallback() {
 	suite.Contains(suite.statsdServer.String(), "blocklist")
 }
 
+func (suite *StatsdTestSuite) TestEventDNSQueriesSkipped() {
+	suite.statsd.EventDNSQueriesSkipped(mtglib.NewEventDNSQueriesSkipped(0, 3))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.dns_queries_skipped:3|c")
+	suite.Contains(suite.statsdServer.String(), "AAAA")
+}
+
+func (suite *StatsdTestSuite) TestEventDoHQueriesLimited() {
+	suite.statsd.EventDoHQueriesLimited(mtglib.NewEventDoHQueriesLimited(4))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.doh_queries_limited:4|c")
+}
+
+func (suite *StatsdTestSuite) TestEventTelegramDCSkipped() {
+	suite.statsd.EventTelegramDCSkipped(mtglib.NewEventTelegramDCSkipped("connID", 2, 4))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_dc_skips_total:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventTelegramDialMetrics() {
+	suite.statsd.EventTelegramDialMetrics(mtglib.NewEventTelegramDialMetrics(2, 10, 1))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_dial_total:10|c")
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_dial_total:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventWorkerPoolPressure() {
+	suite.statsd.EventWorkerPoolPressure(mtglib.NewEventWorkerPoolPressure(95, 100, true))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.worker_pool_pressure:1|g")
+}
+
+func (suite *StatsdTestSuite) TestEventClientTimeSkew() {
+	suite.statsd.EventClientTimeSkew(
+		mtglib.NewEventClientTimeSkew("connID", net.ParseIP("10.0.0.10"), -1500*time.Millisecond))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.faketls_client_time_skew:1500|ms")
+	suite.NotContains(suite.statsdServer.String(), "mtg.faketls_skew_rejections_total")
+
+	suite.statsd.EventClientTimeSkew(
+		mtglib.NewEventClientTimeSkewChecked("connID", net.ParseIP("10.0.0.10"), time.Minute, true))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.faketls_skew_rejections_total:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventDomainFrontingDial() {
+	suite.statsd.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 250*time.Millisecond, false))
+	suite.statsd.EventDomainFrontingDial(
+		mtglib.NewEventDomainFrontingDial("connID", 5*time.Second, true))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.domain_fronting_dial_duration:250|ms")
+	suite.Contains(suite.statsdServer.String(), "mtg.domain_fronting_dial_failures_total:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventWorkerQueueWait() {
+	suite.statsd.EventWorkerQueueWait(
+		mtglib.NewEventWorkerQueueWait("connID", 15*time.Millisecond))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.worker_queue_wait:15|ms")
+}
+
+func (suite *StatsdTestSuite) TestEventAcceptBackpressure() {
+	suite.statsd.EventAcceptBackpressure(
+		mtglib.NewEventAcceptBackpressure(40 * time.Millisecond))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.accept_backpressure:40|ms")
+}
+
+func (suite *StatsdTestSuite) TestEventTelegramHandshakeRetried() {
+	suite.statsd.EventTelegramHandshakeRetried(
+		mtglib.NewEventTelegramHandshakeRetried("connID", 2, true))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_handshake_retries_total:1|c")
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_handshake_retry_failures_total:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventRelayStarted() {
+	suite.statsd.EventRelayStarted(
+		mtglib.NewEventRelayStarted("connID", 2, 300*time.Millisecond))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.relay_started_total:1|c")
+	suite.Contains(suite.statsdServer.String(), "mtg.handshake_duration:300|ms")
+}
+
+func (suite *StatsdTestSuite) TestEventTarpitted() {
+	suite.statsd.EventTarpitted(
+		mtglib.NewEventTarpitted("connID", net.ParseIP("10.0.0.10")))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.tarpitted_connections:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventRelaysLimited() {
+	suite.statsd.EventRelaysLimited(
+		mtglib.NewEventRelaysLimited("connID", net.ParseIP("10.0.0.10")))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.relays_limited:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventConcurrencyMetrics() {
+	suite.statsd.EventConcurrencyMetrics(mtglib.NewEventConcurrencyMetrics(10, 200))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.worker_pool_busy:10|g")
+	suite.Contains(suite.statsdServer.String(), "mtg.relays_active:200|g")
+}
+
+func (suite *StatsdTestSuite) TestEventDomainFrontingLimited() {
+	suite.statsd.EventDomainFrontingLimited(
+		mtglib.NewEventDomainFrontingLimited("connID", net.ParseIP("10.0.0.10")))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.domain_fronting_limited:1|c")
+}
+
+func (suite *StatsdTestSuite) TestEventDraining() {
+	suite.statsd.EventDraining(mtglib.NewEventDraining())
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.draining:1|g")
+}
+
+func (suite *StatsdTestSuite) TestEventDCConfigStale() {
+	suite.statsd.EventDCConfigStale(mtglib.NewEventDCConfigStale(3))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.dc_config_failures:3|g")
+}
+
+func (suite *StatsdTestSuite) TestEventTelegramHandshakeFailed() {
+	suite.statsd.EventTelegramHandshakeFailed(
+		mtglib.NewEventTelegramHandshakeFailed("connID", 2, mtglib.HandshakeFailureExhausted))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_handshake_failures:1|c")
+	suite.Contains(suite.statsdServer.String(), "frame_exhausted")
+}
+
+func (suite *StatsdTestSuite) TestEventConnectedToDCWithTFO() {
+	suite.statsd.EventStart(
+		mtglib.NewEventStart("connID", net.ParseIP("10.0.0.10")))
+	suite.statsd.EventConnectedToDC(
+		mtglib.NewEventConnectedToDCWithTFO("connID", net.ParseIP("10.1.0.10"), 2, true))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.telegram_connections_tfo_total:1|c|#dc:2")
+}
+
+func (suite *StatsdTestSuite) TestEventScannerDetected() {
+	suite.statsd.EventScannerDetected(
+		mtglib.NewEventScannerDetected("connID", net.ParseIP("10.0.0.10"), mtglib.ScannerReasonNotTLS, false))
+	time.Sleep(statsdSleepTime)
+	suite.Contains(suite.statsdServer.String(), "mtg.scanner_probes:1|c")
+	suite.Contains(suite.statsdServer.String(), "not_tls")
+}
+
 func TestStatsd(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, &StatsdTestSuite{})
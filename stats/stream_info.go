@@ -1,6 +1,7 @@
 package stats
 
 import (
+	"net"
 	"time"
 
 	statsd "github.com/smira/go-statsd"
@@ -12,6 +13,8 @@ type streamInfo struct {
 	startTime       time.Time // время начала сессии
 	firstByteTime   time.Time // время получения первого байта (для TTFB)
 	hasFirstByte    bool      // флаг получения первого байта
+	bytesToClient   uint64    // трафик Telegram -> клиент (для throughput)
+	bytesFromClient uint64    // трафик клиент -> Telegram (для throughput)
 }
 
 func (s streamInfo) T(key string) statsd.Tag {
@@ -23,6 +26,8 @@ func (s *streamInfo) Reset() {
 	s.hasFirstByte = false
 	s.startTime = time.Time{}
 	s.firstByteTime = time.Time{}
+	s.bytesToClient = 0
+	s.bytesFromClient = 0
 
 	for k := range s.tags {
 		delete(s.tags, k)
@@ -36,3 +41,11 @@ func getDirection(isRead bool) string {
 
 	return TagDirectionFromClient
 }
+
+func getIPFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return TagIPFamilyIPv4
+	}
+
+	return TagIPFamilyIPv6
+}